@@ -4,6 +4,7 @@ import (
 	"cloudpico-gateway/internal/app"
 	"cloudpico-gateway/internal/config"
 	"cloudpico-gateway/internal/logging"
+	"cloudpico-shared/buildinfo"
 	"context"
 	"errors"
 	"fmt"
@@ -13,7 +14,10 @@ import (
 	"syscall"
 )
 
+// version, commit and buildDate are set at build time via -ldflags, e.g.
+// "-X main.version=1.2.3 -X main.commit=<sha> -X main.buildDate=<RFC 3339>".
 var version = "dev"
+var commit, buildDate string
 var appName = "cloudpico-gateway"
 
 func main() {
@@ -23,12 +27,17 @@ func main() {
 		os.Exit(1)
 	}
 
-	logger := logging.New(cfg, version, appName)
+	build := buildinfo.New(version, commit, buildDate)
+	logger := logging.New(cfg, build, appName)
 	slog.SetDefault(logger)
 
 	slog.Info("starting",
 		"app", appName,
-		"version", version,
+		"version", build.Version,
+		"commit", build.Commit,
+		"build_date", build.BuildDate,
+		"go_version", build.GoVersion,
+		"platform", build.OS+"/"+build.Arch,
 		"env", cfg.AppEnv,
 		"log_level", cfg.LogLevel.String(),
 	)
@@ -36,7 +45,7 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	if err := app.Run(ctx, cfg); err != nil && !errors.Is(err, context.Canceled) {
+	if err := app.Run(ctx, cfg, build); err != nil && !errors.Is(err, context.Canceled) {
 		slog.Error("run failed", "error", err)
 		os.Exit(1)
 	}
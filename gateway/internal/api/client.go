@@ -0,0 +1,88 @@
+// Package api is a minimal client for the cloudpico server's HTTP API.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Station is the subset of the server's station resource the gateway uses.
+type Station struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type page[T any] struct {
+	Items  []T `json:"items"`
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type errorBody struct {
+	Message string `json:"message"`
+}
+
+// Client calls the server API.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the server at baseURL (e.g. "http://server:8080").
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// stationsPageSize is the server's maximum page size for /api/v1/stations.
+const stationsPageSize = 1000
+
+// Stations returns every station known to the server, following pagination.
+func (c *Client) Stations(ctx context.Context) ([]Station, error) {
+	var stations []Station
+	for offset := 0; ; {
+		var p page[Station]
+		q := url.Values{"limit": {strconv.Itoa(stationsPageSize)}, "offset": {strconv.Itoa(offset)}}
+		if err := c.get(ctx, "/api/v1/stations?"+q.Encode(), &p); err != nil {
+			return nil, fmt.Errorf("list stations: %w", err)
+		}
+		stations = append(stations, p.Items...)
+		offset += len(p.Items)
+		if len(p.Items) == 0 || offset >= p.Total {
+			return stations, nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body errorBody
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, body.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
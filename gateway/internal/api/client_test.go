@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestClient_Stations(t *testing.T) {
+	all := []Station{{ID: "1", Name: "home"}, {ID: "2", Name: "garden"}, {ID: "3", Name: "attic"}}
+
+	t.Run("follows pagination", func(t *testing.T) {
+		var calls int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if r.URL.Path != "/api/v1/stations" {
+				t.Errorf("path = %s; want /api/v1/stations", r.URL.Path)
+			}
+			// Serve two items per page regardless of the requested limit.
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			end := min(len(all), offset+2)
+			_ = json.NewEncoder(w).Encode(page[Station]{Items: all[offset:end], Total: len(all), Limit: 2, Offset: offset})
+		}))
+		defer srv.Close()
+
+		got, err := NewClient(srv.URL + "/").Stations(context.Background())
+		if err != nil {
+			t.Fatalf("Stations() = %v", err)
+		}
+		if len(got) != 3 || got[2] != all[2] {
+			t.Errorf("Stations() = %+v; want %+v", got, all)
+		}
+		if calls != 2 {
+			t.Errorf("calls = %d; want 2", calls)
+		}
+	})
+
+	t.Run("reports server error message", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error": "Internal Server Error", "message": "database is locked"}`))
+		}))
+		defer srv.Close()
+
+		_, err := NewClient(srv.URL).Stations(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "database is locked") {
+			t.Errorf("Stations() err = %v; want server message", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// adminShutdownTimeout bounds how long the admin API waits for requests in
+// flight at shutdown.
+const adminShutdownTimeout = 5 * time.Second
+
+// deviceTracer turns full logging of single BLE devices on and off; it is
+// implemented by *ble.Listener.
+type deviceTracer interface {
+	TraceDevice(addr string, on bool)
+	TracedDevices() []string
+}
+
+// adminHandler returns the routes of the admin API: those of each input.
+func adminHandler(inputs []input) http.Handler {
+	mux := http.NewServeMux()
+	for _, in := range inputs {
+		if in.admin != nil {
+			in.admin(mux)
+		}
+	}
+	return mux
+}
+
+// serveAdmin serves h on ln until ctx is canceled. The admin API is meant for
+// the gateway's host only; nothing is authenticated.
+func serveAdmin(ctx context.Context, ln net.Listener, h http.Handler) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// bleTraceRoutes registers the routes listing, adding and removing the BLE
+// devices whose every advertisement is logged.
+func bleTraceRoutes(mux *http.ServeMux, tracer deviceTracer) {
+	list := func(w http.ResponseWriter) {
+		devices := tracer.TracedDevices()
+		if devices == nil {
+			devices = []string{}
+		}
+		writeAdminJSON(w, http.StatusOK, map[string][]string{"devices": devices})
+	}
+	mux.HandleFunc("GET /debug/ble/devices", func(w http.ResponseWriter, r *http.Request) {
+		list(w)
+	})
+	mux.HandleFunc("PUT /debug/ble/devices/{addr}", func(w http.ResponseWriter, r *http.Request) {
+		addr, err := net.ParseMAC(r.PathValue("addr"))
+		if err != nil || len(addr) != 6 {
+			writeAdminJSON(w, http.StatusBadRequest, map[string]string{"error": "addr must be a Bluetooth address such as AA:BB:CC:DD:EE:FF"})
+			return
+		}
+		tracer.TraceDevice(strings.ToUpper(addr.String()), true)
+		list(w)
+	})
+	mux.HandleFunc("DELETE /debug/ble/devices/{addr}", func(w http.ResponseWriter, r *http.Request) {
+		tracer.TraceDevice(r.PathValue("addr"), false)
+		list(w)
+	})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("gateway: write admin response failed", "error", err)
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fakeTracer struct {
+	traced []string
+}
+
+func (f *fakeTracer) TraceDevice(addr string, on bool) {
+	addr = strings.ToUpper(addr)
+	f.traced = slices.DeleteFunc(f.traced, func(a string) bool { return a == addr })
+	if on {
+		f.traced = append(f.traced, addr)
+	}
+}
+
+func (f *fakeTracer) TracedDevices() []string { return f.traced }
+
+func TestAdminHandler_BLETrace(t *testing.T) {
+	tracer := &fakeTracer{}
+	h := adminHandler([]input{
+		{name: "simulate"},
+		{name: "ble", admin: func(mux *http.ServeMux) { bleTraceRoutes(mux, tracer) }},
+	})
+	for _, tc := range []struct {
+		method, path string
+		wantStatus   int
+		wantBody     string
+	}{
+		{http.MethodGet, "/debug/ble/devices", http.StatusOK, `{"devices":[]}`},
+		{http.MethodPut, "/debug/ble/devices/aa:bb:cc:dd:ee:ff", http.StatusOK, `{"devices":["AA:BB:CC:DD:EE:FF"]}`},
+		{http.MethodPut, "/debug/ble/devices/garden", http.StatusBadRequest, `"error"`},
+		{http.MethodGet, "/debug/ble/devices", http.StatusOK, `{"devices":["AA:BB:CC:DD:EE:FF"]}`},
+		{http.MethodDelete, "/debug/ble/devices/AA:BB:CC:DD:EE:FF", http.StatusOK, `{"devices":[]}`},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.wantStatus || !strings.Contains(rec.Body.String(), tc.wantBody) {
+			t.Errorf("%s %s = %d %s; want %d containing %s", tc.method, tc.path, rec.Code, rec.Body, tc.wantStatus, tc.wantBody)
+		}
+	}
+}
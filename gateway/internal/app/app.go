@@ -1,19 +1,59 @@
 package app
 
 import (
+	"cloudpico-gateway/internal/api"
 	"cloudpico-gateway/internal/ble"
 	"cloudpico-gateway/internal/config"
 	"cloudpico-gateway/internal/mqtt"
+	"cloudpico-gateway/internal/outbox"
+	"cloudpico-gateway/internal/sensor"
+	"cloudpico-gateway/internal/simulate"
+	"cloudpico-gateway/internal/stationmap"
 	"context"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpico-shared/buildinfo"
+	cloudpico_shared "cloudpico-shared/types"
 )
 
-func Run(ctx context.Context, cfg config.Config) error {
+// shutdownTimeout bounds how long Run waits for inputs to stop.
+const shutdownTimeout = 10 * time.Second
+
+// input is a telemetry source started by Run. run blocks until ctx is canceled.
+type input struct {
+	name string
+	run  func(ctx context.Context) error
+	// stationIDs are the stations the input is configured to publish for, as
+	// far as they are known before any telemetry arrives.
+	stationIDs []string
+	// health, if set, adds the input's devices and state to a gateway health
+	// message.
+	health func(h *mqtt.GatewayHealth)
+	// admin, if set, registers the input's routes of the admin API.
+	admin func(mux *http.ServeMux)
+}
+
+// Run starts the gateway. build describes the binary (set via ldflags); its
+// version is reported upstream in telemetry metadata and the full build in
+// station health messages.
+//
+// Shutdown happens in reverse order of startup: the inputs are stopped first,
+// then the outbox replays what it can, the retained gateway health message is
+// cleared, and the MQTT connection is closed last, so no reading produced
+// before shutdown is published to a closed client.
+func Run(ctx context.Context, cfg config.Config, build buildinfo.Info) error {
 	slog.Info("initializing gateway",
 		"mqtt_broker", cfg.MQTTBroker,
 		"mqtt_port", cfg.MQTTPort,
 		"mqtt_client_id", cfg.MQTTClientID,
+		"inputs", cfg.Inputs,
 	)
 
 	// Initialize MQTT client
@@ -22,32 +62,336 @@ func Run(ctx context.Context, cfg config.Config) error {
 		return err
 	}
 
-	// Connect to MQTT broker before starting BLE listener
+	// Readings stay in the outbox until the server acks them; on reconnect the
+	// unacknowledged ones are replayed.
+	box := outbox.New(mqttClient, outbox.Options{
+		MaxPending: cfg.OutboxMaxPending,
+		AckTimeout: cfg.OutboxAckTimeout,
+	})
+	mqttClient.SetAckHandler(box.HandleAck)
+	mqttClient.SetOnConnected(box.Trigger)
+
+	stations := stationmap.Map{}
+	if cfg.StationMapFile != "" {
+		stations, err = stationmap.Load(cfg.StationMapFile)
+		if err != nil {
+			return err
+		}
+	}
+	inputs, err := buildInputs(cfg, box, mqttClient, stations, build)
+	if err != nil {
+		return err
+	}
+	if err := validateStations(ctx, cfg, stations, inputs); err != nil {
+		return err
+	}
+
+	var adminLn net.Listener
+	if cfg.AdminAddr != "" {
+		adminLn, err = net.Listen("tcp", cfg.AdminAddr)
+		if err != nil {
+			return fmt.Errorf("admin API: %w", err)
+		}
+		defer adminLn.Close()
+	}
+
+	// Connect to MQTT broker before starting the inputs
 	// This ensures we're connected before processing telemetry
 	if err := mqttClient.Connect(ctx); err != nil {
 		return fmt.Errorf("mqtt connect failed: %w", err)
 	}
 	defer mqttClient.Disconnect()
 
+	boxCtx, stopBox := context.WithCancel(context.Background())
+	boxDone := make(chan struct{})
+	go func() {
+		defer close(boxDone)
+		box.Run(boxCtx)
+	}()
+
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	healthDone := make(chan struct{})
+	go func() {
+		defer close(healthDone)
+		if cfg.HealthPublishInterval <= 0 {
+			return
+		}
+		publishHealth(healthCtx, cfg.HealthPublishInterval, func() mqtt.GatewayHealth {
+			return gatewayHealth(cfg.MQTTClientID, build, box.Len(), inputs)
+		}, mqttClient.PublishGatewayHealth)
+	}()
+
+	if adminLn != nil {
+		slog.Info("gateway: admin API listening", "addr", adminLn.Addr().String())
+		go func() {
+			if err := serveAdmin(ctx, adminLn, adminHandler(inputs)); err != nil {
+				slog.Error("gateway: admin API stopped", "error", err)
+			}
+		}()
+	}
+
+	var wg sync.WaitGroup
+	for _, in := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			supervise(ctx, in)
+		}()
+	}
+	<-ctx.Done()
+
+	slog.Info("gateway shutting down")
+	inputsDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(inputsDone)
+	}()
+	select {
+	case <-inputsDone:
+	case <-time.After(shutdownTimeout):
+		slog.Warn("gateway: inputs did not stop in time", "timeout", shutdownTimeout.String())
+	}
+
+	stopBox()
+	<-boxDone
+	if n, err := box.Replay(); err != nil {
+		slog.Warn("gateway: final replay interrupted", "sent", n, "pending", box.Len(), "error", err)
+	} else if pending := box.Len(); pending > 0 {
+		slog.Info("gateway: unacknowledged readings dropped at shutdown", "pending", pending)
+	}
+
+	stopHealth()
+	<-healthDone
+	if cfg.HealthPublishInterval > 0 {
+		if err := mqttClient.ClearGatewayHealth(cfg.MQTTClientID); err != nil {
+			slog.Warn("gateway: clear health failed", "gateway_id", cfg.MQTTClientID, "error", err)
+		}
+	}
+	return nil
+}
+
+// buildInputs creates the inputs selected by cfg.Inputs. Configuration errors
+// (bad rules or sensor files) are returned before anything is started.
+func buildInputs(cfg config.Config, box *outbox.Outbox, mqttClient *mqtt.Client, stations stationmap.Map, build buildinfo.Info) ([]input, error) {
+	var inputs []input
+	for _, name := range cfg.Inputs {
+		switch name {
+		case config.InputBLE:
+			in, err := bleInput(cfg, box, mqttClient, stations, build)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, in)
+		case config.InputI2C:
+			in, err := i2cInput(cfg, box, mqttClient, stations, build)
+			if err != nil {
+				return nil, err
+			}
+			inputs = append(inputs, in)
+		case config.InputSimulate:
+			sim := simulate.New(box, cfg.SimulateStationIDs, cfg.SensorPollInterval, build.Version)
+			inputs = append(inputs, input{name: name, run: sim.Run})
+		default:
+			return nil, fmt.Errorf("unknown input %q", name)
+		}
+	}
+	return inputs, nil
+}
+
+func bleInput(cfg config.Config, box *outbox.Outbox, mqttClient *mqtt.Client, stations stationmap.Map, build buildinfo.Info) (input, error) {
+	rules := ble.DefaultRules()
+	if cfg.BLERulesFile != "" {
+		var err error
+		rules, err = ble.LoadRules(cfg.BLERulesFile)
+		if err != nil {
+			return input{}, err
+		}
+	}
+	bleHandler := ble.NewBLESensorHandler(box, build.Version, stations)
+	router := ble.Router{
+		ble.HandlerSensor: bleHandler.HandleMatch,
+	}
+	if err := router.Validate(rules); err != nil {
+		return input{}, err
+	}
+	var stationIDs []string
+	for _, rule := range rules {
+		if rule.StationID != "" {
+			stationIDs = append(stationIDs, rule.StationID)
+		}
+	}
 	bleListener := ble.NewListener(ble.Options{
 		Adapter: "hci0",
-		Filter: ble.Filter{
-			LocalName:            "",
-			CompanyID:            0xFFFF,
-			ManufacturerDataPref: []byte{0x01, 0xD0},
+		Rules:   rules,
+		Watchdog: ble.WatchdogOptions{
+			Timeout:       cfg.BLEWatchdogTimeout,
+			ResetAdapter:  cfg.BLEWatchdogResetAdapter,
+			EscalateAfter: cfg.BLEWatchdogEscalateAfter,
+			// A wedged scanner silences every BLE station: report the ones
+			// configured or seen so far.
+			OnHealthChange: func(healthy bool, lastSeen time.Time) {
+				ids := slices.Compact(slices.Sorted(slices.Values(append(slices.Clone(stationIDs), bleHandler.Stations()...))))
+				for _, id := range ids {
+					err := mqttClient.PublishStationHealth(mqtt.StationHealth{
+						StationID: id,
+						LastSeen:  lastSeen,
+						Healthy:   healthy,
+						Metadata:  healthMetadata(build),
+					})
+					if err != nil {
+						slog.Warn("ble: publish health failed", "station_id", id, "error", err)
+					}
+				}
+			},
+		},
+		DebugLog: ble.DebugLogOptions{
+			DeviceInterval:  cfg.BLEDebugLogInterval,
+			SummaryInterval: cfg.BLEDebugSummaryInterval,
 		},
 	})
-	bleHandler := ble.NewBLESensorHandler(mqttClient)
-	go func() {
-		err := bleListener.Run(ctx, bleHandler.HandleMatch)
+	return input{
+		name: config.InputBLE,
+		run: func(ctx context.Context) error {
+			return bleListener.Run(ctx, router.HandleMatch)
+		},
+		stationIDs: stationIDs,
+		health: func(h *mqtt.GatewayHealth) {
+			for _, d := range bleHandler.Devices() {
+				h.Devices = append(h.Devices, mqtt.DeviceHealth{
+					Source:    stationmap.KindBLE + ":" + d.DeviceID,
+					StationID: d.StationID,
+					LastSeen:  timeOrNil(d.LastSeen),
+				})
+			}
+			dedup := bleHandler.DedupStats()
+			h.Dedup = &mqtt.DedupHealth{Published: dedup.Published, Duplicates: dedup.Duplicates}
+			adapter := bleListener.AdapterState()
+			h.BLEAdapter = &mqtt.BLEAdapterHealth{
+				Healthy:           adapter.Healthy,
+				LastAdvertisement: timeOrNil(adapter.LastSeen),
+				Restarts:          adapter.Restarts,
+				BlueZDisconnects:  adapter.BlueZDisconnects,
+			}
+		},
+		admin: func(mux *http.ServeMux) {
+			bleTraceRoutes(mux, bleListener)
+		},
+	}, nil
+}
+
+func i2cInput(cfg config.Config, box *outbox.Outbox, mqttClient *mqtt.Client, stations stationmap.Map, build buildinfo.Info) (input, error) {
+	defs := sensor.DefaultDefinitions(cfg.BME280Address, cfg.DeviceStationID, cfg.SensorPollInterval)
+	if cfg.SensorsFile != "" {
+		var err error
+		defs, err = sensor.LoadDefinitions(cfg.SensorsFile, cfg.SensorPollInterval)
 		if err != nil {
-			slog.Warn("ble listener could not be initialized; gateway continues without BLE",
-				"error", err,
-			)
+			return input{}, err
 		}
-	}()
-	<-ctx.Done()
+	}
+	stationIDs := make([]string, len(defs))
+	for i := range defs {
+		defs[i].StationID = stations.Resolve(stationmap.KindI2C, defs[i].Name, defs[i].StationID)
+		stationIDs[i] = defs[i].StationID
+	}
+	poller := sensor.NewPoller(box, defs, sensor.Options{
+		GatewayVersion: build.Version,
+		OnHealthChange: func(h sensor.Health) {
+			err := mqttClient.PublishStationHealth(mqtt.StationHealth{
+				StationID: h.StationID,
+				LastSeen:  h.LastSuccess,
+				Healthy:   h.Healthy,
+				Metadata:  healthMetadata(build),
+			})
+			if err != nil {
+				slog.Warn("sensor: publish health failed", "station_id", h.StationID, "error", err)
+			}
+		},
+	})
+	return input{
+		name:       config.InputI2C,
+		run:        poller.Run,
+		stationIDs: stationIDs,
+		health: func(h *mqtt.GatewayHealth) {
+			for _, s := range poller.Health() {
+				h.Devices = append(h.Devices, mqtt.DeviceHealth{
+					Source:    stationmap.KindI2C + ":" + s.Name,
+					StationID: s.StationID,
+					LastSeen:  timeOrNil(s.LastSuccess),
+				})
+			}
+		},
+	}, nil
+}
 
-	slog.Info("gateway shutting down")
+// healthMetadata identifies the gateway build in station health messages.
+func healthMetadata(build buildinfo.Info) *cloudpico_shared.Metadata {
+	return &cloudpico_shared.Metadata{
+		GatewayVersion:   build.Version,
+		GatewayCommit:    build.Commit,
+		GatewayBuildDate: build.BuildDate,
+	}
+}
+
+// validateStations checks the mapped and configured stations against the
+// server's station list. The server creates stations on their first reading,
+// so unknown stations are only a warning unless cfg.StationMapStrict is set.
+func validateStations(ctx context.Context, cfg config.Config, stations stationmap.Map, inputs []input) error {
+	ids := stations.StationIDs()
+	for _, in := range inputs {
+		ids = append(ids, in.stationIDs...)
+	}
+	if cfg.ServerURL == "" || len(ids) == 0 {
+		return nil
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	unknown, err := stationmap.Unknown(ctx, api.NewClient(cfg.ServerURL), ids)
+	switch {
+	case err != nil && cfg.StationMapStrict:
+		return fmt.Errorf("validate stations: %w", err)
+	case err != nil:
+		slog.Warn("gateway: could not validate stations against server", "server_url", cfg.ServerURL, "error", err)
+	case len(unknown) > 0 && cfg.StationMapStrict:
+		return fmt.Errorf("stations not known to server: %s", strings.Join(unknown, ", "))
+	case len(unknown) > 0:
+		slog.Warn("gateway: stations not known to server; they will be created on first reading", "stations", unknown)
+	default:
+		slog.Info("gateway: stations validated against server", "count", len(ids))
+	}
 	return nil
 }
+
+// supervise runs in until ctx is canceled, restarting it with backoff whenever
+// it returns early, so one failing input never stops the others.
+func supervise(ctx context.Context, in input) {
+	const (
+		minBackoff = time.Second
+		maxBackoff = time.Minute
+		// An input that ran this long before failing is considered to have
+		// recovered, and the backoff starts over.
+		stableAfter = time.Minute
+	)
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := in.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) >= stableAfter {
+			backoff = minBackoff
+		}
+		if err != nil {
+			slog.Warn("gateway: input failed; restarting", "input", in.name, "error", err, "retry_in", backoff.String())
+		} else {
+			slog.Warn("gateway: input stopped unexpectedly; restarting", "input", in.name, "retry_in", backoff.String())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
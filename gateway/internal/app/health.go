@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"cloudpico-gateway/internal/mqtt"
+	"cloudpico-shared/buildinfo"
+)
+
+// publishHealth publishes the message built by snapshot right away and then
+// every interval until ctx is canceled. Failed publishes are logged and the
+// next tick tries again.
+func publishHealth(ctx context.Context, interval time.Duration, snapshot func() mqtt.GatewayHealth, publish func(mqtt.GatewayHealth) error) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		h := snapshot()
+		if err := publish(h); err != nil {
+			slog.Warn("gateway: publish health failed", "gateway_id", h.GatewayID, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// gatewayHealth builds the gateway health message from the outbox depth and
+// what each input reports about its devices.
+func gatewayHealth(gatewayID string, build buildinfo.Info, bufferDepth int, inputs []input) mqtt.GatewayHealth {
+	h := mqtt.GatewayHealth{
+		GatewayID:   gatewayID,
+		Timestamp:   time.Now(),
+		Devices:     []mqtt.DeviceHealth{},
+		BufferDepth: bufferDepth,
+		Metadata:    healthMetadata(build),
+	}
+	for _, in := range inputs {
+		if in.health != nil {
+			in.health(&h)
+		}
+	}
+	return h
+}
+
+// timeOrNil returns nil for the zero time, which health messages report as null.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloudpico-gateway/internal/mqtt"
+	"cloudpico-shared/buildinfo"
+)
+
+func TestPublishHealth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	published := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		publishHealth(ctx, time.Millisecond, func() mqtt.GatewayHealth {
+			return mqtt.GatewayHealth{GatewayID: "gw"}
+		}, func(mqtt.GatewayHealth) error {
+			if published++; published == 3 {
+				cancel()
+			}
+			return errors.New("not connected") // failures do not stop the publisher
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publishHealth did not return after ctx was canceled")
+	}
+	if published < 3 {
+		t.Errorf("published %d messages; want at least 3", published)
+	}
+}
+
+func TestPublishHealth_PublishesAtStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	published := 0
+	publishHealth(ctx, time.Hour, func() mqtt.GatewayHealth {
+		return mqtt.GatewayHealth{}
+	}, func(mqtt.GatewayHealth) error {
+		published++
+		return nil
+	})
+	if published != 1 {
+		t.Errorf("published %d messages; want 1 before the first interval", published)
+	}
+}
+
+func TestGatewayHealth(t *testing.T) {
+	seen := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	inputs := []input{
+		{name: "simulate"},
+		{name: "i2c", health: func(h *mqtt.GatewayHealth) {
+			h.Devices = append(h.Devices,
+				mqtt.DeviceHealth{Source: "i2c:indoor", StationID: "home", LastSeen: timeOrNil(seen)},
+				mqtt.DeviceHealth{Source: "i2c:attic", StationID: "attic", LastSeen: timeOrNil(time.Time{})},
+			)
+		}},
+	}
+	h := gatewayHealth("gw-1", buildinfo.New("1.2.3", "abc", ""), 7, inputs)
+
+	if h.GatewayID != "gw-1" || h.BufferDepth != 7 || h.Metadata.GatewayVersion != "1.2.3" {
+		t.Errorf("gatewayHealth() = %+v", h)
+	}
+	if len(h.Devices) != 2 || !h.Devices[0].LastSeen.Equal(seen) || h.Devices[1].LastSeen != nil {
+		t.Errorf("Devices = %+v; want indoor seen at %v and attic never seen", h.Devices, seen)
+	}
+	if h.Dedup != nil || h.BLEAdapter != nil {
+		t.Errorf("Dedup = %v, BLEAdapter = %v; want nil without the BLE input", h.Dedup, h.BLEAdapter)
+	}
+}
@@ -0,0 +1,82 @@
+package ble
+
+import "time"
+
+// Frames of a message that is not complete within frameAssemblyTimeout are
+// dropped; at most frameAssemblyMaxPending messages are buffered at a time.
+const (
+	frameAssemblyTimeout    = 30 * time.Second
+	frameAssemblyMaxPending = 256
+)
+
+type frameKey struct {
+	deviceID  uint32
+	readingID uint32
+}
+
+type pendingMessage struct {
+	chunks   [][]byte
+	received int
+	started  time.Time
+}
+
+// frameAssembler reassembles multi-frame sensor messages keyed by device and
+// reading ID. Sensors repeat every frame for the advertising duration, so
+// duplicate frames are expected and ignored. It is not safe for concurrent use.
+type frameAssembler struct {
+	pending map[frameKey]*pendingMessage
+}
+
+func newFrameAssembler() *frameAssembler {
+	return &frameAssembler{pending: make(map[frameKey]*pendingMessage)}
+}
+
+// add records f and returns the message body once every frame has arrived.
+func (a *frameAssembler) add(f sensorFrame, now time.Time) ([]byte, bool) {
+	a.expire(now)
+	key := frameKey{f.DeviceID, f.ReadingID}
+	p := a.pending[key]
+	if p != nil && len(p.chunks) != f.Count {
+		// The sensor restarted its reading counter with a different layout.
+		p = nil
+	}
+	if p == nil {
+		if len(a.pending) >= frameAssemblyMaxPending {
+			a.evictOldest()
+		}
+		p = &pendingMessage{chunks: make([][]byte, f.Count), started: now}
+		a.pending[key] = p
+	}
+	if p.chunks[f.Index] == nil {
+		p.chunks[f.Index] = append([]byte{}, f.Data...)
+		p.received++
+	}
+	if p.received < f.Count {
+		return nil, false
+	}
+	delete(a.pending, key)
+	var body []byte
+	for _, c := range p.chunks {
+		body = append(body, c...)
+	}
+	return body, true
+}
+
+func (a *frameAssembler) expire(now time.Time) {
+	for key, p := range a.pending {
+		if now.Sub(p.started) > frameAssemblyTimeout {
+			delete(a.pending, key)
+		}
+	}
+}
+
+func (a *frameAssembler) evictOldest() {
+	var oldest frameKey
+	var oldestStarted time.Time
+	for key, p := range a.pending {
+		if oldestStarted.IsZero() || p.started.Before(oldestStarted) {
+			oldest, oldestStarted = key, p.started
+		}
+	}
+	delete(a.pending, oldest)
+}
@@ -0,0 +1,61 @@
+package ble
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func mustParseFrame(t *testing.T, data []byte) sensorFrame {
+	t.Helper()
+	f, err := parseSensorFrame(data)
+	if err != nil {
+		t.Fatalf("parseSensorFrame() error = %v", err)
+	}
+	return f
+}
+
+func TestFrameAssembler(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	want := append(append([]byte(nil), goldenFrames[0][sensorFrameHeaderLen:]...), goldenFrames[1][sensorFrameHeaderLen:]...)
+
+	t.Run("reassembles out of order with duplicates", func(t *testing.T) {
+		a := newFrameAssembler()
+		if _, ok := a.add(mustParseFrame(t, goldenFrames[1]), now); ok {
+			t.Fatal("add(frame 1) complete = true; want false")
+		}
+		if _, ok := a.add(mustParseFrame(t, goldenFrames[1]), now); ok {
+			t.Fatal("add(duplicate frame 1) complete = true; want false")
+		}
+		body, ok := a.add(mustParseFrame(t, goldenFrames[0]), now.Add(time.Second))
+		if !ok || !bytes.Equal(body, want) {
+			t.Fatalf("add(frame 0) = % X, %v; want % X, true", body, ok, want)
+		}
+		if len(a.pending) != 0 {
+			t.Errorf("pending = %d; want 0", len(a.pending))
+		}
+	})
+
+	t.Run("drops stale frames", func(t *testing.T) {
+		a := newFrameAssembler()
+		a.add(mustParseFrame(t, goldenFrames[0]), now)
+		if _, ok := a.add(mustParseFrame(t, goldenFrames[1]), now.Add(frameAssemblyTimeout+time.Second)); ok {
+			t.Error("add(after timeout) complete = true; want false")
+		}
+	})
+
+	t.Run("bounds pending messages", func(t *testing.T) {
+		a := newFrameAssembler()
+		for i := range frameAssemblyMaxPending + 10 {
+			f := mustParseFrame(t, goldenFrames[0])
+			f.ReadingID = uint32(i)
+			a.add(f, now.Add(time.Duration(i)*time.Millisecond))
+		}
+		if len(a.pending) != frameAssemblyMaxPending {
+			t.Errorf("pending = %d; want %d", len(a.pending), frameAssemblyMaxPending)
+		}
+		if _, ok := a.pending[frameKey{0x12345678, 0}]; ok {
+			t.Error("oldest message still pending; want evicted")
+		}
+	})
+}
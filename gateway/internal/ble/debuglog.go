@@ -0,0 +1,184 @@
+package ble
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugLogOptions throttles the debug logging of advertisements, which near
+// busy environments would otherwise log every advertisement of every device.
+type DebugLogOptions struct {
+	// DeviceInterval is the minimum time between two logged advertisements of
+	// one device that match no rule; 0 logs them all.
+	DeviceInterval time.Duration
+	// SummaryInterval is how often the devices seen, the advertisement rate
+	// and the matches are logged; 0 disables the summary.
+	SummaryInterval time.Duration
+}
+
+// advertLog logs advertisements: at debug level, non-matching ones throttled
+// per device plus a periodic summary; at info level, every advertisement of a
+// traced device.
+type advertLog struct {
+	opts   DebugLogOptions
+	logger *slog.Logger // nil logs to slog.Default()
+
+	mu         sync.Mutex
+	traced     map[string]struct{}  // addresses, upper case
+	lastLogged map[string]time.Time // by address, for DeviceInterval
+
+	// Counts since periodStart, for the summary.
+	periodStart time.Time
+	devices     map[string]struct{}
+	adverts     int
+	matches     int
+	suppressed  int
+}
+
+func newAdvertLog(opts DebugLogOptions) *advertLog {
+	return &advertLog{
+		opts:       opts,
+		traced:     make(map[string]struct{}),
+		lastLogged: make(map[string]time.Time),
+		devices:    make(map[string]struct{}),
+	}
+}
+
+func (a *advertLog) log() *slog.Logger {
+	if a.logger != nil {
+		return a.logger
+	}
+	return slog.Default()
+}
+
+// trace turns logging every advertisement of the device at addr on or off.
+func (a *advertLog) trace(addr string, on bool) {
+	addr = strings.ToUpper(addr)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if on {
+		a.traced[addr] = struct{}{}
+	} else {
+		delete(a.traced, addr)
+	}
+}
+
+// tracedDevices returns the traced addresses, sorted.
+func (a *advertLog) tracedDevices() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Sorted(maps.Keys(a.traced))
+}
+
+// observe logs advertisement r, seen at now; matched reports whether m, the
+// match of a rule, is set.
+func (a *advertLog) observe(r ScanResult, m Match, matched bool, now time.Time) {
+	logger := a.log()
+	debug := logger.Enabled(context.Background(), slog.LevelDebug)
+	addr := strings.ToUpper(r.Address)
+
+	a.mu.Lock()
+	_, traced := a.traced[addr]
+	logUnmatched := false
+	var summary []any
+	if debug {
+		if a.periodStart.IsZero() {
+			a.periodStart = now
+		}
+		a.devices[addr] = struct{}{}
+		a.adverts++
+		if matched {
+			a.matches++
+		} else if !traced {
+			last, ok := a.lastLogged[addr]
+			switch {
+			case a.opts.DeviceInterval <= 0:
+				logUnmatched = true
+			case !ok || now.Sub(last) >= a.opts.DeviceInterval:
+				a.lastLogged[addr] = now
+				logUnmatched = true
+			default:
+				a.suppressed++
+			}
+		}
+		// Without a summary, periods still end to forget throttled devices.
+		period := a.opts.SummaryInterval
+		if period <= 0 {
+			period = a.opts.DeviceInterval
+		}
+		if period > 0 && now.Sub(a.periodStart) >= period {
+			summary = a.summarizeLocked(now)
+			if a.opts.SummaryInterval <= 0 {
+				summary = nil
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	switch {
+	case traced:
+		rule := ""
+		if matched {
+			rule = m.Rule
+		}
+		logger.Info("ble: advertisement",
+			"addr", r.Address,
+			"name", r.LocalName,
+			"rssi", r.RSSI,
+			"manufacturer_data", manufacturerData(r),
+			"rule", rule,
+		)
+	case logUnmatched && len(r.ManufacturerData) > 0:
+		logger.Debug("ble: advertisement did not match filter",
+			"addr", r.Address,
+			"name", r.LocalName,
+			"manufacturer_data", manufacturerData(r),
+		)
+	}
+	if summary != nil {
+		logger.Debug("ble: advertisement summary", summary...)
+	}
+}
+
+// summarizeLocked returns the summary attributes of the period ending at now
+// and starts the next one. Throttled devices not seen for DeviceInterval are
+// forgotten, so addresses that come and go do not accumulate.
+func (a *advertLog) summarizeLocked(now time.Time) []any {
+	elapsed := now.Sub(a.periodStart)
+	rate := float64(a.adverts)
+	if elapsed > 0 {
+		rate = float64(a.adverts) / elapsed.Seconds()
+	}
+	summary := []any{
+		"period", elapsed.Round(time.Second),
+		"devices", len(a.devices),
+		"adverts_per_sec", math.Round(rate*10) / 10,
+		"matches", a.matches,
+		"suppressed", a.suppressed,
+	}
+	a.periodStart = now
+	clear(a.devices)
+	a.adverts, a.matches, a.suppressed = 0, 0, 0
+	for addr, last := range a.lastLogged {
+		if now.Sub(last) >= a.opts.DeviceInterval {
+			delete(a.lastLogged, addr)
+		}
+	}
+	return summary
+}
+
+// manufacturerData formats the manufacturer data elements of r for logging.
+func manufacturerData(r ScanResult) []string {
+	out := make([]string, 0, len(r.ManufacturerData))
+	for _, md := range r.ManufacturerData {
+		out = append(out, fmt.Sprintf("0x%04X:% X", md.CompanyID, md.Data))
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package ble
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAdvertLog(opts DebugLogOptions, level slog.Level) (*advertLog, *bytes.Buffer) {
+	var buf bytes.Buffer
+	a := newAdvertLog(opts)
+	a.logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(_ []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+	return a, &buf
+}
+
+func logLines(buf *bytes.Buffer) []string {
+	s := strings.TrimSpace(buf.String())
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+func TestAdvertLog_ThrottlesPerDevice(t *testing.T) {
+	a, buf := newTestAdvertLog(DebugLogOptions{DeviceInterval: time.Minute}, slog.LevelDebug)
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	other := sensorAdvert("AA:00:00:00:00:01", 0x004C, []byte{0x02, 0x15})
+	noisy := sensorAdvert("AA:00:00:00:00:02", 0x004C, []byte{0x02, 0x15})
+	for i := range 10 {
+		a.observe(noisy, Match{}, false, start.Add(time.Duration(i)*time.Second))
+	}
+	a.observe(other, Match{}, false, start.Add(5*time.Second))
+	a.observe(noisy, Match{}, false, start.Add(time.Minute))
+
+	lines := logLines(buf)
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines; want 3 (first, other device, after the interval):\n%s", len(lines), buf)
+	}
+	for i, addr := range []string{"AA:00:00:00:00:02", "AA:00:00:00:00:01", "AA:00:00:00:00:02"} {
+		if !strings.Contains(lines[i], "did not match filter") || !strings.Contains(lines[i], "addr="+addr) {
+			t.Errorf("line %d = %q; want the unmatched advertisement of %s", i, lines[i], addr)
+		}
+	}
+}
+
+func TestAdvertLog_Summary(t *testing.T) {
+	a, buf := newTestAdvertLog(DebugLogOptions{DeviceInterval: time.Hour, SummaryInterval: 10 * time.Second}, slog.LevelDebug)
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sensor := sensorAdvert("AA:00:00:00:00:01", 0xFFFF, []byte{0x01, 0xD0})
+	noisy := sensorAdvert("AA:00:00:00:00:02", 0x004C, []byte{0x02, 0x15})
+	for i := range 10 {
+		now := start.Add(time.Duration(i) * time.Second)
+		a.observe(noisy, Match{}, false, now)
+		a.observe(noisy, Match{}, false, now)
+		if i%2 == 0 {
+			a.observe(sensor, Match{Rule: "default"}, true, now)
+		}
+	}
+	a.observe(noisy, Match{}, false, start.Add(10*time.Second))
+
+	lines := logLines(buf)
+	summary := lines[len(lines)-1]
+	for _, want := range []string{"advertisement summary", "period=10s", "devices=2", "adverts_per_sec=2.6", "matches=5", "suppressed=20"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary = %q; want %s", summary, want)
+		}
+	}
+	if len(a.devices) != 0 || a.adverts != 0 || !a.periodStart.Equal(start.Add(10*time.Second)) {
+		t.Errorf("period not reset: devices=%d adverts=%d start=%v", len(a.devices), a.adverts, a.periodStart)
+	}
+}
+
+func TestAdvertLog_TraceLogsEveryAdvertisementAtInfo(t *testing.T) {
+	a, buf := newTestAdvertLog(DebugLogOptions{DeviceInterval: time.Hour, SummaryInterval: time.Hour}, slog.LevelInfo)
+	a.trace("aa:00:00:00:00:01", true)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	traced := sensorAdvert("AA:00:00:00:00:01", 0xFFFF, []byte{0x01, 0xD0})
+	a.observe(traced, Match{Rule: "default"}, true, now)
+	a.observe(traced, Match{}, false, now)
+	a.observe(sensorAdvert("AA:00:00:00:00:02", 0x004C, nil), Match{}, false, now)
+
+	lines := logLines(buf)
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines; want the 2 traced advertisements:\n%s", len(lines), buf)
+	}
+	if !strings.Contains(lines[0], "level=INFO") || !strings.Contains(lines[0], "rule=default") {
+		t.Errorf("line 0 = %q; want the matched advertisement at info level", lines[0])
+	}
+	if got := a.tracedDevices(); len(got) != 1 || got[0] != "AA:00:00:00:00:01" {
+		t.Errorf("tracedDevices() = %q; want [AA:00:00:00:00:01]", got)
+	}
+	a.trace("AA:00:00:00:00:01", false)
+	if got := a.tracedDevices(); len(got) != 0 {
+		t.Errorf("tracedDevices() after untrace = %q; want none", got)
+	}
+}
@@ -1,10 +1,12 @@
 package ble
 
 import (
-	"cloudpico-gateway/internal/mqtt"
+	"cloudpico-gateway/internal/stationmap"
 	"cloudpico-gateway/internal/utils"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,38 +15,117 @@ import (
 
 const bleDedupMaxIDsPerDevice = 500
 
+// TelemetryPublisher sends telemetry upstream. Implemented by *mqtt.Client and *outbox.Outbox.
+type TelemetryPublisher interface {
+	PublishTelemetry(t cloudpico_shared.Telemetry) error
+}
+
 // BLESensorHandler processes BLE sensor readings with deduplication and MQTT publishing.
 type BLESensorHandler struct {
-	mqttClient *mqtt.Client
-	dedupMu    sync.Mutex
-	seen       map[string]map[uint32]struct{}
+	mqttClient     TelemetryPublisher
+	gatewayVersion string
+	stations       stationmap.Map
+	dedupMu        sync.Mutex
+	seen           map[string]map[uint32]struct{}
+	frames         *frameAssembler          // guarded by dedupMu
+	status         map[string]*SensorStatus // latest status per device, guarded by dedupMu
+	stationIDs     map[string]struct{}      // stations published for, guarded by dedupMu
+	devices        map[string]DeviceState   // per device, guarded by dedupMu
+	dedup          DedupStats               // guarded by dedupMu
+}
+
+// DeviceState is the latest reading activity of one sensor.
+type DeviceState struct {
+	// DeviceID is the 8-digit hex ID the sensor advertises.
+	DeviceID  string
+	StationID string
+	// LastSeen is when the last reading from the device arrived, duplicates
+	// included.
+	LastSeen time.Time
 }
 
-// NewBLESensorHandler creates a new BLE sensor handler.
-func NewBLESensorHandler(mqttClient *mqtt.Client) *BLESensorHandler {
+// DedupStats counts the readings the handler received since it started.
+type DedupStats struct {
+	Published  int // first receipts, passed on for publishing
+	Duplicates int // repeated advertisements of a reading already published
+}
+
+// NewBLESensorHandler creates a new BLE sensor handler. gatewayVersion is
+// reported in the metadata of every published reading; stations assigns
+// device IDs to stations ahead of the matching rule's station.
+func NewBLESensorHandler(mqttClient TelemetryPublisher, gatewayVersion string, stations stationmap.Map) *BLESensorHandler {
 	return &BLESensorHandler{
-		mqttClient: mqttClient,
-		seen:       make(map[string]map[uint32]struct{}),
+		mqttClient:     mqttClient,
+		gatewayVersion: gatewayVersion,
+		stations:       stations,
+		seen:           make(map[string]map[uint32]struct{}),
+		frames:         newFrameAssembler(),
+		status:         make(map[string]*SensorStatus),
+		stationIDs:     make(map[string]struct{}),
+		devices:        make(map[string]DeviceState),
 	}
 }
 
-// HandleMatch processes a BLE match, deduplicates readings, and publishes telemetry.
+// Stations returns the stations readings were published for, sorted.
+func (h *BLESensorHandler) Stations() []string {
+	h.dedupMu.Lock()
+	defer h.dedupMu.Unlock()
+	ids := make([]string, 0, len(h.stationIDs))
+	for id := range h.stationIDs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Devices returns the devices readings were received from, sorted by device ID.
+func (h *BLESensorHandler) Devices() []DeviceState {
+	h.dedupMu.Lock()
+	defer h.dedupMu.Unlock()
+	out := make([]DeviceState, 0, len(h.devices))
+	for _, d := range h.devices {
+		out = append(out, d)
+	}
+	slices.SortFunc(out, func(a, b DeviceState) int { return strings.Compare(a.DeviceID, b.DeviceID) })
+	return out
+}
+
+// DedupStats returns the reading counts since the handler was created.
+func (h *BLESensorHandler) DedupStats() DedupStats {
+	h.dedupMu.Lock()
+	defer h.dedupMu.Unlock()
+	return h.dedup
+}
+
+// HandleMatch processes a BLE match by payload type: status frames are
+// recorded, multi-frame readings are reassembled, and readings are
+// deduplicated and published as telemetry.
 func (h *BLESensorHandler) HandleMatch(m Match) {
-	sr, err := ParseSensorPayload(m.Data)
-	if err != nil {
-		slog.Debug("ble: ignore non-sensor payload", "addr", m.Address, "error", err)
+	if sensorPayloadTypeOf(m.Data) == sensorPayloadStatus {
+		h.handleStatus(m)
+		return
+	}
+	sr, ok := h.parse(m)
+	if !ok {
 		return
 	}
 
 	h.dedupMu.Lock()
 	deviceKey := fmt.Sprintf("%08X", sr.DeviceID)
+	status := h.status[deviceKey]
 	if h.seen[deviceKey] == nil {
 		h.seen[deviceKey] = make(map[uint32]struct{})
 	}
 	if _, ok := h.seen[deviceKey][sr.ReadingID]; ok {
+		h.dedup.Duplicates++
+		if d, ok := h.devices[deviceKey]; ok {
+			d.LastSeen = m.SeenAt
+			h.devices[deviceKey] = d
+		}
 		h.dedupMu.Unlock()
 		return
 	}
+	h.dedup.Published++
 	h.seen[deviceKey][sr.ReadingID] = struct{}{}
 	if len(h.seen[deviceKey]) > bleDedupMaxIDsPerDevice {
 		h.seen[deviceKey] = make(map[uint32]struct{})
@@ -52,21 +133,54 @@ func (h *BLESensorHandler) HandleMatch(m Match) {
 	}
 	h.dedupMu.Unlock()
 
-	// Use device ID from payload as station ID (format: pico-{device_id})
-	stationID := fmt.Sprintf("pico-%08X", sr.DeviceID)
+	// Use the station map, then the rule's station assignment, else the device ID
+	// from the payload (format: pico-{device_id})
+	stationID := h.stations.Resolve(stationmap.KindBLE, deviceKey, m.StationID)
+	if stationID == "" {
+		stationID = fmt.Sprintf("pico-%08X", sr.DeviceID)
+	}
 	temp := sr.Temperature
 	hum := sr.Humidity
 	press := sr.Pressure
 	seq := int(sr.ReadingID)
+	rssi := int(m.RSSI)
+	firmware := sr.FirmwareVersion
+	var battery *float64
+	var interval *int
+	if status != nil {
+		if firmware == "" {
+			firmware = status.FirmwareVersion
+		}
+		if status.BatteryMillivolts > 0 {
+			v := float64(status.BatteryMillivolts) / 1000
+			battery = &v
+		}
+		if status.IntervalSeconds > 0 {
+			v := int(status.IntervalSeconds)
+			interval = &v
+		}
+	}
 	telemetry := cloudpico_shared.Telemetry{
 		StationID:   stationID,
 		Timestamp:   time.Now(),
 		Temperature: &temp,
 		Humidity:    &hum,
 		Pressure:    &press,
+		Battery:     battery,
 		Sequence:    &seq,
+		RSSI:        &rssi,
+		Interval:    interval,
+		Metadata: &cloudpico_shared.Metadata{
+			GatewayVersion:  h.gatewayVersion,
+			FirmwareVersion: firmware,
+		},
 	}
 
+	h.dedupMu.Lock()
+	h.stationIDs[stationID] = struct{}{}
+	h.devices[deviceKey] = DeviceState{DeviceID: deviceKey, StationID: stationID, LastSeen: m.SeenAt}
+	h.dedupMu.Unlock()
+
 	if err := h.mqttClient.PublishTelemetry(telemetry); err != nil {
 		slog.Warn("ble: failed to publish telemetry", "addr", m.Address, "reading_id", sr.ReadingID, "error", err)
 		return
@@ -76,8 +190,75 @@ func (h *BLESensorHandler) HandleMatch(m Match) {
 		"device_id", sr.DeviceID,
 		"station_id", stationID,
 		"reading_id", sr.ReadingID,
+		"firmware", firmware,
 		"rssi", m.RSSI,
 		"T", sr.Temperature, "P", sr.Pressure, "H", sr.Humidity,
 		"data", utils.BytesToHex(m.Data),
 	)
 }
+
+// parse returns the reading in m, or ok=false when m is not a sensor payload
+// or is a frame of a message that is not complete yet.
+func (h *BLESensorHandler) parse(m Match) (*SensorReading, bool) {
+	if sensorPayloadTypeOf(m.Data) != sensorPayloadFrame {
+		sr, err := ParseSensorPayload(m.Data)
+		if err != nil {
+			slog.Debug("ble: ignore non-sensor payload", "addr", m.Address, "error", err)
+			return nil, false
+		}
+		return sr, true
+	}
+
+	f, err := parseSensorFrame(m.Data)
+	if err != nil {
+		slog.Debug("ble: ignore invalid sensor frame", "addr", m.Address, "error", err)
+		return nil, false
+	}
+	h.dedupMu.Lock()
+	body, complete := h.frames.add(f, m.SeenAt)
+	h.dedupMu.Unlock()
+	if !complete {
+		return nil, false
+	}
+	sr, err := parseSensorBody(f.DeviceID, f.ReadingID, body)
+	if err != nil {
+		slog.Warn("ble: invalid multi-frame sensor message", "addr", m.Address, "device_id", f.DeviceID, "reading_id", f.ReadingID, "error", err)
+		return nil, false
+	}
+	return sr, true
+}
+
+// handleStatus records the status frame in m. The battery voltage, firmware
+// version and reading interval it carries are attached to the device's
+// following readings.
+func (h *BLESensorHandler) handleStatus(m Match) {
+	st, err := parseSensorStatus(m.Data)
+	if err != nil {
+		slog.Debug("ble: ignore invalid sensor status", "addr", m.Address, "error", err)
+		return
+	}
+	deviceKey := fmt.Sprintf("%08X", st.DeviceID)
+	h.dedupMu.Lock()
+	prev := h.status[deviceKey]
+	h.status[deviceKey] = st
+	h.dedupMu.Unlock()
+
+	// Sensors repeat a status frame for the whole advertising duration.
+	if prev != nil && *prev == *st {
+		return
+	}
+	slog.Info("ble: sensor status",
+		"addr", m.Address,
+		"device_id", st.DeviceID,
+		"uptime", time.Duration(st.UptimeSeconds)*time.Second,
+		"battery_mv", st.BatteryMillivolts,
+		"sensor_errors", st.SensorErrors,
+		"advert_errors", st.AdvertErrors,
+		"firmware", st.FirmwareVersion,
+		"interval", time.Duration(st.IntervalSeconds)*time.Second,
+		"rssi", m.RSSI,
+	)
+	if prev != nil && st.UptimeSeconds < prev.UptimeSeconds {
+		slog.Warn("ble: sensor restarted", "device_id", st.DeviceID, "previous_uptime", time.Duration(prev.UptimeSeconds)*time.Second)
+	}
+}
@@ -0,0 +1,90 @@
+package ble
+
+import (
+	"testing"
+	"time"
+
+	"cloudpico-gateway/internal/stationmap"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+type recordingPublisher struct {
+	published []cloudpico_shared.Telemetry
+}
+
+func (p *recordingPublisher) PublishTelemetry(t cloudpico_shared.Telemetry) error {
+	p.published = append(p.published, t)
+	return nil
+}
+
+func TestBLESensorHandler_HandleMatch(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	t.Run("publishes a reading once", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		h := NewBLESensorHandler(pub, "1.0.0", stationmap.Map{})
+		h.HandleMatch(Match{Address: "AA", Data: golden, RSSI: -71, SeenAt: now})
+		h.HandleMatch(Match{Address: "AA", Data: golden, RSSI: -71, SeenAt: now})
+		if len(pub.published) != 1 {
+			t.Fatalf("published %d readings; want 1", len(pub.published))
+		}
+		if got := pub.published[0]; got.StationID != "pico-12345678" || *got.Temperature != 21.5 || got.Battery != nil || got.RSSI == nil || *got.RSSI != -71 {
+			t.Errorf("published %+v", got)
+		}
+	})
+
+	t.Run("tracks devices and duplicates", func(t *testing.T) {
+		h := NewBLESensorHandler(&recordingPublisher{}, "1.0.0", stationmap.Map{})
+		h.HandleMatch(Match{Address: "AA", Data: golden, SeenAt: now})
+		h.HandleMatch(Match{Address: "AA", Data: golden, SeenAt: now.Add(time.Second)})
+		h.HandleMatch(Match{Address: "AA", Data: golden, SeenAt: now.Add(2 * time.Second)})
+
+		if got := h.DedupStats(); got != (DedupStats{Published: 1, Duplicates: 2}) {
+			t.Errorf("DedupStats() = %+v; want 1 published, 2 duplicates", got)
+		}
+		want := []DeviceState{{DeviceID: "12345678", StationID: "pico-12345678", LastSeen: now.Add(2 * time.Second)}}
+		if got := h.Devices(); len(got) != 1 || got[0] != want[0] {
+			t.Errorf("Devices() = %+v; want %+v", got, want)
+		}
+	})
+
+	t.Run("reassembles frames", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		h := NewBLESensorHandler(pub, "1.0.0", stationmap.Map{})
+		h.HandleMatch(Match{Address: "AA", Data: goldenFrames[0], SeenAt: now})
+		if len(pub.published) != 0 {
+			t.Fatalf("published %d readings after first frame; want 0", len(pub.published))
+		}
+		h.HandleMatch(Match{Address: "AA", Data: goldenFrames[1], SeenAt: now})
+		if len(pub.published) != 1 {
+			t.Fatalf("published %d readings; want 1", len(pub.published))
+		}
+		if got := pub.published[0].Metadata.FirmwareVersion; got != "1.4.2" {
+			t.Errorf("FirmwareVersion = %q; want %q", got, "1.4.2")
+		}
+	})
+
+	t.Run("attaches status to readings", func(t *testing.T) {
+		pub := &recordingPublisher{}
+		h := NewBLESensorHandler(pub, "1.0.0", stationmap.Map{})
+		h.HandleMatch(Match{Address: "AA", Data: goldenStatus, SeenAt: now})
+		if len(pub.published) != 0 {
+			t.Fatalf("published %d readings for a status frame; want 0", len(pub.published))
+		}
+		h.HandleMatch(Match{Address: "AA", Data: golden, SeenAt: now})
+		if len(pub.published) != 1 {
+			t.Fatalf("published %d readings; want 1", len(pub.published))
+		}
+		got := pub.published[0]
+		if got.Battery == nil || *got.Battery != 3.3 {
+			t.Errorf("Battery = %v; want 3.3", got.Battery)
+		}
+		if got.Metadata.FirmwareVersion != "1.4.2" {
+			t.Errorf("FirmwareVersion = %q; want %q", got.Metadata.FirmwareVersion, "1.4.2")
+		}
+		if got.Interval == nil || *got.Interval != 120 {
+			t.Errorf("Interval = %v; want 120", got.Interval)
+		}
+	})
+}
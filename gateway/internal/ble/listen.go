@@ -4,9 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
-
-	"tinygo.org/x/bluetooth"
 )
 
 // Match is a single observation of your Pico beacon.
@@ -17,6 +16,11 @@ type Match struct {
 	CompanyID uint16
 	Data      []byte
 	SeenAt    time.Time
+
+	// Rule, Handler and StationID come from the rule that matched.
+	Rule      string
+	Handler   string
+	StationID string
 }
 
 type Filter struct {
@@ -27,99 +31,249 @@ type Filter struct {
 
 type Options struct {
 	Adapter string // "hci0" by default
-	Filter  Filter
+	// Rules are evaluated in order with first-match semantics. When empty,
+	// Filter is used as a single rule routed to the sensor handler.
+	Rules  []Rule
+	Filter Filter
+	// Watchdog restarts scanning when advertisements stop; see WatchdogOptions.
+	Watchdog WatchdogOptions
+	// DebugLog throttles the debug logging of advertisements.
+	DebugLog DebugLogOptions
+	// ReconnectBackoff is the first wait before re-creating the adapter after
+	// BlueZ went away (see IsBlueZDisconnect); it doubles up to
+	// maxReconnectBackoff while BlueZ stays away. Defaults to one second.
+	ReconnectBackoff time.Duration
 }
 
-// Listener wraps BlueZ scanning with context cancellation.
+// maxReconnectBackoff caps the wait between adapter re-creations.
+const maxReconnectBackoff = 30 * time.Second
+
+// Listener wraps BLE scanning with context cancellation.
 type Listener struct {
-	adapter *bluetooth.Adapter
-	opts    Options
+	source   ScanSource
+	opts     Options
+	watchdog *watchdog
+	adverts  *advertLog
+	// disconnects counts the times BlueZ went away under a scan.
+	disconnects atomic.Int64
 }
 
+// NewListener returns a Listener scanning on the BlueZ adapter named in opts.
 func NewListener(opts Options) *Listener {
 	if opts.Adapter == "" {
 		opts.Adapter = "hci0"
 	}
+	return NewListenerWithSource(NewBlueZSource(opts.Adapter), opts)
+}
 
+// NewListenerWithSource returns a Listener reading advertisements from source.
+func NewListenerWithSource(source ScanSource, opts Options) *Listener {
+	if opts.Adapter == "" {
+		opts.Adapter = "hci0"
+	}
+	if len(opts.Rules) == 0 {
+		opts.Rules = []Rule{{Name: "default", Filter: opts.Filter, Handler: HandlerSensor}}
+	}
+	if opts.ReconnectBackoff <= 0 {
+		opts.ReconnectBackoff = time.Second
+	}
 	return &Listener{
-		adapter: bluetooth.NewAdapter(opts.Adapter),
-		opts:    opts,
+		source:   source,
+		opts:     opts,
+		watchdog: newWatchdog(opts.Watchdog),
+		adverts:  newAdvertLog(opts.DebugLog),
 	}
 }
 
+// AdapterState returns the advertisement flow seen so far.
+func (l *Listener) AdapterState() AdapterState {
+	st := l.watchdog.state()
+	st.BlueZDisconnects = int(l.disconnects.Load())
+	return st
+}
+
+// TraceDevice turns logging every advertisement of the device with the
+// Bluetooth address addr, e.g. "AA:BB:CC:DD:EE:FF", on or off. Traced
+// advertisements are logged at info level whatever the log level and the
+// DebugLog throttling.
+func (l *Listener) TraceDevice(addr string, on bool) {
+	l.adverts.trace(addr, on)
+}
+
+// TracedDevices returns the addresses of the traced devices, sorted.
+func (l *Listener) TracedDevices() []string {
+	return l.adverts.tracedDevices()
+}
+
 func (l *Listener) Run(ctx context.Context, onMatch func(Match)) error {
 	slog.Info("ble: enabling adapter", "adapter", l.opts.Adapter)
-	if err := l.adapter.Enable(); err != nil {
+	if err := l.source.Enable(); err != nil {
 		return fmt.Errorf("ble enable (%s): %w", l.opts.Adapter, err)
 	}
 	slog.Info("ble: adapter enabled", "adapter", l.opts.Adapter)
 
-	go func() {
-		<-ctx.Done()
-		_ = l.adapter.StopScan()
-	}()
-
-	slog.Info("ble: scanning started",
-		"filter_name", l.opts.Filter.LocalName,
-		"filter_company", fmt.Sprintf("0x%04X", l.opts.Filter.CompanyID),
-		"filter_prefix", fmt.Sprintf("% X", l.opts.Filter.ManufacturerDataPref),
-	)
-
-	// adapter.Scan blocks until StopScan() or error.
-	err := l.adapter.Scan(func(a *bluetooth.Adapter, r bluetooth.ScanResult) {
-		obs := Match{
-			Address:   r.Address.String(),
-			RSSI:      r.RSSI,
-			LocalName: r.LocalName(),
-			SeenAt:    time.Now(),
-		}
+	for i, rule := range l.opts.Rules {
+		slog.Info("ble: filter rule",
+			"index", i,
+			"rule", rule.Name,
+			"handler", rule.Handler,
+			"station_id", rule.StationID,
+			"filter_name", rule.Filter.LocalName,
+			"filter_company", fmt.Sprintf("0x%04X", rule.Filter.CompanyID),
+			"filter_prefix", fmt.Sprintf("% X", rule.Filter.ManufacturerDataPref),
+		)
+	}
 
-		// Collect manufacturer data for debug logging
-		var allMfgData []struct {
-			CompanyID uint16
-			Data      []byte
-		}
-		for _, md := range r.ManufacturerData() {
-			allMfgData = append(allMfgData, struct {
-				CompanyID uint16
-				Data      []byte
-			}{md.CompanyID, append([]byte(nil), md.Data...)})
-		}
+	backoff := l.opts.ReconnectBackoff
+	for {
+		slog.Info("ble: scanning started", "rules", len(l.opts.Rules), "watchdog_timeout", l.opts.Watchdog.Timeout)
+		lastSeen := l.watchdog.state().LastSeen
+		stale, err := l.scan(ctx, onMatch)
 
-		if l.opts.Filter.LocalName != "" && obs.LocalName != l.opts.Filter.LocalName {
-			return
+		// If ctx canceled, treat as clean shutdown.
+		if ctx.Err() != nil {
+			slog.Info("ble: scanning stopped (context canceled)")
+			return nil
 		}
-
-		for _, md := range r.ManufacturerData() {
-			if l.opts.Filter.CompanyID != 0 && md.CompanyID != l.opts.Filter.CompanyID {
-				continue
+		if IsBlueZDisconnect(err) {
+			// Back off from the start only once a scan got advertisements, so
+			// an adapter that fails every scan is not re-created in a loop.
+			if !l.watchdog.state().LastSeen.Equal(lastSeen) {
+				backoff = l.opts.ReconnectBackoff
 			}
-			if !hasPrefix(md.Data, l.opts.Filter.ManufacturerDataPref) {
-				continue
+			if err := l.reconnect(ctx, err, &backoff); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				slog.Info("ble: scanning stopped (context canceled)")
+				return nil
+			}
+			continue
+		}
+		if !stale {
+			if err != nil {
+				return fmt.Errorf("ble scan: %w", err)
 			}
+			slog.Info("ble: scanning stopped")
+			return nil
+		}
 
-			obs.CompanyID = md.CompanyID
-			obs.Data = append([]byte(nil), md.Data...)
+		l.watchdog.restarted()
+		if l.opts.Watchdog.ResetAdapter {
+			l.resetAdapter()
+		}
+		if ctx.Err() != nil {
+			slog.Info("ble: scanning stopped (context canceled)")
+			return nil
+		}
+	}
+}
 
-			if onMatch != nil {
-				onMatch(obs)
-			}
+// scan runs one scan session until it is stopped by ctx or, with the
+// watchdog enabled, by a silence; stale reports the latter.
+func (l *Listener) scan(ctx context.Context, onMatch func(Match)) (stale bool, err error) {
+	l.watchdog.start(time.Now())
+	done := make(chan struct{})
+	staleCh := l.supervise(ctx, done)
+	// source.Scan blocks until StopScan() or error.
+	err = l.source.Scan(func(r ScanResult) {
+		now := time.Now()
+		l.watchdog.seen(now)
+		obs, ok := l.match(r, now)
+		l.adverts.observe(r, obs, ok, now)
+		if !ok {
 			return
 		}
+		if onMatch != nil {
+			onMatch(obs)
+		}
 	})
+	close(done)
+	return <-staleCh, err
+}
 
-	// If ctx canceled, treat as clean shutdown.
-	if ctx.Err() != nil {
-		slog.Info("ble: scanning stopped (context canceled)")
+// reconnect re-creates the adapter after BlueZ went away with cause, waiting
+// *backoff before each attempt and doubling it, until an attempt succeeds or
+// ctx is done. Scan sources that cannot reconnect fail with cause.
+func (l *Listener) reconnect(ctx context.Context, cause error, backoff *time.Duration) error {
+	reconnector, ok := l.source.(AdapterReconnector)
+	if !ok {
+		return fmt.Errorf("ble scan: %w", cause)
+	}
+	n := l.disconnects.Add(1)
+	slog.Warn("ble: bluez disconnected; re-creating adapter",
+		"adapter", l.opts.Adapter, "disconnects", n, "retry_in", *backoff, "error", cause)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*backoff):
+		}
+		*backoff = min(*backoff*2, maxReconnectBackoff)
+		if err := reconnector.Reconnect(); err != nil {
+			slog.Warn("ble: adapter re-creation failed", "adapter", l.opts.Adapter, "retry_in", *backoff, "error", err)
+			continue
+		}
+		slog.Info("ble: bluez reconnected; resuming scan", "adapter", l.opts.Adapter)
 		return nil
 	}
+}
+
+// resetAdapter power-cycles the adapter and enables it again. Failures are
+// logged; scanning restarts either way.
+func (l *Listener) resetAdapter() {
+	resetter, ok := l.source.(AdapterResetter)
+	if !ok {
+		slog.Warn("ble: adapter reset not supported by scan source", "adapter", l.opts.Adapter)
+		return
+	}
+	slog.Warn("ble: resetting adapter", "adapter", l.opts.Adapter)
+	if err := resetter.ResetAdapter(); err != nil {
+		slog.Error("ble: adapter reset failed", "adapter", l.opts.Adapter, "error", err)
+		return
+	}
+	if err := l.source.Enable(); err != nil {
+		slog.Error("ble: enable after adapter reset failed", "adapter", l.opts.Adapter, "error", err)
+	}
+}
 
-	if err != nil {
-		return fmt.Errorf("ble scan: %w", err)
+// match evaluates the rules in order against r and returns the first matching
+// manufacturer data element of the first matching rule as a Match.
+func (l *Listener) match(r ScanResult, seenAt time.Time) (Match, bool) {
+	for _, rule := range l.opts.Rules {
+		md, ok := rule.Filter.match(r)
+		if !ok {
+			continue
+		}
+		return Match{
+			Address:   r.Address,
+			RSSI:      r.RSSI,
+			LocalName: r.LocalName,
+			CompanyID: md.CompanyID,
+			Data:      append([]byte(nil), md.Data...),
+			SeenAt:    seenAt,
+			Rule:      rule.Name,
+			Handler:   rule.Handler,
+			StationID: rule.StationID,
+		}, true
 	}
+	return Match{}, false
+}
 
-	slog.Info("ble: scanning stopped")
-	return nil
+// match returns the first manufacturer data element of r accepted by f.
+func (f Filter) match(r ScanResult) (ManufacturerData, bool) {
+	if f.LocalName != "" && r.LocalName != f.LocalName {
+		return ManufacturerData{}, false
+	}
+	for _, md := range r.ManufacturerData {
+		if f.CompanyID != 0 && md.CompanyID != f.CompanyID {
+			continue
+		}
+		if hasPrefix(md.Data, f.ManufacturerDataPref) {
+			return md, true
+		}
+	}
+	return ManufacturerData{}, false
 }
 
 func hasPrefix(b, pref []byte) bool {
@@ -0,0 +1,313 @@
+package ble
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeSource is a ScanSource that replays a fixed list of results and then
+// blocks until StopScan is called.
+type fakeSource struct {
+	results   []ScanResult
+	enableErr error
+	scanErr   error
+
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newFakeSource(results ...ScanResult) *fakeSource {
+	return &fakeSource{results: results, stopped: make(chan struct{})}
+}
+
+func (f *fakeSource) Enable() error { return f.enableErr }
+
+func (f *fakeSource) Scan(onResult func(ScanResult)) error {
+	if f.scanErr != nil {
+		return f.scanErr
+	}
+	for _, r := range f.results {
+		onResult(r)
+	}
+	<-f.stopped
+	return nil
+}
+
+func (f *fakeSource) StopScan() error {
+	f.once.Do(func() { close(f.stopped) })
+	return nil
+}
+
+func sensorAdvert(addr string, companyID uint16, data []byte) ScanResult {
+	return ScanResult{
+		Address:          addr,
+		RSSI:             -60,
+		LocalName:        "pico2w-sensor",
+		ManufacturerData: []ManufacturerData{{CompanyID: companyID, Data: data}},
+	}
+}
+
+func runListener(t *testing.T, l *Listener) []Match {
+	t.Helper()
+	var (
+		mu      sync.Mutex
+		matches []Match
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := l.Run(ctx, func(m Match) {
+		mu.Lock()
+		matches = append(matches, m)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("Run() = %v; want nil", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return matches
+}
+
+func TestListener_Run(t *testing.T) {
+	filter := Filter{CompanyID: 0xFFFF, ManufacturerDataPref: []byte{0x01, 0xD0}}
+
+	t.Run("dispatches matching advertisements", func(t *testing.T) {
+		src := newFakeSource(
+			sensorAdvert("AA:BB", 0xFFFF, []byte{0x01, 0xD0, 0x02}),
+			sensorAdvert("CC:DD", 0x004C, []byte{0x01, 0xD0, 0x03}),
+			sensorAdvert("EE:FF", 0xFFFF, []byte{0x02, 0x00}),
+		)
+		l := NewListenerWithSource(src, Options{Filter: filter})
+
+		matches := runListener(t, l)
+
+		if len(matches) != 1 {
+			t.Fatalf("got %d matches; want 1", len(matches))
+		}
+		m := matches[0]
+		if m.Address != "AA:BB" || m.CompanyID != 0xFFFF || m.RSSI != -60 {
+			t.Errorf("match = %+v; want addr AA:BB company 0xFFFF rssi -60", m)
+		}
+		if len(m.Data) != 3 || m.Data[2] != 0x02 {
+			t.Errorf("match data = % X; want 01 D0 02", m.Data)
+		}
+		if m.SeenAt.IsZero() {
+			t.Error("match SeenAt is zero")
+		}
+	})
+
+	t.Run("filters by local name", func(t *testing.T) {
+		other := sensorAdvert("AA:BB", 0xFFFF, []byte{0x01, 0xD0})
+		other.LocalName = "other"
+		src := newFakeSource(other, sensorAdvert("CC:DD", 0xFFFF, []byte{0x01, 0xD0}))
+		l := NewListenerWithSource(src, Options{Filter: Filter{LocalName: "pico2w-sensor", CompanyID: 0xFFFF}})
+
+		matches := runListener(t, l)
+
+		if len(matches) != 1 || matches[0].Address != "CC:DD" {
+			t.Errorf("matches = %+v; want single match from CC:DD", matches)
+		}
+	})
+
+	t.Run("zero company ID matches any company", func(t *testing.T) {
+		src := newFakeSource(sensorAdvert("AA:BB", 0x004C, []byte{0x01, 0xD0}))
+		l := NewListenerWithSource(src, Options{Filter: Filter{ManufacturerDataPref: []byte{0x01, 0xD0}}})
+
+		matches := runListener(t, l)
+
+		if len(matches) != 1 {
+			t.Errorf("got %d matches; want 1", len(matches))
+		}
+	})
+
+	t.Run("uses first matching manufacturer element", func(t *testing.T) {
+		r := ScanResult{
+			Address: "AA:BB",
+			ManufacturerData: []ManufacturerData{
+				{CompanyID: 0x004C, Data: []byte{0x01, 0xD0, 0x01}},
+				{CompanyID: 0xFFFF, Data: []byte{0x01, 0xD0, 0x02}},
+				{CompanyID: 0xFFFF, Data: []byte{0x01, 0xD0, 0x03}},
+			},
+		}
+		l := NewListenerWithSource(newFakeSource(r), Options{Filter: filter})
+
+		matches := runListener(t, l)
+
+		if len(matches) != 1 {
+			t.Fatalf("got %d matches; want 1", len(matches))
+		}
+		if matches[0].Data[2] != 0x02 {
+			t.Errorf("match data = % X; want second element", matches[0].Data)
+		}
+	})
+
+	t.Run("match data is a copy", func(t *testing.T) {
+		data := []byte{0x01, 0xD0, 0x05}
+		src := newFakeSource(sensorAdvert("AA:BB", 0xFFFF, data))
+		l := NewListenerWithSource(src, Options{Filter: filter})
+
+		matches := runListener(t, l)
+		data[2] = 0xFF
+
+		if len(matches) != 1 || matches[0].Data[2] != 0x05 {
+			t.Errorf("match data changed with source buffer: %+v", matches)
+		}
+	})
+
+	t.Run("nil onMatch is allowed", func(t *testing.T) {
+		src := newFakeSource(sensorAdvert("AA:BB", 0xFFFF, []byte{0x01, 0xD0}))
+		l := NewListenerWithSource(src, Options{Filter: filter})
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		if err := l.Run(ctx, nil); err != nil {
+			t.Errorf("Run(nil) = %v; want nil", err)
+		}
+	})
+
+	t.Run("returns enable error", func(t *testing.T) {
+		src := newFakeSource()
+		src.enableErr = errors.New("no adapter")
+		l := NewListenerWithSource(src, Options{Filter: filter})
+
+		err := l.Run(context.Background(), nil)
+
+		if err == nil || !errors.Is(err, src.enableErr) {
+			t.Errorf("Run() = %v; want wrapped enable error", err)
+		}
+	})
+
+	t.Run("returns scan error", func(t *testing.T) {
+		src := newFakeSource()
+		src.scanErr = errors.New("dbus gone")
+		l := NewListenerWithSource(src, Options{Filter: filter})
+
+		err := l.Run(context.Background(), nil)
+
+		if err == nil || !errors.Is(err, src.scanErr) {
+			t.Errorf("Run() = %v; want wrapped scan error", err)
+		}
+	})
+
+	t.Run("defaults adapter name", func(t *testing.T) {
+		l := NewListenerWithSource(newFakeSource(), Options{})
+		if l.opts.Adapter != "hci0" {
+			t.Errorf("Adapter = %q; want hci0", l.opts.Adapter)
+		}
+	})
+}
+
+// restartingSource is a ScanSource whose scans fail as if bluetoothd had
+// exited until Reconnect succeeds; later scans replay results like fakeSource.
+type restartingSource struct {
+	*fakeSource
+	mu           sync.Mutex
+	reconnectErr []error // returned by successive Reconnect calls, then nil
+	reconnects   int
+	connected    bool
+}
+
+func (s *restartingSource) Scan(onResult func(ScanResult)) error {
+	s.mu.Lock()
+	connected := s.connected
+	s.mu.Unlock()
+	if !connected {
+		return dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown", Body: []any{"The name org.bluez was not provided"}}
+	}
+	return s.fakeSource.Scan(onResult)
+}
+
+func (s *restartingSource) Reconnect() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconnects++
+	if s.reconnects <= len(s.reconnectErr) {
+		return s.reconnectErr[s.reconnects-1]
+	}
+	s.connected = true
+	return nil
+}
+
+func TestListener_BlueZRestart(t *testing.T) {
+	filter := Filter{CompanyID: 0xFFFF}
+
+	t.Run("re-creates the adapter and resumes scanning", func(t *testing.T) {
+		src := &restartingSource{
+			fakeSource:   newFakeSource(sensorAdvert("AA:BB", 0xFFFF, []byte{0x01})),
+			reconnectErr: []error{errors.New("bluez not up yet")},
+		}
+		l := NewListenerWithSource(src, Options{Filter: filter, ReconnectBackoff: time.Millisecond})
+
+		matches := runListener(t, l)
+
+		if len(matches) != 1 {
+			t.Errorf("got %d matches; want 1 after reconnecting", len(matches))
+		}
+		if src.reconnects != 2 {
+			t.Errorf("reconnects = %d; want a retry after the failed attempt", src.reconnects)
+		}
+		if st := l.AdapterState(); st.BlueZDisconnects != 1 {
+			t.Errorf("BlueZDisconnects = %d; want 1", st.BlueZDisconnects)
+		}
+	})
+
+	t.Run("fails without reconnect support", func(t *testing.T) {
+		src := newFakeSource()
+		src.scanErr = dbus.ErrClosed
+		l := NewListenerWithSource(src, Options{Filter: filter})
+
+		if err := l.Run(context.Background(), nil); !errors.Is(err, dbus.ErrClosed) {
+			t.Errorf("Run() = %v; want the disconnect error", err)
+		}
+	})
+}
+
+func TestIsBlueZDisconnect(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection closed", fmt.Errorf("scan: %w", dbus.ErrClosed), true},
+		{"service unknown", dbus.Error{Name: "org.freedesktop.DBus.Error.ServiceUnknown"}, true},
+		{"no reply", dbus.Error{Name: "org.freedesktop.DBus.Error.NoReply"}, true},
+		{"access denied", dbus.Error{Name: "org.freedesktop.DBus.Error.AccessDenied"}, false},
+		{"scan stopped", errors.New("bluetooth: scan was stopped unexpectedly"), true},
+		{"other", errors.New("bluetooth: a scan is already in progress"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBlueZDisconnect(tt.err); got != tt.want {
+				t.Errorf("IsBlueZDisconnect(%v) = %v; want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_hasPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		b    []byte
+		pref []byte
+		want bool
+	}{
+		{"empty prefix", []byte{0x01}, nil, true},
+		{"match", []byte{0x01, 0xD0, 0x02}, []byte{0x01, 0xD0}, true},
+		{"mismatch", []byte{0x01, 0xD1}, []byte{0x01, 0xD0}, false},
+		{"too short", []byte{0x01}, []byte{0x01, 0xD0}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasPrefix(tt.b, tt.pref); got != tt.want {
+				t.Errorf("hasPrefix(% X, % X) = %v; want %v", tt.b, tt.pref, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,10 +8,12 @@ import (
 
 // Sensor payload format (little-endian): magic 0x01 0xD0, device_id uint32,
 // reading_id uint32, temperature float32, pressure float32, humidity float32 (22 bytes total).
+// Newer firmware appends major, minor, patch uint8 (25 bytes); 0.0.0 means unknown.
 const (
 	sensorPayloadMagic0 = 0x01
 	sensorPayloadMagic1 = 0xD0
 	sensorPayloadLen    = 22
+	sensorPayloadExtLen = sensorPayloadLen + 3
 )
 
 // SensorReading is a parsed BLE sensor advertisement (device_id + T/P/H + reading_id for dedup).
@@ -21,6 +23,11 @@ type SensorReading struct {
 	Temperature float64
 	Pressure    float64
 	Humidity    float64
+	// FirmwareVersion is "major.minor.patch", or empty when the sensor does not report it.
+	FirmwareVersion string
+	// IntervalSeconds is the sensor's current interval between readings, or
+	// 0 when it does not report it.
+	IntervalSeconds uint16
 }
 
 // ParseSensorPayload parses manufacturer data from a Pico sensor advertisement.
@@ -37,11 +44,178 @@ func ParseSensorPayload(data []byte) (*SensorReading, error) {
 	temp := math.Float32frombits(binary.LittleEndian.Uint32(data[10:14]))
 	press := math.Float32frombits(binary.LittleEndian.Uint32(data[14:18]))
 	hum := math.Float32frombits(binary.LittleEndian.Uint32(data[18:22]))
+	var firmware string
+	if len(data) >= sensorPayloadExtLen {
+		major, minor, patch := data[22], data[23], data[24]
+		if major != 0 || minor != 0 || patch != 0 {
+			firmware = fmt.Sprintf("%d.%d.%d", major, minor, patch)
+		}
+	}
 	return &SensorReading{
-		DeviceID:    deviceID,
-		ReadingID:   readingID,
-		Temperature: float64(temp),
-		Pressure:    float64(press),
-		Humidity:    float64(hum),
+		DeviceID:        deviceID,
+		ReadingID:       readingID,
+		Temperature:     float64(temp),
+		Pressure:        float64(press),
+		Humidity:        float64(hum),
+		FirmwareVersion: firmware,
 	}, nil
 }
+
+// Readings too large for one advertisement are split into frames (see
+// sensor/payload): magic 0x01 0xD1, device_id uint32, reading_id uint32, frame
+// index (high nibble) and count (low nibble), then a chunk of the message
+// body. The body is a sequence of tag uint8, length uint8, value fields.
+const (
+	sensorFrameMagic1    = 0xD1
+	sensorFrameHeaderLen = 11
+
+	sensorTagTemperature     = 0x01
+	sensorTagPressure        = 0x02
+	sensorTagHumidity        = 0x03
+	sensorTagFirmwareVersion = 0x04
+)
+
+// sensorFrame is one frame of a multi-frame sensor message.
+type sensorFrame struct {
+	DeviceID  uint32
+	ReadingID uint32
+	Index     int
+	Count     int
+	Data      []byte
+}
+
+// parseSensorFrame parses one frame of a multi-frame sensor message.
+func parseSensorFrame(data []byte) (sensorFrame, error) {
+	if len(data) < sensorFrameHeaderLen {
+		return sensorFrame{}, fmt.Errorf("frame too short: %d", len(data))
+	}
+	if sensorPayloadTypeOf(data) != sensorPayloadFrame {
+		return sensorFrame{}, fmt.Errorf("invalid frame magic: %02X %02X", data[0], data[1])
+	}
+	f := sensorFrame{
+		DeviceID:  binary.LittleEndian.Uint32(data[2:6]),
+		ReadingID: binary.LittleEndian.Uint32(data[6:10]),
+		Index:     int(data[10] >> 4),
+		Count:     int(data[10] & 0x0F),
+		Data:      data[sensorFrameHeaderLen:],
+	}
+	if f.Count == 0 || f.Index >= f.Count {
+		return sensorFrame{}, fmt.Errorf("invalid frame %d of %d", f.Index, f.Count)
+	}
+	return f, nil
+}
+
+// parseSensorBody parses a reassembled multi-frame message body. Unknown tags
+// are skipped; temperature, pressure and humidity are required.
+func parseSensorBody(deviceID, readingID uint32, body []byte) (*SensorReading, error) {
+	sr := &SensorReading{DeviceID: deviceID, ReadingID: readingID}
+	var seen uint8
+	for len(body) > 0 {
+		if len(body) < 2 || len(body) < 2+int(body[1]) {
+			return nil, fmt.Errorf("truncated field at tag %02X", body[0])
+		}
+		tag, value := body[0], body[2:2+int(body[1])]
+		body = body[2+len(value):]
+		switch tag {
+		case sensorTagTemperature, sensorTagPressure, sensorTagHumidity:
+			if len(value) != 4 {
+				return nil, fmt.Errorf("invalid length %d for tag %02X", len(value), tag)
+			}
+			v := float64(math.Float32frombits(binary.LittleEndian.Uint32(value)))
+			switch tag {
+			case sensorTagTemperature:
+				sr.Temperature = v
+			case sensorTagPressure:
+				sr.Pressure = v
+			case sensorTagHumidity:
+				sr.Humidity = v
+			}
+			seen |= 1 << tag
+		case sensorTagFirmwareVersion:
+			if len(value) == 3 && (value[0] != 0 || value[1] != 0 || value[2] != 0) {
+				sr.FirmwareVersion = fmt.Sprintf("%d.%d.%d", value[0], value[1], value[2])
+			}
+		}
+	}
+	if seen != 1<<sensorTagTemperature|1<<sensorTagPressure|1<<sensorTagHumidity {
+		return nil, fmt.Errorf("missing reading field")
+	}
+	return sr, nil
+}
+
+// Status frames report sensor health between telemetry adverts (see
+// sensor/payload): magic 0x01 0xD2, device_id uint32, uptime seconds uint32,
+// battery millivolts uint16 (0 means unknown), sensor read errors uint16, BLE
+// advertising errors uint16, firmware major, minor, patch uint8 (19 bytes),
+// then, from firmware with an adaptive interval, the reading interval in
+// seconds uint16.
+const (
+	sensorStatusMagic1      = 0xD2
+	sensorStatusLen         = 19
+	sensorStatusIntervalLen = sensorStatusLen + 2
+)
+
+// sensorPayloadType identifies the kind of a sensor advertisement by its magic.
+type sensorPayloadType int
+
+const (
+	sensorPayloadUnknown sensorPayloadType = iota
+	sensorPayloadReading
+	sensorPayloadFrame
+	sensorPayloadStatus
+)
+
+// sensorPayloadTypeOf returns the type of the sensor advertisement data.
+func sensorPayloadTypeOf(data []byte) sensorPayloadType {
+	if len(data) < 2 || data[0] != sensorPayloadMagic0 {
+		return sensorPayloadUnknown
+	}
+	switch data[1] {
+	case sensorPayloadMagic1:
+		return sensorPayloadReading
+	case sensorFrameMagic1:
+		return sensorPayloadFrame
+	case sensorStatusMagic1:
+		return sensorPayloadStatus
+	}
+	return sensorPayloadUnknown
+}
+
+// SensorStatus is a parsed BLE sensor status frame.
+type SensorStatus struct {
+	DeviceID      uint32
+	UptimeSeconds uint32
+	// BatteryMillivolts is 0 when the sensor does not measure its battery.
+	BatteryMillivolts uint16
+	SensorErrors      uint16
+	AdvertErrors      uint16
+	// FirmwareVersion is "major.minor.patch", or empty when the sensor does not report it.
+	FirmwareVersion string
+	// IntervalSeconds is the sensor's current interval between readings, or
+	// 0 when it does not report it.
+	IntervalSeconds uint16
+}
+
+// parseSensorStatus parses a status frame.
+func parseSensorStatus(data []byte) (*SensorStatus, error) {
+	if len(data) < sensorStatusLen {
+		return nil, fmt.Errorf("status too short: %d", len(data))
+	}
+	if sensorPayloadTypeOf(data) != sensorPayloadStatus {
+		return nil, fmt.Errorf("invalid status magic: %02X %02X", data[0], data[1])
+	}
+	st := &SensorStatus{
+		DeviceID:          binary.LittleEndian.Uint32(data[2:6]),
+		UptimeSeconds:     binary.LittleEndian.Uint32(data[6:10]),
+		BatteryMillivolts: binary.LittleEndian.Uint16(data[10:12]),
+		SensorErrors:      binary.LittleEndian.Uint16(data[12:14]),
+		AdvertErrors:      binary.LittleEndian.Uint16(data[14:16]),
+	}
+	if major, minor, patch := data[16], data[17], data[18]; major != 0 || minor != 0 || patch != 0 {
+		st.FirmwareVersion = fmt.Sprintf("%d.%d.%d", major, minor, patch)
+	}
+	if len(data) >= sensorStatusIntervalLen {
+		st.IntervalSeconds = binary.LittleEndian.Uint16(data[19:21])
+	}
+	return st, nil
+}
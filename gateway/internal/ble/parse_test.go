@@ -0,0 +1,196 @@
+package ble
+
+import (
+	"testing"
+)
+
+// golden is the payload the firmware encodes for device 0x12345678, reading 42,
+// 21.5 °C, 1013.25 hPa, 55 % (see sensor/payload tests).
+var golden = []byte{
+	0x01, 0xD0,
+	0x78, 0x56, 0x34, 0x12,
+	0x2A, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0xAC, 0x41,
+	0x00, 0x50, 0x7D, 0x44,
+	0x00, 0x00, 0x5C, 0x42,
+}
+
+func TestParseSensorPayload(t *testing.T) {
+	t.Run("parses firmware payload", func(t *testing.T) {
+		sr, err := ParseSensorPayload(golden)
+		if err != nil {
+			t.Fatalf("ParseSensorPayload() error = %v", err)
+		}
+		want := SensorReading{DeviceID: 0x12345678, ReadingID: 42, Temperature: 21.5, Pressure: 1013.25, Humidity: 55}
+		if *sr != want {
+			t.Errorf("ParseSensorPayload() = %+v; want %+v", *sr, want)
+		}
+	})
+
+	t.Run("parses firmware version trailer", func(t *testing.T) {
+		data := append(append([]byte(nil), golden...), 1, 4, 2)
+		sr, err := ParseSensorPayload(data)
+		if err != nil {
+			t.Fatalf("ParseSensorPayload() error = %v", err)
+		}
+		if sr.FirmwareVersion != "1.4.2" {
+			t.Errorf("FirmwareVersion = %q; want %q", sr.FirmwareVersion, "1.4.2")
+		}
+	})
+
+	t.Run("treats 0.0.0 as unknown", func(t *testing.T) {
+		data := append(append([]byte(nil), golden...), 0, 0, 0)
+		sr, err := ParseSensorPayload(data)
+		if err != nil {
+			t.Fatalf("ParseSensorPayload() error = %v", err)
+		}
+		if sr.FirmwareVersion != "" {
+			t.Errorf("FirmwareVersion = %q; want empty", sr.FirmwareVersion)
+		}
+	})
+
+	t.Run("rejects short payload", func(t *testing.T) {
+		if _, err := ParseSensorPayload(golden[:sensorPayloadLen-1]); err == nil {
+			t.Error("ParseSensorPayload(short) = nil error; want error")
+		}
+	})
+
+	t.Run("rejects bad magic", func(t *testing.T) {
+		bad := append([]byte(nil), golden...)
+		bad[0] = 0x02
+		if _, err := ParseSensorPayload(bad); err == nil {
+			t.Error("ParseSensorPayload(bad magic) = nil error; want error")
+		}
+	})
+}
+
+// goldenFrames are the frames the firmware encodes for the golden reading with
+// firmware 1.4.2 (see sensor/payload tests).
+var goldenFrames = [][]byte{
+	{
+		0x01, 0xD1,
+		0x78, 0x56, 0x34, 0x12,
+		0x2A, 0x00, 0x00, 0x00,
+		0x02,
+		0x01, 0x04, 0x00, 0x00, 0xAC, 0x41,
+		0x02, 0x04, 0x00, 0x50, 0x7D, 0x44,
+		0x03, 0x04,
+	},
+	{
+		0x01, 0xD1,
+		0x78, 0x56, 0x34, 0x12,
+		0x2A, 0x00, 0x00, 0x00,
+		0x12,
+		0x00, 0x00, 0x5C, 0x42,
+		0x04, 0x03, 0x01, 0x04, 0x02,
+	},
+}
+
+func TestParseSensorFrame(t *testing.T) {
+	t.Run("parses firmware frames", func(t *testing.T) {
+		var body []byte
+		for i, data := range goldenFrames {
+			f, err := parseSensorFrame(data)
+			if err != nil {
+				t.Fatalf("parseSensorFrame(%d) error = %v", i, err)
+			}
+			if f.DeviceID != 0x12345678 || f.ReadingID != 42 || f.Index != i || f.Count != 2 {
+				t.Errorf("parseSensorFrame(%d) = %+v", i, f)
+			}
+			body = append(body, f.Data...)
+		}
+		sr, err := parseSensorBody(0x12345678, 42, body)
+		if err != nil {
+			t.Fatalf("parseSensorBody() error = %v", err)
+		}
+		want := SensorReading{DeviceID: 0x12345678, ReadingID: 42, Temperature: 21.5, Pressure: 1013.25, Humidity: 55, FirmwareVersion: "1.4.2"}
+		if *sr != want {
+			t.Errorf("parseSensorBody() = %+v; want %+v", *sr, want)
+		}
+	})
+
+	t.Run("skips unknown tags", func(t *testing.T) {
+		body := append([]byte{0x7F, 0x02, 0xAA, 0xBB}, goldenFrames[0][sensorFrameHeaderLen:]...)
+		body = append(body, goldenFrames[1][sensorFrameHeaderLen:]...)
+		if _, err := parseSensorBody(1, 2, body); err != nil {
+			t.Errorf("parseSensorBody(unknown tag) error = %v", err)
+		}
+	})
+
+	t.Run("rejects incomplete body", func(t *testing.T) {
+		if _, err := parseSensorBody(1, 2, goldenFrames[0][sensorFrameHeaderLen:]); err == nil {
+			t.Error("parseSensorBody(first frame only) = nil error; want error")
+		}
+	})
+
+	t.Run("rejects bad frames", func(t *testing.T) {
+		if _, err := parseSensorFrame(golden); err == nil {
+			t.Error("parseSensorFrame(single-frame payload) = nil error; want error")
+		}
+		if _, err := parseSensorFrame(goldenFrames[0][:sensorFrameHeaderLen-1]); err == nil {
+			t.Error("parseSensorFrame(short) = nil error; want error")
+		}
+		bad := append([]byte(nil), goldenFrames[0]...)
+		bad[10] = 0x20
+		if _, err := parseSensorFrame(bad); err == nil {
+			t.Error("parseSensorFrame(count 0) = nil error; want error")
+		}
+	})
+}
+
+// goldenStatus is the status frame the firmware encodes for device 0x12345678
+// after 3600 s at 3300 mV with 2 sensor errors, 1 advert error, firmware 1.4.2
+// and a 120 s reading interval (see sensor/payload tests).
+var goldenStatus = []byte{
+	0x01, 0xD2,
+	0x78, 0x56, 0x34, 0x12,
+	0x10, 0x0E, 0x00, 0x00,
+	0xE4, 0x0C,
+	0x02, 0x00,
+	0x01, 0x00,
+	0x01, 0x04, 0x02,
+	0x78, 0x00,
+}
+
+func TestParseSensorStatus(t *testing.T) {
+	st, err := parseSensorStatus(goldenStatus)
+	if err != nil {
+		t.Fatalf("parseSensorStatus() error = %v", err)
+	}
+	want := SensorStatus{DeviceID: 0x12345678, UptimeSeconds: 3600, BatteryMillivolts: 3300, SensorErrors: 2, AdvertErrors: 1, FirmwareVersion: "1.4.2", IntervalSeconds: 120}
+	if *st != want {
+		t.Errorf("parseSensorStatus() = %+v; want %+v", *st, want)
+	}
+	// Firmware without an adaptive interval ends the frame at the version.
+	st, err = parseSensorStatus(goldenStatus[:sensorStatusLen])
+	want.IntervalSeconds = 0
+	if err != nil || *st != want {
+		t.Errorf("parseSensorStatus(without interval) = %+v, %v; want %+v", st, err, want)
+	}
+	if _, err := parseSensorStatus(goldenStatus[:sensorStatusLen-1]); err == nil {
+		t.Error("parseSensorStatus(short) = nil error; want error")
+	}
+	if _, err := parseSensorStatus(append(append([]byte(nil), golden...), 0, 0, 0)); err == nil {
+		t.Error("parseSensorStatus(reading) = nil error; want error")
+	}
+}
+
+func TestSensorPayloadTypeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want sensorPayloadType
+	}{
+		{"reading", golden, sensorPayloadReading},
+		{"frame", goldenFrames[0], sensorPayloadFrame},
+		{"status", goldenStatus, sensorPayloadStatus},
+		{"unknown magic", []byte{0x01, 0xDF}, sensorPayloadUnknown},
+		{"other vendor", []byte{0x4C, 0x00}, sensorPayloadUnknown},
+		{"empty", nil, sensorPayloadUnknown},
+	}
+	for _, tt := range tests {
+		if got := sensorPayloadTypeOf(tt.data); got != tt.want {
+			t.Errorf("%s: sensorPayloadTypeOf() = %d; want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package ble
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HandlerSensor is the handler name for Pico sensor advertisements (see BLESensorHandler).
+const HandlerSensor = "sensor"
+
+// Rule maps advertisements matching Filter to a named handler and, optionally,
+// a fixed station ID. Rules are evaluated in order; the first match wins.
+type Rule struct {
+	Name      string
+	Filter    Filter
+	Handler   string
+	StationID string // overrides the station ID derived from the payload when set
+}
+
+// DefaultRules matches Pico sensor advertisements (company 0xFFFF, magic 01 D0),
+// the frames of multi-frame sensor messages (magic 01 D1) and sensor status
+// frames (magic 01 D2).
+func DefaultRules() []Rule {
+	return []Rule{{
+		Name:    "pico-sensor",
+		Filter:  Filter{CompanyID: 0xFFFF, ManufacturerDataPref: []byte{0x01, 0xD0}},
+		Handler: HandlerSensor,
+	}, {
+		Name:    "pico-sensor-frames",
+		Filter:  Filter{CompanyID: 0xFFFF, ManufacturerDataPref: []byte{0x01, sensorFrameMagic1}},
+		Handler: HandlerSensor,
+	}, {
+		Name:    "pico-sensor-status",
+		Filter:  Filter{CompanyID: 0xFFFF, ManufacturerDataPref: []byte{0x01, sensorStatusMagic1}},
+		Handler: HandlerSensor,
+	}}
+}
+
+// ruleJSON is the on-disk form of a Rule. company_id accepts "0xFFFF" or "65535";
+// prefix is hex, with optional spaces ("01D0" or "01 D0").
+type ruleJSON struct {
+	Name      string `json:"name"`
+	LocalName string `json:"local_name"`
+	CompanyID string `json:"company_id"`
+	Prefix    string `json:"prefix"`
+	Handler   string `json:"handler"`
+	StationID string `json:"station_id"`
+}
+
+// LoadRules reads a JSON array of rules from path.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ble rules: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// ParseRules decodes a JSON array of rules.
+func ParseRules(data []byte) ([]Rule, error) {
+	var raw []ruleJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse ble rules: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("parse ble rules: no rules defined")
+	}
+	rules := make([]Rule, 0, len(raw))
+	for i, r := range raw {
+		rule := Rule{
+			Name:      strings.TrimSpace(r.Name),
+			Handler:   strings.TrimSpace(r.Handler),
+			StationID: strings.TrimSpace(r.StationID),
+			Filter:    Filter{LocalName: r.LocalName},
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.Handler == "" {
+			rule.Handler = HandlerSensor
+		}
+		if s := strings.TrimSpace(r.CompanyID); s != "" {
+			id, err := strconv.ParseUint(s, 0, 16)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid company_id %q: %w", rule.Name, s, err)
+			}
+			rule.Filter.CompanyID = uint16(id)
+		}
+		if s := strings.ReplaceAll(strings.TrimSpace(r.Prefix), " ", ""); s != "" {
+			prefix, err := hex.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: invalid prefix %q: %w", rule.Name, r.Prefix, err)
+			}
+			rule.Filter.ManufacturerDataPref = prefix
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Router dispatches matches to handlers by the matching rule's Handler name.
+type Router map[string]func(Match)
+
+// Validate reports rules that name a handler the router does not know.
+func (rt Router) Validate(rules []Rule) error {
+	for _, rule := range rules {
+		if _, ok := rt[rule.Handler]; !ok {
+			return fmt.Errorf("rule %q: unknown handler %q", rule.Name, rule.Handler)
+		}
+	}
+	return nil
+}
+
+// HandleMatch calls the handler registered for m.Handler.
+func (rt Router) HandleMatch(m Match) {
+	h, ok := rt[m.Handler]
+	if !ok {
+		slog.Warn("ble: no handler for rule", "rule", m.Rule, "handler", m.Handler)
+		return
+	}
+	h(m)
+}
@@ -0,0 +1,94 @@
+package ble
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	t.Run("parses rules in order", func(t *testing.T) {
+		rules, err := ParseRules([]byte(`[
+			{"name": "garden", "company_id": "0xFFFF", "prefix": "01 D0", "station_id": "garden"},
+			{"local_name": "pico2w-sensor", "company_id": "65535", "handler": "sensor"}
+		]`))
+		if err != nil {
+			t.Fatalf("ParseRules() = %v", err)
+		}
+		if len(rules) != 2 {
+			t.Fatalf("got %d rules; want 2", len(rules))
+		}
+		r := rules[0]
+		if r.Name != "garden" || r.Handler != HandlerSensor || r.StationID != "garden" ||
+			r.Filter.CompanyID != 0xFFFF || !bytes.Equal(r.Filter.ManufacturerDataPref, []byte{0x01, 0xD0}) {
+			t.Errorf("rules[0] = %+v", r)
+		}
+		if rules[1].Name != "rule-2" || rules[1].Filter.LocalName != "pico2w-sensor" || rules[1].Filter.CompanyID != 0xFFFF {
+			t.Errorf("rules[1] = %+v", rules[1])
+		}
+	})
+
+	for name, input := range map[string]string{
+		"invalid json":   `{`,
+		"empty list":     `[]`,
+		"bad company id": `[{"company_id": "0x1FFFF"}]`,
+		"bad hex prefix": `[{"prefix": "0G"}]`,
+		"odd-length hex": `[{"prefix": "01D"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseRules([]byte(input)); err == nil {
+				t.Errorf("ParseRules(%s) = nil error; want error", input)
+			}
+		})
+	}
+}
+
+func TestListener_matchRules(t *testing.T) {
+	l := NewListenerWithSource(newFakeSource(), Options{Rules: []Rule{
+		{Name: "garden", Filter: Filter{CompanyID: 0xFFFF, ManufacturerDataPref: []byte{0x01, 0xD0, 0x01}}, Handler: "sensor", StationID: "garden"},
+		{Name: "any-pico", Filter: Filter{CompanyID: 0xFFFF, ManufacturerDataPref: []byte{0x01, 0xD0}}, Handler: "sensor"},
+		{Name: "other", Filter: Filter{CompanyID: 0x004C}, Handler: "other"},
+	}})
+
+	tests := []struct {
+		name      string
+		result    ScanResult
+		wantRule  string
+		wantMatch bool
+	}{
+		{"first rule wins", sensorAdvert("AA", 0xFFFF, []byte{0x01, 0xD0, 0x01}), "garden", true},
+		{"falls through to later rule", sensorAdvert("BB", 0xFFFF, []byte{0x01, 0xD0, 0x02}), "any-pico", true},
+		{"different handler", sensorAdvert("CC", 0x004C, []byte{0x99}), "other", true},
+		{"no rule matches", sensorAdvert("DD", 0x0001, []byte{0x01}), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := l.match(tt.result, time.Now())
+			if ok != tt.wantMatch || m.Rule != tt.wantRule {
+				t.Errorf("match() = %q, %v; want %q, %v", m.Rule, ok, tt.wantRule, tt.wantMatch)
+			}
+		})
+	}
+
+	if m, _ := l.match(sensorAdvert("AA", 0xFFFF, []byte{0x01, 0xD0, 0x01}), time.Now()); m.StationID != "garden" {
+		t.Errorf("StationID = %q; want garden", m.StationID)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var got []string
+	rt := Router{"sensor": func(m Match) { got = append(got, m.Address) }}
+
+	if err := rt.Validate(DefaultRules()); err != nil {
+		t.Errorf("Validate(DefaultRules) = %v; want nil", err)
+	}
+	if err := rt.Validate([]Rule{{Name: "x", Handler: "missing"}}); err == nil {
+		t.Error("Validate(unknown handler) = nil; want error")
+	}
+
+	rt.HandleMatch(Match{Address: "AA", Handler: "sensor"})
+	rt.HandleMatch(Match{Address: "BB", Handler: "missing"})
+	if len(got) != 1 || got[0] != "AA" {
+		t.Errorf("dispatched = %v; want [AA]", got)
+	}
+}
@@ -0,0 +1,136 @@
+package ble
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"tinygo.org/x/bluetooth"
+)
+
+// ManufacturerData is a single manufacturer-specific data element from an advertisement.
+type ManufacturerData struct {
+	CompanyID uint16
+	Data      []byte
+}
+
+// ScanResult is the subset of a BLE advertisement the listener needs.
+type ScanResult struct {
+	Address          string
+	RSSI             int16
+	LocalName        string
+	ManufacturerData []ManufacturerData
+}
+
+// ScanSource produces BLE scan results. It is implemented by the BlueZ adapter
+// in production and by fakes in tests.
+type ScanSource interface {
+	Enable() error
+	// Scan blocks, calling onResult for every advertisement, until StopScan is called or an error occurs.
+	Scan(onResult func(ScanResult)) error
+	StopScan() error
+}
+
+// AdapterReconnector is implemented by scan sources that can drop their
+// adapter and create it again, as needed after BlueZ left the bus.
+type AdapterReconnector interface {
+	Reconnect() error
+}
+
+// bluezGoneErrors are the DBus errors calls to BlueZ fail with while
+// bluetoothd is not on the system bus.
+var bluezGoneErrors = []string{
+	"org.freedesktop.DBus.Error.ServiceUnknown",
+	"org.freedesktop.DBus.Error.NameHasNoOwner",
+	"org.freedesktop.DBus.Error.NoReply",
+	"org.freedesktop.DBus.Error.Disconnected",
+	"org.freedesktop.DBus.Error.UnknownObject",
+}
+
+// IsBlueZDisconnect reports whether a scan error means BlueZ went away, as
+// when bluetoothd restarts: the DBus connection closed, BlueZ no longer owns
+// its bus name, or the adapter stopped discovering or lost power under the
+// scan, which is how the exit shows while a scan runs.
+func IsBlueZDisconnect(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, dbus.ErrClosed) {
+		return true
+	}
+	var dbusErr dbus.Error
+	if errors.As(err, &dbusErr) {
+		return slices.Contains(bluezGoneErrors, dbusErr.Name)
+	}
+	// The bluetooth package reports the last two with unexported errors.
+	msg := err.Error()
+	return strings.HasSuffix(msg, "scan was stopped unexpectedly") || strings.HasSuffix(msg, "adaptor is not powered")
+}
+
+// bluezSource adapts a tinygo bluetooth adapter (BlueZ over DBus on Linux) to ScanSource.
+type bluezSource struct {
+	name    string
+	adapter *bluetooth.Adapter
+}
+
+// NewBlueZSource returns a ScanSource backed by the named BlueZ adapter (e.g. "hci0").
+func NewBlueZSource(adapterName string) ScanSource {
+	return &bluezSource{name: adapterName, adapter: bluetooth.NewAdapter(adapterName)}
+}
+
+func (s *bluezSource) Enable() error {
+	return s.adapter.Enable()
+}
+
+func (s *bluezSource) Scan(onResult func(ScanResult)) error {
+	return s.adapter.Scan(func(_ *bluetooth.Adapter, r bluetooth.ScanResult) {
+		res := ScanResult{
+			Address:   r.Address.String(),
+			RSSI:      r.RSSI,
+			LocalName: r.LocalName(),
+		}
+		for _, md := range r.ManufacturerData() {
+			res.ManufacturerData = append(res.ManufacturerData, ManufacturerData{
+				CompanyID: md.CompanyID,
+				Data:      append([]byte(nil), md.Data...),
+			})
+		}
+		onResult(res)
+	})
+}
+
+func (s *bluezSource) StopScan() error {
+	return s.adapter.StopScan()
+}
+
+// Reconnect replaces the adapter with a new one and enables it. The old one
+// keeps object handles and scan state from the BlueZ instance that exited;
+// the system bus connection is dialled again if it was closed.
+func (s *bluezSource) Reconnect() error {
+	s.adapter = bluetooth.NewAdapter(s.name)
+	return s.adapter.Enable()
+}
+
+// adapterPowerCycle is how long the adapter stays powered off on reset.
+const adapterPowerCycle = 2 * time.Second
+
+// ResetAdapter powers the adapter off and on again through BlueZ, which also
+// resets the controller's scan state.
+func (s *bluezSource) ResetAdapter() error {
+	bus, err := dbus.SystemBus()
+	if err != nil {
+		return err
+	}
+	obj := bus.Object("org.bluez", dbus.ObjectPath("/org/bluez/"+s.name))
+	if err := obj.SetProperty("org.bluez.Adapter1.Powered", dbus.MakeVariant(false)); err != nil {
+		return fmt.Errorf("power off %s: %w", s.name, err)
+	}
+	time.Sleep(adapterPowerCycle)
+	if err := obj.SetProperty("org.bluez.Adapter1.Powered", dbus.MakeVariant(true)); err != nil {
+		return fmt.Errorf("power on %s: %w", s.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package ble
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WatchdogOptions configures the listener's stale data watchdog. BlueZ can
+// wedge and stop delivering advertisements without returning an error; the
+// watchdog notices the silence and restarts scanning.
+type WatchdogOptions struct {
+	// Timeout is how long the listener may see no advertisement at all,
+	// matching or not, before it restarts scanning. Zero disables the
+	// watchdog.
+	Timeout time.Duration
+	// ResetAdapter power-cycles the adapter before each restart, when the
+	// scan source implements AdapterResetter.
+	ResetAdapter bool
+	// EscalateAfter is the number of consecutive restarts without an
+	// advertisement after which OnHealthChange reports the flow unhealthy.
+	// Values below 1 mean 1.
+	EscalateAfter int
+	// OnHealthChange, if set, is called with healthy=false on escalation and
+	// with healthy=true when advertisements resume afterwards. lastSeen is
+	// the time of the last advertisement (zero when none was seen). It runs
+	// on the scanning goroutine.
+	OnHealthChange func(healthy bool, lastSeen time.Time)
+}
+
+// AdapterResetter is implemented by scan sources that can power-cycle their
+// adapter.
+type AdapterResetter interface {
+	ResetAdapter() error
+}
+
+// watchdog tracks advertisement flow across scan sessions.
+type watchdog struct {
+	opts WatchdogOptions
+
+	mu        sync.Mutex
+	lastSeen  time.Time // last advertisement
+	since     time.Time // start of the current silence check
+	restarts  int       // consecutive restarts without an advertisement
+	escalated bool
+}
+
+// AdapterState is the advertisement flow the listener's watchdog observes.
+type AdapterState struct {
+	// Healthy is false from an escalation until advertisements resume.
+	Healthy bool
+	// LastSeen is the time of the last advertisement, zero when none was seen.
+	LastSeen time.Time
+	// Restarts counts consecutive scan restarts without an advertisement.
+	Restarts int
+	// BlueZDisconnects counts the times BlueZ went away under a scan, e.g.
+	// because bluetoothd restarted, since the listener started.
+	BlueZDisconnects int
+}
+
+func newWatchdog(opts WatchdogOptions) *watchdog {
+	if opts.EscalateAfter < 1 {
+		opts.EscalateAfter = 1
+	}
+	return &watchdog{opts: opts}
+}
+
+// start begins a scan session at now.
+func (w *watchdog) start(now time.Time) {
+	w.mu.Lock()
+	w.since = now
+	w.mu.Unlock()
+}
+
+// seen records an advertisement at now and reports recovery after an
+// escalation.
+func (w *watchdog) seen(now time.Time) {
+	w.mu.Lock()
+	w.lastSeen, w.since = now, now
+	recovered := w.escalated
+	if w.restarts > 0 {
+		slog.Info("ble: advertisements resumed", "restarts", w.restarts)
+	}
+	w.restarts, w.escalated = 0, false
+	w.mu.Unlock()
+	if recovered && w.opts.OnHealthChange != nil {
+		w.opts.OnHealthChange(true, now)
+	}
+}
+
+// state returns the current advertisement flow.
+func (w *watchdog) state() AdapterState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return AdapterState{Healthy: !w.escalated, LastSeen: w.lastSeen, Restarts: w.restarts}
+}
+
+// stale reports whether no advertisement was seen for the timeout at now.
+func (w *watchdog) stale(now time.Time) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return now.Sub(w.since) >= w.opts.Timeout
+}
+
+// restarted counts a restart for silence and escalates once EscalateAfter
+// consecutive restarts saw nothing.
+func (w *watchdog) restarted() {
+	w.mu.Lock()
+	w.restarts++
+	restarts, lastSeen := w.restarts, w.lastSeen
+	escalate := !w.escalated && restarts >= w.opts.EscalateAfter
+	w.escalated = w.escalated || escalate
+	w.mu.Unlock()
+
+	if !escalate {
+		slog.Warn("ble: no advertisements; restarting scan",
+			"timeout", w.opts.Timeout, "restarts", restarts, "last_seen", lastSeen)
+		return
+	}
+	slog.Error("ble: no advertisements after repeated scan restarts; reporting unhealthy",
+		"timeout", w.opts.Timeout, "restarts", restarts, "last_seen", lastSeen)
+	if w.opts.OnHealthChange != nil {
+		w.opts.OnHealthChange(false, lastSeen)
+	}
+}
+
+// stopRetry is how often StopScan is retried on shutdown, in case it was
+// called before Scan started.
+const stopRetry = time.Second
+
+// supervise stops the scan when ctx is done or, with the watchdog enabled,
+// when advertisements went stale, until done is closed. The returned channel
+// then reports whether it stopped the scan for staleness.
+func (l *Listener) supervise(ctx context.Context, done <-chan struct{}) <-chan bool {
+	out := make(chan bool, 1)
+	go func() {
+		var tick <-chan time.Time
+		if l.opts.Watchdog.Timeout > 0 {
+			t := time.NewTicker(max(l.opts.Watchdog.Timeout/4, time.Millisecond))
+			defer t.Stop()
+			tick = t.C
+		}
+		stale := false
+		defer func() { out <- stale }()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				_ = l.source.StopScan()
+				select {
+				case <-done:
+					return
+				case <-time.After(stopRetry):
+				}
+			case now := <-tick:
+				if !stale && l.watchdog.stale(now) {
+					stale = true
+					_ = l.source.StopScan()
+				}
+			}
+		}
+	}()
+	return out
+}
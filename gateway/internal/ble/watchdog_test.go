@@ -0,0 +1,152 @@
+package ble
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sessionSource is a ScanSource whose scan sessions each replay the results
+// of the next entry in sessions (then block until StopScan). Sessions past
+// the end see nothing.
+type sessionSource struct {
+	mu       sync.Mutex
+	sessions [][]ScanResult
+	scans    int
+	resets   int
+	stop     chan struct{}
+}
+
+func (s *sessionSource) Enable() error { return nil }
+
+func (s *sessionSource) Scan(onResult func(ScanResult)) error {
+	s.mu.Lock()
+	var results []ScanResult
+	if s.scans < len(s.sessions) {
+		results = s.sessions[s.scans]
+	}
+	s.scans++
+	stop := make(chan struct{})
+	s.stop = stop
+	s.mu.Unlock()
+	for _, r := range results {
+		onResult(r)
+	}
+	<-stop
+	return nil
+}
+
+func (s *sessionSource) StopScan() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	return nil
+}
+
+func (s *sessionSource) ResetAdapter() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resets++
+	return nil
+}
+
+func (s *sessionSource) counts() (scans, resets int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scans, s.resets
+}
+
+func TestListener_Watchdog(t *testing.T) {
+	advert := sensorAdvert("AA:BB", 0x004C, []byte{0x02}) // matches no rule; still counts as flow
+
+	t.Run("restarts scanning and escalates after repeated silence", func(t *testing.T) {
+		src := &sessionSource{sessions: [][]ScanResult{{advert}}}
+		var (
+			mu     sync.Mutex
+			health []bool
+		)
+		l := NewListenerWithSource(src, Options{
+			Filter: Filter{CompanyID: 0xFFFF},
+			Watchdog: WatchdogOptions{
+				Timeout:       20 * time.Millisecond,
+				ResetAdapter:  true,
+				EscalateAfter: 2,
+				OnHealthChange: func(healthy bool, lastSeen time.Time) {
+					mu.Lock()
+					health = append(health, healthy)
+					mu.Unlock()
+					if lastSeen.IsZero() {
+						t.Error("lastSeen is zero after an advertisement was seen")
+					}
+				},
+			},
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+		defer cancel()
+		if err := l.Run(ctx, nil); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+
+		scans, resets := src.counts()
+		if scans < 3 || resets != scans-1 {
+			t.Errorf("scans = %d, resets = %d; want >= 3 scans and a reset before each restart", scans, resets)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(health) != 1 || health[0] {
+			t.Errorf("health changes = %v; want a single unhealthy report", health)
+		}
+		if st := l.AdapterState(); st.Healthy || st.LastSeen.IsZero() || st.Restarts < 2 {
+			t.Errorf("AdapterState() = %+v; want unhealthy after >= 2 restarts with a last advertisement", st)
+		}
+	})
+
+	t.Run("reports recovery when advertisements resume", func(t *testing.T) {
+		src := &sessionSource{sessions: [][]ScanResult{nil, nil, {advert}}}
+		var (
+			mu     sync.Mutex
+			health []bool
+		)
+		l := NewListenerWithSource(src, Options{
+			Watchdog: WatchdogOptions{
+				Timeout:       20 * time.Millisecond,
+				EscalateAfter: 2,
+				OnHealthChange: func(healthy bool, _ time.Time) {
+					mu.Lock()
+					health = append(health, healthy)
+					mu.Unlock()
+				},
+			},
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := l.Run(ctx, nil); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(health) < 2 || health[0] || !health[1] {
+			t.Errorf("health changes = %v; want unhealthy then healthy", health)
+		}
+		if _, resets := src.counts(); resets != 0 {
+			t.Errorf("resets = %d; want 0 without ResetAdapter", resets)
+		}
+	})
+
+	t.Run("disabled keeps a single scan", func(t *testing.T) {
+		src := &sessionSource{}
+		l := NewListenerWithSource(src, Options{})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := l.Run(ctx, nil); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		if scans, _ := src.counts(); scans != 1 {
+			t.Errorf("scans = %d; want 1", scans)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// defaultMQTTClientIDBase is the client ID prefix used when MQTT_CLIENT_ID is unset.
+const defaultMQTTClientIDBase = "cloudpico-gateway"
+
+// machineIDFiles are read in order for a stable machine identity.
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// defaultMQTTClientID returns the base client ID with a suffix derived from
+// the machine ID, or the hostname when there is none, so gateways left on
+// the default do not take over each other's broker session. The identity is
+// hashed so the machine ID itself is not sent to the broker. Without either,
+// the base alone is returned.
+func defaultMQTTClientID(readFile func(string) ([]byte, error), hostname func() (string, error)) string {
+	identity := ""
+	for _, path := range machineIDFiles {
+		if b, err := readFile(path); err == nil {
+			if identity = strings.TrimSpace(string(b)); identity != "" {
+				break
+			}
+		}
+	}
+	if identity == "" {
+		if h, err := hostname(); err == nil {
+			identity = strings.TrimSpace(h)
+		}
+	}
+	if identity == "" {
+		return defaultMQTTClientIDBase
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return defaultMQTTClientIDBase + "-" + hex.EncodeToString(sum[:4])
+}
+
+// hostMQTTClientID is defaultMQTTClientID for this host.
+func hostMQTTClientID() string {
+	return defaultMQTTClientID(os.ReadFile, os.Hostname)
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDefaultMQTTClientID(t *testing.T) {
+	files := func(content map[string]string) func(string) ([]byte, error) {
+		return func(path string) ([]byte, error) {
+			if c, ok := content[path]; ok {
+				return []byte(c), nil
+			}
+			return nil, os.ErrNotExist
+		}
+	}
+	host := func(name string, err error) func() (string, error) {
+		return func() (string, error) { return name, err }
+	}
+
+	fromMachineID := defaultMQTTClientID(files(map[string]string{"/etc/machine-id": "0123456789abcdef\n"}), host("pi", nil))
+	if !strings.HasPrefix(fromMachineID, "cloudpico-gateway-") || len(fromMachineID) != len("cloudpico-gateway-")+8 {
+		t.Errorf("client ID = %q; want cloudpico-gateway- and 8 hex digits", fromMachineID)
+	}
+	if strings.Contains(fromMachineID, "0123456789") {
+		t.Errorf("client ID = %q exposes the machine ID", fromMachineID)
+	}
+	if again := defaultMQTTClientID(files(map[string]string{"/var/lib/dbus/machine-id": "0123456789abcdef"}), host("other", nil)); again != fromMachineID {
+		t.Errorf("client ID from dbus machine-id = %q; want stable %q", again, fromMachineID)
+	}
+
+	fromHost := defaultMQTTClientID(files(nil), host("pi", nil))
+	if fromHost == fromMachineID || fromHost == defaultMQTTClientIDBase {
+		t.Errorf("client ID from hostname = %q; want a distinct suffixed ID", fromHost)
+	}
+	if other := defaultMQTTClientID(files(nil), host("pi2", nil)); other == fromHost {
+		t.Errorf("client IDs for different hosts are both %q", other)
+	}
+
+	if got := defaultMQTTClientID(files(map[string]string{"/etc/machine-id": " \n"}), host("", errors.New("no hostname"))); got != defaultMQTTClientIDBase {
+		t.Errorf("client ID without identity = %q; want %q", got, defaultMQTTClientIDBase)
+	}
+}
@@ -7,6 +7,15 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"cloudpico-gateway/internal/topic"
+)
+
+// Input names accepted in GATEWAY_INPUTS.
+const (
+	InputBLE      = "ble"
+	InputI2C      = "i2c"
+	InputSimulate = "simulate"
 )
 
 type Config struct {
@@ -14,11 +23,63 @@ type Config struct {
 	LogLevel     slog.Level
 	MQTTBroker   string
 	MQTTPort     int
-	MQTTClientID string
+	MQTTClientID string // defaults to a per-machine ID; see defaultMQTTClientID
+	// TelemetryTopic is the topic readings are published on, with
+	// {gateway_id} standing for MQTTClientID.
+	TelemetryTopic topic.Template
+
+	// Inputs lists the telemetry sources the gateway starts, in order.
+	Inputs []string
+	// SimulateStationIDs are the stations the simulate input publishes for.
+	SimulateStationIDs []string
 
 	BME280Address      uint16
 	SensorPollInterval time.Duration
 	DeviceStationID    string
+
+	// OutboxMaxPending bounds the number of unacknowledged readings kept for replay.
+	OutboxMaxPending int
+	// OutboxAckTimeout is how long to wait for a server ack before resending a reading.
+	OutboxAckTimeout time.Duration
+
+	// HealthPublishInterval is how often the gateway publishes its health on
+	// gateways/<MQTTClientID>/health. Zero disables the periodic message.
+	HealthPublishInterval time.Duration
+
+	// BLERulesFile is an optional JSON file of BLE filter rules; empty uses ble.DefaultRules.
+	BLERulesFile string
+	// BLEWatchdogTimeout is how long the BLE input may see no advertisement at
+	// all before it restarts scanning. Zero disables the watchdog.
+	BLEWatchdogTimeout time.Duration
+	// BLEWatchdogResetAdapter power-cycles the adapter before a watchdog
+	// restart.
+	BLEWatchdogResetAdapter bool
+	// BLEWatchdogEscalateAfter is the number of consecutive watchdog restarts
+	// after which the BLE stations are reported unhealthy.
+	BLEWatchdogEscalateAfter int
+	// BLEDebugLogInterval is the minimum time between two debug lines about
+	// advertisements of one device that match no rule. Zero logs them all.
+	BLEDebugLogInterval time.Duration
+	// BLEDebugSummaryInterval is how often a summary of the advertisements
+	// seen is logged at debug level. Zero disables the summary.
+	BLEDebugSummaryInterval time.Duration
+	// SensorsFile is an optional JSON file of I2C sensor definitions; empty polls
+	// the single BME280 at BME280Address for DeviceStationID.
+	SensorsFile string
+	// StationMapFile is an optional JSON file mapping BLE devices and I2C
+	// sensors to station IDs; see package stationmap.
+	StationMapFile string
+
+	// ServerURL is the server's HTTP base URL, used at startup to check that
+	// mapped stations exist. Empty skips the check.
+	ServerURL string
+	// StationMapStrict makes unknown or unverifiable stations a startup error
+	// instead of a warning.
+	StationMapStrict bool
+
+	// AdminAddr is the listen address of the admin HTTP API, e.g.
+	// 127.0.0.1:8081. Empty disables it.
+	AdminAddr string
 }
 
 func LoadFromEnv() (Config, error) {
@@ -57,7 +118,35 @@ func LoadFromEnv() (Config, error) {
 
 	mqttClientID := strings.TrimSpace(os.Getenv("MQTT_CLIENT_ID"))
 	if mqttClientID == "" {
-		mqttClientID = "cloudpico-gateway"
+		mqttClientID = hostMQTTClientID()
+	}
+
+	telemetryTopicStr := strings.TrimSpace(os.Getenv("MQTT_TELEMETRY_TOPIC"))
+	if telemetryTopicStr == "" {
+		telemetryTopicStr = topic.DefaultTelemetry
+	}
+	telemetryTopic, err := topic.Parse(telemetryTopicStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid MQTT_TELEMETRY_TOPIC: %w", err)
+	}
+	if telemetryTopic.Uses(topic.GatewayID) {
+		if err := topic.CheckLevel(mqttClientID); err != nil {
+			return Config{}, fmt.Errorf("MQTT_TELEMETRY_TOPIC uses %s, but MQTT_CLIENT_ID cannot fill it: %w", topic.GatewayID, err)
+		}
+	}
+
+	inputsStr := strings.TrimSpace(os.Getenv("GATEWAY_INPUTS"))
+	if inputsStr == "" {
+		inputsStr = InputBLE
+	}
+	inputs, err := parseInputs(inputsStr)
+	if err != nil {
+		return Config{}, err
+	}
+
+	simulateStationIDs := splitList(os.Getenv("SIMULATE_STATION_IDS"))
+	if len(simulateStationIDs) == 0 {
+		simulateStationIDs = []string{"simulated"}
 	}
 
 	bme280AddressStr := strings.TrimSpace(os.Getenv("BME280_ADDRESS"))
@@ -86,18 +175,177 @@ func LoadFromEnv() (Config, error) {
 		deviceStationID = "home"
 	}
 
+	outboxMaxPendingStr := strings.TrimSpace(os.Getenv("OUTBOX_MAX_PENDING"))
+	if outboxMaxPendingStr == "" {
+		outboxMaxPendingStr = "1000"
+	}
+	outboxMaxPending, err := strconv.Atoi(outboxMaxPendingStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid OUTBOX_MAX_PENDING %q: %w", outboxMaxPendingStr, err)
+	}
+	if outboxMaxPending <= 0 {
+		return Config{}, fmt.Errorf("OUTBOX_MAX_PENDING must be positive, got %d", outboxMaxPending)
+	}
+
+	outboxAckTimeoutStr := strings.TrimSpace(os.Getenv("OUTBOX_ACK_TIMEOUT"))
+	if outboxAckTimeoutStr == "" {
+		outboxAckTimeoutStr = "30s"
+	}
+	outboxAckTimeout, err := time.ParseDuration(outboxAckTimeoutStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid OUTBOX_ACK_TIMEOUT %q: %w", outboxAckTimeoutStr, err)
+	}
+	if outboxAckTimeout <= 0 {
+		return Config{}, fmt.Errorf("OUTBOX_ACK_TIMEOUT must be positive, got %v", outboxAckTimeout)
+	}
+
+	healthPublishIntervalStr := strings.TrimSpace(os.Getenv("HEALTH_PUBLISH_INTERVAL"))
+	if healthPublishIntervalStr == "" {
+		healthPublishIntervalStr = "60s"
+	}
+	healthPublishInterval, err := time.ParseDuration(healthPublishIntervalStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HEALTH_PUBLISH_INTERVAL %q: %w", healthPublishIntervalStr, err)
+	}
+	if healthPublishInterval < 0 {
+		return Config{}, fmt.Errorf("HEALTH_PUBLISH_INTERVAL must not be negative, got %v", healthPublishInterval)
+	}
+
+	bleRulesFile := strings.TrimSpace(os.Getenv("BLE_RULES_FILE"))
+
+	bleWatchdogTimeoutStr := strings.TrimSpace(os.Getenv("BLE_WATCHDOG_TIMEOUT"))
+	if bleWatchdogTimeoutStr == "" {
+		bleWatchdogTimeoutStr = "5m"
+	}
+	bleWatchdogTimeout, err := time.ParseDuration(bleWatchdogTimeoutStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BLE_WATCHDOG_TIMEOUT %q: %w", bleWatchdogTimeoutStr, err)
+	}
+	if bleWatchdogTimeout < 0 {
+		return Config{}, fmt.Errorf("BLE_WATCHDOG_TIMEOUT must not be negative, got %v", bleWatchdogTimeout)
+	}
+
+	bleWatchdogResetAdapterStr := strings.TrimSpace(os.Getenv("BLE_WATCHDOG_RESET_ADAPTER"))
+	if bleWatchdogResetAdapterStr == "" {
+		bleWatchdogResetAdapterStr = "false"
+	}
+	bleWatchdogResetAdapter, err := strconv.ParseBool(bleWatchdogResetAdapterStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BLE_WATCHDOG_RESET_ADAPTER %q: %w", bleWatchdogResetAdapterStr, err)
+	}
+
+	bleWatchdogEscalateAfterStr := strings.TrimSpace(os.Getenv("BLE_WATCHDOG_ESCALATE_AFTER"))
+	if bleWatchdogEscalateAfterStr == "" {
+		bleWatchdogEscalateAfterStr = "3"
+	}
+	bleWatchdogEscalateAfter, err := strconv.Atoi(bleWatchdogEscalateAfterStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BLE_WATCHDOG_ESCALATE_AFTER %q: %w", bleWatchdogEscalateAfterStr, err)
+	}
+	if bleWatchdogEscalateAfter <= 0 {
+		return Config{}, fmt.Errorf("BLE_WATCHDOG_ESCALATE_AFTER must be positive, got %d", bleWatchdogEscalateAfter)
+	}
+
+	bleDebugLogIntervalStr := strings.TrimSpace(os.Getenv("BLE_DEBUG_LOG_INTERVAL"))
+	if bleDebugLogIntervalStr == "" {
+		bleDebugLogIntervalStr = "1m"
+	}
+	bleDebugLogInterval, err := time.ParseDuration(bleDebugLogIntervalStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BLE_DEBUG_LOG_INTERVAL %q: %w", bleDebugLogIntervalStr, err)
+	}
+	if bleDebugLogInterval < 0 {
+		return Config{}, fmt.Errorf("BLE_DEBUG_LOG_INTERVAL must not be negative, got %v", bleDebugLogInterval)
+	}
+
+	bleDebugSummaryIntervalStr := strings.TrimSpace(os.Getenv("BLE_DEBUG_SUMMARY_INTERVAL"))
+	if bleDebugSummaryIntervalStr == "" {
+		bleDebugSummaryIntervalStr = "1m"
+	}
+	bleDebugSummaryInterval, err := time.ParseDuration(bleDebugSummaryIntervalStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid BLE_DEBUG_SUMMARY_INTERVAL %q: %w", bleDebugSummaryIntervalStr, err)
+	}
+	if bleDebugSummaryInterval < 0 {
+		return Config{}, fmt.Errorf("BLE_DEBUG_SUMMARY_INTERVAL must not be negative, got %v", bleDebugSummaryInterval)
+	}
+
+	sensorsFile := strings.TrimSpace(os.Getenv("SENSORS_FILE"))
+	stationMapFile := strings.TrimSpace(os.Getenv("STATION_MAP_FILE"))
+	serverURL := strings.TrimSpace(os.Getenv("SERVER_URL"))
+
+	stationMapStrictStr := strings.TrimSpace(os.Getenv("STATION_MAP_STRICT"))
+	if stationMapStrictStr == "" {
+		stationMapStrictStr = "false"
+	}
+	stationMapStrict, err := strconv.ParseBool(stationMapStrictStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid STATION_MAP_STRICT %q: %w", stationMapStrictStr, err)
+	}
+	if stationMapStrict && serverURL == "" {
+		return Config{}, fmt.Errorf("STATION_MAP_STRICT requires SERVER_URL")
+	}
+
 	return Config{
-		AppEnv:             appEnv,
-		LogLevel:           level,
-		MQTTBroker:         mqttBroker,
-		MQTTPort:           mqttPort,
-		MQTTClientID:       mqttClientID,
-		BME280Address:      uint16(bme280Address),
-		SensorPollInterval: sensorPollInterval,
-		DeviceStationID:    deviceStationID,
+		AppEnv:                   appEnv,
+		LogLevel:                 level,
+		MQTTBroker:               mqttBroker,
+		MQTTPort:                 mqttPort,
+		MQTTClientID:             mqttClientID,
+		TelemetryTopic:           telemetryTopic,
+		Inputs:                   inputs,
+		SimulateStationIDs:       simulateStationIDs,
+		BME280Address:            uint16(bme280Address),
+		SensorPollInterval:       sensorPollInterval,
+		DeviceStationID:          deviceStationID,
+		OutboxMaxPending:         outboxMaxPending,
+		OutboxAckTimeout:         outboxAckTimeout,
+		HealthPublishInterval:    healthPublishInterval,
+		BLERulesFile:             bleRulesFile,
+		BLEWatchdogTimeout:       bleWatchdogTimeout,
+		BLEWatchdogResetAdapter:  bleWatchdogResetAdapter,
+		BLEWatchdogEscalateAfter: bleWatchdogEscalateAfter,
+		BLEDebugLogInterval:      bleDebugLogInterval,
+		BLEDebugSummaryInterval:  bleDebugSummaryInterval,
+		SensorsFile:              sensorsFile,
+		StationMapFile:           stationMapFile,
+		ServerURL:                serverURL,
+		StationMapStrict:         stationMapStrict,
+		AdminAddr:                strings.TrimSpace(os.Getenv("ADMIN_ADDR")),
 	}, nil
 }
 
+func parseInputs(s string) ([]string, error) {
+	var inputs []string
+	seen := make(map[string]bool)
+	for _, in := range splitList(strings.ToLower(s)) {
+		switch in {
+		case InputBLE, InputI2C, InputSimulate:
+		default:
+			return nil, fmt.Errorf("invalid GATEWAY_INPUTS %q: unknown input %q (allowed: ble, i2c, simulate)", s, in)
+		}
+		if !seen[in] {
+			seen[in] = true
+			inputs = append(inputs, in)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("invalid GATEWAY_INPUTS %q: no inputs", s)
+	}
+	return inputs, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func parseLogLevel(s string) (slog.Level, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
 	case "debug":
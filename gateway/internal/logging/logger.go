@@ -8,10 +8,13 @@ import (
 	"github.com/lmittmann/tint"
 
 	"cloudpico-gateway/internal/config"
+	"cloudpico-shared/buildinfo"
 )
 
-func New(cfg config.Config, version string, appName string) *slog.Logger {
-	if version == "dev" {
+// New returns the application logger. Release builds log JSON and tag every
+// line with the build metadata; dev builds log colored text for terminals.
+func New(cfg config.Config, build buildinfo.Info, appName string) *slog.Logger {
+	if build.Version == "dev" {
 		h := tint.NewHandler(os.Stdout, &tint.Options{
 			Level:      cfg.LogLevel,
 			AddSource:  true,
@@ -25,7 +28,9 @@ func New(cfg config.Config, version string, appName string) *slog.Logger {
 	})
 	return slog.New(h).With(
 		"app", appName,
-		"version", version,
+		"version", build.Version,
+		"commit", build.ShortCommit(),
+		"build_date", build.BuildDate,
 		"env", cfg.AppEnv,
 	)
 }
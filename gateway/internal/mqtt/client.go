@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"cloudpico-gateway/internal/config"
+	"cloudpico-gateway/internal/topic"
 	cloudpico_shared "cloudpico-shared/types"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
@@ -22,12 +23,105 @@ type Client struct {
 
 	stopCh   chan struct{}
 	stopOnce sync.Once
+
+	ackHandler  func(cloudpico_shared.Ack)
+	onConnected func()
+
+	takeover takeoverDetector // guarded by mu
+}
+
+// A broker disconnects the older of two clients sharing a client ID, and with
+// auto-reconnect both keep kicking each other off. Connections lost within
+// takeoverWindow of connecting are counted; takeoverDrops of them in a row
+// are reported as a likely client ID collision.
+const (
+	takeoverWindow = 30 * time.Second
+	takeoverDrops  = 3
+)
+
+// takeoverDetector counts consecutive connections lost shortly after connecting.
+type takeoverDetector struct {
+	connectedAt time.Time
+	quickDrops  int
+}
+
+func (d *takeoverDetector) connected(now time.Time) {
+	d.connectedAt = now
 }
 
+// lost records a lost connection and returns how long it lasted and the
+// number of consecutive quick drops including this one.
+func (d *takeoverDetector) lost(now time.Time) (time.Duration, int) {
+	lasted := now.Sub(d.connectedAt)
+	if !d.connectedAt.IsZero() && lasted < takeoverWindow {
+		d.quickDrops++
+	} else {
+		d.quickDrops = 0
+	}
+	d.connectedAt = time.Time{}
+	return lasted, d.quickDrops
+}
+
+// ackTopic matches the acknowledgements the server publishes per station and
+// sequence, stations/{id}/ack/{sequence}.
+const ackTopic = "stations/+/ack/+"
+
 type StationHealth struct {
 	StationID string    `json:"station_id"`
 	LastSeen  time.Time `json:"last_seen"`
 	Healthy   bool      `json:"healthy"`
+	// Metadata carries the gateway and sensor firmware versions, when known.
+	Metadata *cloudpico_shared.Metadata `json:"metadata,omitempty"`
+}
+
+// GatewayHealth is the periodic status of the gateway, published retained on
+// gatewayHealthTopic.
+type GatewayHealth struct {
+	GatewayID string    `json:"gateway_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Devices are the sensors the inputs read from, BLE devices once a
+	// reading arrived and I2C sensors from startup.
+	Devices []DeviceHealth `json:"devices"`
+	// BufferDepth is the number of readings waiting for a server ack.
+	BufferDepth int `json:"buffer_depth"`
+	// Dedup counts BLE readings by outcome; nil without the BLE input.
+	Dedup *DedupHealth `json:"dedup,omitempty"`
+	// BLEAdapter is the scanner state; nil without the BLE input.
+	BLEAdapter *BLEAdapterHealth          `json:"ble_adapter,omitempty"`
+	Metadata   *cloudpico_shared.Metadata `json:"metadata,omitempty"`
+}
+
+// DeviceHealth is the last activity of one sensor. Source names it as in the
+// station map, e.g. "ble:0A1B2C3D" or "i2c:indoor".
+type DeviceHealth struct {
+	Source    string `json:"source"`
+	StationID string `json:"station_id"`
+	// LastSeen is nil until the first reading.
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+// DedupHealth counts BLE readings published and dropped as duplicates since
+// the gateway started.
+type DedupHealth struct {
+	Published  int `json:"published"`
+	Duplicates int `json:"duplicates"`
+}
+
+// BLEAdapterHealth is the state of the BLE scanner.
+type BLEAdapterHealth struct {
+	Healthy bool `json:"healthy"`
+	// LastAdvertisement is nil until the first advertisement.
+	LastAdvertisement *time.Time `json:"last_advertisement"`
+	// Restarts counts consecutive watchdog restarts without an advertisement.
+	Restarts int `json:"restarts"`
+	// BlueZDisconnects counts the times BlueZ left the bus under the scanner
+	// since the gateway started.
+	BlueZDisconnects int `json:"bluez_disconnects"`
+}
+
+// gatewayHealthTopic returns the topic of the gateway's health messages.
+func gatewayHealthTopic(gatewayID string) string {
+	return "gateways/" + gatewayID + "/health"
 }
 
 func NewClient(cfg config.Config) (*Client, error) {
@@ -53,13 +147,35 @@ func NewClient(cfg config.Config) (*Client, error) {
 	opts.SetPingTimeout(10 * time.Second)
 
 	// Callbacks keep internal state accurate
-	opts.SetOnConnectHandler(func(_ mqtt.Client) {
+	opts.SetOnConnectHandler(func(client mqtt.Client) {
+		c.mu.Lock()
+		c.takeover.connected(time.Now())
+		c.mu.Unlock()
 		c.setConnected(true)
-		slog.Info("mqtt connected", "broker", cfg.MQTTBroker, "port", cfg.MQTTPort)
+		slog.Info("mqtt connected", "broker", cfg.MQTTBroker, "port", cfg.MQTTPort, "client_id", cfg.MQTTClientID)
+		// Clean sessions drop subscriptions, so resubscribe on every connect.
+		if c.ackHandler != nil {
+			token := client.Subscribe(ackTopic, 1, c.ackCallback)
+			token.Wait()
+			if err := token.Error(); err != nil {
+				slog.Error("mqtt ack subscribe failed", "topic", ackTopic, "error", err)
+			}
+		}
+		if c.onConnected != nil {
+			go c.onConnected()
+		}
 	})
 
 	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		c.mu.Lock()
+		lasted, drops := c.takeover.lost(time.Now())
+		c.mu.Unlock()
 		c.setConnected(false)
+		if drops >= takeoverDrops {
+			slog.Error("mqtt connection keeps dropping right after connect; another client is probably using the same client ID",
+				"client_id", cfg.MQTTClientID, "consecutive_drops", drops, "connected_for", lasted.Round(time.Millisecond).String(), "error", err)
+			return
+		}
 		slog.Warn("mqtt connection lost", "error", err)
 	})
 
@@ -108,33 +224,98 @@ func (c *Client) Connect(ctx context.Context) error {
 	}
 }
 
-// PublishTelemetry publishes telemetry data to the station topic.
+// SetAckHandler registers fn for server acknowledgements. Call before Connect.
+func (c *Client) SetAckHandler(fn func(cloudpico_shared.Ack)) {
+	c.ackHandler = fn
+}
+
+// SetOnConnected registers fn to run (in its own goroutine) after every successful
+// connect, including reconnects. Call before Connect.
+func (c *Client) SetOnConnected(fn func()) {
+	c.onConnected = fn
+}
+
+func (c *Client) ackCallback(_ mqtt.Client, msg mqtt.Message) {
+	var ack cloudpico_shared.Ack
+	if err := json.Unmarshal(msg.Payload(), &ack); err != nil {
+		slog.Warn("mqtt: invalid ack", "topic", msg.Topic(), "error", err)
+		return
+	}
+	c.ackHandler(ack)
+}
+
+// PublishTelemetry publishes telemetry data on the configured telemetry
+// topic. When the topic contains {metric}, each metric of the reading is sent
+// as its own message carrying only that metric, along with the reading's
+// timestamp, sequence, battery, RSSI and metadata.
 func (c *Client) PublishTelemetry(telemetry cloudpico_shared.Telemetry) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("mqtt client not connected")
 	}
 
-	topic := fmt.Sprintf("stations/%s/telemetry", telemetry.StationID)
-
 	if telemetry.Timestamp.IsZero() {
 		telemetry.Timestamp = time.Now()
 	}
 
+	if !c.cfg.TelemetryTopic.Uses(topic.Metric) {
+		return c.publishTelemetry(telemetry, "")
+	}
+	parts := splitMetrics(telemetry)
+	if len(parts) == 0 {
+		return fmt.Errorf("telemetry for station %s has no metrics to publish", telemetry.StationID)
+	}
+	for _, p := range parts {
+		if err := c.publishTelemetry(p.telemetry, p.metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type metricTelemetry struct {
+	metric    string
+	telemetry cloudpico_shared.Telemetry
+}
+
+// splitMetrics returns a copy of t for each metric it has, in the order of
+// topic.Metrics, with the other metrics cleared.
+func splitMetrics(t cloudpico_shared.Telemetry) []metricTelemetry {
+	base := t
+	for _, name := range topic.Metrics {
+		*base.Metric(name) = nil
+	}
+	var parts []metricTelemetry
+	for _, name := range topic.Metrics {
+		if v := *t.Metric(name); v != nil {
+			p := base
+			*p.Metric(name) = v
+			parts = append(parts, metricTelemetry{metric: name, telemetry: p})
+		}
+	}
+	return parts
+}
+
+func (c *Client) publishTelemetry(telemetry cloudpico_shared.Telemetry, metric string) error {
+	name, err := c.cfg.TelemetryTopic.Expand(c.cfg.MQTTClientID, telemetry.StationID, metric)
+	if err != nil {
+		return fmt.Errorf("publish telemetry: %w", err)
+	}
+
 	data, err := json.Marshal(telemetry)
 	if err != nil {
 		return fmt.Errorf("marshal telemetry: %w", err)
 	}
 
-	token := c.client.Publish(topic, 1, false, data)
+	token := c.client.Publish(name, 1, false, data)
 	if !token.WaitTimeout(5 * time.Second) {
-		return fmt.Errorf("publish timeout for topic %s", topic)
+		return fmt.Errorf("publish timeout for topic %s", name)
 	}
 	if token.Error() != nil {
-		slog.Error("failed to publish telemetry", "topic", topic, "error", token.Error())
+		slog.Error("failed to publish telemetry", "topic", name, "error", token.Error())
 		return fmt.Errorf("publish telemetry: %w", token.Error())
 	}
 
-	slog.Debug("published telemetry", "topic", topic, "station_id", telemetry.StationID)
+	slog.Debug("published telemetry", "topic", name, "station_id", telemetry.StationID)
 	return nil
 }
 
@@ -173,6 +354,46 @@ func (c *Client) PublishStationHealth(health StationHealth) error {
 	return nil
 }
 
+// PublishGatewayHealth publishes the gateway's status, retained, so
+// subscribers get the latest one on subscribing.
+func (c *Client) PublishGatewayHealth(health GatewayHealth) error {
+	if health.Timestamp.IsZero() {
+		health.Timestamp = time.Now()
+	}
+	data, err := json.Marshal(health)
+	if err != nil {
+		return fmt.Errorf("marshal gateway health: %w", err)
+	}
+	topic := gatewayHealthTopic(health.GatewayID)
+	if err := c.publishRetained(topic, data); err != nil {
+		return fmt.Errorf("publish gateway health: %w", err)
+	}
+	slog.Debug("published gateway health", "topic", topic, "devices", len(health.Devices), "buffer_depth", health.BufferDepth)
+	return nil
+}
+
+// ClearGatewayHealth removes the retained health message of gatewayID, so a
+// gateway that shut down cleanly is not mistaken for a running one.
+func (c *Client) ClearGatewayHealth(gatewayID string) error {
+	if err := c.publishRetained(gatewayHealthTopic(gatewayID), nil); err != nil {
+		return fmt.Errorf("clear gateway health: %w", err)
+	}
+	return nil
+}
+
+// publishRetained publishes payload as the retained message of topic. An
+// empty payload deletes the retained message.
+func (c *Client) publishRetained(topic string, payload []byte) error {
+	if !c.IsConnected() {
+		return fmt.Errorf("mqtt client not connected")
+	}
+	token := c.client.Publish(topic, 1, true, payload)
+	if !token.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("publish timeout for topic %s", topic)
+	}
+	return token.Error()
+}
+
 // IsConnected returns whether the client is connected.
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
@@ -0,0 +1,53 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+func TestTakeoverDetector(t *testing.T) {
+	var d takeoverDetector
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, drops := d.lost(now); drops != 0 {
+		t.Errorf("lost before any connect: drops = %d; want 0", drops)
+	}
+	for want := 1; want <= takeoverDrops; want++ {
+		d.connected(now)
+		now = now.Add(2 * time.Second)
+		lasted, drops := d.lost(now)
+		if drops != want || lasted != 2*time.Second {
+			t.Errorf("quick drop %d: lost() = %v, %d; want 2s, %d", want, lasted, drops, want)
+		}
+		now = now.Add(5 * time.Second)
+	}
+
+	d.connected(now)
+	if _, drops := d.lost(now.Add(takeoverWindow)); drops != 0 {
+		t.Errorf("drop after a stable connection: drops = %d; want 0", drops)
+	}
+}
+
+func TestSplitMetrics(t *testing.T) {
+	temp, pressure, battery, seq := 21.5, 1013.0, 3.1, 7
+	in := cloudpico_shared.Telemetry{StationID: "garden", Temperature: &temp, Pressure: &pressure, Battery: &battery, Sequence: &seq}
+
+	parts := splitMetrics(in)
+	if len(parts) != 2 || parts[0].metric != "temperature" || parts[1].metric != "pressure" {
+		t.Fatalf("splitMetrics() = %+v; want temperature and pressure", parts)
+	}
+	for _, p := range parts {
+		got := p.telemetry
+		if got.StationID != "garden" || got.Battery != &battery || got.Sequence != &seq || got.Humidity != nil {
+			t.Errorf("%s part = %+v; want the reading's other fields kept", p.metric, got)
+		}
+	}
+	if parts[0].telemetry.Pressure != nil || parts[1].telemetry.Temperature != nil {
+		t.Errorf("parts carry each other's metrics: %+v", parts)
+	}
+	if in.Temperature != &temp || in.Pressure != &pressure {
+		t.Errorf("splitMetrics() changed its argument")
+	}
+}
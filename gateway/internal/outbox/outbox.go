@@ -0,0 +1,237 @@
+// Package outbox buffers published telemetry until the server acknowledges it,
+// and replays whatever is still unacknowledged after a disconnect.
+//
+// Readings are keyed by station ID and sequence number rather than timestamp:
+// the gateway's timestamps are estimates (the sensors have no clock), while
+// the server's ack carries the authoritative timestamp it stored. Pruning by
+// key means a replay interrupted half way only resends the readings the server
+// has not confirmed. A reading the client publishes as one message per metric
+// shares its key between the messages, so it stays buffered, with only the
+// unconfirmed metrics, until the server has acknowledged every one.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// Publisher sends telemetry upstream. Implemented by *mqtt.Client.
+type Publisher interface {
+	PublishTelemetry(t cloudpico_shared.Telemetry) error
+	IsConnected() bool
+}
+
+// Options configures an Outbox.
+type Options struct {
+	// MaxPending bounds the buffer; the oldest reading is dropped when it is full.
+	MaxPending int
+	// AckTimeout is how long a sent reading waits for an ack before it is resent.
+	AckTimeout time.Duration
+}
+
+type key struct {
+	stationID string
+	sequence  int
+}
+
+type entry struct {
+	telemetry cloudpico_shared.Telemetry
+	added     uint64 // insertion order
+	sentAt    time.Time
+}
+
+// Outbox tracks telemetry that has not yet been acknowledged by the server.
+type Outbox struct {
+	publisher Publisher
+	opts      Options
+	now       func() time.Time
+
+	mu      sync.Mutex
+	pending map[key]*entry
+	counter uint64
+
+	kick chan struct{}
+}
+
+func New(publisher Publisher, opts Options) *Outbox {
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 1000
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = 30 * time.Second
+	}
+	return &Outbox{
+		publisher: publisher,
+		opts:      opts,
+		now:       time.Now,
+		pending:   make(map[key]*entry),
+		kick:      make(chan struct{}, 1),
+	}
+}
+
+// PublishTelemetry buffers t and tries to send it immediately. Telemetry
+// without a Sequence cannot be acknowledged and is sent straight through.
+// A failed send is not an error: the reading stays buffered and is replayed later.
+func (o *Outbox) PublishTelemetry(t cloudpico_shared.Telemetry) error {
+	if t.Sequence == nil {
+		return o.publisher.PublishTelemetry(t)
+	}
+	k := key{stationID: t.StationID, sequence: *t.Sequence}
+
+	o.mu.Lock()
+	if _, exists := o.pending[k]; exists {
+		o.mu.Unlock()
+		return nil
+	}
+	if len(o.pending) >= o.opts.MaxPending {
+		o.dropOldestLocked()
+	}
+	o.counter++
+	e := &entry{telemetry: t, added: o.counter}
+	o.pending[k] = e
+	o.mu.Unlock()
+
+	if err := o.send(k, e); err != nil {
+		slog.Debug("outbox: send failed; reading buffered for replay",
+			"station_id", t.StationID, "sequence", *t.Sequence, "error", err)
+	}
+	return nil
+}
+
+// HandleAck removes the acknowledged metrics of a reading from the buffer, and
+// the reading once none is left. An ack without metrics covers the whole
+// reading.
+func (o *Outbox) HandleAck(ack cloudpico_shared.Ack) {
+	k := key{stationID: ack.StationID, sequence: ack.Sequence}
+	o.mu.Lock()
+	e, ok := o.pending[k]
+	if !ok {
+		o.mu.Unlock()
+		return
+	}
+	for _, name := range ack.Metrics {
+		if field := e.telemetry.Metric(name); field != nil {
+			*field = nil
+		}
+	}
+	remaining := e.telemetry.Metrics()
+	if len(ack.Metrics) == 0 || len(remaining) == 0 {
+		delete(o.pending, k)
+	}
+	estimate := e.telemetry.Timestamp
+	o.mu.Unlock()
+	if len(ack.Metrics) > 0 && len(remaining) > 0 {
+		slog.Debug("outbox: reading partly acknowledged",
+			"station_id", ack.StationID, "sequence", ack.Sequence, "pending_metrics", remaining)
+		return
+	}
+	if skew := ack.Timestamp.Sub(estimate); skew != 0 {
+		slog.Debug("outbox: server timestamp differs from estimate",
+			"station_id", ack.StationID, "sequence", ack.Sequence, "skew", skew.String())
+	}
+}
+
+// Len returns the number of unacknowledged readings.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Replay resends, oldest first, every buffered reading that was never sent or
+// whose ack has timed out. It stops at the first failed send and returns the
+// number of readings sent.
+func (o *Outbox) Replay() (int, error) {
+	if !o.publisher.IsConnected() {
+		return 0, nil
+	}
+	now := o.now()
+	o.mu.Lock()
+	type item struct {
+		k key
+		e *entry
+	}
+	var due []item
+	for k, e := range o.pending {
+		if e.sentAt.IsZero() || now.Sub(e.sentAt) >= o.opts.AckTimeout {
+			due = append(due, item{k, e})
+		}
+	}
+	o.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].e.added < due[j].e.added })
+
+	sent := 0
+	for _, it := range due {
+		if err := o.send(it.k, it.e); err != nil {
+			return sent, fmt.Errorf("replay %s/%d: %w", it.k.stationID, it.k.sequence, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Trigger asks Run to replay now, e.g. right after the MQTT connection is restored.
+func (o *Outbox) Trigger() {
+	select {
+	case o.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run replays pending readings every AckTimeout, and on Trigger, until ctx is canceled.
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.opts.AckTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.kick:
+		}
+		n, err := o.Replay()
+		if err != nil {
+			slog.Warn("outbox: replay interrupted", "sent", n, "pending", o.Len(), "error", err)
+		} else if n > 0 {
+			slog.Info("outbox: replayed readings", "sent", n, "pending", o.Len())
+		}
+	}
+}
+
+// send publishes what is left of e unless it has been acknowledged in the
+// meantime.
+func (o *Outbox) send(k key, e *entry) error {
+	o.mu.Lock()
+	_, stillPending := o.pending[k]
+	t := e.telemetry
+	o.mu.Unlock()
+	if !stillPending {
+		return nil
+	}
+	if err := o.publisher.PublishTelemetry(t); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	e.sentAt = o.now()
+	o.mu.Unlock()
+	return nil
+}
+
+func (o *Outbox) dropOldestLocked() {
+	var oldest key
+	var oldestAdded uint64
+	first := true
+	for k, e := range o.pending {
+		if first || e.added < oldestAdded {
+			oldest, oldestAdded, first = k, e.added, false
+		}
+	}
+	delete(o.pending, oldest)
+	slog.Warn("outbox: buffer full; dropping oldest reading", "station_id", oldest.stationID, "sequence", oldest.sequence)
+}
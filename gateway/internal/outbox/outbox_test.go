@@ -0,0 +1,190 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// fakePublisher records sent sequences and fails once failAfter sends have succeeded (if >= 0).
+type fakePublisher struct {
+	connected bool
+	sent      []int
+	last      cloudpico_shared.Telemetry
+	failAfter int
+}
+
+func (f *fakePublisher) PublishTelemetry(t cloudpico_shared.Telemetry) error {
+	if !f.connected || (f.failAfter >= 0 && len(f.sent) >= f.failAfter) {
+		return errors.New("not connected")
+	}
+	seq := -1
+	if t.Sequence != nil {
+		seq = *t.Sequence
+	}
+	f.sent = append(f.sent, seq)
+	f.last = t
+	return nil
+}
+
+func (f *fakePublisher) IsConnected() bool { return f.connected }
+
+func reading(seq int) cloudpico_shared.Telemetry {
+	return cloudpico_shared.Telemetry{StationID: "pico-1", Timestamp: time.Unix(int64(seq), 0), Sequence: &seq}
+}
+
+func ack(seq int) cloudpico_shared.Ack {
+	return cloudpico_shared.Ack{StationID: "pico-1", Sequence: seq, Timestamp: time.Unix(int64(seq), 0)}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOutbox(t *testing.T) {
+	t.Run("keeps readings until acked", func(t *testing.T) {
+		pub := &fakePublisher{connected: true, failAfter: -1}
+		o := New(pub, Options{})
+
+		for i := 1; i <= 3; i++ {
+			if err := o.PublishTelemetry(reading(i)); err != nil {
+				t.Fatalf("PublishTelemetry(%d) = %v", i, err)
+			}
+		}
+		o.HandleAck(ack(2))
+
+		if o.Len() != 2 {
+			t.Errorf("Len() = %d; want 2", o.Len())
+		}
+		if !equalInts(pub.sent, []int{1, 2, 3}) {
+			t.Errorf("sent = %v; want [1 2 3]", pub.sent)
+		}
+	})
+
+	t.Run("replays only unsent readings after partial replay", func(t *testing.T) {
+		pub := &fakePublisher{failAfter: -1}
+		o := New(pub, Options{AckTimeout: time.Hour})
+		for i := 1; i <= 4; i++ {
+			_ = o.PublishTelemetry(reading(i))
+		}
+		if len(pub.sent) != 0 {
+			t.Fatalf("sent while offline: %v", pub.sent)
+		}
+
+		pub.connected = true
+		pub.failAfter = 2
+		n, err := o.Replay()
+		if err == nil || n != 2 {
+			t.Fatalf("Replay() = %d, %v; want 2 and an error", n, err)
+		}
+		o.HandleAck(ack(1))
+
+		pub.failAfter = -1
+		if n, err := o.Replay(); err != nil || n != 2 {
+			t.Fatalf("second Replay() = %d, %v; want 2, nil", n, err)
+		}
+		if !equalInts(pub.sent, []int{1, 2, 3, 4}) {
+			t.Errorf("sent = %v; want each reading exactly once", pub.sent)
+		}
+	})
+
+	t.Run("resends after ack timeout", func(t *testing.T) {
+		pub := &fakePublisher{connected: true, failAfter: -1}
+		o := New(pub, Options{AckTimeout: time.Minute})
+		now := time.Unix(1000, 0)
+		o.now = func() time.Time { return now }
+		_ = o.PublishTelemetry(reading(1))
+
+		if n, _ := o.Replay(); n != 0 {
+			t.Errorf("Replay() before timeout sent %d; want 0", n)
+		}
+		now = now.Add(time.Minute)
+		if n, _ := o.Replay(); n != 1 {
+			t.Errorf("Replay() after timeout sent %d; want 1", n)
+		}
+		o.HandleAck(ack(1))
+		now = now.Add(time.Hour)
+		if n, _ := o.Replay(); n != 0 {
+			t.Errorf("Replay() after ack sent %d; want 0", n)
+		}
+	})
+
+	t.Run("keeps a split reading until every metric is acked", func(t *testing.T) {
+		pub := &fakePublisher{connected: true, failAfter: -1}
+		o := New(pub, Options{AckTimeout: time.Minute})
+		now := time.Unix(1000, 0)
+		o.now = func() time.Time { return now }
+		r := reading(1)
+		temp, humidity, pressure := 21.5, 55.0, 1013.0
+		r.Temperature, r.Humidity, r.Pressure = &temp, &humidity, &pressure
+		_ = o.PublishTelemetry(r)
+
+		// Only the temperature message was stored; the others were lost.
+		partial := ack(1)
+		partial.Metrics = []string{cloudpico_shared.MetricTemperature}
+		o.HandleAck(partial)
+		if o.Len() != 1 {
+			t.Fatalf("Len() after a partial ack = %d; want 1", o.Len())
+		}
+		now = now.Add(time.Minute)
+		if n, _ := o.Replay(); n != 1 {
+			t.Fatalf("Replay() after timeout sent %d; want 1", n)
+		}
+		if got := pub.last.Metrics(); len(got) != 2 || got[0] != cloudpico_shared.MetricHumidity || got[1] != cloudpico_shared.MetricPressure {
+			t.Errorf("resent metrics = %v; want [humidity pressure]", got)
+		}
+
+		rest := ack(1)
+		rest.Metrics = []string{cloudpico_shared.MetricHumidity, cloudpico_shared.MetricPressure}
+		o.HandleAck(rest)
+		if o.Len() != 0 {
+			t.Errorf("Len() after every metric was acked = %d; want 0", o.Len())
+		}
+	})
+
+	t.Run("ignores duplicate sequence", func(t *testing.T) {
+		pub := &fakePublisher{connected: true, failAfter: -1}
+		o := New(pub, Options{})
+		_ = o.PublishTelemetry(reading(7))
+		_ = o.PublishTelemetry(reading(7))
+
+		if len(pub.sent) != 1 {
+			t.Errorf("sent = %v; want one send", pub.sent)
+		}
+	})
+
+	t.Run("drops oldest when full", func(t *testing.T) {
+		pub := &fakePublisher{failAfter: -1}
+		o := New(pub, Options{MaxPending: 2, AckTimeout: time.Hour})
+		for i := 1; i <= 3; i++ {
+			_ = o.PublishTelemetry(reading(i))
+		}
+		pub.connected = true
+		_, _ = o.Replay()
+
+		if !equalInts(pub.sent, []int{2, 3}) {
+			t.Errorf("sent = %v; want [2 3]", pub.sent)
+		}
+	})
+
+	t.Run("passes through telemetry without sequence", func(t *testing.T) {
+		pub := &fakePublisher{connected: true, failAfter: -1}
+		o := New(pub, Options{})
+		if err := o.PublishTelemetry(cloudpico_shared.Telemetry{StationID: "pico-1"}); err != nil {
+			t.Fatalf("PublishTelemetry() = %v", err)
+		}
+		if o.Len() != 0 || len(pub.sent) != 1 {
+			t.Errorf("Len() = %d, sent = %v; want 0 buffered, 1 sent", o.Len(), pub.sent)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package sensor
+
+import (
+	"periph.io/x/conn/v3/i2c"
+	"periph.io/x/conn/v3/physic"
+	"periph.io/x/devices/v3/bmxx80"
+)
+
+// bmxx80Device adapts the periph BME280/BMP280 driver. The BMP280 has no
+// humidity sensor, so humidity is only reported for the BME280.
+type bmxx80Device struct {
+	dev         *bmxx80.Dev
+	hasHumidity bool
+}
+
+func openBME280(bus i2c.Bus, addr uint16) (Device, error) {
+	return openBMxx80(bus, addr, true)
+}
+
+func openBMP280(bus i2c.Bus, addr uint16) (Device, error) {
+	return openBMxx80(bus, addr, false)
+}
+
+func openBMxx80(bus i2c.Bus, addr uint16, hasHumidity bool) (Device, error) {
+	dev, err := bmxx80.NewI2C(bus, addr, &bmxx80.DefaultOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &bmxx80Device{dev: dev, hasHumidity: hasHumidity}, nil
+}
+
+func (d *bmxx80Device) Sense() (Reading, error) {
+	var env physic.Env
+	if err := d.dev.Sense(&env); err != nil {
+		return Reading{}, err
+	}
+	temp := env.Temperature.Celsius()
+	press := float64(env.Pressure) / float64(100*physic.Pascal)
+	r := Reading{Temperature: &temp, Pressure: &press}
+	if d.hasHumidity {
+		hum := float64(env.Humidity) / float64(physic.PercentRH)
+		r.Humidity = &hum
+	}
+	return r, nil
+}
+
+func (d *bmxx80Device) Halt() error {
+	return d.dev.Halt()
+}
@@ -0,0 +1,295 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+
+	"periph.io/x/conn/v3/i2c"
+	"periph.io/x/conn/v3/i2c/i2creg"
+	"periph.io/x/host/v3"
+)
+
+// Publisher sends telemetry upstream. Implemented by *mqtt.Client and *outbox.Outbox.
+type Publisher interface {
+	PublishTelemetry(t cloudpico_shared.Telemetry) error
+}
+
+// Options configures a Poller.
+type Options struct {
+	// GatewayVersion is reported in the metadata of every published reading.
+	GatewayVersion string
+	// RetryDelay is the wait after the first failed read; it doubles with each
+	// consecutive failure up to MaxRetryDelay. A successful read restores the
+	// sensor's normal interval.
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+	// ReinitAfter is the number of consecutive failures after which the sensor
+	// is reported unhealthy and its device is halted and opened again.
+	ReinitAfter int
+	// OnHealthChange, if set, is called whenever a sensor becomes healthy or
+	// unhealthy. It runs on the sensor's polling goroutine.
+	OnHealthChange func(Health)
+}
+
+// Health is the polling state of one sensor.
+type Health struct {
+	Name      string
+	StationID string
+	Healthy   bool
+	// ConsecutiveFailures counts open and read failures since the last good read.
+	ConsecutiveFailures int
+	LastError           string
+	LastSuccess         time.Time
+}
+
+// Poller reads every defined sensor on its own interval and publishes the readings.
+type Poller struct {
+	defs      []Definition
+	publisher Publisher
+	opts      Options
+
+	drivers map[string]Driver
+	openBus func(name string) (i2c.BusCloser, error)
+	now     func() time.Time
+
+	mu     sync.Mutex
+	health map[string]Health
+}
+
+func NewPoller(publisher Publisher, defs []Definition, opts Options) *Poller {
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = time.Second
+	}
+	if opts.MaxRetryDelay <= 0 {
+		opts.MaxRetryDelay = 5 * time.Minute
+	}
+	if opts.ReinitAfter <= 0 {
+		opts.ReinitAfter = 3
+	}
+	health := make(map[string]Health, len(defs))
+	for _, def := range defs {
+		health[def.Name] = Health{Name: def.Name, StationID: def.StationID}
+	}
+	return &Poller{
+		defs:      defs,
+		publisher: publisher,
+		opts:      opts,
+		drivers:   drivers,
+		openBus:   openPeriphBus,
+		now:       time.Now,
+		health:    health,
+	}
+}
+
+var hostInit = sync.OnceValue(func() error {
+	_, err := host.Init()
+	return err
+})
+
+func openPeriphBus(name string) (i2c.BusCloser, error) {
+	if err := hostInit(); err != nil {
+		return nil, fmt.Errorf("init periph host: %w", err)
+	}
+	return i2creg.Open(name)
+}
+
+// Health returns the current state of every sensor, in definition order.
+func (p *Poller) Health() []Health {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Health, 0, len(p.defs))
+	for _, def := range p.defs {
+		out = append(out, p.health[def.Name])
+	}
+	return out
+}
+
+// Run polls all sensors concurrently until ctx is canceled, then halts the
+// devices, closes the buses and returns nil. Open, read and publish failures
+// are handled per sensor and never stop the others.
+func (p *Poller) Run(ctx context.Context) error {
+	buses := &busSet{open: p.openBus, buses: make(map[string]i2c.BusCloser)}
+	defer buses.closeAll()
+
+	var wg sync.WaitGroup
+	for _, def := range p.defs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.poll(ctx, def, buses)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// poll reads one sensor immediately and then every def.Interval. After a
+// failed open or read it retries with exponential backoff, and every
+// ReinitAfter consecutive failures it halts the device so the next attempt
+// opens it from scratch.
+func (p *Poller) poll(ctx context.Context, def Definition, buses *busSet) {
+	log := slog.With("sensor", def.Name, "bus", def.Bus, "address", fmt.Sprintf("0x%02x", def.Address))
+	var dev Device
+	halt := func() {
+		if dev == nil {
+			return
+		}
+		if err := dev.Halt(); err != nil {
+			log.Warn("sensor: halt failed", "error", err)
+		}
+		dev = nil
+	}
+	defer halt()
+
+	seq := 0
+	failures := 0
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if dev == nil {
+			d, err := p.open(def, buses)
+			if err == nil {
+				log.Info("sensor: opened", "driver", def.Driver, "station_id", def.StationID)
+				dev = d
+			} else {
+				failures++
+				timer.Reset(p.fail(log, def, failures, fmt.Errorf("open: %w", err)))
+				continue
+			}
+		}
+
+		r, err := dev.Sense()
+		if err != nil {
+			failures++
+			if failures%p.opts.ReinitAfter == 0 {
+				log.Info("sensor: re-initializing device", "consecutive_failures", failures)
+				halt()
+			}
+			timer.Reset(p.fail(log, def, failures, fmt.Errorf("sense: %w", err)))
+			continue
+		}
+
+		failures = 0
+		p.setHealth(def, func(h *Health) {
+			h.Healthy = true
+			h.ConsecutiveFailures = 0
+			h.LastSuccess = p.now()
+		})
+		seq++
+		// A failed publish is not the sensor's fault, so it keeps its schedule.
+		if err := p.publish(def, r, seq); err != nil {
+			log.Warn("sensor: publish failed", "sequence", seq, "error", err)
+		}
+		timer.Reset(def.Interval)
+	}
+}
+
+// fail records a failed attempt and returns how long to wait before the next one.
+func (p *Poller) fail(log *slog.Logger, def Definition, failures int, err error) time.Duration {
+	delay := p.opts.MaxRetryDelay
+	if shift := failures - 1; shift < 32 && p.opts.RetryDelay<<shift < delay {
+		delay = p.opts.RetryDelay << shift
+	}
+	log.Warn("sensor: poll failed", "error", err, "consecutive_failures", failures, "retry_in", delay.String())
+	p.setHealth(def, func(h *Health) {
+		h.ConsecutiveFailures = failures
+		h.LastError = err.Error()
+		if failures >= p.opts.ReinitAfter {
+			h.Healthy = false
+		}
+	})
+	return delay
+}
+
+// setHealth applies update to def's health and reports the sensor becoming
+// healthy, or unhealthy on reaching ReinitAfter consecutive failures.
+func (p *Poller) setHealth(def Definition, update func(*Health)) {
+	p.mu.Lock()
+	h := p.health[def.Name]
+	wasHealthy := h.Healthy
+	update(&h)
+	p.health[def.Name] = h
+	p.mu.Unlock()
+
+	if h.Healthy == wasHealthy && (h.Healthy || h.ConsecutiveFailures != p.opts.ReinitAfter) {
+		return
+	}
+	slog.Info("sensor: health changed", "sensor", def.Name, "station_id", def.StationID,
+		"healthy", h.Healthy, "consecutive_failures", h.ConsecutiveFailures)
+	if p.opts.OnHealthChange != nil {
+		p.opts.OnHealthChange(h)
+	}
+}
+
+func (p *Poller) open(def Definition, buses *busSet) (Device, error) {
+	driver, ok := p.drivers[def.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q", def.Driver)
+	}
+	bus, err := buses.get(def.Bus)
+	if err != nil {
+		return nil, err
+	}
+	return driver(bus, def.Address)
+}
+
+func (p *Poller) publish(def Definition, r Reading, seq int) error {
+	t := cloudpico_shared.Telemetry{
+		StationID:   def.StationID,
+		Timestamp:   p.now(),
+		Temperature: r.Temperature,
+		Humidity:    r.Humidity,
+		Pressure:    r.Pressure,
+		Sequence:    &seq,
+		Metadata:    &cloudpico_shared.Metadata{GatewayVersion: p.opts.GatewayVersion},
+	}
+	if err := p.publisher.PublishTelemetry(t); err != nil {
+		return err
+	}
+	slog.Debug("sensor: reading published", "sensor", def.Name, "station_id", def.StationID, "sequence", seq)
+	return nil
+}
+
+// busSet opens each I2C bus once and shares it between the sensors on it;
+// periph serializes transactions on a bus.
+type busSet struct {
+	open func(name string) (i2c.BusCloser, error)
+
+	mu    sync.Mutex
+	buses map[string]i2c.BusCloser
+}
+
+func (b *busSet) get(name string) (i2c.Bus, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if bus, ok := b.buses[name]; ok {
+		return bus, nil
+	}
+	bus, err := b.open(name)
+	if err != nil {
+		return nil, fmt.Errorf("open i2c bus %q: %w", name, err)
+	}
+	b.buses[name] = bus
+	return bus, nil
+}
+
+func (b *busSet) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, bus := range b.buses {
+		if err := bus.Close(); err != nil {
+			slog.Warn("sensor: close i2c bus failed", "bus", name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+
+	"periph.io/x/conn/v3/i2c"
+	"periph.io/x/conn/v3/physic"
+)
+
+type fakePublisher struct {
+	mu   sync.Mutex
+	sent []cloudpico_shared.Telemetry
+}
+
+func (f *fakePublisher) PublishTelemetry(t cloudpico_shared.Telemetry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, t)
+	return nil
+}
+
+func (f *fakePublisher) byStation() map[string][]cloudpico_shared.Telemetry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := make(map[string][]cloudpico_shared.Telemetry)
+	for _, t := range f.sent {
+		m[t.StationID] = append(m[t.StationID], t)
+	}
+	return m
+}
+
+type fakeBus struct {
+	name string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+var _ i2c.BusCloser = (*fakeBus)(nil)
+
+func (b *fakeBus) String() string                    { return b.name }
+func (b *fakeBus) Tx(addr uint16, w, r []byte) error { return nil }
+func (b *fakeBus) SetSpeed(f physic.Frequency) error { return nil }
+
+func (b *fakeBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+func (b *fakeBus) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+type fakeDevice struct {
+	temp    float64
+	failing bool
+	// failNext fails that many Sense calls before succeeding.
+	failNext int
+
+	mu     sync.Mutex
+	halted bool
+}
+
+func (d *fakeDevice) Sense() (Reading, error) {
+	if d.failing || d.failNext > 0 {
+		d.failNext--
+		return Reading{}, errors.New("i2c: remote i/o error")
+	}
+	temp := d.temp
+	return Reading{Temperature: &temp}, nil
+}
+
+func (d *fakeDevice) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.halted = true
+	return nil
+}
+
+func (d *fakeDevice) isHalted() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.halted
+}
+
+func TestPoller(t *testing.T) {
+	pub := &fakePublisher{}
+	healthy := &fakeDevice{temp: 21.5}
+	flaky := &fakeDevice{failing: true}
+	var (
+		busMu sync.Mutex
+		buses []*fakeBus
+	)
+
+	p := NewPoller(pub, []Definition{
+		{Name: "healthy", Bus: "1", Address: 0x76, Driver: "fake", StationID: "home", Interval: 5 * time.Millisecond},
+		{Name: "flaky", Bus: "1", Address: 0x77, Driver: "fake", StationID: "attic", Interval: 5 * time.Millisecond},
+		{Name: "missing", Bus: "9", Address: 0x76, Driver: "fake", StationID: "garage", Interval: 5 * time.Millisecond},
+	}, Options{GatewayVersion: "1.2.3", RetryDelay: time.Millisecond, MaxRetryDelay: 5 * time.Millisecond, ReinitAfter: 2})
+	p.drivers = map[string]Driver{"fake": func(bus i2c.Bus, addr uint16) (Device, error) {
+		if addr == 0x76 {
+			return healthy, nil
+		}
+		return flaky, nil
+	}}
+	p.openBus = func(name string) (i2c.BusCloser, error) {
+		if name == "9" {
+			return nil, errors.New("no such bus")
+		}
+		busMu.Lock()
+		defer busMu.Unlock()
+		b := &fakeBus{name: name}
+		buses = append(buses, b)
+		return b, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(pub.byStation()["home"]) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() = %v; want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+
+	sent := pub.byStation()
+	home := sent["home"]
+	if len(home) < 3 {
+		t.Fatalf("published %d readings for home; want at least 3", len(home))
+	}
+	for i, r := range home {
+		if r.Sequence == nil || *r.Sequence != i+1 {
+			t.Errorf("home[%d].Sequence = %v; want %d", i, r.Sequence, i+1)
+		}
+		if r.Temperature == nil || *r.Temperature != 21.5 {
+			t.Errorf("home[%d].Temperature = %v; want 21.5", i, r.Temperature)
+		}
+		if r.Metadata == nil || r.Metadata.GatewayVersion != "1.2.3" {
+			t.Errorf("home[%d].Metadata = %+v; want gateway version 1.2.3", i, r.Metadata)
+		}
+	}
+	if len(sent["attic"]) != 0 || len(sent["garage"]) != 0 {
+		t.Errorf("published readings for failing sensors: %v", sent)
+	}
+	if !healthy.isHalted() || !flaky.isHalted() {
+		t.Errorf("halted = %v, %v; want both devices halted", healthy.isHalted(), flaky.isHalted())
+	}
+	if len(buses) != 1 || buses[0].name != "1" || !buses[0].isClosed() {
+		t.Errorf("opened %d buses; want bus 1 opened once and closed", len(buses))
+	}
+}
+
+func TestPoller_recovers(t *testing.T) {
+	pub := &fakePublisher{}
+	dev := &fakeDevice{temp: 18, failNext: 5}
+	var (
+		mu      sync.Mutex
+		opens   int
+		changes []Health
+	)
+	p := NewPoller(pub, []Definition{
+		{Name: "indoor", Address: 0x76, Driver: "fake", StationID: "home", Interval: time.Hour},
+	}, Options{
+		RetryDelay:    time.Millisecond,
+		MaxRetryDelay: 2 * time.Millisecond,
+		ReinitAfter:   2,
+		OnHealthChange: func(h Health) {
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, h)
+		},
+	})
+	p.drivers = map[string]Driver{"fake": func(bus i2c.Bus, addr uint16) (Device, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		opens++
+		return dev, nil
+	}}
+	p.openBus = func(name string) (i2c.BusCloser, error) { return &fakeBus{name: name}, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = p.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(pub.byStation()["home"]) < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := len(pub.byStation()["home"]); got != 1 {
+		t.Fatalf("published %d readings; want 1", got)
+	}
+	// Five failures with ReinitAfter 2: the device is reopened after the 2nd and 4th.
+	if opens != 3 {
+		t.Errorf("opened device %d times; want 3", opens)
+	}
+	if len(changes) != 2 || changes[0].Healthy || changes[0].ConsecutiveFailures != 2 || !changes[1].Healthy {
+		t.Errorf("health changes = %+v; want unhealthy after 2 failures, then healthy", changes)
+	}
+	h := p.Health()
+	if len(h) != 1 || !h[0].Healthy || h[0].ConsecutiveFailures != 0 || h[0].LastSuccess.IsZero() ||
+		h[0].LastError == "" {
+		t.Errorf("Health() = %+v; want healthy with last success and last error", h)
+	}
+}
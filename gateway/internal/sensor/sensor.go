@@ -0,0 +1,161 @@
+// Package sensor polls environmental sensors wired to the gateway's own I2C
+// buses and publishes their readings as telemetry.
+//
+// Each sensor is described by a Definition (bus, address, driver, station ID,
+// interval). Definitions are polled concurrently and independently: a sensor
+// that fails to open or read is logged and retried, and never stops the others.
+package sensor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"periph.io/x/conn/v3/i2c"
+)
+
+// Reading is a single sample. Nil fields are not measured by the device.
+type Reading struct {
+	Temperature *float64 // °C
+	Humidity    *float64 // %
+	Pressure    *float64 // hPa
+}
+
+// Device is an opened sensor.
+type Device interface {
+	Sense() (Reading, error)
+	Halt() error
+}
+
+// Driver opens the device at addr on bus.
+type Driver func(bus i2c.Bus, addr uint16) (Device, error)
+
+// drivers maps the driver names accepted in definitions to their constructors.
+var drivers = map[string]Driver{
+	"bme280": openBME280,
+	"bmp280": openBMP280,
+}
+
+// Definition describes one sensor to poll.
+type Definition struct {
+	Name string
+	// Bus is the I2C bus name or number as understood by periph (e.g. "1" or
+	// "/dev/i2c-1"); empty selects the first bus found.
+	Bus       string
+	Address   uint16
+	Driver    string
+	StationID string
+	Interval  time.Duration
+}
+
+// DefaultDefinitions returns the single BME280 sensor configured through the
+// BME280_ADDRESS, DEVICE_STATION_ID and SENSOR_POLL_INTERVAL settings.
+func DefaultDefinitions(address uint16, stationID string, interval time.Duration) []Definition {
+	return []Definition{{
+		Name:      "bme280",
+		Address:   address,
+		Driver:    "bme280",
+		StationID: stationID,
+		Interval:  interval,
+	}}
+}
+
+type definitionJSON struct {
+	Name      string `json:"name"`
+	Bus       string `json:"bus"`
+	Address   string `json:"address"`
+	Driver    string `json:"driver"`
+	StationID string `json:"station_id"`
+	Interval  string `json:"interval"`
+}
+
+// LoadDefinitions reads sensor definitions from a JSON file; see ParseDefinitions.
+func LoadDefinitions(path string, defaultInterval time.Duration) ([]Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sensors file: %w", err)
+	}
+	defs, err := ParseDefinitions(data, defaultInterval)
+	if err != nil {
+		return nil, fmt.Errorf("sensors file %s: %w", path, err)
+	}
+	return defs, nil
+}
+
+// ParseDefinitions parses a JSON array of sensors:
+//
+//	[{"name": "indoor", "bus": "1", "address": "0x76", "driver": "bme280",
+//	  "station_id": "home", "interval": "30s"}]
+//
+// bus and interval are optional; interval defaults to defaultInterval.
+func ParseDefinitions(data []byte, defaultInterval time.Duration) ([]Definition, error) {
+	var raw []definitionJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse sensors: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("no sensors defined")
+	}
+	defs := make([]Definition, 0, len(raw))
+	names := make(map[string]bool, len(raw))
+	devices := make(map[string]string, len(raw))
+	for i, r := range raw {
+		def, err := r.definition(defaultInterval)
+		if err != nil {
+			return nil, fmt.Errorf("sensor %d (%s): %w", i, r.Name, err)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("sensor %d: duplicate name %q", i, def.Name)
+		}
+		names[def.Name] = true
+		device := fmt.Sprintf("%s@0x%02x", def.Bus, def.Address)
+		if other, ok := devices[device]; ok {
+			return nil, fmt.Errorf("sensor %q: bus %q address 0x%02x already used by %q", def.Name, def.Bus, def.Address, other)
+		}
+		devices[device] = def.Name
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (r definitionJSON) definition(defaultInterval time.Duration) (Definition, error) {
+	def := Definition{
+		Name:      strings.TrimSpace(r.Name),
+		Bus:       strings.TrimSpace(r.Bus),
+		Driver:    strings.ToLower(strings.TrimSpace(r.Driver)),
+		StationID: strings.TrimSpace(r.StationID),
+		Interval:  defaultInterval,
+	}
+	if def.Name == "" {
+		return Definition{}, fmt.Errorf("name is required")
+	}
+	if _, ok := drivers[def.Driver]; !ok {
+		return Definition{}, fmt.Errorf("unknown driver %q", r.Driver)
+	}
+	if def.StationID == "" {
+		return Definition{}, fmt.Errorf("station_id is required")
+	}
+	addr, err := strconv.ParseUint(strings.TrimSpace(r.Address), 0, 16)
+	if err != nil {
+		return Definition{}, fmt.Errorf("invalid address %q: %w", r.Address, err)
+	}
+	// 7-bit addresses outside the reserved ranges.
+	if addr < 0x08 || addr > 0x77 {
+		return Definition{}, fmt.Errorf("address 0x%02x out of range 0x08-0x77", addr)
+	}
+	def.Address = uint16(addr)
+	if s := strings.TrimSpace(r.Interval); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return Definition{}, fmt.Errorf("invalid interval %q: %w", s, err)
+		}
+		def.Interval = d
+	}
+	if def.Interval <= 0 {
+		return Definition{}, fmt.Errorf("interval must be positive, got %v", def.Interval)
+	}
+	return def, nil
+}
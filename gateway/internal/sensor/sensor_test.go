@@ -0,0 +1,63 @@
+package sensor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDefinitions(t *testing.T) {
+	t.Run("parses definitions with defaults", func(t *testing.T) {
+		defs, err := ParseDefinitions([]byte(`[
+			{"name": "indoor", "bus": "1", "address": "0x76", "driver": "BME280", "station_id": "home", "interval": "30s"},
+			{"name": "attic", "address": "119", "driver": "bmp280", "station_id": "attic"}
+		]`), time.Minute)
+		if err != nil {
+			t.Fatalf("ParseDefinitions() = %v", err)
+		}
+		if len(defs) != 2 {
+			t.Fatalf("got %d definitions; want 2", len(defs))
+		}
+		want := Definition{Name: "indoor", Bus: "1", Address: 0x76, Driver: "bme280", StationID: "home", Interval: 30 * time.Second}
+		if defs[0] != want {
+			t.Errorf("defs[0] = %+v; want %+v", defs[0], want)
+		}
+		want = Definition{Name: "attic", Address: 0x77, Driver: "bmp280", StationID: "attic", Interval: time.Minute}
+		if defs[1] != want {
+			t.Errorf("defs[1] = %+v; want %+v", defs[1], want)
+		}
+	})
+
+	t.Run("same address on different buses", func(t *testing.T) {
+		_, err := ParseDefinitions([]byte(`[
+			{"name": "a", "bus": "1", "address": "0x76", "driver": "bme280", "station_id": "a"},
+			{"name": "b", "bus": "2", "address": "0x76", "driver": "bme280", "station_id": "b"}
+		]`), time.Minute)
+		if err != nil {
+			t.Errorf("ParseDefinitions() = %v; want nil", err)
+		}
+	})
+
+	for name, input := range map[string]string{
+		"invalid json":      `{`,
+		"empty list":        `[]`,
+		"missing name":      `[{"address": "0x76", "driver": "bme280", "station_id": "a"}]`,
+		"unknown driver":    `[{"name": "a", "address": "0x76", "driver": "dht22", "station_id": "a"}]`,
+		"missing station":   `[{"name": "a", "address": "0x76", "driver": "bme280"}]`,
+		"bad address":       `[{"name": "a", "address": "0xZZ", "driver": "bme280", "station_id": "a"}]`,
+		"reserved address":  `[{"name": "a", "address": "0x78", "driver": "bme280", "station_id": "a"}]`,
+		"bad interval":      `[{"name": "a", "address": "0x76", "driver": "bme280", "station_id": "a", "interval": "soon"}]`,
+		"negative interval": `[{"name": "a", "address": "0x76", "driver": "bme280", "station_id": "a", "interval": "-1s"}]`,
+		"duplicate name": `[
+			{"name": "a", "address": "0x76", "driver": "bme280", "station_id": "a"},
+			{"name": "a", "address": "0x77", "driver": "bme280", "station_id": "a"}]`,
+		"duplicate device": `[
+			{"name": "a", "bus": "1", "address": "0x76", "driver": "bme280", "station_id": "a"},
+			{"name": "b", "bus": "1", "address": "0x76", "driver": "bmp280", "station_id": "b"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseDefinitions([]byte(input), time.Minute); err == nil {
+				t.Errorf("ParseDefinitions(%s) = nil error; want error", input)
+			}
+		})
+	}
+}
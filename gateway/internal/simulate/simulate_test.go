@@ -0,0 +1,66 @@
+package simulate
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+type fakePublisher struct {
+	mu   sync.Mutex
+	sent []cloudpico_shared.Telemetry
+}
+
+func (f *fakePublisher) PublishTelemetry(t cloudpico_shared.Telemetry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, t)
+	return nil
+}
+
+func (f *fakePublisher) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.sent)
+}
+
+func TestSimulator_Run(t *testing.T) {
+	pub := &fakePublisher{}
+	s := New(pub, []string{"sim-a", "sim-b"}, time.Millisecond, "1.2.3")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Run(ctx) }()
+	deadline := time.Now().Add(2 * time.Second)
+	for pub.len() < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() = %v; want nil", err)
+	}
+
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	if len(pub.sent) < 4 {
+		t.Fatalf("published %d readings; want at least 4", len(pub.sent))
+	}
+	for i, r := range pub.sent[:4] {
+		wantStation := []string{"sim-a", "sim-b"}[i%2]
+		if r.StationID != wantStation || r.Sequence == nil || *r.Sequence != i/2+1 {
+			t.Errorf("sent[%d] = %s/%v; want %s/%d", i, r.StationID, r.Sequence, wantStation, i/2+1)
+		}
+		if r.Temperature == nil || r.Humidity == nil || r.Pressure == nil {
+			t.Fatalf("sent[%d] missing values: %+v", i, r)
+		}
+		if *r.Humidity < 0 || *r.Humidity > 100 || *r.Pressure < 950 || *r.Pressure > 1080 {
+			t.Errorf("sent[%d] values out of range: humidity %v, pressure %v", i, *r.Humidity, *r.Pressure)
+		}
+		if r.Metadata == nil || r.Metadata.GatewayVersion != "1.2.3" {
+			t.Errorf("sent[%d].Metadata = %+v; want gateway version 1.2.3", i, r.Metadata)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package stationmap assigns telemetry sources to server stations.
+//
+// A source is "<kind>:<id>": "ble:<device id>" for a Pico sensor (the 8-digit
+// hex device ID from its advertisement, e.g. "ble:0A1B2C3D") and
+// "i2c:<sensor name>" for a sensor on the gateway's own I2C bus. A mapped
+// source overrides the station ID a BLE rule or sensor definition would
+// otherwise give it.
+package stationmap
+
+import (
+	"cloudpico-gateway/internal/api"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source kinds.
+const (
+	KindBLE = "ble"
+	KindI2C = "i2c"
+)
+
+// Map is a source to station ID mapping. The zero value maps nothing.
+type Map struct {
+	stations map[string]string
+}
+
+type entryJSON struct {
+	Source    string `json:"source"`
+	StationID string `json:"station_id"`
+}
+
+// Load reads a mapping from a JSON file; see Parse.
+func Load(path string) (Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Map{}, fmt.Errorf("read station map: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return Map{}, fmt.Errorf("station map %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes a JSON array of mappings:
+//
+//	[{"source": "ble:0A1B2C3D", "station_id": "garden"},
+//	 {"source": "i2c:indoor", "station_id": "home"}]
+func Parse(data []byte) (Map, error) {
+	var raw []entryJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Map{}, fmt.Errorf("parse station map: %w", err)
+	}
+	m := Map{stations: make(map[string]string, len(raw))}
+	for i, e := range raw {
+		kind, id, err := parseSource(e.Source)
+		if err != nil {
+			return Map{}, fmt.Errorf("entry %d: %w", i, err)
+		}
+		stationID := strings.TrimSpace(e.StationID)
+		if stationID == "" {
+			return Map{}, fmt.Errorf("entry %d (%s): station_id is required", i, e.Source)
+		}
+		key := kind + ":" + id
+		if _, dup := m.stations[key]; dup {
+			return Map{}, fmt.Errorf("entry %d: duplicate source %q", i, key)
+		}
+		m.stations[key] = stationID
+	}
+	return m, nil
+}
+
+func parseSource(s string) (kind, id string, err error) {
+	kind, id, ok := strings.Cut(strings.TrimSpace(s), ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	id = strings.TrimSpace(id)
+	if !ok || id == "" {
+		return "", "", fmt.Errorf("invalid source %q (want kind:id)", s)
+	}
+	switch kind {
+	case KindBLE:
+		id = strings.ToUpper(id)
+	case KindI2C:
+	default:
+		return "", "", fmt.Errorf("invalid source %q: unknown kind %q (allowed: ble, i2c)", s, kind)
+	}
+	return kind, id, nil
+}
+
+// Station returns the station mapped to the source, if any. BLE device IDs
+// are matched case-insensitively.
+func (m Map) Station(kind, id string) (string, bool) {
+	if kind == KindBLE {
+		id = strings.ToUpper(id)
+	}
+	stationID, ok := m.stations[kind+":"+id]
+	return stationID, ok
+}
+
+// Resolve returns the station mapped to the source, or fallback.
+func (m Map) Resolve(kind, id, fallback string) string {
+	if stationID, ok := m.Station(kind, id); ok {
+		return stationID
+	}
+	return fallback
+}
+
+// StationIDs returns every mapped station ID, without duplicates.
+func (m Map) StationIDs() []string {
+	seen := make(map[string]bool, len(m.stations))
+	var ids []string
+	for _, id := range m.stations {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// StationLister lists the server's stations. Implemented by *api.Client.
+type StationLister interface {
+	Stations(ctx context.Context) ([]api.Station, error)
+}
+
+// Unknown returns the station IDs in ids that match neither the ID nor the
+// name of a server station. Telemetry may address a station either way.
+func Unknown(ctx context.Context, lister StationLister, ids []string) ([]string, error) {
+	stations, err := lister.Stations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, 2*len(stations))
+	for _, s := range stations {
+		known[s.ID] = true
+		known[s.Name] = true
+	}
+	var unknown []string
+	for _, id := range ids {
+		if !known[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	return unknown, nil
+}
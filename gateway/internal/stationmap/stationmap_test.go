@@ -0,0 +1,87 @@
+package stationmap
+
+import (
+	"cloudpico-gateway/internal/api"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("resolves mapped sources", func(t *testing.T) {
+		m, err := Parse([]byte(`[
+			{"source": "ble:0a1b2c3d", "station_id": "garden"},
+			{"source": "I2C:indoor", "station_id": "home"},
+			{"source": "i2c:attic", "station_id": "home"}
+		]`))
+		if err != nil {
+			t.Fatalf("Parse() = %v", err)
+		}
+		tests := []struct {
+			kind, id, fallback, want string
+		}{
+			{KindBLE, "0A1B2C3D", "rule-station", "garden"},
+			{KindBLE, "0a1b2c3d", "", "garden"},
+			{KindBLE, "FFFFFFFF", "rule-station", "rule-station"},
+			{KindI2C, "indoor", "default", "home"},
+			{KindI2C, "INDOOR", "default", "default"},
+		}
+		for _, tt := range tests {
+			if got := m.Resolve(tt.kind, tt.id, tt.fallback); got != tt.want {
+				t.Errorf("Resolve(%s, %s, %q) = %q; want %q", tt.kind, tt.id, tt.fallback, got, tt.want)
+			}
+		}
+		ids := m.StationIDs()
+		slices.Sort(ids)
+		if !slices.Equal(ids, []string{"garden", "home"}) {
+			t.Errorf("StationIDs() = %v; want [garden home]", ids)
+		}
+	})
+
+	t.Run("zero map resolves to fallback", func(t *testing.T) {
+		if got := (Map{}).Resolve(KindBLE, "0A1B2C3D", "x"); got != "x" {
+			t.Errorf("Resolve() = %q; want x", got)
+		}
+	})
+
+	for name, input := range map[string]string{
+		"invalid json":     `{`,
+		"missing kind":     `[{"source": "0A1B2C3D", "station_id": "a"}]`,
+		"unknown kind":     `[{"source": "usb:1", "station_id": "a"}]`,
+		"empty id":         `[{"source": "i2c:", "station_id": "a"}]`,
+		"missing station":  `[{"source": "i2c:indoor"}]`,
+		"duplicate source": `[{"source": "ble:0a1b2c3d", "station_id": "a"}, {"source": "ble:0A1B2C3D", "station_id": "b"}]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse([]byte(input)); err == nil {
+				t.Errorf("Parse(%s) = nil error; want error", input)
+			}
+		})
+	}
+}
+
+type fakeLister struct {
+	stations []api.Station
+	err      error
+}
+
+func (f fakeLister) Stations(ctx context.Context) ([]api.Station, error) {
+	return f.stations, f.err
+}
+
+func TestUnknown(t *testing.T) {
+	lister := fakeLister{stations: []api.Station{{ID: "1", Name: "home"}, {ID: "2", Name: "garden"}}}
+	unknown, err := Unknown(context.Background(), lister, []string{"home", "2", "attic"})
+	if err != nil {
+		t.Fatalf("Unknown() = %v", err)
+	}
+	if !slices.Equal(unknown, []string{"attic"}) {
+		t.Errorf("Unknown() = %v; want [attic]", unknown)
+	}
+
+	wantErr := errors.New("connection refused")
+	if _, err := Unknown(context.Background(), fakeLister{err: wantErr}, []string{"home"}); !errors.Is(err, wantErr) {
+		t.Errorf("Unknown() err = %v; want %v", err, wantErr)
+	}
+}
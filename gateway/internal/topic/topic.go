@@ -0,0 +1,104 @@
+// Package topic expands the MQTT topic templates telemetry is published on,
+// e.g. "sites/{gateway_id}/{station_id}/{metric}".
+package topic
+
+import (
+	"fmt"
+	"strings"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// Placeholders a template may contain.
+const (
+	GatewayID = "{gateway_id}"
+	StationID = "{station_id}"
+	// Metric makes the gateway publish each metric of a reading as its own
+	// message; see Metrics.
+	Metric = "{metric}"
+)
+
+// DefaultTelemetry is the topic the server subscribes to by default
+// (stations/+/telemetry).
+const DefaultTelemetry = "stations/" + StationID + "/telemetry"
+
+// Metrics are the values {metric} expands to, named as in the telemetry JSON
+// without the unit.
+var Metrics = cloudpico_shared.MetricNames
+
+// Template is a parsed topic template. The zero value is not valid.
+type Template struct {
+	raw  string
+	uses map[string]bool
+}
+
+// Parse checks that s is a topic a client may publish to, apart from its
+// placeholders, and that every brace opens or closes a known placeholder.
+func Parse(s string) (Template, error) {
+	if s == "" {
+		return Template{}, fmt.Errorf("empty topic")
+	}
+	if strings.ContainsAny(s, "+#") {
+		return Template{}, fmt.Errorf("topic %q contains an MQTT wildcard", s)
+	}
+	t := Template{raw: s, uses: make(map[string]bool)}
+	for rest := s; rest != ""; {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			break
+		}
+		if rest[open] == '}' {
+			return Template{}, fmt.Errorf("topic %q has an unopened '}'", s)
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return Template{}, fmt.Errorf("topic %q has an unclosed '{'", s)
+		}
+		p := rest[open : open+end+1]
+		switch p {
+		case GatewayID, StationID, Metric:
+		default:
+			return Template{}, fmt.Errorf("topic %q has unknown placeholder %s (allowed: %s, %s, %s)", s, p, GatewayID, StationID, Metric)
+		}
+		t.uses[p] = true
+		rest = rest[open+end+1:]
+	}
+	return t, nil
+}
+
+// String returns the template as parsed.
+func (t Template) String() string {
+	return t.raw
+}
+
+// Uses reports whether the template contains placeholder p.
+func (t Template) Uses(p string) bool {
+	return t.uses[p]
+}
+
+// Expand returns the topic for a gateway, station and metric. A value that
+// would add topic levels or wildcards is an error; metric is ignored unless
+// the template contains {metric}.
+func (t Template) Expand(gatewayID, stationID, metric string) (string, error) {
+	values := []string{GatewayID, gatewayID, StationID, stationID, Metric, metric}
+	for i := 0; i < len(values); i += 2 {
+		if t.uses[values[i]] {
+			if err := CheckLevel(values[i+1]); err != nil {
+				return "", fmt.Errorf("expand %s in topic %q: %w", values[i], t.raw, err)
+			}
+		}
+	}
+	return strings.NewReplacer(values...).Replace(t.raw), nil
+}
+
+// CheckLevel reports why v cannot stand for a placeholder: it is empty or
+// contains a level separator or wildcard.
+func CheckLevel(v string) error {
+	if v == "" {
+		return fmt.Errorf("empty value")
+	}
+	if strings.ContainsAny(v, "/+#") {
+		return fmt.Errorf("value %q contains '/', '+' or '#'", v)
+	}
+	return nil
+}
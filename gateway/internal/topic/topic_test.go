@@ -0,0 +1,49 @@
+package topic
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{
+		DefaultTelemetry,
+		"sites/{gateway_id}/{station_id}/{metric}",
+		"weather/{station_id}-{metric}",
+		"plain/topic",
+	} {
+		if _, err := Parse(s); err != nil {
+			t.Errorf("Parse(%q) error = %v", s, err)
+		}
+	}
+	for _, s := range []string{
+		"",
+		"stations/+/telemetry",
+		"stations/#",
+		"stations/{station}/telemetry",
+		"stations/{station_id/telemetry",
+		"stations/station_id}/telemetry",
+		"stations/{{station_id}}",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) error = nil; want an error", s)
+		}
+	}
+}
+
+func TestTemplate_Expand(t *testing.T) {
+	tmpl, err := Parse("sites/{gateway_id}/{station_id}/{metric}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tmpl.Expand("gw-1", "garden", "humidity"); got != "sites/gw-1/garden/humidity" || err != nil {
+		t.Errorf("Expand() = %q, %v; want sites/gw-1/garden/humidity", got, err)
+	}
+	for _, station := range []string{"", "a/b", "a+", "#"} {
+		if _, err := tmpl.Expand("gw-1", station, "humidity"); err == nil {
+			t.Errorf("Expand() with station %q error = nil; want an error", station)
+		}
+	}
+
+	def, _ := Parse(DefaultTelemetry)
+	if got, err := def.Expand("gw/1", "garden", ""); got != "stations/garden/telemetry" || err != nil {
+		t.Errorf("Expand() = %q, %v; want unused placeholders' values ignored", got, err)
+	}
+}
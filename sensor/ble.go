@@ -1,32 +1,27 @@
 // BLE advertising for Pico 2 W so the gateway can discover the device.
-// Manufacturer data format: [0:2] magic 0x01 0xD0, [2:6] device_id uint32 LE,
-// [6:10] reading_id uint32 LE, [10:14] temp float32 LE, [14:18] pressure float32 LE,
-// [18:22] humidity float32 LE (22 bytes total).
+// The manufacturer data format is defined in the payload package.
 package main
 
 import (
-	"encoding/binary"
-	"math"
 	"time"
 
-	"tinygo.org/x/bluetooth"
-)
+	"cloudpico-sensor/payload"
 
-const (
-	blePayloadMagic0 = 0x01
-	blePayloadMagic1 = 0xD0
-	blePayloadMinLen = 22
+	"tinygo.org/x/bluetooth"
 )
 
 type SendAdvertisementsOptions struct {
 	Interval time.Duration
 	Duration time.Duration
+	// FirmwareVersion is appended to every payload; 0.0.0 means unknown.
+	FirmwareVersion payload.Version
 }
 
 type BLE struct {
 	deviceID             uint32
 	adapter              *bluetooth.Adapter
-	readingData          [blePayloadMinLen]byte
+	readingData          [payload.ExtLen]byte
+	statusData           [payload.StatusLen]byte
 	advertisementOptions bluetooth.AdvertisementOptions
 	advertisement        bluetooth.Advertisement
 
@@ -42,10 +37,11 @@ func NewBLE(deviceID uint32, options SendAdvertisementsOptions) (*BLE, error) {
 	ble := &BLE{
 		adapter:       adapter,
 		deviceID:      deviceID,
-		readingData:   [blePayloadMinLen]byte{},
+		readingData:   [payload.ExtLen]byte{},
 		advertisement: *adapter.DefaultAdvertisement(),
 		sleepDuration: options.Duration,
 	}
+	payload.EncodeVersion(ble.readingData[:], options.FirmwareVersion)
 	ble.advertisementOptions = bluetooth.AdvertisementOptions{
 		AdvertisementType: bluetooth.AdvertisingTypeNonConnInd,
 		LocalName:         "pico2w-sensor",
@@ -60,16 +56,10 @@ func NewBLE(deviceID uint32, options SendAdvertisementsOptions) (*BLE, error) {
 var counter uint32 = 0
 
 // EncodeReadingPayload builds the manufacturer data payload: magic (2) + device_id (4) + reading_id (4) + T/P/H (12).
-// Uses the reusable payloadBuf to avoid heap allocations.
+// The firmware version trailer is written once in NewBLE and left untouched.
+// Uses the reusable readingData buffer to avoid heap allocations.
 func (b *BLE) EncodeReadingPayload(reading Reading, id uint32) {
-
-	b.readingData[0] = blePayloadMagic0
-	b.readingData[1] = blePayloadMagic1
-	binary.LittleEndian.PutUint32(b.readingData[2:6], b.deviceID)
-	binary.LittleEndian.PutUint32(b.readingData[6:10], id)
-	binary.LittleEndian.PutUint32(b.readingData[10:14], math.Float32bits(reading.Temperature))
-	binary.LittleEndian.PutUint32(b.readingData[14:18], math.Float32bits(reading.Pressure))
-	binary.LittleEndian.PutUint32(b.readingData[18:22], math.Float32bits(reading.Humidity))
+	payload.Encode(b.readingData[:], b.deviceID, id, reading)
 }
 
 func (b *BLE) Send(sensorReading Reading) (uint32, error) {
@@ -77,17 +67,33 @@ func (b *BLE) Send(sensorReading Reading) (uint32, error) {
 	counter++
 
 	b.EncodeReadingPayload(sensorReading, id)
+	if err := b.advertise(b.readingData[:]); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// SendStatus advertises a status frame instead of a reading. It does not
+// consume a reading ID.
+func (b *BLE) SendStatus(status payload.Status) error {
+	payload.EncodeStatus(b.statusData[:], b.deviceID, status)
+	return b.advertise(b.statusData[:])
+}
 
+// advertise broadcasts data as the manufacturer data for the advertising
+// duration.
+func (b *BLE) advertise(data []byte) error {
+	b.advertisementOptions.ManufacturerData[0].Data = data
 	if err := b.advertisement.Configure(b.advertisementOptions); err != nil {
-		return 0, err
+		return err
 	}
 
 	if err := b.advertisement.Start(); err != nil {
 		b.advertisement.Stop()
-		return 0, err
+		return err
 	}
 
 	time.Sleep(b.sleepDuration)
 	b.advertisement.Stop()
-	return id, nil
+	return nil
 }
@@ -10,18 +10,56 @@ import (
 	"fmt"
 	"machine"
 	"strconv"
+	"strings"
 	"time"
+
+	"cloudpico-sensor/payload"
 )
 
-const SENSOR_POLL_INTERVAL = 2000 * time.Millisecond
+// Readings are sent every READING_INTERVAL_MIN while the weather changes and
+// up to READING_INTERVAL_MAX apart while it is stable; see payload.Pacer.
+const READING_INTERVAL_MIN = 2000 * time.Millisecond
+const READING_INTERVAL_MAX = 60 * time.Second
+
+// READING_CHANGE_THRESHOLD is the change between consecutive readings that
+// brings the interval back to READING_INTERVAL_MIN, well above the BME280's
+// noise.
+var READING_CHANGE_THRESHOLD = payload.Reading{Temperature: 0.3, Pressure: 0.2, Humidity: 2}
+
 const BLE_ADVERTISEMENT_INTERVAL = 100 * time.Millisecond
 const BLE_ADVERTISEMENT_DURATION = 420 * time.Millisecond
 const BOOT_DELAY = 5000 * time.Millisecond
 
+// STATUS_EVERY is how many advert slots pass between status frames; a status
+// slot takes the place of a reading.
+const STATUS_EVERY = 30
+
 // deviceIDStr is set at build time via -ldflags "-X main.deviceIDStr=0x12345678"
 // Format: -ldflags "-X main.deviceIDStr=0x12345678" or "-X main.deviceIDStr=305419896"
 var deviceIDStr string
 
+// firmwareVersion is set at build time via -ldflags "-X main.firmwareVersion=1.2.3"
+// and advertised so the gateway can report it upstream.
+var firmwareVersion string
+
+// parseFirmwareVersion parses "major.minor.patch" (optionally prefixed with "v").
+// Returns the zero Version (unknown) if s is empty or invalid.
+func parseFirmwareVersion(s string) payload.Version {
+	parts := strings.Split(strings.TrimPrefix(s, "v"), ".")
+	if len(parts) != 3 {
+		return payload.Version{}
+	}
+	var nums [3]uint8
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 8)
+		if err != nil {
+			return payload.Version{}
+		}
+		nums[i] = uint8(n)
+	}
+	return payload.Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}
+}
+
 // parseDeviceIDFromStr parses deviceIDStr and returns the uint32 value.
 // Returns 0 if deviceIDStr is empty or invalid.
 func parseDeviceIDFromStr(s string) uint32 {
@@ -43,17 +81,20 @@ func parseDeviceIDFromStr(s string) uint32 {
 
 func main() {
 	deviceID := parseDeviceIDFromStr(deviceIDStr)
+	version := parseFirmwareVersion(firmwareVersion)
 
 	machine.Serial.Configure(machine.UARTConfig{})
 
 	led := machine.LED
 	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
 
-	fmt.Printf("boot: pico2w BLE beacon + BME280 sensor (device_id: 0x%08X)\r\n", deviceID)
+	fmt.Printf("boot: pico2w BLE beacon + BME280 sensor (device_id: 0x%08X, firmware: %d.%d.%d)\r\n",
+		deviceID, version.Major, version.Minor, version.Patch)
 
 	ble, err := NewBLE(deviceID, SendAdvertisementsOptions{
-		Interval: BLE_ADVERTISEMENT_INTERVAL,
-		Duration: BLE_ADVERTISEMENT_DURATION,
+		Interval:        BLE_ADVERTISEMENT_INTERVAL,
+		Duration:        BLE_ADVERTISEMENT_DURATION,
+		FirmwareVersion: version,
 	})
 	if err != nil {
 		fmt.Printf("ERROR: BLE initialization failed: %v\r\n", err)
@@ -66,25 +107,51 @@ func main() {
 		return
 	}
 
-	sleepDuration := SENSOR_POLL_INTERVAL - BLE_ADVERTISEMENT_DURATION
+	boot := time.Now()
+	// Battery voltage is not measured yet: on the Pico 2 W, VSYS is sampled
+	// on a pin shared with the radio, so it is reported as unknown (0).
+	status := payload.Status{FirmwareVersion: version}
+	schedule := payload.Schedule{StatusEvery: STATUS_EVERY}
+	pacer := payload.Pacer{
+		Min:       READING_INTERVAL_MIN,
+		Max:       READING_INTERVAL_MAX,
+		Threshold: READING_CHANGE_THRESHOLD,
+	}
+
 	for {
 		led.High()
+		sleepDuration := pacer.Interval() - BLE_ADVERTISEMENT_DURATION
+
+		if schedule.Next() == payload.FrameStatus {
+			status.UptimeSeconds = uint32(time.Since(boot) / time.Second)
+			status.IntervalSeconds = uint16(pacer.Interval() / time.Second)
+			if err := ble.SendStatus(status); err != nil {
+				fmt.Printf("ERROR: BLE status advertisement failed: %v\r\n", err)
+				status.AdvertErrors = payload.IncSaturating(status.AdvertErrors)
+			}
+			led.Low()
+			time.Sleep(sleepDuration)
+			continue
+		}
 
 		reading, err := sensor.Read()
 
 		if err != nil {
+			status.SensorErrors = payload.IncSaturating(status.SensorErrors)
 			time.Sleep(sleepDuration)
 			continue
 		}
+		sleepDuration = pacer.Observe(reading) - BLE_ADVERTISEMENT_DURATION
 
 		fmt.Println("Sending BLE advertisement...")
 		reading_id, err := ble.Send(reading)
 		if err != nil {
 			fmt.Printf("ERROR: BLE advertisement update failed: %v\r\n", err)
+			status.AdvertErrors = payload.IncSaturating(status.AdvertErrors)
 			time.Sleep(sleepDuration)
 			continue
 		}
-		fmt.Printf("BLE advertisement sent (reading_id: %d)\r\n", reading_id)
+		fmt.Printf("BLE advertisement sent (reading_id: %d, next in %v)\r\n", reading_id, pacer.Interval())
 
 		led.Low()
 		time.Sleep(sleepDuration)
@@ -0,0 +1,153 @@
+package payload
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Readings that no longer fit a single legacy advertisement are sent as a
+// message split across several frames, each a separate advertisement the
+// gateway reassembles by (device_id, reading_id).
+//
+// Frame format (little-endian): [0:2] magic 0x01 0xD1, [2:6] device_id uint32,
+// [6:10] reading_id uint32, [10] frame index (high nibble) and frame count
+// (low nibble), [11:] up to FrameDataLen bytes of the message body.
+//
+// The body is a sequence of fields: tag uint8, length uint8, value. Readers
+// skip tags they do not know, so metrics can be added without breaking older
+// gateways.
+const (
+	FrameMagic1    = 0xD1
+	FrameHeaderLen = 11
+	// FrameLen is the largest frame; it matches the size of a versioned
+	// single-frame payload so it fits the same advertisement.
+	FrameLen     = ExtLen
+	FrameDataLen = FrameLen - FrameHeaderLen
+	MaxFrames    = 15
+	MaxBodyLen   = MaxFrames * FrameDataLen
+)
+
+// Body field tags.
+const (
+	TagTemperature     = 0x01 // float32, °C
+	TagPressure        = 0x02 // float32, hPa
+	TagHumidity        = 0x03 // float32, %
+	TagFirmwareVersion = 0x04 // major, minor, patch uint8
+)
+
+// AppendFloat32 appends a float32 field to body.
+func AppendFloat32(body []byte, tag uint8, v float32) []byte {
+	body = append(body, tag, 4)
+	return binary.LittleEndian.AppendUint32(body, math.Float32bits(v))
+}
+
+// AppendReading appends the temperature, pressure and humidity of reading and,
+// unless it is 0.0.0, the firmware version to body.
+func AppendReading(body []byte, reading Reading, v Version) []byte {
+	body = AppendFloat32(body, TagTemperature, reading.Temperature)
+	body = AppendFloat32(body, TagPressure, reading.Pressure)
+	body = AppendFloat32(body, TagHumidity, reading.Humidity)
+	if !v.IsZero() {
+		body = append(body, TagFirmwareVersion, 3, v.Major, v.Minor, v.Patch)
+	}
+	return body
+}
+
+// FrameCount returns the number of frames needed to send a body of n bytes.
+func FrameCount(n int) int {
+	if n == 0 {
+		return 1
+	}
+	return (n + FrameDataLen - 1) / FrameDataLen
+}
+
+// EncodeFrame writes frame index of body into buf, which must be at least
+// FrameLen bytes, and returns the frame length. It does not allocate.
+func EncodeFrame(buf []byte, deviceID uint32, readingID uint32, body []byte, index int) (int, error) {
+	count := FrameCount(len(body))
+	if count > MaxFrames {
+		return 0, errors.New("body too long")
+	}
+	if index < 0 || index >= count {
+		return 0, errors.New("frame index out of range")
+	}
+	_ = buf[FrameLen-1] // bounds check hint
+	buf[0] = Magic0
+	buf[1] = FrameMagic1
+	binary.LittleEndian.PutUint32(buf[2:6], deviceID)
+	binary.LittleEndian.PutUint32(buf[6:10], readingID)
+	buf[10] = byte(index<<4 | count)
+	chunk := body[index*FrameDataLen : min((index+1)*FrameDataLen, len(body))]
+	return FrameHeaderLen + copy(buf[FrameHeaderLen:], chunk), nil
+}
+
+// Frame is one decoded frame of a multi-frame message.
+type Frame struct {
+	DeviceID  uint32
+	ReadingID uint32
+	Index     int
+	Count     int
+	Data      []byte // aliases the decoded buffer
+}
+
+// DecodeFrame is the inverse of EncodeFrame. It mirrors the gateway's parser
+// and exists so round trips can be tested on the host.
+func DecodeFrame(buf []byte) (Frame, error) {
+	if len(buf) < FrameHeaderLen {
+		return Frame{}, errors.New("frame too short")
+	}
+	if buf[0] != Magic0 || buf[1] != FrameMagic1 {
+		return Frame{}, errors.New("invalid magic")
+	}
+	f := Frame{
+		DeviceID:  binary.LittleEndian.Uint32(buf[2:6]),
+		ReadingID: binary.LittleEndian.Uint32(buf[6:10]),
+		Index:     int(buf[10] >> 4),
+		Count:     int(buf[10] & 0x0F),
+		Data:      buf[FrameHeaderLen:],
+	}
+	if f.Count == 0 || f.Index >= f.Count {
+		return Frame{}, errors.New("invalid frame index")
+	}
+	return f, nil
+}
+
+// DecodeBody returns the reading and firmware version in a reassembled body.
+// Unknown tags are skipped; temperature, pressure and humidity are required.
+func DecodeBody(body []byte) (Reading, Version, error) {
+	var reading Reading
+	var v Version
+	var seen uint8
+	for len(body) > 0 {
+		if len(body) < 2 || len(body) < 2+int(body[1]) {
+			return Reading{}, Version{}, errors.New("truncated field")
+		}
+		tag, value := body[0], body[2:2+int(body[1])]
+		body = body[2+len(value):]
+		switch tag {
+		case TagTemperature, TagPressure, TagHumidity:
+			if len(value) != 4 {
+				return Reading{}, Version{}, errors.New("invalid float field")
+			}
+			f := math.Float32frombits(binary.LittleEndian.Uint32(value))
+			switch tag {
+			case TagTemperature:
+				reading.Temperature = f
+			case TagPressure:
+				reading.Pressure = f
+			case TagHumidity:
+				reading.Humidity = f
+			}
+			seen |= 1 << tag
+		case TagFirmwareVersion:
+			if len(value) == 3 {
+				v = Version{Major: value[0], Minor: value[1], Patch: value[2]}
+			}
+		}
+	}
+	if seen != 1<<TagTemperature|1<<TagPressure|1<<TagHumidity {
+		return Reading{}, Version{}, errors.New("missing reading field")
+	}
+	return reading, v, nil
+}
@@ -0,0 +1,110 @@
+package payload
+
+import (
+	"bytes"
+	"testing"
+)
+
+// goldenFrames are the frames for the golden reading with firmware 1.4.2.
+// The gateway reassembly tests use the same vectors.
+var goldenFrames = [][]byte{
+	{
+		0x01, 0xD1,
+		0x78, 0x56, 0x34, 0x12,
+		0x2A, 0x00, 0x00, 0x00,
+		0x02,
+		0x01, 0x04, 0x00, 0x00, 0xAC, 0x41,
+		0x02, 0x04, 0x00, 0x50, 0x7D, 0x44,
+		0x03, 0x04,
+	},
+	{
+		0x01, 0xD1,
+		0x78, 0x56, 0x34, 0x12,
+		0x2A, 0x00, 0x00, 0x00,
+		0x12,
+		0x00, 0x00, 0x5C, 0x42,
+		0x04, 0x03, 0x01, 0x04, 0x02,
+	},
+}
+
+func TestEncodeFrame_golden(t *testing.T) {
+	body := AppendReading(nil, Reading{Temperature: 21.5, Pressure: 1013.25, Humidity: 55}, Version{Major: 1, Minor: 4, Patch: 2})
+	if got := FrameCount(len(body)); got != len(goldenFrames) {
+		t.Fatalf("FrameCount(%d) = %d; want %d", len(body), got, len(goldenFrames))
+	}
+	for i, want := range goldenFrames {
+		var buf [FrameLen]byte
+		n, err := EncodeFrame(buf[:], 0x12345678, 42, body, i)
+		if err != nil {
+			t.Fatalf("EncodeFrame(%d) error = %v", i, err)
+		}
+		if !bytes.Equal(buf[:n], want) {
+			t.Errorf("EncodeFrame(%d) = % X; want % X", i, buf[:n], want)
+		}
+	}
+}
+
+func TestFrames_roundTrip(t *testing.T) {
+	reading := Reading{Temperature: -3.25, Pressure: 1001.5, Humidity: 87}
+	body := AppendReading(nil, reading, Version{})
+	// A metric this reader does not know must be skipped.
+	body = AppendFloat32(body, 0x7F, 3.3)
+
+	var assembled []byte
+	for i := range FrameCount(len(body)) {
+		var buf [FrameLen]byte
+		n, err := EncodeFrame(buf[:], 7, 9, body, i)
+		if err != nil {
+			t.Fatalf("EncodeFrame(%d) error = %v", i, err)
+		}
+		f, err := DecodeFrame(buf[:n])
+		if err != nil {
+			t.Fatalf("DecodeFrame(%d) error = %v", i, err)
+		}
+		if f.DeviceID != 7 || f.ReadingID != 9 || f.Index != i || f.Count != FrameCount(len(body)) {
+			t.Fatalf("DecodeFrame(%d) = %+v", i, f)
+		}
+		assembled = append(assembled, f.Data...)
+	}
+	got, v, err := DecodeBody(assembled)
+	if err != nil {
+		t.Fatalf("DecodeBody() error = %v", err)
+	}
+	if got != reading || !v.IsZero() {
+		t.Errorf("DecodeBody() = %+v, %+v; want %+v, 0.0.0", got, v, reading)
+	}
+}
+
+func TestEncodeFrame_invalid(t *testing.T) {
+	var buf [FrameLen]byte
+	if _, err := EncodeFrame(buf[:], 1, 2, make([]byte, MaxBodyLen+1), 0); err == nil {
+		t.Error("EncodeFrame(body too long) = nil error; want error")
+	}
+	if _, err := EncodeFrame(buf[:], 1, 2, make([]byte, FrameDataLen), 1); err == nil {
+		t.Error("EncodeFrame(index out of range) = nil error; want error")
+	}
+}
+
+func TestDecodeFrame_invalid(t *testing.T) {
+	if _, err := DecodeFrame(goldenFrames[0][:FrameHeaderLen-1]); err == nil {
+		t.Error("DecodeFrame(short) = nil error; want error")
+	}
+	if _, err := DecodeFrame(golden); err == nil {
+		t.Error("DecodeFrame(single-frame payload) = nil error; want error")
+	}
+	bad := append([]byte(nil), goldenFrames[0]...)
+	bad[10] = 0x22
+	if _, err := DecodeFrame(bad); err == nil {
+		t.Error("DecodeFrame(index >= count) = nil error; want error")
+	}
+}
+
+func TestDecodeBody_invalid(t *testing.T) {
+	full := AppendReading(nil, Reading{}, Version{})
+	if _, _, err := DecodeBody(full[:len(full)-1]); err == nil {
+		t.Error("DecodeBody(truncated) = nil error; want error")
+	}
+	if _, _, err := DecodeBody(full[:12]); err == nil {
+		t.Error("DecodeBody(no humidity) = nil error; want error")
+	}
+}
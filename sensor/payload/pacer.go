@@ -0,0 +1,52 @@
+package payload
+
+import "time"
+
+// Pacer adapts the interval between readings to how fast they change:
+// readings are sent every Min while any metric moves by at least its
+// Threshold between consecutive readings, as when a storm front passes, and
+// the interval doubles after every quiet reading up to Max, saving battery
+// while the weather is stable.
+type Pacer struct {
+	Min, Max time.Duration
+	// Threshold holds the change in each metric (°C, hPa, %) that counts as
+	// fast; a zero field ignores that metric.
+	Threshold Reading
+
+	interval time.Duration
+	last     Reading
+	seen     bool
+}
+
+// Interval returns the current interval, Min until the first Observe.
+func (p *Pacer) Interval() time.Duration {
+	if p.interval == 0 {
+		return p.Min
+	}
+	return p.interval
+}
+
+// Observe takes a new reading and returns the interval until the next one.
+func (p *Pacer) Observe(r Reading) time.Duration {
+	switch {
+	case !p.seen || p.changed(r):
+		p.interval = p.Min
+	default:
+		p.interval = min(2*p.Interval(), p.Max)
+	}
+	p.last, p.seen = r, true
+	return p.interval
+}
+
+func (p *Pacer) changed(r Reading) bool {
+	return exceeds(r.Temperature-p.last.Temperature, p.Threshold.Temperature) ||
+		exceeds(r.Pressure-p.last.Pressure, p.Threshold.Pressure) ||
+		exceeds(r.Humidity-p.last.Humidity, p.Threshold.Humidity)
+}
+
+func exceeds(delta, threshold float32) bool {
+	if threshold <= 0 {
+		return false
+	}
+	return delta >= threshold || -delta >= threshold
+}
@@ -0,0 +1,38 @@
+package payload
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacer(t *testing.T) {
+	p := Pacer{
+		Min:       2 * time.Second,
+		Max:       10 * time.Second,
+		Threshold: Reading{Temperature: 0.5, Pressure: 0.3},
+	}
+	if got := p.Interval(); got != p.Min {
+		t.Fatalf("Interval() before the first reading = %v; want %v", got, p.Min)
+	}
+	stable := Reading{Temperature: 20, Pressure: 1013, Humidity: 50}
+	for i, step := range []struct {
+		r    Reading
+		want time.Duration
+	}{
+		{stable, 2 * time.Second},
+		{stable, 4 * time.Second},
+		{Reading{Temperature: 20.2, Pressure: 1013.1, Humidity: 60}, 8 * time.Second}, // below thresholds; humidity ignored
+		{stable, 10 * time.Second},
+		{stable, 10 * time.Second},
+		{Reading{Temperature: 20, Pressure: 1012.6, Humidity: 50}, 2 * time.Second}, // pressure falling
+		{Reading{Temperature: 19.4, Pressure: 1012.6, Humidity: 50}, 2 * time.Second},
+		{Reading{Temperature: 19.4, Pressure: 1012.6, Humidity: 50}, 4 * time.Second},
+	} {
+		if got := p.Observe(step.r); got != step.want {
+			t.Errorf("reading %d: Observe() = %v; want %v", i, got, step.want)
+		}
+		if got := p.Interval(); got != step.want {
+			t.Errorf("reading %d: Interval() = %v; want %v", i, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package payload encodes sensor readings into the BLE manufacturer data
+// format understood by the gateway. It has no machine or bluetooth imports so
+// it can be built and tested on the host.
+//
+// Format (little-endian): [0:2] magic 0x01 0xD0, [2:6] device_id uint32,
+// [6:10] reading_id uint32, [10:14] temp float32, [14:18] pressure float32,
+// [18:22] humidity float32 (22 bytes total). Firmware that knows its version
+// appends [22:25] major, minor, patch uint8 (ExtLen bytes); 0.0.0 means unknown.
+package payload
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	Magic0 = 0x01
+	Magic1 = 0xD0
+	Len    = 22
+	ExtLen = Len + 3
+)
+
+// Version is the firmware version carried in the optional payload trailer.
+type Version struct {
+	Major uint8
+	Minor uint8
+	Patch uint8
+}
+
+// IsZero reports whether v is the "unknown" version 0.0.0.
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// Reading is a single sensor sample in firmware units (°C, hPa, %).
+type Reading struct {
+	Temperature float32
+	Pressure    float32
+	Humidity    float32
+}
+
+// Encode writes the payload for reading into buf, which must be at least Len bytes.
+// It does not allocate.
+func Encode(buf []byte, deviceID uint32, readingID uint32, reading Reading) {
+	_ = buf[Len-1] // bounds check hint
+	buf[0] = Magic0
+	buf[1] = Magic1
+	binary.LittleEndian.PutUint32(buf[2:6], deviceID)
+	binary.LittleEndian.PutUint32(buf[6:10], readingID)
+	binary.LittleEndian.PutUint32(buf[10:14], math.Float32bits(reading.Temperature))
+	binary.LittleEndian.PutUint32(buf[14:18], math.Float32bits(reading.Pressure))
+	binary.LittleEndian.PutUint32(buf[18:22], math.Float32bits(reading.Humidity))
+}
+
+// Decode is the inverse of Encode. It mirrors the gateway's parser and exists
+// so encode/decode round trips can be tested on the host.
+func Decode(buf []byte) (deviceID uint32, readingID uint32, reading Reading, err error) {
+	if len(buf) < Len {
+		return 0, 0, Reading{}, errors.New("payload too short")
+	}
+	if buf[0] != Magic0 || buf[1] != Magic1 {
+		return 0, 0, Reading{}, errors.New("invalid magic")
+	}
+	deviceID = binary.LittleEndian.Uint32(buf[2:6])
+	readingID = binary.LittleEndian.Uint32(buf[6:10])
+	reading = Reading{
+		Temperature: math.Float32frombits(binary.LittleEndian.Uint32(buf[10:14])),
+		Pressure:    math.Float32frombits(binary.LittleEndian.Uint32(buf[14:18])),
+		Humidity:    math.Float32frombits(binary.LittleEndian.Uint32(buf[18:22])),
+	}
+	return deviceID, readingID, reading, nil
+}
+
+// EncodeVersion writes the firmware version trailer into buf, which must be at
+// least ExtLen bytes. It does not allocate.
+func EncodeVersion(buf []byte, v Version) {
+	_ = buf[ExtLen-1] // bounds check hint
+	buf[Len] = v.Major
+	buf[Len+1] = v.Minor
+	buf[Len+2] = v.Patch
+}
+
+// DecodeVersion returns the firmware version trailer, or ok=false when buf is
+// a plain Len-byte payload or carries 0.0.0.
+func DecodeVersion(buf []byte) (v Version, ok bool) {
+	if len(buf) < ExtLen {
+		return Version{}, false
+	}
+	v = Version{Major: buf[Len], Minor: buf[Len+1], Patch: buf[Len+2]}
+	return v, !v.IsZero()
+}
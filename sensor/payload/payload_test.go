@@ -0,0 +1,96 @@
+package payload
+
+import (
+	"bytes"
+	"testing"
+)
+
+// golden is the payload for device 0x12345678, reading 42, 21.5 °C, 1013.25 hPa, 55 %.
+// The gateway parser tests use the same vector.
+var golden = []byte{
+	0x01, 0xD0,
+	0x78, 0x56, 0x34, 0x12,
+	0x2A, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0xAC, 0x41,
+	0x00, 0x50, 0x7D, 0x44,
+	0x00, 0x00, 0x5C, 0x42,
+}
+
+func TestEncode_golden(t *testing.T) {
+	var buf [Len]byte
+	Encode(buf[:], 0x12345678, 42, Reading{Temperature: 21.5, Pressure: 1013.25, Humidity: 55})
+
+	if !bytes.Equal(buf[:], golden) {
+		t.Errorf("Encode() = % X; want % X", buf[:], golden)
+	}
+}
+
+func TestEncodeDecode_roundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		deviceID  uint32
+		readingID uint32
+		reading   Reading
+	}{
+		{"typical", 0xCAFEBABE, 1, Reading{Temperature: 19.75, Pressure: 998.5, Humidity: 61.25}},
+		{"negative temperature", 1, 0xFFFFFFFF, Reading{Temperature: -12.5, Pressure: 1030, Humidity: 90}},
+		{"zero values", 0, 0, Reading{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf [Len]byte
+			Encode(buf[:], tt.deviceID, tt.readingID, tt.reading)
+
+			deviceID, readingID, reading, err := Decode(buf[:])
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if deviceID != tt.deviceID || readingID != tt.readingID || reading != tt.reading {
+				t.Errorf("Decode() = %08X, %d, %+v; want %08X, %d, %+v",
+					deviceID, readingID, reading, tt.deviceID, tt.readingID, tt.reading)
+			}
+		})
+	}
+}
+
+func TestDecode_invalid(t *testing.T) {
+	if _, _, _, err := Decode(golden[:Len-1]); err == nil {
+		t.Error("Decode(short) = nil error; want error")
+	}
+	bad := append([]byte(nil), golden...)
+	bad[1] = 0x00
+	if _, _, _, err := Decode(bad); err == nil {
+		t.Error("Decode(bad magic) = nil error; want error")
+	}
+}
+
+func TestEncode_noAllocs(t *testing.T) {
+	var buf [Len]byte
+	allocs := testing.AllocsPerRun(100, func() {
+		Encode(buf[:], 1, 2, Reading{Temperature: 1, Pressure: 2, Humidity: 3})
+	})
+	if allocs != 0 {
+		t.Errorf("Encode allocs = %v; want 0", allocs)
+	}
+}
+
+func TestVersion_roundTrip(t *testing.T) {
+	var buf [ExtLen]byte
+	Encode(buf[:], 0x12345678, 42, Reading{Temperature: 21.5, Pressure: 1013.25, Humidity: 55})
+	EncodeVersion(buf[:], Version{Major: 1, Minor: 4, Patch: 2})
+
+	if !bytes.Equal(buf[:Len], golden) {
+		t.Errorf("reading bytes = % X; want % X", buf[:Len], golden)
+	}
+	v, ok := DecodeVersion(buf[:])
+	if !ok || v != (Version{Major: 1, Minor: 4, Patch: 2}) {
+		t.Errorf("DecodeVersion() = %+v, %v; want {1 4 2}, true", v, ok)
+	}
+	if _, ok := DecodeVersion(golden); ok {
+		t.Error("DecodeVersion(no trailer) ok = true; want false")
+	}
+	EncodeVersion(buf[:], Version{})
+	if _, ok := DecodeVersion(buf[:]); ok {
+		t.Error("DecodeVersion(0.0.0) ok = true; want false")
+	}
+}
@@ -0,0 +1,105 @@
+package payload
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Status frames report the health of a sensor between telemetry adverts.
+//
+// Format (little-endian): [0:2] magic 0x01 0xD2, [2:6] device_id uint32,
+// [6:10] uptime in seconds uint32, [10:12] battery millivolts uint16 (0 means
+// unknown), [12:14] sensor read errors uint16, [14:16] BLE advertising errors
+// uint16, [16:19] firmware major, minor, patch uint8, [19:21] reading interval
+// in seconds uint16 (21 bytes total; 0 means unknown). Error counters
+// saturate instead of wrapping. The gateway also accepts frames of firmware
+// that ends at the version.
+const (
+	StatusMagic1 = 0xD2
+	StatusLen    = 21
+)
+
+// Status is the payload of a status frame.
+type Status struct {
+	UptimeSeconds     uint32
+	BatteryMillivolts uint16
+	SensorErrors      uint16
+	AdvertErrors      uint16
+	FirmwareVersion   Version
+	// IntervalSeconds is the current interval between readings; see Pacer.
+	IntervalSeconds uint16
+}
+
+// EncodeStatus writes the status frame into buf, which must be at least
+// StatusLen bytes. It does not allocate.
+func EncodeStatus(buf []byte, deviceID uint32, s Status) {
+	_ = buf[StatusLen-1] // bounds check hint
+	buf[0] = Magic0
+	buf[1] = StatusMagic1
+	binary.LittleEndian.PutUint32(buf[2:6], deviceID)
+	binary.LittleEndian.PutUint32(buf[6:10], s.UptimeSeconds)
+	binary.LittleEndian.PutUint16(buf[10:12], s.BatteryMillivolts)
+	binary.LittleEndian.PutUint16(buf[12:14], s.SensorErrors)
+	binary.LittleEndian.PutUint16(buf[14:16], s.AdvertErrors)
+	buf[16] = s.FirmwareVersion.Major
+	buf[17] = s.FirmwareVersion.Minor
+	buf[18] = s.FirmwareVersion.Patch
+	binary.LittleEndian.PutUint16(buf[19:21], s.IntervalSeconds)
+}
+
+// DecodeStatus is the inverse of EncodeStatus. It mirrors the gateway's parser
+// and exists so round trips can be tested on the host.
+func DecodeStatus(buf []byte) (deviceID uint32, s Status, err error) {
+	if len(buf) < StatusLen {
+		return 0, Status{}, errors.New("status too short")
+	}
+	if buf[0] != Magic0 || buf[1] != StatusMagic1 {
+		return 0, Status{}, errors.New("invalid magic")
+	}
+	return binary.LittleEndian.Uint32(buf[2:6]), Status{
+		UptimeSeconds:     binary.LittleEndian.Uint32(buf[6:10]),
+		BatteryMillivolts: binary.LittleEndian.Uint16(buf[10:12]),
+		SensorErrors:      binary.LittleEndian.Uint16(buf[12:14]),
+		AdvertErrors:      binary.LittleEndian.Uint16(buf[14:16]),
+		FirmwareVersion:   Version{Major: buf[16], Minor: buf[17], Patch: buf[18]},
+		IntervalSeconds:   binary.LittleEndian.Uint16(buf[19:21]),
+	}, nil
+}
+
+// IncSaturating returns n+1, or n when n is already the largest uint16.
+func IncSaturating(n uint16) uint16 {
+	if n == 0xFFFF {
+		return n
+	}
+	return n + 1
+}
+
+// FrameType is the kind of advert sent in a slot of a Schedule.
+type FrameType uint8
+
+const (
+	FrameTelemetry FrameType = iota
+	FrameStatus
+)
+
+// Schedule rotates advert slots between telemetry and status frames: every
+// StatusEvery-th slot, starting with the first so the gateway learns the
+// sensor's state right after boot, is a status frame. StatusEvery <= 0
+// disables status frames.
+type Schedule struct {
+	StatusEvery int
+	slot        int
+}
+
+// Next returns the frame type of the next slot.
+func (s *Schedule) Next() FrameType {
+	if s.StatusEvery <= 0 {
+		return FrameTelemetry
+	}
+	slot := s.slot
+	s.slot = (s.slot + 1) % s.StatusEvery
+	if slot == 0 {
+		return FrameStatus
+	}
+	return FrameTelemetry
+}
@@ -0,0 +1,71 @@
+package payload
+
+import (
+	"bytes"
+	"testing"
+)
+
+// goldenStatus is the status frame for device 0x12345678 after 3600 s at
+// 3300 mV with 2 sensor errors, 1 advert error, firmware 1.4.2 and a 120 s
+// reading interval. The gateway parser tests use the same vector.
+var goldenStatus = []byte{
+	0x01, 0xD2,
+	0x78, 0x56, 0x34, 0x12,
+	0x10, 0x0E, 0x00, 0x00,
+	0xE4, 0x0C,
+	0x02, 0x00,
+	0x01, 0x00,
+	0x01, 0x04, 0x02,
+	0x78, 0x00,
+}
+
+func TestEncodeStatus_golden(t *testing.T) {
+	var buf [StatusLen]byte
+	s := Status{UptimeSeconds: 3600, BatteryMillivolts: 3300, SensorErrors: 2, AdvertErrors: 1, FirmwareVersion: Version{Major: 1, Minor: 4, Patch: 2}, IntervalSeconds: 120}
+	EncodeStatus(buf[:], 0x12345678, s)
+	if !bytes.Equal(buf[:], goldenStatus) {
+		t.Fatalf("EncodeStatus() = % X; want % X", buf[:], goldenStatus)
+	}
+	deviceID, got, err := DecodeStatus(buf[:])
+	if err != nil {
+		t.Fatalf("DecodeStatus() error = %v", err)
+	}
+	if deviceID != 0x12345678 || got != s {
+		t.Errorf("DecodeStatus() = %08X, %+v; want 12345678, %+v", deviceID, got, s)
+	}
+}
+
+func TestDecodeStatus_invalid(t *testing.T) {
+	if _, _, err := DecodeStatus(goldenStatus[:StatusLen-1]); err == nil {
+		t.Error("DecodeStatus(short) = nil error; want error")
+	}
+	if _, _, err := DecodeStatus(append(append([]byte(nil), golden...), 0, 0, 0)); err == nil {
+		t.Error("DecodeStatus(telemetry payload) = nil error; want error")
+	}
+}
+
+func TestIncSaturating(t *testing.T) {
+	if got := IncSaturating(1); got != 2 {
+		t.Errorf("IncSaturating(1) = %d; want 2", got)
+	}
+	if got := IncSaturating(0xFFFF); got != 0xFFFF {
+		t.Errorf("IncSaturating(0xFFFF) = %d; want 0xFFFF", got)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	s := Schedule{StatusEvery: 3}
+	want := []FrameType{FrameStatus, FrameTelemetry, FrameTelemetry, FrameStatus, FrameTelemetry, FrameTelemetry, FrameStatus}
+	for i, w := range want {
+		if got := s.Next(); got != w {
+			t.Errorf("slot %d: Next() = %d; want %d", i, got, w)
+		}
+	}
+
+	off := Schedule{}
+	for i := range 3 {
+		if got := off.Next(); got != FrameTelemetry {
+			t.Errorf("disabled slot %d: Next() = %d; want FrameTelemetry", i, got)
+		}
+	}
+}
@@ -4,17 +4,16 @@ package main
 import (
 	"machine"
 
+	"cloudpico-sensor/payload"
+
 	"tinygo.org/x/drivers/bme280"
 )
 
 // RunSensor configures I2C and BME280, then blocks in a loop reading and
 // printing T/P/H every 2 seconds.
 
-type Reading struct {
-	Temperature float32
-	Pressure    float32
-	Humidity    float32
-}
+// Reading is the sample type encoded into BLE advertisements.
+type Reading = payload.Reading
 
 type Sensor struct {
 	device *bme280.Device
@@ -12,9 +12,13 @@ import (
 	"cloudpico-server/internal/app"
 	"cloudpico-server/internal/config"
 	"cloudpico-server/internal/logging"
+	"cloudpico-shared/buildinfo"
 )
 
+// version, commit and buildDate are set at build time via -ldflags, e.g.
+// "-X main.version=1.2.3 -X main.commit=<sha> -X main.buildDate=<RFC 3339>".
 var version = "dev"
+var commit, buildDate string
 var appName = "cloudpico-server"
 
 func main() {
@@ -24,21 +28,24 @@ func main() {
 		os.Exit(1)
 	}
 
-	logger := logging.New(cfg, version, appName)
+	build := buildinfo.New(version, commit, buildDate)
+	logger := logging.New(cfg, build, appName)
 	slog.SetDefault(logger)
 
 	slog.Info("starting",
 		"app", appName,
-		"version", version,
-		"env", cfg.AppEnv,
-		"log_level", cfg.LogLevel.String(),
-		"http_addr", cfg.HTTPAddr,
+		"version", build.Version,
+		"commit", build.Commit,
+		"build_date", build.BuildDate,
+		"go_version", build.GoVersion,
+		"platform", build.OS+"/"+build.Arch,
+		"config", cfg,
 	)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	if err := app.Run(ctx, cfg); err != nil && !errors.Is(err, context.Canceled) {
+	if err := app.Run(ctx, cfg, build); err != nil && !errors.Is(err, context.Canceled) {
 		slog.Error("run failed", "err", err)
 		os.Exit(1)
 	}
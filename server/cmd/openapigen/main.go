@@ -0,0 +1,35 @@
+// Command openapigen writes the OpenAPI specification the server serves at
+// /api/v1/openapi.json, from the weather module's routes and types.
+//
+//	go generate ./cmd/openapigen
+package main
+
+//go:generate go run . -o ../../internal/modules/weather/openapi.json
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"cloudpico-server/internal/modules/weather"
+)
+
+func main() {
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	src, err := weather.OpenAPI()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapigen: %v\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapigen: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather"
+)
+
+// TestGeneratedFileUpToDate fails when the API routes or types change without
+// regenerating the specification; run go generate ./cmd/openapigen.
+func TestGeneratedFileUpToDate(t *testing.T) {
+	want, err := weather.OpenAPI()
+	if err != nil {
+		t.Fatalf("OpenAPI() = %v; want nil", err)
+	}
+	got, err := os.ReadFile("../../internal/modules/weather/openapi.json")
+	if err != nil {
+		t.Fatalf("read generated file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("internal/modules/weather/openapi.json is out of date; run go generate ./cmd/openapigen")
+	}
+}
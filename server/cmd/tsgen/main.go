@@ -0,0 +1,72 @@
+// Command tsgen writes TypeScript interfaces for the HTTP API and telemetry
+// types so web clients stay in lockstep with the server.
+//
+//	go generate ./cmd/tsgen
+package main
+
+//go:generate go run . -o ../../../web/types/api.ts
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"cloudpico-shared/buildinfo"
+	cloudpico_shared "cloudpico-shared/types"
+
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/tsgen"
+	"cloudpico-server/internal/utils"
+)
+
+// roots are the types written to the output; the structs they reference are
+// included automatically.
+var roots = []any{
+	types.Station{},
+	types.StationDetail{},
+	types.Reading{},
+	cloudpico_shared.Telemetry{},
+	types.Annotation{},
+	types.Snapshot{},
+	types.Climate{},
+	types.Aggregate{},
+	types.Stats{},
+	types.LatestMetrics{},
+	types.QuotaStatus{},
+	buildinfo.Info{},
+	utils.Page[tsgen.T]{},
+	utils.ErrorResponse{},
+}
+
+func main() {
+	out := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	src, err := generate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgen: %v\n", err)
+		os.Exit(1)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*out, src, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate() ([]byte, error) {
+	g := tsgen.New()
+	if err := g.Add(roots...); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := g.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
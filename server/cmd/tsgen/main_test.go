@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestGeneratedFileUpToDate fails when the API types change without
+// regenerating web/types; run go generate ./cmd/tsgen.
+func TestGeneratedFileUpToDate(t *testing.T) {
+	want, err := generate()
+	if err != nil {
+		t.Fatalf("generate() = %v; want nil", err)
+	}
+	got, err := os.ReadFile("../../../web/types/api.ts")
+	if err != nil {
+		t.Fatalf("read generated file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("web/types/api.ts is out of date; run go generate ./cmd/tsgen")
+	}
+}
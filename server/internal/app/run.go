@@ -2,35 +2,41 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
 
 	"cloudpico-server/internal/config"
 	db "cloudpico-server/internal/db"
+	"cloudpico-server/internal/events"
 	httpapi "cloudpico-server/internal/httpapi"
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/modules/admin"
+	"cloudpico-server/internal/modules/alerts"
+	"cloudpico-server/internal/modules/auth"
+	"cloudpico-server/internal/modules/reports"
+	"cloudpico-server/internal/modules/status"
+	"cloudpico-server/internal/modules/uploads"
+	"cloudpico-server/internal/modules/usage"
 	weather "cloudpico-server/internal/modules/weather"
+	"cloudpico-server/internal/modules/weather/health"
+	"cloudpico-server/internal/modules/weather/photos"
+	weatherrepository "cloudpico-server/internal/modules/weather/repository"
+	weathertypes "cloudpico-server/internal/modules/weather/types"
 	weatherviews "cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/modules/weather/webhook"
 	"cloudpico-server/internal/mqtt"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-server/internal/utils"
+	"cloudpico-shared/buildinfo"
 	"cloudpico-tools/migrate"
 )
 
-func Run(ctx context.Context, cfg config.Config) error {
-	slog.Info("config loaded",
-		"appEnv", cfg.AppEnv,
-		"logLevel", cfg.LogLevel.String(),
-		"httpAddr", cfg.HTTPAddr,
-		"staticDir", cfg.StaticDir,
-		"sqliteDriver", cfg.SQLiteDriver,
-		"sqlitePath", cfg.SQLitePath,
-		"sqliteMaxOpenConns", cfg.SQLiteMaxOpenConns,
-		"sqliteMaxIdleConns", cfg.SQLiteMaxIdleConns,
-		"sqliteConnMaxLifetime", cfg.SQLiteConnMaxLifetime,
-		"mqttBroker", cfg.MQTTBroker,
-		"mqttPort", cfg.MQTTPort,
-		"mqttTopic", cfg.MQTTTopic,
-	)
+func Run(ctx context.Context, cfg config.Config, build buildinfo.Info) error {
 	dbConn, err := db.Open(cfg)
 	if err != nil {
 		return err
@@ -45,6 +51,13 @@ func Run(ctx context.Context, cfg config.Config) error {
 	if err := migrate.Run(dbConn); err != nil {
 		return err
 	}
+	if err := logConfigChanges(dbConn, cfg, build.Version, time.Now()); err != nil {
+		slog.Warn("config snapshot failed", "error", err)
+	}
+	readOnlyConn := openSQLConsole(cfg)
+	if readOnlyConn != nil {
+		defer db.Close(readOnlyConn)
+	}
 
 	var ok int
 	err = dbConn.QueryRow(`SELECT 1`).Scan(&ok)
@@ -55,6 +68,9 @@ func Run(ctx context.Context, cfg config.Config) error {
 		return errors.New("database connection failed")
 	}
 	slog.Info("database connection successful")
+	db.ExportStats(dbConn)
+	dbMonitor := db.NewMonitor(dbConn, cfg)
+	go dbMonitor.Run(ctx)
 
 	// Set MQTT handler before Connect so OnConnectHandler can subscribe immediately.
 	// The broker may send queued messages right after CONNACK; we must be subscribed
@@ -62,9 +78,103 @@ func Run(ctx context.Context, cfg config.Config) error {
 	if err := weatherviews.LoadTemplates(); err != nil {
 		return err
 	}
+	bus := events.NewBus()
 	mqttSubscriber := mqtt.NewSubscriber(cfg)
-	mux := httpapi.NewMux(dbConn, cfg.StaticDir, mqttSubscriber)
-	weather.RegisterFeature(mux, dbConn, mqttSubscriber)
+	mux := httpapi.NewMux(dbConn, dbMonitor, cfg.StaticDir, mqttSubscriber, build)
+	if cfg.DebugEndpoints {
+		httpapi.RegisterDebug(mux)
+		if !cfg.AuthEnabled {
+			slog.Warn("debug endpoints are served without authentication; enable AUTH_ENABLED or keep HTTP_ADDR private")
+		}
+	}
+	cookies, err := newCookieSigner(cfg.CookieSecret)
+	if err != nil {
+		return err
+	}
+	weatherRepository, err := weatherrepository.Open(dbConn, cfg.ReadingsBackend)
+	if err != nil {
+		return err
+	}
+	weatherRepository = weatherrepository.NewCoalescing(weatherrepository.NewInstrumented(weatherRepository, cfg.SlowQueryThreshold))
+	var webhooks []webhook.Definition
+	if cfg.WebhooksFile != "" {
+		webhooks, err = webhook.LoadDefinitions(cfg.WebhooksFile)
+		if err != nil {
+			return err
+		}
+		slog.Info("webhooks loaded", "count", len(webhooks), "file", cfg.WebhooksFile)
+	}
+	healthWeights, err := health.ParseWeights(cfg.HealthWeights)
+	if err != nil {
+		return fmt.Errorf("invalid HEALTH_WEIGHTS: %w", err)
+	}
+	var uploadDests []uploads.Destination
+	if cfg.UploadsFile != "" {
+		uploadDests, err = uploads.LoadDestinations(cfg.UploadsFile)
+		if err != nil {
+			return err
+		}
+		slog.Info("upload destinations loaded", "count", len(uploadDests), "file", cfg.UploadsFile)
+	}
+	jwtSecret, err := newJWTSecret(cfg.JWTSecret)
+	if err != nil {
+		return err
+	}
+	sched := scheduler.New()
+	diskGuard := db.NewDiskGuard(dbConn, cfg, func(free uint64) {
+		onDiskSpaceLow(bus, sched, free, cfg.DiskMinFreeBytes)
+	})
+	usageModule := usage.NewModule()
+	authModule := auth.NewModule(auth.Options{
+		Enabled:       cfg.AuthEnabled,
+		Secret:        jwtSecret,
+		TTL:           cfg.JWTTTL,
+		AdminUsername: cfg.AuthAdminUsername,
+		AdminPassword: cfg.AuthAdminPassword,
+	})
+	reportGenerator := reports.NewGenerator(weatherRepository, cfg.ReportsDir, 7*24*time.Hour, cfg.ReportPDFCommand)
+	modules := []module.Module{
+		weather.NewModule(weather.Options{
+			Repository:          weatherRepository,
+			Photos:              photos.NewStore(cfg.UploadsDir, cfg.UploadMaxBytes),
+			Cookies:             cookies,
+			MergeWindow:         cfg.ReadingsMergeWindow,
+			IngestAcks:          cfg.IngestAcks,
+			Webhooks:            webhooks,
+			RetentionDays:       cfg.ReadingsRetentionDays,
+			HourlyRetentionDays: cfg.HourlyRollupRetentionDays,
+			DailyRetentionDays:  cfg.DailyRollupRetentionDays,
+			HealthWeights:       healthWeights,
+			Quotas: weathertypes.Quotas{
+				MaxStations:       cfg.QuotaMaxStations,
+				MaxReadingsPerDay: cfg.QuotaMaxReadingsPerDay,
+				MaxRetentionDays:  cfg.QuotaMaxRetentionDays,
+			},
+			APIDocs:   cfg.AppEnv == "dev",
+			DiskSpace: diskGuard.Check,
+		}),
+		reports.NewModule(reportGenerator, cfg.ReportSchedule),
+		admin.NewModule(sched, readOnlyConn),
+		alerts.NewModule(),
+		uploads.NewModule(uploadDests, weatherRepository),
+		usageModule,
+		status.NewModule(build, weatherRepository),
+		authModule,
+	}
+	if err := module.Migrate(dbConn, modules); err != nil {
+		return err
+	}
+	deps := module.Deps{DB: dbConn, Subscriber: mqttSubscriber, Bus: bus, Scheduler: sched}
+	if err := module.RegisterRoutes(mux, deps, modules); err != nil {
+		return err
+	}
+	if err := module.StartWorkers(ctx, modules); err != nil {
+		return err
+	}
+	schedCtx, schedCancel := context.WithCancel(ctx)
+	defer schedCancel()
+	sched.Start(schedCtx)
+	go diskGuard.Run(schedCtx)
 
 	// Use a short timeout for initial MQTT connect so we don't block startup when broker is down (e.g. E2E).
 	connectCtx, connectCancel := context.WithTimeout(ctx, 5*time.Second)
@@ -75,7 +185,7 @@ func Run(ctx context.Context, cfg config.Config) error {
 		// Continue so HTTP server and /healthz still work when MQTT is unavailable (e.g. E2E).
 	}
 
-	srv := httpapi.NewServer(cfg, mux)
+	srv := httpapi.NewServer(cfg, mux, usageModule.Handler(authModule.Handler(mux), usage.ClientInternal))
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -83,6 +193,19 @@ func Run(ctx context.Context, cfg config.Config) error {
 		errCh <- srv.ListenAndServe()
 	}()
 
+	// The public API is optional: when it fails the private server keeps running.
+	// It serves anonymous read-only data, so it is not behind authModule.
+	var publicSrv *http.Server
+	if cfg.PublicAPIAddr != "" {
+		publicSrv = httpapi.NewPublicServer(cfg, mux, usageModule.Handler(mux, usage.ClientPublic))
+		go func() {
+			slog.Info("public api listening", "addr", cfg.PublicAPIAddr)
+			if err := publicSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("public api stopped", "error", err)
+			}
+		}()
+	}
+
 	select {
 	case <-ctx.Done():
 	case err := <-errCh:
@@ -95,13 +218,26 @@ func Run(ctx context.Context, cfg config.Config) error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	slog.Info("scheduler stopping")
+	schedCancel()
+	sched.Wait()
+
 	slog.Info("mqtt disconnecting")
 	mqttSubscriber.Disconnect()
 
 	slog.Info("http shutting down")
+	if publicSrv != nil {
+		if err := publicSrv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("public api shutdown", "error", err)
+		}
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		return err
 	}
+	// Requests have finished; keep the counts since the last scheduled flush.
+	if err := usageModule.Flush(shutdownCtx); err != nil {
+		slog.Error("flush api usage", "error", err)
+	}
 
 	err = <-errCh
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -110,3 +246,87 @@ func Run(ctx context.Context, cfg config.Config) error {
 
 	return ctx.Err()
 }
+
+// newCookieSigner returns a signer keyed by secret, or by a random key when no
+// secret is configured.
+// openSQLConsole opens the read-only pool the admin SQL console queries
+// through, or returns nil to leave the console out. It reads every table,
+// password and API key hashes included, so it is served only with auth.
+func openSQLConsole(cfg config.Config) *sql.DB {
+	if !cfg.AuthEnabled {
+		slog.Warn("sql console disabled; it requires AUTH_ENABLED")
+		return nil
+	}
+	readOnlyConn, err := db.OpenReadOnly(cfg)
+	if err != nil {
+		slog.Warn("sql console disabled", "error", err)
+		return nil
+	}
+	return readOnlyConn
+}
+
+func newCookieSigner(secret string) (*utils.CookieSigner, error) {
+	if secret != "" {
+		return utils.NewCookieSigner([]byte(secret)), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate cookie key: %w", err)
+	}
+	slog.Warn("COOKIE_SECRET not set; using a random key, UI state cookies reset on restart")
+	return utils.NewCookieSigner(key), nil
+}
+
+// newJWTSecret returns secret as a key, or a random key when no secret is
+// configured.
+func newJWTSecret(secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generate jwt key: %w", err)
+	}
+	slog.Warn("JWT_SECRET not set; using a random key, logins end on restart")
+	return key, nil
+}
+
+// onDiskSpaceLow raises a server-wide alert and runs the retention job early,
+// as the disk guard has started refusing ingest.
+func onDiskSpaceLow(bus *events.Bus, sched *scheduler.Scheduler, free uint64, minFree int64) {
+	bus.Publish(events.Event{
+		Topic: events.AlertFired,
+		Payload: alerts.Fired{
+			Rule:     "disk_space",
+			Severity: alerts.SeverityCritical,
+			Message:  fmt.Sprintf("Only %d MiB free on the database volume (minimum %d MiB); ingest is refused until space is freed.", free>>20, minFree>>20),
+		},
+	})
+	if _, err := sched.Trigger("retention"); err != nil {
+		slog.Warn("disk space low: retention not triggered", "error", err)
+	}
+}
+
+// logConfigChanges logs how cfg differs from the configuration of the last
+// start, if any, and saves it for the next one.
+func logConfigChanges(dbConn *sql.DB, cfg config.Config, version string, now time.Time) error {
+	prev, err := config.LoadSnapshot(dbConn)
+	if err != nil {
+		return err
+	}
+	cur := config.Snapshot{Settings: cfg.Settings(), Version: version, StartedAt: now}
+	if prev != nil {
+		if changes := config.Diff(prev.Settings, cur.Settings); len(changes) > 0 {
+			attrs := make([]any, len(changes))
+			for i, c := range changes {
+				attrs[i] = slog.Group(c.Name, "old", c.Old, "new", c.New)
+			}
+			slog.Info("config changed since last start",
+				"previous_version", prev.Version,
+				"previous_start", prev.StartedAt,
+				slog.Group("changes", attrs...),
+			)
+		}
+	}
+	return config.SaveSnapshot(dbConn, cur)
+}
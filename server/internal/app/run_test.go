@@ -0,0 +1,29 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cloudpico-server/internal/config"
+	"cloudpico-server/internal/db"
+)
+
+func TestOpenSQLConsole(t *testing.T) {
+	cfg := config.Config{SQLiteDriver: "sqlite3", SQLitePath: filepath.Join(t.TempDir(), "app.db")}
+	rw, err := db.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Close()
+
+	if ro := openSQLConsole(cfg); ro != nil {
+		ro.Close()
+		t.Error("openSQLConsole() opened the console without auth")
+	}
+	cfg.AuthEnabled = true
+	ro := openSQLConsole(cfg)
+	if ro == nil {
+		t.Fatal("openSQLConsole() = nil with auth enabled")
+	}
+	ro.Close()
+}
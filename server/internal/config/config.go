@@ -1,37 +1,162 @@
 package config
 
 import (
+	"cloudpico-server/internal/scheduler"
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// Config is the server configuration. Each field is read from the environment
+// variable named by its env tag; the secret option keeps the value out of logs
+// and the file option marks the path of a config file (see Settings).
 type Config struct {
-	AppEnv   string
-	LogLevel slog.Level
-	HTTPAddr string
+	AppEnv   string     `env:"APP_ENV"`
+	LogLevel slog.Level `env:"LOG_LEVEL"`
+	HTTPAddr string     `env:"HTTP_ADDR"`
+	// HTTPReadTimeout bounds reading a request's headers and body, and
+	// HTTPHandlerTimeout handling it (see httpapi.NewServer); 0 disables
+	// either.
+	HTTPReadTimeout    time.Duration `env:"HTTP_READ_TIMEOUT"`
+	HTTPHandlerTimeout time.Duration `env:"HTTP_HANDLER_TIMEOUT"`
 
 	// StaticDir is the absolute path to the directory served at /static/.
 	// Set via STATIC_DIR (relative paths are resolved against the process working directory at startup).
-	StaticDir string
-
-	SQLiteDriver          string
-	SQLiteDSN             string
-	SQLitePath            string
-	SQLiteMaxOpenConns    int
-	SQLiteMaxIdleConns    int
-	SQLiteConnMaxLifetime time.Duration
-
-	MQTTBroker   string
-	MQTTPort     int
-	MQTTClientID string
-	MQTTTopic    string // Topic pattern to subscribe to, e.g., "stations/+/telemetry"
+	StaticDir string `env:"STATIC_DIR"`
+
+	SQLiteDriver          string        `env:"SQLITE_DRIVER"`
+	SQLiteDSN             string        `env:"SQLITE_DSN"`
+	SQLitePath            string        `env:"SQLITE_PATH"`
+	SQLiteMaxOpenConns    int           `env:"SQLITE_MAX_OPEN_CONNS"`
+	SQLiteMaxIdleConns    int           `env:"SQLITE_MAX_IDLE_CONNS"`
+	SQLiteConnMaxLifetime time.Duration `env:"SQLITE_CONN_MAX_LIFETIME"`
+	// SQLiteLogQueries logs every SQL statement with its duration and row
+	// count at DEBUG, and failed or slow (SlowQueryThreshold) ones at WARN.
+	SQLiteLogQueries bool `env:"SQLITE_LOG_QUERIES"`
+	// SQLiteLogRedact lists glob patterns of column and argument names whose
+	// values the SQL logger replaces with "<redacted>".
+	SQLiteLogRedact []string `env:"SQLITE_LOG_REDACT"`
+	// DBHealthInterval is how often the database health monitor writes its
+	// heartbeat (see db.Monitor).
+	DBHealthInterval time.Duration `env:"DB_HEALTH_INTERVAL"`
+	// DiskMinFreeBytes is the free space on the database volume below which
+	// ingest is refused (see db.DiskGuard). Zero disables the guard.
+	DiskMinFreeBytes int64 `env:"DISK_MIN_FREE_BYTES"`
+
+	MQTTBroker   string `env:"MQTT_BROKER"`
+	MQTTPort     int    `env:"MQTT_PORT"`
+	MQTTClientID string `env:"MQTT_CLIENT_ID"`
+	MQTTTopic    string `env:"MQTT_TOPIC"` // Topic pattern to subscribe to, e.g., "stations/+/telemetry"
+
+	// UploadsDir is the absolute path where uploaded files (station photos) are stored and served from at /uploads/.
+	UploadsDir     string `env:"UPLOADS_DIR"`
+	UploadMaxBytes int64  `env:"UPLOAD_MAX_BYTES"`
+
+	// ReportsDir is the absolute path where generated reports are written and served from at /reports/.
+	ReportsDir string `env:"REPORTS_DIR"`
+	// ReportSchedule is a cron expression or interval spec (see scheduler.ParseSpec).
+	ReportSchedule string `env:"REPORT_SCHEDULE"`
+	// ReportPDFCommand optionally converts each HTML report to PDF. {html} and {pdf} are replaced
+	// with the input and output paths, e.g. "chromium --headless --print-to-pdf={pdf} {html}".
+	ReportPDFCommand string `env:"REPORT_PDF_COMMAND"`
+
+	// CookieSecret is the HMAC key for signed UI state cookies. When empty a random
+	// key is generated at startup, so cookies reset on every restart.
+	CookieSecret string `env:"COOKIE_SECRET,secret"`
+
+	// ReadingsMergeWindow buckets partial readings (some metrics missing) so they
+	// merge into the reading already stored in the same bucket. Zero disables it.
+	ReadingsMergeWindow time.Duration `env:"READINGS_MERGE_WINDOW"`
+	// IngestAcks publishes an ack on stations/{id}/ack/{sequence} once a
+	// reading carrying a sequence is stored, for gateways that buffer readings
+	// until the server has them.
+	IngestAcks bool `env:"INGEST_ACKS"`
+	// ReadingsRetentionDays is how many days of raw readings the daily
+	// retention job keeps for stations without their own override. Zero keeps
+	// readings forever.
+	ReadingsRetentionDays int `env:"READINGS_RETENTION_DAYS"`
+	// HourlyRollupRetentionDays and DailyRollupRetentionDays are how many
+	// days of hourly and daily rollups the retention job keeps likewise.
+	HourlyRollupRetentionDays int `env:"HOURLY_ROLLUP_RETENTION_DAYS"`
+	DailyRollupRetentionDays  int `env:"DAILY_ROLLUP_RETENTION_DAYS"`
+	// QuotaMaxStations, QuotaMaxReadingsPerDay and QuotaMaxRetentionDays are
+	// the storage quotas (see types.Quotas). Zero leaves a quota unlimited.
+	QuotaMaxStations       int `env:"QUOTA_MAX_STATIONS"`
+	QuotaMaxReadingsPerDay int `env:"QUOTA_MAX_READINGS_PER_DAY"`
+	QuotaMaxRetentionDays  int `env:"QUOTA_MAX_RETENTION_DAYS"`
+	// HealthWeights weighs the components of station health scores, as
+	// name=weight pairs over the defaults (see health.ParseWeights).
+	HealthWeights string `env:"HEALTH_WEIGHTS"`
+	// ReadingsBackend selects where readings are stored; see repository.Open.
+	ReadingsBackend string `env:"READINGS_BACKEND"`
+	// WebhooksFile is a JSON file of inbound webhook definitions (see
+	// webhook.ParseDefinitions). Empty disables webhooks.
+	WebhooksFile string `env:"WEBHOOKS_FILE,file"`
+	// UploadsFile is a JSON file of Weather Underground / CWOP upload
+	// destinations (see uploads.ParseDestinations). Empty disables uploads.
+	UploadsFile string `env:"UPLOADS_FILE,file"`
+	// SlowQueryThreshold is the repository call duration logged at WARN; with
+	// SQLiteLogQueries it applies to single statements too. Zero disables the
+	// log; durations are exported at /metrics regardless.
+	SlowQueryThreshold time.Duration `env:"SLOW_QUERY_THRESHOLD"`
+	// DebugEndpoints serves the pprof profiles and expvar variables under
+	// /debug (see httpapi.RegisterDebug), restricted to admins when auth is
+	// enabled.
+	DebugEndpoints bool `env:"DEBUG_ENDPOINTS"`
+
+	// PublicAPIAddr is a second listen address serving a read-only subset of
+	// the API to anyone (see httpapi.NewPublicHandler). Empty disables it.
+	PublicAPIAddr string `env:"PUBLIC_API_ADDR"`
+	// PublicAPIRateLimit is the number of public API requests per minute
+	// allowed from one IP.
+	PublicAPIRateLimit int `env:"PUBLIC_API_RATE_LIMIT"`
+	// PublicAPILicense and PublicAPIAttribution are sent with every public API
+	// response; an empty attribution is omitted.
+	PublicAPILicense     string `env:"PUBLIC_API_LICENSE"`
+	PublicAPIAttribution string `env:"PUBLIC_API_ATTRIBUTION"`
+
+	// APIRateLimit is the number of /api/ requests per minute allowed from one
+	// client (API key or IP) on HTTPAddr; 0 disables the limit. APIRateBurst is
+	// how many a client may send at once.
+	APIRateLimit int `env:"API_RATE_LIMIT"`
+	APIRateBurst int `env:"API_RATE_BURST"`
+
+	// Compression lists the response encodings offered ("gzip", "zstd"), most
+	// preferred first; empty disables compression. CompressionMinBytes is the
+	// smallest response body compressed.
+	Compression         []string `env:"HTTP_COMPRESSION"`
+	CompressionMinBytes int      `env:"HTTP_COMPRESSION_MIN_BYTES"`
+
+	// AuthEnabled requires a login for the dashboard and the API, with admin
+	// and write routes reserved to admins (see the auth module).
+	AuthEnabled bool `env:"AUTH_ENABLED"`
+	// JWTSecret is the HMAC key signing login tokens. When empty a random key is
+	// generated at startup, so every login ends on restart.
+	JWTSecret string `env:"JWT_SECRET,secret"`
+	// JWTTTL is how long a login token stays valid.
+	JWTTTL time.Duration `env:"JWT_TTL"`
+	// AuthAdminUsername and AuthAdminPassword create an admin at startup when
+	// no user of that name exists. Empty creates none.
+	AuthAdminUsername string `env:"AUTH_ADMIN_USERNAME"`
+	AuthAdminPassword string `env:"AUTH_ADMIN_PASSWORD,secret"`
 }
 
+// defaultSQLiteLogRedact covers credentials that auth tables are expected to
+// hold (password hashes, API keys, tokens).
+const defaultSQLiteLogRedact = "*password*,*secret*,*token*,*_hash,api_key*"
+
+// minCookieSecretLen is the shortest accepted COOKIE_SECRET (256 bits).
+const minCookieSecretLen = 32
+
+// minJWTSecretLen is the shortest accepted JWT_SECRET (256 bits).
+const minJWTSecretLen = 32
+
 func LoadFromEnv() (Config, error) {
 	appEnv := strings.TrimSpace(os.Getenv("APP_ENV"))
 	if appEnv == "" {
@@ -57,6 +182,30 @@ func LoadFromEnv() (Config, error) {
 		httpAddr = ":8080"
 	}
 
+	httpReadTimeoutStr := strings.TrimSpace(os.Getenv("HTTP_READ_TIMEOUT"))
+	if httpReadTimeoutStr == "" {
+		httpReadTimeoutStr = "10s"
+	}
+	httpReadTimeout, err := time.ParseDuration(httpReadTimeoutStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HTTP_READ_TIMEOUT %q: %w", httpReadTimeoutStr, err)
+	}
+	if httpReadTimeout < 0 {
+		return Config{}, fmt.Errorf("HTTP_READ_TIMEOUT must not be negative, got %v", httpReadTimeout)
+	}
+
+	httpHandlerTimeoutStr := strings.TrimSpace(os.Getenv("HTTP_HANDLER_TIMEOUT"))
+	if httpHandlerTimeoutStr == "" {
+		httpHandlerTimeoutStr = "10s"
+	}
+	httpHandlerTimeout, err := time.ParseDuration(httpHandlerTimeoutStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HTTP_HANDLER_TIMEOUT %q: %w", httpHandlerTimeoutStr, err)
+	}
+	if httpHandlerTimeout < 0 {
+		return Config{}, fmt.Errorf("HTTP_HANDLER_TIMEOUT must not be negative, got %v", httpHandlerTimeout)
+	}
+
 	staticDir := strings.TrimSpace(os.Getenv("STATIC_DIR"))
 	if staticDir == "" {
 		staticDir = "static"
@@ -103,6 +252,30 @@ func LoadFromEnv() (Config, error) {
 		return Config{}, fmt.Errorf("invalid SQLITE_CONN_MAX_LIFETIME %q: %w", strings.TrimSpace(os.Getenv("SQLITE_CONN_MAX_LIFETIME")), err)
 	}
 
+	dbHealthIntervalStr := strings.TrimSpace(os.Getenv("DB_HEALTH_INTERVAL"))
+	if dbHealthIntervalStr == "" {
+		dbHealthIntervalStr = "15s"
+	}
+	dbHealthInterval, err := time.ParseDuration(dbHealthIntervalStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid DB_HEALTH_INTERVAL %q: %w", dbHealthIntervalStr, err)
+	}
+	if dbHealthInterval < time.Second {
+		return Config{}, fmt.Errorf("DB_HEALTH_INTERVAL must be at least 1s, got %v", dbHealthInterval)
+	}
+
+	diskMinFreeBytesStr := strings.TrimSpace(os.Getenv("DISK_MIN_FREE_BYTES"))
+	if diskMinFreeBytesStr == "" {
+		diskMinFreeBytesStr = "268435456" // 256 MiB
+	}
+	diskMinFreeBytes, err := strconv.ParseInt(diskMinFreeBytesStr, 10, 64)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid DISK_MIN_FREE_BYTES %q: %w", diskMinFreeBytesStr, err)
+	}
+	if diskMinFreeBytes < 0 {
+		return Config{}, fmt.Errorf("DISK_MIN_FREE_BYTES must not be negative, got %d", diskMinFreeBytes)
+	}
+
 	mqttBroker := strings.TrimSpace(os.Getenv("MQTT_BROKER"))
 	if mqttBroker == "" {
 		mqttBroker = "localhost"
@@ -127,21 +300,352 @@ func LoadFromEnv() (Config, error) {
 		mqttTopic = "stations/+/telemetry"
 	}
 
+	uploadsDir := strings.TrimSpace(os.Getenv("UPLOADS_DIR"))
+	if uploadsDir == "" {
+		uploadsDir = "../dev/uploads"
+	}
+	uploadsDir, err = filepath.Abs(uploadsDir)
+	if err != nil {
+		return Config{}, fmt.Errorf("UPLOADS_DIR %q: %w", uploadsDir, err)
+	}
+
+	uploadMaxBytesStr := strings.TrimSpace(os.Getenv("UPLOAD_MAX_BYTES"))
+	if uploadMaxBytesStr == "" {
+		uploadMaxBytesStr = "2097152"
+	}
+	uploadMaxBytes, err := strconv.ParseInt(uploadMaxBytesStr, 10, 64)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid UPLOAD_MAX_BYTES %q: %w", uploadMaxBytesStr, err)
+	}
+	if uploadMaxBytes <= 0 {
+		return Config{}, fmt.Errorf("invalid UPLOAD_MAX_BYTES %q: must be positive", uploadMaxBytesStr)
+	}
+
+	reportsDir := strings.TrimSpace(os.Getenv("REPORTS_DIR"))
+	if reportsDir == "" {
+		reportsDir = "../dev/reports"
+	}
+	reportsDir, err = filepath.Abs(reportsDir)
+	if err != nil {
+		return Config{}, fmt.Errorf("REPORTS_DIR %q: %w", reportsDir, err)
+	}
+
+	reportSchedule := strings.TrimSpace(os.Getenv("REPORT_SCHEDULE"))
+	if reportSchedule == "" {
+		reportSchedule = "0 6 * * 1"
+	}
+	if _, err := scheduler.ParseSpec(reportSchedule); err != nil {
+		return Config{}, fmt.Errorf("invalid REPORT_SCHEDULE %q: %w", reportSchedule, err)
+	}
+
+	reportPDFCommand := strings.TrimSpace(os.Getenv("REPORT_PDF_COMMAND"))
+
+	readingsMergeWindowStr := strings.TrimSpace(os.Getenv("READINGS_MERGE_WINDOW"))
+	if readingsMergeWindowStr == "" {
+		readingsMergeWindowStr = "0s"
+	}
+	readingsMergeWindow, err := time.ParseDuration(readingsMergeWindowStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid READINGS_MERGE_WINDOW %q: %w", readingsMergeWindowStr, err)
+	}
+	if readingsMergeWindow < 0 {
+		return Config{}, fmt.Errorf("READINGS_MERGE_WINDOW must not be negative, got %v", readingsMergeWindow)
+	}
+
+	ingestAcksStr := strings.TrimSpace(os.Getenv("INGEST_ACKS"))
+	if ingestAcksStr == "" {
+		ingestAcksStr = "true"
+	}
+	ingestAcks, err := strconv.ParseBool(ingestAcksStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid INGEST_ACKS %q: %w", ingestAcksStr, err)
+	}
+
+	readingsRetentionDaysStr := strings.TrimSpace(os.Getenv("READINGS_RETENTION_DAYS"))
+	if readingsRetentionDaysStr == "" {
+		readingsRetentionDaysStr = "0"
+	}
+	readingsRetentionDays, err := strconv.Atoi(readingsRetentionDaysStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid READINGS_RETENTION_DAYS %q: %w", readingsRetentionDaysStr, err)
+	}
+	if readingsRetentionDays < 0 {
+		return Config{}, fmt.Errorf("READINGS_RETENTION_DAYS must not be negative, got %d", readingsRetentionDays)
+	}
+
+	hourlyRollupRetentionDaysStr := strings.TrimSpace(os.Getenv("HOURLY_ROLLUP_RETENTION_DAYS"))
+	if hourlyRollupRetentionDaysStr == "" {
+		hourlyRollupRetentionDaysStr = "0"
+	}
+	hourlyRollupRetentionDays, err := strconv.Atoi(hourlyRollupRetentionDaysStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HOURLY_ROLLUP_RETENTION_DAYS %q: %w", hourlyRollupRetentionDaysStr, err)
+	}
+	if hourlyRollupRetentionDays < 0 {
+		return Config{}, fmt.Errorf("HOURLY_ROLLUP_RETENTION_DAYS must not be negative, got %d", hourlyRollupRetentionDays)
+	}
+
+	dailyRollupRetentionDaysStr := strings.TrimSpace(os.Getenv("DAILY_ROLLUP_RETENTION_DAYS"))
+	if dailyRollupRetentionDaysStr == "" {
+		dailyRollupRetentionDaysStr = "0"
+	}
+	dailyRollupRetentionDays, err := strconv.Atoi(dailyRollupRetentionDaysStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid DAILY_ROLLUP_RETENTION_DAYS %q: %w", dailyRollupRetentionDaysStr, err)
+	}
+	if dailyRollupRetentionDays < 0 {
+		return Config{}, fmt.Errorf("DAILY_ROLLUP_RETENTION_DAYS must not be negative, got %d", dailyRollupRetentionDays)
+	}
+
+	quotaMaxStationsStr := strings.TrimSpace(os.Getenv("QUOTA_MAX_STATIONS"))
+	if quotaMaxStationsStr == "" {
+		quotaMaxStationsStr = "0"
+	}
+	quotaMaxStations, err := strconv.Atoi(quotaMaxStationsStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid QUOTA_MAX_STATIONS %q: %w", quotaMaxStationsStr, err)
+	}
+	if quotaMaxStations < 0 {
+		return Config{}, fmt.Errorf("QUOTA_MAX_STATIONS must not be negative, got %d", quotaMaxStations)
+	}
+
+	quotaMaxReadingsPerDayStr := strings.TrimSpace(os.Getenv("QUOTA_MAX_READINGS_PER_DAY"))
+	if quotaMaxReadingsPerDayStr == "" {
+		quotaMaxReadingsPerDayStr = "0"
+	}
+	quotaMaxReadingsPerDay, err := strconv.Atoi(quotaMaxReadingsPerDayStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid QUOTA_MAX_READINGS_PER_DAY %q: %w", quotaMaxReadingsPerDayStr, err)
+	}
+	if quotaMaxReadingsPerDay < 0 {
+		return Config{}, fmt.Errorf("QUOTA_MAX_READINGS_PER_DAY must not be negative, got %d", quotaMaxReadingsPerDay)
+	}
+
+	quotaMaxRetentionDaysStr := strings.TrimSpace(os.Getenv("QUOTA_MAX_RETENTION_DAYS"))
+	if quotaMaxRetentionDaysStr == "" {
+		quotaMaxRetentionDaysStr = "0"
+	}
+	quotaMaxRetentionDays, err := strconv.Atoi(quotaMaxRetentionDaysStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid QUOTA_MAX_RETENTION_DAYS %q: %w", quotaMaxRetentionDaysStr, err)
+	}
+	if quotaMaxRetentionDays < 0 {
+		return Config{}, fmt.Errorf("QUOTA_MAX_RETENTION_DAYS must not be negative, got %d", quotaMaxRetentionDays)
+	}
+
+	healthWeights := strings.TrimSpace(os.Getenv("HEALTH_WEIGHTS"))
+
+	readingsBackend := strings.ToLower(strings.TrimSpace(os.Getenv("READINGS_BACKEND")))
+	if readingsBackend == "" {
+		readingsBackend = "sqlite"
+	}
+
+	webhooksFile := strings.TrimSpace(os.Getenv("WEBHOOKS_FILE"))
+	uploadsFile := strings.TrimSpace(os.Getenv("UPLOADS_FILE"))
+
+	sqliteLogQueriesStr := strings.TrimSpace(os.Getenv("SQLITE_LOG_QUERIES"))
+	if sqliteLogQueriesStr == "" {
+		sqliteLogQueriesStr = "false"
+	}
+	sqliteLogQueries, err := strconv.ParseBool(sqliteLogQueriesStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SQLITE_LOG_QUERIES %q: %w", sqliteLogQueriesStr, err)
+	}
+
+	sqliteLogRedactStr, ok := os.LookupEnv("SQLITE_LOG_REDACT")
+	if !ok {
+		sqliteLogRedactStr = defaultSQLiteLogRedact
+	}
+	var sqliteLogRedact []string
+	for _, p := range strings.Split(sqliteLogRedactStr, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			if _, err := path.Match(p, ""); err != nil {
+				return Config{}, fmt.Errorf("invalid SQLITE_LOG_REDACT pattern %q: %w", p, err)
+			}
+			sqliteLogRedact = append(sqliteLogRedact, p)
+		}
+	}
+
+	slowQueryThresholdStr := strings.TrimSpace(os.Getenv("SLOW_QUERY_THRESHOLD"))
+	if slowQueryThresholdStr == "" {
+		slowQueryThresholdStr = "250ms"
+	}
+	slowQueryThreshold, err := time.ParseDuration(slowQueryThresholdStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SLOW_QUERY_THRESHOLD %q: %w", slowQueryThresholdStr, err)
+	}
+	if slowQueryThreshold < 0 {
+		return Config{}, fmt.Errorf("SLOW_QUERY_THRESHOLD must not be negative, got %v", slowQueryThreshold)
+	}
+
+	debugEndpointsStr := strings.TrimSpace(os.Getenv("DEBUG_ENDPOINTS"))
+	if debugEndpointsStr == "" {
+		debugEndpointsStr = "false"
+	}
+	debugEndpoints, err := strconv.ParseBool(debugEndpointsStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid DEBUG_ENDPOINTS %q: %w", debugEndpointsStr, err)
+	}
+
+	publicAPIAddr := strings.TrimSpace(os.Getenv("PUBLIC_API_ADDR"))
+	if publicAPIAddr != "" && publicAPIAddr == httpAddr {
+		return Config{}, fmt.Errorf("PUBLIC_API_ADDR must differ from HTTP_ADDR (%s)", httpAddr)
+	}
+	publicAPIRateLimitStr := strings.TrimSpace(os.Getenv("PUBLIC_API_RATE_LIMIT"))
+	if publicAPIRateLimitStr == "" {
+		publicAPIRateLimitStr = "60"
+	}
+	publicAPIRateLimit, err := strconv.Atoi(publicAPIRateLimitStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid PUBLIC_API_RATE_LIMIT %q: %w", publicAPIRateLimitStr, err)
+	}
+	if publicAPIRateLimit <= 0 {
+		return Config{}, fmt.Errorf("PUBLIC_API_RATE_LIMIT must be > 0, got %d", publicAPIRateLimit)
+	}
+	publicAPILicense := strings.TrimSpace(os.Getenv("PUBLIC_API_LICENSE"))
+	if publicAPILicense == "" {
+		publicAPILicense = "CC-BY-4.0"
+	}
+	publicAPIAttribution := strings.TrimSpace(os.Getenv("PUBLIC_API_ATTRIBUTION"))
+
+	apiRateLimitStr := strings.TrimSpace(os.Getenv("API_RATE_LIMIT"))
+	if apiRateLimitStr == "" {
+		apiRateLimitStr = "600"
+	}
+	apiRateLimit, err := strconv.Atoi(apiRateLimitStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid API_RATE_LIMIT %q: %w", apiRateLimitStr, err)
+	}
+	if apiRateLimit < 0 {
+		return Config{}, fmt.Errorf("API_RATE_LIMIT must be >= 0, got %d", apiRateLimit)
+	}
+	apiRateBurstStr := strings.TrimSpace(os.Getenv("API_RATE_BURST"))
+	if apiRateBurstStr == "" {
+		apiRateBurstStr = "100"
+	}
+	apiRateBurst, err := strconv.Atoi(apiRateBurstStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid API_RATE_BURST %q: %w", apiRateBurstStr, err)
+	}
+	if apiRateBurst <= 0 {
+		return Config{}, fmt.Errorf("API_RATE_BURST must be > 0, got %d", apiRateBurst)
+	}
+
+	compressionStr, ok := os.LookupEnv("HTTP_COMPRESSION")
+	if !ok {
+		compressionStr = "gzip"
+	}
+	var compression []string
+	for _, enc := range strings.Split(compressionStr, ",") {
+		enc = strings.ToLower(strings.TrimSpace(enc))
+		switch {
+		case enc == "":
+		case enc != "gzip" && enc != "zstd":
+			return Config{}, fmt.Errorf("invalid HTTP_COMPRESSION encoding %q (allowed: gzip, zstd)", enc)
+		case !slices.Contains(compression, enc):
+			compression = append(compression, enc)
+		}
+	}
+	compressionMinBytesStr := strings.TrimSpace(os.Getenv("HTTP_COMPRESSION_MIN_BYTES"))
+	if compressionMinBytesStr == "" {
+		compressionMinBytesStr = "1024"
+	}
+	compressionMinBytes, err := strconv.Atoi(compressionMinBytesStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid HTTP_COMPRESSION_MIN_BYTES %q: %w", compressionMinBytesStr, err)
+	}
+	if compressionMinBytes < 0 {
+		return Config{}, fmt.Errorf("HTTP_COMPRESSION_MIN_BYTES must be >= 0, got %d", compressionMinBytes)
+	}
+
+	cookieSecret := strings.TrimSpace(os.Getenv("COOKIE_SECRET"))
+	if cookieSecret != "" && len(cookieSecret) < minCookieSecretLen {
+		return Config{}, fmt.Errorf("invalid COOKIE_SECRET: must be at least %d bytes", minCookieSecretLen)
+	}
+
+	authEnabledStr := strings.TrimSpace(os.Getenv("AUTH_ENABLED"))
+	if authEnabledStr == "" {
+		authEnabledStr = "false"
+	}
+	authEnabled, err := strconv.ParseBool(authEnabledStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid AUTH_ENABLED %q: %w", authEnabledStr, err)
+	}
+	jwtSecret := strings.TrimSpace(os.Getenv("JWT_SECRET"))
+	if jwtSecret != "" && len(jwtSecret) < minJWTSecretLen {
+		return Config{}, fmt.Errorf("invalid JWT_SECRET: must be at least %d bytes", minJWTSecretLen)
+	}
+	jwtTTLStr := strings.TrimSpace(os.Getenv("JWT_TTL"))
+	if jwtTTLStr == "" {
+		jwtTTLStr = "24h"
+	}
+	jwtTTL, err := time.ParseDuration(jwtTTLStr)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid JWT_TTL %q: %w", jwtTTLStr, err)
+	}
+	if jwtTTL <= 0 {
+		return Config{}, fmt.Errorf("JWT_TTL must be > 0, got %s", jwtTTL)
+	}
+	authAdminUsername := strings.TrimSpace(os.Getenv("AUTH_ADMIN_USERNAME"))
+	authAdminPassword := os.Getenv("AUTH_ADMIN_PASSWORD")
+	if authAdminUsername != "" && authAdminPassword == "" {
+		return Config{}, fmt.Errorf("AUTH_ADMIN_PASSWORD is required with AUTH_ADMIN_USERNAME")
+	}
+
 	return Config{
-		AppEnv:                appEnv,
-		LogLevel:              level,
-		HTTPAddr:              httpAddr,
-		StaticDir:             staticDir,
-		SQLiteDriver:          sqliteDriver,
-		SQLiteDSN:             sqliteDSN,
-		SQLitePath:            sqlitePath,
-		SQLiteMaxOpenConns:    sqliteMaxOpenConns,
-		SQLiteMaxIdleConns:    sqliteMaxIdleConns,
-		SQLiteConnMaxLifetime: sqliteConnMaxLifetime,
-		MQTTBroker:            mqttBroker,
-		MQTTPort:              mqttPort,
-		MQTTClientID:          mqttClientID,
-		MQTTTopic:             mqttTopic,
+		AppEnv:                    appEnv,
+		LogLevel:                  level,
+		HTTPAddr:                  httpAddr,
+		HTTPReadTimeout:           httpReadTimeout,
+		HTTPHandlerTimeout:        httpHandlerTimeout,
+		StaticDir:                 staticDir,
+		SQLiteDriver:              sqliteDriver,
+		SQLiteDSN:                 sqliteDSN,
+		SQLitePath:                sqlitePath,
+		SQLiteMaxOpenConns:        sqliteMaxOpenConns,
+		SQLiteMaxIdleConns:        sqliteMaxIdleConns,
+		SQLiteConnMaxLifetime:     sqliteConnMaxLifetime,
+		SQLiteLogQueries:          sqliteLogQueries,
+		SQLiteLogRedact:           sqliteLogRedact,
+		DBHealthInterval:          dbHealthInterval,
+		DiskMinFreeBytes:          diskMinFreeBytes,
+		MQTTBroker:                mqttBroker,
+		MQTTPort:                  mqttPort,
+		MQTTClientID:              mqttClientID,
+		MQTTTopic:                 mqttTopic,
+		UploadsDir:                uploadsDir,
+		UploadMaxBytes:            uploadMaxBytes,
+		ReportsDir:                reportsDir,
+		ReportSchedule:            reportSchedule,
+		ReportPDFCommand:          reportPDFCommand,
+		CookieSecret:              cookieSecret,
+		ReadingsMergeWindow:       readingsMergeWindow,
+		IngestAcks:                ingestAcks,
+		ReadingsRetentionDays:     readingsRetentionDays,
+		HourlyRollupRetentionDays: hourlyRollupRetentionDays,
+		DailyRollupRetentionDays:  dailyRollupRetentionDays,
+		QuotaMaxStations:          quotaMaxStations,
+		QuotaMaxReadingsPerDay:    quotaMaxReadingsPerDay,
+		QuotaMaxRetentionDays:     quotaMaxRetentionDays,
+		HealthWeights:             healthWeights,
+		ReadingsBackend:           readingsBackend,
+		WebhooksFile:              webhooksFile,
+		UploadsFile:               uploadsFile,
+		SlowQueryThreshold:        slowQueryThreshold,
+		DebugEndpoints:            debugEndpoints,
+		PublicAPIAddr:             publicAPIAddr,
+		PublicAPIRateLimit:        publicAPIRateLimit,
+		PublicAPILicense:          publicAPILicense,
+		PublicAPIAttribution:      publicAPIAttribution,
+		APIRateLimit:              apiRateLimit,
+		APIRateBurst:              apiRateBurst,
+		Compression:               compression,
+		CompressionMinBytes:       compressionMinBytes,
+		AuthEnabled:               authEnabled,
+		JWTSecret:                 jwtSecret,
+		JWTTTL:                    jwtTTL,
+		AuthAdminUsername:         authAdminUsername,
+		AuthAdminPassword:         authAdminPassword,
 	}, nil
 }
 
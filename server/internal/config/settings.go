@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Redacted replaces the value of a secret that is set.
+const Redacted = "<redacted>"
+
+// Setting is one configuration value as logged and persisted, named after
+// its environment variable.
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Settings returns the configuration in field order. Secrets read Redacted
+// when set and stay empty otherwise; credentials in SQLITE_DSN parameters
+// are redacted the same way. A config file reads as its path with a SHA-256
+// prefix of its contents, so an edit shows up like any other change.
+func (c Config) Settings() []Setting {
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	out := make([]Setting, 0, t.NumField())
+	for i := range t.NumField() {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		value := formatValue(v.Field(i))
+		switch {
+		case value == "":
+		case opts == "secret":
+			value = Redacted
+		case opts == "file":
+			value += " (" + fileDigest(value) + ")"
+		case name == "SQLITE_DSN":
+			value = redactDSN(value)
+		}
+		out = append(out, Setting{Name: name, Value: value})
+	}
+	return out
+}
+
+// LogValue logs the configuration as a group of its Settings.
+func (c Config) LogValue() slog.Value {
+	settings := c.Settings()
+	attrs := make([]slog.Attr, len(settings))
+	for i, s := range settings {
+		attrs[i] = slog.String(s.Name, s.Value)
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func formatValue(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// fileDigest returns "sha256:" and the first 12 hex digits of the digest of
+// the file at path, or why it could not be read.
+func fileDigest(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "unreadable: " + err.Error()
+	}
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// dsnSecrets are the words that mark a DSN parameter as a credential, e.g.
+// _auth_pass or _pragma_key.
+var dsnSecrets = []string{"pass", "secret", "token", "key"}
+
+// redactDSN redacts the values of the credential parameters of a DSN.
+func redactDSN(dsn string) string {
+	base, query, ok := strings.Cut(dsn, "?")
+	if !ok {
+		return dsn
+	}
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		name, _, _ := strings.Cut(p, "=")
+		lower := strings.ToLower(name)
+		if n, err := url.QueryUnescape(lower); err == nil {
+			lower = n
+		}
+		for _, word := range dsnSecrets {
+			if strings.Contains(lower, word) {
+				params[i] = name + "=" + Redacted
+				break
+			}
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+// Change is a setting that differs from an earlier configuration. A setting
+// one of them does not have counts as empty.
+type Change struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// Diff returns the settings of cur that differ from prev, in the order of
+// cur, followed by those only prev sets.
+func Diff(prev, cur []Setting) []Change {
+	old := make(map[string]string, len(prev))
+	for _, s := range prev {
+		old[s.Name] = s.Value
+	}
+	var out []Change
+	for _, s := range cur {
+		if v := old[s.Name]; v != s.Value {
+			out = append(out, Change{Name: s.Name, Old: v, New: s.Value})
+		}
+		delete(old, s.Name)
+	}
+	for _, s := range prev {
+		if v := old[s.Name]; v != "" {
+			out = append(out, Change{Name: s.Name, Old: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-tools/migrate"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSettings(t *testing.T) {
+	webhooks := filepath.Join(t.TempDir(), "webhooks.json")
+	if err := os.WriteFile(webhooks, []byte(`[]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		AppEnv:       "prod",
+		LogLevel:     slog.LevelWarn,
+		SQLiteDSN:    "file:app.db?_auth_user=admin&_auth_pass=hunter2&_journal_mode=WAL",
+		JWTSecret:    strings.Repeat("s", minJWTSecretLen),
+		JWTTTL:       90 * time.Minute,
+		Compression:  []string{"zstd", "gzip"},
+		WebhooksFile: webhooks,
+		UploadsFile:  filepath.Join(t.TempDir(), "missing.json"),
+		APIRateLimit: 600,
+	}
+	got := make(map[string]string)
+	for _, s := range cfg.Settings() {
+		got[s.Name] = s.Value
+	}
+	for name, want := range map[string]string{
+		"APP_ENV":             "prod",
+		"LOG_LEVEL":           "WARN",
+		"SQLITE_DSN":          "file:app.db?_auth_user=admin&_auth_pass=<redacted>&_journal_mode=WAL",
+		"JWT_SECRET":          Redacted,
+		"COOKIE_SECRET":       "",
+		"AUTH_ADMIN_PASSWORD": "",
+		"JWT_TTL":             "1h30m0s",
+		"HTTP_COMPRESSION":    "zstd,gzip",
+		"SQLITE_LOG_REDACT":   "",
+		"API_RATE_LIMIT":      "600",
+		// sha256("[]")
+		"WEBHOOKS_FILE": webhooks + " (sha256:4f53cda18c2b)",
+	} {
+		if v, ok := got[name]; !ok || v != want {
+			t.Errorf("%s = %q; want %q", name, v, want)
+		}
+	}
+	if v := got["UPLOADS_FILE"]; !strings.Contains(v, "unreadable") {
+		t.Errorf("UPLOADS_FILE = %q; want the file reported unreadable", v)
+	}
+	if n := reflect.TypeOf(cfg).NumField(); len(got) != n {
+		t.Errorf("Settings() has %d settings; want one per field, %d", len(got), n)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("starting", "config", cfg)
+	if out := buf.String(); strings.Contains(out, "hunter2") || strings.Contains(out, cfg.JWTSecret) || !strings.Contains(out, `"config":{"APP_ENV":"prod"`) {
+		t.Errorf("log = %s; want the redacted settings grouped under config", out)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := []Setting{{"A", "1"}, {"B", "x"}, {"GONE", "old"}, {"EMPTY", ""}}
+	cur := []Setting{{"A", "1"}, {"B", "y"}, {"NEW", "z"}, {"BLANK", ""}}
+	want := []Change{{Name: "B", Old: "x", New: "y"}, {Name: "NEW", New: "z"}, {Name: "GONE", Old: "old"}}
+	if got := Diff(prev, cur); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v; want %+v", got, want)
+	}
+	if got := Diff(cur, cur); got != nil {
+		t.Errorf("Diff() of equal settings = %+v; want nil", got)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := migrate.Run(db); err != nil {
+		t.Fatalf("migrate.Run() error = %v", err)
+	}
+
+	if s, err := LoadSnapshot(db); s != nil || err != nil {
+		t.Fatalf("LoadSnapshot() on a new database = %+v, %v; want nil, nil", s, err)
+	}
+	started := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, want := range []Snapshot{
+		{Settings: []Setting{{"APP_ENV", "dev"}}, Version: "1.0.0", StartedAt: started},
+		{Settings: []Setting{{"APP_ENV", "prod"}, {"JWT_SECRET", Redacted}}, Version: "1.1.0", StartedAt: started.Add(time.Hour)},
+	} {
+		if err := SaveSnapshot(db, want); err != nil {
+			t.Fatalf("SaveSnapshot() #%d error = %v", i, err)
+		}
+		got, err := LoadSnapshot(db)
+		if err != nil || got == nil || !reflect.DeepEqual(*got, want) {
+			t.Errorf("LoadSnapshot() #%d = %+v, %v; want %+v", i, got, err, want)
+		}
+	}
+}
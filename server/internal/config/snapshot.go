@@ -0,0 +1,57 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Snapshot is the configuration a server started with, kept in the
+// config_snapshot table so the next start can tell what changed.
+type Snapshot struct {
+	Settings  []Setting
+	Version   string // build version
+	StartedAt time.Time
+}
+
+const (
+	loadSnapshotSQL = `SELECT settings, version, started_at FROM config_snapshot WHERE id = 1`
+	saveSnapshotSQL = `INSERT INTO config_snapshot (id, settings, version, started_at) VALUES (1, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET settings = excluded.settings, version = excluded.version, started_at = excluded.started_at`
+)
+
+// LoadSnapshot returns the snapshot saved by the last start, or nil when
+// there is none.
+func LoadSnapshot(db *sql.DB) (*Snapshot, error) {
+	var settings, version, startedAt string
+	err := db.QueryRow(loadSnapshotSQL).Scan(&settings, &version, &startedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load config snapshot: %w", err)
+	}
+	s := &Snapshot{Version: version}
+	if err := json.Unmarshal([]byte(settings), &s.Settings); err != nil {
+		return nil, fmt.Errorf("load config snapshot: %w", err)
+	}
+	if s.StartedAt, err = time.Parse(time.RFC3339Nano, startedAt); err != nil {
+		return nil, fmt.Errorf("load config snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// SaveSnapshot replaces the saved snapshot with s.
+func SaveSnapshot(db *sql.DB, s Snapshot) error {
+	settings, err := json.Marshal(s.Settings)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(saveSnapshotSQL, string(settings), s.Version, s.StartedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("save config snapshot: %w", err)
+	}
+	return nil
+}
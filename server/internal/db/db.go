@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -19,7 +20,12 @@ func Open(cfg config.Config) (*sql.DB, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open(cfg.SQLiteDriver, dsn)
+	var db *sql.DB
+	if cfg.SQLiteLogQueries {
+		db, err = openLogged(cfg.SQLiteDriver, dsn, cfg.SlowQueryThreshold, cfg.SQLiteLogRedact)
+	} else {
+		db, err = sql.Open(cfg.SQLiteDriver, dsn)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("db open: %w", err)
 	}
@@ -44,6 +50,37 @@ func Open(cfg config.Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// OpenReadOnly opens a second pool on the database of cfg that cannot write:
+// the file is opened read-only and every connection has the query_only
+// pragma set. It serves ad-hoc queries, such as the admin SQL console, that
+// must not change data whatever they contain. An in-memory database has no
+// file to share, so it is an error.
+func OpenReadOnly(cfg config.Config) (*sql.DB, error) {
+	dsn := cfg.SQLiteDSN
+	if dsn == "" {
+		dsn = cfg.SQLitePath
+	}
+	if dbFile(dsn) == "" {
+		return nil, errors.New("db open read-only: in-memory database")
+	}
+	if !strings.HasPrefix(dsn, "file:") {
+		dsn = "file:" + dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open(cfg.SQLiteDriver, dsn+sep+"mode=ro&_query_only=true&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("db open read-only: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("db ping read-only: %w", err)
+	}
+	return db, nil
+}
+
 func Close(db *sql.DB) error {
 	if db == nil {
 		return nil
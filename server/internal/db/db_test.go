@@ -0,0 +1,40 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"cloudpico-server/internal/config"
+)
+
+func TestOpenReadOnly(t *testing.T) {
+	cfg := config.Config{SQLiteDriver: "sqlite3", SQLitePath: filepath.Join(t.TempDir(), "app.db")}
+	rw, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer rw.Close()
+	if _, err := rw.Exec(`CREATE TABLE t (v INTEGER); INSERT INTO t VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+
+	ro, err := OpenReadOnly(cfg)
+	if err != nil {
+		t.Fatalf("OpenReadOnly() error = %v", err)
+	}
+	defer ro.Close()
+	var v int
+	if err := ro.QueryRow(`SELECT v FROM t`).Scan(&v); err != nil || v != 1 {
+		t.Errorf("SELECT = %d, %v; want 1", v, err)
+	}
+	if _, err := ro.Exec(`INSERT INTO t VALUES (2)`); err == nil {
+		t.Error("INSERT on the read-only pool succeeded")
+	}
+	if _, err := ro.Exec(`PRAGMA query_only = off; DELETE FROM t`); err == nil {
+		t.Error("DELETE after lifting query_only succeeded")
+	}
+
+	if _, err := OpenReadOnly(config.Config{SQLiteDriver: "sqlite3", SQLitePath: ":memory:"}); err == nil {
+		t.Error("OpenReadOnly(:memory:) succeeded")
+	}
+}
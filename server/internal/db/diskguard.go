@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/config"
+	"cloudpico-server/internal/metrics"
+)
+
+// ErrDiskSpaceLow is returned by DiskGuard.Check while the database volume
+// has less free space than DISK_MIN_FREE_BYTES.
+var ErrDiskSpaceLow = errors.New("disk space low")
+
+// diskCheckInterval is how stale the free space Check relies on may get.
+const diskCheckInterval = 10 * time.Second
+
+var (
+	diskFree = metrics.Default.NewGauge("cloudpico_db_disk_free_bytes",
+		"Free space on the database volume at the last check.")
+	diskLow = metrics.Default.NewGauge("cloudpico_db_disk_space_low",
+		"1 while ingest is refused for lack of disk space, else 0.")
+)
+
+// DiskGuard watches the free space on the volume holding the database, so
+// ingest can be refused before a full disk fails writes halfway through the
+// WAL. Below the configured minimum it turns protective: Check returns
+// ErrDiskSpaceLow, the WAL is checkpointed and truncated, and onLow runs
+// (e.g. to raise an alert and prune old readings). It turns back once free
+// space is a tenth above the minimum, so it does not flap at the threshold.
+type DiskGuard struct {
+	db      *sql.DB
+	dir     string // "" disables the guard
+	minFree uint64
+	onLow   func(free uint64)
+	now     func() time.Time
+	statfs  func(dir string) (uint64, error)
+
+	mu      sync.Mutex
+	checked time.Time
+	free    uint64
+	low     bool
+}
+
+// NewDiskGuard returns a guard of the database db was opened on with cfg,
+// calling onLow, if not nil, every time it turns protective. It is disabled
+// when cfg.DiskMinFreeBytes is 0, the database is in memory or the platform
+// cannot report free space.
+func NewDiskGuard(db *sql.DB, cfg config.Config, onLow func(free uint64)) *DiskGuard {
+	dsn := cfg.SQLiteDSN
+	if dsn == "" {
+		dsn = cfg.SQLitePath
+	}
+	g := &DiskGuard{db: db, minFree: uint64(cfg.DiskMinFreeBytes), onLow: onLow, now: time.Now, statfs: freeBytes}
+	path := dbFile(dsn)
+	if path == "" || cfg.DiskMinFreeBytes <= 0 {
+		return g
+	}
+	if _, err := freeBytes(filepath.Dir(path)); errors.Is(err, errors.ErrUnsupported) {
+		slog.Warn("disk space guard unsupported on this platform; disabled")
+		return g
+	}
+	g.dir = filepath.Dir(path)
+	return g
+}
+
+// Run checks the free space every diskCheckInterval until ctx is done, so
+// the guard turns protective even while nothing is ingested.
+func (g *DiskGuard) Run(ctx context.Context) {
+	if g.dir == "" {
+		return
+	}
+	t := time.NewTicker(diskCheckInterval)
+	defer t.Stop()
+	for {
+		g.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Check returns an error wrapping ErrDiskSpaceLow while the guard is
+// protective. It measures the free space again when the last measurement is
+// older than diskCheckInterval.
+func (g *DiskGuard) Check() error {
+	if g.dir == "" {
+		return nil
+	}
+	g.mu.Lock()
+	stale := g.now().Sub(g.checked) >= diskCheckInterval
+	g.mu.Unlock()
+	if stale {
+		g.refresh(context.Background())
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.low {
+		return nil
+	}
+	return fmt.Errorf("%w: %d MiB free on the database volume, the minimum is %d MiB", ErrDiskSpaceLow, g.free>>20, g.minFree>>20)
+}
+
+func (g *DiskGuard) refresh(ctx context.Context) {
+	free, err := g.statfs(g.dir)
+	now := g.now()
+	g.mu.Lock()
+	g.checked = now
+	if err != nil {
+		g.mu.Unlock()
+		slog.Warn("disk space check failed", "dir", g.dir, "error", err)
+		return
+	}
+	was := g.low
+	switch {
+	case free < g.minFree:
+		g.low = true
+	case free >= g.minFree+g.minFree/10:
+		g.low = false
+	}
+	g.free = free
+	low := g.low
+	g.mu.Unlock()
+
+	diskFree.Set(float64(free))
+	switch {
+	case low && !was:
+		diskLow.Set(1)
+		slog.Error("disk space low; refusing ingest", "dir", g.dir, "free_bytes", free, "min_free_bytes", g.minFree)
+		g.protect(ctx, free)
+	case !low && was:
+		diskLow.Set(0)
+		slog.Info("disk space recovered; accepting ingest", "dir", g.dir, "free_bytes", free)
+	}
+}
+
+// protect frees what it can at once and hands over to onLow.
+func (g *DiskGuard) protect(ctx context.Context, free uint64) {
+	if _, err := g.db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		slog.Warn("wal checkpoint failed", "error", err)
+	}
+	if g.onLow != nil {
+		g.onLow(free)
+	}
+}
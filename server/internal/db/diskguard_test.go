@@ -0,0 +1,81 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/config"
+)
+
+func TestDiskGuard(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	const mib = 1 << 20
+	var lows []uint64
+	g := NewDiskGuard(conn, config.Config{SQLitePath: filepath.Join(t.TempDir(), "app.db"), DiskMinFreeBytes: 100 * mib}, func(free uint64) {
+		lows = append(lows, free)
+	})
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	free := uint64(500 * mib)
+	g.statfs = func(string) (uint64, error) { return free, nil }
+
+	for _, step := range []struct {
+		free    uint64
+		wantLow bool
+	}{
+		{500 * mib, false},
+		{99 * mib, true},
+		{105 * mib, true}, // within a tenth of the minimum
+		{110 * mib, false},
+		{50 * mib, true},
+	} {
+		free = step.free
+		now = now.Add(diskCheckInterval)
+		err := g.Check()
+		if low := errors.Is(err, ErrDiskSpaceLow); low != step.wantLow {
+			t.Errorf("Check() with %d MiB free = %v; want low %v", step.free/mib, err, step.wantLow)
+		}
+	}
+	if len(lows) != 2 || lows[0] != 99*mib || lows[1] != 50*mib {
+		t.Errorf("onLow called with %v; want once per turn to protective", lows)
+	}
+	if err := g.Check(); err == nil || err.Error() != "disk space low: 50 MiB free on the database volume, the minimum is 100 MiB" {
+		t.Errorf("Check() = %v", err)
+	}
+
+	free = 500 * mib
+	now = now.Add(diskCheckInterval / 2)
+	if err := g.Check(); err == nil {
+		t.Error("Check() within the check interval = nil; want the last measurement kept")
+	}
+}
+
+func TestDiskGuard_disabled(t *testing.T) {
+	for name, cfg := range map[string]config.Config{
+		"no minimum": {SQLitePath: "data/app.db"},
+		"in memory":  {SQLiteDSN: "file::memory:?cache=shared", DiskMinFreeBytes: 1 << 60},
+	} {
+		g := NewDiskGuard(nil, cfg, nil)
+		g.statfs = func(string) (uint64, error) { return 0, nil }
+		if err := g.Check(); err != nil {
+			t.Errorf("%s: Check() = %v; want nil", name, err)
+		}
+	}
+}
+
+func TestFreeBytes(t *testing.T) {
+	free, err := freeBytes(t.TempDir())
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil || free == 0 {
+		t.Errorf("freeBytes() = %d, %v; want the free space of the temp volume", free, err)
+	}
+}
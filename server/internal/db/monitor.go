@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/config"
+	"cloudpico-server/internal/metrics"
+)
+
+// Database states reported by Monitor.
+const (
+	StateUnknown     = "unknown" // not probed yet
+	StateOK          = "ok"
+	StateLocked      = "locked"      // the write lock stayed taken past the busy timeout
+	StateDiskFull    = "disk_full"   // the disk or the database size limit is full
+	StateUnavailable = "unavailable" // the file cannot be opened, read or written
+	StateTimeout     = "timeout"     // the probe did not finish in time, e.g. every connection is busy
+	StateError       = "error"       // any other failure
+)
+
+var (
+	dbUp = metrics.Default.NewGauge("cloudpico_db_up",
+		"1 when the last database health probe succeeded, else 0.")
+	probeFailures = metrics.Default.NewCounterVec("cloudpico_db_probe_failures_total",
+		"Failed database health probes, by state.", "state")
+	reopens = metrics.Default.NewCounter("cloudpico_db_reopens_total",
+		"Times the idle database connections were dropped after the file became reachable again.")
+)
+
+// probeTimeout bounds one probe. It is longer than the default busy timeout
+// (see buildDSN) so that a held lock reports as locked rather than timeout.
+const probeTimeout = 10 * time.Second
+
+// defaultMaxIdleConns is what database/sql keeps when SQLiteMaxIdleConns is
+// left unset.
+const defaultMaxIdleConns = 2
+
+const heartbeatSQL = `INSERT INTO db_heartbeat (id, at) VALUES (1, ?)
+ON CONFLICT (id) DO UPDATE SET at = excluded.at`
+
+// Health is the outcome of the latest database probe.
+type Health struct {
+	State     string    `json:"state"`
+	Error     string    `json:"error,omitempty"`
+	Since     time.Time `json:"since"` // when the database entered State
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// OK reports whether the database took the last probe's write.
+func (h Health) OK() bool {
+	return h.State == StateOK
+}
+
+// Monitor probes the database at an interval by writing the db_heartbeat
+// row; a ping alone succeeds on a locked database or a full disk. The
+// outcome is kept for /readyz and exported at /metrics, and changes are
+// logged.
+//
+// When a probe finds the file unavailable but it can be found on disk again,
+// e.g. after an NFS blip, the monitor drops the pool's idle connections,
+// which may hold stale handles, and probes once more on a fresh one.
+// Connections in use at the time finish their queries undisturbed.
+type Monitor struct {
+	db       *sql.DB
+	path     string // database file; "" when in memory or unknown
+	maxIdle  int
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	health Health
+}
+
+// NewMonitor returns a monitor of db, opened with cfg, probing every
+// cfg.DBHealthInterval once Run.
+func NewMonitor(db *sql.DB, cfg config.Config) *Monitor {
+	maxIdle := cfg.SQLiteMaxIdleConns
+	if maxIdle < 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	dsn := cfg.SQLiteDSN
+	if dsn == "" {
+		dsn = cfg.SQLitePath
+	}
+	return &Monitor{
+		db:       db,
+		path:     dbFile(dsn),
+		maxIdle:  maxIdle,
+		interval: cfg.DBHealthInterval,
+		now:      time.Now,
+		health:   Health{State: StateUnknown},
+	}
+}
+
+// dbFile returns the path of the database file named by a DSN or path, or ""
+// for an in-memory database.
+func dbFile(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path
+}
+
+// Run probes the database at once and then every interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	m.Check(ctx)
+	t := time.NewTicker(m.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Health returns the outcome of the latest probe.
+func (m *Monitor) Health() Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health
+}
+
+// Check probes the database now, reopening the pool if that may help, and
+// records the outcome. A probe cut short by ctx is not recorded.
+func (m *Monitor) Check(ctx context.Context) Health {
+	err := m.probe(ctx)
+	if err != nil && classify(err) == StateUnavailable && m.reachable() {
+		m.reopen()
+		err = m.probe(ctx)
+	}
+	if ctx.Err() != nil {
+		return m.Health()
+	}
+	return m.record(err)
+}
+
+func (m *Monitor) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	_, err := m.db.ExecContext(ctx, heartbeatSQL, m.now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+// reachable reports whether the database file can be found, so reopening
+// has a chance.
+func (m *Monitor) reachable() bool {
+	if m.path == "" {
+		return true
+	}
+	_, err := os.Stat(m.path)
+	return err == nil
+}
+
+// reopen closes the idle connections; the next query opens a new one.
+func (m *Monitor) reopen() {
+	m.db.SetMaxIdleConns(0)
+	m.db.SetMaxIdleConns(m.maxIdle)
+	reopens.Inc()
+	slog.Warn("database unavailable; reopened idle connections", "path", m.path)
+}
+
+func (m *Monitor) record(err error) Health {
+	h := Health{State: StateOK, CheckedAt: m.now()}
+	if err != nil {
+		h.State, h.Error = classify(err), err.Error()
+		probeFailures.Inc(h.State)
+	}
+	m.mu.Lock()
+	prev := m.health
+	h.Since = prev.Since
+	if h.State != prev.State {
+		h.Since = h.CheckedAt
+	}
+	m.health = h
+	m.mu.Unlock()
+
+	switch {
+	case h.OK():
+		dbUp.Set(1)
+		if prev.State != StateOK && prev.State != StateUnknown {
+			slog.Info("database healthy again", "was", prev.State, "down_for", h.CheckedAt.Sub(prev.Since).Round(time.Second))
+		}
+	default:
+		dbUp.Set(0)
+		if h.State != prev.State {
+			slog.Error("database unhealthy", "state", h.State, "error", err)
+		}
+	}
+	return h
+}
+
+// classify maps a probe error to a state. It matches SQLite's own messages
+// rather than go-sqlite3 error codes, so any SQLITE_DRIVER built on SQLite
+// classifies alike.
+func classify(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return StateTimeout
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return StateLocked
+	case strings.Contains(msg, "database or disk is full"):
+		return StateDiskFull
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone),
+		strings.Contains(msg, "disk I/O error"),
+		strings.Contains(msg, "unable to open database file"),
+		strings.Contains(msg, "attempt to write a readonly database"),
+		strings.Contains(msg, "file is not a database"):
+		return StateUnavailable
+	}
+	return StateError
+}
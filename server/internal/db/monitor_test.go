@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/config"
+	"cloudpico-tools/migrate"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want string
+	}{
+		{errors.New("database is locked"), StateLocked},
+		{fmt.Errorf("exec: %w", errors.New("database table is locked: readings")), StateLocked},
+		{errors.New("database or disk is full"), StateDiskFull},
+		{errors.New("disk I/O error: stale NFS file handle"), StateUnavailable},
+		{errors.New("unable to open database file: no such file or directory"), StateUnavailable},
+		{errors.New("attempt to write a readonly database"), StateUnavailable},
+		{sql.ErrConnDone, StateUnavailable},
+		{context.DeadlineExceeded, StateTimeout},
+		{errors.New("no such table: db_heartbeat"), StateError},
+	} {
+		if got := classify(tt.err); got != tt.want {
+			t.Errorf("classify(%q) = %q; want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDBFile(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"data/app.db":                       "data/app.db",
+		"file:/data/app.db?_busy_timeout=1": "/data/app.db",
+		"file::memory:?cache=shared":        "",
+		":memory:":                          "",
+	} {
+		if got := dbFile(dsn); got != want {
+			t.Errorf("dbFile(%q) = %q; want %q", dsn, got, want)
+		}
+	}
+}
+
+// newMonitor returns a monitor of the database at path, with a short busy
+// timeout so a held lock is reported quickly, and a clock stepping a minute
+// per probe.
+func newMonitor(t *testing.T, path string) (*Monitor, *sql.DB) {
+	t.Helper()
+	cfg := config.Config{SQLiteDSN: "file:" + path + "?_busy_timeout=50", SQLiteMaxIdleConns: 1, DBHealthInterval: time.Minute}
+	conn, err := sql.Open("sqlite3", cfg.SQLiteDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	m := NewMonitor(conn, cfg)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	return m, conn
+}
+
+func TestMonitor_locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	m, conn := newMonitor(t, path)
+	if err := migrate.Run(conn); err != nil {
+		t.Fatalf("migrate.Run() error = %v", err)
+	}
+	if h := m.Health(); h.State != StateUnknown || h.OK() {
+		t.Errorf("Health() before a probe = %+v; want unknown", h)
+	}
+	if h := m.Check(context.Background()); !h.OK() {
+		t.Fatalf("Check() = %+v; want ok", h)
+	}
+
+	other, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	tx, err := other.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`DELETE FROM db_heartbeat`); err != nil {
+		t.Fatal(err)
+	}
+	failures := probeFailures.Value(StateLocked)
+	locked := m.Check(context.Background())
+	if locked.State != StateLocked || locked.Error == "" || dbUp.Value() != 0 || probeFailures.Value(StateLocked) != failures+1 {
+		t.Errorf("Check() with the write lock held = %+v, up %v; want locked", locked, dbUp.Value())
+	}
+	if again := m.Check(context.Background()); !again.Since.Equal(locked.Since) || !again.CheckedAt.After(locked.CheckedAt) {
+		t.Errorf("second Check() = %+v; want the state kept since %v", again, locked.Since)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if h := m.Check(context.Background()); !h.OK() || !h.Since.Equal(h.CheckedAt) || dbUp.Value() != 1 {
+		t.Errorf("Check() after the lock is released = %+v, up %v; want ok since now", h, dbUp.Value())
+	}
+}
+
+func TestMonitor_reopen(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nfs")
+	path := filepath.Join(dir, "app.db")
+	m, _ := newMonitor(t, path)
+
+	before := reopens.Value()
+	if h := m.Check(context.Background()); h.State != StateUnavailable {
+		t.Errorf("Check() with the directory missing = %+v; want unavailable", h)
+	}
+	if got := reopens.Value(); got != before {
+		t.Errorf("reopens with the file missing = %d; want %d", got, before)
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("this is not a database, not even close to one"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if h := m.Check(context.Background()); h.State != StateUnavailable {
+		t.Errorf("Check() on a corrupt file = %+v; want unavailable", h)
+	}
+	if got := reopens.Value(); got != before+1 {
+		t.Errorf("reopens = %d; want %d", got, before+1)
+	}
+
+	// A database is restored in its place.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if err := migrate.Run(restored); err != nil {
+		t.Fatalf("migrate.Run() error = %v", err)
+	}
+	if h := m.Check(context.Background()); !h.OK() {
+		t.Errorf("Check() once the file is restored = %+v; want ok", h)
+	}
+}
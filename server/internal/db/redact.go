@@ -0,0 +1,245 @@
+package db
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// redactor decides which statement arguments must not be logged. Patterns are
+// path.Match globs compared case-insensitively against the argument's name
+// (sql.Named) and against the column its placeholder is bound to, as found
+// in "col = ?" comparisons, "SET col = ?" and INSERT column lists.
+type redactor struct {
+	patterns []string
+}
+
+func newRedactor(patterns []string) *redactor {
+	r := &redactor{}
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			r.patterns = append(r.patterns, p)
+		}
+	}
+	return r
+}
+
+func (r *redactor) matches(name string) bool {
+	if name == "" {
+		return false
+	}
+	name = strings.ToLower(name)
+	for _, p := range r.patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitive returns the ordinals (1-based) of the arguments of query that
+// must be redacted, given their names in order (empty for positional ones).
+func (r *redactor) sensitive(query string, names []string) map[int]bool {
+	if r == nil || len(r.patterns) == 0 {
+		return nil
+	}
+	out := make(map[int]bool)
+	for i, name := range names {
+		if r.matches(name) {
+			out[i+1] = true
+		}
+	}
+	byOrdinal, byName := placeholderColumns(query)
+	for ordinal, col := range byOrdinal {
+		if r.matches(col) {
+			out[ordinal] = true
+		}
+	}
+	for i, name := range names {
+		if name != "" && r.matches(byName[name]) {
+			out[i+1] = true
+		}
+	}
+	return out
+}
+
+type sqlToken struct {
+	text        string
+	placeholder bool
+}
+
+// tokenizeSQL splits query into identifiers, placeholders and punctuation,
+// dropping string literals and comments.
+func tokenizeSQL(query string) []sqlToken {
+	var out []sqlToken
+	rs := []rune(query)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			for i++; i < len(rs); i++ {
+				if rs[i] == '\'' {
+					if i+1 < len(rs) && rs[i+1] == '\'' {
+						i++
+						continue
+					}
+					i++
+					break
+				}
+			}
+			out = append(out, sqlToken{text: "'"})
+		case c == '-' && i+1 < len(rs) && rs[i+1] == '-':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '`' || c == '[':
+			end := map[rune]rune{'"': '"', '`': '`', '[': ']'}[c]
+			j := i + 1
+			for j < len(rs) && rs[j] != end {
+				j++
+			}
+			out = append(out, sqlToken{text: string(rs[i+1 : min(j, len(rs))])})
+			i = j + 1
+		case c == '?' || ((c == ':' || c == '@' || c == '$') && i+1 < len(rs) && isIdentRune(rs[i+1])):
+			j := i + 1
+			for j < len(rs) && isIdentRune(rs[j]) {
+				j++
+			}
+			out = append(out, sqlToken{text: string(rs[i:j]), placeholder: true})
+			i = j
+		case isIdentRune(c):
+			j := i
+			for j < len(rs) && (isIdentRune(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			out = append(out, sqlToken{text: string(rs[i:j])})
+			i = j
+		case strings.ContainsRune("<>=!", c):
+			j := i
+			for j < len(rs) && strings.ContainsRune("<>=!", rs[j]) {
+				j++
+			}
+			out = append(out, sqlToken{text: string(rs[i:j])})
+			i = j
+		default:
+			out = append(out, sqlToken{text: string(c)})
+			i++
+		}
+	}
+	return out
+}
+
+// isIdent reports whether tok is a (possibly qualified) name.
+func isIdent(tok sqlToken) bool {
+	return !tok.placeholder && tok.text != "" && isIdentRune([]rune(tok.text)[0])
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+var comparisonTokens = map[string]bool{
+	"=": true, "==": true, "!=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+	"like": true, "glob": true, "is": true,
+}
+
+// placeholderColumns maps the placeholders of query to the columns they are
+// bound to, by ordinal for "?" and "?NNN" and by name for ":name", "@name"
+// and "$name". Placeholders whose column is not evident are left out.
+func placeholderColumns(query string) (byOrdinal map[int]string, byName map[string]string) {
+	byOrdinal = make(map[int]string)
+	byName = make(map[string]string)
+	tokens := tokenizeSQL(query)
+
+	var insertCols []string
+	if cols, ok := insertColumns(tokens); ok {
+		insertCols = cols
+	}
+
+	next := 1 // ordinal of the next bare "?"
+	depth, inValues, col := 0, false, 0
+	for i, tok := range tokens {
+		switch {
+		case strings.EqualFold(tok.text, "values"):
+			inValues = true
+		case tok.text == "(":
+			depth++
+			if depth == 1 {
+				col = 0
+			}
+		case tok.text == ")":
+			depth--
+		case tok.text == ",":
+			if depth == 1 {
+				col++
+			}
+		case strings.EqualFold(tok.text, "select"), strings.EqualFold(tok.text, "on"), strings.EqualFold(tok.text, "returning"):
+			inValues = false
+		}
+		if !tok.placeholder {
+			continue
+		}
+
+		column := ""
+		if inValues && depth == 1 && len(insertCols) > 0 {
+			column = insertCols[col%len(insertCols)]
+		} else if i >= 2 && comparisonTokens[strings.ToLower(tokens[i-1].text)] && isIdent(tokens[i-2]) {
+			column = tokens[i-2].text
+		}
+		if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+			column = column[dot+1:]
+		}
+
+		switch {
+		case tok.text == "?":
+			if column != "" {
+				byOrdinal[next] = column
+			}
+			next++
+		case tok.text[0] == '?':
+			n, err := strconv.Atoi(tok.text[1:])
+			if err != nil {
+				continue
+			}
+			if column != "" {
+				byOrdinal[n] = column
+			}
+			next = max(next, n+1)
+		default:
+			if column != "" {
+				byName[tok.text[1:]] = column
+			}
+		}
+	}
+	return byOrdinal, byName
+}
+
+// insertColumns returns the column list of an "INSERT [OR ...] INTO t (...)"
+// statement.
+func insertColumns(tokens []sqlToken) ([]string, bool) {
+	i := 0
+	for i < len(tokens) && !strings.EqualFold(tokens[i].text, "into") {
+		if i == 0 && !strings.EqualFold(tokens[i].text, "insert") && !strings.EqualFold(tokens[i].text, "replace") {
+			return nil, false
+		}
+		i++
+	}
+	// INTO, table name, "(".
+	if i+2 >= len(tokens) || tokens[i+2].text != "(" {
+		return nil, false
+	}
+	var cols []string
+	for j := i + 3; j < len(tokens); j++ {
+		switch tokens[j].text {
+		case ")":
+			return cols, true
+		case ",":
+		default:
+			cols = append(cols, tokens[j].text)
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlaceholderColumns(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantOrdinal map[int]string
+		wantName    map[string]string
+	}{
+		{"comparisons", `SELECT id FROM users WHERE users.email = ? AND created_at >= ? AND name LIKE ?`,
+			map[int]string{1: "email", 2: "created_at", 3: "name"}, map[string]string{}},
+		{"update", `UPDATE users SET password_hash = ?, name = ? WHERE id = ?`,
+			map[int]string{1: "password_hash", 2: "name", 3: "id"}, map[string]string{}},
+		{"insert rows", `INSERT OR REPLACE INTO api_keys (station_id, "key") VALUES (?, ?), (?, ?) RETURNING id`,
+			map[int]string{1: "station_id", 2: "key", 3: "station_id", 4: "key"}, map[string]string{}},
+		{"insert select", `INSERT INTO t (a) SELECT x FROM u WHERE secret = ?`,
+			map[int]string{1: "secret"}, map[string]string{}},
+		{"numbered and named", `SELECT 1 FROM t WHERE token = ?2 AND owner = :owner AND '?' != ? -- note = ?`,
+			map[int]string{2: "token"}, map[string]string{"owner": "owner"}},
+		{"no column", `SELECT ? + 1`, map[int]string{}, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			byOrdinal, byName := placeholderColumns(tt.query)
+			if !reflect.DeepEqual(byOrdinal, tt.wantOrdinal) {
+				t.Errorf("byOrdinal = %v; want %v", byOrdinal, tt.wantOrdinal)
+			}
+			if !reflect.DeepEqual(byName, tt.wantName) {
+				t.Errorf("byName = %v; want %v", byName, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestRedactorSensitive(t *testing.T) {
+	r := newRedactor([]string{"*password*", " API_KEY* ", "*_hash"})
+	got := r.sensitive(`UPDATE users SET Password_Hash = ?, name = ? WHERE api_key = :k`, []string{"", "", "k"})
+	want := map[int]bool{1: true, 3: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sensitive() = %v; want %v", got, want)
+	}
+	if got := newRedactor([]string{"secret"}).sensitive(`SELECT ?`, []string{"secret"}); !got[1] {
+		t.Errorf("sensitive(named arg) = %v; want the argument redacted", got)
+	}
+	if got := newRedactor(nil).sensitive(`UPDATE t SET password = ?`, []string{""}); len(got) != 0 {
+		t.Errorf("sensitive(no patterns) = %v; want none", got)
+	}
+}
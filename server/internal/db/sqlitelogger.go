@@ -0,0 +1,304 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+)
+
+// Argument sizes above which logged values are redacted or truncated.
+const (
+	maxLoggedBlob   = 64
+	maxLoggedString = 256
+)
+
+// openLogged opens driverName like sql.Open but logs every statement after it
+// runs: the query, its arguments, duration, rows affected or returned, and
+// any error. Statements are logged at DEBUG; failures and statements taking
+// at least slow (when positive) at WARN. Arguments matching redact (see
+// redactor) are never logged.
+func openLogged(driverName, dsn string, slow time.Duration, redact []string) (*sql.DB, error) {
+	// sql.Open does not connect; it only resolves the registered driver.
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	if err := probe.Close(); err != nil {
+		return nil, err
+	}
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(&loggingConnector{Connector: connector, log: &queryLogger{slow: slow, redact: newRedactor(redact)}}), nil
+}
+
+// dsnConnector adapts a driver without DriverContext to driver.Connector.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+
+func (c dsnConnector) Driver() driver.Driver { return c.driver }
+
+type loggingConnector struct {
+	driver.Connector
+	log *queryLogger
+}
+
+func (c *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{Conn: conn, log: c.log}, nil
+}
+
+type queryLogger struct {
+	slow   time.Duration
+	redact *redactor
+}
+
+// done logs one finished statement. rows is -1 when unknown.
+func (l *queryLogger) done(ctx context.Context, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	d := time.Since(start)
+	level, msg := slog.LevelDebug, "sql query"
+	switch {
+	case err != nil:
+		level, msg = slog.LevelWarn, "sql query failed"
+	case l.slow > 0 && d >= l.slow:
+		level, msg = slog.LevelWarn, "slow sql query"
+	}
+	if !logging.FromContext(ctx).Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("query", strings.Join(strings.Fields(query), " ")),
+		slog.Any("args", l.loggedArgs(query, args)),
+		slog.Duration("duration", d),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logging.FromContext(ctx).LogAttrs(ctx, level, msg, attrs...)
+}
+
+// loggedArgs returns args safe to log: sensitive values are redacted, large
+// blobs are replaced by their size and long strings are truncated.
+func (l *queryLogger) loggedArgs(query string, args []driver.NamedValue) []any {
+	names := make([]string, len(args))
+	for i, a := range args {
+		names[i] = a.Name
+	}
+	sensitive := l.redact.sensitive(query, names)
+	out := make([]any, len(args))
+	for i, a := range args {
+		if sensitive[i+1] {
+			out[i] = "<redacted>"
+			continue
+		}
+		switch v := a.Value.(type) {
+		case []byte:
+			if len(v) > maxLoggedBlob {
+				out[i] = fmt.Sprintf("<blob %d bytes>", len(v))
+			} else {
+				out[i] = fmt.Sprintf("%x", v)
+			}
+		case string:
+			if len(v) > maxLoggedString {
+				out[i] = fmt.Sprintf("%s… (%d bytes)", v[:maxLoggedString], len(v))
+			} else {
+				out[i] = v
+			}
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// loggingConn logs the statements run on conn. It implements the context
+// variants of the driver interfaces and falls back to driver.ErrSkip, so
+// database/sql prepares statements when conn lacks them.
+type loggingConn struct {
+	driver.Conn
+	log *queryLogger
+}
+
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	c.log.done(ctx, query, args, start, rowsAffected(res, err), err)
+	return res, err
+}
+
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		c.log.done(ctx, query, args, start, -1, err)
+		return nil, err
+	}
+	return &loggingRows{Rows: rows, ctx: ctx, log: c.log, query: query, args: args, start: start}, nil
+}
+
+func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loggingStmt{Stmt: stmt, log: c.log, query: query}, nil
+}
+
+func (c *loggingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *loggingConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *loggingConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *loggingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type loggingStmt struct {
+	driver.Stmt
+	log   *queryLogger
+	query string
+}
+
+func (s *loggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			res, err = s.Stmt.Exec(values)
+		}
+	}
+	s.log.done(ctx, s.query, args, start, rowsAffected(res, err), err)
+	return res, err
+}
+
+func (s *loggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	if err != nil {
+		s.log.done(ctx, s.query, args, start, -1, err)
+		return nil, err
+	}
+	return &loggingRows{Rows: rows, ctx: ctx, log: s.log, query: s.query, args: args, start: start}, nil
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, fmt.Errorf("driver does not support named parameter %q", a.Name)
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
+
+func rowsAffected(res driver.Result, err error) int64 {
+	if err != nil || res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// loggingRows counts the rows read and logs the statement when closed, so the
+// duration includes stepping through the results.
+type loggingRows struct {
+	driver.Rows
+	ctx   context.Context
+	log   *queryLogger
+	query string
+	args  []driver.NamedValue
+	start time.Time
+	n     int64
+	err   error
+}
+
+func (r *loggingRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.n++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *loggingRows) Close() error {
+	err := r.Rows.Close()
+	r.log.done(r.ctx, r.query, r.args, r.start, r.n, r.err)
+	return err
+}
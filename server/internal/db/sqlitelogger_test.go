@@ -0,0 +1,122 @@
+package db
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenLogged(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	db, err := openLogged("sqlite3", ":memory:", 0, nil)
+	if err != nil {
+		t.Fatalf("openLogged() = %v; want nil", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatalf("close db: %v", err)
+		}
+	}()
+	db.SetMaxOpenConns(1) // one in-memory database
+
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER PRIMARY KEY, data BLOB, note TEXT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+
+	t.Run("exec logs rows affected and redacts blobs", func(t *testing.T) {
+		logs.Reset()
+		_, err := db.Exec(`INSERT INTO t (data, note)
+			VALUES (?, ?), (?, ?)`, make([]byte, 1024), strings.Repeat("x", 300), []byte{0xab}, "short")
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		out := logs.String()
+		for _, want := range []string{"level=DEBUG", `query="INSERT INTO t (data, note) VALUES (?, ?), (?, ?)"`, "<blob 1024 bytes>", "(300 bytes)", "ab", "short", "rows=2", "duration="} {
+			if !strings.Contains(out, want) {
+				t.Errorf("log missing %q; got %q", want, out)
+			}
+		}
+	})
+
+	t.Run("query logs rows returned on close", func(t *testing.T) {
+		logs.Reset()
+		rows, err := db.Query(`SELECT id FROM t ORDER BY id`)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		for rows.Next() {
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatalf("close rows: %v", err)
+		}
+		if out := logs.String(); !strings.Contains(out, "rows=2") {
+			t.Errorf("log = %q; want rows=2", out)
+		}
+	})
+
+	t.Run("sensitive arguments are redacted", func(t *testing.T) {
+		redacting, err := openLogged("sqlite3", ":memory:", 0, []string{"note"})
+		if err != nil {
+			t.Fatalf("openLogged() = %v; want nil", err)
+		}
+		defer func() {
+			if err := redacting.Close(); err != nil {
+				t.Fatalf("close db: %v", err)
+			}
+		}()
+		redacting.SetMaxOpenConns(1)
+		if _, err := redacting.Exec(`CREATE TABLE t (note TEXT)`); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+		logs.Reset()
+		if _, err := redacting.Exec(`INSERT INTO t (note) VALUES (?)`, "hunter2"); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		out := logs.String()
+		if !strings.Contains(out, "<redacted>") || strings.Contains(out, "hunter2") {
+			t.Errorf("log = %q; want the note redacted", out)
+		}
+	})
+
+	t.Run("errors are logged at WARN", func(t *testing.T) {
+		logs.Reset()
+		if _, err := db.Exec(`INSERT INTO missing VALUES (1)`); err == nil {
+			t.Fatal("insert into missing table = nil error; want error")
+		}
+		out := logs.String()
+		if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "sql query failed") || !strings.Contains(out, "no such table") {
+			t.Errorf("log = %q; want a WARN with the error", out)
+		}
+	})
+}
+
+func TestOpenLogged_slow(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	db, err := openLogged("sqlite3", ":memory:", time.Nanosecond, nil)
+	if err != nil {
+		t.Fatalf("openLogged() = %v; want nil", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatalf("close db: %v", err)
+		}
+	}()
+
+	var n int
+	if err := db.QueryRow(`SELECT 1`).Scan(&n); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if out := logs.String(); !strings.Contains(out, "slow sql query") || !strings.Contains(out, "rows=1") {
+		t.Errorf("log = %q; want a slow query WARN with rows=1", out)
+	}
+}
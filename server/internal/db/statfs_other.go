@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package db
+
+import "errors"
+
+func freeBytes(string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
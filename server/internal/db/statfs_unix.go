@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package db
+
+import "syscall"
+
+// freeBytes returns the space on the volume holding dir that is available to
+// this process.
+func freeBytes(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"database/sql"
+
+	"cloudpico-server/internal/metrics"
+)
+
+// ExportStats exports the connection pool statistics of db at /metrics. Call
+// it once, for the server's pool.
+func ExportStats(db *sql.DB) {
+	exportStats(metrics.Default, db)
+}
+
+func exportStats(r *metrics.Registry, db *sql.DB) {
+	stat := func(f func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return f(db.Stats()) }
+	}
+	r.NewGaugeFunc("cloudpico_db_max_open_connections", "Maximum open database connections; 0 is unlimited.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewGaugeFunc("cloudpico_db_open_connections", "Open database connections, in use or idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("cloudpico_db_in_use_connections", "Database connections running a query or transaction.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("cloudpico_db_idle_connections", "Idle database connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewCounterFunc("cloudpico_db_wait_count_total", "Queries that waited for a free database connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("cloudpico_db_wait_duration_seconds_total", "Time spent waiting for a free database connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	r.NewCounterFunc("cloudpico_db_max_idle_closed_total", "Database connections closed because the idle pool was full.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	r.NewCounterFunc("cloudpico_db_max_lifetime_closed_total", "Database connections closed after SQLITE_CONN_MAX_LIFETIME.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
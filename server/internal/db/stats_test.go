@@ -0,0 +1,39 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/metrics"
+)
+
+func TestExportStats(t *testing.T) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(3)
+	if err := conn.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	r := metrics.NewRegistry()
+	exportStats(r, conn)
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() = %v; want nil", err)
+	}
+	for _, want := range []string{
+		"cloudpico_db_max_open_connections 3\n",
+		"cloudpico_db_open_connections 1\n",
+		"cloudpico_db_in_use_connections 0\n",
+		"cloudpico_db_idle_connections 1\n",
+		"# TYPE cloudpico_db_wait_count_total counter\ncloudpico_db_wait_count_total 0\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("WriteText() = %s\nwant it to contain %q", b.String(), want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package events provides an in-process publish/subscribe bus used to decouple
+// server modules (ingest, live streams, webhooks, alerting, caches).
+package events
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Topic identifies a kind of event.
+type Topic string
+
+const (
+	// ReadingCreated is published after a reading has been persisted.
+	// Payload is a cloudpico_shared.Telemetry.
+	ReadingCreated Topic = "reading.created"
+	// StationUpdated is published when station metadata changes.
+	StationUpdated Topic = "station.updated"
+	// AlertFired is published when an alert rule triggers.
+	// Payload is an alerts.Fired.
+	AlertFired Topic = "alert.fired"
+)
+
+// Event is a single notification delivered to subscribers.
+type Event struct {
+	Topic     Topic
+	Time      time.Time
+	StationID string
+	Payload   any
+}
+
+// Handler receives events. Handlers run synchronously on the publisher's
+// goroutine and must not block; hand off to a channel for slow work.
+type Handler func(Event)
+
+type subscription struct {
+	id      int
+	handler Handler
+}
+
+// Bus is a synchronous, topic-based event bus. The zero value is not usable; use NewBus.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[Topic][]subscription
+	nextID int
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[Topic][]subscription)}
+}
+
+// Subscribe registers h for topic and returns a function that removes the subscription.
+func (b *Bus) Subscribe(topic Topic, h Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	b.subs[topic] = append(b.subs[topic], subscription{id: id, handler: h})
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			subs := b.subs[topic]
+			for i, s := range subs {
+				if s.id == id {
+					b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// Publish delivers e to every subscriber of e.Topic. A panicking handler is
+// logged and does not prevent delivery to the remaining subscribers.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	b.mu.RLock()
+	subs := b.subs[e.Topic]
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		deliver(s.handler, e)
+	}
+}
+
+func deliver(h Handler, e Event) {
+	defer func() {
+		if err := recover(); err != nil {
+			slog.Error("event handler panic", "topic", e.Topic, "error", err)
+		}
+	}()
+	h(e)
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"testing"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	t.Run("delivers to subscribers of the topic only", func(t *testing.T) {
+		b := NewBus()
+		var readings, alerts int
+		b.Subscribe(ReadingCreated, func(Event) { readings++ })
+		b.Subscribe(ReadingCreated, func(Event) { readings++ })
+		b.Subscribe(AlertFired, func(Event) { alerts++ })
+
+		b.Publish(Event{Topic: ReadingCreated, StationID: "1"})
+
+		if readings != 2 {
+			t.Errorf("reading handlers called %d times; want 2", readings)
+		}
+		if alerts != 0 {
+			t.Errorf("alert handler called %d times; want 0", alerts)
+		}
+	})
+
+	t.Run("sets time when missing", func(t *testing.T) {
+		b := NewBus()
+		var got Event
+		b.Subscribe(StationUpdated, func(e Event) { got = e })
+
+		b.Publish(Event{Topic: StationUpdated})
+
+		if got.Time.IsZero() {
+			t.Error("event time is zero; want publish time")
+		}
+	})
+
+	t.Run("unsubscribe stops delivery", func(t *testing.T) {
+		b := NewBus()
+		var first, second int
+		unsub := b.Subscribe(ReadingCreated, func(Event) { first++ })
+		b.Subscribe(ReadingCreated, func(Event) { second++ })
+
+		unsub()
+		unsub() // idempotent
+		b.Publish(Event{Topic: ReadingCreated})
+
+		if first != 0 || second != 1 {
+			t.Errorf("calls = (%d, %d); want (0, 1)", first, second)
+		}
+	})
+
+	t.Run("panicking handler does not stop delivery", func(t *testing.T) {
+		b := NewBus()
+		called := false
+		b.Subscribe(AlertFired, func(Event) { panic("boom") })
+		b.Subscribe(AlertFired, func(Event) { called = true })
+
+		b.Publish(Event{Topic: AlertFired})
+
+		if !called {
+			t.Error("second handler not called after panic")
+		}
+	})
+
+	t.Run("nil bus publish is a no-op", func(t *testing.T) {
+		var b *Bus
+		b.Publish(Event{Topic: ReadingCreated})
+	})
+}
@@ -0,0 +1,64 @@
+package graphql
+
+import "fmt"
+
+// Query cost limit. The cost of a query is the number of values it may
+// return: each selected field counts once for every item of the lists above
+// it, so aliasing a list field many times under another list is as expensive
+// as the rows it fetches. Lists count as their field's Size, or
+// defaultListSize without one.
+const (
+	maxCost         = 1_000_000
+	defaultListSize = 100
+)
+
+// checkCost refuses an operation whose cost exceeds maxCost. It runs after
+// the variables are coerced, so list sizes taken from arguments see their
+// values, and skips what @skip and @include leave out.
+func (e *executor) checkCost(op *operation) error {
+	if cost := e.cost(e.schema.query, op.selection); cost > maxCost {
+		return fmt.Errorf("the query may return more than %d values; select fewer fields or lower list limits", maxCost)
+	}
+	return nil
+}
+
+// cost returns the cost of sel on one obj, or a number above maxCost once it
+// is certain to exceed it.
+func (e *executor) cost(obj *Object, sel []selection) int {
+	keys, fields := e.collectFields(sel)
+	total := 0
+	for _, key := range keys {
+		f := fields[key][0]
+		total++
+		if f.name == "__typename" {
+			continue
+		}
+		def := obj.Fields[f.name]
+		items := 1
+		if def.typ.elem != nil {
+			items = defaultListSize
+			if def.Size != nil {
+				args, err := e.argValues(def, f)
+				if err != nil {
+					continue // the field fails without resolving anything
+				}
+				items = max(0, def.Size(args))
+			}
+		}
+		per := 0
+		if child := e.schema.objects[def.typ.named()]; child != nil {
+			var childSel []selection
+			for _, f := range fields[key] {
+				childSel = append(childSel, f.selection...)
+			}
+			per = e.cost(child, childSel)
+		} else if def.typ.elem != nil {
+			per = 1
+		}
+		if per > 0 && items > (maxCost-total)/per {
+			return maxCost + 1
+		}
+		total += items * per
+	}
+	return total
+}
@@ -0,0 +1,535 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is left out when the request
+// failed before execution (see Executed), else it is the result of the
+// query, null when an error reached its root.
+type Response struct {
+	Data   any
+	Errors []Error
+
+	executed bool
+}
+
+// Executed reports whether the query ran: false when the request could not
+// be parsed or validated.
+func (r *Response) Executed() bool { return r.executed }
+
+// MarshalJSON writes the response in the GraphQL over HTTP format.
+func (r *Response) MarshalJSON() ([]byte, error) {
+	out := &object{}
+	if r.executed {
+		out.set("data", r.Data)
+	}
+	if len(r.Errors) > 0 {
+		out.set("errors", r.Errors)
+	}
+	return out.MarshalJSON()
+}
+
+// Error is a request or field error.
+type Error struct {
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+	Path      []any      `json:"path,omitempty"` // response keys and list indexes
+}
+
+// Location is a position in the query.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// object is a result object, marshaled with its keys in selection order.
+type object struct {
+	keys   []string
+	values []any
+}
+
+func (o *object) set(key string, v any) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, v)
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Execute parses, validates and runs req against s. Resolver errors become
+// field errors: the field is null and the error is listed with its path.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		se := err.(*SyntaxError)
+		return &Response{Errors: []Error{{Message: se.Msg, Locations: locate(req.Query, se.Pos)}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	v := &validator{schema: s, doc: doc, op: op, query: req.Query}
+	if errs := v.validate(); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	e := &executor{schema: s, doc: doc, vars: vars, query: req.Query}
+	if err := e.checkCost(op); err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+	res := &Response{executed: true}
+	if data, ok := e.selectionSet(ctx, s.query, nil, op.selection, nil); ok {
+		res.Data = data
+	}
+	res.Errors = e.errors
+	return res
+}
+
+// operation picks the operation to run: the one named name, or the only one
+// when name is empty.
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("the document has several operations; choose one with operationName")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+// locate converts a byte offset in query to a line and column.
+func locate(query string, pos int) []Location {
+	pos = min(pos, len(query))
+	line := 1 + strings.Count(query[:pos], "\n")
+	col := 1 + len([]rune(query[strings.LastIndexByte(query[:pos], '\n')+1:pos]))
+	return []Location{{Line: line, Column: col}}
+}
+
+type executor struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]any
+	query  string
+	errors []Error
+}
+
+func (e *executor) fieldError(f *field, path []any, msg string) {
+	e.errors = append(e.errors, Error{Message: msg, Locations: locate(e.query, f.pos), Path: append([]any(nil), path...)})
+}
+
+// selectionSet resolves the fields sel selects on source. It returns false
+// when a non-null field is null, making the whole object null.
+func (e *executor) selectionSet(ctx context.Context, obj *Object, source any, sel []selection, path []any) (*object, bool) {
+	keys, fields := e.collectFields(sel)
+	out := &object{}
+	for _, key := range keys {
+		v, ok := e.field(ctx, obj, source, fields[key], append(path, key))
+		if !ok {
+			return nil, false
+		}
+		out.set(key, v)
+	}
+	return out, true
+}
+
+// collectFields groups the fields sel selects by response key, in order,
+// expanding fragments and dropping what @skip and @include leave out.
+func (e *executor) collectFields(sel []selection) (keys []string, fields map[string][]*field) {
+	fields = make(map[string][]*field)
+	visited := make(map[string]bool)
+	var walk func(sel []selection)
+	walk = func(sel []selection) {
+		for _, s := range sel {
+			switch s := s.(type) {
+			case *field:
+				if !e.included(s.directives) {
+					continue
+				}
+				key := s.responseKey()
+				if _, ok := fields[key]; !ok {
+					keys = append(keys, key)
+				}
+				fields[key] = append(fields[key], s)
+			case *fragmentSpread:
+				if !e.included(s.directives) || visited[s.name] {
+					continue
+				}
+				visited[s.name] = true
+				walk(e.doc.fragments[s.name].selection)
+			case *inlineFragment:
+				if e.included(s.directives) {
+					walk(s.selection)
+				}
+			}
+		}
+	}
+	walk(sel)
+	return keys, fields
+}
+
+// included evaluates @skip and @include.
+func (e *executor) included(ds []directive) bool {
+	for _, d := range ds {
+		v, _ := coerceLiteral(typeRef{name: "Boolean", nonNull: true}, d.args[0].value, e.vars)
+		if on, _ := v.(bool); on == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// field resolves and completes the fields selected under one response key.
+func (e *executor) field(ctx context.Context, obj *Object, source any, fields []*field, path []any) (any, bool) {
+	f := fields[0]
+	if f.name == "__typename" {
+		return obj.Name, true
+	}
+	def := obj.Fields[f.name]
+	args, err := e.argValues(def, f)
+	if err == nil {
+		var v any
+		if v, err = def.Resolve(ctx, source, args); err == nil {
+			return e.complete(ctx, def.typ, fields, v, path)
+		}
+	}
+	e.fieldError(f, path, err.Error())
+	return nil, !def.typ.nonNull
+}
+
+// complete converts v, the value of a field of type t, to its result. It
+// returns false when the value is null but must not be.
+func (e *executor) complete(ctx context.Context, t typeRef, fields []*field, v any, path []any) (any, bool) {
+	if !t.nonNull {
+		// A nullable field absorbs a null from below.
+		if out, ok := e.completeValue(ctx, t, fields, v, path); ok {
+			return out, true
+		}
+		return nil, true
+	}
+	t.nonNull = false
+	out, ok := e.completeValue(ctx, t, fields, v, path)
+	if ok && out == nil {
+		e.fieldError(fields[0], path, "non-nullable field is null")
+		return nil, false
+	}
+	return out, ok
+}
+
+func (e *executor) completeValue(ctx context.Context, t typeRef, fields []*field, v any, path []any) (any, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, true
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, true
+	}
+	if t.elem != nil {
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fieldError(fields[0], path, "resolver returned a non-list value for a list")
+			return nil, false
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			item, ok := e.complete(ctx, *t.elem, fields, rv.Index(i).Interface(), append(path, i))
+			if !ok {
+				return nil, false
+			}
+			list[i] = item
+		}
+		return list, true
+	}
+	if scalars[t.name] {
+		out, err := serialize(t.name, rv)
+		if err != nil {
+			e.fieldError(fields[0], path, err.Error())
+			return nil, false
+		}
+		return out, true
+	}
+	var sel []selection
+	for _, f := range fields {
+		sel = append(sel, f.selection...)
+	}
+	out, ok := e.selectionSet(ctx, e.schema.objects[t.name], v, sel, path)
+	if !ok {
+		return nil, false
+	}
+	return out, true
+}
+
+// serialize converts a resolved scalar to its result.
+func serialize(scalar string, rv reflect.Value) (any, error) {
+	switch scalar {
+	case "Int":
+		if rv.CanInt() && rv.Int() >= math.MinInt32 && rv.Int() <= math.MaxInt32 {
+			return rv.Int(), nil
+		}
+	case "Float":
+		switch {
+		case rv.CanFloat() && !math.IsNaN(rv.Float()) && !math.IsInf(rv.Float(), 0):
+			return rv.Float(), nil
+		case rv.CanInt():
+			return float64(rv.Int()), nil
+		}
+	case "String":
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+	case "ID":
+		if rv.Kind() == reflect.String {
+			return rv.String(), nil
+		}
+		if rv.CanInt() {
+			return fmt.Sprint(rv.Int()), nil
+		}
+	case "Boolean":
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+	}
+	return nil, fmt.Errorf("resolver returned %v (%s) for %s", rv.Interface(), rv.Type(), scalar)
+}
+
+// argValues coerces the arguments f passes to def. Validation has checked
+// their types, so only values of variables can still be wrong here.
+func (e *executor) argValues(def *Field, f *field) (map[string]any, error) {
+	args := make(map[string]any, len(def.args))
+	for name, t := range def.args {
+		var lit value
+		given := false
+		for _, a := range f.args {
+			if a.name == name {
+				lit, given = a.value, true
+			}
+		}
+		if vn, ok := lit.(variable); ok {
+			_, given = e.vars[string(vn)]
+		}
+		if !given {
+			if d := def.Args[name].Default; d != nil {
+				args[name] = d
+			} else if t.nonNull {
+				return nil, fmt.Errorf("argument %q is required", name)
+			}
+			continue
+		}
+		v, err := coerceLiteral(t, lit, e.vars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+		args[name] = v
+	}
+	return args, nil
+}
+
+// coerceLiteral converts a literal to a value of type t, replacing variables
+// with their values; variables that were not given are null.
+func coerceLiteral(t typeRef, lit value, vars map[string]any) (any, error) {
+	if vn, ok := lit.(variable); ok {
+		v := vars[string(vn)]
+		if v == nil && t.nonNull {
+			return nil, fmt.Errorf("variable $%s must not be null", vn)
+		}
+		return v, nil
+	}
+	if lit == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected %s, got null", t)
+		}
+		return nil, nil
+	}
+	if t.elem != nil {
+		items, ok := lit.([]value)
+		if !ok {
+			items = []value{lit}
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			v, err := coerceLiteral(*t.elem, item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	switch v := lit.(type) {
+	case int64:
+		switch t.name {
+		case "Int":
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int(v), nil
+			}
+		case "Float":
+			return float64(v), nil
+		case "ID":
+			return fmt.Sprint(v), nil
+		}
+	case float64:
+		if t.name == "Float" {
+			return v, nil
+		}
+	case string:
+		if t.name == "String" || t.name == "ID" {
+			return v, nil
+		}
+	case bool:
+		if t.name == "Boolean" {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %s", t, printLiteral(lit))
+}
+
+// coerceVariables converts the JSON values of the variables op declares to
+// their types, applying defaults. Variables that are neither given nor
+// defaulted are left out.
+func coerceVariables(op *operation, given map[string]any) (map[string]any, error) {
+	vars := make(map[string]any, len(op.variables))
+	for _, vd := range op.variables {
+		v, ok := given[vd.name]
+		if !ok {
+			if vd.def != nil {
+				d, err := coerceLiteral(vd.typ, vd.def, nil)
+				if err != nil {
+					return nil, fmt.Errorf("variable $%s: %w", vd.name, err)
+				}
+				vars[vd.name] = d
+			} else if vd.typ.nonNull {
+				return nil, fmt.Errorf("variable $%s of type %s is required", vd.name, vd.typ)
+			}
+			continue
+		}
+		c, err := coerceInput(vd.typ, v)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", vd.name, err)
+		}
+		vars[vd.name] = c
+	}
+	return vars, nil
+}
+
+// coerceInput converts a JSON value to a value of type t.
+func coerceInput(t typeRef, v any) (any, error) {
+	if v == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected %s, got null", t)
+		}
+		return nil, nil
+	}
+	if t.elem != nil {
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			c, err := coerceInput(*t.elem, item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	}
+	switch v := v.(type) {
+	case float64:
+		switch t.name {
+		case "Int":
+			if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int(v), nil
+			}
+		case "Float":
+			return v, nil
+		case "ID":
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				return fmt.Sprint(int64(v)), nil
+			}
+		}
+	case string:
+		if t.name == "String" || t.name == "ID" {
+			return v, nil
+		}
+	case bool:
+		if t.name == "Boolean" {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, got %v", t, v)
+}
+
+// printLiteral formats a literal as in a query, for error messages.
+func printLiteral(lit value) string {
+	switch v := lit.(type) {
+	case nil:
+		return "null"
+	case string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	case variable:
+		return "$" + string(v)
+	case enumValue:
+		return string(v)
+	case []value:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = printLiteral(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case objectValue:
+		parts := make([]string, len(v))
+		for i, a := range v {
+			parts[i] = a.name + ": " + printLiteral(a.value)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(lit)
+}
@@ -0,0 +1,269 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testBook struct {
+	Title  string
+	Pages  *int
+	Author string
+}
+
+// testSchema has books with an author each and a field that always fails.
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	pages := 320
+	books := []testBook{{Title: "Dune", Pages: &pages, Author: "Herbert"}, {Title: "Solaris", Author: "Lem"}}
+	get := func(f func(testBook) any) ResolveFunc {
+		return func(_ context.Context, source any, _ map[string]any) (any, error) { return f(source.(testBook)), nil }
+	}
+	author := &Object{Name: "Author", Fields: map[string]*Field{
+		"name": {Type: "String!", Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) { return source, nil }},
+	}}
+	book := &Object{Name: "Book", Fields: map[string]*Field{
+		"title":  {Type: "String!", Resolve: get(func(b testBook) any { return b.Title })},
+		"pages":  {Type: "Int", Resolve: get(func(b testBook) any { return b.Pages })},
+		"author": {Type: "Author!", Resolve: get(func(b testBook) any { return b.Author })},
+		"broken": {Type: "String", Resolve: func(context.Context, any, map[string]any) (any, error) { return nil, errors.New("boom") }},
+		"strict": {Type: "String!", Resolve: get(func(b testBook) any { return map[string]*string{"Dune": new(string)}[b.Title] })},
+		"related": {
+			Type:    "[Book!]!",
+			Args:    map[string]Arg{"limit": {Type: "Int", Default: 10}},
+			Resolve: func(context.Context, any, map[string]any) (any, error) { return books, nil },
+			Size:    func(args map[string]any) int { return args["limit"].(int) },
+		},
+		"quote": {
+			Type: "String!",
+			Args: map[string]Arg{"n": {Type: "Int!", Default: 1}, "sep": {Type: "String"}},
+			Resolve: func(_ context.Context, source any, args map[string]any) (any, error) {
+				sep, _ := args["sep"].(string)
+				return strings.Repeat(source.(testBook).Title+sep, args["n"].(int)), nil
+			},
+		},
+	}}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"books": {Type: "[Book!]!", Resolve: func(context.Context, any, map[string]any) (any, error) { return books, nil }},
+		"book": {
+			Type: "Book",
+			Args: map[string]Arg{"title": {Type: "String!"}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				for _, b := range books {
+					if b.Title == args["title"] {
+						return b, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		"tags": {
+			Type: "[String!]",
+			Args: map[string]Arg{"in": {Type: "[String!]!"}},
+			Resolve: func(_ context.Context, _ any, args map[string]any) (any, error) {
+				return args["in"], nil
+			},
+		},
+	}}
+	s, err := NewSchema(query, book, author)
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+	return s
+}
+
+func marshal(t *testing.T, res *Response) string {
+	t.Helper()
+	b, err := json.Marshal(res)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return string(b)
+}
+
+func TestExecute(t *testing.T) {
+	s := testSchema(t)
+	for _, tt := range []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "nested selections in order",
+			req:  Request{Query: `{ books { title pages author { name } } }`},
+			want: `{"data":{"books":[{"title":"Dune","pages":320,"author":{"name":"Herbert"}},{"title":"Solaris","pages":null,"author":{"name":"Lem"}}]}}`,
+		},
+		{
+			name: "aliases and arguments",
+			req:  Request{Query: `{ a: book(title: "Dune") { q: quote(n: 2, sep: "!") } b: book(title: "Nope") { title } }`},
+			want: `{"data":{"a":{"q":"Dune!Dune!"},"b":null}}`,
+		},
+		{
+			name: "argument defaults",
+			req:  Request{Query: `{ book(title: "Dune") { quote } }`},
+			want: `{"data":{"book":{"quote":"Dune"}}}`,
+		},
+		{
+			name: "variables and defaults",
+			req: Request{
+				Query:     `query Q($t: String!, $n: Int = 3) { book(title: $t) { quote(n: $n) } }`,
+				Variables: map[string]any{"t": "Solaris"},
+			},
+			want: `{"data":{"book":{"quote":"SolarisSolarisSolaris"}}}`,
+		},
+		{
+			name: "list argument from a single value",
+			req:  Request{Query: `{ tags(in: "x") }`},
+			want: `{"data":{"tags":["x"]}}`,
+		},
+		{
+			name: "fragments and typename",
+			req:  Request{Query: `{ book(title: "Dune") { ...F ... on Book { pages } ... { __typename } } } fragment F on Book { title title }`},
+			want: `{"data":{"book":{"title":"Dune","pages":320,"__typename":"Book"}}}`,
+		},
+		{
+			name: "skip and include",
+			req: Request{
+				Query:     `query($yes: Boolean!) { book(title: "Dune") { title @skip(if: $yes) pages @include(if: $yes) ... @include(if: false) { author { name } } } }`,
+				Variables: map[string]any{"yes": true},
+			},
+			want: `{"data":{"book":{"pages":320}}}`,
+		},
+		{
+			name: "named operation",
+			req:  Request{Query: `query A { books { title } } query B { book(title: "Dune") { title } }`, OperationName: "B"},
+			want: `{"data":{"book":{"title":"Dune"}}}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Execute(context.Background(), tt.req)
+			if got := marshal(t, res); got != tt.want {
+				t.Errorf("Execute() = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_fieldErrors(t *testing.T) {
+	s := testSchema(t)
+
+	t.Run("nullable field is null", func(t *testing.T) {
+		res := s.Execute(context.Background(), Request{Query: `{ book(title: "Dune") { title broken } }`})
+		want := `{"data":{"book":{"title":"Dune","broken":null}},"errors":[{"message":"boom","locations":[{"line":1,"column":31}],"path":["book","broken"]}]}`
+		if got := marshal(t, res); got != want {
+			t.Errorf("Execute() = %s; want %s", got, want)
+		}
+	})
+
+	t.Run("null in a non-null field nulls the nearest nullable parent", func(t *testing.T) {
+		res := s.Execute(context.Background(), Request{Query: "{\n  books { strict }\n  book(title: \"Dune\") { title }\n}"})
+		want := `{"data":null,"errors":[{"message":"non-nullable field is null","locations":[{"line":2,"column":11}],"path":["books",1,"strict"]}]}`
+		if got := marshal(t, res); got != want {
+			t.Errorf("Execute() = %s; want %s", got, want)
+		}
+		if !res.Executed() {
+			t.Error("Executed() = false; want true")
+		}
+	})
+}
+
+func TestExecute_requestErrors(t *testing.T) {
+	s := testSchema(t)
+	for _, tt := range []struct {
+		name, query string
+		vars        map[string]any
+		op          string
+		want        string
+	}{
+		{"syntax", `{ books { title }`, nil, "", `expected "}"`},
+		{"unterminated string", `{ book(title: "Dune) { title } }`, nil, "", "unterminated string"},
+		{"mutation", `mutation { books { title } }`, nil, "", "mutation operations are not supported"},
+		{"several operations", `query A { books { title } } query B { books { title } }`, nil, "", "choose one with operationName"},
+		{"unknown operation", `query A { books { title } }`, nil, "B", `unknown operation "B"`},
+		{"unknown field", `{ books { isbn } }`, nil, "", `cannot query field "isbn" on type Book`},
+		{"missing selection", `{ books }`, nil, "", "needs a selection of fields"},
+		{"selection on a scalar", `{ books { title { x } } }`, nil, "", "has no fields to select"},
+		{"missing argument", `{ book { title } }`, nil, "", `requires argument "title"`},
+		{"unknown argument", `{ books(first: 1) { title } }`, nil, "", `unknown argument "first"`},
+		{"argument type", `{ book(title: 5) { title } }`, nil, "", "expected String!, got 5"},
+		{"undeclared variable", `{ book(title: $t) { title } }`, nil, "", "undeclared variable $t"},
+		{"variable type", `query($t: Int!) { book(title: $t) { title } }`, nil, "", "cannot be used as"},
+		{"nullable variable", `query($t: String) { book(title: $t) { title } }`, nil, "", "cannot be used as"},
+		{"missing variable", `query($t: String!) { book(title: $t) { title } }`, nil, "", "variable $t of type String! is required"},
+		{"variable value", `query($n: Int) { books { quote(n: $n) } }`, map[string]any{"n": 1.5}, "", "expected Int, got 1.5"},
+		{"unknown fragment", `{ books { ...F } }`, nil, "", `unknown fragment "F"`},
+		{"fragment cycle", `{ books { ...A } } fragment A on Book { ...B } fragment B on Book { ...A }`, nil, "", `fragment "A" spreads itself`},
+		{"fragment type", `{ books { ...F } } fragment F on Author { name }`, nil, "", "cannot be spread on Book"},
+		{"unknown directive", `{ books @defer { title } }`, nil, "", "unknown directive @defer"},
+		{"conflicting keys", `{ book(title: "Dune") { x: title x: pages } }`, nil, "", `"x" selects different fields`},
+		{"conflicting arguments", `{ book(title: "Dune") { quote(n: 1) quote(n: 2) } }`, nil, "", `"quote" selects different fields or arguments`},
+		{"too many fields", "{ books { " + strings.Repeat("title ", maxFields+1) + "} }", nil, "", "more than 500 fields"},
+		{"nested lists", `{ books { related(limit: 1000) { related(limit: 1000) { title } } } }`, nil, "", "more than 1000000 values"},
+		{"aliased lists", "query($n: Int) { books { " + aliases(20, "related(limit: $n) { title }") + "} }",
+			map[string]any{"n": 1000.0}, "", "more than 1000000 values"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.Execute(context.Background(), Request{Query: tt.query, Variables: tt.vars, OperationName: tt.op})
+			if res.Executed() {
+				t.Fatalf("Executed() = true; want false")
+			}
+			if got := marshal(t, res); strings.Contains(got, `"data"`) || len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, tt.want) {
+				t.Errorf("Execute() = %s; want errors only, with %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSchema_errors(t *testing.T) {
+	resolve := func(context.Context, any, map[string]any) (any, error) { return nil, nil }
+	for _, tt := range []struct {
+		name  string
+		field *Field
+		want  string
+	}{
+		{"no resolver", &Field{Type: "String"}, "has no resolver"},
+		{"bad type", &Field{Type: "[String", Resolve: resolve}, "invalid type"},
+		{"unknown type", &Field{Type: "Widget", Resolve: resolve}, `unknown type "Widget"`},
+		{"object argument", &Field{Type: "String", Args: map[string]Arg{"q": {Type: "Query"}}, Resolve: resolve}, "invalid argument type"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSchema(&Object{Name: "Query", Fields: map[string]*Field{"f": tt.field}})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewSchema() error = %v; want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// aliases selects sel n times under the aliases a0, a1, ….
+func aliases(n int, sel string) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "a%d: %s ", i, sel)
+	}
+	return b.String()
+}
+
+func TestExecute_cost(t *testing.T) {
+	s := testSchema(t)
+	// 100 books (the default list size) × 5 aliases × 1001 values is within
+	// the limit, and lists are counted by their limit, not their results.
+	res := s.Execute(context.Background(), Request{
+		Query:     "query($n: Int) { books { " + aliases(5, "related(limit: $n) { title }") + "} }",
+		Variables: map[string]any{"n": 1000.0},
+	})
+	if !res.Executed() || len(res.Errors) > 0 {
+		t.Errorf("Execute() = %s; want it to run", marshal(t, res))
+	}
+	// Skipped fields cost nothing.
+	res = s.Execute(context.Background(), Request{
+		Query: `{ books { related(limit: 1000) { related(limit: 1000) @skip(if: true) { title } title } } }`,
+	})
+	if !res.Executed() {
+		t.Errorf("Execute() = %s; want skipped fields left out of the cost", marshal(t, res))
+	}
+}
@@ -0,0 +1,64 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"cloudpico-server/internal/utils"
+)
+
+// maxRequestBytes caps the size of a POST body.
+const maxRequestBytes = 64 << 10
+
+// Handler serves GraphQL over HTTP: GET with query, operationName and
+// variables (JSON) parameters, or POST with a JSON Request body.
+type Handler struct {
+	schema *Schema
+}
+
+func NewHandler(schema *Schema) *Handler {
+	return &Handler{schema: schema}
+}
+
+// ServeHTTP runs the query. Requests that cannot be parsed or validated are
+// answered with 400 and the errors; executed queries with 200, the data and
+// any field errors.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, &Response{Errors: []Error{{Message: err.Error()}}})
+		return
+	}
+	res := h.schema.Execute(r.Context(), req)
+	status := http.StatusOK
+	if !res.Executed() {
+		status = http.StatusBadRequest
+	}
+	utils.WriteJSON(w, status, res)
+}
+
+func readRequest(r *http.Request) (Request, error) {
+	var req Request
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return req, errors.New("variables must be a JSON object")
+			}
+		}
+	} else {
+		if ct := r.Header.Get("Content-Type"); ct != "" && !strings.HasPrefix(ct, "application/json") {
+			return req, errors.New("Content-Type must be application/json")
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			return req, errors.New("body must be a JSON object with a query")
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return req, errors.New("query is required")
+	}
+	return req, nil
+}
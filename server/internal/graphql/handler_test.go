@@ -0,0 +1,72 @@
+package graphql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(testSchema(t))
+	for _, tt := range []struct {
+		name, method, target, contentType, body string
+		wantCode                                int
+		wantBody                                string
+	}{
+		{
+			name: "GET", method: http.MethodGet,
+			target:   "/graphql?" + url.Values{"query": {`query($t: String!) { book(title: $t) { title } }`}, "variables": {`{"t":"Dune"}`}}.Encode(),
+			wantCode: http.StatusOK, wantBody: `{"data":{"book":{"title":"Dune"}}}`,
+		},
+		{
+			name: "POST", method: http.MethodPost, target: "/graphql", contentType: "application/json",
+			body:     `{"query":"query A { books { title } } query B { book(title: \"Solaris\") { title } }","operationName":"B"}`,
+			wantCode: http.StatusOK, wantBody: `{"data":{"book":{"title":"Solaris"}}}`,
+		},
+		{
+			name: "field errors are 200", method: http.MethodPost, target: "/graphql",
+			body:     `{"query":"{ book(title: \"Dune\") { broken } }"}`,
+			wantCode: http.StatusOK, wantBody: `{"data":{"book":{"broken":null}},"errors":[{"message":"boom"`,
+		},
+		{
+			name: "invalid query", method: http.MethodPost, target: "/graphql",
+			body:     `{"query":"{ nope }"}`,
+			wantCode: http.StatusBadRequest, wantBody: `{"errors":[{"message":"cannot query field`,
+		},
+		{
+			name: "missing query", method: http.MethodGet, target: "/graphql",
+			wantCode: http.StatusBadRequest, wantBody: `{"errors":[{"message":"query is required"}]}`,
+		},
+		{
+			name: "invalid variables", method: http.MethodGet, target: "/graphql?query=%7Bbooks%7Btitle%7D%7D&variables=%5B%5D",
+			wantCode: http.StatusBadRequest, wantBody: `{"errors":[{"message":"variables must be a JSON object"}]}`,
+		},
+		{
+			name: "invalid body", method: http.MethodPost, target: "/graphql", body: `query`,
+			wantCode: http.StatusBadRequest, wantBody: `{"errors":[{"message":"body must be a JSON object with a query"}]}`,
+		},
+		{
+			name: "content type", method: http.MethodPost, target: "/graphql", contentType: "application/graphql", body: `{ books { title } }`,
+			wantCode: http.StatusBadRequest, wantBody: `{"errors":[{"message":"Content-Type must be application/json"}]}`,
+		},
+		{
+			name: "body too large", method: http.MethodPost, target: "/graphql",
+			body:     `{"query":"{ books { title } }` + strings.Repeat(" ", maxRequestBytes) + `"}`,
+			wantCode: http.StatusBadRequest, wantBody: `body must be a JSON object`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %s; want %d %s", tt.method, tt.target, rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,530 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind      string // "query", "mutation" or "subscription"
+	name      string
+	variables []variableDef
+	selection []selection
+}
+
+type variableDef struct {
+	name string
+	typ  typeRef
+	def  value // nil without a default
+}
+
+// typeRef is a variable type such as [Int!]!.
+type typeRef struct {
+	name    string   // named type; empty for lists
+	elem    *typeRef // list element type
+	nonNull bool
+}
+
+func (t typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selection     []selection
+}
+
+// selection is a *field, *fragmentSpread or *inlineFragment.
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	args       []argument
+	directives []directive
+	selection  []selection
+	pos        int
+}
+
+// responseKey is the key of the field in the result.
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []directive
+	pos        int
+}
+
+type inlineFragment struct {
+	typeCondition string // empty applies to any type
+	directives    []directive
+	selection     []selection
+}
+
+type argument struct {
+	name  string
+	value value
+}
+
+type directive struct {
+	name string
+	args []argument
+	pos  int
+}
+
+// value is a literal: nil (null), bool, int64, float64, string, enumValue,
+// variable, []value or objectValue.
+type value any
+
+type enumValue string
+
+type variable string
+
+type objectValue []argument
+
+// token kinds.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	text string // punctuator, name, number or decoded string
+	pos  int
+}
+
+// SyntaxError reports a malformed document.
+type SyntaxError struct {
+	Pos int // byte offset in the document
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at offset %d: %s", e.Pos, e.Msg)
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// parse parses src as an executable document: operations and fragments.
+func parse(src string) (doc *document, err error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, se
+		}
+	}()
+	p.next()
+	doc = &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.isPunct("{"):
+			doc.operations = append(doc.operations, &operation{kind: "query", selection: p.selectionSet()})
+		case p.tok.kind == tokName && p.tok.text == "fragment":
+			f := p.fragmentDefinition()
+			if _, dup := doc.fragments[f.name]; dup {
+				p.fail(fmt.Sprintf("fragment %q is defined twice", f.name))
+			}
+			doc.fragments[f.name] = f
+		case p.tok.kind == tokName && (p.tok.text == "query" || p.tok.text == "mutation" || p.tok.text == "subscription"):
+			doc.operations = append(doc.operations, p.operationDefinition())
+		default:
+			p.fail("expected an operation or fragment")
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail("the document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) fail(msg string) {
+	panic(&SyntaxError{Pos: p.tok.pos, Msg: msg})
+}
+
+func (p *parser) isPunct(s string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == s
+}
+
+func (p *parser) expectPunct(s string) {
+	if !p.isPunct(s) {
+		p.fail(fmt.Sprintf("expected %q", s))
+	}
+	p.next()
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.fail("expected a name")
+	}
+	s := p.tok.text
+	p.next()
+	return s
+}
+
+func (p *parser) operationDefinition() *operation {
+	op := &operation{kind: p.name()}
+	if p.tok.kind == tokName {
+		op.name = p.name()
+	}
+	if p.isPunct("(") {
+		p.next()
+		for !p.isPunct(")") {
+			p.expectPunct("$")
+			v := variableDef{name: p.name()}
+			p.expectPunct(":")
+			v.typ = p.typeRef()
+			if p.isPunct("=") {
+				p.next()
+				v.def = p.value(true)
+			}
+			op.variables = append(op.variables, v)
+		}
+		p.next()
+	}
+	if len(p.directives()) > 0 {
+		p.fail("operation directives are not supported")
+	}
+	op.selection = p.selectionSet()
+	return op
+}
+
+func (p *parser) typeRef() typeRef {
+	var t typeRef
+	if p.isPunct("[") {
+		p.next()
+		elem := p.typeRef()
+		p.expectPunct("]")
+		t.elem = &elem
+	} else {
+		t.name = p.name()
+	}
+	if p.isPunct("!") {
+		p.next()
+		t.nonNull = true
+	}
+	return t
+}
+
+func (p *parser) fragmentDefinition() *fragment {
+	p.next() // "fragment"
+	f := &fragment{name: p.name()}
+	if f.name == "on" {
+		p.fail(`a fragment cannot be named "on"`)
+	}
+	if p.tok.kind != tokName || p.tok.text != "on" {
+		p.fail(`expected "on"`)
+	}
+	p.next()
+	f.typeCondition = p.name()
+	if len(p.directives()) > 0 {
+		p.fail("fragment definition directives are not supported")
+	}
+	f.selection = p.selectionSet()
+	return f
+}
+
+func (p *parser) selectionSet() []selection {
+	p.expectPunct("{")
+	var sel []selection
+	for !p.isPunct("}") {
+		if p.tok.kind == tokEOF {
+			p.fail(`expected "}"`)
+		}
+		sel = append(sel, p.selection())
+	}
+	p.next()
+	if len(sel) == 0 {
+		p.fail("empty selection set")
+	}
+	return sel
+}
+
+func (p *parser) selection() selection {
+	if p.isPunct("...") {
+		pos := p.tok.pos
+		p.next()
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			return &fragmentSpread{name: p.name(), directives: p.directives(), pos: pos}
+		}
+		f := &inlineFragment{}
+		if p.tok.kind == tokName {
+			p.next() // "on"
+			f.typeCondition = p.name()
+		}
+		f.directives = p.directives()
+		f.selection = p.selectionSet()
+		return f
+	}
+	pos := p.tok.pos
+	f := &field{pos: pos, name: p.name()}
+	if p.isPunct(":") {
+		p.next()
+		f.alias, f.name = f.name, p.name()
+	}
+	f.args = p.arguments(false)
+	f.directives = p.directives()
+	if p.isPunct("{") {
+		f.selection = p.selectionSet()
+	}
+	return f
+}
+
+func (p *parser) arguments(constant bool) []argument {
+	if !p.isPunct("(") {
+		return nil
+	}
+	p.next()
+	var args []argument
+	for !p.isPunct(")") {
+		a := argument{name: p.name()}
+		p.expectPunct(":")
+		a.value = p.value(constant)
+		args = append(args, a)
+	}
+	p.next()
+	return args
+}
+
+func (p *parser) directives() []directive {
+	var ds []directive
+	for p.isPunct("@") {
+		pos := p.tok.pos
+		p.next()
+		ds = append(ds, directive{name: p.name(), args: p.arguments(false), pos: pos})
+	}
+	return ds
+}
+
+func (p *parser) value(constant bool) value {
+	t := p.tok
+	switch t.kind {
+	case tokPunct:
+		switch t.text {
+		case "$":
+			if constant {
+				p.fail("variables are not allowed here")
+			}
+			p.next()
+			return variable(p.name())
+		case "[":
+			p.next()
+			list := []value{}
+			for !p.isPunct("]") {
+				if p.tok.kind == tokEOF {
+					p.fail(`expected "]"`)
+				}
+				list = append(list, p.value(constant))
+			}
+			p.next()
+			return list
+		case "{":
+			p.next()
+			obj := objectValue{}
+			for !p.isPunct("}") {
+				a := argument{name: p.name()}
+				p.expectPunct(":")
+				a.value = p.value(constant)
+				obj = append(obj, a)
+			}
+			p.next()
+			return obj
+		}
+	case tokInt:
+		p.next()
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			panic(&SyntaxError{Pos: t.pos, Msg: "integer out of range"})
+		}
+		return n
+	case tokFloat:
+		p.next()
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			panic(&SyntaxError{Pos: t.pos, Msg: "invalid float"})
+		}
+		return f
+	case tokString:
+		p.next()
+		return t.text
+	case tokName:
+		p.next()
+		switch t.text {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(t.text)
+	}
+	p.fail("expected a value")
+	return nil
+}
+
+// next reads the next token, skipping whitespace, commas and comments.
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+		} else if strings.HasPrefix(p.src[p.pos:], "\uFEFF") {
+			p.pos += len("\uFEFF")
+		} else {
+			break
+		}
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = token{kind: tokPunct, text: "...", pos: start}
+	case strings.IndexByte("!$&()=:@[]{|}", c) >= 0:
+		p.pos++
+		p.tok = token{kind: tokPunct, text: string(c), pos: start}
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: p.src[start:p.pos], pos: start}
+	case c == '-' || c >= '0' && c <= '9':
+		p.number()
+	case c == '"':
+		p.string()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		panic(&SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", r)})
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
+
+func (p *parser) number() {
+	start := p.pos
+	digits := func() int {
+		n := 0
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	intStart := p.pos
+	if digits() == 0 || p.src[intStart] == '0' && p.pos-intStart > 1 {
+		panic(&SyntaxError{Pos: start, Msg: "invalid number"})
+	}
+	kind := tokInt
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		if digits() == 0 {
+			panic(&SyntaxError{Pos: start, Msg: "invalid number"})
+		}
+		kind = tokFloat
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		if digits() == 0 {
+			panic(&SyntaxError{Pos: start, Msg: "invalid number"})
+		}
+		kind = tokFloat
+	}
+	if p.pos < len(p.src) && (isNameChar(p.src[p.pos]) || p.src[p.pos] == '.') {
+		panic(&SyntaxError{Pos: start, Msg: "invalid number"})
+	}
+	p.tok = token{kind: kind, text: p.src[start:p.pos], pos: start}
+}
+
+// string reads a quoted or block string.
+func (p *parser) string() {
+	start := p.pos
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			panic(&SyntaxError{Pos: start, Msg: "unterminated string"})
+		}
+		raw := p.src[p.pos+3 : p.pos+3+end]
+		p.pos += 3 + end + 3
+		p.tok = token{kind: tokString, text: strings.TrimSpace(raw), pos: start}
+		return
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			panic(&SyntaxError{Pos: start, Msg: "unterminated string"})
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			p.tok = token{kind: tokString, text: b.String(), pos: start}
+			return
+		case c != '\\':
+			b.WriteByte(c)
+			p.pos++
+		case p.pos+1 < len(p.src) && strings.IndexByte(`"\/bfnrt`, p.src[p.pos+1]) >= 0:
+			b.WriteByte(map[byte]byte{'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}[p.src[p.pos+1]])
+			p.pos += 2
+		case strings.HasPrefix(p.src[p.pos:], `\u`) && p.pos+6 <= len(p.src):
+			r, err := strconv.ParseUint(p.src[p.pos+2:p.pos+6], 16, 32)
+			if err != nil {
+				panic(&SyntaxError{Pos: p.pos, Msg: "invalid unicode escape"})
+			}
+			b.WriteRune(rune(r))
+			p.pos += 6
+		default:
+			panic(&SyntaxError{Pos: p.pos, Msg: "invalid escape sequence"})
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Package graphql executes GraphQL queries against a schema of objects whose
+// fields are resolved by Go functions. It covers what read-only API clients
+// use: queries with variables, aliases, arguments, fragments and the @skip
+// and @include directives. Mutations, subscriptions, introspection, input
+// objects, enums, interfaces and unions are not supported.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ResolveFunc returns the value of a field of source, the value the parent
+// field resolved to (nil for fields of the query type). args holds the
+// field's arguments coerced to their types: int, float64, string, bool or
+// []any; arguments that are neither given nor defaulted are left out.
+//
+// Scalar fields may return the matching Go type or a pointer to it, nil
+// pointers being null; list fields return a slice; object fields return the
+// source of their own fields.
+type ResolveFunc func(ctx context.Context, source any, args map[string]any) (any, error)
+
+// Object is an object type.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Field is a field of an object type.
+type Field struct {
+	// Type is the field type in GraphQL notation, e.g. "[Reading!]!": a
+	// scalar (Int, Float, String, Boolean, ID) or an object of the schema.
+	Type    string
+	Args    map[string]Arg
+	Resolve ResolveFunc
+	// Size returns the most items a list field resolves to given its
+	// arguments, for the query cost limit; lists without it count as
+	// defaultListSize.
+	Size func(args map[string]any) int
+
+	typ  typeRef
+	args map[string]typeRef
+}
+
+// Arg is an argument of a field.
+type Arg struct {
+	Type    string // a scalar or a list of scalars, e.g. "ID!"
+	Default any    // used when the argument is not given; nil for none
+}
+
+// scalars are the built-in scalar types.
+var scalars = map[string]bool{"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true}
+
+// Schema is a validated set of object types with a query type as the root
+// of every query.
+type Schema struct {
+	query   *Object
+	objects map[string]*Object
+}
+
+// NewSchema checks query and the other object types its fields name, and
+// parses their type notation.
+func NewSchema(query *Object, types ...*Object) (*Schema, error) {
+	s := &Schema{query: query, objects: make(map[string]*Object)}
+	for _, obj := range append([]*Object{query}, types...) {
+		if obj.Name == "" || scalars[obj.Name] {
+			return nil, fmt.Errorf("graphql: invalid object name %q", obj.Name)
+		}
+		if _, dup := s.objects[obj.Name]; dup {
+			return nil, fmt.Errorf("graphql: two objects are named %q", obj.Name)
+		}
+		s.objects[obj.Name] = obj
+	}
+	for _, obj := range s.objects {
+		names := make([]string, 0, len(obj.Fields))
+		for name := range obj.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := s.initField(obj, name, obj.Fields[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) initField(obj *Object, name string, f *Field) error {
+	if f.Resolve == nil {
+		return fmt.Errorf("graphql: %s.%s has no resolver", obj.Name, name)
+	}
+	typ, err := parseType(f.Type)
+	if err != nil {
+		return fmt.Errorf("graphql: %s.%s: %w", obj.Name, name, err)
+	}
+	if t := typ.named(); !scalars[t] && s.objects[t] == nil {
+		return fmt.Errorf("graphql: %s.%s: unknown type %q", obj.Name, name, t)
+	}
+	f.typ = typ
+	f.args = make(map[string]typeRef, len(f.Args))
+	for argName, arg := range f.Args {
+		at, err := parseType(arg.Type)
+		if err != nil || !scalars[at.named()] {
+			return fmt.Errorf("graphql: %s.%s(%s): invalid argument type %q", obj.Name, name, argName, arg.Type)
+		}
+		f.args[argName] = at
+	}
+	return nil
+}
+
+// parseType parses type notation such as "[ID!]!".
+func parseType(s string) (t typeRef, err error) {
+	p := &parser{src: s}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*SyntaxError); !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("invalid type %q", s)
+		}
+	}()
+	p.next()
+	t = p.typeRef()
+	if p.tok.kind != tokEOF {
+		p.fail("trailing input")
+	}
+	return t, nil
+}
+
+// named is the type with list and non-null wrappers removed.
+func (t typeRef) named() string {
+	for t.elem != nil {
+		t = *t.elem
+	}
+	return t.name
+}
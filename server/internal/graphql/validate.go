@@ -0,0 +1,262 @@
+package graphql
+
+import "fmt"
+
+// maxFields caps the fields written in a query, counting each expansion of a
+// fragment, which bounds the work of validating it. What running it costs is
+// bounded by maxCost.
+const maxFields = 500
+
+// validator checks an operation against the schema before it runs, so
+// execution only meets errors in variable values and resolvers.
+type validator struct {
+	schema *Schema
+	doc    *document
+	op     *operation
+	query  string
+	errs   []Error
+	vars   map[string]variableDef
+	fields int
+}
+
+func (v *validator) errorf(pos int, format string, args ...any) {
+	v.errs = append(v.errs, Error{Message: fmt.Sprintf(format, args...), Locations: locate(v.query, pos)})
+}
+
+func (v *validator) validate() []Error {
+	if v.op.kind != "query" {
+		return []Error{{Message: fmt.Sprintf("%s operations are not supported", v.op.kind)}}
+	}
+	v.vars = make(map[string]variableDef, len(v.op.variables))
+	for _, vd := range v.op.variables {
+		if _, dup := v.vars[vd.name]; dup {
+			v.errs = append(v.errs, Error{Message: fmt.Sprintf("variable $%s is declared twice", vd.name)})
+		}
+		v.vars[vd.name] = vd
+		if !scalars[vd.typ.named()] {
+			v.errs = append(v.errs, Error{Message: fmt.Sprintf("variable $%s has unknown type %s", vd.name, vd.typ)})
+			continue
+		}
+		if vd.def != nil {
+			if _, err := coerceLiteral(vd.typ, vd.def, nil); err != nil {
+				v.errs = append(v.errs, Error{Message: fmt.Sprintf("variable $%s default: %s", vd.name, err)})
+			}
+		}
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	v.selectionSet(v.schema.query, v.op.selection, nil)
+	if len(v.errs) == 0 {
+		v.fieldsCanMerge(v.schema.query, v.op.selection)
+	}
+	return v.errs
+}
+
+// selectionSet checks sel against obj; spreading names the fragments being
+// expanded, to catch cycles.
+func (v *validator) selectionSet(obj *Object, sel []selection, spreading []string) {
+	for _, s := range sel {
+		switch s := s.(type) {
+		case *field:
+			v.field(obj, s, spreading)
+		case *fragmentSpread:
+			v.directives(s.directives)
+			frag, ok := v.doc.fragments[s.name]
+			if !ok {
+				v.errorf(s.pos, "unknown fragment %q", s.name)
+				continue
+			}
+			if frag.typeCondition != obj.Name {
+				v.errorf(s.pos, "fragment %q on %s cannot be spread on %s", s.name, frag.typeCondition, obj.Name)
+				continue
+			}
+			cycle := false
+			for _, name := range spreading {
+				cycle = cycle || name == s.name
+			}
+			if cycle {
+				v.errorf(s.pos, "fragment %q spreads itself", s.name)
+				continue
+			}
+			v.selectionSet(obj, frag.selection, append(spreading, s.name))
+		case *inlineFragment:
+			v.directives(s.directives)
+			if s.typeCondition != "" && s.typeCondition != obj.Name {
+				v.errs = append(v.errs, Error{Message: fmt.Sprintf("inline fragment on %s cannot be spread on %s", s.typeCondition, obj.Name)})
+				continue
+			}
+			v.selectionSet(obj, s.selection, spreading)
+		}
+	}
+}
+
+func (v *validator) field(obj *Object, f *field, spreading []string) {
+	v.fields++
+	if v.fields == maxFields+1 {
+		v.errorf(f.pos, "the query selects more than %d fields", maxFields)
+	}
+	v.directives(f.directives)
+	if f.name == "__typename" {
+		if len(f.args) > 0 || f.selection != nil {
+			v.errorf(f.pos, "__typename takes no arguments or selections")
+		}
+		return
+	}
+	def, ok := obj.Fields[f.name]
+	if !ok {
+		v.errorf(f.pos, "cannot query field %q on type %s", f.name, obj.Name)
+		return
+	}
+	seen := make(map[string]bool, len(f.args))
+	for _, a := range f.args {
+		t, ok := def.args[a.name]
+		switch {
+		case !ok:
+			v.errorf(f.pos, "unknown argument %q on field %s.%s", a.name, obj.Name, f.name)
+		case seen[a.name]:
+			v.errorf(f.pos, "argument %q is given twice", a.name)
+		default:
+			v.value(f.pos, fmt.Sprintf("argument %q", a.name), t, def.Args[a.name].Default != nil, a.value)
+		}
+		seen[a.name] = true
+	}
+	for name, t := range def.args {
+		if t.nonNull && def.Args[name].Default == nil && !seen[name] {
+			v.errorf(f.pos, "field %s.%s requires argument %q", obj.Name, f.name, name)
+		}
+	}
+	child := v.schema.objects[def.typ.named()]
+	switch {
+	case child == nil && f.selection != nil:
+		v.errorf(f.pos, "field %q of type %s has no fields to select", f.name, def.typ)
+	case child != nil && f.selection == nil:
+		v.errorf(f.pos, "field %q of type %s needs a selection of fields", f.name, def.typ)
+	case child != nil:
+		v.selectionSet(child, f.selection, spreading)
+	}
+}
+
+// directives accepts @skip(if: Boolean!) and @include(if: Boolean!).
+func (v *validator) directives(ds []directive) {
+	for _, d := range ds {
+		if d.name != "skip" && d.name != "include" {
+			v.errorf(d.pos, "unknown directive @%s", d.name)
+			continue
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			v.errorf(d.pos, "@%s takes exactly one argument, if", d.name)
+			continue
+		}
+		v.value(d.pos, "@"+d.name+"(if)", typeRef{name: "Boolean", nonNull: true}, false, d.args[0].value)
+	}
+}
+
+// value checks that lit, or the variables in it, fit type t at a location
+// that has a default when hasDefault.
+func (v *validator) value(pos int, what string, t typeRef, hasDefault bool, lit value) {
+	switch lit := lit.(type) {
+	case variable:
+		vd, ok := v.vars[string(lit)]
+		if !ok {
+			v.errorf(pos, "%s uses undeclared variable $%s", what, lit)
+			return
+		}
+		if !varFits(vd, t, hasDefault) {
+			v.errorf(pos, "variable $%s of type %s cannot be used as %s of type %s", lit, vd.typ, what, t)
+		}
+		return
+	case []value:
+		if t.elem != nil {
+			for _, item := range lit {
+				v.value(pos, what, *t.elem, false, item)
+			}
+			return
+		}
+	}
+	if _, err := coerceLiteral(t, lit, nil); err != nil {
+		v.errorf(pos, "%s: %s", what, err)
+	}
+}
+
+// varFits reports whether a variable can be used where type loc is expected.
+func varFits(vd variableDef, loc typeRef, locHasDefault bool) bool {
+	vt := vd.typ
+	if loc.nonNull && !vt.nonNull {
+		if vd.def == nil && !locHasDefault {
+			return false
+		}
+		loc.nonNull = false
+	}
+	return typesFit(vt, loc)
+}
+
+func typesFit(vt, loc typeRef) bool {
+	if loc.nonNull {
+		if !vt.nonNull {
+			return false
+		}
+		vt.nonNull, loc.nonNull = false, false
+		return typesFit(vt, loc)
+	}
+	if vt.nonNull {
+		vt.nonNull = false
+		return typesFit(vt, loc)
+	}
+	if loc.elem != nil || vt.elem != nil {
+		return loc.elem != nil && vt.elem != nil && typesFit(*vt.elem, *loc.elem)
+	}
+	return vt.name == loc.name
+}
+
+// fieldsCanMerge checks that the fields selected under one response key are
+// the same field with the same arguments, so they merge into one result.
+func (v *validator) fieldsCanMerge(obj *Object, sel []selection) {
+	byKey := make(map[string][]*field)
+	var keys []string
+	var walk func(sel []selection)
+	walk = func(sel []selection) {
+		for _, s := range sel {
+			switch s := s.(type) {
+			case *field:
+				key := s.responseKey()
+				if _, ok := byKey[key]; !ok {
+					keys = append(keys, key)
+				}
+				byKey[key] = append(byKey[key], s)
+			case *fragmentSpread:
+				walk(v.doc.fragments[s.name].selection)
+			case *inlineFragment:
+				walk(s.selection)
+			}
+		}
+	}
+	walk(sel)
+	for _, key := range keys {
+		fields := byKey[key]
+		first := fields[0]
+		var children []selection
+		for _, f := range fields {
+			if f.name != first.name || printArgs(f.args) != printArgs(first.args) {
+				v.errorf(f.pos, "%q selects different fields or arguments; use aliases", key)
+				return
+			}
+			children = append(children, f.selection...)
+		}
+		if first.name == "__typename" {
+			continue
+		}
+		if child := v.schema.objects[obj.Fields[first.name].typ.named()]; child != nil {
+			v.fieldsCanMerge(child, children)
+		}
+	}
+}
+
+// printArgs formats arguments in name order, for comparison.
+func printArgs(args []argument) string {
+	out := make(map[string]string, len(args))
+	for _, a := range args {
+		out[a.name] = printLiteral(a.value)
+	}
+	return fmt.Sprint(out) // fmt sorts map keys
+}
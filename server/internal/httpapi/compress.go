@@ -0,0 +1,260 @@
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings NewCompressHandler can produce.
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// CompressOptions configures NewCompressHandler.
+type CompressOptions struct {
+	// Encodings are the encodings offered, most preferred first; empty
+	// disables compression.
+	Encodings []string
+	// MinBytes is the smallest response body compressed; smaller ones are not
+	// worth the CPU and the headers.
+	MinBytes int
+}
+
+// compressibleTypes are the media types compressed: text formats such as
+// JSON exports, pages and CSS, not images or archives.
+var compressibleTypes = map[string]bool{
+	"application/json":         true,
+	"application/x-ndjson":     true,
+	"application/geo+json":     true,
+	"application/problem+json": true,
+	"application/javascript":   true,
+	"application/xml":          true,
+	"image/svg+xml":            true,
+}
+
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	// Event streams are flushed event by event, which leaves little to
+	// compress.
+	return strings.HasPrefix(mt, "text/") && mt != "text/event-stream" || compressibleTypes[mt]
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	zstdWriters = sync.Pool{New: func() any {
+		// Fast and single threaded: responses are small and concurrent.
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// encoder is a pooled gzip or zstd writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func getEncoder(encoding string, w io.Writer) encoder {
+	var enc encoder
+	if encoding == EncodingZstd {
+		enc = zstdWriters.Get().(*zstd.Encoder)
+	} else {
+		enc = gzipWriters.Get().(*gzip.Writer)
+	}
+	enc.Reset(w)
+	return enc
+}
+
+func putEncoder(enc encoder) {
+	enc.Reset(io.Discard)
+	switch enc := enc.(type) {
+	case *zstd.Encoder:
+		zstdWriters.Put(enc)
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	}
+}
+
+// NewCompressHandler compresses the responses of next to clients accepting
+// one of opts.Encodings, for text bodies of at least opts.MinBytes. Range
+// requests and responses already encoded are left alone.
+func NewCompressHandler(next http.Handler, opts CompressOptions) http.Handler {
+	if len(opts.Encodings) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), opts.Encodings)
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minBytes: opts.MinBytes, status: http.StatusOK}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns the first of offered that acceptEncoding allows,
+// or "". Encodings with q=0 are refused; other weights are not ranked, the
+// server's preference decides.
+func negotiateEncoding(acceptEncoding string, offered []string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, enc := range offered {
+		if accepted[enc] || accepted["*"] {
+			return enc
+		}
+	}
+	return ""
+}
+
+// compressWriter holds back up to minBytes of the body to decide whether to
+// compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status      int
+	wroteHeader bool // by the handler
+	decided     bool // headers sent, compressing or not
+	buf         bytes.Buffer
+	enc         encoder // nil when not compressing
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.status, cw.wroteHeader = code, true
+	// Informational and bodiless responses go out now.
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+	cw.buf.Write(p)
+	if cw.buf.Len() >= cw.minBytes {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide sends the headers, compressing when the body is big enough and of a
+// compressible type, then the held back bytes.
+func (cw *compressWriter) decide(bigEnough bool) error {
+	cw.decided = true
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && cw.buf.Len() > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf.Bytes()))
+	}
+	if compressible(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+		if bigEnough && h.Get("Content-Encoding") == "" {
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+			cw.enc = getEncoder(cw.encoding, cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+// Flush sends what was written so far, so streamed exports reach the client
+// as they are produced.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if err := cw.decide(cw.buf.Len() >= cw.minBytes); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return
+		}
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack lets handlers take over the connection, uncompressed.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(cw.ResponseWriter).Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter { return cw.ResponseWriter }
+
+// close ends the response: a body smaller than minBytes goes out
+// uncompressed.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// The handler wrote nothing; the server sends its own 200.
+			return
+		}
+		if err := cw.decide(false); err != nil {
+			slog.Debug("http: write response failed", "error", err)
+		}
+		return
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Close(); err != nil {
+			slog.Debug("http: finish compressed response failed", "error", err)
+		}
+		putEncoder(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	offered := []string{EncodingZstd, EncodingGzip}
+	for _, tc := range []struct{ accept, want string }{
+		{"", ""},
+		{"gzip, deflate, br", "gzip"},
+		{"gzip, zstd", "zstd"},
+		{"zstd;q=0, gzip;q=0.5", "gzip"},
+		{"*", "zstd"},
+		{"br", ""},
+	} {
+		if got := negotiateEncoding(tc.accept, offered); got != tc.want {
+			t.Errorf("negotiateEncoding(%q) = %q; want %q", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestCompressHandler(t *testing.T) {
+	big := strings.Repeat(`{"temperature":21.5}`, 100)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/big":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, big)
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{}`)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, big)
+		case "/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, big)
+		}
+	})
+	h := NewCompressHandler(next, CompressOptions{Encodings: []string{EncodingGzip, EncodingZstd}, MinBytes: 1024})
+	do := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("/big", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("big JSON headers = %v; want gzip encoded, varying on Accept-Encoding", rec.Header())
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != big {
+		t.Errorf("gunzipped body = %d bytes; want the original %d", len(body), len(big))
+	}
+
+	rec = do("/big", "zstd")
+	if rec.Header().Get("Content-Encoding") != "zstd" {
+		t.Fatalf("Content-Encoding = %q; want zstd", rec.Header().Get("Content-Encoding"))
+	}
+	zd, err := zstd.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zd.Close()
+	if body, _ := io.ReadAll(zd); string(body) != big {
+		t.Errorf("zstd decoded body = %d bytes; want the original %d", len(body), len(big))
+	}
+
+	for _, tc := range []struct{ path, accept, why string }{
+		{"/big", "", "client does not accept an encoding"},
+		{"/small", "gzip", "body under the threshold"},
+		{"/image", "gzip", "not a text type"},
+		{"/stream", "gzip", "an event stream"},
+	} {
+		rec := do(tc.path, tc.accept)
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s (%s) Content-Encoding = %q; want none", tc.path, tc.why, rec.Header().Get("Content-Encoding"))
+		}
+		if tc.path == "/small" && rec.Body.String() != `{}` {
+			t.Errorf("small body = %q; want it unchanged", rec.Body)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package httpapi
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+)
+
+// RegisterDebug serves the runtime profiles of net/http/pprof under
+// /debug/pprof/ and the expvar variables, including memstats, at /debug/vars.
+// They expose the server's internals, so only register them when enabled and
+// guard them (the auth module requires the admin role for /debug/).
+//
+// CPU profiles, traces and named profiles with ?seconds= run as long as asked,
+// so they are Streaming and exempt from the handler timeout.
+func RegisterDebug(mux *http.ServeMux) {
+	mux.Handle("GET /debug/pprof/", Streaming(http.HandlerFunc(pprof.Index)))
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.Handle("GET /debug/pprof/profile", Streaming(http.HandlerFunc(pprof.Profile)))
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.Handle("GET /debug/pprof/trace", Streaming(http.HandlerFunc(pprof.Trace)))
+	mux.Handle("GET /debug/vars", expvar.Handler())
+}
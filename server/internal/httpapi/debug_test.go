@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterDebug(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterDebug(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &vars); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("/debug/vars = %d %v; want 200 JSON", rec.Code, err)
+	}
+	if _, ok := vars["memstats"]; !ok {
+		t.Error("/debug/vars has no memstats")
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Errorf("/debug/pprof/goroutine = %d %.80q; want the goroutine profile", rec.Code, rec.Body)
+	}
+
+	for path, want := range map[string]bool{"/debug/pprof/profile": true, "/debug/pprof/heap": true, "/debug/pprof/cmdline": false} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		h, _ := mux.Handler(r)
+		if got := isStreaming(h, r); got != want {
+			t.Errorf("%s streaming = %v; want %v", path, got, want)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package httpapi
 
 import (
+	"cloudpico-server/internal/logging"
 	"cloudpico-server/internal/utils"
 	"database/sql"
-	"log/slog"
 	"net/http"
 )
 
@@ -28,7 +28,7 @@ func NewHealthchecker(db *sql.DB, mqttStatus MQTTConnectedChecker) healthchecker
 func (h *healthcheckerImpl) handleHealthz(w http.ResponseWriter, r *http.Request) {
 	var ok int
 	if err := h.db.QueryRow(`SELECT 1`).Scan(&ok); err != nil {
-		slog.Error("failed to check database connectivity", "error", err)
+		logging.FromContext(r.Context()).Error("failed to check database connectivity", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to check database connectivity")
 		return
 	}
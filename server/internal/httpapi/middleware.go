@@ -1,11 +1,32 @@
 package httpapi
 
 import (
-	"log/slog"
+	"bufio"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/metrics"
+)
+
+var (
+	httpRequests = metrics.Default.NewCounterVec("cloudpico_http_requests_total",
+		"HTTP requests served, by status code.", "code")
+	httpDurations = metrics.Default.NewHistogramVec("cloudpico_http_request_duration_seconds",
+		"Duration of HTTP requests, by route pattern.", "route", metrics.DefaultDurationBuckets)
 )
 
+// unmatchedRoute labels requests no route matched, so scans of random paths
+// add no series.
+const unmatchedRoute = "unmatched"
+
+// router finds the pattern a request is routed by, as *http.ServeMux does.
+type router interface {
+	Handler(r *http.Request) (h http.Handler, pattern string)
+}
+
 type statusRecorder struct {
 	http.ResponseWriter
 	status int
@@ -16,18 +37,41 @@ func (sr *statusRecorder) WriteHeader(code int) {
 	sr.ResponseWriter.WriteHeader(code)
 }
 
-func requestLogger(next http.Handler) http.Handler {
+// Hijack lets WebSocket handlers take over the connection, which is logged
+// as switching protocols.
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(sr.ResponseWriter).Hijack()
+	if err == nil {
+		sr.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter { return sr.ResponseWriter }
+
+// requestLogger logs every request and records it at /metrics, timed under
+// the pattern routes matches it with. The pattern is looked up here because
+// middleware between this and the mux copies the request, which hides the
+// Pattern the mux sets.
+func requestLogger(next http.Handler, routes router) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		_, route := routes.Handler(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
 
 		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 		next.ServeHTTP(sr, r)
 
-		slog.Info("http request",
+		d := time.Since(start)
+		httpRequests.Inc(strconv.Itoa(sr.status))
+		httpDurations.ObserveDuration(route, d)
+		logging.FromContext(r.Context()).Info("http request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", sr.status,
-			"duration_ms", time.Since(start).Milliseconds(),
+			"duration_ms", d.Milliseconds(),
 		)
 	})
 }
@@ -0,0 +1,37 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestLogger_metrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /teapots/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	// Middleware copying the request, as auth does, must not hide the route.
+	h := requestLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r.WithContext(r.Context()))
+	}), mux)
+
+	teapots, notFound := httpRequests.Value("418"), httpRequests.Value("404")
+	route, unmatched := httpDurations.Count("GET /teapots/{id}"), httpDurations.Count(unmatchedRoute)
+	for _, path := range []string{"/teapots/1", "/teapots/2", "/wp-login.php"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := httpRequests.Value("418") - teapots; got != 2 {
+		t.Errorf("requests with code 418 = %d; want 2", got)
+	}
+	if got := httpRequests.Value("404") - notFound; got != 1 {
+		t.Errorf("requests with code 404 = %d; want 1", got)
+	}
+	if got := httpDurations.Count("GET /teapots/{id}") - route; got != 2 {
+		t.Errorf("durations of GET /teapots/{id} = %d; want 2", got)
+	}
+	if got := httpDurations.Count(unmatchedRoute) - unmatched; got != 1 {
+		t.Errorf("durations of unmatched requests = %d; want 1", got)
+	}
+}
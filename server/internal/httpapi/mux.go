@@ -5,11 +5,17 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+
+	"cloudpico-server/internal/metrics"
+	"cloudpico-shared/buildinfo"
 )
 
-func NewMux(db *sql.DB, staticDir string, mqttStatus MQTTConnectedChecker) *http.ServeMux {
+func NewMux(db *sql.DB, dbHealth DBHealthChecker, staticDir string, mqttStatus MQTTConnectedChecker, build buildinfo.Info) *http.ServeMux {
 	mux := http.NewServeMux()
 	registerHealthcheck(mux, db, mqttStatus)
+	registerReadiness(mux, dbHealth)
+	registerVersion(mux, build)
+	mux.Handle("GET /metrics", metrics.Default.Handler())
 	if staticDir != "" {
 		if _, err := os.Stat(staticDir); err == nil {
 			mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
@@ -0,0 +1,242 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+)
+
+// publicRoutes are the read-only endpoints served by the public API.
+var publicRoutes = []string{
+	"GET /api/v1/stations",
+	"GET /api/v1/stations.geojson",
+	"GET /api/v1/stations/{id}/latest",
+	"GET /api/v1/stations/{id}/latest/metrics",
+	"GET /api/v1/stations/{id}/readings",
+	"GET /api/v1/stations/{id}/aggregate",
+	"GET /api/v1/stations/{id}/stats",
+	"GET /api/v1/stations/{id}/climate",
+	"GET /api/v1/status",
+}
+
+// internalFields are JSON object keys removed from public responses: server
+// paths, shadow station links, software versions, retention settings and
+// ingest timing.
+var internalFields = map[string]bool{
+	"photoPath":       true,
+	"shadowOf":        true,
+	"gatewayVersion":  true,
+	"firmwareVersion": true,
+	"retentionDays":   true,
+	"receivedAt":      true,
+}
+
+// dataQuality is sent in X-Data-Quality: readings are published as the
+// station reported them, without quality control.
+const dataQuality = "raw"
+
+// PublicOptions configures the public API.
+type PublicOptions struct {
+	// RateLimit is the number of requests per minute allowed from one IP,
+	// with bursts of the same size.
+	RateLimit int
+	// License identifies the data license, e.g. "CC-BY-4.0".
+	License string
+	// Attribution is the credit line users of the data must show; empty omits
+	// the header.
+	Attribution string
+}
+
+// NewPublicHandler serves the publicRoutes of mux to anyone: CORS is open,
+// requests are rate limited per client IP, every response carries the data
+// license headers, and internal fields are stripped from JSON bodies.
+func NewPublicHandler(mux http.Handler, opts PublicOptions) http.Handler {
+	routes := http.NewServeMux()
+	for _, pattern := range publicRoutes {
+		routes.Handle(pattern, mux)
+	}
+	limiter := newRateLimiter(opts.RateLimit, opts.RateLimit, time.Minute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", "*")
+		h.Set("Access-Control-Expose-Headers", "X-Data-License, X-Data-Attribution, X-Data-Quality, Retry-After, Deprecation, X-Units")
+		h.Set("X-Data-License", opts.License)
+		if opts.Attribution != "" {
+			h.Set("X-Data-Attribution", opts.Attribution)
+		}
+		h.Set("X-Data-Quality", dataQuality)
+
+		if ok, retryAfter := limiter.allow(clientIP(r), time.Now()); !ok {
+			writeRateLimited(w, retryAfter)
+			return
+		}
+		if r.Method == http.MethodOptions {
+			h.Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "*")
+			h.Set("Access-Control-Max-Age", "86400")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		sw := &strippingWriter{w: w, status: http.StatusOK}
+		routes.ServeHTTP(sw, r)
+		if err := sw.finish(); err != nil {
+			logging.FromContext(r.Context()).Error("public api: strip internal fields failed", "path", r.URL.Path, "error", err)
+		}
+	})
+}
+
+// strippingWriter removes internalFields from JSON and NDJSON responses as
+// they are written. NDJSON, e.g. a readings export, is rewritten one line at a
+// time and streams through; a JSON document, at most one page of results, is
+// held until the handler returns. Other bodies pass through unchanged.
+type strippingWriter struct {
+	w       http.ResponseWriter
+	status  int
+	started bool
+	json    bool // hold and rewrite the whole body
+	ndjson  bool // rewrite each complete line
+	buf     bytes.Buffer
+}
+
+func (s *strippingWriter) Header() http.Header { return s.w.Header() }
+
+func (s *strippingWriter) WriteHeader(code int) {
+	if s.started {
+		return
+	}
+	s.started, s.status = true, code
+	contentType := s.w.Header().Get("Content-Type")
+	s.json = strings.HasPrefix(contentType, "application/json")
+	s.ndjson = strings.HasPrefix(contentType, "application/x-ndjson")
+	if s.json || s.ndjson {
+		s.w.Header().Del("Content-Length")
+	}
+	if !s.json {
+		s.w.WriteHeader(code)
+	}
+}
+
+func (s *strippingWriter) Write(p []byte) (int, error) {
+	s.WriteHeader(http.StatusOK)
+	switch {
+	case s.json:
+		return s.buf.Write(p)
+	case s.ndjson:
+		s.buf.Write(p)
+		if err := s.writeLines(false); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	default:
+		return s.w.Write(p)
+	}
+}
+
+// writeLines strips and sends the complete lines held, and with all the
+// incomplete last one too.
+func (s *strippingWriter) writeLines(all bool) error {
+	end := bytes.LastIndexByte(s.buf.Bytes(), '\n') + 1
+	if all {
+		end = s.buf.Len()
+	}
+	if end == 0 {
+		return nil
+	}
+	stripped, err := stripInternalFields(s.buf.Next(end))
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(stripped)
+	return err
+}
+
+// Flush sends the complete NDJSON lines held so far; a JSON document is only
+// sent whole.
+func (s *strippingWriter) Flush() {
+	if s.json {
+		return
+	}
+	_ = http.NewResponseController(s.w).Flush()
+}
+
+func (s *strippingWriter) Unwrap() http.ResponseWriter { return s.w }
+
+// finish sends what the handler left held: the JSON document, or a last
+// NDJSON line without a newline. A JSON document that cannot be rewritten is
+// answered 500 rather than sent with its internal fields.
+func (s *strippingWriter) finish() error {
+	switch {
+	case s.json:
+		body, err := stripInternalFields(s.buf.Bytes())
+		if err != nil {
+			http.Error(s.w, "internal error", http.StatusInternalServerError)
+			return err
+		}
+		s.w.WriteHeader(s.status)
+		_, _ = s.w.Write(body)
+	case s.ndjson:
+		return s.writeLines(true)
+	}
+	return nil
+}
+
+// stripInternalFields removes internalFields from every object in body, a
+// JSON document or a stream of them such as NDJSON.
+func stripInternalFields(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(strip(doc)); err != nil {
+			return nil, err
+		}
+	}
+	if out.Len() == 0 {
+		return body, nil
+	}
+	return out.Bytes(), nil
+}
+
+func strip(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if internalFields[k] {
+				delete(v, k)
+				continue
+			}
+			v[k] = strip(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = strip(child)
+		}
+	}
+	return v
+}
+
+// clientIP returns the host part of r.RemoteAddr. Forwarding headers are not
+// trusted since they are set by the client.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPublicHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/stations", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[{"id":"1","name":"Garden","photoPath":"stations/1.png","firmwareVersion":"1.2.0","latest":{"value":21.5,"receivedAt":"2025-01-01T00:00:00Z"}}],"total":1}`))
+	})
+	mux.HandleFunc("POST /api/v1/stations/{id}/favorite", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private endpoint reached through the public API")
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private endpoint reached through the public API")
+	})
+	h := NewPublicHandler(mux, PublicOptions{RateLimit: 3, License: "CC-BY-4.0", Attribution: "Garden station"})
+
+	do := func(method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodGet, "/api/v1/stations")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET stations status = %d; want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, field := range []string{"photoPath", "firmwareVersion", "receivedAt"} {
+		if strings.Contains(body, field) {
+			t.Errorf("body contains internal field %q: %s", field, body)
+		}
+	}
+	if !strings.Contains(body, `"value":21.5`) || !strings.Contains(body, `"name":"Garden"`) {
+		t.Errorf("body lost public fields: %s", body)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin": "*",
+		"X-Data-License":              "CC-BY-4.0",
+		"X-Data-Attribution":          "Garden station",
+		"X-Data-Quality":              "raw",
+		"Content-Type":                "application/json",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q; want %q", header, got, want)
+		}
+	}
+
+	if rec := do(http.MethodPost, "/api/v1/stations/1/favorite"); rec.Code != http.StatusNotFound {
+		t.Errorf("POST favorite status = %d; want 404", rec.Code)
+	}
+	// Third request: the burst of 3 is now used up.
+	if rec := do(http.MethodOptions, "/api/v1/stations"); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("OPTIONS status = %d, allow methods %q; want 204 with CORS headers", rec.Code, rec.Header().Get("Access-Control-Allow-Methods"))
+	}
+	rec = do(http.MethodGet, "/metrics")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "20" {
+		t.Errorf("4th request status = %d, Retry-After %q; want 429, 20", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec.Header().Get("X-Data-License") == "" {
+		t.Error("rate limited response lacks the license header")
+	}
+}
+
+func TestPublicHandler_streamsNDJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/stations/{id}/readings", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("{\"value\":1.5,\"receivedAt\":\"2025-01-01T00:00:01Z\"}\n{\"val"))
+		http.NewResponseController(w).Flush()
+		// The first line reached the client, stripped, while the handler still runs.
+		if got := rec.Body.String(); got != "{\"value\":1.5}\n" || !rec.Flushed {
+			t.Errorf("body while streaming = %q, flushed %v; want the first line", got, rec.Flushed)
+		}
+		_, _ = w.Write([]byte("ue\":2,\"receivedAt\":\"2025-01-01T00:00:02Z\"}"))
+	})
+	h := NewPublicHandler(mux, PublicOptions{RateLimit: 10})
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/readings?format=ndjson", nil))
+
+	if want := "{\"value\":1.5}\n{\"value\":2}\n"; rec.Body.String() != want {
+		t.Errorf("body = %q; want %q", rec.Body, want)
+	}
+}
+
+func TestStripInternalFields_NDJSON(t *testing.T) {
+	body := "{\"stationId\":\"1\",\"value\":1.5,\"receivedAt\":\"2025-01-01T00:00:01Z\"}\n" +
+		"{\"stationId\":\"1\",\"value\":2}\n"
+	got, err := stripInternalFields([]byte(body))
+	if err != nil {
+		t.Fatalf("stripInternalFields: %v", err)
+	}
+	want := "{\"stationId\":\"1\",\"value\":1.5}\n{\"stationId\":\"1\",\"value\":2}\n"
+	if string(got) != want {
+		t.Errorf("stripInternalFields() = %q; want %q, one object per line", got, want)
+	}
+}
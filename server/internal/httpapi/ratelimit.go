@@ -0,0 +1,127 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/utils"
+)
+
+// RateLimitOptions configures NewRateLimitHandler.
+type RateLimitOptions struct {
+	// Rate is the number of API requests per minute allowed from one client;
+	// 0 disables the limit.
+	Rate int
+	// Burst is the number of requests a client may make at once after being
+	// idle.
+	Burst int
+}
+
+// NewRateLimitHandler limits the /api/ and /graphql requests each client
+// sends to next, answering the excess with 429 and Retry-After, so a
+// misbehaving poller cannot starve the database for everyone else. Clients
+// are told apart by their API key when they send one (see clientKey), else by
+// IP. Pages and static files are not limited.
+func NewRateLimitHandler(next http.Handler, opts RateLimitOptions) http.Handler {
+	if opts.Rate <= 0 {
+		return next
+	}
+	limiter := newRateLimiter(opts.Rate, opts.Burst, time.Minute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/graphql" {
+			if ok, retryAfter := limiter.allow(clientKey(r), time.Now()); !ok {
+				writeRateLimited(w, retryAfter)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeRateLimited answers a request over its client's limit.
+func writeRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	utils.WriteError(w, http.StatusTooManyRequests, "rate limit exceeded")
+}
+
+// clientKey identifies the client of r: a hash of the bearer token, API key
+// or webhook token it sends, else its IP. Keys are not verified here, so a
+// client rotating made-up keys is not held back; the limit guards against
+// runaway pollers, not attackers.
+func clientKey(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.Header.Get("X-API-Key")
+	}
+	if token == "" {
+		token = r.Header.Get("X-Webhook-Token")
+	}
+	if token = strings.TrimSpace(token); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return "ip:" + clientIP(r)
+}
+
+// rateLimiter is a token bucket per client: each client may burst up to
+// burst requests and regains rate tokens per period.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	period time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate, burst int, period time.Duration) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(rate),
+		burst:   float64(max(burst, 1)),
+		period:  period,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token for client at now, or reports how long until one is
+// available.
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rate := l.rate / l.period.Seconds() // tokens per second
+	// Buckets idle long enough to refill are full again; drop them so the
+	// map only holds recent clients.
+	refill := time.Duration(l.burst / rate * float64(time.Second))
+	if now.Sub(l.lastSweep) >= l.period {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) >= refill {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 2, time.Minute)
+	now := time.Unix(1700000000, 0)
+	for i := range 2 {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d denied; want allowed", i)
+		}
+	}
+	if ok, retry := l.allow("a", now); ok || retry != 30*time.Second {
+		t.Errorf("3rd request = %v, %v; want denied, retry in 30s", ok, retry)
+	}
+	if ok, _ := l.allow("b", now); !ok {
+		t.Error("other client denied; want its own bucket")
+	}
+	if ok, _ := l.allow("a", now.Add(30*time.Second)); !ok {
+		t.Error("request after refill denied; want allowed")
+	}
+	l.allow("c", now.Add(3*time.Minute))
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("idle bucket not swept")
+	}
+}
+
+func TestRateLimiter_Burst(t *testing.T) {
+	l := newRateLimiter(60, 3, time.Minute)
+	now := time.Unix(1700000000, 0)
+	for i := range 3 {
+		if ok, _ := l.allow("a", now); !ok {
+			t.Fatalf("request %d denied; want allowed within the burst", i)
+		}
+	}
+	if ok, retry := l.allow("a", now); ok || retry != time.Second {
+		t.Errorf("4th request = %v, %v; want denied, retry in 1s", ok, retry)
+	}
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := NewRateLimitHandler(next, RateLimitOptions{Rate: 60, Burst: 1})
+	do := func(path, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("/api/v1/stations", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("1st request = %d; want 200", rec.Code)
+	}
+	rec := do("/api/v1/stations", "", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" ||
+		!strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") ||
+		!strings.Contains(rec.Body.String(), `"message":"rate limit exceeded"`) {
+		t.Errorf("2nd request = %d, Retry-After %q, %s; want a 429 JSON error retrying in 1s",
+			rec.Code, rec.Header().Get("Retry-After"), rec.Body)
+	}
+	for _, tc := range []struct{ header, value string }{
+		{"Authorization", "Bearer poller-1"},
+		{"X-API-Key", "poller-2"},
+	} {
+		if rec := do("/api/v1/stations", tc.header, tc.value); rec.Code != http.StatusOK {
+			t.Errorf("request with %s from the same IP = %d; want 200, the key's own bucket", tc.header, rec.Code)
+		}
+	}
+	if rec := do("/graphql", "", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("GraphQL request = %d; want 429, limited with the API", rec.Code)
+	}
+	if rec := do("/static/css/main.css", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("static file = %d; want 200, not limited", rec.Code)
+	}
+}
@@ -0,0 +1,27 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"cloudpico-server/internal/db"
+	"cloudpico-server/internal/utils"
+)
+
+// DBHealthChecker is implemented by *db.Monitor for readiness.
+type DBHealthChecker interface {
+	Health() db.Health
+}
+
+// registerReadiness serves GET /readyz: 200 while the database takes writes
+// and 503 otherwise, with the monitor's latest outcome either way. Unlike
+// /healthz it does not query the database itself, so polling it is cheap.
+func registerReadiness(mux *http.ServeMux, dbHealth DBHealthChecker) {
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		h := dbHealth.Health()
+		status, code := "ready", http.StatusOK
+		if !h.OK() {
+			status, code = "not ready", http.StatusServiceUnavailable
+		}
+		utils.WriteJSON(w, code, map[string]any{"status": status, "database": h})
+	})
+}
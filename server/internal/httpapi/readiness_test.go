@@ -0,0 +1,41 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/db"
+)
+
+type fixedHealth db.Health
+
+func (f fixedHealth) Health() db.Health { return db.Health(f) }
+
+func TestReadiness(t *testing.T) {
+	since := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		name     string
+		health   db.Health
+		wantCode int
+		wantBody string
+	}{
+		{"ok", db.Health{State: db.StateOK, Since: since, CheckedAt: since}, http.StatusOK,
+			`{"database":{"state":"ok","since":"2025-03-01T12:00:00Z","checkedAt":"2025-03-01T12:00:00Z"},"status":"ready"}`},
+		{"locked", db.Health{State: db.StateLocked, Error: "database is locked", Since: since, CheckedAt: since.Add(time.Minute)}, http.StatusServiceUnavailable,
+			`{"database":{"state":"locked","error":"database is locked","since":"2025-03-01T12:00:00Z","checkedAt":"2025-03-01T12:01:00Z"},"status":"not ready"}`},
+		{"not probed yet", db.Health{State: db.StateUnknown}, http.StatusServiceUnavailable, `"state":"unknown"`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			registerReadiness(mux, fixedHealth(tt.health))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantCode || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET /readyz = %d %s; want %d %s", rec.Code, rec.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"log/slog"
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+)
+
+// RequestIDHeader carries the ID correlating a request's log lines. An ID
+// sent by the client or a proxy is kept; otherwise one is generated.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds an incoming request ID.
+const maxRequestIDLen = 128
+
+// requestID tags the request with an ID, echoed in the response, and stores
+// a logger adding it as request_id in the request context for handlers to
+// log through (see logging.FromContext).
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		l := slog.Default().With("request_id", id)
+		next.ServeHTTP(w, r.WithContext(logging.NewContext(r.Context(), l)))
+	})
+}
+
+// validRequestID reports whether id is short and printable ASCII without
+// spaces, so it cannot forge log fields or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	h := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handled")
+	}))
+	for _, tt := range []struct {
+		name, in string
+		keep     bool
+	}{
+		{name: "missing"},
+		{name: "incoming", in: "req-42", keep: true},
+		{name: "spaces", in: "a b"},
+		{name: "control characters", in: "a\x1bb"},
+		{name: "too long", in: strings.Repeat("x", maxRequestIDLen+1)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.in != "" {
+				req.Header.Set(RequestIDHeader, tt.in)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tt.keep && id != tt.in {
+				t.Errorf("%s = %q; want the incoming %q", RequestIDHeader, id, tt.in)
+			}
+			if !tt.keep && (id == tt.in || !validRequestID(id)) {
+				t.Errorf("%s = %q; want a generated ID", RequestIDHeader, id)
+			}
+			if want := "request_id=" + id; !strings.Contains(buf.String(), want) {
+				t.Errorf("log = %q; want it to contain %s", buf.String(), want)
+			}
+		})
+	}
+}
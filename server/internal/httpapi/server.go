@@ -5,9 +5,40 @@ import (
 	"net/http"
 )
 
-func NewServer(config config.Config, mux *http.ServeMux) *http.Server {
+// NewServer serves mux, the handler wrapping routes, at config.HTTPAddr, with
+// API requests rate limited per client (see NewRateLimitHandler) and
+// responses compressed (see NewCompressHandler). Requests are tagged with an
+// ID (see RequestIDHeader), timed per route of routes and, unless Streaming,
+// cut off after config.HTTPHandlerTimeout.
+func NewServer(config config.Config, routes *http.ServeMux, mux http.Handler) *http.Server {
 	return &http.Server{
-		Addr:    config.HTTPAddr,
-		Handler: requestLogger(mux),
+		Addr:              config.HTTPAddr,
+		ReadHeaderTimeout: config.HTTPReadTimeout,
+		Handler: requestID(requestLogger(timeout(NewRateLimitHandler(NewCompressHandler(mux, compressOptions(config)), RateLimitOptions{
+			Rate:  config.APIRateLimit,
+			Burst: config.APIRateBurst,
+		}), routes, timeoutOptions(config)), routes)),
 	}
 }
+
+// NewPublicServer serves the public read-only API (see NewPublicHandler) of
+// mux, the handler wrapping routes, at config.PublicAPIAddr.
+func NewPublicServer(config config.Config, routes *http.ServeMux, mux http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              config.PublicAPIAddr,
+		ReadHeaderTimeout: config.HTTPReadTimeout,
+		Handler: requestID(requestLogger(timeout(NewCompressHandler(NewPublicHandler(mux, PublicOptions{
+			RateLimit:   config.PublicAPIRateLimit,
+			License:     config.PublicAPILicense,
+			Attribution: config.PublicAPIAttribution,
+		}), compressOptions(config)), routes, timeoutOptions(config)), routes)),
+	}
+}
+
+func compressOptions(config config.Config) CompressOptions {
+	return CompressOptions{Encodings: config.Compression, MinBytes: config.CompressionMinBytes}
+}
+
+func timeoutOptions(config config.Config) TimeoutOptions {
+	return TimeoutOptions{Read: config.HTTPReadTimeout, Handler: config.HTTPHandlerTimeout}
+}
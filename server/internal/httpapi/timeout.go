@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+// TimeoutOptions configures the per-request timeouts of NewServer and
+// NewPublicServer. A zero duration disables that timeout.
+type TimeoutOptions struct {
+	// Read bounds reading the request body, from when the handler starts.
+	Read time.Duration
+	// Handler bounds the handler. Its context is cancelled at the deadline,
+	// which aborts the repository queries it runs.
+	Handler time.Duration
+}
+
+// Streaming marks h as serving a long-lived response, such as a WebSocket or
+// server-sent events, which the request timeouts leave alone.
+func Streaming(h http.Handler) http.Handler {
+	return streamingHandler{Handler: h}
+}
+
+// StreamingIf marks h as Streaming for the requests match reports true for,
+// such as an export served from the same route as a bounded response.
+func StreamingIf(h http.Handler, match func(*http.Request) bool) http.Handler {
+	return streamingHandler{Handler: h, match: match}
+}
+
+type streamingHandler struct {
+	http.Handler
+	match func(*http.Request) bool // nil matches every request
+}
+
+// timeout applies opts to every request routes does not route to a Streaming
+// handler. A handler that has not started its response by the deadline is
+// answered 504 with a JSON error once it returns; whatever it writes after
+// the deadline is dropped. The handler runs on the request's goroutine, so a
+// timed out request holds nothing once its queries see the cancellation.
+func timeout(next http.Handler, routes router, opts TimeoutOptions) http.Handler {
+	if opts.Read <= 0 && opts.Handler <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, _ := routes.Handler(r); isStreaming(h, r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		if opts.Handler > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.Handler)
+			defer cancel()
+		}
+		r = r.WithContext(ctx)
+		if opts.Read > 0 && r.Body != nil && r.Body != http.NoBody {
+			// The server's ReadTimeout would also cut off streams, which
+			// keep reading the connection in the background.
+			rc := http.NewResponseController(w)
+			if rc.SetReadDeadline(time.Now().Add(opts.Read)) == nil {
+				r.Body = &deadlineBody{ReadCloser: r.Body, rc: rc}
+			}
+		}
+		if opts.Handler <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header().Clone()
+		tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+		next.ServeHTTP(tw, r)
+		if tw.timedOut || !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// Drop the headers the handler set, e.g. Content-Encoding.
+			clear(w.Header())
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			logging.FromContext(ctx).Warn("request timed out", "method", r.Method, "path", r.URL.Path, "timeout", opts.Handler)
+			utils.WriteError(w, http.StatusGatewayTimeout, "request timed out")
+		}
+	})
+}
+
+func isStreaming(h http.Handler, r *http.Request) bool {
+	s, ok := h.(streamingHandler)
+	return ok && (s.match == nil || s.match(r))
+}
+
+// timeoutWriter passes a response through unless it starts after ctx's
+// deadline.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if tw.wroteHeader || tw.timedOut {
+		return
+	}
+	if errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.WriteHeader(http.StatusOK)
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter { return tw.ResponseWriter }
+
+// deadlineBody lifts the connection's read deadline once the body has been
+// read, so it does not cut off the response or the next request.
+type deadlineBody struct {
+	io.ReadCloser
+	rc   *http.ResponseController
+	done bool
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.lift()
+	}
+	return n, err
+}
+
+func (b *deadlineBody) Close() error {
+	b.lift()
+	return b.ReadCloser.Close()
+}
+
+func (b *deadlineBody) lift() {
+	if !b.done {
+		b.done = true
+		b.rc.SetReadDeadline(time.Time{})
+	}
+}
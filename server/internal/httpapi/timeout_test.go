@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/utils"
+)
+
+func TestTimeout(t *testing.T) {
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		<-r.Context().Done()
+		w.Write([]byte("late"))
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /slow", slow)
+	mux.HandleFunc("GET /fast", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.Handle("GET /stream", Streaming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			t.Error("streaming handler cancelled")
+		case <-time.After(50 * time.Millisecond):
+		}
+		w.Write([]byte("events"))
+	})))
+	mux.Handle("GET /export", StreamingIf(http.HandlerFunc(slow), func(r *http.Request) bool {
+		return r.URL.Query().Get("format") == "ndjson"
+	}))
+	h := timeout(mux, mux, TimeoutOptions{Handler: 10 * time.Millisecond})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	var body utils.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); rec.Code != http.StatusGatewayTimeout || err != nil {
+		t.Errorf("slow: %d %q; want 504 with a JSON error", rec.Code, rec.Body)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Errorf("slow: Content-Encoding = %q; want the handler's headers dropped", enc)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/export", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("export: %d; want 504 for a request the route does not stream", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/export?format=ndjson", nil)
+	if h, _ := mux.Handler(req); !isStreaming(h, req) {
+		t.Error("export?format=ndjson not streaming")
+	}
+
+	for path, want := range map[string]string{"/fast": "ok", "/stream": "events"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("%s: %d %q; want 200 %q", path, rec.Code, rec.Body, want)
+		}
+	}
+}
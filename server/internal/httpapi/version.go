@@ -0,0 +1,18 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"cloudpico-server/internal/utils"
+	"cloudpico-shared/buildinfo"
+)
+
+// registerVersion serves the build metadata at GET /version, where deploy
+// checks look for it, and at GET /api/v1/version with the rest of the API.
+func registerVersion(mux *http.ServeMux, build buildinfo.Info) {
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteJSON(w, http.StatusOK, build)
+	}
+	mux.HandleFunc("GET /version", serve)
+	mux.HandleFunc("GET /api/v1/version", serve)
+}
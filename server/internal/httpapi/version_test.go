@@ -0,0 +1,31 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloudpico-shared/buildinfo"
+)
+
+func TestVersion(t *testing.T) {
+	build := buildinfo.New("1.2.3", "0123456789abcdef", "2025-03-01T12:00:00Z")
+	mux := http.NewServeMux()
+	registerVersion(mux, build)
+
+	for _, path := range []string{"/version", "/api/v1/version"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d", path, rec.Code)
+		}
+		var got buildinfo.Info
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got != build {
+			t.Errorf("%s: version = %+v, want %+v", path, got, build)
+		}
+	}
+}
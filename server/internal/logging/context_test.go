@@ -0,0 +1,21 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	if got := FromContext(context.Background()); got != slog.Default() {
+		t.Errorf("FromContext() without a logger = %v; want slog.Default()", got)
+	}
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil)).With("request_id", "abc")
+	FromContext(NewContext(context.Background(), l)).Info("hello")
+	if !strings.Contains(buf.String(), "msg=hello request_id=abc") {
+		t.Errorf("log = %q; want the line tagged with the request ID", buf.String())
+	}
+}
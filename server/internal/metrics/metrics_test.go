@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("query_seconds", "Query duration.", "query", []float64{0.5, 0.1})
+	h.Observe("b", 0.05)
+	h.Observe("b", 0.1)
+	h.ObserveDuration("b", 2*time.Second)
+	h.Observe("a", 0.3)
+
+	if got := h.Count("b"); got != 3 {
+		t.Errorf("Count(b) = %d; want 3", got)
+	}
+	if got := h.Count("missing"); got != 0 {
+		t.Errorf("Count(missing) = %d; want 0", got)
+	}
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() = %v; want nil", err)
+	}
+	want := `# HELP query_seconds Query duration.
+# TYPE query_seconds histogram
+query_seconds_bucket{query="a",le="0.1"} 0
+query_seconds_bucket{query="a",le="0.5"} 1
+query_seconds_bucket{query="a",le="+Inf"} 1
+query_seconds_sum{query="a"} 0.3
+query_seconds_count{query="a"} 1
+query_seconds_bucket{query="b",le="0.1"} 2
+query_seconds_bucket{query="b",le="0.5"} 2
+query_seconds_bucket{query="b",le="+Inf"} 3
+query_seconds_sum{query="b"} 2.15
+query_seconds_count{query="b"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewHistogramVec("empty_seconds", "Nothing observed.", "query", DefaultDurationBuckets)
+	rec := httptest.NewRecorder()
+
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q; want text/plain", ct)
+	}
+	if body := rec.Body.String(); body != "# HELP empty_seconds Nothing observed.\n# TYPE empty_seconds histogram\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("events_total", "Events seen.")
+	c.Inc()
+	c.Inc()
+	if got := c.Value(); got != 2 {
+		t.Errorf("Value() = %d; want 2", got)
+	}
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() = %v; want nil", err)
+	}
+	if want := "# HELP events_total Events seen.\n# TYPE events_total counter\nevents_total 2\n"; b.String() != want {
+		t.Errorf("WriteText() = %q; want %q", b.String(), want)
+	}
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("shared_total", "Shared results.", "query")
+	c.Inc("b")
+	c.Inc("a")
+	c.Inc("b")
+	if got := c.Value("b"); got != 2 {
+		t.Errorf("Value(b) = %d; want 2", got)
+	}
+	if got := c.Value("missing"); got != 0 {
+		t.Errorf("Value(missing) = %d; want 0", got)
+	}
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() = %v; want nil", err)
+	}
+	want := "# HELP shared_total Shared results.\n# TYPE shared_total counter\nshared_total{query=\"a\"} 1\nshared_total{query=\"b\"} 2\n"
+	if b.String() != want {
+		t.Errorf("WriteText() = %q; want %q", b.String(), want)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("queue_depth", "Items queued.")
+	g.Set(1.5)
+	if got := g.Value(); got != 1.5 {
+		t.Errorf("Value() = %v; want 1.5", got)
+	}
+	g.Set(0)
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() = %v; want nil", err)
+	}
+	if want := "# HELP queue_depth Items queued.\n# TYPE queue_depth gauge\nqueue_depth 0\n"; b.String() != want {
+		t.Errorf("WriteText() = %q; want %q", b.String(), want)
+	}
+}
+
+func TestFuncMetrics(t *testing.T) {
+	r := NewRegistry()
+	open, waits := 2, 0
+	r.NewGaugeFunc("open_conns", "Open connections.", func() float64 { return float64(open) })
+	r.NewCounterFunc("waits_total", "Waits for a connection.", func() float64 { return float64(waits) })
+	open, waits = 1, 7
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() = %v; want nil", err)
+	}
+	want := "# HELP open_conns Open connections.\n# TYPE open_conns gauge\nopen_conns 1\n" +
+		"# HELP waits_total Waits for a connection.\n# TYPE waits_total counter\nwaits_total 7\n"
+	if b.String() != want {
+		t.Errorf("WriteText() = %q; want %q", b.String(), want)
+	}
+}
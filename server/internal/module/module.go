@@ -0,0 +1,83 @@
+// Package module defines the interface server features implement and the
+// helpers app.Run uses to compose them.
+package module
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/mqtt"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-tools/migrate"
+)
+
+// Deps are the shared services handed to every module. Module-specific
+// settings are passed to the module's constructor instead.
+type Deps struct {
+	DB         *sql.DB
+	Subscriber *mqtt.Subscriber
+	Bus        *events.Bus
+	Scheduler  *scheduler.Scheduler
+}
+
+// Module is one server feature.
+type Module interface {
+	// Name identifies the module in logs, errors and migration versions.
+	Name() string
+	// Migrations returns the module's own migrations (0001_name.sql, ...) at
+	// the root of the FS, or nil. They run after the core schema.
+	Migrations() fs.FS
+	// RegisterRoutes adds the module's handlers to mux and keeps what it needs
+	// from deps.
+	RegisterRoutes(mux *http.ServeMux, deps Deps) error
+	// StartWorkers subscribes to topics and registers or starts background work.
+	// It is called after every module registered its routes and before the
+	// scheduler starts and MQTT connects; goroutines must stop when ctx is done.
+	StartWorkers(ctx context.Context) error
+}
+
+// Migrate applies each module's migrations.
+func Migrate(db *sql.DB, modules []Module) error {
+	for _, m := range modules {
+		fsys := m.Migrations()
+		if fsys == nil {
+			continue
+		}
+		if err := migrate.RunFS(db, fsys, m.Name()); err != nil {
+			return fmt.Errorf("module %s: migrate: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// RegisterRoutes registers every module's routes, in order. Module names
+// must be unique.
+func RegisterRoutes(mux *http.ServeMux, deps Deps, modules []Module) error {
+	seen := make(map[string]bool, len(modules))
+	for _, m := range modules {
+		if seen[m.Name()] {
+			return fmt.Errorf("module %s: registered twice", m.Name())
+		}
+		seen[m.Name()] = true
+		if err := m.RegisterRoutes(mux, deps); err != nil {
+			return fmt.Errorf("module %s: register routes: %w", m.Name(), err)
+		}
+		slog.Info("module registered", "module", m.Name())
+	}
+	return nil
+}
+
+// StartWorkers starts every module's workers, in order.
+func StartWorkers(ctx context.Context, modules []Module) error {
+	for _, m := range modules {
+		if err := m.StartWorkers(ctx); err != nil {
+			return fmt.Errorf("module %s: start workers: %w", m.Name(), err)
+		}
+	}
+	return nil
+}
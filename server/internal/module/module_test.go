@@ -0,0 +1,109 @@
+package module
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type fakeModule struct {
+	name       string
+	migrations fs.FS
+	routeErr   error
+	workerErr  error
+	log        *[]string
+}
+
+func (m *fakeModule) Name() string      { return m.name }
+func (m *fakeModule) Migrations() fs.FS { return m.migrations }
+
+func (m *fakeModule) RegisterRoutes(mux *http.ServeMux, deps Deps) error {
+	*m.log = append(*m.log, "routes "+m.name)
+	mux.HandleFunc("GET /"+m.name, func(w http.ResponseWriter, r *http.Request) {})
+	return m.routeErr
+}
+
+func (m *fakeModule) StartWorkers(ctx context.Context) error {
+	*m.log = append(*m.log, "workers "+m.name)
+	return m.workerErr
+}
+
+func TestRegisterRoutesAndStartWorkers(t *testing.T) {
+	t.Run("runs modules in order", func(t *testing.T) {
+		var log []string
+		mods := []Module{&fakeModule{name: "a", log: &log}, &fakeModule{name: "b", log: &log}}
+		mux := http.NewServeMux()
+		if err := RegisterRoutes(mux, Deps{}, mods); err != nil {
+			t.Fatalf("RegisterRoutes() = %v; want nil", err)
+		}
+		if err := StartWorkers(context.Background(), mods); err != nil {
+			t.Fatalf("StartWorkers() = %v; want nil", err)
+		}
+		if got := strings.Join(log, ","); got != "routes a,routes b,workers a,workers b" {
+			t.Errorf("calls = %q; want routes then workers, in order", got)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/b", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET /b status = %d; want 200", rec.Code)
+		}
+	})
+
+	t.Run("rejects duplicate names", func(t *testing.T) {
+		var log []string
+		mods := []Module{&fakeModule{name: "a", log: &log}, &fakeModule{name: "a", log: &log}}
+		if err := RegisterRoutes(http.NewServeMux(), Deps{}, mods); err == nil || !strings.Contains(err.Error(), "registered twice") {
+			t.Errorf("RegisterRoutes() = %v; want duplicate error", err)
+		}
+	})
+
+	t.Run("wraps errors with the module name", func(t *testing.T) {
+		var log []string
+		boom := errors.New("boom")
+		err := StartWorkers(context.Background(), []Module{&fakeModule{name: "alerts", workerErr: boom, log: &log}})
+		if !errors.Is(err, boom) || !strings.Contains(err.Error(), "module alerts") {
+			t.Errorf("StartWorkers() = %v; want wrapped boom naming the module", err)
+		}
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	db.SetMaxOpenConns(1)
+
+	migrations := fstest.MapFS{
+		"0001_alerts.sql": {Data: []byte("CREATE TABLE alerts (id INTEGER PRIMARY KEY);")},
+	}
+	mods := []Module{&fakeModule{name: "alerts", migrations: migrations}, &fakeModule{name: "plain"}}
+	for range 2 { // the second run must be a no-op
+		if err := Migrate(db, mods); err != nil {
+			t.Fatalf("Migrate() = %v; want nil", err)
+		}
+	}
+	var version string
+	if err := db.QueryRow("SELECT version FROM schema_migrations").Scan(&version); err != nil {
+		t.Fatalf("read schema_migrations: %v", err)
+	}
+	if version != "alerts/0001" {
+		t.Errorf("version = %q; want alerts/0001", version)
+	}
+	if _, err := db.Exec("INSERT INTO alerts (id) VALUES (1)"); err != nil {
+		t.Errorf("alerts table missing: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package admin serves operator pages for server internals.
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-server/internal/utils"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var jobsTmpl = template.Must(template.New("jobs.html").Funcs(template.FuncMap{
+	"timeOrDash": func(t *time.Time) string {
+		if t == nil {
+			return "–"
+		}
+		return t.UTC().Format("2006-01-02 15:04:05") + " UTC"
+	},
+}).ParseFS(templatesFS, "templates/jobs.html"))
+
+// JobRunner is implemented by *scheduler.Scheduler.
+type JobRunner interface {
+	Jobs() []scheduler.Status
+	Job(name string) (scheduler.Status, error)
+	Trigger(name string) (scheduler.Status, error)
+}
+
+type controller struct {
+	jobs JobRunner
+}
+
+// jobsPage is the data for templates/jobs.html.
+type jobsPage struct {
+	Jobs []scheduler.Status
+	// Running makes the page refresh itself until every run has finished.
+	Running bool
+}
+
+// Module serves the scheduled job pages and API and the SQL console.
+type Module struct {
+	jobs JobRunner
+	sql  *sql.DB
+}
+
+// NewModule returns the admin module. readOnly is the pool the SQL console
+// queries (see db.OpenReadOnly); nil leaves the console out.
+func NewModule(jobs JobRunner, readOnly *sql.DB) *Module {
+	return &Module{jobs: jobs, sql: readOnly}
+}
+
+func (m *Module) Name() string { return "admin" }
+
+func (m *Module) Migrations() fs.FS { return nil }
+
+// RegisterRoutes adds the admin routes.
+func (m *Module) RegisterRoutes(mux *http.ServeMux, _ module.Deps) error {
+	c := &controller{jobs: m.jobs}
+	mux.HandleFunc("GET /admin/jobs", c.handleJobsPage)
+	mux.HandleFunc("POST /admin/jobs/{name}/run", c.handleRunJob)
+	mux.HandleFunc("GET /api/v1/admin/jobs", c.handleJobs)
+	mux.HandleFunc("GET /api/v1/admin/jobs/{name}", c.handleJob)
+	if m.sql != nil {
+		console := &sqlConsole{db: m.sql}
+		mux.HandleFunc("GET /admin/sql", console.handleSQLPage)
+		mux.HandleFunc("POST /api/v1/admin/sql", console.handleSQL)
+		mux.HandleFunc("GET /api/v1/admin/sql.csv", console.handleSQLCSV)
+	}
+	return nil
+}
+
+func (m *Module) StartWorkers(ctx context.Context) error { return nil }
+
+func (c *controller) handleJobs(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, c.jobs.Jobs())
+}
+
+func (c *controller) handleJob(w http.ResponseWriter, r *http.Request) {
+	status, err := c.jobs.Job(r.PathValue("name"))
+	if errors.Is(err, scheduler.ErrJobNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, status)
+}
+
+// handleRunJob starts a job immediately and returns 202 with its status; poll
+// the Location URL for the result. Form posts from the jobs page are
+// redirected back to it instead.
+func (c *controller) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	status, err := c.jobs.Trigger(name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		utils.WriteError(w, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		utils.WriteError(w, http.StatusConflict, "job is already running")
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("failed to trigger job", "job", name, "error", err)
+		utils.WriteError(w, http.StatusServiceUnavailable, "job cannot be started")
+		return
+	}
+	if isFormPost(r) {
+		http.Redirect(w, r, "/admin/jobs", http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/admin/jobs/"+url.PathEscape(name))
+	utils.WriteJSON(w, http.StatusAccepted, status)
+}
+
+// isFormPost reports whether r is a plain HTML form submission.
+func isFormPost(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
+
+func (c *controller) handleJobsPage(w http.ResponseWriter, r *http.Request) {
+	page := jobsPage{Jobs: c.jobs.Jobs()}
+	for _, j := range page.Jobs {
+		page.Running = page.Running || j.Running
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := jobsTmpl.Execute(w, page); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render jobs page", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render jobs page")
+	}
+}
@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/scheduler"
+)
+
+type fakeJobs []scheduler.Status
+
+func (f fakeJobs) Jobs() []scheduler.Status { return f }
+
+func (f fakeJobs) Job(name string) (scheduler.Status, error) {
+	for _, st := range f {
+		if st.Name == name {
+			return st, nil
+		}
+	}
+	return scheduler.Status{}, scheduler.ErrJobNotFound
+}
+
+func (f fakeJobs) Trigger(name string) (scheduler.Status, error) {
+	st, err := f.Job(name)
+	if err != nil {
+		return st, err
+	}
+	if st.Running {
+		return scheduler.Status{}, scheduler.ErrJobRunning
+	}
+	st.Running = true
+	st.LastTrigger = scheduler.TriggerManual
+	return st, nil
+}
+
+func newTestMux(t *testing.T, jobs JobRunner) *http.ServeMux {
+	t.Helper()
+	mux := http.NewServeMux()
+	if err := NewModule(jobs, nil).RegisterRoutes(mux, module.Deps{}); err != nil {
+		t.Fatalf("RegisterRoutes() = %v; want nil", err)
+	}
+	return mux
+}
+
+func TestSQLConsoleNotRegisteredWithoutPool(t *testing.T) {
+	mux := newTestMux(t, fakeJobs{})
+	for _, path := range []string{"/admin/sql", "/api/v1/admin/sql.csv"} {
+		if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, path, nil)); pattern != "" {
+			t.Errorf("%s routed to %q; want no console without a read-only pool", path, pattern)
+		}
+	}
+}
+
+func TestJobsPage(t *testing.T) {
+	start := time.Date(2025, 2, 3, 6, 0, 0, 0, time.UTC)
+	jobs := fakeJobs{{Name: "weekly-report", Spec: "0 6 * * 1", Runs: 2, LastStart: &start, LastDuration: 1500 * time.Millisecond, LastError: "disk full"}}
+
+	t.Run("renders html table", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestMux(t, jobs).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/jobs", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want 200", rec.Code)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"weekly-report", "0 6 * * 1", "2025-02-03 06:00:00 UTC", "1.5s", "disk full", `action="/admin/jobs/weekly-report/run"`} {
+			if !strings.Contains(body, want) {
+				t.Errorf("body missing %q", want)
+			}
+		}
+	})
+
+	t.Run("renders empty state", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestMux(t, fakeJobs{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/jobs", nil))
+
+		if !strings.Contains(rec.Body.String(), "No jobs registered.") {
+			t.Errorf("body missing empty state")
+		}
+	})
+
+	t.Run("serves json", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestMux(t, jobs).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil))
+
+		var got []scheduler.Status
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(got) != 1 || got[0].Name != "weekly-report" || got[0].Runs != 2 {
+			t.Errorf("got = %+v; want weekly-report with 2 runs", got)
+		}
+	})
+}
+
+func TestRunJob(t *testing.T) {
+	jobs := fakeJobs{
+		{Name: "retention", Spec: "@daily"},
+		{Name: "rollup", Spec: "@hourly", Running: true},
+	}
+
+	t.Run("starts job and returns status location", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestMux(t, jobs).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/jobs/retention/run", nil))
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("status = %d; want 202", rec.Code)
+		}
+		if loc := rec.Header().Get("Location"); loc != "/api/v1/admin/jobs/retention" {
+			t.Errorf("Location = %q; want /api/v1/admin/jobs/retention", loc)
+		}
+		var got scheduler.Status
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if !got.Running || got.LastTrigger != scheduler.TriggerManual {
+			t.Errorf("got = %+v; want running manual run", got)
+		}
+	})
+
+	t.Run("redirects form posts back to the jobs page", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/jobs/retention/run", strings.NewReader(""))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		newTestMux(t, jobs).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/admin/jobs" {
+			t.Errorf("status = %d, Location = %q; want 303 to /admin/jobs", rec.Code, rec.Header().Get("Location"))
+		}
+	})
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"unknown job", "/admin/jobs/backup/run", http.StatusNotFound},
+		{"already running", "/admin/jobs/rollup/run", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newTestMux(t, jobs).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobStatus(t *testing.T) {
+	jobs := fakeJobs{{Name: "rollup", Spec: "@hourly", Runs: 3}}
+
+	rec := httptest.NewRecorder()
+	newTestMux(t, jobs).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs/rollup", nil))
+	var got scheduler.Status
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Name != "rollup" || got.Runs != 3 {
+		t.Errorf("got = %+v; want rollup with 3 runs", got)
+	}
+
+	rec = httptest.NewRecorder()
+	newTestMux(t, jobs).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d; want 404", rec.Code)
+	}
+}
@@ -0,0 +1,319 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+var sqlTmpl = template.Must(template.New("sql.html").Funcs(template.FuncMap{
+	"null": func(v any) bool { return v == nil },
+	"value": func(v any) string {
+		if v == nil {
+			return "NULL"
+		}
+		return cell(v)
+	},
+}).ParseFS(templatesFS, "templates/sql.html"))
+
+// Row limits of console queries: a page or JSON response, and a CSV export.
+const (
+	maxSQLRows       = 1000
+	maxSQLExportRows = 100000
+)
+
+// sqlStatements are the first keywords of the statements the console runs.
+var sqlStatements = map[string]bool{"SELECT": true, "WITH": true, "VALUES": true, "EXPLAIN": true}
+
+// errNotReadOnly is returned for queries that would write, however they
+// passed the keyword check.
+var errNotReadOnly = errors.New("only read-only queries are allowed")
+
+// sqlConsole runs ad-hoc queries on a read-only pool (see db.OpenReadOnly).
+// Queries are checked three times: the statement must start with a keyword of
+// sqlStatements, SQLite must report the prepared statement read-only, and
+// the pool cannot write anyway.
+type sqlConsole struct {
+	db *sql.DB
+}
+
+// sqlRequest is the body of POST /api/v1/admin/sql.
+type sqlRequest struct {
+	Query string `json:"query"`
+}
+
+// sqlResult is a query's result. Rows hold the values as SQLite returns them,
+// with text and blobs as strings.
+type sqlResult struct {
+	Columns   []string `json:"columns"`
+	Rows      [][]any  `json:"rows"`
+	Truncated bool     `json:"truncated"` // more rows than the limit matched
+}
+
+// sqlPage is the data for templates/sql.html.
+type sqlPage struct {
+	Query    string
+	Result   *sqlResult
+	Error    string
+	Duration time.Duration
+	Limit    int
+}
+
+// checkQuery returns the statement of q without surrounding space, comments
+// after it and a trailing semicolon, or why the console refuses it: it is
+// empty, holds several statements or does not start with an allowed keyword.
+func checkQuery(q string) (string, error) {
+	stmt, tail := cutStatement(q)
+	stmt = strings.TrimSpace(stmt)
+	if skipComments(tail) != "" {
+		return "", errors.New("only one statement is allowed")
+	}
+	body := skipComments(stmt)
+	if body == "" {
+		return "", errors.New("empty query")
+	}
+	keyword, _, _ := strings.Cut(strings.ToUpper(strings.Join(strings.Fields(body), " ")), " ")
+	keyword = strings.TrimLeft(keyword, "(")
+	if !sqlStatements[keyword] {
+		return "", fmt.Errorf("only SELECT, WITH, VALUES and EXPLAIN queries are allowed, got %s", keyword)
+	}
+	return stmt, nil
+}
+
+// cutStatement splits q at the semicolon ending its first statement, which
+// is the tail SQLite would prepare next; the driver runs every statement of
+// a query without exposing the tail. Semicolons inside literals, quoted
+// identifiers and comments do not count.
+func cutStatement(q string) (stmt, tail string) {
+	for i := 0; i < len(q); i++ {
+		switch c := q[i]; c {
+		case ';':
+			return q[:i], q[i+1:]
+		case '\'', '"', '`':
+			i += closing(q[i+1:], string(c))
+		case '[':
+			i += closing(q[i+1:], "]")
+		case '-':
+			if strings.HasPrefix(q[i:], "--") {
+				i += 1 + closing(q[i+2:], "\n")
+			}
+		case '/':
+			if strings.HasPrefix(q[i:], "/*") {
+				i += 1 + closing(q[i+2:], "*/")
+			}
+		}
+	}
+	return q, ""
+}
+
+// closing returns the length of s up to and including end, or of all of s
+// when end is missing.
+func closing(s, end string) int {
+	if i := strings.Index(s, end); i >= 0 {
+		return i + len(end)
+	}
+	return len(s)
+}
+
+// skipComments returns s without the space and comments it starts with.
+func skipComments(s string) string {
+	for {
+		s = strings.TrimSpace(s)
+		switch {
+		case strings.HasPrefix(s, "--"):
+			s = s[2+closing(s[2:], "\n"):]
+		case strings.HasPrefix(s, "/*"):
+			s = s[2+closing(s[2:], "*/"):]
+		default:
+			return s
+		}
+	}
+}
+
+// run executes q and passes its column names, then up to limit rows, to the
+// callbacks. It reports whether more rows matched.
+func (c *sqlConsole) run(ctx context.Context, q string, limit int, columns func([]string) error, row func([]any) error) (bool, error) {
+	q, err := checkQuery(q)
+	if err != nil {
+		return false, err
+	}
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if err := conn.Raw(func(dc any) error { return checkReadOnly(dc, q) }); err != nil {
+		return false, err
+	}
+	rows, err := conn.QueryContext(ctx, q)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	if err := columns(cols); err != nil {
+		return false, err
+	}
+	for n := 0; rows.Next(); n++ {
+		if n == limit {
+			return true, nil
+		}
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return false, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := row(values); err != nil {
+			return false, err
+		}
+	}
+	return false, rows.Err()
+}
+
+// checkReadOnly prepares q on the driver connection dc and asks SQLite
+// whether it writes. Drivers without the check pass.
+func checkReadOnly(dc any, q string) error {
+	conn, ok := dc.(driver.Conn)
+	if !ok {
+		return nil
+	}
+	stmt, err := conn.Prepare(q)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if ro, ok := stmt.(interface{ Readonly() bool }); ok && !ro.Readonly() {
+		return errNotReadOnly
+	}
+	return nil
+}
+
+// query runs q into a sqlResult of at most maxSQLRows rows.
+func (c *sqlConsole) query(ctx context.Context, q string) (*sqlResult, error) {
+	res := &sqlResult{Rows: [][]any{}}
+	truncated, err := c.run(ctx, q, maxSQLRows,
+		func(cols []string) error { res.Columns = cols; return nil },
+		func(values []any) error { res.Rows = append(res.Rows, values); return nil })
+	if err != nil {
+		return nil, err
+	}
+	res.Truncated = truncated
+	return res, nil
+}
+
+// handleSQLPage renders the console, with the result of query q when given.
+func (c *sqlConsole) handleSQLPage(w http.ResponseWriter, r *http.Request) {
+	page := sqlPage{Query: r.URL.Query().Get("q"), Limit: maxSQLRows}
+	if strings.TrimSpace(page.Query) != "" {
+		start := time.Now()
+		res, err := c.query(r.Context(), page.Query)
+		page.Duration = time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			page.Error = err.Error()
+		}
+		page.Result = res
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := sqlTmpl.Execute(w, page); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render sql page", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render sql page")
+	}
+}
+
+// handleSQL runs the query of the JSON body. Refused and failing queries are
+// answered 400 with SQLite's message.
+func (c *sqlConsole) handleSQL(w http.ResponseWriter, r *http.Request) {
+	var req sqlRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	res, err := c.query(r.Context(), req.Query)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, res)
+}
+
+// handleSQLCSV streams the result of query q as a CSV download, up to
+// maxSQLExportRows rows; NULL is an empty cell.
+func (c *sqlConsole) handleSQLCSV(w http.ResponseWriter, r *http.Request) {
+	body := &writeTracker{w: w}
+	out := csv.NewWriter(body)
+	truncated, err := c.run(r.Context(), r.URL.Query().Get("q"), maxSQLExportRows,
+		func(cols []string) error {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "query.csv"}))
+			return out.Write(cols)
+		},
+		func(values []any) error {
+			record := make([]string, len(values))
+			for i, v := range values {
+				record[i] = cell(v)
+			}
+			return out.Write(record)
+		})
+	if err == nil {
+		out.Flush()
+		err = out.Error()
+	}
+	switch {
+	case err != nil && !body.written:
+		w.Header().Del("Content-Disposition")
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		// The rows sent so far cannot be taken back; abort the download.
+		logging.FromContext(r.Context()).Error("sql csv export failed", "error", err)
+		panic(http.ErrAbortHandler)
+	case truncated:
+		logging.FromContext(r.Context()).Warn("sql csv export truncated", "rows", maxSQLExportRows)
+	}
+}
+
+// writeTracker records whether anything was written to w.
+type writeTracker struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *writeTracker) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
+
+// cell formats a value for CSV: NULL is empty and times are RFC 3339.
+func cell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,151 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/config"
+	"cloudpico-server/internal/db"
+	"cloudpico-server/internal/module"
+)
+
+func newSQLTestMux(t *testing.T) (*http.ServeMux, *sql.DB) {
+	t.Helper()
+	cfg := config.Config{SQLiteDriver: "sqlite3", SQLitePath: filepath.Join(t.TempDir(), "app.db")}
+	rw, err := db.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rw.Close() })
+	if _, err := rw.Exec(`CREATE TABLE stations (id INTEGER PRIMARY KEY, name TEXT, note TEXT);
+		INSERT INTO stations (name, note) VALUES ('garden', NULL), ('attic', 'a,b')`); err != nil {
+		t.Fatal(err)
+	}
+	ro, err := db.OpenReadOnly(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ro.Close() })
+	mux := http.NewServeMux()
+	if err := NewModule(fakeJobs{}, ro).RegisterRoutes(mux, module.Deps{}); err != nil {
+		t.Fatalf("RegisterRoutes() = %v; want nil", err)
+	}
+	return mux, rw
+}
+
+func TestCheckQuery(t *testing.T) {
+	for q, ok := range map[string]bool{
+		"SELECT 1;":                                    true,
+		"  with x AS (SELECT 1) SELECT * FROM x":       true,
+		"(SELECT 1)":                                   true,
+		"EXPLAIN QUERY PLAN SELECT 1":                  true,
+		"SELECT * FROM stations WHERE note LIKE '%;%'": true,
+		"SELECT 1 AS \"a;b\"":                          true,
+		"-- stations\nSELECT * FROM stations":          true,
+		"/* one; two */ SELECT 1; -- done":             true,
+		"SELECT 1 /* ; */":                             true,
+		"-- SELECT 1":                                  false,
+		";":                                            false,
+		"":                                             false,
+		"SELECT 1; DELETE FROM stations":               false,
+		"SELECT ';'; DELETE FROM stations":             false,
+		"SELECT 1; -- x\nDELETE FROM stations":         false,
+		"/* SELECT */ DELETE FROM stations":            false,
+		"DELETE FROM stations":                         false,
+		"PRAGMA query_only = off":                      false,
+		"ATTACH 'x.db' AS x":                           false,
+	} {
+		if _, err := checkQuery(q); (err == nil) != ok {
+			t.Errorf("checkQuery(%q) error = %v; want ok %v", q, err, ok)
+		}
+	}
+}
+
+func TestSQLConsole(t *testing.T) {
+	mux, rw := newSQLTestMux(t)
+	post := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(sqlRequest{Query: query})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/sql", strings.NewReader(string(body))))
+		return rec
+	}
+
+	t.Run("api", func(t *testing.T) {
+		rec := post("SELECT name, note FROM stations ORDER BY id")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d %s; want 200", rec.Code, rec.Body)
+		}
+		var got sqlResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Columns) != 2 || len(got.Rows) != 2 || got.Rows[0][0] != "garden" || got.Rows[0][1] != nil || got.Truncated {
+			t.Errorf("result = %+v", got)
+		}
+	})
+
+	t.Run("semicolons and comments", func(t *testing.T) {
+		rec := post("-- attic only\nSELECT name FROM stations WHERE note LIKE '%,%';")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"attic"`) {
+			t.Errorf("status = %d %s; want 200 with attic", rec.Code, rec.Body)
+		}
+		if rec := post("SELECT name FROM stations WHERE note LIKE '%;%'"); rec.Code != http.StatusOK {
+			t.Errorf("status = %d %s; want 200", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("refuses writes", func(t *testing.T) {
+		for _, q := range []string{
+			"DELETE FROM stations",
+			"WITH x AS (SELECT 1) DELETE FROM stations",
+			"SELECT 1; DROP TABLE stations",
+			"SELECT ';'; DROP TABLE stations",
+		} {
+			if rec := post(q); rec.Code != http.StatusBadRequest {
+				t.Errorf("%q: status = %d %s; want 400", q, rec.Code, rec.Body)
+			}
+		}
+		var n int
+		if err := rw.QueryRow(`SELECT COUNT(*) FROM stations`).Scan(&n); err != nil || n != 2 {
+			t.Errorf("stations = %d, %v; want 2 untouched", n, err)
+		}
+	})
+
+	t.Run("page", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/sql?q="+url.QueryEscape("SELECT name, note FROM stations"), nil))
+		body := rec.Body.String()
+		for _, want := range []string{"<th>note</th>", "<td>attic</td>", `class="sql-null"`, "2 rows", "Download CSV"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("page missing %q; got %q", want, body)
+			}
+		}
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/sql?q="+url.QueryEscape("SELECT nope FROM stations"), nil))
+		if !strings.Contains(rec.Body.String(), "no such column") {
+			t.Errorf("page = %q; want the SQLite error", rec.Body)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/sql.csv?q="+url.QueryEscape("SELECT name, note FROM stations ORDER BY id"), nil))
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/csv") {
+			t.Fatalf("status = %d, type %q; want a CSV", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		if want := "name,note\ngarden,\nattic,\"a,b\"\n"; rec.Body.String() != want {
+			t.Errorf("csv = %q; want %q", rec.Body, want)
+		}
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/sql.csv?q=DELETE+FROM+stations", nil))
+		if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Disposition") != "" {
+			t.Errorf("refused csv = %d %v; want 400 without a download", rec.Code, rec.Header())
+		}
+	})
+}
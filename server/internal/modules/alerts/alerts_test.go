@@ -0,0 +1,250 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/module"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestModule returns the module migrated onto an in-memory database with
+// stations 1 (Garden) and 2 (Attic), and a mux serving its routes.
+func newTestModule(t *testing.T) (*Module, *http.ServeMux) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`
+CREATE TABLE stations (id INTEGER PRIMARY KEY, name TEXT NOT NULL, archived_at TEXT);
+INSERT INTO stations (id, name) VALUES (1, 'Garden'), (2, 'Attic');`); err != nil {
+		t.Fatalf("create stations: %v", err)
+	}
+	m := NewModule()
+	if err := module.Migrate(db, []module.Module{m}); err != nil {
+		t.Fatalf("Migrate() = %v; want nil", err)
+	}
+	mux := http.NewServeMux()
+	if err := m.RegisterRoutes(mux, module.Deps{DB: db}); err != nil {
+		t.Fatalf("RegisterRoutes() = %v; want nil", err)
+	}
+	return m, mux
+}
+
+func TestStore_Silenced(t *testing.T) {
+	m, _ := newTestModule(t)
+	store := m.Silences()
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if _, err := store.Create(Silence{StationID: "1", Start: base, End: base.Add(time.Hour)}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.Create(Silence{Start: base.Add(5 * time.Hour), End: base.Add(6 * time.Hour)}); err != nil {
+		t.Fatalf("Create(global): %v", err)
+	}
+	if _, err := store.Create(Silence{StationID: "9", Start: base, End: base.Add(time.Hour)}); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("Create(unknown station) err = %v; want ErrStationNotFound", err)
+	}
+
+	tests := []struct {
+		name      string
+		stationID string
+		at        time.Time
+		want      bool
+	}{
+		{"station silence start", "1", base, true},
+		{"station silence end is exclusive", "1", base.Add(time.Hour), false},
+		{"other station", "2", base.Add(30 * time.Minute), false},
+		{"global silence", "2", base.Add(5*time.Hour + time.Minute), true},
+		{"before", "1", base.Add(-time.Millisecond), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Silenced(tt.stationID, tt.at)
+			if err != nil || got != tt.want {
+				t.Errorf("Silenced(%s, %v) = %v, %v; want %v, nil", tt.stationID, tt.at, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSilencesAPI(t *testing.T) {
+	_, mux := newTestModule(t)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do(http.MethodPost, "/api/v1/silences", `{"stationId":"1","duration":"2h","reason":" battery "}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d; want 201 (body %s)", rec.Code, rec.Body.String())
+	}
+	var created Silence
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.StationName != "Garden" || created.Reason != "battery" || created.End.Sub(created.Start) != 2*time.Hour {
+		t.Errorf("created = %+v; want Garden, reason battery, 2h", created)
+	}
+
+	for _, tt := range []struct {
+		name, body string
+		want       int
+	}{
+		{"no end", `{}`, http.StatusBadRequest},
+		{"end and duration", `{"end":"2030-01-01T00:00:00Z","duration":"1h"}`, http.StatusBadRequest},
+		{"end before start", `{"start":"2030-01-01T00:00:00Z","end":"2029-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"bad duration", `{"duration":"soon"}`, http.StatusBadRequest},
+		{"unknown station", `{"stationId":"9","duration":"1h"}`, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(http.MethodPost, "/api/v1/silences", tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	// An expired silence is not listed.
+	if rec := do(http.MethodPost, "/api/v1/silences", `{"start":"2020-01-01T00:00:00Z","end":"2020-01-02T00:00:00Z"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("POST expired status = %d; want 201", rec.Code)
+	}
+	var page struct {
+		Items []Silence `json:"items"`
+		Total int       `json:"total"`
+	}
+	if err := json.NewDecoder(do(http.MethodGet, "/api/v1/silences", "").Body).Decode(&page); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if page.Total != 1 || page.Items[0].ID != created.ID {
+		t.Errorf("page = %+v; want only silence %s", page, created.ID)
+	}
+
+	if rec := do(http.MethodDelete, "/api/v1/silences/"+created.ID, ""); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d; want 204", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/api/v1/silences/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d; want 404", rec.Code)
+	}
+}
+
+func TestSilencesPage(t *testing.T) {
+	_, mux := newTestModule(t)
+
+	form := url.Values{"station_id": {""}, "duration": {"4h"}, "reason": {"moving house"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/admin/silences" {
+		t.Fatalf("form POST = %d, Location %q; want 303 to /admin/silences", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/silences", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"All stations", "moving house", "active", `<option value="1">Garden</option>`, `action="/admin/silences/1/delete"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+
+	t.Run("invalid form shows the error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/silences", strings.NewReader("duration=soon"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `class="silence-error"`) {
+			t.Errorf("status = %d; want 400 with the page and error", rec.Code)
+		}
+	})
+
+	t.Run("end from the page", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/silences/1/delete", nil))
+		if rec.Code != http.StatusSeeOther {
+			t.Errorf("status = %d; want 303", rec.Code)
+		}
+	})
+}
+
+func TestAlertBanners(t *testing.T) {
+	m, mux := newTestModule(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	fire := func(at time.Time, stationID string, f Fired) {
+		t.Helper()
+		m.onAlertFired(events.Event{Topic: events.AlertFired, Time: at, StationID: stationID, Payload: f})
+		a := <-m.fired
+		if err := m.events.record(context.Background(), a.stationID, a.fired, a.at); err != nil {
+			t.Fatalf("record(%s) = %v; want nil", f.Rule, err)
+		}
+	}
+	fire(now.Add(-2*time.Hour), "2", Fired{Rule: "humidity", Message: "Above 90%"})
+	fire(now.Add(-30*time.Minute), "1", Fired{Rule: "frost", Message: "Below 0 °C"})
+	fire(now.Add(-10*time.Minute), "", Fired{Rule: "mqtt", Severity: SeverityCritical, Message: "MQTT disconnected"})
+	fire(now.Add(-5*time.Minute), "1", Fired{Rule: "frost", Message: "Below -2 °C"})
+
+	get := func(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	var active []ActiveAlert
+	if err := json.NewDecoder(get("/api/v1/alerts").Body).Decode(&active); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(active) != 2 || active[0].Rule != "mqtt" || active[1].Rule != "frost" {
+		t.Fatalf("active = %+v; want mqtt (critical) then frost, humidity expired", active)
+	}
+	frost := active[1]
+	if frost.StationName != "Garden" || frost.Message != "Below -2 °C" || frost.Severity != SeverityWarning ||
+		!frost.FiredAt.Equal(now.Add(-30*time.Minute)) || !frost.LastFiredAt.Equal(now.Add(-5*time.Minute)) {
+		t.Errorf("frost = %+v; want one Garden warning refreshed by the second firing", frost)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/alerts/"+frost.ID+"/dismiss", nil))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("HX-Trigger") != alertsChangedEvent {
+		t.Fatalf("dismiss = %d, HX-Trigger %q; want 204, %s", rec.Code, rec.Header().Get("HX-Trigger"), alertsChangedEvent)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != viewerCookie {
+		t.Fatalf("cookies = %v; want the viewer cookie", cookies)
+	}
+	if body := get("/partials/alerts", cookies[0]).Body.String(); strings.Contains(body, "Below -2") || !strings.Contains(body, "MQTT disconnected") {
+		t.Errorf("banners after dismissal = %s; want only the mqtt alert", body)
+	}
+	if body := get("/partials/alerts").Body.String(); !strings.Contains(body, "<strong>Garden:</strong> Below -2 °C") {
+		t.Errorf("banners for another viewer = %s; want the frost alert", body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/alerts/99/dismiss", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("dismiss unknown = %d; want 404", rec.Code)
+	}
+}
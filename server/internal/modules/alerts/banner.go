@@ -0,0 +1,316 @@
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/auth"
+	"cloudpico-server/internal/utils"
+)
+
+// Severities of fired alerts.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+const (
+	// alertActiveFor is how long an alert stays active after it last fired.
+	alertActiveFor = time.Hour
+	// firedQueueSize bounds the fired alerts waiting to be stored; alerts
+	// fired while it is full are dropped and logged.
+	firedQueueSize = 100
+	// viewerCookie identifies browsers without a login, for dismissals.
+	viewerCookie       = "alerts_viewer"
+	viewerCookieMaxAge = 365 * 24 * 60 * 60 // 1 year in seconds
+	// alertsChangedEvent is sent in HX-Trigger so the banners refresh right
+	// after a dismissal.
+	alertsChangedEvent = "alerts-changed"
+)
+
+var bannerTmpl = template.Must(template.New("banner.html").Funcs(template.FuncMap{
+	"utc": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04") + " UTC"
+	},
+}).ParseFS(templatesFS, "templates/banner.html"))
+
+// ErrAlertNotFound is returned when an alert ID matches no active alert.
+var ErrAlertNotFound = errors.New("alert not found")
+
+// Fired is the payload of an events.AlertFired event; the event's StationID
+// is the station the alert is about, or "" for the whole server.
+type Fired struct {
+	Rule     string
+	Severity string // SeverityWarning or SeverityCritical; "" is a warning
+	Message  string
+}
+
+// ActiveAlert is an alert fired within alertActiveFor.
+type ActiveAlert struct {
+	ID          string    `json:"id"`
+	Rule        string    `json:"rule"`
+	StationID   string    `json:"stationId,omitempty"`
+	StationName string    `json:"stationName,omitempty"`
+	Severity    string    `json:"severity"`
+	Message     string    `json:"message"`
+	FiredAt     time.Time `json:"firedAt"`
+	LastFiredAt time.Time `json:"lastFiredAt"`
+	Dismissed   bool      `json:"dismissed"` // by the requesting viewer
+}
+
+// eventStore keeps fired alerts and their dismissals.
+type eventStore struct {
+	db *sql.DB
+}
+
+// record stores an alert fired at now: it refreshes the active alert of the
+// same rule and station, or adds one. Alerts no longer active are dropped.
+func (s *eventStore) record(ctx context.Context, stationID string, f Fired, now time.Time) error {
+	severity := f.Severity
+	if severity == "" {
+		severity = SeverityWarning
+	}
+	if severity != SeverityWarning && severity != SeverityCritical {
+		return fmt.Errorf("unknown severity %q", severity)
+	}
+	ts := now.UTC().Format(timeLayout)
+	since := now.Add(-alertActiveFor).UTC().Format(timeLayout)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, `
+DELETE FROM alert_dismissals
+WHERE alert_id IN (SELECT id FROM alert_events WHERE last_fired_at < ?)`, since); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM alert_events WHERE last_fired_at < ?`, since); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `
+UPDATE alert_events SET severity = ?, message = ?, last_fired_at = ?
+WHERE rule = ? AND station_id = ?`, severity, f.Message, ts, f.Rule, stationID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO alert_events (rule, station_id, severity, message, fired_at, last_fired_at)
+VALUES (?, ?, ?, ?, ?, ?)`, f.Rule, stationID, severity, f.Message, ts, ts); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// active returns the alerts active at now, critical first, then newest
+// first, marking those viewer dismissed.
+func (s *eventStore) active(ctx context.Context, viewer string, now time.Time) ([]ActiveAlert, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT CAST(e.id AS TEXT), e.rule, e.station_id, COALESCE(st.name, ''), e.severity, e.message,
+  e.fired_at, e.last_fired_at, d.alert_id IS NOT NULL
+FROM alert_events e
+LEFT JOIN stations st ON CAST(st.id AS TEXT) = e.station_id
+LEFT JOIN alert_dismissals d ON d.alert_id = e.id AND d.viewer = ?
+WHERE e.last_fired_at >= ?
+ORDER BY e.severity = 'critical' DESC, e.fired_at DESC, e.id DESC`,
+		viewer, now.Add(-alertActiveFor).UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close alert events rows", "error", err)
+		}
+	}()
+	out := []ActiveAlert{}
+	for rows.Next() {
+		var a ActiveAlert
+		var fired, last string
+		if err := rows.Scan(&a.ID, &a.Rule, &a.StationID, &a.StationName, &a.Severity, &a.Message,
+			&fired, &last, &a.Dismissed); err != nil {
+			return nil, err
+		}
+		if a.FiredAt, err = time.Parse(time.RFC3339Nano, fired); err != nil {
+			return nil, fmt.Errorf("parse fired_at: %w", err)
+		}
+		if a.LastFiredAt, err = time.Parse(time.RFC3339Nano, last); err != nil {
+			return nil, fmt.Errorf("parse last_fired_at: %w", err)
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// dismiss hides the active alert id from viewer. A dismissed alert shows
+// again if it ends and fires anew.
+func (s *eventStore) dismiss(ctx context.Context, id, viewer string, now time.Time) error {
+	res, err := s.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO alert_dismissals (alert_id, viewer, dismissed_at)
+SELECT id, ?, ? FROM alert_events WHERE id = ? AND last_fired_at >= ?`,
+		viewer, now.UTC().Format(timeLayout), id, now.Add(-alertActiveFor).UTC().Format(timeLayout))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		return nil
+	}
+	var one int
+	err = s.db.QueryRowContext(ctx, `
+SELECT 1 FROM alert_events WHERE id = ? AND last_fired_at >= ?`, id, now.Add(-alertActiveFor).UTC().Format(timeLayout)).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAlertNotFound
+	}
+	return err // already dismissed when nil
+}
+
+// onAlertFired queues e for storing; bus handlers must not block.
+func (m *Module) onAlertFired(e events.Event) {
+	f, ok := e.Payload.(Fired)
+	if !ok {
+		slog.Error("alert fired with an unexpected payload", "payload", fmt.Sprintf("%T", e.Payload))
+		return
+	}
+	select {
+	case m.fired <- firedAlert{stationID: e.StationID, fired: f, at: e.Time}:
+	default:
+		slog.Warn("alert queue full; alert dropped", "rule", f.Rule, "station_id", e.StationID)
+	}
+}
+
+type firedAlert struct {
+	stationID string
+	fired     Fired
+	at        time.Time
+}
+
+// storeFired stores queued alerts until ctx is canceled.
+func (m *Module) storeFired(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-m.fired:
+			if err := m.events.record(ctx, a.stationID, a.fired, a.at); err != nil {
+				slog.Error("record alert failed", "rule", a.fired.Rule, "station_id", a.stationID, "error", err)
+			}
+		}
+	}
+}
+
+// viewerOf returns who dismisses banners in r: the logged-in user, else the
+// browser's viewer cookie. With create, a browser without one is given one;
+// otherwise it returns "".
+func viewerOf(w http.ResponseWriter, r *http.Request, create bool) (string, error) {
+	if u, ok := auth.UserFrom(r.Context()); ok {
+		return "user:" + u.ID, nil
+	}
+	if c, err := r.Cookie(viewerCookie); err == nil && len(c.Value) == 32 {
+		if _, err := hex.DecodeString(c.Value); err == nil {
+			return "browser:" + c.Value, nil
+		}
+	}
+	if !create {
+		return "", nil
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	value := hex.EncodeToString(id)
+	http.SetCookie(w, &http.Cookie{
+		Name:     viewerCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   viewerCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return "browser:" + value, nil
+}
+
+// bannerController serves the active alerts.
+type bannerController struct {
+	m *Module
+}
+
+func (c *bannerController) load(w http.ResponseWriter, r *http.Request) ([]ActiveAlert, bool) {
+	viewer, err := viewerOf(w, r, false)
+	if err == nil {
+		var alerts []ActiveAlert
+		if alerts, err = c.m.events.active(r.Context(), viewer, c.m.now()); err == nil {
+			return alerts, true
+		}
+	}
+	logging.FromContext(r.Context()).Error("load active alerts failed", "error", err)
+	utils.WriteError(w, http.StatusInternalServerError, "failed to load alerts")
+	return nil, false
+}
+
+// handleAlerts returns the active alerts, including those the viewer
+// dismissed.
+func (c *bannerController) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts, ok := c.load(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, alerts)
+}
+
+// handleBannerPartial renders the banners of the active alerts the viewer has
+// not dismissed.
+func (c *bannerController) handleBannerPartial(w http.ResponseWriter, r *http.Request) {
+	alerts, ok := c.load(w, r)
+	if !ok {
+		return
+	}
+	shown := alerts[:0]
+	for _, a := range alerts {
+		if !a.Dismissed {
+			shown = append(shown, a)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := bannerTmpl.Execute(w, shown); err != nil {
+		logging.FromContext(r.Context()).Error("render alert banners failed", "error", err)
+	}
+}
+
+func (c *bannerController) handleDismiss(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		utils.WriteError(w, http.StatusNotFound, "alert not found")
+		return
+	}
+	viewer, err := viewerOf(w, r, true)
+	if err == nil {
+		err = c.m.events.dismiss(r.Context(), id, viewer, c.m.now())
+	}
+	if errors.Is(err, ErrAlertNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "alert not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("dismiss alert failed", "alert_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to dismiss alert")
+		return
+	}
+	w.Header().Set("HX-Trigger", alertsChangedEvent)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,238 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var silencesTmpl = template.Must(template.New("silences.html").Funcs(template.FuncMap{
+	"utc": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04") + " UTC"
+	},
+}).ParseFS(templatesFS, "templates/silences.html"))
+
+// Silences page size: default and maximum limit.
+const (
+	defaultSilencesLimit = 100
+	maxSilencesLimit     = 1000
+)
+
+// maxReasonLen bounds a silence reason in characters.
+const maxReasonLen = 200
+
+// silenceDurations are the choices offered by the silences page form.
+var silenceDurations = []durationOption{
+	{"1h", "1 hour"},
+	{"4h", "4 hours"},
+	{"24h", "1 day"},
+	{"168h", "1 week"},
+}
+
+// createSilenceRequest is the body of POST /api/v1/silences. Start defaults to
+// now; exactly one of End and Duration (a Go duration, e.g. "2h") is required.
+// An empty StationID silences all stations.
+type createSilenceRequest struct {
+	StationID string     `json:"stationId"`
+	Start     *time.Time `json:"start"`
+	End       *time.Time `json:"end"`
+	Duration  string     `json:"duration"`
+	Reason    string     `json:"reason"`
+}
+
+type durationOption struct {
+	Value string // Go duration
+	Label string
+}
+
+// silencesPage is the data for templates/silences.html.
+type silencesPage struct {
+	Silences  []Silence
+	Stations  []Station
+	Durations []durationOption
+	Now       time.Time
+	Error     string
+}
+
+type controller struct {
+	store *Store
+}
+
+func (c *controller) handleSilences(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.ParsePagination(r, defaultSilencesLimit, maxSilencesLimit)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	silences, err := c.store.List(time.Now())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WritePage(w, r, utils.Slice(silences, page), len(silences), page)
+}
+
+// handleCreateSilence accepts JSON, or a form post from the silences page,
+// which is redirected back to the page.
+func (c *controller) handleCreateSilence(w http.ResponseWriter, r *http.Request) {
+	form := isFormPost(r)
+	var req createSilenceRequest
+	if form {
+		req = createSilenceRequest{
+			StationID: r.PostFormValue("station_id"),
+			Duration:  r.PostFormValue("duration"),
+			Reason:    r.PostFormValue("reason"),
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	silence, err := c.newSilence(req)
+	if err != nil {
+		c.writeCreateError(w, r, form, http.StatusBadRequest, err.Error())
+		return
+	}
+	silence, err = c.store.Create(silence)
+	if errors.Is(err, ErrStationNotFound) {
+		c.writeCreateError(w, r, form, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create silence failed", "error", err)
+		c.writeCreateError(w, r, form, http.StatusInternalServerError, "failed to create silence")
+		return
+	}
+	logging.FromContext(r.Context()).Info("silence created", "silence_id", silence.ID, "station_id", silence.StationID,
+		"start", silence.Start, "end", silence.End)
+	if form {
+		http.Redirect(w, r, "/admin/silences", http.StatusSeeOther)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, silence)
+}
+
+// newSilence validates req and resolves its window.
+func (c *controller) newSilence(req createSilenceRequest) (Silence, error) {
+	s := Silence{
+		StationID: strings.TrimSpace(req.StationID),
+		Start:     time.Now(),
+		Reason:    strings.TrimSpace(req.Reason),
+	}
+	if len([]rune(s.Reason)) > maxReasonLen {
+		return Silence{}, fmt.Errorf("'reason' must be at most %d characters", maxReasonLen)
+	}
+	if req.Start != nil {
+		s.Start = *req.Start
+	}
+	switch {
+	case req.End != nil && req.Duration != "":
+		return Silence{}, errors.New("set only one of 'end' and 'duration'")
+	case req.End != nil:
+		s.End = *req.End
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return Silence{}, errors.New("invalid 'duration' (expected e.g. 2h)")
+		}
+		s.End = s.Start.Add(d)
+	default:
+		return Silence{}, errors.New("'end' or 'duration' is required")
+	}
+	if !s.End.After(s.Start) {
+		return Silence{}, errors.New("'end' must be after 'start'")
+	}
+	return s, nil
+}
+
+func (c *controller) writeCreateError(w http.ResponseWriter, r *http.Request, form bool, status int, msg string) {
+	if !form {
+		utils.WriteError(w, status, msg)
+		return
+	}
+	c.renderPage(r.Context(), w, status, msg)
+}
+
+func (c *controller) handleDeleteSilence(w http.ResponseWriter, r *http.Request) {
+	if err := c.deleteSilence(r.Context(), w, r.PathValue("id")); err != nil {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteSilenceForm ends a silence from the silences page; HTML forms
+// cannot send DELETE.
+func (c *controller) handleDeleteSilenceForm(w http.ResponseWriter, r *http.Request) {
+	if err := c.deleteSilence(r.Context(), w, r.PathValue("id")); err != nil {
+		return
+	}
+	http.Redirect(w, r, "/admin/silences", http.StatusSeeOther)
+}
+
+// deleteSilence deletes silence id and writes the error response on failure.
+func (c *controller) deleteSilence(ctx context.Context, w http.ResponseWriter, id string) error {
+	err := c.store.Delete(id)
+	if errors.Is(err, ErrSilenceNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "silence not found")
+		return err
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("delete silence failed", "silence_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to delete silence")
+		return err
+	}
+	logging.FromContext(ctx).Info("silence deleted", "silence_id", id)
+	return nil
+}
+
+func (c *controller) handleSilencesPage(w http.ResponseWriter, r *http.Request) {
+	c.renderPage(r.Context(), w, http.StatusOK, "")
+}
+
+// renderPage writes the silences page with status, showing errMsg above the form.
+func (c *controller) renderPage(ctx context.Context, w http.ResponseWriter, status int, errMsg string) {
+	now := time.Now()
+	silences, err := c.store.List(now)
+	if err != nil {
+		logging.FromContext(ctx).Error("silences page: list silences failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load silences")
+		return
+	}
+	stations, err := c.store.Stations()
+	if err != nil {
+		logging.FromContext(ctx).Error("silences page: list stations failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
+		return
+	}
+	var buf bytes.Buffer
+	data := silencesPage{Silences: silences, Stations: stations, Durations: silenceDurations, Now: now, Error: errMsg}
+	if err := silencesTmpl.Execute(&buf, data); err != nil {
+		logging.FromContext(ctx).Error("failed to render silences page", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render page")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logging.FromContext(ctx).Error("silences page: write response failed", "error", err)
+	}
+}
+
+// isFormPost reports whether r is a plain HTML form submission.
+func isFormPost(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
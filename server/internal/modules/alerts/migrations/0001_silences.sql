@@ -0,0 +1,17 @@
+-- =========================
+-- silences
+-- =========================
+-- Maintenance windows during which alert rules do not fire and offline
+-- detection is suppressed. A NULL station_id silences every station.
+CREATE TABLE IF NOT EXISTS silences (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER REFERENCES stations(id) ON DELETE CASCADE,
+  starts_at  TEXT    NOT NULL,
+  ends_at    TEXT    NOT NULL,
+  reason     TEXT    NOT NULL DEFAULT '',
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_silences_ends_at
+ON silences(ends_at);
@@ -0,0 +1,29 @@
+-- =========================
+-- alert_events
+-- =========================
+-- Alerts fired by alert rules, shown as banners on every page while active.
+-- An alert stays active until no rule has fired it again for a while; a
+-- firing of the same rule and station while it is active refreshes
+-- last_fired_at instead of adding a row. station_id is '' for alerts about
+-- the whole server.
+CREATE TABLE IF NOT EXISTS alert_events (
+  id            INTEGER PRIMARY KEY,
+  rule          TEXT    NOT NULL,
+  station_id    TEXT    NOT NULL DEFAULT '',
+  severity      TEXT    NOT NULL CHECK (severity IN ('warning', 'critical')),
+  message       TEXT    NOT NULL,
+  fired_at      TEXT    NOT NULL,
+  last_fired_at TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_events_rule_station
+ON alert_events(rule, station_id, last_fired_at);
+
+-- Banners dismissed per viewer: "user:<id>" for logged-in users, otherwise
+-- "browser:<random id>" from a cookie.
+CREATE TABLE IF NOT EXISTS alert_dismissals (
+  alert_id     INTEGER NOT NULL REFERENCES alert_events(id) ON DELETE CASCADE,
+  viewer       TEXT    NOT NULL,
+  dismissed_at TEXT    NOT NULL,
+  PRIMARY KEY (alert_id, viewer)
+) WITHOUT ROWID;
@@ -0,0 +1,75 @@
+// Package alerts holds alerting state shared by alert rules and offline
+// detection: silences, per-station and global maintenance windows during
+// which neither fires, and the alerts fired (events.AlertFired), shown as
+// banners on every page until each viewer dismisses them.
+package alerts
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/module"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Module serves the silences API and page, and records fired alerts and
+// serves their banners.
+type Module struct {
+	store  *Store
+	events *eventStore
+	fired  chan firedAlert
+	now    func() time.Time
+}
+
+func NewModule() *Module {
+	return &Module{fired: make(chan firedAlert, firedQueueSize), now: time.Now}
+}
+
+func (m *Module) Name() string { return "alerts" }
+
+func (m *Module) Migrations() fs.FS {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return sub
+}
+
+// RegisterRoutes subscribes to fired alerts and adds the silence and alert
+// routes.
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	m.store = NewStore(deps.DB)
+	m.events = &eventStore{db: deps.DB}
+	if deps.Bus != nil {
+		deps.Bus.Subscribe(events.AlertFired, m.onAlertFired)
+	}
+	c := &controller{store: m.store}
+	mux.HandleFunc("GET /admin/silences", c.handleSilencesPage)
+	mux.HandleFunc("POST /admin/silences/{id}/delete", c.handleDeleteSilenceForm)
+	mux.HandleFunc("GET /api/v1/silences", c.handleSilences)
+	mux.HandleFunc("POST /api/v1/silences", c.handleCreateSilence)
+	mux.HandleFunc("DELETE /api/v1/silences/{id}", c.handleDeleteSilence)
+	b := &bannerController{m: m}
+	mux.HandleFunc("GET /api/v1/alerts", b.handleAlerts)
+	mux.HandleFunc("POST /api/v1/alerts/{id}/dismiss", b.handleDismiss)
+	mux.HandleFunc("GET /partials/alerts", b.handleBannerPartial)
+	return nil
+}
+
+// StartWorkers starts storing fired alerts.
+func (m *Module) StartWorkers(ctx context.Context) error {
+	go m.storeFired(ctx)
+	return nil
+}
+
+// Silences returns the silence store, for alert rules and offline detection
+// to consult before firing. It is nil until RegisterRoutes has run.
+func (m *Module) Silences() *Store {
+	return m.store
+}
@@ -0,0 +1,173 @@
+package alerts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrSilenceNotFound is returned when a silence ID has no match.
+	ErrSilenceNotFound = errors.New("silence not found")
+	// ErrStationNotFound is returned when a silence names an unknown station.
+	ErrStationNotFound = errors.New("station not found")
+)
+
+// timeLayout has a fixed width so stored times compare correctly as text.
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+// Silence is a maintenance window during which alerts do not fire and
+// offline detection is suppressed, for one station or, when StationID is
+// empty, for all of them. It covers [Start, End).
+type Silence struct {
+	ID          string    `json:"id"`
+	StationID   string    `json:"stationId,omitempty"`
+	StationName string    `json:"stationName,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Active reports whether the silence covers t.
+func (s Silence) Active(t time.Time) bool {
+	return !t.Before(s.Start) && t.Before(s.End)
+}
+
+// Station is an entry in the silence form's station selector.
+type Station struct {
+	ID   string
+	Name string
+}
+
+// Store keeps silences in the server database.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// List returns the silences that have not ended by now, earliest first.
+func (s *Store) List(now time.Time) ([]Silence, error) {
+	rows, err := s.db.Query(`
+SELECT CAST(s.id AS TEXT), COALESCE(CAST(s.station_id AS TEXT), ''), COALESCE(st.name, ''),
+  s.starts_at, s.ends_at, s.reason, s.created_at
+FROM silences s
+LEFT JOIN stations st ON st.id = s.station_id
+WHERE s.ends_at > ?
+ORDER BY s.starts_at, s.id`, now.UTC().Format(timeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close silences rows", "error", err)
+		}
+	}()
+	var out []Silence
+	for rows.Next() {
+		var sl Silence
+		var start, end, created string
+		if err := rows.Scan(&sl.ID, &sl.StationID, &sl.StationName, &start, &end, &sl.Reason, &created); err != nil {
+			return nil, err
+		}
+		if sl.Start, err = time.Parse(time.RFC3339Nano, start); err != nil {
+			return nil, fmt.Errorf("parse starts_at: %w", err)
+		}
+		if sl.End, err = time.Parse(time.RFC3339Nano, end); err != nil {
+			return nil, fmt.Errorf("parse ends_at: %w", err)
+		}
+		if sl.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+			return nil, fmt.Errorf("parse created_at: %w", err)
+		}
+		out = append(out, sl)
+	}
+	return out, rows.Err()
+}
+
+// Create stores a silence and returns it with its ID. Times are truncated to
+// milliseconds.
+func (s *Store) Create(sl Silence) (Silence, error) {
+	var stationID any
+	if sl.StationID != "" {
+		id, err := strconv.Atoi(sl.StationID)
+		if err != nil {
+			return Silence{}, ErrStationNotFound
+		}
+		if err := s.db.QueryRow(`SELECT name FROM stations WHERE id = ?`, id).Scan(&sl.StationName); errors.Is(err, sql.ErrNoRows) {
+			return Silence{}, ErrStationNotFound
+		} else if err != nil {
+			return Silence{}, err
+		}
+		stationID = id
+	}
+	sl.Start = sl.Start.UTC().Truncate(time.Millisecond)
+	sl.End = sl.End.UTC().Truncate(time.Millisecond)
+	res, err := s.db.Exec(`INSERT INTO silences (station_id, starts_at, ends_at, reason) VALUES (?, ?, ?, ?)`,
+		stationID, sl.Start.Format(timeLayout), sl.End.Format(timeLayout), sl.Reason)
+	if err != nil {
+		return Silence{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Silence{}, err
+	}
+	sl.ID = strconv.FormatInt(id, 10)
+	sl.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	return sl, nil
+}
+
+// Delete removes a silence, ending it early if it is active.
+func (s *Store) Delete(id string) error {
+	res, err := s.db.Exec(`DELETE FROM silences WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSilenceNotFound
+	}
+	return nil
+}
+
+// Silenced reports whether alerts and offline detection for stationID are
+// suppressed at t, by a silence for that station or a global one.
+func (s *Store) Silenced(stationID string, t time.Time) (bool, error) {
+	ts := t.UTC().Format(timeLayout)
+	var n int
+	err := s.db.QueryRow(`
+SELECT COUNT(*) FROM silences
+WHERE starts_at <= ?1 AND ends_at > ?1
+  AND (station_id IS NULL OR CAST(station_id AS TEXT) = ?2)`, ts, stationID).Scan(&n)
+	return n > 0, err
+}
+
+// Stations returns the stations a silence can target, by name.
+func (s *Store) Stations() ([]Station, error) {
+	rows, err := s.db.Query(`SELECT CAST(id AS TEXT), name FROM stations WHERE archived_at IS NULL ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close stations rows", "error", err)
+		}
+	}()
+	var out []Station
+	for rows.Next() {
+		var st Station
+		if err := rows.Scan(&st.ID, &st.Name); err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix starts every API key, so keys are recognizable in bearer
+// tokens and in leaked text.
+const apiKeyPrefix = "cpk_"
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyNameUsed = errors.New("api key name taken")
+)
+
+// APIKey is a key for scripts and integrations, without the key itself.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// newAPIKey returns a random key.
+func newAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// hashAPIKey returns the stored form of key. Keys are random, so a plain
+// hash suffices where passwords need bcrypt.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// validateNewAPIKey checks the fields of a key to create.
+func validateNewAPIKey(name, role string) error {
+	if name == "" || len(name) > 64 {
+		return errors.New("'name' must be 1 to 64 characters")
+	}
+	if !validRole(role) {
+		return fmt.Errorf("'role' must be %q or %q", RoleViewer, RoleAdmin)
+	}
+	return nil
+}
+
+// createAPIKey stores a new key and returns it with the key itself, which is
+// not kept.
+func (s *store) createAPIKey(ctx context.Context, name, role string) (APIKey, string, error) {
+	key, err := newAPIKey()
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	// A taken name inserts no row instead of failing the UNIQUE constraint.
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO api_keys (name, key_hash, role, created_at)
+SELECT ?1, ?2, ?3, ?4
+WHERE NOT EXISTS (SELECT 1 FROM api_keys WHERE name = ?1)`,
+		name, hashAPIKey(key), role, now.Format(timeLayout))
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return APIKey{}, "", err
+	} else if n == 0 {
+		return APIKey{}, "", ErrAPIKeyNameUsed
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	return APIKey{ID: strconv.FormatInt(id, 10), Name: name, Role: role, CreatedAt: now}, key, nil
+}
+
+// apiKeyByKey returns the key whose hash matches key.
+func (s *store) apiKeyByKey(ctx context.Context, key string) (APIKey, error) {
+	keys, err := s.queryAPIKeys(ctx, `WHERE key_hash = ?`, hashAPIKey(key))
+	if err != nil {
+		return APIKey{}, err
+	}
+	if len(keys) == 0 {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return keys[0], nil
+}
+
+// listAPIKeys returns all keys by name.
+func (s *store) listAPIKeys(ctx context.Context) ([]APIKey, error) {
+	return s.queryAPIKeys(ctx, `ORDER BY name`)
+}
+
+func (s *store) queryAPIKeys(ctx context.Context, where string, args ...any) ([]APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, role, created_at FROM api_keys `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close api keys rows", "error", err)
+		}
+	}()
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		var id int64
+		var created string
+		if err := rows.Scan(&id, &k.Name, &k.Role, &created); err != nil {
+			return nil, err
+		}
+		k.ID = strconv.FormatInt(id, 10)
+		if k.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+			return nil, fmt.Errorf("parse created_at: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// deleteAPIKey revokes the key with id.
+func (s *store) deleteAPIKey(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// isAPIKey reports whether token has the form of an API key rather than a
+// login token.
+func isAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
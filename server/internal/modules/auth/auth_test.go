@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/scheduler"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testNow = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestServer returns the module migrated onto an in-memory database with
+// admin "root", enforcing roles in front of its routes and stub routes for
+// a page, a reading API (echoing the user) and a write.
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	m := NewModule(Options{
+		Enabled:       true,
+		Secret:        []byte("0123456789abcdef0123456789abcdef"),
+		TTL:           time.Hour,
+		AdminUsername: "root",
+		AdminPassword: "root-password",
+	})
+	m.now = func() time.Time { return testNow }
+	if err := module.Migrate(db, []module.Module{m}); err != nil {
+		t.Fatalf("Migrate() = %v; want nil", err)
+	}
+	mux := http.NewServeMux()
+	if err := m.RegisterRoutes(mux, module.Deps{DB: db, Scheduler: scheduler.New()}); err != nil {
+		t.Fatalf("RegisterRoutes() = %v; want nil", err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	mux.HandleFunc("GET /", ok)
+	mux.HandleFunc("GET /api/v1/stations", func(w http.ResponseWriter, r *http.Request) {
+		u, _ := UserFrom(r.Context())
+		_, _ = w.Write([]byte(u.Username))
+	})
+	mux.HandleFunc("POST /api/v1/stations", ok)
+	mux.HandleFunc("PUT /api/v1/favorites", ok)
+	mux.HandleFunc("POST /graphql", ok)
+	mux.HandleFunc("GET /status", ok)
+	return m.Handler(mux)
+}
+
+func do(h http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func login(t *testing.T, h http.Handler, username, password string) string {
+	t.Helper()
+	rec := do(h, http.MethodPost, "/api/v1/auth/login", "", `{"username":"`+username+`","password":"`+password+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login %s = %d %s; want 200", username, rec.Code, rec.Body)
+	}
+	var resp loginResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode login: %v", err)
+	}
+	if !resp.ExpiresAt.Equal(testNow.Add(time.Hour)) {
+		t.Errorf("expiresAt = %v; want %v", resp.ExpiresAt, testNow.Add(time.Hour))
+	}
+	return resp.Token
+}
+
+func TestTokenSigner(t *testing.T) {
+	s := tokenSigner{key: []byte("key")}
+	claims := Claims{Subject: "1", Username: "root", Role: RoleAdmin, IssuedAt: testNow.Unix(), ExpiresAt: testNow.Add(time.Hour).Unix()}
+	token, err := s.sign(claims)
+	if err != nil {
+		t.Fatalf("sign() = %v; want nil", err)
+	}
+	if got, err := s.parse(token, testNow); err != nil || got != claims {
+		t.Errorf("parse() = %+v, %v; want %+v", got, err, claims)
+	}
+
+	parts := strings.Split(token, ".")
+	admin := mustEncode(Claims{Subject: "2", Role: RoleAdmin, ExpiresAt: claims.ExpiresAt})
+	for name, tc := range map[string]struct {
+		token string
+		now   time.Time
+	}{
+		"expired":        {token, testNow.Add(time.Hour)},
+		"other key":      {mustSign(t, tokenSigner{key: []byte("other")}, claims), testNow},
+		"forged payload": {parts[0] + "." + admin + "." + parts[2], testNow},
+		"alg none":       {mustEncode(tokenHeader{Alg: "none"}) + "." + parts[1] + ".", testNow},
+		"malformed":      {"abc", testNow},
+	} {
+		if _, err := s.parse(tc.token, tc.now); err != errInvalidToken {
+			t.Errorf("%s: parse() = %v; want errInvalidToken", name, err)
+		}
+	}
+}
+
+func mustSign(t *testing.T, s tokenSigner, c Claims) string {
+	t.Helper()
+	token, err := s.sign(c)
+	if err != nil {
+		t.Fatalf("sign() = %v; want nil", err)
+	}
+	return token
+}
+
+func TestHandler_Roles(t *testing.T) {
+	h := newTestServer(t)
+	admin := login(t, h, "root", "root-password")
+	if rec := do(h, http.MethodPost, "/api/v1/admin/users", admin, `{"username":"guest","password":"guest-password"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create viewer = %d %s; want 201", rec.Code, rec.Body)
+	}
+	viewer := login(t, h, "guest", "guest-password")
+
+	for _, tc := range []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/status", "", http.StatusOK},
+		{http.MethodGet, "/readyz", "", http.StatusOK},
+		{http.MethodGet, "/version", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/stations", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/stations", "not-a-token", http.StatusUnauthorized},
+		{http.MethodGet, "/", "", http.StatusSeeOther},
+		{http.MethodGet, "/api/v1/stations", viewer, http.StatusOK},
+		{http.MethodPut, "/api/v1/favorites", viewer, http.StatusOK},
+		{http.MethodPost, "/graphql", viewer, http.StatusOK},
+		{http.MethodGet, "/graphql", "", http.StatusUnauthorized},
+		{http.MethodPost, "/api/v1/stations", viewer, http.StatusForbidden},
+		{http.MethodGet, "/api/v1/admin/users", viewer, http.StatusForbidden},
+		{http.MethodPost, "/api/v1/stations", admin, http.StatusOK},
+		{http.MethodGet, "/api/v1/admin/users", admin, http.StatusOK},
+		{http.MethodGet, "/debug/vars", viewer, http.StatusForbidden},
+		{http.MethodGet, "/debug/vars", admin, http.StatusOK},
+	} {
+		if rec := do(h, tc.method, tc.path, tc.token, ""); rec.Code != tc.want {
+			t.Errorf("%s %s (token %.8s) = %d %s; want %d", tc.method, tc.path, tc.token, rec.Code, rec.Body, tc.want)
+		}
+	}
+	if rec := do(h, http.MethodGet, "/api/v1/stations", viewer, ""); rec.Body.String() != "guest" {
+		t.Errorf("UserFrom() in the handler = %q; want guest", rec.Body)
+	}
+	if rec := do(h, http.MethodGet, "/?range=24h", "", ""); rec.Header().Get("Location") != "/login?next=%2F%3Frange%3D24h" {
+		t.Errorf("redirect = %q; want the login form returning to the page", rec.Header().Get("Location"))
+	}
+}
+
+func TestHandler_SessionCookie(t *testing.T) {
+	h := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("username=root&password=root-password&next=%2Fhistory"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/history" {
+		t.Fatalf("POST /login = %d to %q; want 303 to /history", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("cookies = %v; want the HttpOnly session cookie", cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/auth/me", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"username":"root"`) {
+		t.Errorf("GET /api/v1/auth/me = %d %s; want root", rec.Code, rec.Body)
+	}
+}
+
+func TestLoginForm_WrongPassword(t *testing.T) {
+	h := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("username=root&password=wrong"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "Invalid username or password") {
+		t.Errorf("POST /login = %d; want 401 with the form and an error", rec.Code)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Errorf("cookies = %v; want none", rec.Result().Cookies())
+	}
+}
+
+func TestUsers_DeleteRevokesTokens(t *testing.T) {
+	h := newTestServer(t)
+	admin := login(t, h, "root", "root-password")
+	rec := do(h, http.MethodPost, "/api/v1/admin/users", admin, `{"username":"ops","password":"ops-password","role":"admin"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create admin = %d %s; want 201", rec.Code, rec.Body)
+	}
+	var ops User
+	if err := json.NewDecoder(rec.Body).Decode(&ops); err != nil {
+		t.Fatalf("decode user: %v", err)
+	}
+	if rec := do(h, http.MethodPost, "/api/v1/admin/users", admin, `{"username":"ops","password":"ops-password"}`); rec.Code != http.StatusConflict {
+		t.Errorf("create duplicate = %d; want 409", rec.Code)
+	}
+	if rec := do(h, http.MethodPost, "/api/v1/admin/users", admin, `{"username":"x","password":"short"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("create with short password = %d; want 400", rec.Code)
+	}
+	token := login(t, h, "ops", "ops-password")
+	if rec := do(h, http.MethodDelete, "/api/v1/admin/users/"+ops.ID, admin, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete = %d %s; want 204", rec.Code, rec.Body)
+	}
+	if rec := do(h, http.MethodGet, "/api/v1/stations", token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request with a deleted user's token = %d; want 401", rec.Code)
+	}
+	if rec := do(h, http.MethodDelete, "/api/v1/admin/users/1", admin, ""); rec.Code != http.StatusConflict {
+		t.Errorf("delete last admin = %d %s; want 409", rec.Code, rec.Body)
+	}
+	if rec := do(h, http.MethodDelete, "/api/v1/admin/users/99", admin, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete unknown user = %d; want 404", rec.Code)
+	}
+}
+
+func TestHandler_Disabled(t *testing.T) {
+	m := NewModule(Options{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	if rec := do(m.Handler(next), http.MethodPost, "/api/v1/stations", "", ""); rec.Code != http.StatusOK {
+		t.Errorf("write without auth enabled = %d; want 200", rec.Code)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                    "/",
+		"/history?range=24h":  "/history?range=24h",
+		"https://example.com": "/",
+		"//example.com":       "/",
+		"/\\example.com":      "/",
+	} {
+		if got := localPath(in); got != want {
+			t.Errorf("localPath(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestAPIKeys_RolesAndAudit(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	h := newTestServer(t)
+	admin := login(t, h, "root", "root-password")
+	rec := do(h, http.MethodPost, "/api/v1/admin/api-keys", admin, `{"name":"grafana"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create key = %d %s; want 201", rec.Code, rec.Body)
+	}
+	var created createAPIKeyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("decode key: %v", err)
+	}
+	if created.Role != RoleViewer || !strings.HasPrefix(created.Key, apiKeyPrefix) {
+		t.Fatalf("created = %+v; want a viewer key starting with %s", created, apiKeyPrefix)
+	}
+	if rec := do(h, http.MethodGet, "/api/v1/admin/api-keys", admin, ""); strings.Contains(rec.Body.String(), created.Key) {
+		t.Errorf("key list = %s; want the key itself omitted", rec.Body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil)
+	req.Header.Set("X-API-Key", created.Key)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "grafana" {
+		t.Errorf("read with X-API-Key = %d %q; want 200 as grafana", rec.Code, rec.Body)
+	}
+
+	logs.Reset()
+	rec = do(h, http.MethodPost, "/api/v1/stations", created.Key, "")
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Content-Type") != "application/problem+json" ||
+		!strings.Contains(rec.Body.String(), `"detail":"the admin role is required"`) ||
+		!strings.Contains(rec.Body.String(), `"instance":"/api/v1/stations"`) {
+		t.Errorf("write with a viewer key = %d %s %s; want a 403 problem", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	for _, want := range []string{"audit: access denied", "path=/api/v1/stations", "username=grafana", "required_role=admin", `reason="insufficient role"`} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("audit log = %q; want %s", logs.String(), want)
+		}
+	}
+
+	if rec := do(h, http.MethodDelete, "/api/v1/admin/api-keys/"+created.ID, admin, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete key = %d %s; want 204", rec.Code, rec.Body)
+	}
+	logs.Reset()
+	if rec := do(h, http.MethodGet, "/api/v1/stations", created.Key, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("read with a deleted key = %d; want 401", rec.Code)
+	}
+	if !strings.Contains(logs.String(), `reason="invalid token"`) {
+		t.Errorf("audit log = %q; want the invalid key logged", logs.String())
+	}
+}
@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var loginTmpl = template.Must(template.ParseFS(templatesFS, "templates/login.html"))
+
+// loginPage is the data for templates/login.html.
+type loginPage struct {
+	Next     string
+	Username string
+	Error    string
+	User     *User // already logged in
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginResponse is returned by POST /api/v1/auth/login.
+type loginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      User      `json:"user"`
+}
+
+type createAPIKeyRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// createAPIKeyResponse is returned by POST /api/v1/admin/api-keys; Key is
+// shown only there.
+type createAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type createUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type controller struct {
+	m *Module
+}
+
+// login checks the credentials and returns a token, also set as the session
+// cookie. Wrong credentials return ErrUserNotFound.
+func (c *controller) login(w http.ResponseWriter, r *http.Request, username, password string) (loginResponse, error) {
+	u, err := c.m.store.authenticate(r.Context(), username, password)
+	if errors.Is(err, ErrUserNotFound) {
+		logging.FromContext(r.Context()).Warn("audit: login failed", "username", username, "remote_addr", r.RemoteAddr)
+	}
+	if err != nil {
+		return loginResponse{}, err
+	}
+	now := c.m.now()
+	expires := now.Add(c.m.opts.TTL).Truncate(time.Second)
+	token, err := c.m.tokens.sign(Claims{
+		Subject:   u.ID,
+		Username:  u.Username,
+		Role:      u.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expires.Unix(),
+	})
+	if err != nil {
+		return loginResponse{}, err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax keeps the cookie off cross-site form posts, so other sites
+		// cannot make writes on a logged-in admin's behalf.
+		SameSite: http.SameSiteLaxMode,
+	})
+	logging.FromContext(r.Context()).Info("user logged in", "username", u.Username, "role", u.Role)
+	return loginResponse{Token: token, ExpiresAt: expires.UTC(), User: u}, nil
+}
+
+func clearSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+}
+
+func (c *controller) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	resp, err := c.login(w, r, req.Username, req.Password)
+	if errors.Is(err, ErrUserNotFound) {
+		utils.WriteError(w, http.StatusUnauthorized, "invalid username or password")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("login failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to log in")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (c *controller) handleLogout(w http.ResponseWriter, r *http.Request) {
+	clearSession(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *controller) handleMe(w http.ResponseWriter, r *http.Request) {
+	u, err := c.m.authenticate(r)
+	if unauthenticated(err) {
+		utils.WriteError(w, http.StatusUnauthorized, "not logged in")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("authenticate request failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to authenticate")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, u)
+}
+
+func (c *controller) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	page := loginPage{Next: localPath(r.URL.Query().Get("next"))}
+	if u, err := c.m.authenticate(r); err == nil {
+		page.User = &u
+	}
+	c.renderLogin(r.Context(), w, http.StatusOK, page)
+}
+
+func (c *controller) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 4096)
+	page := loginPage{Next: localPath(r.PostFormValue("next")), Username: r.PostFormValue("username")}
+	_, err := c.login(w, r, page.Username, r.PostFormValue("password"))
+	if errors.Is(err, ErrUserNotFound) {
+		page.Error = "Invalid username or password."
+		c.renderLogin(r.Context(), w, http.StatusUnauthorized, page)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("login failed", "error", err)
+		page.Error = "Login failed; try again."
+		c.renderLogin(r.Context(), w, http.StatusInternalServerError, page)
+		return
+	}
+	http.Redirect(w, r, page.Next, http.StatusSeeOther)
+}
+
+func (c *controller) handleLogoutForm(w http.ResponseWriter, r *http.Request) {
+	clearSession(w)
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (c *controller) renderLogin(ctx context.Context, w http.ResponseWriter, status int, page loginPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := loginTmpl.Execute(w, page); err != nil {
+		logging.FromContext(ctx).Error("render login page failed", "error", err)
+	}
+}
+
+// localPath returns next if it is a path on this server, else "/", so the
+// login form cannot redirect elsewhere.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func (c *controller) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := c.m.store.list(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("list users failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to list users")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, users)
+}
+
+func (c *controller) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var req createUserRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Role == "" {
+		req.Role = RoleViewer
+	}
+	if err := validateNewUser(req.Username, req.Password, req.Role); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	u, err := c.m.store.create(r.Context(), req.Username, req.Password, req.Role)
+	if errors.Is(err, ErrUsernameTaken) {
+		utils.WriteError(w, http.StatusConflict, "username taken")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create user failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to create user")
+		return
+	}
+	logging.FromContext(r.Context()).Info("user created", "user_id", u.ID, "username", u.Username, "role", u.Role)
+	utils.WriteJSON(w, http.StatusCreated, u)
+}
+
+func (c *controller) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		utils.WriteError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	err := c.m.store.delete(r.Context(), id)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		utils.WriteError(w, http.StatusNotFound, "user not found")
+		return
+	case errors.Is(err, ErrLastAdmin):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("delete user failed", "user_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to delete user")
+		return
+	}
+	logging.FromContext(r.Context()).Info("user deleted", "user_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *controller) handleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := c.m.store.listAPIKeys(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("list api keys failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to list API keys")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, keys)
+}
+
+func (c *controller) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Role == "" {
+		req.Role = RoleViewer
+	}
+	if err := validateNewAPIKey(req.Name, req.Role); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	k, key, err := c.m.store.createAPIKey(r.Context(), req.Name, req.Role)
+	if errors.Is(err, ErrAPIKeyNameUsed) {
+		utils.WriteError(w, http.StatusConflict, "API key name taken")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create api key failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to create API key")
+		return
+	}
+	logging.FromContext(r.Context()).Info("api key created", "api_key_id", k.ID, "name", k.Name, "role", k.Role)
+	utils.WriteJSON(w, http.StatusCreated, createAPIKeyResponse{APIKey: k, Key: key})
+}
+
+func (c *controller) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		utils.WriteError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	err := c.m.store.deleteAPIKey(r.Context(), id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("delete api key failed", "api_key_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to delete API key")
+		return
+	}
+	logging.FromContext(r.Context()).Info("api key deleted", "api_key_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+// sessionCookie holds the login token of browsers.
+const sessionCookie = "cloudpico_session"
+
+type userKey struct{}
+
+// UserFrom returns the user Handler authenticated for the request of ctx. It
+// reports false on public routes and when auth is disabled.
+func UserFrom(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey{}).(User)
+	return u, ok
+}
+
+// Handler enforces the role each request requires (see requiredRole) before
+// passing it to next. Pages redirect anonymous browsers to the login form;
+// other requests get a 401 problem response, and callers without the role a
+// 403 one. Denied requests are audit logged, except page loads, and reads
+// without credentials. Without Options.Enabled it returns next.
+func (m *Module) Handler(next http.Handler) http.Handler {
+	if !m.opts.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := requiredRole(r)
+		if required == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u, err := m.authenticate(r)
+		if err != nil {
+			if !unauthenticated(err) {
+				logging.FromContext(r.Context()).Error("authenticate request failed", "error", err)
+				utils.WriteError(w, http.StatusInternalServerError, "failed to authenticate")
+				return
+			}
+			if isPage(r) {
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+			if errors.Is(err, errInvalidToken) || r.Method != http.MethodGet && r.Method != http.MethodHead {
+				auditDenied(r, User{}, required, err.Error())
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cloudpico"`)
+			utils.WriteProblem(w, http.StatusUnauthorized, "login or an API key required", r.URL.Path)
+			return
+		}
+		if !allows(u.Role, required) {
+			auditDenied(r, u, required, "insufficient role")
+			utils.WriteProblem(w, http.StatusForbidden, "the "+required+" role is required", r.URL.Path)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, u)))
+	})
+}
+
+// auditDenied logs a request refused by Handler; u is the zero User for
+// unauthenticated requests.
+func auditDenied(r *http.Request, u User, required, reason string) {
+	logging.FromContext(r.Context()).Warn("audit: access denied",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"remote_addr", r.RemoteAddr,
+		"user_id", u.ID,
+		"username", u.Username,
+		"role", u.Role,
+		"required_role", required,
+		"reason", reason,
+	)
+}
+
+// requiredRole returns the role r requires, or "" for public routes: the
+// login, probes and scrapers, static files, the public status page, and
+// webhooks, which check their own tokens. Admin pages and APIs and the
+// runtime debug endpoints require an admin, as does every write except the
+// viewer's own UI preferences and GraphQL queries, which only read.
+// Everything else requires a viewer.
+func requiredRole(r *http.Request) string {
+	p := r.URL.Path
+	switch {
+	case p == "/login", p == "/logout",
+		strings.HasPrefix(p, "/api/v1/auth/"),
+		p == "/healthz", p == "/readyz", p == "/metrics", p == "/version", p == "/api/v1/version",
+		p == "/status", p == "/api/v1/status",
+		strings.HasPrefix(p, "/static/"),
+		strings.HasPrefix(p, "/api/v1/webhooks/"):
+		return ""
+	case strings.HasPrefix(p, "/admin/"), strings.HasPrefix(p, "/api/v1/admin/"), strings.HasPrefix(p, "/debug/"):
+		return RoleAdmin
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		return RoleViewer
+	case isPreference(p), p == "/graphql":
+		return RoleViewer
+	default:
+		return RoleAdmin
+	}
+}
+
+// isPreference reports whether p is a favorites, dashboard card or alert
+// dismissal route.
+func isPreference(p string) bool {
+	return p == "/api/v1/favorites" ||
+		p == "/api/v1/dashboard/metrics" || strings.HasPrefix(p, "/api/v1/dashboard/metrics/") ||
+		strings.HasPrefix(p, "/api/v1/stations/") && strings.HasSuffix(p, "/favorite") ||
+		strings.HasPrefix(p, "/api/v1/alerts/") && strings.HasSuffix(p, "/dismiss")
+}
+
+// isPage reports whether r loads a page in a browser, which is better sent
+// to the login form than given a JSON error.
+func isPage(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		!strings.HasPrefix(r.URL.Path, "/api/") && !strings.HasPrefix(r.URL.Path, "/partials/") &&
+		r.URL.Path != "/graphql"
+}
+
+// errNoCredentials is returned by authenticate for requests without a token
+// or API key.
+var errNoCredentials = errors.New("no credentials")
+
+// unauthenticated reports whether err, from authenticate, means the request
+// has no valid credentials rather than that they could not be checked.
+func unauthenticated(err error) bool {
+	return errors.Is(err, errNoCredentials) || errors.Is(err, errInvalidToken)
+}
+
+// authenticate returns the caller of r, identified by a bearer token, an
+// X-API-Key header or the session cookie. Bearer tokens are login tokens or
+// API keys. Users are read back so deleted accounts are locked out and role
+// changes apply before their tokens expire. An API key authenticates as a
+// User with ID "key:<id>" named after the key.
+func (m *Module) authenticate(r *http.Request) (User, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.Header.Get("X-API-Key")
+	}
+	if token == "" {
+		c, err := r.Cookie(sessionCookie)
+		if err != nil {
+			return User{}, errNoCredentials
+		}
+		token = c.Value
+	}
+	token = strings.TrimSpace(token)
+	if isAPIKey(token) {
+		k, err := m.store.apiKeyByKey(r.Context(), token)
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return User{}, errInvalidToken
+		}
+		if err != nil {
+			return User{}, err
+		}
+		return User{ID: "key:" + k.ID, Username: k.Name, Role: k.Role, CreatedAt: k.CreatedAt}, nil
+	}
+	claims, err := m.tokens.parse(token, m.now())
+	if err != nil {
+		return User{}, err
+	}
+	u, err := m.store.get(r.Context(), claims.Subject)
+	if errors.Is(err, ErrUserNotFound) {
+		return User{}, errInvalidToken
+	}
+	return u, err
+}
@@ -0,0 +1,12 @@
+-- =========================
+-- users
+-- =========================
+-- Accounts that log in to the dashboard and the API. password_hash is a
+-- bcrypt hash; role is "viewer" (read only) or "admin" (everything).
+CREATE TABLE IF NOT EXISTS users (
+  id            INTEGER PRIMARY KEY,
+  username      TEXT    NOT NULL UNIQUE,
+  password_hash TEXT    NOT NULL,
+  role          TEXT    NOT NULL CHECK (role IN ('viewer', 'admin')),
+  created_at    TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
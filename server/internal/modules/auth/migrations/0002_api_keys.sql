@@ -0,0 +1,13 @@
+-- =========================
+-- api_keys
+-- =========================
+-- Keys for scripts and integrations, sent as X-API-Key (or a bearer token).
+-- Only the SHA-256 of a key is stored; the key itself is shown once, when it
+-- is created. role is "viewer" or "admin", as for users.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id         INTEGER PRIMARY KEY,
+  name       TEXT    NOT NULL UNIQUE,
+  key_hash   TEXT    NOT NULL UNIQUE,
+  role       TEXT    NOT NULL CHECK (role IN ('viewer', 'admin')),
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
@@ -0,0 +1,114 @@
+// Package auth adds user accounts and API keys to the server. Users log in
+// with a password and receive a JSON Web Token, sent back as a bearer token or
+// a session cookie; scripts send an API key. Handler then requires a login or
+// key for the dashboard and the API, and the admin role for admin pages and
+// every write. Accounts and keys are viewers (read only) or admins.
+package auth
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/module"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Options configures the module.
+type Options struct {
+	// Enabled makes Handler enforce logins and roles; otherwise every request
+	// passes, though users can still log in.
+	Enabled bool
+	// Secret signs login tokens; TTL is how long they stay valid.
+	Secret []byte
+	TTL    time.Duration
+	// AdminUsername and AdminPassword create an admin at startup when no
+	// user of that name exists. An empty username creates none.
+	AdminUsername string
+	AdminPassword string
+}
+
+// Module stores users, serves login and user management, and guards other
+// routes through Handler.
+type Module struct {
+	opts   Options
+	tokens tokenSigner
+	store  *store
+	now    func() time.Time
+}
+
+func NewModule(opts Options) *Module {
+	return &Module{opts: opts, tokens: tokenSigner{key: opts.Secret}, now: time.Now}
+}
+
+func (m *Module) Name() string { return "auth" }
+
+func (m *Module) Migrations() fs.FS {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return sub
+}
+
+// RegisterRoutes creates the configured admin and adds the login and user
+// routes.
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	m.store = &store{db: deps.DB}
+	if err := m.bootstrap(context.Background()); err != nil {
+		return err
+	}
+	c := &controller{m: m}
+	mux.HandleFunc("GET /login", c.handleLoginPage)
+	mux.HandleFunc("POST /login", c.handleLoginForm)
+	mux.HandleFunc("POST /logout", c.handleLogoutForm)
+	mux.HandleFunc("POST /api/v1/auth/login", c.handleLogin)
+	mux.HandleFunc("POST /api/v1/auth/logout", c.handleLogout)
+	mux.HandleFunc("GET /api/v1/auth/me", c.handleMe)
+	mux.HandleFunc("GET /api/v1/admin/users", c.handleListUsers)
+	mux.HandleFunc("POST /api/v1/admin/users", c.handleCreateUser)
+	mux.HandleFunc("DELETE /api/v1/admin/users/{id}", c.handleDeleteUser)
+	mux.HandleFunc("GET /api/v1/admin/api-keys", c.handleListAPIKeys)
+	mux.HandleFunc("POST /api/v1/admin/api-keys", c.handleCreateAPIKey)
+	mux.HandleFunc("DELETE /api/v1/admin/api-keys/{id}", c.handleDeleteAPIKey)
+	return nil
+}
+
+func (m *Module) StartWorkers(ctx context.Context) error { return nil }
+
+// bootstrap creates the configured admin and warns when nobody could log in.
+func (m *Module) bootstrap(ctx context.Context) error {
+	if m.opts.AdminUsername != "" {
+		exists, err := m.store.exists(ctx, m.opts.AdminUsername)
+		if err != nil {
+			return fmt.Errorf("look up admin user: %w", err)
+		}
+		if !exists {
+			if err := validateNewUser(m.opts.AdminUsername, m.opts.AdminPassword, RoleAdmin); err != nil {
+				return fmt.Errorf("invalid AUTH_ADMIN_USERNAME or AUTH_ADMIN_PASSWORD: %w", err)
+			}
+			if _, err := m.store.create(ctx, m.opts.AdminUsername, m.opts.AdminPassword, RoleAdmin); err != nil {
+				return fmt.Errorf("create admin user: %w", err)
+			}
+			slog.Info("admin user created", "username", m.opts.AdminUsername)
+		}
+	}
+	if !m.opts.Enabled {
+		slog.Warn("AUTH_ENABLED is off; the dashboard and API are open to anyone who can reach the server")
+		return nil
+	}
+	n, err := m.store.count(ctx)
+	if err != nil {
+		return fmt.Errorf("count users: %w", err)
+	}
+	if n == 0 {
+		slog.Warn("auth enabled without users; set AUTH_ADMIN_USERNAME and AUTH_ADMIN_PASSWORD to create an admin")
+	}
+	return nil
+}
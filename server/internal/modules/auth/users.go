@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles, from least to most privileged.
+const (
+	RoleViewer = "viewer"
+	RoleAdmin  = "admin"
+)
+
+// Password length bounds; bcrypt ignores bytes beyond 72.
+const (
+	minPasswordLen = 8
+	maxPasswordLen = 72
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username taken")
+	ErrLastAdmin     = errors.New("the last admin cannot be deleted")
+)
+
+// timeLayout formats users.created_at.
+const timeLayout = "2006-01-02T15:04:05.000Z"
+
+// User is an account, without its password.
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// validRole reports whether role is one of the roles.
+func validRole(role string) bool {
+	return role == RoleViewer || role == RoleAdmin
+}
+
+// allows reports whether role grants what required does; "" requires nothing.
+func allows(role, required string) bool {
+	switch required {
+	case "":
+		return true
+	case RoleViewer:
+		return validRole(role)
+	default:
+		return role == RoleAdmin
+	}
+}
+
+// validateNewUser checks the fields of a user to create.
+func validateNewUser(username, password, role string) error {
+	if username == "" || len(username) > 64 || strings.ContainsAny(username, " \t\r\n") {
+		return errors.New("'username' must be 1 to 64 characters without spaces")
+	}
+	if len(password) < minPasswordLen || len(password) > maxPasswordLen {
+		return fmt.Errorf("'password' must be %d to %d bytes", minPasswordLen, maxPasswordLen)
+	}
+	if !validRole(role) {
+		return fmt.Errorf("'role' must be %q or %q", RoleViewer, RoleAdmin)
+	}
+	return nil
+}
+
+// store keeps users in the server database.
+type store struct {
+	db *sql.DB
+}
+
+// create stores a user with a hash of password.
+func (s *store) create(ctx context.Context, username, password, role string) (User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	// A taken username inserts no row instead of failing the UNIQUE constraint.
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO users (username, password_hash, role, created_at)
+SELECT ?1, ?2, ?3, ?4
+WHERE NOT EXISTS (SELECT 1 FROM users WHERE username = ?1)`,
+		username, string(hash), role, now.Format(timeLayout))
+	if err != nil {
+		return User{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return User{}, err
+	} else if n == 0 {
+		return User{}, ErrUsernameTaken
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return User{}, err
+	}
+	return User{ID: strconv.FormatInt(id, 10), Username: username, Role: role, CreatedAt: now}, nil
+}
+
+// authenticate returns the user with username and password. Unknown users and
+// wrong passwords both return ErrUserNotFound.
+func (s *store) authenticate(ctx context.Context, username, password string) (User, error) {
+	var u User
+	var id int64
+	var hash, created string
+	err := s.db.QueryRowContext(ctx, `SELECT id, username, password_hash, role, created_at FROM users WHERE username = ?`,
+		username).Scan(&id, &u.Username, &hash, &u.Role, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Hash anyway so response times do not tell which usernames exist.
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return User{}, ErrUserNotFound
+	}
+	u.ID = strconv.FormatInt(id, 10)
+	if u.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+		return User{}, fmt.Errorf("parse created_at: %w", err)
+	}
+	return u, nil
+}
+
+// dummyHash is compared against for unknown usernames.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("cloudpico-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// get returns the user with id.
+func (s *store) get(ctx context.Context, id string) (User, error) {
+	users, err := s.query(ctx, `WHERE id = ?`, id)
+	if err != nil {
+		return User{}, err
+	}
+	if len(users) == 0 {
+		return User{}, ErrUserNotFound
+	}
+	return users[0], nil
+}
+
+// list returns all users by username.
+func (s *store) list(ctx context.Context) ([]User, error) {
+	return s.query(ctx, `ORDER BY username`)
+}
+
+func (s *store) query(ctx context.Context, where string, args ...any) ([]User, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, username, role, created_at FROM users `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close users rows", "error", err)
+		}
+	}()
+	users := []User{}
+	for rows.Next() {
+		var u User
+		var id int64
+		var created string
+		if err := rows.Scan(&id, &u.Username, &u.Role, &created); err != nil {
+			return nil, err
+		}
+		u.ID = strconv.FormatInt(id, 10)
+		if u.CreatedAt, err = time.Parse(time.RFC3339Nano, created); err != nil {
+			return nil, fmt.Errorf("parse created_at: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// exists reports whether a user named username exists.
+func (s *store) exists(ctx context.Context, username string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE username = ?`, username).Scan(&n)
+	return n > 0, err
+}
+
+// count returns the number of users.
+func (s *store) count(ctx context.Context) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+// delete removes the user with id. The last admin is kept, so someone can
+// always manage users.
+func (s *store) delete(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `
+DELETE FROM users
+WHERE id = ?1
+  AND (role <> 'admin' OR (SELECT COUNT(*) FROM users WHERE role = 'admin') > 1)`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	return ErrLastAdmin
+}
@@ -0,0 +1,50 @@
+package reports
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"net/http"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/scheduler"
+)
+
+// Module serves generated reports at /reports/ and schedules the generator.
+type Module struct {
+	generator *Generator
+	spec      string
+	sched     *scheduler.Scheduler
+}
+
+// NewModule returns the reports module; spec is the generator's cron schedule.
+func NewModule(generator *Generator, spec string) *Module {
+	return &Module{generator: generator, spec: spec}
+}
+
+func (m *Module) Name() string { return "reports" }
+
+func (m *Module) Migrations() fs.FS { return nil }
+
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	m.sched = deps.Scheduler
+	mux.Handle("GET /reports/", http.StripPrefix("/reports/", http.FileServer(http.Dir(m.generator.Dir))))
+	return nil
+}
+
+// StartWorkers registers the weekly-report job with the scheduler.
+func (m *Module) StartWorkers(ctx context.Context) error {
+	err := m.sched.Register(scheduler.Job{
+		Name: "weekly-report",
+		Spec: m.spec,
+		Run: func(ctx context.Context) error {
+			_, err := m.generator.Generate(ctx)
+			return err
+		},
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("reports registered", "dir", m.generator.Dir, "schedule", m.spec)
+	return nil
+}
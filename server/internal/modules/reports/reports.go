@@ -0,0 +1,174 @@
+// Package reports generates periodic HTML (and optionally PDF) station summaries.
+package reports
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var reportTmpl = template.Must(template.New("report.html").Funcs(template.FuncMap{
+	"value": formatValue,
+}).ParseFS(templatesFS, "templates/report.html"))
+
+func formatValue(v *float64) string {
+	if v == nil {
+		return "–"
+	}
+	return fmt.Sprintf("%.1f", *v)
+}
+
+// Source is the subset of the weather repository used to build reports.
+type Source interface {
+	GetStations(ctx context.Context) ([]types.Station, error)
+	GetSummary(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Summary, error)
+}
+
+// Group is a primary station together with its shadow stations.
+type Group struct {
+	Primary   types.Station
+	Summaries []StationSummary
+}
+
+// StationSummary pairs a station with its aggregates for the report period.
+type StationSummary struct {
+	Station types.Station
+	Summary types.Summary
+}
+
+// Report is the view model for report.html.
+type Report struct {
+	GeneratedAt time.Time
+	From        time.Time
+	To          time.Time
+	Groups      []Group
+}
+
+// Generator writes one report file per run into Dir.
+type Generator struct {
+	Source Source
+	Dir    string
+	Period time.Duration
+	// PDFCommand, if set, is run after the HTML is written; {html} and {pdf} are replaced with file paths.
+	PDFCommand string
+
+	now func() time.Time
+}
+
+func NewGenerator(source Source, dir string, period time.Duration, pdfCommand string) *Generator {
+	return &Generator{Source: source, Dir: dir, Period: period, PDFCommand: pdfCommand, now: time.Now}
+}
+
+// Generate builds a report covering the last Period and returns the path of the HTML file.
+func (g *Generator) Generate(ctx context.Context) (string, error) {
+	to := g.now().UTC()
+	from := to.Add(-g.Period)
+	report, err := g.build(ctx, from, to)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := reportTmpl.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("render report: %w", err)
+	}
+	if err := os.MkdirAll(g.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("create reports dir: %w", err)
+	}
+	htmlPath := filepath.Join(g.Dir, "report-"+to.Format("2006-01-02")+".html")
+	if err := os.WriteFile(htmlPath, buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("write report: %w", err)
+	}
+	slog.Info("report written", "path", htmlPath, "groups", len(report.Groups))
+
+	if g.PDFCommand != "" {
+		pdfPath := strings.TrimSuffix(htmlPath, ".html") + ".pdf"
+		if err := runPDFCommand(ctx, g.PDFCommand, htmlPath, pdfPath); err != nil {
+			return htmlPath, fmt.Errorf("render pdf: %w", err)
+		}
+		slog.Info("report pdf written", "path", pdfPath)
+	}
+	return htmlPath, nil
+}
+
+// build groups shadow stations under their primary and summarizes each station.
+func (g *Generator) build(ctx context.Context, from, to time.Time) (*Report, error) {
+	stations, err := g.Source.GetStations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get stations: %w", err)
+	}
+	byPrimary := make(map[string]*Group)
+	var order []string
+	for _, s := range stations {
+		if s.ShadowOf != "" {
+			continue
+		}
+		byPrimary[s.ID] = &Group{Primary: s}
+		order = append(order, s.ID)
+	}
+	for _, s := range stations {
+		key := s.ID
+		if s.ShadowOf != "" {
+			key = s.ShadowOf
+		}
+		group, ok := byPrimary[key]
+		if !ok {
+			// Orphaned shadow: report it on its own.
+			group = &Group{Primary: s}
+			byPrimary[key] = group
+			order = append(order, key)
+		}
+		sum, err := g.Source.GetSummary(ctx, s.ID, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("get summary for station %s: %w", s.ID, err)
+		}
+		group.Summaries = append(group.Summaries, StationSummary{Station: s, Summary: sum})
+	}
+
+	report := &Report{GeneratedAt: to, From: from, To: to}
+	for _, key := range order {
+		group := byPrimary[key]
+		// Primary first, then shadows by name.
+		sort.SliceStable(group.Summaries, func(i, j int) bool {
+			a, b := group.Summaries[i].Station, group.Summaries[j].Station
+			if (a.ShadowOf == "") != (b.ShadowOf == "") {
+				return a.ShadowOf == ""
+			}
+			return a.Name < b.Name
+		})
+		report.Groups = append(report.Groups, *group)
+	}
+	return report, nil
+}
+
+// runPDFCommand runs the configured headless renderer. The command is split on
+// whitespace; it is not passed through a shell.
+func runPDFCommand(ctx context.Context, command, htmlPath, pdfPath string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	for i, f := range fields {
+		f = strings.ReplaceAll(f, "{html}", htmlPath)
+		fields[i] = strings.ReplaceAll(f, "{pdf}", pdfPath)
+	}
+	out, err := exec.CommandContext(ctx, fields[0], fields[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", fields[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
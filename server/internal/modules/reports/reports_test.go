@@ -0,0 +1,110 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+type fakeSource struct {
+	stations   []types.Station
+	summaries  map[string]types.Summary
+	stationErr error
+}
+
+func (f *fakeSource) GetStations(ctx context.Context) ([]types.Station, error) {
+	return f.stations, f.stationErr
+}
+
+func (f *fakeSource) GetSummary(ctx context.Context, stationID string, from, to time.Time) (types.Summary, error) {
+	s := f.summaries[stationID]
+	s.StationID, s.From, s.To = stationID, from, to
+	return s, nil
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func newTestGenerator(t *testing.T, src Source) *Generator {
+	t.Helper()
+	g := NewGenerator(src, t.TempDir(), 7*24*time.Hour, "")
+	g.now = func() time.Time { return time.Date(2025, 2, 10, 6, 0, 0, 0, time.UTC) }
+	return g
+}
+
+func TestGenerator_Generate(t *testing.T) {
+	t.Run("writes html grouped by primary station", func(t *testing.T) {
+		src := &fakeSource{
+			stations: []types.Station{
+				{ID: "1", Name: "Garden"},
+				{ID: "2", Name: "Roof"},
+				{ID: "3", Name: "Garden spare", ShadowOf: "1"},
+			},
+			summaries: map[string]types.Summary{
+				"1": {Count: 4, Temperature: types.MetricSummary{Count: 4, Min: ptr(1), Avg: ptr(2.25), Max: ptr(4)}},
+			},
+		}
+		g := newTestGenerator(t, src)
+
+		path, err := g.Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generate() = %v; want nil", err)
+		}
+		if filepath.Base(path) != "report-2025-02-10.html" {
+			t.Errorf("path = %s; want report-2025-02-10.html", path)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read report: %v", err)
+		}
+		html := string(b)
+		for _, want := range []string{"<h2>Garden</h2>", "<h2>Roof</h2>", "Garden spare (shadow)", "1.0 / 2.2 / 4.0", "2025-02-03 06:00"} {
+			if !strings.Contains(html, want) {
+				t.Errorf("report missing %q", want)
+			}
+		}
+		if strings.Contains(html, "<h2>Garden spare</h2>") {
+			t.Error("shadow station rendered as its own group")
+		}
+	})
+
+	t.Run("returns station error", func(t *testing.T) {
+		src := &fakeSource{stationErr: errors.New("db locked")}
+		g := newTestGenerator(t, src)
+
+		if _, err := g.Generate(context.Background()); !errors.Is(err, src.stationErr) {
+			t.Errorf("Generate() = %v; want wrapped station error", err)
+		}
+	})
+
+	t.Run("runs pdf command with paths", func(t *testing.T) {
+		g := newTestGenerator(t, &fakeSource{})
+		g.PDFCommand = "cp {html} {pdf}"
+
+		path, err := g.Generate(context.Background())
+		if err != nil {
+			t.Fatalf("Generate() = %v; want nil", err)
+		}
+		if _, err := os.Stat(strings.TrimSuffix(path, ".html") + ".pdf"); err != nil {
+			t.Errorf("pdf not written: %v", err)
+		}
+	})
+
+	t.Run("reports pdf command failure", func(t *testing.T) {
+		g := newTestGenerator(t, &fakeSource{})
+		g.PDFCommand = "false"
+
+		path, err := g.Generate(context.Background())
+		if err == nil {
+			t.Fatal("Generate() = nil; want pdf error")
+		}
+		if _, statErr := os.Stat(path); statErr != nil {
+			t.Errorf("html should still exist: %v", statErr)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package status
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var statusTmpl = template.Must(template.New("status.html").Funcs(template.FuncMap{
+	"percent": func(a *float64) string {
+		if a == nil {
+			return "–"
+		}
+		return fmt.Sprintf("%.2f%%", *a*100)
+	},
+}).ParseFS(templatesFS, "templates/status.html"))
+
+// statusPage is the data for templates/status.html.
+type statusPage struct {
+	Status
+	Uptime   string
+	Stations []stationRow
+	Bars     []historyBar
+}
+
+// stationRow is one station of the status page.
+type stationRow struct {
+	Name        string
+	LastReading string // UTC time, or "" without readings
+	Age         string
+}
+
+// historyBar is one hour of the uptime sparkline.
+type historyBar struct {
+	HeightPct int
+	Class     string // "up", "degraded", "down" or "none" without checks
+	Label     string
+}
+
+type controller struct {
+	m *Module
+}
+
+func (c *controller) handleStatus(w http.ResponseWriter, r *http.Request) {
+	st, err := c.m.load(r.Context(), c.m.now())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("load status failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load status")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, st)
+}
+
+func (c *controller) handleStatusPage(w http.ResponseWriter, r *http.Request) {
+	now := c.m.now()
+	st, err := c.m.load(r.Context(), now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("load status failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load status")
+		return
+	}
+	page := statusPage{Status: st, Uptime: formatDuration(time.Duration(st.UptimeSeconds * float64(time.Second)))}
+	for _, s := range st.Stations {
+		row := stationRow{Name: s.Name, Age: "no readings"}
+		if s.LastReading != nil {
+			row.LastReading = s.LastReading.UTC().Format("2006-01-02 15:04:05") + " UTC"
+			row.Age = formatDuration(now.Sub(*s.LastReading)) + " ago"
+		}
+		page.Stations = append(page.Stations, row)
+	}
+	for _, h := range st.History {
+		page.Bars = append(page.Bars, newHistoryBar(h))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusTmpl.Execute(w, page); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render status page", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render status page")
+	}
+}
+
+// newHistoryBar draws h as a bar as high as its availability. Hours without
+// checks and fully failed hours keep a stub so they stay visible.
+func newHistoryBar(h HourStatus) historyBar {
+	label := h.Start.UTC().Format("2006-01-02 15:00") + " UTC: "
+	a := h.Availability()
+	switch {
+	case a == nil:
+		return historyBar{HeightPct: 10, Class: "none", Label: label + "no checks"}
+	case h.OK == h.Checks:
+		return historyBar{HeightPct: 100, Class: "up", Label: label + "100%"}
+	case h.OK == 0:
+		return historyBar{HeightPct: 10, Class: "down", Label: label + "0%"}
+	default:
+		return historyBar{
+			HeightPct: max(10, int(math.Round(*a*100))),
+			Class:     "degraded",
+			Label:     fmt.Sprintf("%s%.0f%%", label, *a*100),
+		}
+	}
+}
+
+// formatDuration renders d in its two largest units, e.g. "3d 4h" or "5m 12s".
+func formatDuration(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
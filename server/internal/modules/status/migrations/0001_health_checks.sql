@@ -0,0 +1,10 @@
+-- =========================
+-- health_checks
+-- =========================
+-- Results of the periodic "health-check" job, the uptime history of the
+-- status page. ok is 1 when the database answered and MQTT was connected. A
+-- server that is down records nothing, so its downtime shows as a gap.
+CREATE TABLE IF NOT EXISTS health_checks (
+  checked_at TEXT    NOT NULL PRIMARY KEY, -- RFC 3339, UTC
+  ok         INTEGER NOT NULL CHECK (ok IN (0, 1))
+) WITHOUT ROWID;
@@ -0,0 +1,118 @@
+// Package status serves a public summary of the server's health at /status:
+// version, uptime, ingest rate, the last reading of each station and the
+// uptime history recorded by the "health-check" job. It shows nothing an
+// operator would not want public: no errors, settings or admin links.
+package status
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-shared/buildinfo"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// checkSpec is how often the health check runs; historyRetention is how long
+// its results are kept.
+const (
+	checkSpec        = "@every 1m"
+	historyRetention = 30 * 24 * time.Hour
+)
+
+// Source is the subset of the weather repository the status page reads.
+type Source interface {
+	GetStations(ctx context.Context) ([]types.Station, error)
+	GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error)
+	GetReceivedCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error)
+}
+
+// connectedChecker is implemented by *mqtt.Subscriber.
+type connectedChecker interface {
+	Connected() bool
+}
+
+// Module records health checks and serves the status page and API.
+type Module struct {
+	build   buildinfo.Info
+	source  Source
+	started time.Time
+	now     func() time.Time
+
+	db    *sql.DB
+	store *store
+	mqtt  connectedChecker
+	sched *scheduler.Scheduler
+}
+
+// NewModule returns the status module of the running build, reading stations
+// from source.
+func NewModule(build buildinfo.Info, source Source) *Module {
+	return &Module{build: build, source: source, started: time.Now(), now: time.Now}
+}
+
+func (m *Module) Name() string { return "status" }
+
+func (m *Module) Migrations() fs.FS {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return sub
+}
+
+// RegisterRoutes adds the status routes.
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	m.db = deps.DB
+	m.store = &store{db: deps.DB}
+	if deps.Subscriber != nil {
+		m.mqtt = deps.Subscriber
+	}
+	m.sched = deps.Scheduler
+	c := &controller{m: m}
+	mux.HandleFunc("GET /status", c.handleStatusPage)
+	mux.HandleFunc("GET /api/v1/status", c.handleStatus)
+	return nil
+}
+
+// StartWorkers registers the health check job.
+func (m *Module) StartWorkers(ctx context.Context) error {
+	return m.sched.Register(scheduler.Job{
+		Name:       "health-check",
+		Spec:       checkSpec,
+		RunOnStart: true,
+		Run:        m.Check,
+	})
+}
+
+// Check records whether the database answers and MQTT is connected, and
+// drops results older than historyRetention.
+func (m *Module) Check(ctx context.Context) error {
+	now := m.now().UTC()
+	ok := true
+	var one int
+	if err := m.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one); err != nil {
+		// The result cannot be stored either: the gap in the history is the
+		// record of the outage.
+		return err
+	}
+	if m.mqtt != nil && !m.mqtt.Connected() {
+		ok = false
+	}
+	if err := m.store.record(ctx, now, ok); err != nil {
+		return err
+	}
+	if err := m.store.prune(ctx, now.Add(-historyRetention)); err != nil {
+		slog.Warn("prune health checks failed", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package status
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// historyHours is the span of the uptime history, in hourly buckets.
+const historyHours = 24
+
+// ingestWindow is the period the ingest rate is averaged over.
+const ingestWindow = time.Hour
+
+// Status is the public health summary of the server.
+type Status struct {
+	Version   string    `json:"version"`
+	StartedAt time.Time `json:"startedAt"`
+	// UptimeSeconds is how long the server process has been running.
+	UptimeSeconds float64 `json:"uptimeSeconds"`
+	// IngestPerMinute is the rate readings were received at over the last
+	// hour, all stations together.
+	IngestPerMinute float64         `json:"ingestPerMinute"`
+	Stations        []StationStatus `json:"stations"`
+	// Availability is the share of passing health checks over History, or
+	// nil before the first check.
+	Availability *float64 `json:"availability"`
+	// History is the last 24 hours of health checks, oldest first.
+	History []HourStatus `json:"history"`
+}
+
+// StationStatus is the freshness of one station's data.
+type StationStatus struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	LastReading *time.Time `json:"lastReading,omitempty"`
+}
+
+// HourStatus counts the health checks of one UTC hour.
+type HourStatus struct {
+	Start  time.Time `json:"start"`
+	Checks int       `json:"checks"`
+	OK     int       `json:"ok"`
+}
+
+// Availability is the share of passing checks, or nil without checks.
+func (h HourStatus) Availability() *float64 {
+	if h.Checks == 0 {
+		return nil
+	}
+	a := float64(h.OK) / float64(h.Checks)
+	return &a
+}
+
+// load assembles the status at now.
+func (m *Module) load(ctx context.Context, now time.Time) (Status, error) {
+	now = now.UTC()
+	st := Status{
+		Version:       m.build.Version,
+		StartedAt:     m.started.UTC(),
+		UptimeSeconds: now.Sub(m.started).Seconds(),
+		Stations:      []StationStatus{},
+	}
+	stations, err := m.source.GetStations(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("load stations: %w", err)
+	}
+	received := 0
+	for _, s := range stations {
+		ss := StationStatus{ID: s.ID, Name: s.Name}
+		latest, err := m.source.GetLatestReadings(ctx, s.ID, 1)
+		if err != nil {
+			return Status{}, fmt.Errorf("load latest reading of station %s: %w", s.ID, err)
+		}
+		if len(latest) > 0 {
+			ss.LastReading = &latest[0].Time
+		}
+		st.Stations = append(st.Stations, ss)
+		n, err := m.source.GetReceivedCount(ctx, s.ID, now.Add(-ingestWindow), now)
+		if err != nil {
+			return Status{}, fmt.Errorf("count readings of station %s: %w", s.ID, err)
+		}
+		received += n
+	}
+	st.IngestPerMinute = float64(received) / ingestWindow.Minutes()
+
+	from := now.Truncate(time.Hour).Add(-(historyHours - 1) * time.Hour)
+	st.History, err = m.store.history(ctx, from, historyHours)
+	if err != nil {
+		return Status{}, fmt.Errorf("load health checks: %w", err)
+	}
+	var checks, ok int
+	for _, h := range st.History {
+		checks += h.Checks
+		ok += h.OK
+	}
+	st.Availability = HourStatus{Checks: checks, OK: ok}.Availability()
+	return st, nil
+}
+
+// store keeps health check results in the server database.
+type store struct {
+	db *sql.DB
+}
+
+func (s *store) record(ctx context.Context, at time.Time, ok bool) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR REPLACE INTO health_checks (checked_at, ok) VALUES (?, ?)`,
+		at.UTC().Format(time.RFC3339), ok)
+	return err
+}
+
+// prune deletes the checks before cutoff.
+func (s *store) prune(ctx context.Context, cutoff time.Time) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM health_checks WHERE checked_at < ?`, cutoff.UTC().Format(time.RFC3339))
+	return err
+}
+
+// history counts the checks of hours UTC hours from from, every hour
+// included.
+func (s *store) history(ctx context.Context, from time.Time, hours int) ([]HourStatus, error) {
+	out := make([]HourStatus, hours)
+	for i := range out {
+		out[i].Start = from.Add(time.Duration(i) * time.Hour)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT substr(checked_at, 1, 13), COUNT(*), SUM(ok)
+FROM health_checks
+WHERE checked_at >= ? AND checked_at < ?
+GROUP BY 1`, from.UTC().Format(time.RFC3339), from.Add(time.Duration(hours)*time.Hour).UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close health check rows", "error", err)
+		}
+	}()
+	for rows.Next() {
+		var hour string
+		var h HourStatus
+		if err := rows.Scan(&hour, &h.Checks, &h.OK); err != nil {
+			return nil, err
+		}
+		start, err := time.Parse("2006-01-02T15", hour)
+		if err != nil {
+			return nil, err
+		}
+		i := int(start.Sub(from) / time.Hour)
+		if i < 0 || i >= hours {
+			continue
+		}
+		out[i].Checks, out[i].OK = h.Checks, h.OK
+	}
+	return out, rows.Err()
+}
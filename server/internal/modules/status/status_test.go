@@ -0,0 +1,161 @@
+package status
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-shared/buildinfo"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var now = time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+
+// fakeSource has two stations; only the first has readings.
+type fakeSource struct{}
+
+func (fakeSource) GetStations(ctx context.Context) ([]types.Station, error) {
+	return []types.Station{{ID: "1", Name: "Garden"}, {ID: "2", Name: "Attic"}}, nil
+}
+
+func (fakeSource) GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error) {
+	if stationID != "1" {
+		return nil, nil
+	}
+	return []types.Reading{{StationID: "1", Time: now.Add(-90 * time.Second)}}, nil
+}
+
+func (fakeSource) GetReceivedCount(ctx context.Context, stationID string, from, to time.Time) (int, error) {
+	if stationID != "1" {
+		return 0, nil
+	}
+	return 120, nil
+}
+
+type fakeMQTT struct{ connected bool }
+
+func (f *fakeMQTT) Connected() bool { return f.connected }
+
+// newTestModule returns the module migrated onto an in-memory database,
+// started 2 hours before now, and a mux serving its routes.
+func newTestModule(t *testing.T) (*Module, *http.ServeMux) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	m := NewModule(buildinfo.Info{Version: "1.2.3", Commit: "abc"}, fakeSource{})
+	m.started = now.Add(-2 * time.Hour)
+	m.now = func() time.Time { return now }
+	if err := module.Migrate(db, []module.Module{m}); err != nil {
+		t.Fatalf("Migrate() = %v; want nil", err)
+	}
+	mux := http.NewServeMux()
+	if err := m.RegisterRoutes(mux, module.Deps{DB: db, Scheduler: scheduler.New()}); err != nil {
+		t.Fatalf("RegisterRoutes() = %v; want nil", err)
+	}
+	return m, mux
+}
+
+func TestCheck_History(t *testing.T) {
+	m, mux := newTestModule(t)
+	mqtt := &fakeMQTT{connected: true}
+	m.mqtt = mqtt
+	check := func(at time.Time, connected bool) {
+		t.Helper()
+		m.now = func() time.Time { return at }
+		mqtt.connected = connected
+		if err := m.Check(context.Background()); err != nil {
+			t.Fatalf("Check() = %v; want nil", err)
+		}
+	}
+	check(now.Add(-40*24*time.Hour), true) // pruned by the later checks
+	check(now.Add(-90*time.Minute), true)  // 11:00 hour
+	check(now.Add(-80*time.Minute), false) // 11:00 hour, MQTT down
+	check(now.Add(-10*time.Minute), true)  // 12:00 hour
+	m.now = func() time.Time { return now }
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", rec.Code)
+	}
+	var got Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Version != "1.2.3" || got.UptimeSeconds != 7200 || got.IngestPerMinute != 2 {
+		t.Errorf("status = %+v; want version 1.2.3, 2h uptime, 2 readings/min", got)
+	}
+	if len(got.Stations) != 2 || got.Stations[0].LastReading == nil || got.Stations[1].LastReading != nil {
+		t.Errorf("stations = %+v; want Garden with a reading and Attic without", got.Stations)
+	}
+	if len(got.History) != historyHours {
+		t.Fatalf("history has %d hours; want %d", len(got.History), historyHours)
+	}
+	last, prev := got.History[historyHours-1], got.History[historyHours-2]
+	if !last.Start.Equal(now.Truncate(time.Hour)) || last.Checks != 1 || last.OK != 1 || prev.Checks != 2 || prev.OK != 1 {
+		t.Errorf("last hours = %+v, %+v; want 12:00 1/1 and 11:00 1/2", prev, last)
+	}
+	if got.Availability == nil || *got.Availability != 2.0/3 {
+		t.Errorf("availability = %v; want 2/3", got.Availability)
+	}
+	var old int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM health_checks WHERE checked_at < '2025-02-01'`).Scan(&old); err != nil || old != 0 {
+		t.Errorf("checks older than the retention = %d, %v; want 0", old, err)
+	}
+}
+
+func TestStatusPage(t *testing.T) {
+	m, mux := newTestModule(t)
+	if err := m.Check(context.Background()); err != nil {
+		t.Fatalf("Check() = %v; want nil", err)
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"1.2.3", "2h 0m", "2.0 readings/min", "100.00%", "Garden", "1m 30s ago", "no readings", "status-bar-up"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if strings.Count(body, `class="status-bar `) != historyHours {
+		t.Errorf("page has %d bars; want %d", strings.Count(body, `class="status-bar `), historyHours)
+	}
+	if strings.Contains(body, "abc") || strings.Contains(body, "/admin") {
+		t.Error("page shows admin details")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		-time.Second:                   "0s",
+		42 * time.Second:               "42s",
+		5*time.Minute + 12*time.Second: "5m 12s",
+		3*time.Hour + 5*time.Minute:    "3h 5m",
+		76*time.Hour + 30*time.Minute:  "3d 4h",
+	} {
+		if got := formatDuration(d); got != want {
+			t.Errorf("formatDuration(%v) = %q; want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package uploads
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"time"
+)
+
+// cwopTimeout bounds one APRS-IS session: connect, login and send.
+const cwopTimeout = 30 * time.Second
+
+// cwop uploads observations to CWOP over APRS-IS: it logs in with the
+// callsign and passcode, sends one APRS positioned weather report and closes
+// the connection.
+type cwop struct {
+	dest Destination
+}
+
+func (u *cwop) upload(ctx context.Context, obs observation) error {
+	ctx, cancel := context.WithTimeout(ctx, cwopTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", u.dest.Server)
+	if err != nil {
+		return fmt.Errorf("cwop connect: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	r := bufio.NewReader(conn)
+	// The server greets with a "# <software>" banner before accepting a login.
+	if _, err := r.ReadString('\n'); err != nil {
+		return fmt.Errorf("cwop banner: %w", err)
+	}
+	if _, err := fmt.Fprintf(conn, "user %s pass %s vers cloudpico 1.0\r\n", u.dest.Callsign, u.dest.Passcode); err != nil {
+		return fmt.Errorf("cwop login: %w", err)
+	}
+	resp, err := r.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("cwop login: %w", err)
+	}
+	if !strings.HasPrefix(resp, "# logresp") {
+		return fmt.Errorf("cwop login: unexpected response %q", strings.TrimSpace(resp))
+	}
+	if _, err := fmt.Fprintf(conn, "%s\r\n", aprsWeatherPacket(u.dest, obs)); err != nil {
+		return fmt.Errorf("cwop send: %w", err)
+	}
+	return nil
+}
+
+// aprsWeatherPacket formats obs as an APRS positioned weather report with a
+// timestamp, e.g.
+//
+//	EW1234>APRS,TCPIP*:@011200z3746.20N/12225.20W_.../...g...t065h55b10132cloudpico
+//
+// Wind is not measured and sent as unknown ("..."), like any missing value.
+func aprsWeatherPacket(d Destination, obs observation) string {
+	temp, hum, baro := "...", "..", "....."
+	if obs.Temperature != nil {
+		f := int(math.Round(celsiusToFahrenheit(*obs.Temperature)))
+		f = max(-99, min(999, f))
+		if f < 0 {
+			temp = fmt.Sprintf("-%02d", -f)
+		} else {
+			temp = fmt.Sprintf("%03d", f)
+		}
+	}
+	if obs.Humidity != nil {
+		h := int(math.Round(*obs.Humidity))
+		h = max(1, min(100, h))
+		hum = fmt.Sprintf("%02d", h%100) // 100 % is sent as "00"
+	}
+	if obs.Pressure != nil {
+		baro = fmt.Sprintf("%05d", int(math.Round(*obs.Pressure*10)))
+	}
+	return fmt.Sprintf("%s>APRS,TCPIP*:@%sz%s/%s_.../...g...t%sh%sb%scloudpico",
+		d.Callsign,
+		obs.Time.UTC().Format("021504"),
+		aprsCoordinate(d.Latitude, 2, "N", "S"),
+		aprsCoordinate(d.Longitude, 3, "E", "W"),
+		temp, hum, baro,
+	)
+}
+
+// aprsCoordinate formats v in degrees as degrees (degDigits wide), minutes
+// with two decimals and a hemisphere letter, e.g. "3746.20N".
+func aprsCoordinate(v float64, degDigits int, pos, neg string) string {
+	hemi := pos
+	if v < 0 {
+		hemi, v = neg, -v
+	}
+	// Round to hundredths of a minute first so 59.999' carries into degrees.
+	hundredths := int(math.Round(v * 6000))
+	return fmt.Sprintf("%0*d%02d.%02d%s", degDigits, hundredths/6000, hundredths%6000/100, hundredths%100, hemi)
+}
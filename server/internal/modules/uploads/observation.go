@@ -0,0 +1,46 @@
+package uploads
+
+import (
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// maxReadingAge is the oldest metric value that is still uploaded; networks
+// treat an upload as a current observation.
+const maxReadingAge = 15 * time.Minute
+
+// observation is the set of values sent in one upload. Nil values are unknown.
+type observation struct {
+	Time        time.Time // of the newest value
+	Temperature *float64  // °C
+	Humidity    *float64  // %
+	Pressure    *float64  // hPa, reduced to sea level
+}
+
+// newObservation builds an observation from the metrics no older than
+// maxReadingAge at now. ok is false when none is.
+func newObservation(m types.LatestMetrics, now time.Time, altitudeM float64) (obs observation, ok bool) {
+	fresh := func(lm *types.LatestMetric) *float64 {
+		if lm == nil || now.Sub(lm.Time) > maxReadingAge {
+			return nil
+		}
+		if lm.Time.After(obs.Time) {
+			obs.Time = lm.Time
+		}
+		v := lm.Value
+		return &v
+	}
+	obs.Temperature = fresh(m.Temperature)
+	obs.Humidity = fresh(m.Humidity)
+	obs.Pressure = fresh(m.Pressure)
+	if obs.Pressure != nil {
+		t := 15.0 // standard atmosphere when the temperature is unknown
+		if obs.Temperature != nil {
+			t = *obs.Temperature
+		}
+		p := seaLevelPressure(*obs.Pressure, altitudeM, t)
+		obs.Pressure = &p
+	}
+	return obs, obs.Temperature != nil || obs.Humidity != nil || obs.Pressure != nil
+}
@@ -0,0 +1,175 @@
+package uploads
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-server/internal/utils"
+)
+
+// Source is the subset of the weather repository uploads read from.
+type Source interface {
+	GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error)
+}
+
+type uploader interface {
+	upload(ctx context.Context, obs observation) error
+}
+
+// Status is the upload state of one destination. Credentials are never
+// included.
+type Status struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	StationID string `json:"stationId"`
+	Schedule  string `json:"schedule"`
+	Uploads   int    `json:"uploads"`
+	Failures  int    `json:"failures"`
+	Skipped   int    `json:"skipped"`
+	// LastReading is the time of the newest value uploaded; a run without a
+	// newer one is skipped.
+	LastReading *time.Time `json:"lastReading,omitempty"`
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	// SkipReason explains the last run when it was skipped.
+	SkipReason string `json:"skipReason,omitempty"`
+}
+
+// Module uploads readings to the configured destinations and serves their
+// status at GET /api/v1/admin/uploads.
+type Module struct {
+	dests     []Destination
+	source    Source
+	uploaders map[string]uploader
+	sched     *scheduler.Scheduler
+	now       func() time.Time
+
+	mu     sync.Mutex
+	status map[string]*Status
+}
+
+// NewModule returns the uploads module for dests, reading from source.
+func NewModule(dests []Destination, source Source) *Module {
+	m := &Module{
+		dests:     dests,
+		source:    source,
+		uploaders: make(map[string]uploader, len(dests)),
+		now:       time.Now,
+		status:    make(map[string]*Status, len(dests)),
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	for _, d := range dests {
+		switch d.Type {
+		case TypeWunderground:
+			m.uploaders[d.Name] = &wunderground{dest: d, url: wundergroundURL, client: client}
+		case TypeCWOP:
+			m.uploaders[d.Name] = &cwop{dest: d}
+		}
+		m.status[d.Name] = &Status{Name: d.Name, Type: d.Type, StationID: d.StationID, Schedule: d.Schedule}
+	}
+	return m
+}
+
+func (m *Module) Name() string { return "uploads" }
+
+func (m *Module) Migrations() fs.FS { return nil }
+
+// RegisterRoutes adds the upload status route.
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	m.sched = deps.Scheduler
+	mux.HandleFunc("GET /api/v1/admin/uploads", m.handleStatus)
+	return nil
+}
+
+// StartWorkers registers one "upload-<name>" job per destination.
+func (m *Module) StartWorkers(ctx context.Context) error {
+	for _, d := range m.dests {
+		err := m.sched.Register(scheduler.Job{
+			Name: "upload-" + d.Name,
+			Spec: d.Schedule,
+			Run: func(ctx context.Context) error {
+				return m.run(ctx, d)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("upload registered", "name", d.Name, "type", d.Type, "station_id", d.StationID, "schedule", d.Schedule)
+	}
+	return nil
+}
+
+// run uploads the latest observation of d's station unless it has no fresh
+// or new values.
+func (m *Module) run(ctx context.Context, d Destination) error {
+	now := m.now().UTC()
+	metrics, err := m.source.GetLatestMetrics(ctx, d.StationID)
+	if err != nil {
+		return fmt.Errorf("load latest metrics: %w", err)
+	}
+	obs, ok := newObservation(metrics, now, d.AltitudeM)
+	if !ok {
+		m.skip(d.Name, fmt.Sprintf("no reading in the last %s", maxReadingAge))
+		return nil
+	}
+	m.mu.Lock()
+	last := m.status[d.Name].LastReading
+	m.mu.Unlock()
+	if last != nil && !obs.Time.After(*last) {
+		m.skip(d.Name, "no new reading")
+		return nil
+	}
+
+	err = m.uploaders[d.Name].upload(ctx, obs)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.status[d.Name]
+	st.LastAttempt = &now
+	st.SkipReason = ""
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+		slog.Warn("upload failed", "name", d.Name, "type", d.Type, "station_id", d.StationID, "error", err)
+		return err
+	}
+	st.Uploads++
+	st.LastError = ""
+	st.LastSuccess = &now
+	st.LastReading = &obs.Time
+	slog.Debug("upload sent", "name", d.Name, "type", d.Type, "station_id", d.StationID, "reading_time", obs.Time)
+	return nil
+}
+
+func (m *Module) skip(name, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.status[name]
+	st.Skipped++
+	st.SkipReason = reason
+	slog.Debug("upload skipped", "name", name, "reason", reason)
+}
+
+// Statuses returns the status of every destination, in configuration order.
+func (m *Module) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Status, 0, len(m.dests))
+	for _, d := range m.dests {
+		out = append(out, *m.status[d.Name])
+	}
+	return out
+}
+
+func (m *Module) handleStatus(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, m.Statuses())
+}
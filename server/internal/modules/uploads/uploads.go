@@ -0,0 +1,192 @@
+// Package uploads posts the latest readings of selected stations to public
+// weather networks: Weather Underground personal weather stations and the
+// Citizen Weather Observer Program (CWOP) over APRS-IS.
+//
+// Each destination is described by a Destination loaded from a JSON file and
+// runs as a scheduler job named "upload-<name>".
+package uploads
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"cloudpico-server/internal/scheduler"
+)
+
+// Destination types.
+const (
+	TypeWunderground = "wunderground"
+	TypeCWOP         = "cwop"
+)
+
+// Default schedules follow each network's guidance: Weather Underground
+// accepts regular updates every few minutes, CWOP asks for at most one
+// packet every 5 minutes and recommends 10.
+var defaultSchedules = map[string]string{
+	TypeWunderground: "@every 5m",
+	TypeCWOP:         "@every 10m",
+}
+
+const defaultCWOPServer = "cwop.aprs.net:14580"
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Destination configures the upload of one station to one network.
+type Destination struct {
+	Name      string
+	Type      string
+	StationID string
+	// Schedule is a scheduler spec, e.g. "@every 5m".
+	Schedule string
+	// AltitudeM is the station's altitude in metres; when set, pressure is
+	// reduced to sea level as both networks expect.
+	AltitudeM float64
+
+	// Weather Underground station ID and key.
+	ID  string
+	Key string
+
+	// CWOP callsign (or CW/DW registration), APRS-IS passcode ("-1" for
+	// CWOP-only stations), position and server.
+	Callsign  string
+	Passcode  string
+	Latitude  float64
+	Longitude float64
+	Server    string
+}
+
+type destinationJSON struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	StationID string   `json:"station_id"`
+	Schedule  string   `json:"schedule"`
+	AltitudeM float64  `json:"altitude_m"`
+	ID        string   `json:"id"`
+	Key       string   `json:"key"`
+	Callsign  string   `json:"callsign"`
+	Passcode  string   `json:"passcode"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Server    string   `json:"server"`
+}
+
+// LoadDestinations reads upload destinations from a JSON file; see
+// ParseDestinations.
+func LoadDestinations(path string) ([]Destination, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read uploads file: %w", err)
+	}
+	dests, err := ParseDestinations(data)
+	if err != nil {
+		return nil, fmt.Errorf("uploads file %s: %w", path, err)
+	}
+	return dests, nil
+}
+
+// ParseDestinations parses a JSON array of destinations:
+//
+//	[{"name": "wu-garden", "type": "wunderground", "station_id": "1",
+//	  "id": "KCASANFR123", "key": "…", "altitude_m": 120},
+//	 {"name": "cwop-garden", "type": "cwop", "station_id": "1",
+//	  "callsign": "EW1234", "latitude": 37.77, "longitude": -122.42,
+//	  "schedule": "@every 10m"}]
+//
+// schedule defaults to every 5 minutes for Weather Underground and every 10
+// for CWOP; passcode defaults to "-1" and server to cwop.aprs.net:14580.
+func ParseDestinations(data []byte) ([]Destination, error) {
+	var raw []destinationJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse uploads: %w", err)
+	}
+	dests := make([]Destination, 0, len(raw))
+	names := make(map[string]bool, len(raw))
+	for i, r := range raw {
+		d, err := r.destination()
+		if err != nil {
+			return nil, fmt.Errorf("upload %d (%s): %w", i, r.Name, err)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("upload %d: duplicate name %q", i, d.Name)
+		}
+		names[d.Name] = true
+		dests = append(dests, d)
+	}
+	return dests, nil
+}
+
+func (r destinationJSON) destination() (Destination, error) {
+	d := Destination{
+		Name:      strings.TrimSpace(r.Name),
+		Type:      strings.TrimSpace(r.Type),
+		StationID: strings.TrimSpace(r.StationID),
+		Schedule:  strings.TrimSpace(r.Schedule),
+		AltitudeM: r.AltitudeM,
+		ID:        strings.TrimSpace(r.ID),
+		Key:       strings.TrimSpace(r.Key),
+		Callsign:  strings.ToUpper(strings.TrimSpace(r.Callsign)),
+		Passcode:  strings.TrimSpace(r.Passcode),
+		Server:    strings.TrimSpace(r.Server),
+	}
+	if !validName.MatchString(d.Name) {
+		return Destination{}, fmt.Errorf("name must be lowercase letters, digits, '-' or '_'")
+	}
+	if d.StationID == "" {
+		return Destination{}, fmt.Errorf("station_id is required")
+	}
+	if math.IsNaN(d.AltitudeM) || d.AltitudeM < -500 || d.AltitudeM > 9000 {
+		return Destination{}, fmt.Errorf("altitude_m %v out of range", d.AltitudeM)
+	}
+	def, ok := defaultSchedules[d.Type]
+	if !ok {
+		return Destination{}, fmt.Errorf("unknown type %q (allowed: %s, %s)", d.Type, TypeWunderground, TypeCWOP)
+	}
+	if d.Schedule == "" {
+		d.Schedule = def
+	}
+	if _, err := scheduler.ParseSpec(d.Schedule); err != nil {
+		return Destination{}, fmt.Errorf("schedule: %w", err)
+	}
+
+	switch d.Type {
+	case TypeWunderground:
+		if d.ID == "" || d.Key == "" {
+			return Destination{}, fmt.Errorf("id and key are required")
+		}
+	case TypeCWOP:
+		if d.Callsign == "" || strings.ContainsAny(d.Callsign, " >:,") {
+			return Destination{}, fmt.Errorf("callsign is required and must not contain spaces or '>:,'")
+		}
+		if r.Latitude == nil || r.Longitude == nil {
+			return Destination{}, fmt.Errorf("latitude and longitude are required")
+		}
+		d.Latitude, d.Longitude = *r.Latitude, *r.Longitude
+		if d.Latitude < -90 || d.Latitude > 90 || d.Longitude < -180 || d.Longitude > 180 {
+			return Destination{}, fmt.Errorf("latitude/longitude out of range")
+		}
+		if d.Passcode == "" {
+			d.Passcode = "-1"
+		}
+		if d.Server == "" {
+			d.Server = defaultCWOPServer
+		}
+	}
+	return d, nil
+}
+
+// seaLevelPressure reduces station pressure p (hPa) at altitude h (m) to sea
+// level with the hypsometric formula, using temperature t (°C).
+func seaLevelPressure(p, h, t float64) float64 {
+	if h == 0 {
+		return p
+	}
+	return p * math.Pow(1-0.0065*h/(t+0.0065*h+273.15), -5.257)
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
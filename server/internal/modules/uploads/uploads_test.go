@@ -0,0 +1,279 @@
+package uploads
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestParseDestinations(t *testing.T) {
+	t.Run("applies defaults", func(t *testing.T) {
+		dests, err := ParseDestinations([]byte(`[
+			{"name": "wu", "type": "wunderground", "station_id": "1", "id": "KX1", "key": "secret"},
+			{"name": "cw", "type": "cwop", "station_id": "1", "callsign": "ew1234", "latitude": 37.77, "longitude": -122.42, "schedule": "@every 15m"}
+		]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dests) != 2 {
+			t.Fatalf("got %d destinations", len(dests))
+		}
+		if dests[0].Schedule != "@every 5m" {
+			t.Errorf("wunderground schedule = %q", dests[0].Schedule)
+		}
+		cw := dests[1]
+		if cw.Schedule != "@every 15m" || cw.Callsign != "EW1234" || cw.Passcode != "-1" || cw.Server != defaultCWOPServer {
+			t.Errorf("cwop destination = %+v", cw)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		json string
+		want string
+	}{
+		"bad name":        {`[{"name": "Bad Name", "type": "cwop"}]`, "name must be"},
+		"no station":      {`[{"name": "a", "type": "wunderground", "id": "K", "key": "k"}]`, "station_id is required"},
+		"unknown type":    {`[{"name": "a", "type": "aprs", "station_id": "1"}]`, `unknown type "aprs"`},
+		"bad schedule":    {`[{"name": "a", "type": "wunderground", "station_id": "1", "id": "K", "key": "k", "schedule": "often"}]`, "schedule"},
+		"no key":          {`[{"name": "a", "type": "wunderground", "station_id": "1", "id": "K"}]`, "id and key are required"},
+		"no callsign":     {`[{"name": "a", "type": "cwop", "station_id": "1", "latitude": 1, "longitude": 1}]`, "callsign is required"},
+		"no position":     {`[{"name": "a", "type": "cwop", "station_id": "1", "callsign": "EW1"}]`, "latitude and longitude are required"},
+		"bad latitude":    {`[{"name": "a", "type": "cwop", "station_id": "1", "callsign": "EW1", "latitude": 91, "longitude": 0}]`, "out of range"},
+		"bad altitude":    {`[{"name": "a", "type": "wunderground", "station_id": "1", "id": "K", "key": "k", "altitude_m": 10000}]`, "altitude_m"},
+		"duplicate names": {`[{"name": "a", "type": "wunderground", "station_id": "1", "id": "K", "key": "k"}, {"name": "a", "type": "wunderground", "station_id": "2", "id": "K", "key": "k"}]`, "duplicate name"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseDestinations([]byte(tc.json))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewObservation(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m := types.LatestMetrics{
+		StationID:   "1",
+		Temperature: &types.LatestMetric{Value: 15, Time: now.Add(-time.Minute)},
+		Humidity:    &types.LatestMetric{Value: 60, Time: now.Add(-time.Hour)},
+		Pressure:    &types.LatestMetric{Value: 1000, Time: now.Add(-2 * time.Minute)},
+	}
+
+	obs, ok := newObservation(m, now, 0)
+	if !ok {
+		t.Fatal("expected an observation")
+	}
+	if !obs.Time.Equal(now.Add(-time.Minute)) {
+		t.Errorf("time = %v", obs.Time)
+	}
+	if obs.Humidity != nil {
+		t.Errorf("stale humidity kept: %v", *obs.Humidity)
+	}
+	if obs.Pressure == nil || *obs.Pressure != 1000 {
+		t.Errorf("pressure at sea level altitude = %v", obs.Pressure)
+	}
+
+	obs, _ = newObservation(m, now, 120)
+	// About 1 hPa per 8 m near sea level.
+	if obs.Pressure == nil || math.Abs(*obs.Pressure-1014.4) > 0.5 {
+		t.Errorf("sea level pressure at 120 m = %v", *obs.Pressure)
+	}
+
+	if _, ok := newObservation(m, now.Add(time.Hour), 0); ok {
+		t.Error("expected no observation when every metric is stale")
+	}
+}
+
+func TestAPRSWeatherPacket(t *testing.T) {
+	d := Destination{Callsign: "EW1234", Latitude: 37.77, Longitude: -122.42}
+	obs := observation{
+		Time:        time.Date(2025, 3, 1, 12, 5, 0, 0, time.UTC),
+		Temperature: ptr(18.3),
+		Humidity:    ptr(100),
+		Pressure:    ptr(1013.24),
+	}
+	want := "EW1234>APRS,TCPIP*:@011205z3746.20N/12225.20W_.../...g...t065h00b10132cloudpico"
+	if got := aprsWeatherPacket(d, obs); got != want {
+		t.Errorf("packet:\n got %s\nwant %s", got, want)
+	}
+
+	obs = observation{Time: obs.Time, Temperature: ptr(-20)}
+	want = "EW1234>APRS,TCPIP*:@011205z3746.20N/12225.20W_.../...g...t-04h..b.....cloudpico"
+	if got := aprsWeatherPacket(d, obs); got != want {
+		t.Errorf("packet with unknowns:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestAPRSCoordinate(t *testing.T) {
+	for _, tc := range []struct {
+		v         float64
+		degDigits int
+		pos, neg  string
+		want      string
+	}{
+		{37.77, 2, "N", "S", "3746.20N"},
+		{-33.8688, 2, "N", "S", "3352.13S"},
+		{-122.42, 3, "E", "W", "12225.20W"},
+		{5.5, 3, "E", "W", "00530.00E"},
+		{10.99999, 2, "N", "S", "1100.00N"},
+	} {
+		if got := aprsCoordinate(tc.v, tc.degDigits, tc.pos, tc.neg); got != tc.want {
+			t.Errorf("aprsCoordinate(%v) = %q, want %q", tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestWunderground_Upload(t *testing.T) {
+	var query map[string][]string
+	body := "success\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	u := &wunderground{dest: Destination{ID: "KX1", Key: "secret"}, url: srv.URL, client: srv.Client()}
+	obs := observation{
+		Time:        time.Date(2025, 3, 1, 12, 5, 0, 0, time.UTC),
+		Temperature: ptr(20),
+		Pressure:    ptr(1013.25),
+	}
+	if err := u.upload(context.Background(), obs); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"ID":       "KX1",
+		"PASSWORD": "secret",
+		"action":   "updateraw",
+		"dateutc":  "2025-03-01 12:05:00",
+		"tempf":    "68.0",
+		"baromin":  "29.92",
+	} {
+		if got := strings.Join(query[key], ","); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if _, ok := query["humidity"]; ok {
+		t.Error("unknown humidity sent")
+	}
+
+	body = "INVALIDPASSWORDID|Password or key and/or id are incorrect"
+	err := u.upload(context.Background(), obs)
+	if err == nil || !strings.Contains(err.Error(), "INVALIDPASSWORDID") {
+		t.Fatalf("err = %v", err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks the key: %v", err)
+	}
+}
+
+func TestCWOP_Upload(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		_, _ = conn.Write([]byte("# aprsc 2.1.19\r\n"))
+		login, _ := r.ReadString('\n')
+		_, _ = conn.Write([]byte("# logresp EW1234 unverified, server CWOP-1\r\n"))
+		packet, _ := r.ReadString('\n')
+		lines <- []string{strings.TrimSpace(login), strings.TrimSpace(packet)}
+	}()
+
+	d := Destination{Callsign: "EW1234", Passcode: "-1", Latitude: 37.77, Longitude: -122.42, Server: ln.Addr().String()}
+	obs := observation{Time: time.Date(2025, 3, 1, 12, 5, 0, 0, time.UTC), Humidity: ptr(55)}
+	if err := (&cwop{dest: d}).upload(context.Background(), obs); err != nil {
+		t.Fatal(err)
+	}
+	got := <-lines
+	if got[0] != "user EW1234 pass -1 vers cloudpico 1.0" {
+		t.Errorf("login = %q", got[0])
+	}
+	if got[1] != aprsWeatherPacket(d, obs) {
+		t.Errorf("packet = %q", got[1])
+	}
+}
+
+type fakeSource struct {
+	metrics types.LatestMetrics
+}
+
+func (f *fakeSource) GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error) {
+	m := f.metrics
+	m.StationID = stationID
+	return m, nil
+}
+
+type fakeUploader struct {
+	calls int
+	err   error
+}
+
+func (f *fakeUploader) upload(ctx context.Context, obs observation) error {
+	f.calls++
+	return f.err
+}
+
+func TestModule_Run(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	src := &fakeSource{metrics: types.LatestMetrics{
+		Temperature: &types.LatestMetric{Value: 15, Time: now.Add(-time.Minute)},
+	}}
+	d := Destination{Name: "wu", Type: TypeWunderground, StationID: "1", Schedule: "@every 5m", ID: "KX1", Key: "secret"}
+	m := NewModule([]Destination{d}, src)
+	m.now = func() time.Time { return now }
+	up := &fakeUploader{}
+	m.uploaders["wu"] = up
+
+	if err := m.run(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	// Same reading again: skipped.
+	if err := m.run(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	// A newer reading that fails to upload.
+	src.metrics.Temperature = &types.LatestMetric{Value: 16, Time: now}
+	up.err = context.DeadlineExceeded
+	if err := m.run(context.Background(), d); err == nil {
+		t.Fatal("expected upload error")
+	}
+
+	st := m.Statuses()[0]
+	if up.calls != 2 || st.Uploads != 1 || st.Failures != 1 || st.Skipped != 1 {
+		t.Errorf("calls = %d, status = %+v", up.calls, st)
+	}
+	if st.LastError == "" || st.LastReading == nil || !st.LastReading.Equal(now.Add(-time.Minute)) {
+		t.Errorf("status = %+v", st)
+	}
+
+	rec := httptest.NewRecorder()
+	m.handleStatus(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/uploads", nil))
+	if strings.Contains(rec.Body.String(), "secret") || strings.Contains(rec.Body.String(), "KX1") {
+		t.Errorf("status leaks credentials: %s", rec.Body.String())
+	}
+	var got []Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got) != 1 || got[0].Name != "wu" {
+		t.Errorf("status response = %s (%v)", rec.Body.String(), err)
+	}
+}
@@ -0,0 +1,66 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// wundergroundURL is the Weather Underground PWS upload endpoint.
+const wundergroundURL = "https://weatherstation.wunderground.com/weatherstation/updateweatherstation.php"
+
+// hPaToInHg converts hectopascals to inches of mercury.
+const hPaToInHg = 0.0295299830714
+
+// wunderground uploads observations with the PWS upload protocol: one GET
+// request whose query carries the station credentials and values in
+// imperial units. The service answers "success" in the body.
+type wunderground struct {
+	dest   Destination
+	url    string
+	client *http.Client
+}
+
+func (u *wunderground) upload(ctx context.Context, obs observation) error {
+	q := url.Values{}
+	q.Set("ID", u.dest.ID)
+	q.Set("PASSWORD", u.dest.Key)
+	q.Set("action", "updateraw")
+	q.Set("softwaretype", "cloudpico")
+	q.Set("dateutc", obs.Time.UTC().Format("2006-01-02 15:04:05"))
+	if obs.Temperature != nil {
+		q.Set("tempf", strconv.FormatFloat(celsiusToFahrenheit(*obs.Temperature), 'f', 1, 64))
+	}
+	if obs.Humidity != nil {
+		q.Set("humidity", strconv.FormatFloat(*obs.Humidity, 'f', 0, 64))
+	}
+	if obs.Pressure != nil {
+		q.Set("baromin", strconv.FormatFloat(*obs.Pressure*hPaToInHg, 'f', 2, 64))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.url+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		// The URL carries the station key; drop it from the error.
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("wunderground request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	text := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK || !strings.EqualFold(text, "success") {
+		return fmt.Errorf("wunderground: status %d: %q", resp.StatusCode, text)
+	}
+	return nil
+}
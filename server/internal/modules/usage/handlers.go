@@ -0,0 +1,94 @@
+package usage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+var usageTmpl = template.Must(template.ParseFS(templatesFS, "templates/usage.html"))
+
+// Usage window in days: default and maximum of the days parameter.
+const (
+	defaultUsageDays = 30
+	maxUsageDays     = 366
+)
+
+// usagePage is the data for templates/usage.html.
+type usagePage struct {
+	Days  int
+	Usage []Usage
+	Total int64
+}
+
+type controller struct {
+	m *Module
+}
+
+// parseDays returns the days parameter of r.
+func parseDays(r *http.Request) (int, error) {
+	s := r.URL.Query().Get("days")
+	if s == "" {
+		return defaultUsageDays, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > maxUsageDays {
+		return 0, fmt.Errorf("'days' must be an integer between 1 and %d", maxUsageDays)
+	}
+	return n, nil
+}
+
+// load flushes pending counts and returns usage over the last days days,
+// today included.
+func (c *controller) load(ctx context.Context, days int) ([]Usage, error) {
+	if err := c.m.Flush(ctx); err != nil {
+		logging.FromContext(ctx).Warn("flush api usage failed", "error", err)
+	}
+	return c.m.store.list(ctx, c.m.now().UTC().AddDate(0, 0, 1-days))
+}
+
+func (c *controller) handleUsage(w http.ResponseWriter, r *http.Request) {
+	days, err := parseDays(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	usage, err := c.load(r.Context(), days)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, usage)
+}
+
+func (c *controller) handleUsagePage(w http.ResponseWriter, r *http.Request) {
+	days, err := parseDays(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	usage, err := c.load(r.Context(), days)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("load api usage failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load usage")
+		return
+	}
+	page := usagePage{Days: days, Usage: usage}
+	for _, u := range usage {
+		page.Total += u.Requests
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := usageTmpl.Execute(w, page); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render usage page", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render usage page")
+	}
+}
@@ -0,0 +1,13 @@
+-- =========================
+-- api_usage
+-- =========================
+-- Daily API request counts per station and client. station_id is the ID from
+-- the request path ('' for requests not about one station); client is
+-- "webhook:<name>", "token:<hash prefix>", "public" or "internal".
+CREATE TABLE IF NOT EXISTS api_usage (
+  day        TEXT    NOT NULL, -- UTC date, YYYY-MM-DD
+  station_id TEXT    NOT NULL,
+  client     TEXT    NOT NULL,
+  requests   INTEGER NOT NULL,
+  PRIMARY KEY (day, station_id, client)
+) WITHOUT ROWID;
@@ -0,0 +1,124 @@
+// Package usage counts API requests per station and per client (webhook,
+// token or listener) to show which integrations consume the data. Counts are
+// kept in memory and flushed to the api_usage table by the "usage-flush" job.
+package usage
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/scheduler"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// flushSpec is how often pending counts are written to the database.
+const flushSpec = "@every 1m"
+
+// Module counts requests passing through Handler and serves the usage page
+// and API.
+type Module struct {
+	counter *counter
+	store   *store
+	sched   *scheduler.Scheduler
+	now     func() time.Time
+}
+
+func NewModule() *Module {
+	return &Module{counter: newCounter(), now: time.Now}
+}
+
+func (m *Module) Name() string { return "usage" }
+
+func (m *Module) Migrations() fs.FS {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		panic(err) // the embedded directory always exists
+	}
+	return sub
+}
+
+// RegisterRoutes adds the usage routes.
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	m.store = &store{db: deps.DB}
+	m.sched = deps.Scheduler
+	c := &controller{m: m}
+	mux.HandleFunc("GET /admin/usage", c.handleUsagePage)
+	mux.HandleFunc("GET /api/v1/admin/usage", c.handleUsage)
+	return nil
+}
+
+// StartWorkers registers the flush job.
+func (m *Module) StartWorkers(ctx context.Context) error {
+	return m.sched.Register(scheduler.Job{
+		Name: "usage-flush",
+		Spec: flushSpec,
+		Run:  m.Flush,
+	})
+}
+
+// Handler counts the successful API requests next serves. listener is the
+// client recorded for requests without a webhook or token, e.g.
+// ClientPublic for the public API.
+func (m *Module) Handler(next http.Handler, listener string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !counted(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r)
+		// Failed requests (unknown stations, bad tokens, rate limits) are not
+		// data consumed and would let clients create arbitrary rows.
+		if sr.status < http.StatusBadRequest {
+			m.counter.record(m.now(), stationOf(r), clientOf(r, listener))
+		}
+	})
+}
+
+// Flush writes pending counts to the database. On failure they are kept for
+// the next flush.
+func (m *Module) Flush(ctx context.Context) error {
+	if m.store == nil {
+		return nil
+	}
+	counts, dropped := m.counter.take()
+	if dropped > 0 {
+		slog.Warn("api usage counters full; requests not counted", "dropped", dropped, "max_keys", maxPendingKeys)
+	}
+	if len(counts) == 0 {
+		return nil
+	}
+	if err := m.store.add(ctx, counts); err != nil {
+		m.counter.restore(counts)
+		return err
+	}
+	return nil
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if !sr.wroteHeader {
+		sr.status, sr.wroteHeader = code, true
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	sr.wroteHeader = true
+	return sr.ResponseWriter.Write(p)
+}
+
+// Unwrap lets streaming handlers flush through the recorder.
+func (sr *statusRecorder) Unwrap() http.ResponseWriter { return sr.ResponseWriter }
@@ -0,0 +1,173 @@
+package usage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clients of requests without a token or webhook, by listener.
+const (
+	ClientInternal = "internal"
+	ClientPublic   = "public"
+)
+
+// maxPendingKeys bounds the counters held between flushes; requests for new
+// day/station/client combinations beyond it are dropped and logged.
+const maxPendingKeys = 10000
+
+// dayLayout formats api_usage.day.
+const dayLayout = "2006-01-02"
+
+// Usage is the request count of one client for one station over a period.
+type Usage struct {
+	StationID   string `json:"stationId,omitempty"`
+	StationName string `json:"stationName,omitempty"`
+	Client      string `json:"client"`
+	Requests    int64  `json:"requests"`
+	FirstDay    string `json:"firstDay"`
+	LastDay     string `json:"lastDay"`
+}
+
+type counterKey struct {
+	day, stationID, client string
+}
+
+// counter holds request counts in memory until they are flushed.
+type counter struct {
+	mu      sync.Mutex
+	pending map[counterKey]int64
+	dropped int64
+}
+
+func newCounter() *counter {
+	return &counter{pending: make(map[counterKey]int64)}
+}
+
+func (c *counter) record(now time.Time, stationID, client string) {
+	k := counterKey{day: now.UTC().Format(dayLayout), stationID: stationID, client: client}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.pending[k]; !ok && len(c.pending) >= maxPendingKeys {
+		c.dropped++
+		return
+	}
+	c.pending[k]++
+}
+
+// take returns the pending counts and the number of dropped requests, and
+// resets both.
+func (c *counter) take() (map[counterKey]int64, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending, dropped := c.pending, c.dropped
+	c.pending, c.dropped = make(map[counterKey]int64), 0
+	return pending, dropped
+}
+
+// restore adds counts back after a failed flush.
+func (c *counter) restore(counts map[counterKey]int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, n := range counts {
+		c.pending[k] += n
+	}
+}
+
+// counted reports whether r is an API request whose usage is tracked: data
+// and webhook endpoints, not the admin API.
+func counted(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/v1/") && !strings.HasPrefix(r.URL.Path, "/api/v1/admin/")
+}
+
+// stationOf returns the station ID of a /api/v1/stations/{id}/... path, or ""
+// for other paths.
+func stationOf(r *http.Request) string {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/stations/")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	return id
+}
+
+// clientOf identifies the integration behind r: the webhook name for webhook
+// posts, a hash prefix of the bearer token or X-API-Key when one is sent (the
+// token itself is never stored), and otherwise the listener.
+func clientOf(r *http.Request, listener string) string {
+	if name, ok := strings.CutPrefix(r.URL.Path, "/api/v1/webhooks/"); ok && name != "" {
+		return "webhook:" + name
+	}
+	token := r.Header.Get("X-API-Key")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	if token = strings.TrimSpace(token); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:4])
+	}
+	return listener
+}
+
+// store keeps usage counts in the server database.
+type store struct {
+	db *sql.DB
+}
+
+// add adds counts to the stored daily totals in one transaction.
+func (s *store) add(ctx context.Context, counts map[counterKey]int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.PrepareContext(ctx, `
+INSERT INTO api_usage (day, station_id, client, requests) VALUES (?, ?, ?, ?)
+ON CONFLICT (day, station_id, client) DO UPDATE SET requests = requests + excluded.requests`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stmt.Close() }()
+	for k, n := range counts {
+		if _, err := stmt.ExecContext(ctx, k.day, k.stationID, k.client, n); err != nil {
+			return fmt.Errorf("add usage: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// list returns usage per station and client since the given day, busiest
+// first.
+func (s *store) list(ctx context.Context, since time.Time) ([]Usage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT u.station_id, COALESCE(st.name, ''), u.client, SUM(u.requests), MIN(u.day), MAX(u.day)
+FROM api_usage u
+LEFT JOIN stations st ON CAST(st.id AS TEXT) = u.station_id
+WHERE u.day >= ?
+GROUP BY u.station_id, u.client
+ORDER BY SUM(u.requests) DESC, u.station_id, u.client`, since.UTC().Format(dayLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("close usage rows", "error", err)
+		}
+	}()
+	out := []Usage{}
+	for rows.Next() {
+		var u Usage
+		if err := rows.Scan(&u.StationID, &u.StationName, &u.Client, &u.Requests, &u.FirstDay, &u.LastDay); err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,192 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/scheduler"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestModule returns the module migrated onto an in-memory database with
+// station 1 (Garden), and a mux serving its routes.
+func newTestModule(t *testing.T) (*Module, *http.ServeMux) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("close db: %v", err)
+		}
+	})
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`
+CREATE TABLE stations (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+INSERT INTO stations (id, name) VALUES (1, 'Garden');`); err != nil {
+		t.Fatalf("create stations: %v", err)
+	}
+	m := NewModule()
+	m.now = func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) }
+	if err := module.Migrate(db, []module.Module{m}); err != nil {
+		t.Fatalf("Migrate() = %v; want nil", err)
+	}
+	mux := http.NewServeMux()
+	if err := m.RegisterRoutes(mux, module.Deps{DB: db, Scheduler: scheduler.New()}); err != nil {
+		t.Fatalf("RegisterRoutes() = %v; want nil", err)
+	}
+	return m, mux
+}
+
+func TestClientOf(t *testing.T) {
+	for name, tc := range map[string]struct {
+		path    string
+		header  [2]string
+		want    string
+		station string
+	}{
+		"listener":     {"/api/v1/stations/1/latest", [2]string{}, ClientPublic, "1"},
+		"bearer token": {"/api/v1/stations/1", [2]string{"Authorization", "Bearer abc"}, "token:ba7816bf", "1"},
+		"api key":      {"/api/v1/stations", [2]string{"X-API-Key", "abc"}, "token:ba7816bf", ""},
+		"webhook":      {"/api/v1/webhooks/acme", [2]string{"Authorization", "Bearer abc"}, "webhook:acme", ""},
+		"geojson":      {"/api/v1/stations.geojson", [2]string{}, ClientPublic, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header[0] != "" {
+				r.Header.Set(tc.header[0], tc.header[1])
+			}
+			if got := clientOf(r, ClientPublic); got != tc.want {
+				t.Errorf("clientOf = %q, want %q", got, tc.want)
+			}
+			if got := stationOf(r); got != tc.station {
+				t.Errorf("stationOf = %q, want %q", got, tc.station)
+			}
+		})
+	}
+}
+
+func TestHandler_CountsAndFlushes(t *testing.T) {
+	m, mux := newTestModule(t)
+	api := http.NewServeMux()
+	api.HandleFunc("GET /api/v1/stations/{id}/latest", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "1" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	})
+	api.HandleFunc("GET /api/v1/admin/usage", mux.ServeHTTP)
+	h := m.Handler(api, ClientInternal)
+
+	get := func(path, token string) {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	get("/api/v1/stations/1/latest", "")
+	get("/api/v1/stations/1/latest", "")
+	get("/api/v1/stations/1/latest", "abc")
+	get("/api/v1/stations/99/latest", "") // 404: not counted
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+	get("/api/v1/stations/1/latest", "")
+	get("/api/v1/admin/usage", "") // admin API: not counted
+
+	// The API flushes the pending request before reading.
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/usage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var got []Usage
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Usage{
+		{StationID: "1", StationName: "Garden", Client: ClientInternal, Requests: 3, FirstDay: "2025-03-01", LastDay: "2025-03-01"},
+		{StationID: "1", StationName: "Garden", Client: "token:ba7816bf", Requests: 1, FirstDay: "2025-03-01", LastDay: "2025-03-01"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("usage = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("usage[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestUsageAPI_Days(t *testing.T) {
+	m, mux := newTestModule(t)
+	if _, err := m.store.db.Exec(`INSERT INTO api_usage VALUES ('2025-01-01', '1', 'public', 5), ('2025-02-28', '1', 'public', 2)`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		query    string
+		status   int
+		requests int64
+	}{
+		{"", http.StatusOK, 2},
+		{"?days=90", http.StatusOK, 7},
+		{"?days=0", http.StatusBadRequest, 0},
+		{"?days=x", http.StatusBadRequest, 0},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/usage"+tc.query, nil))
+		if rec.Code != tc.status {
+			t.Errorf("%q: status = %d, want %d", tc.query, rec.Code, tc.status)
+			continue
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var got []Usage
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got) != 1 || got[0].Requests != tc.requests {
+			t.Errorf("%q: usage = %s, want %d requests", tc.query, rec.Body.String(), tc.requests)
+		}
+	}
+}
+
+func TestUsagePage(t *testing.T) {
+	m, mux := newTestModule(t)
+	m.counter.record(m.now(), "1", "webhook:acme")
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/usage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Garden", "webhook:acme", "1 successful API requests in the last 30 days"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+}
+
+func TestCounter_Bounded(t *testing.T) {
+	c := newCounter()
+	now := time.Now()
+	for i := range maxPendingKeys + 5 {
+		c.record(now, "", "token:"+strconv.Itoa(i))
+	}
+	counts, dropped := c.take()
+	if len(counts) != maxPendingKeys || dropped != 5 {
+		t.Errorf("kept %d keys, dropped %d", len(counts), dropped)
+	}
+}
@@ -0,0 +1,93 @@
+// Package batch serves POST /api/v1/readings:batch, which ingests a backlog
+// of telemetry (e.g. buffered by a gateway while offline) in one request.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+// Request limits.
+const (
+	maxBodyBytes = 8 << 20
+	maxItems     = 5000
+)
+
+// Source is the ingest source of batch readings, as seen in logs.
+const Source = "api/batch"
+
+// IngestFunc ingests payloads, each a telemetry object, and returns one error
+// per payload (nil when stored); err reports a failure of the whole batch.
+type IngestFunc func(ctx context.Context, source string, payloads []json.RawMessage) (errs []error, err error)
+
+// ItemError reports why the item at Index was not stored.
+type ItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// Response is the body of a processed batch. Errors is empty when every item
+// was accepted.
+type Response struct {
+	Accepted int         `json:"accepted"`
+	Rejected int         `json:"rejected"`
+	Errors   []ItemError `json:"errors"`
+}
+
+// Handler serves POST /api/v1/readings:batch.
+type Handler struct {
+	ingest IngestFunc
+}
+
+func NewHandler(ingest IngestFunc) *Handler {
+	return &Handler{ingest: ingest}
+}
+
+// ServeHTTP ingests the JSON array of telemetry objects in the body. The
+// accepted items are stored in one transaction; rejected items are reported
+// by index and do not affect the others, so a client can resend just those.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&items); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, "body too large")
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, "body must be a JSON array of telemetry objects")
+		return
+	}
+	if len(items) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "batch is empty")
+		return
+	}
+	if len(items) > maxItems {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d readings per batch", maxItems))
+		return
+	}
+
+	errs, err := h.ingest(r.Context(), Source, items)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("batch: store readings failed", "items", len(items), "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to store readings")
+		return
+	}
+	resp := Response{Errors: []ItemError{}}
+	for i, err := range errs {
+		if err != nil {
+			resp.Errors = append(resp.Errors, ItemError{Index: i, Error: err.Error()})
+		}
+	}
+	resp.Rejected = len(resp.Errors)
+	resp.Accepted = len(items) - resp.Rejected
+	if resp.Rejected > 0 {
+		logging.FromContext(r.Context()).Warn("batch: readings rejected", "accepted", resp.Accepted, "rejected", resp.Rejected, "first_error", resp.Errors[0].Error)
+	}
+	utils.WriteJSON(w, http.StatusOK, resp)
+}
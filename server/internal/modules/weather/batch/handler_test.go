@@ -0,0 +1,93 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var (
+		got      []json.RawMessage
+		source   string
+		itemErrs map[int]error
+		batchErr error
+	)
+	h := NewHandler(func(_ context.Context, src string, payloads []json.RawMessage) ([]error, error) {
+		source, got = src, payloads
+		if batchErr != nil {
+			return nil, batchErr
+		}
+		errs := make([]error, len(payloads))
+		for i, err := range itemErrs {
+			errs[i] = err
+		}
+		return errs, nil
+	})
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/readings:batch", h)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/readings:batch", strings.NewReader(body)))
+		return rec
+	}
+	const body = `[{"station_id":"1","timestamp":"2025-01-01T00:00:00Z","temperature_c":1},{"station_id":"1"},{"station_id":"2","timestamp":"2025-01-01T00:00:00Z","pressure_hpa":1000}]`
+
+	t.Run("reports rejected items by index", func(t *testing.T) {
+		itemErrs = map[int]error{1: errors.New("validate: timestamp is required")}
+		defer func() { itemErrs = nil }()
+		rec := post(body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want 200 (body %s)", rec.Code, rec.Body.String())
+		}
+		var resp Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		want := Response{Accepted: 2, Rejected: 1, Errors: []ItemError{{Index: 1, Error: "validate: timestamp is required"}}}
+		if !reflect.DeepEqual(resp, want) {
+			t.Errorf("response = %+v; want %+v", resp, want)
+		}
+		if len(got) != 3 || source != Source {
+			t.Errorf("ingested %d payloads from %q; want 3 from %s", len(got), source, Source)
+		}
+	})
+
+	t.Run("all accepted", func(t *testing.T) {
+		rec := post(body)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"accepted":3,"rejected":0,"errors":[]`) {
+			t.Errorf("POST = %d %s; want 200 with 3 accepted", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("batch failure", func(t *testing.T) {
+		batchErr = errors.New("database is locked")
+		defer func() { batchErr = nil }()
+		if rec := post(body); rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want 500", rec.Code)
+		}
+	})
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"not an array", `{"station_id":"1"}`, http.StatusBadRequest},
+		{"invalid json", `[`, http.StatusBadRequest},
+		{"empty", `[]`, http.StatusBadRequest},
+		{"too many items", "[" + strings.Repeat("{},", maxItems) + "{}]", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(tt.body); rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
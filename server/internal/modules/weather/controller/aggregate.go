@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+// handleAggregate returns min/avg/max per metric of station {id} in hourly
+// or daily buckets, for charts that do not need every reading.
+func (c *weatherControllerImpl) handleAggregate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	bucket, width, from, to, err := parseAggregateQuery(r, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	units, err := parseUnitsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("aggregate: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+
+	buckets, err := c.repository.GetAggregate(r.Context(), station.ID, from, to, width)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("aggregate: load failed", "station_id", id, "bucket", bucket, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load aggregate")
+		return
+	}
+	convertAggregate(buckets, units)
+	utils.WriteJSON(w, http.StatusOK, types.Aggregate{
+		StationID: station.ID,
+		Bucket:    bucket,
+		Units:     units,
+		From:      from,
+		To:        to,
+		Buckets:   buckets,
+	})
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleAggregate(t *testing.T) {
+	serve := func(repo *mockRepo, query string) *httptest.ResponseRecorder {
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/aggregate"+query, nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		ctrl.handleAggregate(rec, req)
+		return rec
+	}
+
+	t.Run("returns buckets", func(t *testing.T) {
+		start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		repo := &mockRepo{
+			station:   types.Station{ID: "1"},
+			aggregate: []types.AggregateBucket{{Start: start, Count: 24}},
+		}
+		rec := serve(repo, "?bucket=1d&from=2025-02-01T06:30:00Z&to=2025-02-08T00:00:00Z")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d, body %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var got types.Aggregate
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.StationID != "1" || got.Bucket != "1d" || len(got.Buckets) != 1 || got.Buckets[0].Count != 24 {
+			t.Errorf("aggregate = %+v; want station 1, 1d, one bucket of 24", got)
+		}
+		if !got.From.Equal(start) || !repo.lastAggregateFrom.Equal(start) {
+			t.Errorf("from = %v, repo from = %v; want rounded down to %v", got.From, repo.lastAggregateFrom, start)
+		}
+		if repo.lastAggregateBucket != 24*time.Hour {
+			t.Errorf("repo bucket = %v; want 24h", repo.lastAggregateBucket)
+		}
+	})
+
+	t.Run("converts to imperial units", func(t *testing.T) {
+		temp, pressure := 20.0, 1000.0
+		repo := &mockRepo{
+			station: types.Station{ID: "1"},
+			aggregate: []types.AggregateBucket{{
+				Temperature: types.MetricSummary{Count: 1, Min: &temp, Avg: &temp, Max: &temp},
+				Pressure:    types.MetricSummary{Count: 1, Avg: &pressure},
+			}},
+		}
+		rec := serve(repo, "?units=imperial")
+
+		var got types.Aggregate
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.Units != "imperial" || len(got.Buckets) != 1 {
+			t.Fatalf("aggregate = %+v; want imperial, one bucket", got)
+		}
+		b := got.Buckets[0]
+		if *b.Temperature.Min != 68 || *b.Temperature.Max != 68 || b.Pressure.Min != nil || math.Abs(*b.Pressure.Avg-29.53) > 0.01 {
+			t.Errorf("bucket = %+v; want 68 °F and 29.53 inHg", b)
+		}
+		if temp != 20 {
+			t.Errorf("repository values converted in place: %v", temp)
+		}
+	})
+
+	t.Run("returns 400 for unknown units", func(t *testing.T) {
+		rec := serve(&mockRepo{station: types.Station{ID: "1"}}, "?units=kelvin")
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("defaults to hourly buckets over 24 hours", func(t *testing.T) {
+		repo := &mockRepo{station: types.Station{ID: "1"}}
+		rec := serve(repo, "?to=2025-02-01T12:00:00Z")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		if repo.lastAggregateBucket != time.Hour || repo.lastAggregateTo.Sub(repo.lastAggregateFrom) != 24*time.Hour {
+			t.Errorf("repo called with bucket %v over [%v, %v]; want 1h over 24h", repo.lastAggregateBucket, repo.lastAggregateFrom, repo.lastAggregateTo)
+		}
+	})
+
+	t.Run("returns 400 when query is invalid", func(t *testing.T) {
+		for query, want := range map[string]string{
+			"?bucket=5m": "bucket",
+			"?from=2025-02-02T00:00:00Z&to=2025-02-01T00:00:00Z": "from",
+			"?from=2020-01-01T00:00:00Z&to=2025-01-01T00:00:00Z": "buckets",
+		} {
+			rec := serve(&mockRepo{}, query)
+			if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("%s: status = %d, body %q; want 400 mentioning %q", query, rec.Code, rec.Body.String(), want)
+			}
+		}
+	})
+
+	t.Run("returns 404 for unknown station", func(t *testing.T) {
+		rec := serve(&mockRepo{stationErr: repository.ErrStationNotFound}, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("returns 500 when repository fails", func(t *testing.T) {
+		rec := serve(&mockRepo{station: types.Station{ID: "1"}, aggregateErr: errors.New("db error")}, "")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+// maxAnnotationNoteLen bounds a note in characters.
+const maxAnnotationNoteLen = 500
+
+// createAnnotationRequest is the body of POST /api/v1/stations/{id}/annotations.
+// End defaults to Start, marking a single point in time.
+type createAnnotationRequest struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end"`
+	Note  string     `json:"note"`
+}
+
+// readingsPage is the readings envelope with the annotations overlapping the
+// requested range, so clients can mark them alongside the readings. Cursor
+// pages also carry the cursors of the older (next) and newer (prev) pages.
+// Units is the unit system of the readings.
+type readingsPage struct {
+	utils.Page[fieldsReading]
+	NextCursor  string             `json:"nextCursor,omitempty"`
+	PrevCursor  string             `json:"prevCursor,omitempty"`
+	Annotations []types.Annotation `json:"annotations"`
+	Units       string             `json:"units"`
+}
+
+func (c *weatherControllerImpl) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	from, to, page, err := parseReadingsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	annotations, err := c.repository.GetAnnotations(r.Context(), id, from, to)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WritePage(w, r, utils.Slice(annotations, page), len(annotations), page)
+}
+
+func (c *weatherControllerImpl) handleCreateAnnotation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	var req createAnnotationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		utils.WriteError(w, http.StatusBadRequest, "'note' is required")
+		return
+	}
+	if len([]rune(req.Note)) > maxAnnotationNoteLen {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("'note' must be at most %d characters", maxAnnotationNoteLen))
+		return
+	}
+	if req.Start.IsZero() {
+		utils.WriteError(w, http.StatusBadRequest, "'start' is required")
+		return
+	}
+	end := req.Start
+	if req.End != nil {
+		end = *req.End
+	}
+	if end.Before(req.Start) {
+		utils.WriteError(w, http.StatusBadRequest, "'end' must be >= 'start'")
+		return
+	}
+
+	annotation, err := c.repository.CreateAnnotation(r.Context(), id, req.Start, end, req.Note)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create annotation failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to create annotation")
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, annotation)
+}
+
+func (c *weatherControllerImpl) handleDeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	id, annotationID := r.PathValue("id"), r.PathValue("annotationId")
+	err := c.repository.DeleteAnnotation(r.Context(), id, annotationID)
+	if errors.Is(err, repository.ErrAnnotationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "annotation not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("delete annotation failed", "station_id", id, "annotation_id", annotationID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to delete annotation")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// annotationMarkers formats annotations for display.
+func annotationMarkers(annotations []types.Annotation) []views.AnnotationMarker {
+	markers := make([]views.AnnotationMarker, 0, len(annotations))
+	for _, a := range annotations {
+		markers = append(markers, views.AnnotationMarker{
+			Label: timeRangeLabel(a.Start, a.End),
+			Title: a.Start.Format(time.RFC3339) + "/" + a.End.Format(time.RFC3339),
+			Note:  a.Note,
+		})
+	}
+	return markers
+}
+
+// timeRangeLabel formats [start, end] for display. The end time is shown
+// without its date when the range falls within one day, and not at all for a
+// point in time.
+func timeRangeLabel(start, end time.Time) string {
+	const dateTime, timeOnly = "2006-01-02 3:04 PM", "3:04 PM"
+	label := start.Format(dateTime)
+	switch {
+	case end.Equal(start):
+	case end.Format(time.DateOnly) == start.Format(time.DateOnly):
+		label += " – " + end.Format(timeOnly)
+	default:
+		label += " – " + end.Format(dateTime)
+	}
+	return label
+}
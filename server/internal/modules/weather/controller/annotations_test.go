@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+)
+
+func Test_handleCreateAnnotation(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		repoErr    error
+		wantStatus int
+	}{
+		{"range", `{"start":"2025-01-01T10:00:00Z","end":"2025-01-01T12:00:00Z","note":" sensor moved indoors "}`, nil, http.StatusCreated},
+		{"point", `{"start":"2025-01-01T10:00:00Z","note":"calibration changed"}`, nil, http.StatusCreated},
+		{"missing note", `{"start":"2025-01-01T10:00:00Z","note":"  "}`, nil, http.StatusBadRequest},
+		{"note too long", `{"start":"2025-01-01T10:00:00Z","note":"` + strings.Repeat("x", maxAnnotationNoteLen+1) + `"}`, nil, http.StatusBadRequest},
+		{"missing start", `{"note":"x"}`, nil, http.StatusBadRequest},
+		{"end before start", `{"start":"2025-01-01T10:00:00Z","end":"2025-01-01T09:00:00Z","note":"x"}`, nil, http.StatusBadRequest},
+		{"invalid JSON", `{`, nil, http.StatusBadRequest},
+		{"unknown station", `{"start":"2025-01-01T10:00:00Z","note":"x"}`, repository.ErrStationNotFound, http.StatusNotFound},
+		{"repository error", `{"start":"2025-01-01T10:00:00Z","note":"x"}`, errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{annotationErr: tt.repoErr}
+			ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/stations/1/annotations", strings.NewReader(tt.body))
+			req.SetPathValue("id", "1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleCreateAnnotation(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusCreated {
+				return
+			}
+			a := repo.lastAnnotation
+			if strings.TrimSpace(a.Note) != a.Note || a.End.Before(a.Start) {
+				t.Errorf("created annotation = %+v; want trimmed note and end >= start", a)
+			}
+		})
+	}
+}
+
+func Test_handleAnnotations(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo := &mockRepo{annotations: []types.Annotation{
+		{ID: "1", StationID: "1", Start: start, End: start, Note: "a"},
+		{ID: "2", StationID: "1", Start: start, End: start, Note: "b"},
+	}}
+	ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+	mux := http.NewServeMux()
+	ctrl.RegisterRoutes(mux)
+
+	t.Run("lists a page", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/annotations?limit=1", nil))
+		var page struct {
+			Items []types.Annotation `json:"items"`
+			Total int                `json:"total"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if page.Total != 2 || len(page.Items) != 1 || page.Items[0].Note != "a" {
+			t.Errorf("page = %+v; want total 2 with first item", page)
+		}
+	})
+
+	t.Run("readings include annotations", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/readings", nil))
+		var page readingsPage
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(page.Annotations) != 2 || page.Limit != defaultReadingsLimit {
+			t.Errorf("page = %+v; want 2 annotations and the page envelope", page)
+		}
+	})
+
+	t.Run("readings fail when annotations fail", func(t *testing.T) {
+		repo.annotationsErr = errors.New("db error")
+		defer func() { repo.annotationsErr = nil }()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/readings", nil))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/stations/1/annotations/1", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNoContent)
+		}
+		repo.annotationErr = repository.ErrAnnotationNotFound
+		defer func() { repo.annotationErr = nil }()
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/stations/1/annotations/9", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func Test_annotationMarkers(t *testing.T) {
+	start := time.Date(2025, 1, 2, 15, 4, 0, 0, time.UTC)
+	got := annotationMarkers([]types.Annotation{
+		{Start: start, End: start, Note: "point"},
+		{Start: start, End: start.Add(2 * time.Hour), Note: "same day"},
+		{Start: start, End: start.Add(24 * time.Hour), Note: "multi day"},
+	})
+	want := []views.AnnotationMarker{
+		{Label: "2025-01-02 3:04 PM", Title: "2025-01-02T15:04:00Z/2025-01-02T15:04:00Z", Note: "point"},
+		{Label: "2025-01-02 3:04 PM – 5:04 PM", Title: "2025-01-02T15:04:00Z/2025-01-02T17:04:00Z", Note: "same day"},
+		{Label: "2025-01-02 3:04 PM – 2025-01-03 3:04 PM", Title: "2025-01-02T15:04:00Z/2025-01-03T15:04:00Z", Note: "multi day"},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("marker %d = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+}
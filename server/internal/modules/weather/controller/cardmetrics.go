@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+// The metrics shown on dashboard cards are kept per browser in a signed
+// cookie, in display order. Without the cookie every metric in the registry
+// is shown.
+const (
+	cardMetricsCookieName   = "weather_card_metrics"
+	cardMetricsCookieMaxAge = 365 * 24 * 60 * 60 // 1 year in seconds
+	// cardMetricsChangedEvent is sent in HX-Trigger so the dashboard
+	// refreshes right after a change.
+	cardMetricsChangedEvent = "card-metrics-changed"
+)
+
+type cardMetricsBody struct {
+	Metrics []string `json:"metrics"`
+}
+
+// handleCardMetrics returns the metrics shown on dashboard cards, in order.
+func (c *weatherControllerImpl) handleCardMetrics(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, cardMetricsBody{Metrics: readCardMetrics(c.cookies, r)})
+}
+
+// handleSetCardMetrics replaces the card metrics with the given names, in
+// order; this is also how they are reordered.
+func (c *weatherControllerImpl) handleSetCardMetrics(w http.ResponseWriter, r *http.Request) {
+	var req cardMetricsBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	names := make([]string, 0, len(req.Metrics))
+	for _, name := range req.Metrics {
+		if _, ok := types.LookupMetric(name); !ok {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown metric %q", name))
+			return
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	c.writeCardMetrics(w, names)
+}
+
+// handleEnableCardMetric appends metric {metric} to the card metrics.
+func (c *weatherControllerImpl) handleEnableCardMetric(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("metric")
+	if _, ok := types.LookupMetric(name); !ok {
+		utils.WriteError(w, http.StatusNotFound, "metric not found")
+		return
+	}
+	names := readCardMetrics(c.cookies, r)
+	if !slices.Contains(names, name) {
+		names = append(names, name)
+	}
+	c.writeCardMetrics(w, names)
+}
+
+// handleDisableCardMetric removes metric {metric} from the card metrics.
+func (c *weatherControllerImpl) handleDisableCardMetric(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("metric")
+	names := slices.DeleteFunc(readCardMetrics(c.cookies, r), func(s string) bool { return s == name })
+	c.writeCardMetrics(w, names)
+}
+
+// handleCardMetricsPartial renders the dashboard's card metric toggles.
+func (c *weatherControllerImpl) handleCardMetricsPartial(w http.ResponseWriter, r *http.Request) {
+	data := views.DashboardData{CardMetrics: cardMetricOptions(readCardMetrics(c.cookies, r))}
+	var buf bytes.Buffer
+	if err := views.RenderCardMetricsPartial(&buf, &data); err != nil {
+		logging.FromContext(r.Context()).Error("card metrics partial render failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logging.FromContext(r.Context()).Error("card metrics partial: write response failed", "error", err)
+	}
+}
+
+func (c *weatherControllerImpl) writeCardMetrics(w http.ResponseWriter, names []string) {
+	c.cookies.SetCookie(w, &http.Cookie{
+		Name:     cardMetricsCookieName,
+		Value:    strings.Join(names, ","),
+		Path:     "/",
+		MaxAge:   cardMetricsCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   false, // set true if you serve over HTTPS only
+	})
+	w.Header().Set("HX-Trigger", cardMetricsChangedEvent)
+	utils.WriteJSON(w, http.StatusOK, cardMetricsBody{Metrics: names})
+}
+
+// readCardMetrics returns the card metric names in display order: every
+// registry metric when the cookie is missing or fails signature checks, and
+// otherwise the known names it lists.
+func readCardMetrics(cookies *utils.CookieSigner, r *http.Request) []string {
+	value, err := cookies.ReadCookie(r, cardMetricsCookieName)
+	if err != nil {
+		names := make([]string, 0, len(types.Metrics))
+		for _, m := range types.Metrics {
+			names = append(names, m.Name)
+		}
+		return names
+	}
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if _, ok := types.LookupMetric(name); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// cardMetricOptions lists the enabled metrics in display order, then the
+// disabled ones in registry order.
+func cardMetricOptions(enabled []string) []views.CardMetricOption {
+	out := make([]views.CardMetricOption, 0, len(types.Metrics))
+	for _, name := range enabled {
+		m, _ := types.LookupMetric(name)
+		out = append(out, views.CardMetricOption{Name: m.Name, Label: m.Label, Enabled: true})
+	}
+	for _, m := range types.Metrics {
+		if !slices.Contains(enabled, m.Name) {
+			out = append(out, views.CardMetricOption{Name: m.Name, Label: m.Label})
+		}
+	}
+	return out
+}
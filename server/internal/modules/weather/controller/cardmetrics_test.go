@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+func Test_cardMetrics(t *testing.T) {
+	cookies := utils.NewCookieSigner([]byte("0123456789abcdef0123456789abcdef"))
+	mux := http.NewServeMux()
+	NewWeatherController(&mockRepo{}, nil, cookies, nil).RegisterRoutes(mux)
+
+	// do sends a request carrying the card metrics cookie from the previous response.
+	var cookie *http.Cookie
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == cardMetricsCookieName {
+				cookie = c
+			}
+		}
+		return rec
+	}
+	names := func(rec *httptest.ResponseRecorder) []string {
+		var body cardMetricsBody
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body.Metrics
+	}
+
+	if got, want := names(do(http.MethodGet, "/api/v1/dashboard/metrics", "")), []string{"temperature", "humidity", "pressure"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("initial metrics = %v; want %v", got, want)
+	}
+
+	rec := do(http.MethodPut, "/api/v1/dashboard/metrics", `{"metrics":["pressure","humidity","pressure"]}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("HX-Trigger") != cardMetricsChangedEvent {
+		t.Fatalf("PUT status = %d, HX-Trigger = %q; want 200, %s", rec.Code, rec.Header().Get("HX-Trigger"), cardMetricsChangedEvent)
+	}
+	if got, want := names(rec), []string{"pressure", "humidity"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PUT metrics = %v; want %v", got, want)
+	}
+
+	if got, want := names(do(http.MethodDelete, "/api/v1/dashboard/metrics/humidity", "")), []string{"pressure"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after DELETE metrics = %v; want %v", got, want)
+	}
+	if got, want := names(do(http.MethodPost, "/api/v1/dashboard/metrics/temperature", "")), []string{"pressure", "temperature"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after POST metrics = %v; want %v", got, want)
+	}
+	if got, want := names(do(http.MethodGet, "/api/v1/dashboard/metrics", "")), []string{"pressure", "temperature"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GET metrics = %v; want %v", got, want)
+	}
+
+	t.Run("disabling every metric is kept", func(t *testing.T) {
+		saved := cookie
+		defer func() { cookie = saved }()
+		do(http.MethodPut, "/api/v1/dashboard/metrics", `{"metrics":[]}`)
+		if got := names(do(http.MethodGet, "/api/v1/dashboard/metrics", "")); len(got) != 0 {
+			t.Errorf("metrics = %v; want none", got)
+		}
+	})
+
+	t.Run("rejects unknown metrics", func(t *testing.T) {
+		if rec := do(http.MethodPut, "/api/v1/dashboard/metrics", `{"metrics":["co2"]}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("PUT status = %d; want 400", rec.Code)
+		}
+		if rec := do(http.MethodPost, "/api/v1/dashboard/metrics/co2", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("POST status = %d; want 404", rec.Code)
+		}
+	})
+
+	t.Run("ignores a tampered cookie", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dashboard/metrics", nil)
+		req.AddCookie(&http.Cookie{Name: cardMetricsCookieName, Value: "pressure"})
+		if got := readCardMetrics(cookies, req); len(got) != 3 {
+			t.Errorf("metrics = %v; want the defaults", got)
+		}
+	})
+}
+
+func Test_cardMetricOptions(t *testing.T) {
+	got := cardMetricOptions([]string{"pressure", "temperature"})
+	want := []views.CardMetricOption{
+		{Name: "pressure", Label: "Pressure", Enabled: true},
+		{Name: "temperature", Label: "Temperature", Enabled: true},
+		{Name: "humidity", Label: "Humidity"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cardMetricOptions() = %+v; want %+v", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+// handleClimate returns the monthly climate matrix of station {id}, built
+// from the daily rollups.
+func (c *weatherControllerImpl) handleClimate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("climate: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	climate, err := c.climate(r.Context(), station.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("climate: load failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load climate")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, climate)
+}
+
+// handleClimatePage renders the monthly climate page of station {id}: a
+// min/avg/max table per year and the average temperature of each month
+// across years. Clients preferring JSON (see wantsJSON) get the climate API
+// response instead.
+func (c *weatherControllerImpl) handleClimatePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	if wantsJSON(r) {
+		c.handleClimate(w, r)
+		return
+	}
+	id := r.PathValue("id")
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("climate page: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	climate, err := c.climate(r.Context(), station.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("climate page: load failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load climate")
+		return
+	}
+
+	data := views.ClimateData{Station: station}
+	for month := time.January; month <= time.December; month++ {
+		data.Matrix = append(data.Matrix, views.ClimateMatrixRow{Month: month.String()[:3]})
+	}
+	for _, y := range climate.Years {
+		data.MatrixYears = append(data.MatrixYears, y.Year)
+		table := views.ClimateYearTable{Year: y.Year}
+		for i, m := range y.Months {
+			cell := ""
+			if m != nil {
+				cell = formatClimateValue(m.Temperature.Avg, "%.1f")
+				table.Rows = append(table.Rows, views.ClimateMonthRow{
+					Month:       time.Month(i + 1).String()[:3],
+					Days:        m.Days,
+					TempMin:     formatClimateValue(m.Temperature.Min, "%.1f"),
+					TempAvg:     cell,
+					TempMax:     formatClimateValue(m.Temperature.Max, "%.1f"),
+					TempChange:  formatClimateValue(m.TemperatureAvgChange, "%+.1f"),
+					HumidityAvg: formatClimateValue(m.Humidity.Avg, "%.0f"),
+					PressureAvg: formatClimateValue(m.Pressure.Avg, "%.0f"),
+				})
+			}
+			data.Matrix[i].Cells = append(data.Matrix[i].Cells, cell)
+		}
+		data.Years = append(data.Years, table)
+	}
+	slices.Reverse(data.Years)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := views.RenderClimate(w, &data); err != nil {
+		logging.FromContext(r.Context()).Error("climate template render failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render page")
+		return
+	}
+}
+
+// climate loads the monthly climate of a station and arranges it by year.
+func (c *weatherControllerImpl) climate(ctx context.Context, stationID string) (types.Climate, error) {
+	months, err := c.repository.GetMonthlyClimate(ctx, stationID)
+	if err != nil {
+		return types.Climate{}, err
+	}
+	return buildClimate(stationID, months)
+}
+
+// buildClimate groups months (ordered oldest first) into years and fills the
+// year-over-year temperature change of each month.
+func buildClimate(stationID string, months []types.MonthlyClimate) (types.Climate, error) {
+	out := types.Climate{StationID: stationID, Years: []types.ClimateYear{}}
+	byMonth := make(map[string]*types.MonthlyClimate, len(months))
+	for i := range months {
+		m := &months[i]
+		t, err := time.Parse("2006-01", m.Month)
+		if err != nil {
+			return types.Climate{}, fmt.Errorf("parse climate month %q: %w", m.Month, err)
+		}
+		if prev := byMonth[t.AddDate(-1, 0, 0).Format("2006-01")]; prev != nil && prev.Temperature.Avg != nil && m.Temperature.Avg != nil {
+			change := *m.Temperature.Avg - *prev.Temperature.Avg
+			m.TemperatureAvgChange = &change
+		}
+		byMonth[m.Month] = m
+		if n := len(out.Years); n == 0 || out.Years[n-1].Year != t.Year() {
+			out.Years = append(out.Years, types.ClimateYear{Year: t.Year()})
+		}
+		out.Years[len(out.Years)-1].Months[t.Month()-1] = m
+	}
+	return out, nil
+}
+
+// formatClimateValue formats v with format, or returns "" when v is nil.
+func formatClimateValue(v *float64, format string) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf(format, *v)
+}
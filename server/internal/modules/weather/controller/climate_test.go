@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func climateMonth(month string, avg float64) types.MonthlyClimate {
+	return types.MonthlyClimate{Month: month, Days: 1, Count: 1, Temperature: types.MetricSummary{Count: 1, Avg: &avg}}
+}
+
+func Test_buildClimate(t *testing.T) {
+	months := []types.MonthlyClimate{
+		climateMonth("2024-03", 5.0),
+		climateMonth("2024-04", 9.0),
+		climateMonth("2025-03", 6.5),
+		{Month: "2025-04", Days: 2, Count: 2},
+		climateMonth("2025-05", 12.0),
+	}
+
+	climate, err := buildClimate("1", months)
+	if err != nil {
+		t.Fatalf("buildClimate() = %v; want nil", err)
+	}
+	if len(climate.Years) != 2 || climate.Years[0].Year != 2024 || climate.Years[1].Year != 2025 {
+		t.Fatalf("Years = %+v; want 2024, 2025", climate.Years)
+	}
+	y2024, y2025 := climate.Years[0].Months, climate.Years[1].Months
+	if y2024[0] != nil || y2024[2] == nil || y2024[2].TemperatureAvgChange != nil {
+		t.Errorf("2024 = %+v; want March without a change and no January", y2024)
+	}
+	if got := y2025[2].TemperatureAvgChange; got == nil || *got != 1.5 {
+		t.Errorf("2025-03 change = %v; want 1.5", got)
+	}
+	if y2025[3].TemperatureAvgChange != nil {
+		t.Errorf("2025-04 change = %v; want nil without a temperature", *y2025[3].TemperatureAvgChange)
+	}
+	if y2025[4].TemperatureAvgChange != nil {
+		t.Errorf("2025-05 change = %v; want nil without the previous year", *y2025[4].TemperatureAvgChange)
+	}
+
+	if _, err := buildClimate("1", []types.MonthlyClimate{{Month: "2025"}}); err == nil {
+		t.Error("buildClimate(invalid month) = nil error; want error")
+	}
+}
+
+func Test_handleClimate(t *testing.T) {
+	tests := []struct {
+		name       string
+		repo       *mockRepo
+		wantStatus int
+		wantYears  int
+	}{
+		{"matrix", &mockRepo{station: types.Station{ID: "1"}, climate: []types.MonthlyClimate{climateMonth("2024-01", 1), climateMonth("2025-01", 2)}}, http.StatusOK, 2},
+		{"no rollups", &mockRepo{station: types.Station{ID: "1"}}, http.StatusOK, 0},
+		{"unknown station", &mockRepo{stationErr: repository.ErrStationNotFound}, http.StatusNotFound, 0},
+		{"repository error", &mockRepo{station: types.Station{ID: "1"}, climateErr: errors.New("db error")}, http.StatusInternalServerError, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := NewWeatherController(tt.repo, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/climate", nil)
+			req.SetPathValue("id", "1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleClimate(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var got types.Climate
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.StationID != "1" || got.Years == nil || len(got.Years) != tt.wantYears {
+				t.Errorf("climate = %+v; want station 1 with %d years", got, tt.wantYears)
+			}
+		})
+	}
+}
+
+func Test_handleClimatePage_JSON(t *testing.T) {
+	repo := &mockRepo{station: types.Station{ID: "1"}, climate: []types.MonthlyClimate{climateMonth("2025-01", 2)}}
+	ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+	req := httptest.NewRequest(http.MethodGet, "/climate/1", nil)
+	req.Header.Set("Accept", "application/json")
+	req.SetPathValue("id", "1")
+	rec := httptest.NewRecorder()
+
+	ctrl.handleClimatePage(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	var got types.Climate
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.StationID != "1" || len(got.Years) != 1 {
+		t.Errorf("climate = %+v; want station 1 with 1 year", got)
+	}
+}
@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+// defaultPairTolerance is how far apart a shadow and primary reading may be to count as simultaneous.
+const defaultPairTolerance = 2 * time.Minute
+
+type createShadowRequest struct {
+	Name string `json:"name"`
+}
+
+// handleCreateShadow registers a shadow station for the primary station {id}.
+func (c *weatherControllerImpl) handleCreateShadow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	var req createShadowRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.WriteError(w, http.StatusBadRequest, "'name' is required")
+		return
+	}
+
+	station, err := c.repository.CreateShadowStation(r.Context(), id, req.Name)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if errors.Is(err, repository.ErrQuotaExceeded) {
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if errors.Is(err, repository.ErrShadowConflict) {
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, repository.ErrInvalidShadow) {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create shadow station failed", "station_id", id, "name", req.Name, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to create shadow station")
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, station)
+}
+
+// handleComparison reports bias and drift of shadow station {id} against its primary.
+func (c *weatherControllerImpl) handleComparison(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	metric, from, to, err := parseMetricRangeQuery(r, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	tolerance := defaultPairTolerance
+	if s := r.URL.Query().Get("tolerance"); s != "" {
+		tolerance, err = time.ParseDuration(s)
+		if err != nil || tolerance <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "invalid 'tolerance' (expected positive duration, e.g. 2m)")
+			return
+		}
+	}
+
+	shadow, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if shadow.ShadowOf == "" {
+		utils.WriteError(w, http.StatusBadRequest, "station is not a shadow station")
+		return
+	}
+
+	primarySeries, err := c.repository.GetMetricSeries(r.Context(), shadow.ShadowOf, metric, from, to)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	shadowSeries, err := c.repository.GetMetricSeries(r.Context(), shadow.ID, metric, from, to)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	cmp := compareSeries(primarySeries, shadowSeries, tolerance)
+	cmp.PrimaryID = shadow.ShadowOf
+	cmp.ShadowID = shadow.ID
+	cmp.Metric = metric
+	cmp.From = from
+	cmp.To = to
+	utils.WriteJSON(w, http.StatusOK, cmp)
+}
+
+// compareSeries pairs each shadow point with the nearest primary point within
+// tolerance and summarizes the differences (shadow − primary). Both series must
+// be sorted by time. Drift is the least-squares slope of the difference per day.
+func compareSeries(primary, shadow []types.MetricPoint, tolerance time.Duration) types.Comparison {
+	var (
+		n                  int
+		sumDiff, sumAbs    float64
+		sumX, sumXX, sumXY float64
+		origin             time.Time
+	)
+	j := 0
+	for _, s := range shadow {
+		for j+1 < len(primary) && absDuration(primary[j+1].Time.Sub(s.Time)) <= absDuration(primary[j].Time.Sub(s.Time)) {
+			j++
+		}
+		if j >= len(primary) || absDuration(primary[j].Time.Sub(s.Time)) > tolerance {
+			continue
+		}
+		if n == 0 {
+			origin = s.Time
+		}
+		diff := s.Value - primary[j].Value
+		x := s.Time.Sub(origin).Hours() / 24
+		n++
+		sumDiff += diff
+		sumAbs += math.Abs(diff)
+		sumX += x
+		sumXX += x * x
+		sumXY += x * diff
+	}
+
+	out := types.Comparison{Pairs: n}
+	if n == 0 {
+		return out
+	}
+	bias := sumDiff / float64(n)
+	mae := sumAbs / float64(n)
+	out.Bias = &bias
+	out.MeanAbsError = &mae
+	if denom := float64(n)*sumXX - sumX*sumX; n >= 2 && denom > 0 {
+		slope := (float64(n)*sumXY - sumX*sumDiff) / denom
+		out.DriftPerDay = &slope
+	}
+	return out
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_compareSeries(t *testing.T) {
+	t0 := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	pt := func(offset time.Duration, v float64) types.MetricPoint {
+		return types.MetricPoint{Time: t0.Add(offset), Value: v}
+	}
+
+	t.Run("constant bias has zero drift", func(t *testing.T) {
+		primary := []types.MetricPoint{pt(0, 20), pt(24*time.Hour, 21), pt(48*time.Hour, 22)}
+		shadow := []types.MetricPoint{pt(30*time.Second, 20.5), pt(24*time.Hour+10*time.Second, 21.5), pt(48*time.Hour, 22.5)}
+
+		got := compareSeries(primary, shadow, time.Minute)
+
+		if got.Pairs != 3 {
+			t.Fatalf("Pairs = %d; want 3", got.Pairs)
+		}
+		if math.Abs(*got.Bias-0.5) > 1e-9 || math.Abs(*got.MeanAbsError-0.5) > 1e-9 {
+			t.Errorf("Bias, MAE = %v, %v; want 0.5, 0.5", *got.Bias, *got.MeanAbsError)
+		}
+		if got.DriftPerDay == nil || math.Abs(*got.DriftPerDay) > 1e-3 {
+			t.Errorf("DriftPerDay = %v; want ~0", got.DriftPerDay)
+		}
+	})
+
+	t.Run("growing difference is reported as drift", func(t *testing.T) {
+		primary := []types.MetricPoint{pt(0, 10), pt(24*time.Hour, 10), pt(48*time.Hour, 10)}
+		shadow := []types.MetricPoint{pt(0, 10), pt(24*time.Hour, 11), pt(48*time.Hour, 12)}
+
+		got := compareSeries(primary, shadow, time.Minute)
+
+		if got.DriftPerDay == nil || math.Abs(*got.DriftPerDay-1) > 1e-9 {
+			t.Errorf("DriftPerDay = %v; want 1", got.DriftPerDay)
+		}
+	})
+
+	t.Run("points outside tolerance are not paired", func(t *testing.T) {
+		primary := []types.MetricPoint{pt(0, 10)}
+		shadow := []types.MetricPoint{pt(10*time.Minute, 12)}
+
+		got := compareSeries(primary, shadow, time.Minute)
+
+		if got.Pairs != 0 || got.Bias != nil || got.DriftPerDay != nil {
+			t.Errorf("compareSeries = %+v; want no pairs", got)
+		}
+	})
+
+	t.Run("pairs with nearest primary point", func(t *testing.T) {
+		primary := []types.MetricPoint{pt(0, 0), pt(time.Minute, 10), pt(2*time.Minute, 100)}
+		shadow := []types.MetricPoint{pt(70*time.Second, 11)}
+
+		got := compareSeries(primary, shadow, time.Minute)
+
+		if got.Pairs != 1 || *got.Bias != 1 {
+			t.Errorf("compareSeries = %+v; want one pair with bias 1", got)
+		}
+		if got.DriftPerDay != nil {
+			t.Errorf("DriftPerDay = %v; want nil for a single pair", *got.DriftPerDay)
+		}
+	})
+}
+
+func Test_handleCreateShadow(t *testing.T) {
+	t.Run("creates shadow station", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/stations/1/shadows", strings.NewReader(`{"name":" new-sensor "}`))
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleCreateShadow(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusCreated)
+		}
+		var got types.Station
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.Name != "new-sensor" || got.ShadowOf != "1" {
+			t.Errorf("station = %+v; want new-sensor shadowing 1", got)
+		}
+	})
+
+	tests := []struct {
+		name string
+		repo *mockRepo
+		body string
+		want int
+	}{
+		{"invalid JSON", &mockRepo{}, `{`, http.StatusBadRequest},
+		{"missing name", &mockRepo{}, `{"name":"  "}`, http.StatusBadRequest},
+		{"unknown primary", &mockRepo{shadowErr: repository.ErrStationNotFound}, `{"name":"x"}`, http.StatusNotFound},
+		{"invalid link", &mockRepo{shadowErr: fmt.Errorf("%w: a station cannot shadow itself", repository.ErrInvalidShadow)}, `{"name":"x"}`, http.StatusBadRequest},
+		{"storage failure", &mockRepo{shadowErr: errors.New("upsert shadow station \"x\": SQLITE_BUSY")}, `{"name":"x"}`, http.StatusInternalServerError},
+		{"linked elsewhere", &mockRepo{shadowErr: fmt.Errorf("%w: station %q already shadows station 2", repository.ErrShadowConflict, "x")}, `{"name":"x"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := NewWeatherController(tt.repo, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/stations/1/shadows", strings.NewReader(tt.body))
+			req.SetPathValue("id", "1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleCreateShadow(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusInternalServerError && strings.Contains(rec.Body.String(), "SQLITE") {
+				t.Errorf("body = %q; want the storage error left out", rec.Body)
+			}
+		})
+	}
+}
+
+func Test_handleComparison(t *testing.T) {
+	now := time.Now().UTC()
+
+	t.Run("compares shadow with primary", func(t *testing.T) {
+		repo := &mockRepo{
+			station: types.Station{ID: "2", Name: "new", ShadowOf: "1"},
+			series: map[string][]types.MetricPoint{
+				"1": {{Time: now.Add(-time.Hour), Value: 20}},
+				"2": {{Time: now.Add(-time.Hour), Value: 21}},
+			},
+		}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/2/comparison", nil)
+		req.SetPathValue("id", "2")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleComparison(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		var got types.Comparison
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.PrimaryID != "1" || got.ShadowID != "2" || got.Pairs != 1 || got.Bias == nil || *got.Bias != 1 {
+			t.Errorf("comparison = %+v; want 1 vs 2 with bias 1", got)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		repo   *mockRepo
+		target string
+		want   int
+	}{
+		{"invalid tolerance", &mockRepo{}, "/c?tolerance=abc", http.StatusBadRequest},
+		{"invalid metric", &mockRepo{}, "/c?metric=wind", http.StatusBadRequest},
+		{"unknown station", &mockRepo{stationErr: repository.ErrStationNotFound}, "/c", http.StatusNotFound},
+		{"station lookup fails", &mockRepo{stationErr: errors.New("db error")}, "/c", http.StatusInternalServerError},
+		{"not a shadow", &mockRepo{station: types.Station{ID: "2"}}, "/c", http.StatusBadRequest},
+		{"series fails", &mockRepo{station: types.Station{ID: "2", ShadowOf: "1"}, seriesErr: errors.New("db error")}, "/c", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := NewWeatherController(tt.repo, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.SetPathValue("id", "2")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleComparison(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// validator derives the ETag and Last-Modified of a response from what it
+// was built from, so polling clients get 304 Not Modified without the server
+// building the body again.
+type validator struct {
+	h        hash.Hash64
+	modified time.Time
+}
+
+func newValidator() *validator {
+	return &validator{h: fnv.New64a()}
+}
+
+// add folds values into the ETag.
+func (v *validator) add(values ...any) {
+	fmt.Fprintln(v.h, values...)
+}
+
+// addNewest folds in a station's newest reading, if any: its time, and its
+// receipt time, which a partial reading merged into it advances. The receipt
+// time, or the reading time for readings stored without one, is the
+// Last-Modified.
+func (v *validator) addNewest(latest []types.Reading) {
+	if len(latest) == 0 {
+		v.add("none")
+		return
+	}
+	r := latest[0]
+	modified := r.Time
+	if r.ReceivedAt != nil {
+		modified = *r.ReceivedAt
+	}
+	v.add(r.StationID, r.Time.UnixNano(), modified.UnixNano())
+	if modified.After(v.modified) {
+		v.modified = modified
+	}
+}
+
+// etag returns a weak entity tag: the body may be sent compressed.
+func (v *validator) etag() string {
+	return fmt.Sprintf(`W/"%016x"`, v.h.Sum64())
+}
+
+// addStations folds in the station metadata.
+func (v *validator) addStations(stations []types.Station) {
+	if err := json.NewEncoder(v.h).Encode(stations); err != nil {
+		// Unreachable for stations; a constant keeps the ETag valid.
+		v.add("stations")
+	}
+}
@@ -1,7 +1,11 @@
 package controller
 
 import (
+	"cloudpico-server/internal/httpapi"
+	"cloudpico-server/internal/modules/weather/health"
+	"cloudpico-server/internal/modules/weather/photos"
 	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/utils"
 	"net/http"
 )
 
@@ -11,18 +15,58 @@ type WeatherController interface {
 
 type weatherControllerImpl struct {
 	repository repository.WeatherRepository
+	photos     *photos.Store       // nil disables photo uploads
+	cookies    *utils.CookieSigner // nil leaves UI state cookies unsigned
+	health     *health.Tracker     // nil leaves stations unscored
 }
 
-func NewWeatherController(repository repository.WeatherRepository) WeatherController {
-	return &weatherControllerImpl{repository: repository}
+func NewWeatherController(repository repository.WeatherRepository, photoStore *photos.Store, cookies *utils.CookieSigner, healthTracker *health.Tracker) WeatherController {
+	return &weatherControllerImpl{repository: repository, photos: photoStore, cookies: cookies, health: healthTracker}
 }
 
 func (c *weatherControllerImpl) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /", c.handleDashboard)
 	mux.HandleFunc("GET /history", c.handleHistory)
+	mux.HandleFunc("GET /stations/{id}", c.handleStationPage)
+	mux.HandleFunc("GET /climate/{id}", c.handleClimatePage)
 	mux.HandleFunc("GET /partials/history", c.handleHistoryPartial)
 	mux.HandleFunc("GET /partials/stations", c.handleStationsPartial)
+	mux.HandleFunc("GET /partials/histogram", c.handleHistogramPartial)
+	mux.HandleFunc("GET /partials/gaps", c.handleGapsPartial)
+	mux.HandleFunc("GET /partials/card-metrics", c.handleCardMetricsPartial)
 	mux.HandleFunc("GET /api/v1/stations", c.handleStations)
+	mux.HandleFunc("POST /api/v1/stations", c.handleCreateStation)
+	mux.HandleFunc("GET /api/v1/stations.geojson", c.handleStationsGeoJSON)
+	mux.HandleFunc("GET /api/v1/favorites", c.handleFavorites)
+	mux.HandleFunc("PUT /api/v1/favorites", c.handleSetFavorites)
+	mux.HandleFunc("POST /api/v1/stations/{id}/favorite", c.handleAddFavorite)
+	mux.HandleFunc("DELETE /api/v1/stations/{id}/favorite", c.handleRemoveFavorite)
+	mux.HandleFunc("GET /api/v1/dashboard/metrics", c.handleCardMetrics)
+	mux.HandleFunc("PUT /api/v1/dashboard/metrics", c.handleSetCardMetrics)
+	mux.HandleFunc("POST /api/v1/dashboard/metrics/{metric}", c.handleEnableCardMetric)
+	mux.HandleFunc("DELETE /api/v1/dashboard/metrics/{metric}", c.handleDisableCardMetric)
 	mux.HandleFunc("GET /api/v1/stations/{id}/latest", c.handleLatest)
-	mux.HandleFunc("GET /api/v1/stations/{id}/readings", c.handleReadings)
+	mux.HandleFunc("GET /api/v1/stations/{id}/latest/metrics", c.handleLatestMetrics)
+	mux.Handle("GET /api/v1/stations/{id}/readings", httpapi.StreamingIf(http.HandlerFunc(c.handleReadings), streamsNDJSON))
+	mux.Handle("GET /api/v1/stations/{id}/readings.csv", httpapi.Streaming(http.HandlerFunc(c.handleReadingsCSV)))
+	mux.HandleFunc("GET /api/v1/stations/{id}/annotations", c.handleAnnotations)
+	mux.HandleFunc("POST /api/v1/stations/{id}/annotations", c.handleCreateAnnotation)
+	mux.HandleFunc("DELETE /api/v1/stations/{id}/annotations/{annotationId}", c.handleDeleteAnnotation)
+	mux.HandleFunc("GET /api/v1/stations/{id}/snapshots", c.handleSnapshots)
+	mux.HandleFunc("POST /api/v1/stations/{id}/snapshots", c.handleCreateSnapshot)
+	mux.HandleFunc("DELETE /api/v1/stations/{id}/snapshots/{snapshotId}", c.handleDeleteSnapshot)
+	mux.HandleFunc("GET /api/v1/stations/{id}/histogram", c.handleHistogram)
+	mux.HandleFunc("GET /api/v1/stations/{id}/aggregate", c.handleAggregate)
+	mux.HandleFunc("GET /api/v1/stations/{id}/stats", c.handleStats)
+	mux.HandleFunc("GET /api/v1/stations/{id}/gaps", c.handleGaps)
+	mux.HandleFunc("GET /api/v1/stations/{id}/climate", c.handleClimate)
+	mux.HandleFunc("POST /api/v1/stations/{id}/shadows", c.handleCreateShadow)
+	mux.HandleFunc("GET /api/v1/stations/{id}/comparison", c.handleComparison)
+	mux.HandleFunc("PUT /api/v1/stations/{id}/location", c.handleSetLocation)
+	mux.HandleFunc("PUT /api/v1/stations/{id}/retention", c.handleSetRetention)
+	mux.HandleFunc("POST /api/v1/stations/{id}/photo", c.handleUploadPhoto)
+	mux.HandleFunc("DELETE /api/v1/stations/{id}/photo", c.handleDeletePhoto)
+	if c.photos != nil {
+		mux.Handle("GET "+photos.URLPrefix, c.photos.Handler())
+	}
 }
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+// readingsCSVHeader names the columns of the readings CSV export.
+var readingsCSVHeader = []string{"time", "temperature_c", "humidity_pct", "pressure_hpa", "received_at"}
+
+// csvFileTime formats range bounds in export file names.
+const csvFileTime = "20060102T150405Z"
+
+// handleReadingsCSV streams the readings of station {id} over [from, to]
+// (default: the last 24 hours) as a CSV download, oldest first, writing each
+// row as it is read. Metrics the station did not report are empty cells.
+func (c *weatherControllerImpl) handleReadingsCSV(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	from, to, err := parseRangeQuery(r, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("readings csv: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+
+	filename := "station-" + station.ID + "_" + from.UTC().Format(csvFileTime) + "_" + to.UTC().Format(csvFileTime) + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	body := &writeTracker{w: w}
+	out := csv.NewWriter(body)
+	err = out.Write(readingsCSVHeader)
+	if err == nil {
+		err = c.repository.EachReading(r.Context(), station.ID, from, to, func(sr types.StoredReading) error {
+			return out.Write(readingsCSVRecord(sr))
+		})
+	}
+	if err == nil {
+		out.Flush()
+		err = out.Error()
+	}
+	finishExport(r.Context(), w, body, "readings csv", id, err)
+}
+
+// finishExport ends a streamed export that failed with err, if it did. Before
+// any output the client gets an error response; after, the status is sent, so
+// the response is aborted and the client sees a failed download rather than
+// a truncated one.
+func finishExport(ctx context.Context, w http.ResponseWriter, body *writeTracker, export string, stationID string, err error) {
+	if err == nil {
+		return
+	}
+	logging.FromContext(ctx).Error(export+": export failed", "station_id", stationID, "error", err)
+	if !body.written {
+		utils.WriteError(w, http.StatusInternalServerError, "failed to export readings")
+		return
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// readingsCSVRecord formats sr as a row of readingsCSVHeader.
+func readingsCSVRecord(sr types.StoredReading) []string {
+	record := []string{sr.Time.UTC().Format(time.RFC3339Nano), "", "", "", ""}
+	for i, v := range []*float64{sr.Temperature, sr.Humidity, sr.Pressure} {
+		if v != nil {
+			record[i+1] = strconv.FormatFloat(*v, 'f', -1, 64)
+		}
+	}
+	if sr.ReceivedAt != nil {
+		record[4] = sr.ReceivedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return record
+}
+
+// writeTracker records whether anything was written to w.
+type writeTracker struct {
+	w       io.Writer
+	written bool
+}
+
+func (t *writeTracker) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleReadingsCSV(t *testing.T) {
+	serve := func(repo *mockRepo, query string) *httptest.ResponseRecorder {
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/readings.csv"+query, nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		ctrl.handleReadingsCSV(rec, req)
+		return rec
+	}
+	ts := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	temp, hum := 21.5, 40.0
+
+	t.Run("streams readings as a CSV download", func(t *testing.T) {
+		received := ts.Add(2 * time.Second)
+		repo := &mockRepo{
+			station: types.Station{ID: "1"},
+			stored: []types.StoredReading{
+				{Time: ts, Temperature: &temp, Humidity: &hum, ReceivedAt: &received},
+				{Time: ts.Add(time.Minute), Humidity: &hum},
+			},
+		}
+		rec := serve(repo, "?from=2025-02-01T00:00:00Z&to=2025-03-01T00:00:00Z")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d, body %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		wantDisposition := `attachment; filename=station-1_20250201T000000Z_20250301T000000Z.csv`
+		if cd := rec.Header().Get("Content-Disposition"); cd != wantDisposition {
+			t.Errorf("Content-Disposition = %q; want %q", cd, wantDisposition)
+		}
+		want := "time,temperature_c,humidity_pct,pressure_hpa,received_at\n" +
+			"2025-02-01T10:00:00Z,21.5,40,,2025-02-01T10:00:02Z\n" +
+			"2025-02-01T10:01:00Z,,40,,\n"
+		if got := rec.Body.String(); got != want {
+			t.Errorf("body = %q; want %q", got, want)
+		}
+		if !repo.lastReadingsTo.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("to = %v; want 2025-03-01", repo.lastReadingsTo)
+		}
+	})
+
+	t.Run("returns 400 for an invalid range", func(t *testing.T) {
+		rec := serve(&mockRepo{}, "?from=yesterday")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("returns 404 for unknown station", func(t *testing.T) {
+		rec := serve(&mockRepo{stationErr: repository.ErrStationNotFound}, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("returns 500 when the export fails before any output", func(t *testing.T) {
+		rec := serve(&mockRepo{station: types.Station{ID: "1"}, storedErr: errors.New("db error")}, "")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("aborts the response when the export fails midway", func(t *testing.T) {
+		// Enough rows to overflow the CSV writer's buffer before the error.
+		stored := make([]types.StoredReading, 500)
+		for i := range stored {
+			stored[i] = types.StoredReading{Time: ts.Add(time.Duration(i) * time.Minute), Temperature: &temp}
+		}
+		repo := &mockRepo{station: types.Station{ID: "1"}, stored: stored, storedErr: errors.New("db error")}
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("recovered %v; want http.ErrAbortHandler", r)
+			}
+		}()
+		serve(repo, "")
+	})
+}
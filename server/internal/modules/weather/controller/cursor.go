@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/utils"
+)
+
+// errInvalidCursor is returned for malformed cursors and ones issued for
+// another station.
+var errInvalidCursor = errors.New("invalid 'cursor'")
+
+// Readings API cursors are opaque to clients: base64url of
+// "<station_id>|<direction>|<ts>", where direction is cursorOlder or
+// cursorNewer and ts the RFC 3339 time of the reading the page continues from.
+const (
+	cursorOlder = "o"
+	cursorNewer = "n"
+)
+
+// encodeReadingsCursor returns the API cursor of c for station stationID.
+func encodeReadingsCursor(stationID string, c repository.ReadingsCursor) string {
+	dir := cursorOlder
+	if c.Newer {
+		dir = cursorNewer
+	}
+	raw := stationID + "|" + dir + "|" + c.Time.UTC().Format(time.RFC3339Nano)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeReadingsCursor parses an API cursor of station stationID; "" is the
+// newest page.
+func decodeReadingsCursor(stationID, s string) (repository.ReadingsCursor, error) {
+	if s == "" {
+		return repository.ReadingsCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return repository.ReadingsCursor{}, errInvalidCursor
+	}
+	station, rest, ok := strings.Cut(string(raw), "|")
+	if !ok || station != stationID {
+		return repository.ReadingsCursor{}, errInvalidCursor
+	}
+	dir, ts, ok := strings.Cut(rest, "|")
+	if !ok || (dir != cursorOlder && dir != cursorNewer) {
+		return repository.ReadingsCursor{}, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return repository.ReadingsCursor{}, errInvalidCursor
+	}
+	return repository.ReadingsCursor{Time: t, Newer: dir == cursorNewer}, nil
+}
+
+// readingsCursorPage loads the page of readings of station id at the cursor
+// query parameter: limit readings, newest first, seeking on (station_id, ts)
+// so deep pages cost the same as the first. The envelope's links and
+// NextCursor/PrevCursor continue towards older and newer readings; Offset is
+// always 0. Readings are in units and carry only the metrics in fields, all
+// when it is empty.
+func (c *weatherControllerImpl) readingsCursorPage(r *http.Request, id string, from, to time.Time, limit int, fields []string, units string) (readingsPage, error) {
+	param := r.URL.Query().Get("cursor")
+	cursor, err := decodeReadingsCursor(id, param)
+	if err != nil {
+		return readingsPage{}, err
+	}
+	readings, err := c.repository.GetReadingsPage(r.Context(), id, from, to, cursor, limit+1, fields)
+	if err != nil {
+		return readingsPage{}, err
+	}
+	// One reading past the page tells whether more follow in the cursor's
+	// direction.
+	more := len(readings) > limit
+	if more {
+		if cursor.Newer {
+			readings = readings[1:] // newest first; drop the newest
+		} else {
+			readings = readings[:limit]
+		}
+	}
+	hasOlder, hasNewer := more, !cursor.Time.IsZero()
+	if cursor.Newer {
+		hasOlder, hasNewer = !cursor.Time.IsZero(), more
+	}
+
+	total, err := c.repository.GetReadingsCount(r.Context(), id, from, to)
+	if err != nil {
+		return readingsPage{}, err
+	}
+	page := utils.NewPage(r, selectFields(convertReadings(readings, units), fields), total, utils.Pagination{Limit: limit})
+	page.Links = utils.PageLinks{Self: readingsCursorURL(r, limit, param)}
+	out := readingsPage{Page: page}
+	if len(readings) != 0 {
+		if hasOlder {
+			out.NextCursor = encodeReadingsCursor(id, repository.ReadingsCursor{Time: readings[len(readings)-1].Time})
+			out.Links.Next = readingsCursorURL(r, limit, out.NextCursor)
+		}
+		if hasNewer {
+			out.PrevCursor = encodeReadingsCursor(id, repository.ReadingsCursor{Time: readings[0].Time, Newer: true})
+			out.Links.Prev = readingsCursorURL(r, limit, out.PrevCursor)
+		}
+	}
+	return out, nil
+}
+
+// readingsCursorURL returns the request URL for the page at cursor, keeping
+// the other query parameters.
+func readingsCursorURL(r *http.Request, limit int, cursor string) string {
+	u := *r.URL
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Del("offset")
+	if cursor == "" {
+		q.Del("cursor")
+	} else {
+		q.Set("cursor", cursor)
+	}
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
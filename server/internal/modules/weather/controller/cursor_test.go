@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_readingsCursor(t *testing.T) {
+	c := repository.ReadingsCursor{Time: time.Date(2025, 3, 1, 10, 0, 0, 500, time.UTC), Newer: true}
+	s := encodeReadingsCursor("1", c)
+	got, err := decodeReadingsCursor("1", s)
+	if err != nil || got != c {
+		t.Errorf("decode(encode(%+v)) = %+v, %v", c, got, err)
+	}
+	for _, bad := range []string{"!", "bm90LWEtY3Vyc29y", encodeReadingsCursor("2", c)} {
+		if _, err := decodeReadingsCursor("1", bad); err != errInvalidCursor {
+			t.Errorf("decode(%q) error = %v; want errInvalidCursor", bad, err)
+		}
+	}
+}
+
+func Test_handleReadings_cursor(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	readings := []types.Reading{
+		{StationID: "1", Time: base},
+		{StationID: "1", Time: base.Add(-time.Hour)},
+		{StationID: "1", Time: base.Add(-2 * time.Hour)},
+	}
+	get := func(t *testing.T, repo *mockRepo, query string) (*httptest.ResponseRecorder, readingsPage) {
+		t.Helper()
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/readings?"+query, nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		ctrl.handleReadings(rec, req)
+		var page readingsPage
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return rec, page
+	}
+
+	t.Run("first page links to older readings", func(t *testing.T) {
+		repo := &mockRepo{readings: readings, readingsCount: 3}
+		rec, page := get(t, repo, "limit=2")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		if repo.lastReadingsLimit != 3 || repo.readingsCursors[0] != (repository.ReadingsCursor{}) {
+			t.Errorf("limit = %d, cursors %+v; want one past the page from the newest", repo.lastReadingsLimit, repo.readingsCursors)
+		}
+		if len(page.Items) != 2 || page.Total != 3 || page.PrevCursor != "" || page.Links.Prev != "" {
+			t.Errorf("page = %+v; want 2 of 3 readings and no newer page", page)
+		}
+		want := encodeReadingsCursor("1", repository.ReadingsCursor{Time: base.Add(-time.Hour)})
+		if page.NextCursor != want {
+			t.Errorf("nextCursor = %q; want %q", page.NextCursor, want)
+		}
+		next, err := url.Parse(page.Links.Next)
+		if err != nil || next.Query().Get("cursor") != want || next.Query().Has("offset") {
+			t.Errorf("links.next = %q; want the cursor and no offset", page.Links.Next)
+		}
+		if rec.Header().Get("Deprecation") != "" {
+			t.Error("cursor page marked deprecated")
+		}
+	})
+
+	t.Run("next page seeks from the cursor", func(t *testing.T) {
+		repo := &mockRepo{readings: readings[2:], readingsCount: 3}
+		cursor := repository.ReadingsCursor{Time: base.Add(-time.Hour)}
+		_, page := get(t, repo, "limit=2&cursor="+encodeReadingsCursor("1", cursor))
+
+		if len(repo.readingsCursors) != 1 || repo.readingsCursors[0] != cursor {
+			t.Errorf("cursors = %+v; want %+v", repo.readingsCursors, cursor)
+		}
+		if len(page.Items) != 1 || page.NextCursor != "" || page.PrevCursor == "" {
+			t.Errorf("page = %+v; want the last page with a newer page", page)
+		}
+	})
+
+	t.Run("rejects foreign cursor", func(t *testing.T) {
+		rec, _ := get(t, &mockRepo{}, "cursor="+encodeReadingsCursor("2", repository.ReadingsCursor{Time: base}))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("offset is deprecated", func(t *testing.T) {
+		rec, _ := get(t, &mockRepo{readings: readings, readingsCount: 3}, "offset=1")
+		if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "true" {
+			t.Errorf("status = %d, Deprecation %q; want 200 and true", rec.Code, rec.Header().Get("Deprecation"))
+		}
+		rec, _ = get(t, &mockRepo{}, "offset=1&cursor=x")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("cursor with offset: status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
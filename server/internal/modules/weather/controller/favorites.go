@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+// Favorite stations are kept per browser in a signed cookie, most important
+// first; the dashboard shows them before the other stations.
+const (
+	favoritesCookieName   = "weather_favorites"
+	favoritesCookieMaxAge = 365 * 24 * 60 * 60 // 1 year in seconds
+	maxFavorites          = 100
+	// favoritesChangedEvent is sent in HX-Trigger so the dashboard refreshes
+	// right after a change.
+	favoritesChangedEvent = "favorites-changed"
+)
+
+type favoritesBody struct {
+	StationIDs []string `json:"stationIds"`
+}
+
+// handleFavorites returns the favorite station IDs in dashboard order.
+func (c *weatherControllerImpl) handleFavorites(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, favoritesBody{StationIDs: readFavoritesCookie(c.cookies, r)})
+}
+
+// handleSetFavorites replaces the favorites with the given station IDs, in
+// order; this is also how favorites are reordered.
+func (c *weatherControllerImpl) handleSetFavorites(w http.ResponseWriter, r *http.Request) {
+	var req favoritesBody
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16384)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(req.StationIDs) > maxFavorites {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("at most %d favorites", maxFavorites))
+		return
+	}
+	stations, err := c.repository.GetStations(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set favorites: get stations failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
+		return
+	}
+	known := make(map[string]bool, len(stations))
+	for _, s := range stations {
+		known[s.ID] = true
+	}
+	ids := make([]string, 0, len(req.StationIDs))
+	for _, id := range req.StationIDs {
+		if !known[id] {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("unknown station %q", id))
+			return
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	c.writeFavorites(w, ids)
+}
+
+// handleAddFavorite appends station {id} to the favorites.
+func (c *weatherControllerImpl) handleAddFavorite(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, err := c.repository.GetStation(r.Context(), id); errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	} else if err != nil {
+		logging.FromContext(r.Context()).Error("add favorite: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	ids := readFavoritesCookie(c.cookies, r)
+	if !slices.Contains(ids, id) {
+		if len(ids) >= maxFavorites {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("at most %d favorites", maxFavorites))
+			return
+		}
+		ids = append(ids, id)
+	}
+	c.writeFavorites(w, ids)
+}
+
+// handleRemoveFavorite removes station {id} from the favorites.
+func (c *weatherControllerImpl) handleRemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	ids := slices.DeleteFunc(readFavoritesCookie(c.cookies, r), func(s string) bool { return s == id })
+	c.writeFavorites(w, ids)
+}
+
+func (c *weatherControllerImpl) writeFavorites(w http.ResponseWriter, ids []string) {
+	writeFavoritesCookie(c.cookies, w, ids)
+	w.Header().Set("HX-Trigger", favoritesChangedEvent)
+	utils.WriteJSON(w, http.StatusOK, favoritesBody{StationIDs: ids})
+}
+
+// readFavoritesCookie returns the favorite station IDs, or none when the
+// cookie is missing or fails signature checks.
+func readFavoritesCookie(cookies *utils.CookieSigner, r *http.Request) []string {
+	value, err := cookies.ReadCookie(r, favoritesCookieName)
+	if err != nil || value == "" {
+		return []string{}
+	}
+	ids := strings.Split(value, ",")
+	if len(ids) > maxFavorites {
+		ids = ids[:maxFavorites]
+	}
+	return ids
+}
+
+func writeFavoritesCookie(cookies *utils.CookieSigner, w http.ResponseWriter, ids []string) {
+	cookies.SetCookie(w, &http.Cookie{
+		Name:     favoritesCookieName,
+		Value:    strings.Join(ids, ","),
+		Path:     "/",
+		MaxAge:   favoritesCookieMaxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   false, // set true if you serve over HTTPS only
+	})
+}
+
+// orderByFavorites returns stations with the favorites first, in favorites
+// order, followed by the others in their original order.
+func orderByFavorites(stations []types.Station, favorites []string) []types.Station {
+	rank := make(map[string]int, len(favorites))
+	for i, id := range favorites {
+		rank[id] = i
+	}
+	out := slices.Clone(stations)
+	slices.SortStableFunc(out, func(a, b types.Station) int {
+		ra, aFav := rank[a.ID]
+		rb, bFav := rank[b.ID]
+		switch {
+		case aFav && bFav:
+			return ra - rb
+		case aFav:
+			return -1
+		case bFav:
+			return 1
+		}
+		return 0
+	})
+	return out
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+func Test_favorites(t *testing.T) {
+	cookies := utils.NewCookieSigner([]byte("0123456789abcdef0123456789abcdef"))
+	repo := &mockRepo{
+		stations: []types.Station{{ID: "1", Name: "Attic"}, {ID: "2", Name: "Garden"}, {ID: "3", Name: "Roof"}},
+		station:  types.Station{ID: "3", Name: "Roof"},
+	}
+	ctrl := NewWeatherController(repo, nil, cookies, nil).(*weatherControllerImpl)
+	mux := http.NewServeMux()
+	ctrl.RegisterRoutes(mux)
+
+	// do sends a request carrying the favorites cookie from the previous response.
+	var cookie *http.Cookie
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == favoritesCookieName {
+				cookie = c
+			}
+		}
+		return rec
+	}
+	ids := func(rec *httptest.ResponseRecorder) []string {
+		var body favoritesBody
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return body.StationIDs
+	}
+
+	if got := ids(do(http.MethodGet, "/api/v1/favorites", "")); len(got) != 0 {
+		t.Errorf("initial favorites = %v; want none", got)
+	}
+
+	rec := do(http.MethodPut, "/api/v1/favorites", `{"stationIds":["2","1","2"]}`)
+	if rec.Code != http.StatusOK || rec.Header().Get("HX-Trigger") != favoritesChangedEvent {
+		t.Fatalf("PUT status = %d, HX-Trigger = %q; want 200, %s", rec.Code, rec.Header().Get("HX-Trigger"), favoritesChangedEvent)
+	}
+	if got, want := ids(rec), []string{"2", "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PUT favorites = %v; want %v", got, want)
+	}
+
+	if got, want := ids(do(http.MethodPost, "/api/v1/stations/3/favorite", "")), []string{"2", "1", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after POST favorites = %v; want %v", got, want)
+	}
+	if got, want := ids(do(http.MethodDelete, "/api/v1/stations/1/favorite", "")), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after DELETE favorites = %v; want %v", got, want)
+	}
+	if got, want := ids(do(http.MethodGet, "/api/v1/favorites", "")), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GET favorites = %v; want %v", got, want)
+	}
+
+	t.Run("PUT rejects unknown stations", func(t *testing.T) {
+		if rec := do(http.MethodPut, "/api/v1/favorites", `{"stationIds":["9"]}`); rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want 400", rec.Code)
+		}
+	})
+
+	t.Run("POST returns 404 for unknown station", func(t *testing.T) {
+		repo.stationErr = repository.ErrStationNotFound
+		defer func() { repo.stationErr = nil }()
+		if rec := do(http.MethodPost, "/api/v1/stations/9/favorite", ""); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want 404", rec.Code)
+		}
+	})
+
+	t.Run("ignores a tampered cookie", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/favorites", nil)
+		req.AddCookie(&http.Cookie{Name: favoritesCookieName, Value: "1,2"})
+		if got := readFavoritesCookie(cookies, req); len(got) != 0 {
+			t.Errorf("favorites = %v; want none", got)
+		}
+	})
+}
+
+func Test_orderByFavorites(t *testing.T) {
+	stations := []types.Station{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}}
+	got := orderByFavorites(stations, []string{"3", "9", "1"})
+	var order []string
+	for _, s := range got {
+		order = append(order, s.ID)
+	}
+	if want := []string{"3", "1", "2", "4"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v; want %v", order, want)
+	}
+	if stations[0].ID != "1" {
+		t.Error("orderByFavorites modified its input")
+	}
+}
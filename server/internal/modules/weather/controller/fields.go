@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// fieldsReading is a reading as served by the readings and latest endpoints:
+// metrics not selected with ?fields= are left out rather than reported as 0.
+// Without fields it encodes like types.Reading.
+type fieldsReading struct {
+	StationID   string     `json:"stationId"`
+	Time        time.Time  `json:"time"`
+	Value       *float64   `json:"value,omitempty"`
+	HumidityPct *float64   `json:"humidityPct,omitempty"`
+	PressureHpa *float64   `json:"pressureHpa,omitempty"`
+	ReceivedAt  *time.Time `json:"receivedAt,omitempty"`
+}
+
+// parseFieldsQuery parses fields, a comma-separated list of metrics to return.
+// Empty means every metric.
+func parseFieldsQuery(r *http.Request) ([]string, error) {
+	s := r.URL.Query().Get("fields")
+	if s == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if !histogramMetrics[f] {
+			return nil, errors.New("invalid 'fields' (allowed: temperature, humidity, pressure)")
+		}
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// selectFields returns readings with only the metrics in fields, all when it
+// is empty.
+func selectFields(readings []types.Reading, fields []string) []fieldsReading {
+	has := func(metric string) bool {
+		return len(fields) == 0 || slices.Contains(fields, metric)
+	}
+	out := make([]fieldsReading, len(readings))
+	for i, rd := range readings {
+		out[i] = fieldsReading{StationID: rd.StationID, Time: rd.Time, ReceivedAt: rd.ReceivedAt}
+		if has(types.MetricTemperature) {
+			out[i].Value = &rd.Value
+		}
+		if has(types.MetricHumidity) {
+			out[i].HumidityPct = &rd.HumidityPct
+		}
+		if has(types.MetricPressure) {
+			out[i].PressureHpa = &rd.PressureHpa
+		}
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_parseFieldsQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{query: "", want: nil},
+		{query: "fields=pressure", want: []string{types.MetricPressure}},
+		{query: "fields=temperature,%20humidity,temperature", want: []string{types.MetricTemperature, types.MetricHumidity}},
+		{query: "fields=wind", wantErr: true},
+		{query: "fields=temperature,", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/readings?"+tt.query, nil)
+			got, err := parseFieldsQuery(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFieldsQuery() err = %v; wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseFieldsQuery() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectFields(t *testing.T) {
+	readings := []types.Reading{{StationID: "1", Value: 0, HumidityPct: 40, PressureHpa: 1000}}
+
+	all := selectFields(readings, nil)[0]
+	if all.Value == nil || *all.Value != 0 || all.HumidityPct == nil || all.PressureHpa == nil {
+		t.Errorf("selectFields(nil) = %+v; want every metric, zeros included", all)
+	}
+	some := selectFields(readings, []string{types.MetricHumidity})[0]
+	if some.Value != nil || some.HumidityPct == nil || *some.HumidityPct != 40 || some.PressureHpa != nil {
+		t.Errorf("selectFields(humidity) = %+v; want humidity only", some)
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+// handleStationsGeoJSON serves all stations as a GeoJSON FeatureCollection with
+// the latest reading in each feature's properties.
+func (c *weatherControllerImpl) handleStationsGeoJSON(w http.ResponseWriter, r *http.Request) {
+	stations, err := c.repository.GetStations(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("geojson: get stations failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
+		return
+	}
+
+	fc := types.FeatureCollection{Type: "FeatureCollection", Features: make([]types.Feature, 0, len(stations))}
+	for _, s := range stations {
+		latest, err := c.repository.GetLatestReadings(r.Context(), s.ID, 1)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("geojson: get latest reading failed", "station_id", s.ID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load reading")
+			return
+		}
+		f := types.Feature{
+			Type:       "Feature",
+			ID:         s.ID,
+			Properties: types.StationProperties{Name: s.Name, ShadowOf: s.ShadowOf},
+		}
+		if s.Latitude != nil && s.Longitude != nil {
+			f.Geometry = &types.Geometry{Type: "Point", Coordinates: [2]float64{*s.Longitude, *s.Latitude}}
+		}
+		if len(latest) != 0 {
+			f.Properties.Latest = &latest[0]
+		}
+		fc.Features = append(fc.Features, f)
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(fc); err != nil {
+		logging.FromContext(r.Context()).Error("geojson: encode failed", "error", err)
+	}
+}
+
+type setLocationRequest struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// validateLocation checks that coordinates are set together and in range.
+func validateLocation(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("'latitude' and 'longitude' must be set together")
+	}
+	if latitude != nil && (*latitude < -90 || *latitude > 90) {
+		return errors.New("'latitude' must be between -90 and 90")
+	}
+	if longitude != nil && (*longitude < -180 || *longitude > 180) {
+		return errors.New("'longitude' must be between -180 and 180")
+	}
+	return nil
+}
+
+// handleSetLocation sets or (with both fields null) clears the coordinates of station {id}.
+func (c *weatherControllerImpl) handleSetLocation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req setLocationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if err := validateLocation(req.Latitude, req.Longitude); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err := c.repository.SetStationLocation(r.Context(), id, req.Latitude, req.Longitude)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set location failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to update station")
+		return
+	}
+	station, err := c.repository.GetStation(r.Context(), id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set location: reload station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, station)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleStationsGeoJSON(t *testing.T) {
+	lat, lon := 52.23, 21.01
+	serve := func(repo *mockRepo) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		NewWeatherController(repo, nil, nil, nil).RegisterRoutes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations.geojson", nil))
+		return rec
+	}
+
+	t.Run("returns feature collection", func(t *testing.T) {
+		repo := &mockRepo{
+			stations: []types.Station{
+				{ID: "1", Name: "Garden", Latitude: &lat, Longitude: &lon},
+				{ID: "2", Name: "Roof"},
+			},
+			latest: []types.Reading{{StationID: "1", Time: time.Date(2025, 2, 3, 14, 0, 0, 0, time.UTC), Value: 21.5}},
+		}
+
+		rec := serve(repo)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want 200", rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/geo+json" {
+			t.Errorf("Content-Type = %q; want application/geo+json", ct)
+		}
+		var fc types.FeatureCollection
+		if err := json.NewDecoder(rec.Body).Decode(&fc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+			t.Fatalf("collection = %+v; want 2 features", fc)
+		}
+		f := fc.Features[0]
+		if f.Geometry == nil || f.Geometry.Type != "Point" || f.Geometry.Coordinates != [2]float64{lon, lat} {
+			t.Errorf("geometry = %+v; want Point [lon, lat]", f.Geometry)
+		}
+		if f.Properties.Name != "Garden" || f.Properties.Latest == nil || f.Properties.Latest.Value != 21.5 {
+			t.Errorf("properties = %+v", f.Properties)
+		}
+		if fc.Features[1].Geometry != nil {
+			t.Errorf("station without location has geometry %+v; want null", fc.Features[1].Geometry)
+		}
+	})
+
+	t.Run("empty collection has features array", func(t *testing.T) {
+		rec := serve(&mockRepo{})
+		if !strings.Contains(rec.Body.String(), `"features":[]`) {
+			t.Errorf("body = %s; want empty features array", rec.Body.String())
+		}
+	})
+
+	t.Run("stations error", func(t *testing.T) {
+		if rec := serve(&mockRepo{stationsErr: errors.New("db")}); rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want 500", rec.Code)
+		}
+	})
+}
+
+func Test_handleSetLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		repo *mockRepo
+		body string
+		want int
+	}{
+		{"sets location", &mockRepo{station: types.Station{ID: "1"}}, `{"latitude": 52.2, "longitude": 21.0}`, http.StatusOK},
+		{"clears location", &mockRepo{station: types.Station{ID: "1"}}, `{"latitude": null, "longitude": null}`, http.StatusOK},
+		{"invalid json", &mockRepo{}, `{`, http.StatusBadRequest},
+		{"only one coordinate", &mockRepo{}, `{"latitude": 52.2}`, http.StatusBadRequest},
+		{"latitude out of range", &mockRepo{}, `{"latitude": 91, "longitude": 0}`, http.StatusBadRequest},
+		{"longitude out of range", &mockRepo{}, `{"latitude": 0, "longitude": -181}`, http.StatusBadRequest},
+		{"unknown station", &mockRepo{locationErr: repository.ErrStationNotFound}, `{"latitude": 1, "longitude": 1}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			NewWeatherController(tt.repo, nil, nil, nil).RegisterRoutes(mux)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/stations/1/location", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("passes coordinates to repository", func(t *testing.T) {
+		repo := &mockRepo{}
+		mux := http.NewServeMux()
+		NewWeatherController(repo, nil, nil, nil).RegisterRoutes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/stations/1/location", strings.NewReader(`{"latitude": 52.2, "longitude": 21.0}`)))
+
+		if repo.location[0] == nil || *repo.location[0] != 52.2 || repo.location[1] == nil || *repo.location[1] != 21.0 {
+			t.Errorf("location = %v; want [52.2 21.0]", repo.location)
+		}
+	})
+}
@@ -2,47 +2,81 @@ package controller
 
 import (
 	"bytes"
-	"log/slog"
+	"context"
+	"errors"
 	"net/http"
-	"sort"
+	"slices"
 	"time"
 
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/photos"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
 	"cloudpico-server/internal/modules/weather/views"
 	"cloudpico-server/internal/utils"
 )
 
+// stationReading builds a dashboard card from a station and its latest readings (at most one is used).
+func stationReading(s types.Station, latest []types.Reading) views.StationReading {
+	sr := views.StationReading{StationID: s.ID, StationName: s.Name}
+	if s.PhotoPath != "" {
+		sr.ThumbURL = photos.URL(photos.ThumbPath(s.PhotoPath))
+	}
+	if len(latest) != 0 {
+		sr.Reading = &latest[0]
+	}
+	return sr
+}
+
 func (c *weatherControllerImpl) handleStationsPartial(w http.ResponseWriter, r *http.Request) {
 	data := views.DashboardData{}
-	stations, err := c.repository.GetStations()
+	stations, err := c.repository.GetStations(r.Context())
 	if err != nil {
-		slog.Error("stations partial: get stations failed", "error", err)
+		logging.FromContext(r.Context()).Error("stations partial: get stations failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
 		return
 	}
 
-	for _, s := range stations {
-		latest, err := c.repository.GetLatestReadings(s.ID, 1)
+	favorites := readFavoritesCookie(c.cookies, r)
+	cardMetrics := readCardMetrics(c.cookies, r)
+	now := time.Now().UTC()
+	dayUptime, err := c.dayUptime(r.Context(), stations, now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("stations partial: get uptime failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load uptime")
+		return
+	}
+	for _, s := range orderByFavorites(stations, favorites) {
+		latest, err := c.repository.GetLatestReadings(r.Context(), s.ID, 1)
 		if err != nil {
-			slog.Error("stations partial: get latest reading failed", "station_id", s.ID, "error", err)
+			logging.FromContext(r.Context()).Error("stations partial: get latest reading failed", "station_id", s.ID, "error", err)
 			utils.WriteError(w, http.StatusInternalServerError, "failed to load reading")
 			return
 		}
-		if len(latest) != 0 {
-			data.Stations = append(data.Stations, views.StationReading{StationID: s.ID, StationName: s.Name, Reading: &latest[0]})
-			continue
+		metrics, err := c.repository.GetLatestMetrics(r.Context(), s.ID)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("stations partial: get latest metrics failed", "station_id", s.ID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load reading")
+			return
 		}
-		data.Stations = append(data.Stations, views.StationReading{StationID: s.ID, StationName: s.Name, Reading: nil})
+		sr := stationReading(s, latest)
+		sr.Metrics = withFreshness(metrics, now)
+		sr.Favorite = slices.Contains(favorites, s.ID)
+		sr.CardMetrics = cardMetrics
+		sr.Sparkline = c.cardSparkline(r.Context(), s.ID, cardMetrics, now)
+		sr.Health = c.stationHealth(s.ID, latest, dayUptime[s.ID], now)
+		data.Stations = append(data.Stations, sr)
 	}
 
 	var buf bytes.Buffer
 	if err := views.RenderStationsPartial(&buf, &data); err != nil {
-		slog.Error("stations partial render failed", "error", err)
+		logging.FromContext(r.Context()).Error("stations partial render failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to render")
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if _, err := w.Write(buf.Bytes()); err != nil {
-		slog.Error("stations partial: write response failed", "error", err)
+		logging.FromContext(r.Context()).Error("stations partial: write response failed", "error", err)
 	}
 }
 
@@ -53,43 +87,67 @@ func (c *weatherControllerImpl) handleDashboard(w http.ResponseWriter, r *http.R
 	}
 
 	data := views.DashboardData{}
-	stations, err := c.repository.GetStations()
+	stations, err := c.repository.GetStations(r.Context())
 	if err != nil {
-		slog.Error("dashboard: get stations failed", "error", err)
+		logging.FromContext(r.Context()).Error("dashboard: get stations failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
 		return
 	}
+	// Without stations the dashboard would be an empty grid; guide the
+	// first setup instead.
+	if len(stations) == 0 {
+		c.renderOnboarding(r.Context(), w, http.StatusOK, &views.OnboardingData{})
+		return
+	}
 
-	for _, s := range stations {
-		latest, err := c.repository.GetLatestReadings(s.ID, 1)
+	favorites := readFavoritesCookie(c.cookies, r)
+	cardMetrics := readCardMetrics(c.cookies, r)
+	now := time.Now().UTC()
+	dayUptime, err := c.dayUptime(r.Context(), stations, now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("dashboard: get uptime failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load uptime")
+		return
+	}
+	for _, s := range orderByFavorites(stations, favorites) {
+		latest, err := c.repository.GetLatestReadings(r.Context(), s.ID, 1)
 		if err != nil {
-			slog.Error("dashboard: get latest reading failed", "station_id", s.ID, "error", err)
+			logging.FromContext(r.Context()).Error("dashboard: get latest reading failed", "station_id", s.ID, "error", err)
 			utils.WriteError(w, http.StatusInternalServerError, "failed to load reading")
 			return
 		}
-		if len(latest) != 0 {
-			data.Stations = append(data.Stations, views.StationReading{StationID: s.ID, StationName: s.Name, Reading: &latest[0]})
-			continue
+		metrics, err := c.repository.GetLatestMetrics(r.Context(), s.ID)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("dashboard: get latest metrics failed", "station_id", s.ID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load reading")
+			return
 		}
-		data.Stations = append(data.Stations, views.StationReading{StationID: s.ID, StationName: s.Name, Reading: nil})
-	}
+		sr := stationReading(s, latest)
+		sr.Metrics = withFreshness(metrics, now)
+		sr.Favorite = slices.Contains(favorites, s.ID)
+		sr.CardMetrics = cardMetrics
+		sr.Sparkline = c.cardSparkline(r.Context(), s.ID, cardMetrics, now)
+		sr.Health = c.stationHealth(s.ID, latest, dayUptime[s.ID], now)
+		data.Stations = append(data.Stations, sr)
+	}
+	data.CardMetrics = cardMetricOptions(cardMetrics)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := views.RenderDashboard(w, &data); err != nil {
-		slog.Error("dashboard template render failed", "error", err)
+		logging.FromContext(r.Context()).Error("dashboard template render failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to render page")
 		return
 	}
 }
 
 func (c *weatherControllerImpl) handleHistory(w http.ResponseWriter, r *http.Request) {
-	stations, err := c.repository.GetStations()
+	stations, err := c.repository.GetStations(r.Context())
 	if err != nil {
-		slog.Error("dashboard: get stations failed", "error", err)
+		logging.FromContext(r.Context()).Error("dashboard: get stations failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
 		return
 	}
-	state := readWeatherStateCookie(r)
+	state := readWeatherStateCookie(c.cookies, r)
 	selectedID := r.URL.Query().Get("station_id")
 	if selectedID == "" {
 		selectedID = state.StationID
@@ -113,22 +171,75 @@ func (c *weatherControllerImpl) handleHistory(w http.ResponseWriter, r *http.Req
 		SelectedStationID: selectedID,
 		SelectedRangeKey:  selectedRangeKey,
 	}
-	writeWeatherStateCookie(w, selectedID, selectedRangeKey, state.Page)
+	// The partial loads without a page param and falls back to the cookie, so a
+	// deep link's page must land in the cookie for the first load to honor it.
+	page, cursor := resolveHistoryPosition(r, state, selectedID, selectedRangeKey)
+	writeWeatherStateCookie(c.cookies, w, selectedID, selectedRangeKey, page, cursor)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if err := views.RenderHistory(w, &data); err != nil {
-		slog.Error("history template render failed", "error", err)
+		logging.FromContext(r.Context()).Error("history template render failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to render page")
 		return
 	}
 }
 
+// Stations page size: default and maximum limit.
+const (
+	defaultStationsLimit = 100
+	maxStationsLimit     = 1000
+)
+
 func (c *weatherControllerImpl) handleStations(w http.ResponseWriter, r *http.Request) {
-	stations, err := c.repository.GetStations()
+	page, err := utils.ParsePagination(r, defaultStationsLimit, maxStationsLimit)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	stations, err := c.repository.GetStations(r.Context())
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, stations)
+	// The stations and their newest readings come from memory; uptime
+	// drifts as time passes without readings, so the ETag also changes
+	// every minute.
+	now := time.Now().UTC()
+	v := newValidator()
+	v.addStations(stations)
+	v.add(now.Truncate(time.Minute).Unix())
+	newest := make(map[string][]types.Reading, len(stations))
+	for _, s := range stations {
+		latest, err := c.repository.GetLatestReadings(r.Context(), s.ID, 1)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		v.addNewest(latest)
+		newest[s.ID] = latest
+	}
+	if c.health != nil {
+		// Rejected messages change health without adding readings.
+		v.add(c.health.Version())
+	}
+	if utils.NotModified(w, r, v.etag(), v.modified) {
+		return
+	}
+	uptime, err := c.uptime(r.Context(), "", stations, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	items := utils.Slice(stations, page)
+	for i := range items {
+		id := items[i].ID
+		items[i].Uptime = uptime[id]
+		var day *float64
+		if u := uptime[id]; u != nil {
+			day = u.Day
+		}
+		items[i].Health = c.stationHealth(id, newest[id], day, now)
+	}
+	utils.WritePage(w, r, items, len(stations), page)
 }
 
 func (c *weatherControllerImpl) handleLatest(w http.ResponseWriter, r *http.Request) {
@@ -143,13 +254,79 @@ func (c *weatherControllerImpl) handleLatest(w http.ResponseWriter, r *http.Requ
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	fields, err := parseFieldsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	units, err := parseUnitsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	latest, err := c.repository.GetLatestReadings(id, limit)
+	// The newest reading is cached whole, so fields only trims the
+	// response, and it tells from memory whether anything changed.
+	latest, err := c.repository.GetLatestReadings(r.Context(), id, 1)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, latest)
+	v := newValidator()
+	v.addNewest(latest)
+	if utils.NotModified(w, r, v.etag(), v.modified) {
+		return
+	}
+	if limit > 1 {
+		latest, err = c.repository.GetLatestReadings(r.Context(), id, limit)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	w.Header().Set(unitsHeader, units)
+	utils.WriteJSON(w, http.StatusOK, selectFields(convertReadings(latest, units), fields))
+}
+
+// handleLatestMetrics returns the latest value of each metric of station {id}
+// with its own timestamps and freshness, since metrics may arrive in separate
+// readings.
+func (c *weatherControllerImpl) handleLatestMetrics(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("latest metrics: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	metrics, err := c.repository.GetLatestMetrics(r.Context(), station.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("latest metrics: load failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load latest metrics")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, withFreshness(metrics, time.Now().UTC()))
+}
+
+// withFreshness fills the age of each metric at now and marks metrics older
+// than the gap threshold as stale.
+func withFreshness(m types.LatestMetrics, now time.Time) *types.LatestMetrics {
+	threshold := time.Duration(float64(defaultGapInterval) * defaultGapFactor)
+	for _, metric := range []**types.LatestMetric{&m.Temperature, &m.Humidity, &m.Pressure} {
+		if *metric == nil {
+			continue
+		}
+		lm := **metric
+		age := now.Sub(lm.Time)
+		lm.AgeSeconds = age.Seconds()
+		lm.Stale = age > threshold
+		*metric = &lm
+	}
+	return &m
 }
 
 func (c *weatherControllerImpl) handleReadings(w http.ResponseWriter, r *http.Request) {
@@ -159,68 +336,145 @@ func (c *weatherControllerImpl) handleReadings(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	from, to, limit, err := parseReadingsQuery(r)
+	from, to, page, err := parseReadingsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	fields, err := parseFieldsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	units, err := parseUnitsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ndjson, err := wantsNDJSON(r)
 	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if ndjson {
+		// A stream has no pages: limit and offset are ignored.
+		c.streamReadingsNDJSON(r.Context(), w, id, from, to, fields, units)
+		return
+	}
 
-	readings, err := c.repository.GetReadings(id, from, to, limit, 0)
+	var out readingsPage
+	q := r.URL.Query()
+	switch {
+	case q.Has("offset") && q.Has("cursor"):
+		utils.WriteError(w, http.StatusBadRequest, "'cursor' and 'offset' are mutually exclusive")
+		return
+	case q.Has("offset"):
+		// Deprecated: offsets scan every skipped row; cursors seek.
+		w.Header().Set("Deprecation", "true")
+		readings, err := c.repository.GetReadings(r.Context(), id, from, to, page.Limit, page.Offset, fields)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		total, err := c.repository.GetReadingsCount(r.Context(), id, from, to)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out.Page = utils.NewPage(r, selectFields(convertReadings(readings, units), fields), total, page)
+	default:
+		out, err = c.readingsCursorPage(r, id, from, to, page.Limit, fields, units)
+		if errors.Is(err, errInvalidCursor) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	annotations, err := c.repository.GetAnnotations(r.Context(), id, from, to)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if annotations == nil {
+		annotations = []types.Annotation{}
+	}
+	out.Annotations = annotations
+	out.Units = units
+	utils.WriteJSON(w, http.StatusOK, out)
+}
 
-	utils.WriteJSON(w, http.StatusOK, readings)
+// historyPage is one page of the history list.
+type historyPage struct {
+	Readings []types.Reading // newest first
+	Cursor   repository.ReadingsCursor
+	HasPrev  bool // newer readings exist
+	HasNext  bool // older readings exist
 }
 
-// buildHistoryPageItems returns page numbers and ellipsis for the pagination bar.
-// It only considers {1, totalPages, current±window}, so work is O(1) in totalPages.
-func buildHistoryPageItems(totalPages, currentPage int) []views.PaginationItem {
-	if totalPages <= 0 {
-		return nil
-	}
-	const window = 2
-	// Collect only pages to show: first, last, and current ± window (clamped).
-	seen := map[int]bool{1: true, totalPages: true}
-	for p := currentPage - window; p <= currentPage+window; p++ {
-		if p >= 1 && p <= totalPages {
-			seen[p] = true
+// loadHistoryPage loads the page of the history list at cursor. One reading past
+// the page tells whether more follow in the cursor's direction. When paging
+// towards newer readings runs out, or a stale cursor finds nothing, it loads
+// the newest page instead, so the first page is always full.
+func (c *weatherControllerImpl) loadHistoryPage(ctx context.Context, stationID string, from, to time.Time, cursor repository.ReadingsCursor) (historyPage, error) {
+	readings, err := c.repository.GetReadingsPage(ctx, stationID, from, to, cursor, historyPageSize+1, nil)
+	if err != nil {
+		return historyPage{}, err
+	}
+	more := len(readings) > historyPageSize
+	atNewest := cursor.Newer && !more
+	stale := !cursor.Newer && !cursor.Time.IsZero() && len(readings) == 0
+	if (atNewest || stale) && !cursor.Time.IsZero() {
+		return c.loadHistoryPage(ctx, stationID, from, to, repository.ReadingsCursor{})
+	}
+	if more {
+		if cursor.Newer {
+			readings = readings[1:] // readings are newest first; drop the newest
+		} else {
+			readings = readings[:historyPageSize]
 		}
 	}
-	sorted := make([]int, 0, len(seen))
-	for p := range seen {
-		sorted = append(sorted, p)
-	}
-	sort.Ints(sorted)
-	var items []views.PaginationItem
-	prev := 0
-	for _, p := range sorted {
-		if prev != 0 && p > prev+1 {
-			items = append(items, views.PaginationItem{Ellipsis: true})
-		}
-		items = append(items, views.PaginationItem{Page: p, Ellipsis: false})
-		prev = p
+	hp := historyPage{Readings: readings, Cursor: cursor}
+	if cursor.Newer {
+		hp.HasPrev, hp.HasNext = more, !cursor.Time.IsZero()
+	} else {
+		hp.HasPrev, hp.HasNext = !cursor.Time.IsZero(), more
 	}
-	return items
+	return hp, nil
+}
+
+// approximateHistoryPage returns the page number to show for a keyset page.
+// Pages are counted from the links followed, so readings arriving or expiring
+// in the meantime shift them; the number is clamped to agree with the links:
+// 1 only without newer readings and totalPages only without older ones.
+func approximateHistoryPage(page, totalPages int, hasPrev, hasNext bool) int {
+	switch {
+	case !hasPrev:
+		return 1
+	case !hasNext:
+		return totalPages
+	}
+	return max(2, min(page, totalPages-1))
 }
 
 func (c *weatherControllerImpl) handleHistoryPartial(w http.ResponseWriter, r *http.Request) {
-	stations, err := c.repository.GetStations()
+	stations, err := c.repository.GetStations(r.Context())
 	if err != nil {
-		slog.Error("history: get stations failed", "error", err)
+		logging.FromContext(r.Context()).Error("history: get stations failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
 		return
 	}
 
-	state := readWeatherStateCookie(r)
+	state := readWeatherStateCookie(c.cookies, r)
 	rangeKey := r.URL.Query().Get("range")
 	if rangeKey == "" {
 		rangeKey = state.RangeKey
 	}
 	rangeInfo, ok := resolveHistoryRange(rangeKey)
 	if !ok && rangeKey != "" {
-		slog.Warn("history: invalid range", "range", rangeKey)
+		logging.FromContext(r.Context()).Warn("history: invalid range", "range", rangeKey)
 	}
 	resolvedRangeKey := rangeKey
 	if resolvedRangeKey == "" || !ok {
@@ -233,14 +487,7 @@ func (c *weatherControllerImpl) handleHistoryPartial(w http.ResponseWriter, r *h
 		requestStation = state.StationID
 	}
 
-	page := parseHistoryPage(r)
-	if r.URL.Query().Get("page") == "" {
-		if requestStation != state.StationID || resolvedRangeKey != state.RangeKey {
-			page = 1
-		} else if state.Page >= 1 {
-			page = state.Page
-		}
-	}
+	page, cursorParam := resolveHistoryPosition(r, state, requestStation, resolvedRangeKey)
 
 	stationID := requestStation
 	var stationName string
@@ -258,17 +505,16 @@ func (c *weatherControllerImpl) handleHistoryPartial(w http.ResponseWriter, r *h
 				HasNext:     false,
 				PrevPage:    1,
 				NextPage:    2,
-				PageItems:   []views.PaginationItem{{Page: 1, Ellipsis: false}},
 			}
 			var buf bytes.Buffer
 			if err := views.RenderHistoryPartial(&buf, &data); err != nil {
-				slog.Error("history partial render failed", "error", err)
+				logging.FromContext(r.Context()).Error("history partial render failed", "error", err)
 				utils.WriteError(w, http.StatusInternalServerError, "failed to render")
 				return
 			}
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
 			if _, err := w.Write(buf.Bytes()); err != nil {
-				slog.Error("history: write response failed", "error", err)
+				logging.FromContext(r.Context()).Error("history: write response failed", "error", err)
 			}
 			return
 		}
@@ -282,17 +528,19 @@ func (c *weatherControllerImpl) handleHistoryPartial(w http.ResponseWriter, r *h
 			}
 		}
 		if stationName == "" {
-			slog.Warn("history: unknown station_id", "station_id", stationID)
+			logging.FromContext(r.Context()).Warn("history: unknown station_id", "station_id", stationID)
 			stationName = "Unknown Station"
 		}
 	}
 
-	now := time.Now().UTC()
+	// Whole seconds let refreshes of several open tabs issue identical
+	// queries, which the repository coalesces.
+	now := time.Now().UTC().Truncate(time.Second)
 	from := now.Add(-rangeInfo.Duration)
 
-	count, err := c.repository.GetReadingsCount(stationID, from, now)
+	count, err := c.repository.GetReadingsCount(r.Context(), stationID, from, now)
 	if err != nil {
-		slog.Error("history: get readings count failed", "station_id", stationID, "error", err)
+		logging.FromContext(r.Context()).Error("history: get readings count failed", "station_id", stationID, "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to load readings")
 		return
 	}
@@ -300,41 +548,67 @@ func (c *weatherControllerImpl) handleHistoryPartial(w http.ResponseWriter, r *h
 	if count > 0 {
 		totalPages = (count + historyPageSize - 1) / historyPageSize
 	}
-	if page < 1 || page > totalPages {
-		page = 1
-	}
-	offset := (page - 1) * historyPageSize
 
-	readings, err := c.repository.GetReadings(stationID, from, now, historyPageSize, offset)
+	hp, err := c.loadHistoryPage(r.Context(), stationID, from, now, parseHistoryCursor(cursorParam))
 	if err != nil {
-		slog.Error("history: get readings failed", "station_id", stationID, "error", err)
+		logging.FromContext(r.Context()).Error("history: get readings failed", "station_id", stationID, "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to load readings")
 		return
 	}
+	page = approximateHistoryPage(page, totalPages, hp.HasPrev, hp.HasNext)
+	var chart *views.Sparkline
+	if resolvedRangeKey == defaultHistoryRangeKey {
+		chart, err = c.loadSparkline(r.Context(), stationID, types.MetricTemperature, now)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("history: get sparkline failed", "station_id", stationID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load readings")
+			return
+		}
+	}
+	annotations, err := c.repository.GetAnnotations(r.Context(), stationID, from, now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("history: get annotations failed", "station_id", stationID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load annotations")
+		return
+	}
+	snapshots, err := c.repository.GetSnapshots(r.Context(), stationID, from, now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("history: get snapshots failed", "station_id", stationID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load snapshots")
+		return
+	}
 
 	data := views.HistoryData{
 		StationName: stationName,
 		StationID:   stationID,
 		RangeLabel:  rangeInfo.Label,
 		RangeKey:    resolvedRangeKey,
-		Readings:    readings,
+		Readings:    hp.Readings,
+		Annotations: annotationMarkers(annotations),
+		Snapshots:   snapshotMarkers(snapshots),
 		CurrentPage: page,
 		TotalPages:  totalPages,
-		HasPrev:     page > 1,
-		HasNext:     page < totalPages,
-		PrevPage:    page - 1,
-		NextPage:    page + 1,
-		PageItems:   buildHistoryPageItems(totalPages, page),
-	}
-	writeWeatherStateCookie(w, stationID, resolvedRangeKey, page)
+		HasPrev:     hp.HasPrev,
+		HasNext:     hp.HasNext,
+		PrevPage:    max(page-1, 1),
+		NextPage:    min(page+1, totalPages),
+		Sparkline:   chart,
+	}
+	if n := len(hp.Readings); n > 0 {
+		data.PrevCursor = formatHistoryCursor(repository.ReadingsCursor{Time: hp.Readings[0].Time, Newer: true})
+		data.NextCursor = formatHistoryCursor(repository.ReadingsCursor{Time: hp.Readings[n-1].Time})
+	}
+	cursor := formatHistoryCursor(hp.Cursor)
+	writeWeatherStateCookie(c.cookies, w, stationID, resolvedRangeKey, page, cursor)
+	setHistoryURLHeader(w, r, historyURL(stationID, resolvedRangeKey, page, cursor))
 	var buf bytes.Buffer
 	if err := views.RenderHistoryPartial(&buf, &data); err != nil {
-		slog.Error("history partial render failed", "error", err)
+		logging.FromContext(r.Context()).Error("history partial render failed", "error", err)
 		utils.WriteError(w, http.StatusInternalServerError, "failed to render")
 		return
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if _, err := w.Write(buf.Bytes()); err != nil {
-		slog.Error("history: write response failed", "error", err)
+		logging.FromContext(r.Context()).Error("history: write response failed", "error", err)
 	}
 }
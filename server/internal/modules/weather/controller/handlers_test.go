@@ -1,15 +1,21 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"cloudpico-server/internal/modules/weather/health"
+	"cloudpico-server/internal/modules/weather/repository"
 	"cloudpico-server/internal/modules/weather/types"
 	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
 )
 
 type mockRepo struct {
@@ -26,19 +32,96 @@ type mockRepo struct {
 	lastReadingsTo        time.Time
 	lastReadingsLimit     int
 	lastReadingsOffset    int
+	lastReadingsFields    []string
+	readingsCursors       []repository.ReadingsCursor // GetReadingsPage calls
 	insertErr             error
+	histogram             []types.HistogramBucket
+	histogramErr          error
+	lastHistogramMetric   string
+	lastHistogramBins     int
+	station               types.Station
+	stationErr            error
+	shadowErr             error
+	createErr             error
+	series                map[string][]types.MetricPoint
+	seriesErr             error
+	summary               types.Summary
+	summaryErr            error
+	stats                 types.Stats
+	statsErr              error
+	aggregate             []types.AggregateBucket
+	aggregateErr          error
+	lastAggregateFrom     time.Time
+	lastAggregateTo       time.Time
+	lastAggregateBucket   time.Duration
+	photoPath             string
+	photoErr              error
+	location              [2]*float64
+	locationErr           error
+	retention             *int
+	retentionErr          error
+	gaps                  map[string][]types.Gap // by station ID
+	gapsErr               error
+	lastGapsThreshold     time.Duration
+	uptime                map[string]float64 // by station ID, for every window
+	uptimeErr             error
+	annotations           []types.Annotation
+	annotationsErr        error // returned by GetAnnotations
+	annotationErr         error // returned by CreateAnnotation and DeleteAnnotation
+	lastAnnotation        types.Annotation
+	snapshots             []types.Snapshot
+	snapshotsErr          error // returned by GetSnapshots
+	snapshotErr           error // returned by CreateSnapshot and DeleteSnapshot
+	lastSnapshot          types.Snapshot
+	climate               []types.MonthlyClimate
+	climateErr            error
+	latestMetrics         types.LatestMetrics
+	latestMetricsErr      error
+	stored                []types.StoredReading // passed to EachReading's fn
+	storedErr             error                 // returned by EachReading after stored
 }
 
-func (m *mockRepo) GetStations() ([]types.Station, error) {
+func (m *mockRepo) GetAnnotations(ctx context.Context, stationID string, from, to time.Time) ([]types.Annotation, error) {
+	return m.annotations, m.annotationsErr
+}
+
+func (m *mockRepo) CreateAnnotation(ctx context.Context, stationID string, start, end time.Time, note string) (types.Annotation, error) {
+	m.lastAnnotation = types.Annotation{ID: "1", StationID: stationID, Start: start, End: end, Note: note}
+	return m.lastAnnotation, m.annotationErr
+}
+
+func (m *mockRepo) DeleteAnnotation(ctx context.Context, stationID string, annotationID string) error {
+	return m.annotationErr
+}
+
+func (m *mockRepo) GetSnapshots(ctx context.Context, stationID string, from, to time.Time) ([]types.Snapshot, error) {
+	return m.snapshots, m.snapshotsErr
+}
+
+func (m *mockRepo) CreateSnapshot(ctx context.Context, stationID string, start, end time.Time, url string) (types.Snapshot, error) {
+	m.lastSnapshot = types.Snapshot{ID: "1", StationID: stationID, Start: start, End: end, URL: url}
+	return m.lastSnapshot, m.snapshotErr
+}
+
+func (m *mockRepo) DeleteSnapshot(ctx context.Context, stationID string, snapshotID string) error {
+	return m.snapshotErr
+}
+
+func (m *mockRepo) GetUptime(ctx context.Context, stationID string, from, to time.Time, threshold time.Duration) (map[string]float64, error) {
+	return m.uptime, m.uptimeErr
+}
+
+func (m *mockRepo) GetStations(ctx context.Context) ([]types.Station, error) {
 	return m.stations, m.stationsErr
 }
 
-func (m *mockRepo) GetLatestReadings(stationID string, limit int) ([]types.Reading, error) {
+func (m *mockRepo) GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error) {
 	return m.latest, m.latestErr
 }
 
-func (m *mockRepo) GetReadings(stationID string, from, to time.Time, limit int, offset int) ([]types.Reading, error) {
+func (m *mockRepo) GetReadings(ctx context.Context, stationID string, from, to time.Time, limit int, offset int, fields []string) ([]types.Reading, error) {
 	m.lastReadingsStationID = stationID
+	m.lastReadingsFields = fields
 	m.lastReadingsFrom = from
 	m.lastReadingsTo = to
 	m.lastReadingsLimit = limit
@@ -46,7 +129,18 @@ func (m *mockRepo) GetReadings(stationID string, from, to time.Time, limit int,
 	return m.readings, m.readingsErr
 }
 
-func (m *mockRepo) GetReadingsCount(stationID string, from, to time.Time) (int, error) {
+// GetReadingsPage returns up to limit of readings whatever the cursor.
+func (m *mockRepo) GetReadingsPage(ctx context.Context, stationID string, from, to time.Time, cursor repository.ReadingsCursor, limit int, fields []string) ([]types.Reading, error) {
+	m.lastReadingsStationID = stationID
+	m.lastReadingsFields = fields
+	m.lastReadingsFrom = from
+	m.lastReadingsTo = to
+	m.lastReadingsLimit = limit
+	m.readingsCursors = append(m.readingsCursors, cursor)
+	return m.readings[:min(limit, len(m.readings))], m.readingsErr
+}
+
+func (m *mockRepo) GetReadingsCount(ctx context.Context, stationID string, from, to time.Time) (int, error) {
 	if m.countErr != nil {
 		return 0, m.countErr
 	}
@@ -56,12 +150,145 @@ func (m *mockRepo) GetReadingsCount(stationID string, from, to time.Time) (int,
 	return len(m.readings), nil
 }
 
-func (m *mockRepo) InsertReading(stationID string, ts time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+func (m *mockRepo) GetReceivedCount(ctx context.Context, stationID string, from, to time.Time) (int, error) {
+	return m.GetReadingsCount(ctx, stationID, from, to)
+}
+
+func (m *mockRepo) EachReading(ctx context.Context, stationID string, from, to time.Time, fn func(types.StoredReading) error) error {
+	m.lastReadingsStationID, m.lastReadingsFrom, m.lastReadingsTo = stationID, from, to
+	for _, sr := range m.stored {
+		if err := fn(sr); err != nil {
+			return err
+		}
+	}
+	return m.storedErr
+}
+
+func (m *mockRepo) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
 	return m.insertErr
 }
 
+func (m *mockRepo) InsertReadings(ctx context.Context, readings []repository.NewReading) ([]error, error) {
+	return make([]error, len(readings)), m.insertErr
+}
+
+func (m *mockRepo) GetHistogram(ctx context.Context, stationID string, metric string, from, to time.Time, bins int) ([]types.HistogramBucket, error) {
+	m.lastHistogramMetric = metric
+	m.lastHistogramBins = bins
+	return m.histogram, m.histogramErr
+}
+
+func (m *mockRepo) GetStation(ctx context.Context, stationID string) (types.Station, error) {
+	return m.station, m.stationErr
+}
+
+func (m *mockRepo) CreateStation(ctx context.Context, name string, latitude *float64, longitude *float64) (types.Station, error) {
+	if m.createErr != nil {
+		return types.Station{}, m.createErr
+	}
+	return types.Station{ID: "7", Name: name, Latitude: latitude, Longitude: longitude}, nil
+}
+
+func (m *mockRepo) CreateShadowStation(ctx context.Context, primaryID string, name string) (types.Station, error) {
+	if m.shadowErr != nil {
+		return types.Station{}, m.shadowErr
+	}
+	return types.Station{ID: "99", Name: name, ShadowOf: primaryID}, nil
+}
+
+func (m *mockRepo) GetMetricSeries(ctx context.Context, stationID string, metric string, from, to time.Time) ([]types.MetricPoint, error) {
+	return m.series[stationID], m.seriesErr
+}
+
+func (m *mockRepo) GetSummary(ctx context.Context, stationID string, from, to time.Time) (types.Summary, error) {
+	return m.summary, m.summaryErr
+}
+
+func (m *mockRepo) GetStats(ctx context.Context, stationID string, from, to time.Time) (types.Stats, error) {
+	m.stats.StationID, m.stats.From, m.stats.To = stationID, from, to
+	return m.stats, m.statsErr
+}
+
+func (m *mockRepo) GetAggregate(ctx context.Context, stationID string, from, to time.Time, bucket time.Duration) ([]types.AggregateBucket, error) {
+	m.lastAggregateFrom, m.lastAggregateTo, m.lastAggregateBucket = from, to, bucket
+	return m.aggregate, m.aggregateErr
+}
+
+func (m *mockRepo) RollupDaily(ctx context.Context, from time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) RollupHourly(ctx context.Context, from time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) DeleteHourlyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) DeleteDailyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	return 0, nil
+}
+
+func (m *mockRepo) GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error) {
+	return m.latestMetrics, m.latestMetricsErr
+}
+
+func (m *mockRepo) GetMonthlyClimate(ctx context.Context, stationID string) ([]types.MonthlyClimate, error) {
+	return m.climate, m.climateErr
+}
+
+func (m *mockRepo) SetStationPhoto(ctx context.Context, stationID string, photoPath string) error {
+	if m.photoErr != nil {
+		return m.photoErr
+	}
+	m.photoPath = photoPath
+	return nil
+}
+
+func (m *mockRepo) SetStationLocation(ctx context.Context, stationID string, latitude *float64, longitude *float64) error {
+	if m.locationErr != nil {
+		return m.locationErr
+	}
+	m.location = [2]*float64{latitude, longitude}
+	return nil
+}
+
+func (m *mockRepo) SetStationRetention(ctx context.Context, stationID string, days *int) error {
+	if m.retentionErr != nil {
+		return m.retentionErr
+	}
+	m.retention = days
+	return nil
+}
+
+func (m *mockRepo) SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error {
+	return nil
+}
+
+func (m *mockRepo) SetStationInterval(ctx context.Context, stationID string, seconds int) error {
+	return nil
+}
+
+func (m *mockRepo) SetStationVersions(ctx context.Context, stationID string, gatewayVersion string, firmwareVersion string) error {
+	return nil
+}
+
+func (m *mockRepo) GetGaps(ctx context.Context, stationID string, from, to time.Time, threshold time.Duration) ([]types.Gap, error) {
+	m.lastGapsThreshold = threshold
+	return m.gaps[stationID], m.gapsErr
+}
+
+func (m *mockRepo) GetReadingTime(ctx context.Context, stationID string, from, to time.Time) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
 func Test_handleDashboard(t *testing.T) {
-	ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+	ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 
 	t.Run("returns 404 when path is not /", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
@@ -88,7 +315,7 @@ func Test_handleDashboard(t *testing.T) {
 	})
 
 	t.Run("returns 500 and error body when GetStations fails", func(t *testing.T) {
-		ctrlErr := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrlErr := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		rec := httptest.NewRecorder()
 
@@ -155,7 +382,7 @@ func Test_handleDashboard(t *testing.T) {
 		if err := views.LoadTemplates(); err != nil {
 			t.Skipf("LoadTemplates failed (embed not available?): %v", err)
 		}
-		ctrlWithStations := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrlWithStations := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		rec := httptest.NewRecorder()
 
@@ -184,7 +411,7 @@ func Test_handleStations(t *testing.T) {
 			{ID: "st-1", Name: "Station One"},
 			{ID: "st-2", Name: "Station Two"},
 		}
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil)
 		rec := httptest.NewRecorder()
 
@@ -202,8 +429,149 @@ func Test_handleStations(t *testing.T) {
 		}
 	})
 
+	t.Run("answers 304 while stations and readings are unchanged", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		repo := &mockRepo{
+			stations: []types.Station{{ID: "st-1", Name: "Station One"}},
+			latest:   []types.Reading{{StationID: "st-1", Time: ts}},
+		}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		get := func(etag string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil)
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			rec := httptest.NewRecorder()
+			ctrl.handleStations(rec, req)
+			return rec
+		}
+
+		// The ETag also changes every minute; retry once across a minute boundary.
+		var etag string
+		code := 0
+		for range 2 {
+			etag = get("").Header().Get("ETag")
+			if code = get(etag).Code; code == http.StatusNotModified {
+				break
+			}
+		}
+		if code != http.StatusNotModified {
+			t.Errorf("unchanged response = %d; want 304", code)
+		}
+		repo.stations[0].Name = "Garden"
+		if rec := get(etag); rec.Code != http.StatusOK {
+			t.Errorf("after rename = %d; want 200", rec.Code)
+		}
+	})
+
+	t.Run("wraps stations in pagination envelope", func(t *testing.T) {
+		stations := []types.Station{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations?limit=1&offset=1", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStations(rec, req)
+
+		var page utils.Page[types.Station]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if page.Total != 3 || page.Limit != 1 || page.Offset != 1 || len(page.Items) != 1 || page.Items[0].ID != "2" {
+			t.Errorf("page = %+v; want item 2 of 3", page)
+		}
+		if page.Links.Next == "" || page.Links.Prev == "" {
+			t.Errorf("links = %+v; want next and prev", page.Links)
+		}
+	})
+
+	t.Run("includes uptime", func(t *testing.T) {
+		repo := &mockRepo{
+			stations: []types.Station{{ID: "1"}, {ID: "2"}},
+			uptime:   map[string]float64{"1": 99.5},
+		}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStations(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil))
+
+		var page utils.Page[types.Station]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(page.Items) != 2 {
+			t.Fatalf("items = %+v; want 2", page.Items)
+		}
+		u := page.Items[0].Uptime
+		if u == nil || u.Day == nil || *u.Day != 99.5 || u.Week == nil || u.Month == nil {
+			t.Errorf("station 1 uptime = %+v; want 99.5 for every window", u)
+		}
+		if page.Items[1].Uptime != nil {
+			t.Errorf("station 2 uptime = %+v; want none", page.Items[1].Uptime)
+		}
+	})
+
+	t.Run("includes health scores", func(t *testing.T) {
+		now := time.Now().UTC()
+		repo := &mockRepo{
+			stations: []types.Station{{ID: "1"}},
+			latest:   []types.Reading{{StationID: "1", Time: now}},
+			uptime:   map[string]float64{"1": 100},
+		}
+		tracker := health.NewTracker(health.DefaultWeights)
+		ctrl := NewWeatherController(repo, nil, nil, tracker).(*weatherControllerImpl)
+		get := func(etag string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil)
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			rec := httptest.NewRecorder()
+			ctrl.handleStations(rec, req)
+			return rec
+		}
+
+		rec := get("")
+		var page utils.Page[types.Station]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		h := page.Items[0].Health
+		if h == nil || h.Score != 100 || h.Status != types.HealthGood || h.Rejections != nil {
+			t.Errorf("health = %+v; want 100 from freshness and gaps", h)
+		}
+
+		// A rejected message changes the score without a new reading.
+		tracker.Reject("1")
+		if rec := get(rec.Header().Get("ETag")); rec.Code != http.StatusOK {
+			t.Errorf("after a rejection = %d; want 200", rec.Code)
+		}
+	})
+
+	t.Run("returns 500 when uptime fails", func(t *testing.T) {
+		repo := &mockRepo{stations: []types.Station{{ID: "1"}}, uptimeErr: errors.New("db error")}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStations(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("returns 400 when offset is invalid", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations?offset=-1", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStations(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
 	t.Run("returns 500 when repository fails", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations", nil)
 		rec := httptest.NewRecorder()
 
@@ -224,7 +592,7 @@ func Test_handleLatest(t *testing.T) {
 		readings := []types.Reading{
 			{StationID: "st-1", Time: time.Now(), Value: 12.5},
 		}
-		ctrl := NewWeatherController(&mockRepo{latest: readings}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{latest: readings}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -241,7 +609,7 @@ func Test_handleLatest(t *testing.T) {
 	})
 
 	t.Run("returns 400 when station id is missing", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations//latest", nil)
 		req.SetPathValue("id", "")
 		rec := httptest.NewRecorder()
@@ -258,7 +626,7 @@ func Test_handleLatest(t *testing.T) {
 	})
 
 	t.Run("returns 500 when repository fails", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{latestErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{latestErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -270,8 +638,94 @@ func Test_handleLatest(t *testing.T) {
 		}
 	})
 
+	t.Run("answers 304 until a newer reading arrives", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		repo := &mockRepo{latest: []types.Reading{{StationID: "st-1", Time: ts, Value: 12.5, ReceivedAt: &ts}}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		get := func(header, value string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest", nil)
+			req.SetPathValue("id", "st-1")
+			if header != "" {
+				req.Header.Set(header, value)
+			}
+			rec := httptest.NewRecorder()
+			ctrl.handleLatest(rec, req)
+			return rec
+		}
+
+		first := get("", "")
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" || first.Header().Get("Last-Modified") != "Wed, 01 Jan 2025 12:00:00 GMT" {
+			t.Fatalf("first response = %d, ETag %q, Last-Modified %q; want 200 with validators",
+				first.Code, etag, first.Header().Get("Last-Modified"))
+		}
+		if rec := get("If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match response = %d, %q; want an empty 304", rec.Code, rec.Body)
+		}
+		if rec := get("If-Modified-Since", first.Header().Get("Last-Modified")); rec.Code != http.StatusNotModified {
+			t.Errorf("If-Modified-Since response = %d; want 304", rec.Code)
+		}
+
+		// A partial reading merged into the newest one advances its receipt time.
+		received := ts.Add(5 * time.Second)
+		repo.latest[0].ReceivedAt = &received
+		if rec := get("If-None-Match", etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+			t.Errorf("after merge = %d, ETag %q; want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"))
+		}
+	})
+
+	t.Run("returns only the requested fields", func(t *testing.T) {
+		readings := []types.Reading{
+			{StationID: "st-1", Time: time.Now(), Value: 12.5, HumidityPct: 60, PressureHpa: 1010},
+		}
+		ctrl := NewWeatherController(&mockRepo{latest: readings}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest?fields=temperature", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleLatest(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `"value":12.5`) || strings.Contains(body, "humidityPct") || strings.Contains(body, "pressureHpa") {
+			t.Errorf("body = %q; want temperature only", body)
+		}
+	})
+
+	t.Run("echoes the unit system in a header", func(t *testing.T) {
+		readings := []types.Reading{{StationID: "st-1", Time: time.Now(), Value: 100}}
+		ctrl := NewWeatherController(&mockRepo{latest: readings}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest?units=imperial", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleLatest(rec, req)
+
+		if got := rec.Header().Get("X-Units"); got != "imperial" {
+			t.Errorf("X-Units = %q; want imperial", got)
+		}
+		if !strings.Contains(rec.Body.String(), `"value":212`) {
+			t.Errorf("body = %q; want 212 °F", rec.Body.String())
+		}
+	})
+
+	t.Run("returns 400 when fields are invalid", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest?fields=wind", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleLatest(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
 	t.Run("returns 400 when limit is invalid", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/latest?limit=abc", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -284,12 +738,76 @@ func Test_handleLatest(t *testing.T) {
 	})
 }
 
+func Test_handleLatestMetrics(t *testing.T) {
+	recent := time.Now().UTC().Add(-time.Minute)
+	metrics := types.LatestMetrics{
+		StationID:   "1",
+		Temperature: &types.LatestMetric{Value: 12.5, Time: recent},
+		Pressure:    &types.LatestMetric{Value: 1001, Time: recent.Add(-time.Hour)},
+	}
+	tests := []struct {
+		name string
+		repo *mockRepo
+		want int
+	}{
+		{"ok", &mockRepo{station: types.Station{ID: "1"}, latestMetrics: metrics}, http.StatusOK},
+		{"unknown station", &mockRepo{stationErr: repository.ErrStationNotFound}, http.StatusNotFound},
+		{"repository error", &mockRepo{station: types.Station{ID: "1"}, latestMetricsErr: errors.New("db error")}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := NewWeatherController(tt.repo, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/latest/metrics", nil)
+			req.SetPathValue("id", "1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleLatestMetrics(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d; want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			var got types.LatestMetrics
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Temperature == nil || got.Temperature.Value != 12.5 || got.Temperature.Stale {
+				t.Errorf("temperature = %+v; want fresh 12.5", got.Temperature)
+			}
+			if got.Pressure == nil || !got.Pressure.Stale || got.Pressure.AgeSeconds < 3600 {
+				t.Errorf("pressure = %+v; want stale, over an hour old", got.Pressure)
+			}
+			if got.Humidity != nil {
+				t.Errorf("humidity = %+v; want null", got.Humidity)
+			}
+		})
+	}
+}
+
+func Test_withFreshness(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	in := types.LatestMetrics{Humidity: &types.LatestMetric{Value: 40, Time: now.Add(-3 * time.Minute)}}
+	got := withFreshness(in, now)
+	if got.Humidity.AgeSeconds != 180 || got.Humidity.Stale {
+		t.Errorf("humidity at the threshold = %+v; want 180 s, not stale", got.Humidity)
+	}
+	if in.Humidity.AgeSeconds != 0 {
+		t.Error("withFreshness modified its input")
+	}
+	got = withFreshness(in, now.Add(time.Second))
+	if !got.Humidity.Stale {
+		t.Errorf("humidity past the threshold = %+v; want stale", got.Humidity)
+	}
+}
+
 func Test_handleReadings(t *testing.T) {
 	t.Run("returns readings on success", func(t *testing.T) {
 		readings := []types.Reading{
 			{StationID: "st-1", Time: time.Now(), Value: 10.0},
 		}
-		ctrl := NewWeatherController(&mockRepo{readings: readings}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{readings: readings}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?from=2025-01-01T00:00:00Z&to=2025-01-02T00:00:00Z&limit=10", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -305,8 +823,88 @@ func Test_handleReadings(t *testing.T) {
 		}
 	})
 
+	t.Run("passes offset and reports total", func(t *testing.T) {
+		repo := &mockRepo{readings: []types.Reading{{StationID: "st-1"}}, readingsCount: 25}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?limit=10&offset=20", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleReadings(rec, req)
+
+		if repo.lastReadingsLimit != 10 || repo.lastReadingsOffset != 20 {
+			t.Errorf("limit, offset = %d, %d; want 10, 20", repo.lastReadingsLimit, repo.lastReadingsOffset)
+		}
+		var page utils.Page[types.Reading]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if page.Total != 25 || page.Links.Next != "" || page.Links.Prev == "" {
+			t.Errorf("page = %+v; want total 25, last page", page)
+		}
+	})
+
+	t.Run("selects only the requested fields", func(t *testing.T) {
+		repo := &mockRepo{readings: []types.Reading{{StationID: "st-1", Value: 10, HumidityPct: 55, PressureHpa: 1012}}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?fields=humidity,temperature", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleReadings(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		if want := []string{types.MetricHumidity, types.MetricTemperature}; !slices.Equal(repo.lastReadingsFields, want) {
+			t.Errorf("repository fields = %v; want %v", repo.lastReadingsFields, want)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `"value":10`) || !strings.Contains(body, `"humidityPct":55`) || strings.Contains(body, "pressureHpa") {
+			t.Errorf("body = %q; want temperature and humidity only", body)
+		}
+	})
+
+	t.Run("converts to imperial units", func(t *testing.T) {
+		repo := &mockRepo{readings: []types.Reading{{StationID: "st-1", Value: 10, HumidityPct: 55, PressureHpa: 1000}}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?units=imperial", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleReadings(rec, req)
+
+		var page readingsPage
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if page.Units != "imperial" || len(page.Items) != 1 {
+			t.Fatalf("page = %+v; want imperial, one reading", page)
+		}
+		got := page.Items[0]
+		if *got.Value != 50 || *got.HumidityPct != 55 || *got.PressureHpa < 29.52 || *got.PressureHpa > 29.54 {
+			t.Errorf("reading = %v °F, %v %%, %v inHg; want 50, 55, 29.53", *got.Value, *got.HumidityPct, *got.PressureHpa)
+		}
+		if repo.readings[0].Value != 10 {
+			t.Errorf("repository readings converted in place")
+		}
+	})
+
+	t.Run("returns 500 when count fails", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{countErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleReadings(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+
 	t.Run("returns 400 when station id is missing", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations//readings", nil)
 		req.SetPathValue("id", "")
 		rec := httptest.NewRecorder()
@@ -322,7 +920,7 @@ func Test_handleReadings(t *testing.T) {
 	})
 
 	t.Run("returns 400 when from is invalid", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?from=not-a-date", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -339,7 +937,7 @@ func Test_handleReadings(t *testing.T) {
 	})
 
 	t.Run("returns 400 when to is invalid", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?to=not-a-date", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -352,7 +950,7 @@ func Test_handleReadings(t *testing.T) {
 	})
 
 	t.Run("returns 400 when from is after to", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?from=2025-01-02T00:00:00Z&to=2025-01-01T00:00:00Z", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -369,7 +967,7 @@ func Test_handleReadings(t *testing.T) {
 	})
 
 	t.Run("returns 400 when limit is invalid", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings?limit=abc", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -382,7 +980,7 @@ func Test_handleReadings(t *testing.T) {
 	})
 
 	t.Run("returns 500 when repository fails", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{readingsErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{readingsErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/readings", nil)
 		req.SetPathValue("id", "st-1")
 		rec := httptest.NewRecorder()
@@ -395,161 +993,6 @@ func Test_handleReadings(t *testing.T) {
 	})
 }
 
-// paginationItemsEqual compares two PaginationItem slices for equality.
-func paginationItemsEqual(a, b []views.PaginationItem) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i].Ellipsis != b[i].Ellipsis || a[i].Page != b[i].Page {
-			return false
-		}
-	}
-	return true
-}
-
-func Test_buildHistoryPageItems(t *testing.T) {
-	t.Run("returns nil when totalPages <= 0", func(t *testing.T) {
-		got := buildHistoryPageItems(0, 1)
-		if got != nil {
-			t.Errorf("buildHistoryPageItems(0, 1) = %v; want nil", got)
-		}
-		got = buildHistoryPageItems(-1, 1)
-		if got != nil {
-			t.Errorf("buildHistoryPageItems(-1, 1) = %v; want nil", got)
-		}
-	})
-
-	t.Run("single page returns one item", func(t *testing.T) {
-		want := []views.PaginationItem{{Page: 1, Ellipsis: false}}
-		got := buildHistoryPageItems(1, 1)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(1, 1) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("two pages with currentPage 1 returns both pages no ellipsis", func(t *testing.T) {
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Page: 2, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(2, 1)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(2, 1) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("currentPage at first page with many pages", func(t *testing.T) {
-		// totalPages=5, current=1 → window 1±2 gives 1,2,3; plus last 5 → [1,2,3,...,5]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Page: 2, Ellipsis: false},
-			{Page: 3, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 5, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(5, 1)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(5, 1) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("currentPage at last page with many pages", func(t *testing.T) {
-		// totalPages=5, current=5 → window 3,4,5; plus first 1 → [1,...,3,4,5]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 3, Ellipsis: false},
-			{Page: 4, Ellipsis: false},
-			{Page: 5, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(5, 5)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(5, 5) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("currentPage in middle with window and ellipsis both sides", func(t *testing.T) {
-		// totalPages=10, current=5 → 1,10 and 3..7 → [1,...,3,4,5,6,7,...,10]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 3, Ellipsis: false},
-			{Page: 4, Ellipsis: false},
-			{Page: 5, Ellipsis: false},
-			{Page: 6, Ellipsis: false},
-			{Page: 7, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 10, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(10, 5)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(10, 5) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("currentPage 2 with many pages", func(t *testing.T) {
-		// totalPages=10, current=2 → 1,10 and 0,1,2,3,4 clamped → 1,2,3,4,10 → [1,2,3,4,...,10]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Page: 2, Ellipsis: false},
-			{Page: 3, Ellipsis: false},
-			{Page: 4, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 10, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(10, 2)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(10, 2) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("currentPage second to last with many pages", func(t *testing.T) {
-		// totalPages=10, current=9 → 1,10 and 7,8,9,10 → [1,...,7,8,9,10]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 7, Ellipsis: false},
-			{Page: 8, Ellipsis: false},
-			{Page: 9, Ellipsis: false},
-			{Page: 10, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(10, 9)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(10, 9) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("all pages fit in window no ellipsis", func(t *testing.T) {
-		// totalPages=5, current=3 → 1,2,3,4,5 all in window → [1,2,3,4,5]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Page: 2, Ellipsis: false},
-			{Page: 3, Ellipsis: false},
-			{Page: 4, Ellipsis: false},
-			{Page: 5, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(5, 3)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(5, 3) = %v; want %v", got, want)
-		}
-	})
-
-	t.Run("currentPage out of range still produces valid items", func(t *testing.T) {
-		// Function does not validate currentPage; currentPage=100 with totalPages=5
-		// window 98..102 adds no pages in [1,5], so only first and last → [1,...,5]
-		want := []views.PaginationItem{
-			{Page: 1, Ellipsis: false},
-			{Ellipsis: true},
-			{Page: 5, Ellipsis: false},
-		}
-		got := buildHistoryPageItems(5, 100)
-		if !paginationItemsEqual(got, want) {
-			t.Errorf("buildHistoryPageItems(5, 100) = %v; want %v", got, want)
-		}
-	})
-}
-
 func Test_handleHistoryPartial(t *testing.T) {
 	if err := views.LoadTemplates(); err != nil {
 		t.Skipf("LoadTemplates failed: %v", err)
@@ -561,7 +1004,7 @@ func Test_handleHistoryPartial(t *testing.T) {
 			{StationID: "st-1", Time: time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC), Value: 12.5},
 		}
 		repo := &mockRepo{stations: stations, readings: readings}
-		ctrl := NewWeatherController(repo).(*weatherControllerImpl)
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=st-1&range=1h", nil)
 		rec := httptest.NewRecorder()
 
@@ -586,19 +1029,22 @@ func Test_handleHistoryPartial(t *testing.T) {
 		if repo.lastReadingsStationID != "st-1" {
 			t.Errorf("station id = %q; want st-1", repo.lastReadingsStationID)
 		}
-		wantLimit := historyPageSize
+		wantLimit := historyPageSize + 1 // one more tells whether an older page exists
 		if repo.lastReadingsLimit != wantLimit {
 			t.Errorf("limit = %d; want %d", repo.lastReadingsLimit, wantLimit)
 		}
-		if repo.lastReadingsOffset != 0 {
-			t.Errorf("offset = %d; want 0", repo.lastReadingsOffset)
+		if len(repo.readingsCursors) != 1 || repo.readingsCursors[0] != (repository.ReadingsCursor{}) {
+			t.Errorf("cursors = %+v; want only the newest page", repo.readingsCursors)
+		}
+		if strings.Contains(body, "history-pagination") {
+			t.Errorf("body should not paginate a single page; got %q", body)
 		}
 	})
 
 	t.Run("defaults to first station and default range", func(t *testing.T) {
 		stations := []types.Station{{ID: "first", Name: "First Station"}, {ID: "second", Name: "Second"}}
 		repo := &mockRepo{stations: stations, readings: nil}
-		ctrl := NewWeatherController(repo).(*weatherControllerImpl)
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -622,7 +1068,7 @@ func Test_handleHistoryPartial(t *testing.T) {
 	t.Run("uses Unknown Station when station_id is invalid", func(t *testing.T) {
 		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
 		repo := &mockRepo{stations: stations, readings: nil}
-		ctrl := NewWeatherController(repo).(*weatherControllerImpl)
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=missing", nil)
 		rec := httptest.NewRecorder()
 
@@ -640,7 +1086,7 @@ func Test_handleHistoryPartial(t *testing.T) {
 	t.Run("falls back to default range when range is invalid", func(t *testing.T) {
 		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
 		repo := &mockRepo{stations: stations, readings: nil}
-		ctrl := NewWeatherController(repo).(*weatherControllerImpl)
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history?range=bad", nil)
 		rec := httptest.NewRecorder()
 
@@ -656,7 +1102,7 @@ func Test_handleHistoryPartial(t *testing.T) {
 	})
 
 	t.Run("returns 500 when GetStations fails", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -669,7 +1115,7 @@ func Test_handleHistoryPartial(t *testing.T) {
 
 	t.Run("returns 500 when GetReadingsCount fails", func(t *testing.T) {
 		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
-		ctrl := NewWeatherController(&mockRepo{stations: stations, countErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations, countErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -682,7 +1128,7 @@ func Test_handleHistoryPartial(t *testing.T) {
 
 	t.Run("returns 500 when GetReadings fails", func(t *testing.T) {
 		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
-		ctrl := NewWeatherController(&mockRepo{stations: stations, readingsErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations, readingsErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/partials/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -693,34 +1139,103 @@ func Test_handleHistoryPartial(t *testing.T) {
 		}
 	})
 
-	t.Run("passes page and offset to GetReadings for page 2", func(t *testing.T) {
-		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
-		readings := make([]types.Reading, 12) // more than one page
+	// pageOf returns n readings an hour apart, newest first, ending at newest.
+	pageOf := func(n int, newest time.Time) []types.Reading {
+		readings := make([]types.Reading, n)
 		for i := range readings {
-			readings[i] = types.Reading{StationID: "st-1", Time: time.Now().Add(-time.Duration(i) * time.Hour), Value: float64(i)}
+			readings[i] = types.Reading{StationID: "st-1", Time: newest.Add(-time.Duration(i) * time.Hour), Value: float64(i)}
 		}
-		repo := &mockRepo{stations: stations, readings: readings, readingsCount: 25} // totalPages=2
-		ctrl := NewWeatherController(repo).(*weatherControllerImpl)
-		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=st-1&range=24h&page=2", nil)
+		return readings
+	}
+
+	t.Run("follows the cursor to an older page", func(t *testing.T) {
+		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
+		newest := time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)
+		repo := &mockRepo{stations: stations, readings: pageOf(historyPageSize+1, newest), readingsCount: 45} // totalPages=3
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=st-1&range=7d&page=2&cursor=older:2025-02-03T11:00:00Z", nil)
 		rec := httptest.NewRecorder()
 
 		ctrl.handleHistoryPartial(rec, req)
 
 		if rec.Code != http.StatusOK {
-			t.Errorf("status = %d; want %d", rec.Code, http.StatusOK)
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
 		}
-		if repo.lastReadingsOffset != historyPageSize {
-			t.Errorf("offset = %d; want %d", repo.lastReadingsOffset, historyPageSize)
+		want := repository.ReadingsCursor{Time: newest.Add(time.Hour)}
+		if len(repo.readingsCursors) != 1 || !repo.readingsCursors[0].Time.Equal(want.Time) || repo.readingsCursors[0].Newer {
+			t.Errorf("cursors = %+v; want %+v", repo.readingsCursors, want)
 		}
 		body := rec.Body.String()
 		if !strings.Contains(body, "aria-current=\"page\">2</span>") {
 			t.Errorf("body should show current page 2 in pagination; got %q", body)
 		}
-		if !strings.Contains(body, "Previous") {
-			t.Errorf("body should show Previous link on page 2; got %q", body)
+		for _, link := range []string{"First", "Previous", "Next", "Last"} {
+			if !strings.Contains(body, link) {
+				t.Errorf("body should show %s link on a middle page; got %q", link, body)
+			}
+		}
+		// The extra reading is not shown; the next page continues after the last one shown.
+		if strings.Contains(body, ">20.0°C") {
+			t.Errorf("body should show %d readings; got %q", historyPageSize, body)
 		}
-		if !strings.Contains(body, "First") {
-			t.Errorf("body should show First link on page 2; got %q", body)
+		if !strings.Contains(body, "page=3&cursor=older%3a2025-02-02T15%3a00%3a00Z") {
+			t.Errorf("body should link the next page after the last reading shown; got %q", body)
+		}
+	})
+
+	t.Run("previous page past the newest reading loads the first page", func(t *testing.T) {
+		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
+		repo := &mockRepo{stations: stations, readings: pageOf(5, time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)), readingsCount: 45}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=st-1&range=7d&page=2&cursor=newer:2025-02-01T10:00:00Z", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistoryPartial(rec, req)
+
+		if len(repo.readingsCursors) != 2 || !repo.readingsCursors[0].Newer || repo.readingsCursors[1] != (repository.ReadingsCursor{}) {
+			t.Errorf("cursors = %+v; want the newer page, then the newest", repo.readingsCursors)
+		}
+		body := rec.Body.String()
+		if strings.Contains(body, "Previous") {
+			t.Errorf("body should show the first page without a Previous link; got %q", body)
+		}
+	})
+
+	t.Run("last link loads the oldest page", func(t *testing.T) {
+		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
+		repo := &mockRepo{stations: stations, readings: pageOf(historyPageSize+1, time.Date(2025, 2, 3, 10, 0, 0, 0, time.UTC)), readingsCount: 45}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=st-1&range=7d&page=3&cursor=oldest", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistoryPartial(rec, req)
+
+		if len(repo.readingsCursors) != 1 || repo.readingsCursors[0] != (repository.ReadingsCursor{Newer: true}) {
+			t.Errorf("cursors = %+v; want the oldest page", repo.readingsCursors)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "aria-current=\"page\">3</span>") || strings.Contains(body, "Next") {
+			t.Errorf("body should show page 3 without a Next link; got %q", body)
+		}
+		if strings.Contains(body, ">0.0°C") {
+			t.Errorf("body should drop the newest, extra reading; got %q", body)
+		}
+	})
+
+	t.Run("pushes the shareable history URL on htmx requests", func(t *testing.T) {
+		stations := []types.Station{{ID: "st-1", Name: "Station One"}}
+		readings := []types.Reading{{StationID: "st-1", Time: time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC)}}
+		repo := &mockRepo{stations: stations, readings: readings, readingsCount: 25}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/history?station_id=st-1&range=6h&page=2&cursor=older:2025-02-03T10:00:00Z", nil)
+		req.Header.Set("HX-Request", "true")
+		req.Header.Set("HX-Current-URL", "http://localhost/history?station_id=st-1&range=6h&page=1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistoryPartial(rec, req)
+
+		if got, want := rec.Header().Get("HX-Push-Url"), "/history?cursor=older%3A2025-02-03T10%3A00%3A00Z&page=2&range=6h&station_id=st-1"; got != want {
+			t.Errorf("HX-Push-Url = %q; want %q", got, want)
 		}
 	})
 }
@@ -736,7 +1251,7 @@ func Test_handleHistory(t *testing.T) {
 	}
 
 	t.Run("defaults to first station and default range when no params or cookies", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -776,7 +1291,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("honors station_id query param", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history?station_id=st-2", nil)
 		rec := httptest.NewRecorder()
 
@@ -797,7 +1312,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("honors range query param", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history?range=7d", nil)
 		rec := httptest.NewRecorder()
 
@@ -818,7 +1333,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("honors both station_id and range query params", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history?station_id=st-2&range=1h", nil)
 		rec := httptest.NewRecorder()
 
@@ -839,7 +1354,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("falls back to cookie state when query params not provided", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		// Set cookie with station_id=st-2 and range=6h
 		cookie := &http.Cookie{
@@ -865,8 +1380,26 @@ func Test_handleHistory(t *testing.T) {
 		}
 	})
 
+	t.Run("deep link page is stored in the cookie for the partial", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/history?station_id=st-2&range=7d&page=3", nil)
+		req.AddCookie(&http.Cookie{Name: "weather_state", Value: "station_id=st-1&range=24h&page=5"})
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistory(rec, req)
+
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 {
+			t.Fatalf("cookies = %d; want 1", len(cookies))
+		}
+		stationID, rangeKey, page := parseCookieValue(cookies[0].Value)
+		if stationID != "st-2" || rangeKey != "7d" || page != 3 {
+			t.Errorf("cookie = %q, %q, %d; want st-2, 7d, 3", stationID, rangeKey, page)
+		}
+	})
+
 	t.Run("query params override cookie state", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history?station_id=st-1&range=7d", nil)
 		// Set cookie with different values
 		cookie := &http.Cookie{
@@ -893,7 +1426,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("rendered HTML includes station selector with all stations", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -920,7 +1453,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("rendered HTML includes range selector with all options", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -953,7 +1486,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("returns 500 when GetStations fails", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stationsErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -969,7 +1502,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("renders HTML successfully when templates are loaded", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -989,7 +1522,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("sets cookie with selected station and range", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: stations}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: stations}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history?station_id=st-2&range=7d", nil)
 		rec := httptest.NewRecorder()
 
@@ -1019,7 +1552,7 @@ func Test_handleHistory(t *testing.T) {
 	})
 
 	t.Run("handles empty stations list gracefully", func(t *testing.T) {
-		ctrl := NewWeatherController(&mockRepo{stations: []types.Station{}}).(*weatherControllerImpl)
+		ctrl := NewWeatherController(&mockRepo{stations: []types.Station{}}, nil, nil, nil).(*weatherControllerImpl)
 		req := httptest.NewRequest(http.MethodGet, "/history", nil)
 		rec := httptest.NewRecorder()
 
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// stationHealth scores a station from its newest reading, if any, and its
+// availability over the last 24 hours, nil when unknown. It returns nil when
+// health scoring is off.
+func (c *weatherControllerImpl) stationHealth(stationID string, latest []types.Reading, dayUptime *float64, now time.Time) *types.Health {
+	if c.health == nil {
+		return nil
+	}
+	var age *time.Duration
+	if len(latest) != 0 {
+		a := now.Sub(latest[0].Time)
+		age = &a
+	}
+	return c.health.Score(stationID, age, dayUptime)
+}
+
+// dayUptime returns the availability of every station over the 24 hours
+// ending at now, by ID, or nil when health scoring, its only user on the
+// dashboard, is off. stations carry the intervals they report.
+func (c *weatherControllerImpl) dayUptime(ctx context.Context, stations []types.Station, now time.Time) (map[string]*float64, error) {
+	if c.health == nil {
+		return nil, nil
+	}
+	pcts, err := c.stationUptime(ctx, "", stations, now.Add(-24*time.Hour), now)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*float64, len(pcts))
+	for id, pct := range pcts {
+		out[id] = &pct
+	}
+	return out, nil
+}
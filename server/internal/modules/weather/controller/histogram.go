@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+var histogramUnits = map[string]string{
+	types.MetricTemperature: "°C",
+	types.MetricHumidity:    "%",
+	types.MetricPressure:    "hPa",
+}
+
+func (c *weatherControllerImpl) handleHistogram(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	metric, from, to, bins, err := parseHistogramQuery(r, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("histogram: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+
+	buckets, err := c.repository.GetHistogram(r.Context(), station.ID, metric, from, to, bins)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	total := 0
+	for _, b := range buckets {
+		total += b.Count
+	}
+	utils.WriteJSON(w, http.StatusOK, types.Histogram{
+		StationID: station.ID,
+		Metric:    metric,
+		From:      from,
+		To:        to,
+		Total:     total,
+		Buckets:   buckets,
+	})
+}
+
+func (c *weatherControllerImpl) handleHistogramPartial(w http.ResponseWriter, r *http.Request) {
+	state := readWeatherStateCookie(c.cookies, r)
+	stationID := r.URL.Query().Get("station_id")
+	if stationID == "" {
+		stationID = state.StationID
+	}
+	rangeKey := r.URL.Query().Get("range")
+	if rangeKey == "" {
+		rangeKey = state.RangeKey
+	}
+	rangeInfo, _ := resolveHistoryRange(rangeKey)
+	metric := r.URL.Query().Get("metric")
+	if !histogramMetrics[metric] {
+		metric = types.MetricTemperature
+	}
+
+	data := views.HistogramData{
+		StationID:  stationID,
+		Metric:     metric,
+		RangeLabel: rangeInfo.Label,
+	}
+	if stationID != "" {
+		now := time.Now().UTC()
+		from := now.Add(-rangeInfo.Duration)
+		buckets, err := c.repository.GetHistogram(r.Context(), stationID, metric, from, now, defaultHistogramBins)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("histogram partial: get histogram failed", "station_id", stationID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load histogram")
+			return
+		}
+		annotations, err := c.repository.GetAnnotations(r.Context(), stationID, from, now)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("histogram partial: get annotations failed", "station_id", stationID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load annotations")
+			return
+		}
+		data.Annotations = annotationMarkers(annotations)
+		unit := histogramUnits[metric]
+		data.Bars, data.Total = buildHistogramBars(buckets, unit)
+		if len(buckets) > 0 {
+			data.MinLabel = fmt.Sprintf("%.1f %s", buckets[0].Lower, unit)
+			data.MaxLabel = fmt.Sprintf("%.1f %s", buckets[len(buckets)-1].Upper, unit)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := views.RenderHistogramPartial(&buf, &data); err != nil {
+		logging.FromContext(r.Context()).Error("histogram partial render failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logging.FromContext(r.Context()).Error("histogram partial: write response failed", "error", err)
+	}
+}
+
+// buildHistogramBars scales bucket counts to bar heights relative to the largest bucket.
+func buildHistogramBars(buckets []types.HistogramBucket, unit string) ([]views.HistogramBar, int) {
+	maxCount, total := 0, 0
+	for _, b := range buckets {
+		total += b.Count
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+	bars := make([]views.HistogramBar, 0, len(buckets))
+	for _, b := range buckets {
+		height := 0
+		if maxCount > 0 {
+			height = b.Count * 100 / maxCount
+		}
+		bars = append(bars, views.HistogramBar{
+			Label:     fmt.Sprintf("%.1f–%.1f %s", b.Lower, b.Upper, unit),
+			Count:     b.Count,
+			HeightPct: height,
+		})
+	}
+	return bars, total
+}
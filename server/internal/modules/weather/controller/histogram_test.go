@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+)
+
+func Test_handleHistogram(t *testing.T) {
+	t.Run("returns buckets and total", func(t *testing.T) {
+		repo := &mockRepo{station: types.Station{ID: "st-1"}, histogram: []types.HistogramBucket{
+			{Lower: 10, Upper: 15, Count: 3},
+			{Lower: 15, Upper: 20, Count: 1},
+		}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/histogram?metric=humidity&bins=2", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogram(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		var got types.Histogram
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.StationID != "st-1" || got.Metric != "humidity" || got.Total != 4 || len(got.Buckets) != 2 {
+			t.Errorf("histogram = %+v; want st-1 humidity total 4 with 2 buckets", got)
+		}
+		if repo.lastHistogramMetric != "humidity" || repo.lastHistogramBins != 2 {
+			t.Errorf("repo called with metric=%q bins=%d; want humidity, 2", repo.lastHistogramMetric, repo.lastHistogramBins)
+		}
+	})
+
+	t.Run("returns 400 when station id is missing", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations//histogram", nil)
+		req.SetPathValue("id", "")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogram(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("returns 400 when query is invalid", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/histogram?metric=wind", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogram(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+		if !strings.Contains(rec.Body.String(), "metric") {
+			t.Errorf("body = %q; expected metric error", rec.Body.String())
+		}
+	})
+
+	t.Run("returns 404 for unknown station", func(t *testing.T) {
+		repo := &mockRepo{stationErr: repository.ErrStationNotFound}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/nope/histogram", nil)
+		req.SetPathValue("id", "nope")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogram(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+		if repo.lastHistogramMetric != "" {
+			t.Error("histogram queried for an unknown station")
+		}
+	})
+
+	t.Run("returns 500 when repository fails", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{histogramErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/histogram", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogram(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
+
+func Test_handleHistogramPartial(t *testing.T) {
+	if err := views.LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+
+	t.Run("renders bars for selected station", func(t *testing.T) {
+		repo := &mockRepo{histogram: []types.HistogramBucket{
+			{Lower: 10, Upper: 15, Count: 4},
+			{Lower: 15, Upper: 20, Count: 2},
+		}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/histogram?station_id=st-1&range=6h&metric=pressure", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogramPartial(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		body := rec.Body.String()
+		if strings.Count(body, `class="histogram-bar"`) != 2 {
+			t.Errorf("body = %q; want 2 bars", body)
+		}
+		if !strings.Contains(body, "height: 100%") || !strings.Contains(body, "height: 50%") {
+			t.Errorf("body = %q; want bars scaled to 100%% and 50%%", body)
+		}
+		if !strings.Contains(body, "Last 6 hours") || !strings.Contains(body, "6 readings") {
+			t.Errorf("body = %q; want range label and total", body)
+		}
+		if repo.lastHistogramMetric != "pressure" {
+			t.Errorf("metric = %q; want pressure", repo.lastHistogramMetric)
+		}
+	})
+
+	t.Run("renders empty state without station", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/histogram", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogramPartial(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		if !strings.Contains(rec.Body.String(), "No readings in selected range") {
+			t.Errorf("body = %q; want empty state", rec.Body.String())
+		}
+	})
+
+	t.Run("returns 500 when repository fails", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{histogramErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/partials/histogram?station_id=st-1", nil)
+		rec := httptest.NewRecorder()
+
+		ctrl.handleHistogramPartial(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON.
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON reports whether the readings request asks for NDJSON, with
+// ?format=ndjson or an Accept header listing ndjsonContentType. An explicit
+// format=json wins over the Accept header.
+func wantsNDJSON(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("format") {
+	case "ndjson":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+	default:
+		return false, errors.New("invalid 'format' (allowed: json, ndjson)")
+	}
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			if mt, _, err := mime.ParseMediaType(part); err == nil && mt == ndjsonContentType {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// streamsNDJSON reports whether r is a valid request for the NDJSON export,
+// which the handler timeout leaves alone.
+func streamsNDJSON(r *http.Request) bool {
+	ndjson, err := wantsNDJSON(r)
+	return ndjson && err == nil
+}
+
+// streamReadingsNDJSON writes every reading of station id in [from, to] as
+// one JSON object per line, oldest first, as the rows are read. Metrics the
+// station did not report are 0, as in the paged response, metrics not in
+// fields are left out, and values are in units, echoed in unitsHeader.
+func (c *weatherControllerImpl) streamReadingsNDJSON(ctx context.Context, w http.ResponseWriter, id string, from, to time.Time, fields []string, units string) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set(unitsHeader, units)
+	body := &writeTracker{w: w}
+	enc := json.NewEncoder(body)
+	err := c.repository.EachReading(ctx, id, from, to, func(sr types.StoredReading) error {
+		reading := types.Reading{
+			StationID:   id,
+			Time:        sr.Time,
+			Value:       orZero(sr.Temperature),
+			HumidityPct: orZero(sr.Humidity),
+			PressureHpa: orZero(sr.Pressure),
+			ReceivedAt:  sr.ReceivedAt,
+		}
+		return enc.Encode(selectFields(convertReadings([]types.Reading{reading}, units), fields)[0])
+	})
+	finishExport(ctx, w, body, "readings ndjson", id, err)
+}
+
+// orZero returns *v, or 0 when v is nil.
+func orZero(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleReadings_NDJSON(t *testing.T) {
+	serve := func(repo *mockRepo, query string, accept string) *httptest.ResponseRecorder {
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/readings"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		ctrl.handleReadings(rec, req)
+		return rec
+	}
+	ts := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	temp := 21.5
+	stored := []types.StoredReading{{Time: ts, Temperature: &temp}, {Time: ts.Add(time.Minute)}}
+	const window = "?from=2025-02-01T00:00:00Z&to=2025-02-02T00:00:00Z"
+
+	for name, tc := range map[string]struct{ query, accept string }{
+		"format param":  {window + "&format=ndjson", ""},
+		"Accept header": {window, "application/json;q=0.5, application/x-ndjson"},
+	} {
+		t.Run("streams one reading per line with "+name, func(t *testing.T) {
+			rec := serve(&mockRepo{stored: stored}, tc.query, tc.accept)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d; want %d, body %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q; want application/x-ndjson", ct)
+			}
+			lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d lines; want 2: %q", len(lines), rec.Body.String())
+			}
+			var first, second types.Reading
+			if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+				t.Fatalf("decode line 1: %v", err)
+			}
+			if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+				t.Fatalf("decode line 2: %v", err)
+			}
+			if first.StationID != "1" || !first.Time.Equal(ts) || first.Value != 21.5 || second.Value != 0 {
+				t.Errorf("readings = %+v, %+v; want 21.5 then an unreported 0, oldest first", first, second)
+			}
+		})
+	}
+
+	t.Run("format=json overrides the Accept header", func(t *testing.T) {
+		rec := serve(&mockRepo{}, window+"&format=json", "application/x-ndjson")
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("Content-Type = %q; want JSON", ct)
+		}
+	})
+
+	t.Run("returns 400 for an unknown format", func(t *testing.T) {
+		rec := serve(&mockRepo{}, window+"&format=xml", "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("returns 500 when the export fails before any output", func(t *testing.T) {
+		rec := serve(&mockRepo{storedErr: errors.New("db error")}, window+"&format=ndjson", "")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
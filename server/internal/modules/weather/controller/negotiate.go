@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// wantsJSON reports whether the Accept header of a page request prefers
+// application/json to text/html: with a higher q-value, or an equal one from
+// a more specific range (as in "application/json, */*"). Pages stay the
+// default: requests without an Accept header, with */* or listing both types
+// alike get HTML.
+func wantsJSON(r *http.Request) bool {
+	jsonQ, jsonS := acceptQuality(r, "application/json")
+	htmlQ, htmlS := acceptQuality(r, "text/html")
+	return jsonQ > htmlQ || jsonQ == htmlQ && jsonQ > 0 && jsonS > htmlS
+}
+
+// acceptQuality returns the q-value the Accept header of r gives mediaType,
+// taken from the most specific range matching it, and that range's
+// specificity: 2 for the type itself, 1 for type/*, 0 for */*. Both are 0 and
+// -1 when no range matches.
+func acceptQuality(r *http.Request, mediaType string) (quality float64, specificity int) {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	specificity = -1
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mt, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			var s int
+			switch mt {
+			case mediaType:
+				s = 2
+			case typ + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s <= specificity {
+				continue
+			}
+			q := 1.0
+			if v, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(v, 64); err != nil {
+					continue
+				}
+			}
+			quality, specificity = q, s
+		}
+	}
+	return quality, specificity
+}
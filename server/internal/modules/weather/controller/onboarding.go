@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+type createStationRequest struct {
+	Name      string   `json:"name"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// handleCreateStation registers a station before it reports, from a JSON body
+// or the onboarding form. Form posts are redirected to the new station's page,
+// or get the onboarding page back with the error.
+func (c *weatherControllerImpl) handleCreateStation(w http.ResponseWriter, r *http.Request) {
+	form := isFormPost(r)
+	var req createStationRequest
+	if form {
+		var err error
+		if req, err = parseCreateStationForm(r); err != nil {
+			c.writeCreateStationError(w, r, form, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateStationName(req.Name); err != nil {
+		c.writeCreateStationError(w, r, form, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateLocation(req.Latitude, req.Longitude); err != nil {
+		c.writeCreateStationError(w, r, form, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	station, err := c.repository.CreateStation(r.Context(), req.Name, req.Latitude, req.Longitude)
+	if errors.Is(err, repository.ErrStationExists) {
+		c.writeCreateStationError(w, r, form, http.StatusConflict, fmt.Sprintf("a station named %q already exists", req.Name))
+		return
+	}
+	if errors.Is(err, repository.ErrQuotaExceeded) {
+		c.writeCreateStationError(w, r, form, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create station failed", "name", req.Name, "error", err)
+		c.writeCreateStationError(w, r, form, http.StatusInternalServerError, "failed to create station")
+		return
+	}
+	logging.FromContext(r.Context()).Info("station created", "station_id", station.ID, "name", station.Name)
+	if form {
+		http.Redirect(w, r, "/stations/"+station.ID, http.StatusSeeOther)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, station)
+}
+
+// writeCreateStationError reports a failed station creation: as a JSON error,
+// or for form posts on the onboarding page with the form refilled.
+func (c *weatherControllerImpl) writeCreateStationError(w http.ResponseWriter, r *http.Request, form bool, status int, msg string) {
+	if !form {
+		utils.WriteError(w, status, msg)
+		return
+	}
+	c.renderOnboarding(r.Context(), w, status, &views.OnboardingData{
+		Error:     msg,
+		Name:      r.PostFormValue("name"),
+		Latitude:  r.PostFormValue("latitude"),
+		Longitude: r.PostFormValue("longitude"),
+	})
+}
+
+// validateStationName rejects names ingest would take for a station ID.
+func validateStationName(name string) error {
+	if name == "" {
+		return errors.New("'name' is required")
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return errors.New("'name' must not be a number, which is read as a station ID")
+	}
+	return nil
+}
+
+// parseCreateStationForm reads the onboarding form; empty coordinates are
+// unset.
+func parseCreateStationForm(r *http.Request) (createStationRequest, error) {
+	req := createStationRequest{Name: r.PostFormValue("name")}
+	for _, f := range []struct {
+		name string
+		dst  **float64
+	}{
+		{"latitude", &req.Latitude},
+		{"longitude", &req.Longitude},
+	} {
+		s := strings.TrimSpace(r.PostFormValue(f.name))
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return createStationRequest{}, fmt.Errorf("invalid '%s' (expected a number)", f.name)
+		}
+		*f.dst = &v
+	}
+	return req, nil
+}
+
+// renderOnboarding writes the onboarding page, shown on the dashboard while
+// there are no stations, with status.
+func (c *weatherControllerImpl) renderOnboarding(ctx context.Context, w http.ResponseWriter, status int, data *views.OnboardingData) {
+	var buf bytes.Buffer
+	if err := views.RenderOnboarding(&buf, data); err != nil {
+		logging.FromContext(ctx).Error("onboarding template render failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render page")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if _, err := buf.WriteTo(w); err != nil {
+		logging.FromContext(ctx).Debug("onboarding: write response failed", "error", err)
+	}
+}
+
+func isFormPost(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/x-www-form-urlencoded") || strings.HasPrefix(ct, "multipart/form-data")
+}
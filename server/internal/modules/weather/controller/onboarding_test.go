@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+)
+
+func Test_handleDashboard_onboarding(t *testing.T) {
+	if err := views.LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+	rec := httptest.NewRecorder()
+
+	ctrl.handleDashboard(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`action="/api/v1/stations"`, "Pair a gateway", "STATION_MAP_FILE"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q; got %q", want, body)
+		}
+	}
+}
+
+func Test_handleCreateStation(t *testing.T) {
+	if err := views.LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	post := func(repo *mockRepo, contentType, body string) *httptest.ResponseRecorder {
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/stations", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		ctrl.handleCreateStation(rec, req)
+		return rec
+	}
+
+	t.Run("creates from JSON", func(t *testing.T) {
+		rec := post(&mockRepo{}, "application/json", `{"name":" garden ","latitude":52.23,"longitude":21.01}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d; want %d (body %s)", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		var got types.Station
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if got.Name != "garden" || got.Latitude == nil || *got.Latitude != 52.23 {
+			t.Errorf("station = %+v; want garden at 52.23", got)
+		}
+	})
+
+	t.Run("form redirects to the station page", func(t *testing.T) {
+		form := url.Values{"name": {"garden"}, "latitude": {""}, "longitude": {""}}
+		rec := post(&mockRepo{}, "application/x-www-form-urlencoded", form.Encode())
+		if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/stations/7" {
+			t.Errorf("status = %d, Location %q; want 303 to /stations/7", rec.Code, rec.Header().Get("Location"))
+		}
+	})
+
+	tests := []struct {
+		name       string
+		repo       *mockRepo
+		body       string
+		wantStatus int
+	}{
+		{"name required", &mockRepo{}, `{"name":" "}`, http.StatusBadRequest},
+		{"numeric name", &mockRepo{}, `{"name":"42"}`, http.StatusBadRequest},
+		{"half a location", &mockRepo{}, `{"name":"garden","latitude":52}`, http.StatusBadRequest},
+		{"name taken", &mockRepo{createErr: repository.ErrStationExists}, `{"name":"garden"}`, http.StatusConflict},
+		{"quota", &mockRepo{createErr: fmt.Errorf("%w: limit", repository.ErrQuotaExceeded)}, `{"name":"garden"}`, http.StatusForbidden},
+		{"repository error", &mockRepo{createErr: errors.New("db error")}, `{"name":"garden"}`, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(tt.repo, "application/json", tt.body); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d; want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("form errors render the onboarding page", func(t *testing.T) {
+		form := url.Values{"name": {"garden"}, "latitude": {"north"}}
+		rec := post(&mockRepo{}, "application/x-www-form-urlencoded", form.Encode())
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "invalid &#39;latitude&#39;") || !strings.Contains(body, `value="garden"`) {
+			t.Errorf("body = %q; want the error and the refilled form", body)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/openapi"
+	"cloudpico-server/internal/utils"
+)
+
+// Query parameters shared by several operations.
+var (
+	fromParam   = openapi.Param{Name: "from", Format: "date-time", Description: "Start of the range (RFC 3339); defaults to 24 hours before to."}
+	toParam     = openapi.Param{Name: "to", Format: "date-time", Description: "End of the range (RFC 3339); defaults to now."}
+	metricParam = openapi.Param{Name: "metric", Enum: []string{types.MetricTemperature, types.MetricHumidity, types.MetricPressure},
+		Description: "Metric; defaults to temperature."}
+	fieldsParam = openapi.Param{Name: "fields", Description: "Comma-separated metrics to return: temperature, humidity, pressure; defaults to all."}
+	unitsParam  = openapi.Param{Name: "units", Enum: []string{unitsMetric, unitsImperial}, Description: "Unit system; defaults to metric."}
+)
+
+// pageParams are the offset pagination parameters of collections with the
+// given limits.
+func pageParams(defaultLimit, maxLimit int) []openapi.Param {
+	return []openapi.Param{
+		{Name: "limit", Type: "integer", Description: "Page size, 1 to " + strconv.Itoa(maxLimit) + "; defaults to " + strconv.Itoa(defaultLimit) + "."},
+		{Name: "offset", Type: "integer", Description: "Items to skip; defaults to 0."},
+	}
+}
+
+// Operations describes the JSON API routes of RegisterRoutes for the OpenAPI
+// specification; a test keeps the two in sync. HTML pages and partials are
+// not part of the API.
+func Operations() []openapi.Operation {
+	stationsTag, readingsTag, dashboardTag := "stations", "readings", "dashboard"
+	return []openapi.Operation{
+		{
+			Pattern: "GET /api/v1/stations", ID: "listStations", Tag: stationsTag,
+			Summary:  "List stations with their availability; honors If-None-Match and If-Modified-Since",
+			Query:    pageParams(defaultStationsLimit, maxStationsLimit),
+			Response: map[string]any{"application/json": utils.Page[types.Station]{}},
+		},
+		{
+			Pattern: "POST /api/v1/stations", ID: "createStation", Tag: stationsTag,
+			Summary: "Register a station before it reports",
+			Request: map[string]any{"application/json": createStationRequest{}},
+			Status:  http.StatusCreated, Response: map[string]any{"application/json": types.Station{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations.geojson", ID: "listStationsGeoJSON", Tag: stationsTag,
+			Summary:  "Stations with a location as GeoJSON points",
+			Response: map[string]any{"application/geo+json": types.FeatureCollection{}},
+		},
+		{
+			Pattern: "GET /api/v1/favorites", ID: "getFavorites", Tag: dashboardTag,
+			Summary:  "Favorite station IDs in dashboard order",
+			Response: map[string]any{"application/json": favoritesBody{}},
+		},
+		{
+			Pattern: "PUT /api/v1/favorites", ID: "setFavorites", Tag: dashboardTag,
+			Summary:  "Replace the favorite stations",
+			Request:  map[string]any{"application/json": favoritesBody{}},
+			Response: map[string]any{"application/json": favoritesBody{}},
+		},
+		{
+			Pattern: "POST /api/v1/stations/{id}/favorite", ID: "addFavorite", Tag: dashboardTag,
+			Summary:  "Append a station to the favorites",
+			Response: map[string]any{"application/json": favoritesBody{}},
+		},
+		{
+			Pattern: "DELETE /api/v1/stations/{id}/favorite", ID: "removeFavorite", Tag: dashboardTag,
+			Summary:  "Remove a station from the favorites",
+			Response: map[string]any{"application/json": favoritesBody{}},
+		},
+		{
+			Pattern: "GET /api/v1/dashboard/metrics", ID: "getCardMetrics", Tag: dashboardTag,
+			Summary:  "Metrics shown on the dashboard cards",
+			Response: map[string]any{"application/json": cardMetricsBody{}},
+		},
+		{
+			Pattern: "PUT /api/v1/dashboard/metrics", ID: "setCardMetrics", Tag: dashboardTag,
+			Summary:  "Replace the dashboard card metrics",
+			Request:  map[string]any{"application/json": cardMetricsBody{}},
+			Response: map[string]any{"application/json": cardMetricsBody{}},
+		},
+		{
+			Pattern: "POST /api/v1/dashboard/metrics/{metric}", ID: "enableCardMetric", Tag: dashboardTag,
+			Summary:  "Show a metric on the dashboard cards",
+			Response: map[string]any{"application/json": cardMetricsBody{}},
+		},
+		{
+			Pattern: "DELETE /api/v1/dashboard/metrics/{metric}", ID: "disableCardMetric", Tag: dashboardTag,
+			Summary:  "Hide a metric from the dashboard cards",
+			Response: map[string]any{"application/json": cardMetricsBody{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/latest", ID: "getLatestReadings", Tag: readingsTag,
+			Summary: "Latest readings of a station, newest first; honors If-None-Match and If-Modified-Since",
+			Query: []openapi.Param{
+				{Name: "limit", Type: "integer", Description: "Number of readings, 1 to 1000; defaults to 100."},
+				fieldsParam, unitsParam,
+			},
+			Response: map[string]any{"application/json": []fieldsReading{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/latest/metrics", ID: "getLatestMetrics", Tag: readingsTag,
+			Summary:  "Latest value of each metric with its freshness",
+			Response: map[string]any{"application/json": types.LatestMetrics{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/readings", ID: "getReadings", Tag: readingsTag,
+			Summary: "Readings of a station with the overlapping annotations, or all of them as NDJSON",
+			Query: append(pageParams(defaultReadingsLimit, maxReadingsLimit),
+				openapi.Param{Name: "from", Format: "date-time", Description: "Start of the range (RFC 3339)."},
+				openapi.Param{Name: "to", Format: "date-time", Description: "End of the range (RFC 3339)."},
+				openapi.Param{Name: "cursor", Description: "nextCursor or prevCursor of a previous page; exclusive with offset, which is deprecated."},
+				fieldsParam, unitsParam,
+				openapi.Param{Name: "format", Enum: []string{"json", "ndjson"}, Description: "Response format; ndjson streams every reading in the range."},
+			),
+			Response: map[string]any{
+				"application/json": readingsPage{},
+				ndjsonContentType:  fieldsReading{},
+			},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/readings.csv", ID: "exportReadingsCSV", Tag: readingsTag,
+			Summary:  "Readings of a station as CSV",
+			Query:    []openapi.Param{fromParam, toParam},
+			Response: map[string]any{"text/csv": ""},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/annotations", ID: "listAnnotations", Tag: readingsTag,
+			Summary: "Annotations overlapping a range",
+			Query: append(pageParams(defaultReadingsLimit, maxReadingsLimit),
+				openapi.Param{Name: "from", Format: "date-time", Description: "Start of the range (RFC 3339)."},
+				openapi.Param{Name: "to", Format: "date-time", Description: "End of the range (RFC 3339)."},
+			),
+			Response: map[string]any{"application/json": utils.Page[types.Annotation]{}},
+		},
+		{
+			Pattern: "POST /api/v1/stations/{id}/annotations", ID: "createAnnotation", Tag: readingsTag,
+			Summary: "Annotate a time range of a station",
+			Request: map[string]any{"application/json": createAnnotationRequest{}},
+			Status:  http.StatusCreated, Response: map[string]any{"application/json": types.Annotation{}},
+		},
+		{
+			Pattern: "DELETE /api/v1/stations/{id}/annotations/{annotationId}", ID: "deleteAnnotation", Tag: readingsTag,
+			Summary: "Delete an annotation",
+			Status:  http.StatusNoContent,
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/snapshots", ID: "listSnapshots", Tag: readingsTag,
+			Summary: "Snapshots overlapping a range",
+			Query: append(pageParams(defaultReadingsLimit, maxReadingsLimit),
+				openapi.Param{Name: "from", Format: "date-time", Description: "Start of the range (RFC 3339)."},
+				openapi.Param{Name: "to", Format: "date-time", Description: "End of the range (RFC 3339)."},
+			),
+			Response: map[string]any{"application/json": utils.Page[types.Snapshot]{}},
+		},
+		{
+			Pattern: "POST /api/v1/stations/{id}/snapshots", ID: "createSnapshot", Tag: readingsTag,
+			Summary: "Attach an image URL to a time range of a station",
+			Request: map[string]any{"application/json": createSnapshotRequest{}},
+			Status:  http.StatusCreated, Response: map[string]any{"application/json": types.Snapshot{}},
+		},
+		{
+			Pattern: "DELETE /api/v1/stations/{id}/snapshots/{snapshotId}", ID: "deleteSnapshot", Tag: readingsTag,
+			Summary: "Delete a snapshot",
+			Status:  http.StatusNoContent,
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/histogram", ID: "getHistogram", Tag: readingsTag,
+			Summary: "Distribution of a metric over a range",
+			Query: []openapi.Param{metricParam, fromParam, toParam,
+				{Name: "bins", Type: "integer", Description: "Number of bins, 1 to " + strconv.Itoa(maxHistogramBins) + "; defaults to " + strconv.Itoa(defaultHistogramBins) + "."}},
+			Response: map[string]any{"application/json": types.Histogram{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/aggregate", ID: "getAggregate", Tag: readingsTag,
+			Summary: "Hourly or daily aggregates of every metric",
+			Query: []openapi.Param{
+				{Name: "bucket", Enum: []string{types.BucketHour, types.BucketDay}, Description: "Bucket width; defaults to 1h."},
+				{Name: "from", Format: "date-time", Description: "Start of the range (RFC 3339); defaults to 24 hours (1h buckets) or 30 days (1d buckets) before to."},
+				toParam, unitsParam,
+			},
+			Response: map[string]any{"application/json": types.Aggregate{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/stats", ID: "getStats", Tag: readingsTag,
+			Summary:  "Statistics of every metric over a range",
+			Query:    []openapi.Param{fromParam, toParam},
+			Response: map[string]any{"application/json": types.Stats{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/gaps", ID: "getGaps", Tag: readingsTag,
+			Summary: "Periods without readings",
+			Query: []openapi.Param{fromParam, toParam,
+				{Name: "interval", Description: "Expected reporting interval as a duration, e.g. 60s; defaults to 1m."},
+				{Name: "factor", Type: "number", Description: "Multiple of the interval without readings that counts as a gap; defaults to 3."}},
+			Response: map[string]any{"application/json": types.GapReport{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/climate", ID: "getClimate", Tag: readingsTag,
+			Summary:  "Monthly climate of a station from the daily rollups",
+			Response: map[string]any{"application/json": types.Climate{}},
+		},
+		{
+			Pattern: "POST /api/v1/stations/{id}/shadows", ID: "createShadowStation", Tag: stationsTag,
+			Summary: "Register a shadow station to compare with the station",
+			Request: map[string]any{"application/json": createShadowRequest{}},
+			Status:  http.StatusCreated, Response: map[string]any{"application/json": types.Station{}},
+		},
+		{
+			Pattern: "GET /api/v1/stations/{id}/comparison", ID: "getComparison", Tag: stationsTag,
+			Summary: "Bias and drift of a shadow station against its primary",
+			Query: []openapi.Param{metricParam, fromParam, toParam,
+				{Name: "tolerance", Description: "Maximum time between paired readings as a duration, e.g. 2m."}},
+			Response: map[string]any{"application/json": types.Comparison{}},
+		},
+		{
+			Pattern: "PUT /api/v1/stations/{id}/location", ID: "setStationLocation", Tag: stationsTag,
+			Summary:  "Set or clear the location of a station",
+			Request:  map[string]any{"application/json": setLocationRequest{}},
+			Response: map[string]any{"application/json": types.Station{}},
+		},
+		{
+			Pattern: "PUT /api/v1/stations/{id}/retention", ID: "setStationRetention", Tag: stationsTag,
+			Summary:  "Set how long raw readings of a station are kept; null reverts to the server default",
+			Request:  map[string]any{"application/json": setRetentionRequest{}},
+			Response: map[string]any{"application/json": types.Station{}},
+		},
+		{
+			Pattern: "POST /api/v1/stations/{id}/photo", ID: "uploadStationPhoto", Tag: stationsTag,
+			Summary:  "Upload a photo of a station, replacing any previous one",
+			Request:  map[string]any{"multipart/form-data": photoUpload{}},
+			Response: map[string]any{"application/json": photoResponse{}},
+		},
+		{
+			Pattern: "DELETE /api/v1/stations/{id}/photo", ID: "deleteStationPhoto", Tag: stationsTag,
+			Summary: "Delete the photo of a station",
+			Status:  http.StatusNoContent,
+		},
+	}
+}
+
+// photoUpload is the form of handleUploadPhoto.
+type photoUpload struct {
+	Photo openapi.File `json:"photo"`
+}
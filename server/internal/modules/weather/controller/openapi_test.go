@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestOperations_MatchRoutes keeps Operations in sync with RegisterRoutes:
+// every operation is served under its pattern and every /api/v1 route is
+// described.
+func TestOperations_MatchRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	NewWeatherController(&mockRepo{}, nil, nil, nil).RegisterRoutes(mux)
+	var described []string
+	for _, op := range Operations() {
+		described = append(described, op.Pattern)
+		method, path, _ := strings.Cut(op.Pattern, " ")
+		req := httptest.NewRequest(method, strings.NewReplacer("{id}", "1", "{annotationId}", "2", "{metric}", "humidity").Replace(path), nil)
+		if _, pattern := mux.Handler(req); pattern != op.Pattern {
+			t.Errorf("operation %s is served by %q", op.Pattern, pattern)
+		}
+	}
+
+	src, err := os.ReadFile("controller.go")
+	if err != nil {
+		t.Fatalf("read controller.go: %v", err)
+	}
+	for _, m := range regexp.MustCompile(`mux\.HandleFunc\("([A-Z]+ /api/v1/[^"]*)"`).FindAllStringSubmatch(string(src), -1) {
+		if !slices.Contains(described, m[1]) {
+			t.Errorf("route %s has no operation; add it to Operations", m[1])
+		}
+	}
+}
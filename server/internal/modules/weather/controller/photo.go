@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/photos"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/utils"
+)
+
+// multipartOverhead allows for form boundaries and headers around the photo part.
+const multipartOverhead = 64 << 10
+
+type photoResponse struct {
+	PhotoURL string `json:"photoUrl"`
+	ThumbURL string `json:"thumbUrl"`
+}
+
+// handleUploadPhoto stores the "photo" form file for station {id}, replacing any previous photo.
+func (c *weatherControllerImpl) handleUploadPhoto(w http.ResponseWriter, r *http.Request) {
+	if c.photos == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, "photo uploads are not configured")
+		return
+	}
+	id := r.PathValue("id")
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("upload photo: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, c.photos.MaxBytes+multipartOverhead)
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, photos.ErrTooLarge.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, "expected multipart form with a 'photo' file")
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	rel, err := c.photos.Save(station.ID, file)
+	switch {
+	case errors.Is(err, photos.ErrTooLarge):
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case errors.Is(err, photos.ErrUnsupportedType):
+		utils.WriteError(w, http.StatusUnsupportedMediaType, photos.ErrUnsupportedType.Error())
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).Error("upload photo: save failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to store photo")
+		return
+	}
+
+	if err := c.repository.SetStationPhoto(r.Context(), station.ID, rel); err != nil {
+		logging.FromContext(r.Context()).Error("upload photo: update station failed", "station_id", id, "error", err)
+		_ = c.photos.Remove(rel)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to update station")
+		return
+	}
+	if err := c.photos.Remove(station.PhotoPath); err != nil {
+		logging.FromContext(r.Context()).Warn("upload photo: remove previous photo failed", "station_id", id, "path", station.PhotoPath, "error", err)
+	}
+	utils.WriteJSON(w, http.StatusOK, photoResponse{
+		PhotoURL: photos.URL(rel),
+		ThumbURL: photos.URL(photos.ThumbPath(rel)),
+	})
+}
+
+// handleDeletePhoto removes the photo of station {id}.
+func (c *weatherControllerImpl) handleDeletePhoto(w http.ResponseWriter, r *http.Request) {
+	if c.photos == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, "photo uploads are not configured")
+		return
+	}
+	id := r.PathValue("id")
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("delete photo: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	if err := c.repository.SetStationPhoto(r.Context(), station.ID, ""); err != nil {
+		logging.FromContext(r.Context()).Error("delete photo: update station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to update station")
+		return
+	}
+	if err := c.photos.Remove(station.PhotoPath); err != nil {
+		logging.FromContext(r.Context()).Warn("delete photo: remove file failed", "station_id", id, "path", station.PhotoPath, "error", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
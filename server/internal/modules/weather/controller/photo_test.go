@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/photos"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func multipartPhoto(t *testing.T, field string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(field, "photo.png")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart: %v", err)
+	}
+	return &body, mw.FormDataContentType()
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func Test_handleUploadPhoto(t *testing.T) {
+	upload := func(t *testing.T, repo *mockRepo, store *photos.Store, field string, data []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := http.NewServeMux()
+		NewWeatherController(repo, store, nil, nil).RegisterRoutes(mux)
+		body, contentType := multipartPhoto(t, field, data)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/stations/1/photo", body)
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("stores photo and updates station", func(t *testing.T) {
+		repo := &mockRepo{station: types.Station{ID: "1", Name: "Garden"}}
+		store := photos.NewStore(t.TempDir(), 1<<20)
+
+		rec := upload(t, repo, store, "photo", testPNG(t))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want 200 (body %s)", rec.Code, rec.Body.String())
+		}
+		var got photoResponse
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if !strings.HasPrefix(repo.photoPath, "stations/1-") || got.ThumbURL != photos.URL(photos.ThumbPath(repo.photoPath)) {
+			t.Errorf("photoPath = %q, response = %+v", repo.photoPath, got)
+		}
+		if _, err := os.Stat(filepath.Join(store.Dir, repo.photoPath)); err != nil {
+			t.Errorf("photo not written: %v", err)
+		}
+	})
+
+	t.Run("removes previous photo", func(t *testing.T) {
+		store := photos.NewStore(t.TempDir(), 1<<20)
+		old, err := store.Save("1", bytes.NewReader(testPNG(t)))
+		if err != nil {
+			t.Fatalf("seed photo: %v", err)
+		}
+		repo := &mockRepo{station: types.Station{ID: "1", PhotoPath: old}}
+
+		if rec := upload(t, repo, store, "photo", testPNG(t)); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want 200", rec.Code)
+		}
+		if _, err := os.Stat(filepath.Join(store.Dir, old)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("old photo still present: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		repo  *mockRepo
+		store *photos.Store
+		field string
+		data  []byte
+		want  int
+	}{
+		{"uploads disabled", &mockRepo{}, nil, "photo", nil, http.StatusServiceUnavailable},
+		{"unknown station", &mockRepo{stationErr: repository.ErrStationNotFound}, photos.NewStore(os.TempDir(), 1<<20), "photo", nil, http.StatusNotFound},
+		{"missing file field", &mockRepo{station: types.Station{ID: "1"}}, photos.NewStore(os.TempDir(), 1<<20), "other", []byte("x"), http.StatusBadRequest},
+		{"unsupported type", &mockRepo{station: types.Station{ID: "1"}}, photos.NewStore(os.TempDir(), 1<<20), "photo", []byte("<svg/>"), http.StatusUnsupportedMediaType},
+		{"too large", &mockRepo{station: types.Station{ID: "1"}}, photos.NewStore(os.TempDir(), 16), "photo", bytes.Repeat([]byte{0}, 1024), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := upload(t, tt.repo, tt.store, tt.field, tt.data); rec.Code != tt.want {
+				t.Errorf("status = %d; want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.repo.photoPath != "" {
+				t.Errorf("station photo updated to %q on failure", tt.repo.photoPath)
+			}
+		})
+	}
+}
+
+func Test_handleDeletePhoto(t *testing.T) {
+	store := photos.NewStore(t.TempDir(), 1<<20)
+	rel, err := store.Save("1", bytes.NewReader(testPNG(t)))
+	if err != nil {
+		t.Fatalf("seed photo: %v", err)
+	}
+	repo := &mockRepo{station: types.Station{ID: "1", PhotoPath: rel}, photoPath: rel}
+	mux := http.NewServeMux()
+	NewWeatherController(repo, store, nil, nil).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/stations/1/photo", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d; want 204", rec.Code)
+	}
+	if repo.photoPath != "" {
+		t.Errorf("photoPath = %q; want cleared", repo.photoPath)
+	}
+	if _, err := os.Stat(filepath.Join(store.Dir, rel)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("photo file still present: %v", err)
+	}
+}
+
+func Test_stationReading(t *testing.T) {
+	withPhoto := stationReading(types.Station{ID: "1", Name: "G", PhotoPath: "stations/1-5.png"}, nil)
+	if withPhoto.ThumbURL != "/uploads/stations/1-5-thumb.jpg" || withPhoto.Reading != nil {
+		t.Errorf("stationReading() = %+v", withPhoto)
+	}
+	withoutPhoto := stationReading(types.Station{ID: "2"}, []types.Reading{{Value: 1}})
+	if withoutPhoto.ThumbURL != "" || withoutPhoto.Reading == nil {
+		t.Errorf("stationReading() = %+v", withoutPhoto)
+	}
+}
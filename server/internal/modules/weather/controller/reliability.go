@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+// A gap is a period longer than the expected reporting interval × factor
+// without readings. The defaults suit the gateway's 60s poll interval;
+// stations that report a longer interval of their own are expected at that
+// (see gapInterval).
+const (
+	defaultGapInterval = time.Minute
+	defaultGapFactor   = 3.0
+	defaultGapSpan     = 24 * time.Hour
+	maxGapSpan         = 31 * 24 * time.Hour
+)
+
+// uptimeTarget is the availability percentage below which a window is
+// highlighted on the station page.
+const uptimeTarget = 99.0
+
+// uptimeWindows are the availability windows reported for each station, using
+// the default gap threshold.
+var uptimeWindows = []struct {
+	label string
+	span  time.Duration
+	field func(*types.Uptime) **float64
+}{
+	{"Last 24 hours", 24 * time.Hour, func(u *types.Uptime) **float64 { return &u.Day }},
+	{"Last 7 days", 7 * 24 * time.Hour, func(u *types.Uptime) **float64 { return &u.Week }},
+	{"Last 30 days", 30 * 24 * time.Hour, func(u *types.Uptime) **float64 { return &u.Month }},
+}
+
+// handleGaps reports the data gaps of station {id}.
+func (c *weatherControllerImpl) handleGaps(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	from, to, interval, factor, err := parseGapsQuery(r, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("interval") == "" {
+		station, err := c.repository.GetStation(r.Context(), id)
+		switch {
+		case err == nil:
+			interval = gapInterval(station)
+		case !errors.Is(err, repository.ErrStationNotFound):
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	report, err := c.gapReport(r.Context(), id, from, to, interval, factor)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, report)
+}
+
+// handleGapsPartial renders the dashboard's gaps widget: one row per station
+// over the last 24 hours with the default threshold.
+func (c *weatherControllerImpl) handleGapsPartial(w http.ResponseWriter, r *http.Request) {
+	stations, err := c.repository.GetStations(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("gaps partial: get stations failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load stations")
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-defaultGapSpan)
+	data := views.GapsData{
+		RangeLabel:     historyRanges["24h"].Label,
+		ThresholdLabel: formatGapDuration(defaultGapFactor * defaultGapInterval.Seconds()),
+	}
+	for _, s := range stations {
+		report, err := c.gapReport(r.Context(), s.ID, from, to, gapInterval(s), defaultGapFactor)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("gaps partial: get gaps failed", "station_id", s.ID, "error", err)
+			utils.WriteError(w, http.StatusInternalServerError, "failed to load gaps")
+			return
+		}
+		data.Rows = append(data.Rows, views.GapRow{
+			StationName: s.Name,
+			Count:       report.Count,
+			Downtime:    formatGapDuration(report.TotalSeconds),
+			Longest:     formatGapDuration(report.LongestSeconds),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := views.RenderGapsPartial(&buf, &data); err != nil {
+		logging.FromContext(r.Context()).Error("gaps partial render failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logging.FromContext(r.Context()).Error("gaps partial: write response failed", "error", err)
+	}
+}
+
+func (c *weatherControllerImpl) gapReport(ctx context.Context, stationID string, from, to time.Time, interval time.Duration, factor float64) (types.GapReport, error) {
+	threshold := time.Duration(float64(interval) * factor)
+	gaps, err := c.repository.GetGaps(ctx, stationID, from, to, threshold)
+	if err != nil {
+		return types.GapReport{}, err
+	}
+	report := types.GapReport{
+		StationID:               stationID,
+		From:                    from,
+		To:                      to,
+		ExpectedIntervalSeconds: interval.Seconds(),
+		Factor:                  factor,
+		ThresholdSeconds:        threshold.Seconds(),
+		Count:                   len(gaps),
+		Gaps:                    gaps,
+	}
+	for _, g := range gaps {
+		report.TotalSeconds += g.Seconds
+		report.LongestSeconds = max(report.LongestSeconds, g.Seconds)
+	}
+	return report, nil
+}
+
+// gapInterval returns the interval a station's readings are expected at:
+// the default, or the longer one it last reported.
+func gapInterval(s types.Station) time.Duration {
+	return max(defaultGapInterval, time.Duration(s.IntervalSeconds)*time.Second)
+}
+
+// stationUptime returns GetUptime for stationID, "" for all stations, over
+// [from, to] with the default gap threshold. The stations among stations
+// that report a longer interval are then queried again with their own.
+func (c *weatherControllerImpl) stationUptime(ctx context.Context, stationID string, stations []types.Station, from, to time.Time) (map[string]float64, error) {
+	pcts, err := c.repository.GetUptime(ctx, stationID, from, to, time.Duration(float64(defaultGapInterval)*defaultGapFactor))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stations {
+		interval := gapInterval(s)
+		if _, ok := pcts[s.ID]; !ok || interval == defaultGapInterval {
+			continue
+		}
+		own, err := c.repository.GetUptime(ctx, s.ID, from, to, time.Duration(float64(interval)*defaultGapFactor))
+		if err != nil {
+			return nil, err
+		}
+		if pct, ok := own[s.ID]; ok {
+			pcts[s.ID] = pct
+		}
+	}
+	return pcts, nil
+}
+
+// uptime returns the availability of each station, by ID, over every
+// uptimeWindows entry ending at now. An empty stationID selects all stations;
+// stations lists those with their reported intervals (see stationUptime).
+func (c *weatherControllerImpl) uptime(ctx context.Context, stationID string, stations []types.Station, now time.Time) (map[string]*types.Uptime, error) {
+	out := make(map[string]*types.Uptime)
+	for _, win := range uptimeWindows {
+		pcts, err := c.stationUptime(ctx, stationID, stations, now.Add(-win.span), now)
+		if err != nil {
+			return nil, err
+		}
+		for id, pct := range pcts {
+			u := out[id]
+			if u == nil {
+				u = &types.Uptime{}
+				out[id] = u
+			}
+			*win.field(u) = &pct
+		}
+	}
+	return out, nil
+}
+
+// parseGapsQuery parses from, to, interval, and factor for the gaps endpoint.
+// Defaults: to=now, from=to-24h, interval=1m, factor=3.
+func parseGapsQuery(r *http.Request, now time.Time) (from time.Time, to time.Time, interval time.Duration, factor float64, err error) {
+	q := r.URL.Query()
+
+	to = now
+	if s := q.Get("to"); s != "" {
+		to, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, 0, errors.New("invalid 'to' (expected RFC3339)")
+		}
+	}
+	from = to.Add(-defaultGapSpan)
+	if s := q.Get("from"); s != "" {
+		from, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, 0, errors.New("invalid 'from' (expected RFC3339)")
+		}
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, 0, 0, errors.New("'from' must be <= 'to'")
+	}
+	if to.Sub(from) > maxGapSpan {
+		return time.Time{}, time.Time{}, 0, 0, fmt.Errorf("range must be <= %d days", int(maxGapSpan.Hours()/24))
+	}
+
+	interval = defaultGapInterval
+	if s := q.Get("interval"); s != "" {
+		interval, err = time.ParseDuration(s)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, 0, errors.New("invalid 'interval' (expected duration, e.g. 60s)")
+		}
+		if interval <= 0 {
+			return time.Time{}, time.Time{}, 0, 0, errors.New("'interval' must be > 0")
+		}
+	}
+
+	factor = defaultGapFactor
+	if s := q.Get("factor"); s != "" {
+		factor, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, 0, errors.New("invalid 'factor' (expected number)")
+		}
+		if factor < 1 {
+			return time.Time{}, time.Time{}, 0, 0, errors.New("'factor' must be >= 1")
+		}
+	}
+	return from, to, interval, factor, nil
+}
+
+// formatGapDuration renders seconds as e.g. "45s", "12m", or "3h 5m".
+func formatGapDuration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second)).Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
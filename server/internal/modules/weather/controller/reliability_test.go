@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+)
+
+func Test_handleGaps(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	gaps := []types.Gap{
+		{Start: start, End: start.Add(10 * time.Minute), Seconds: 600},
+		{Start: start.Add(time.Hour), End: start.Add(time.Hour + 5*time.Minute), Seconds: 300},
+	}
+
+	t.Run("returns gaps with totals", func(t *testing.T) {
+		repo := &mockRepo{gaps: map[string][]types.Gap{"st-1": gaps}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/gaps?interval=30s&factor=4", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleGaps(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		var got types.GapReport
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.StationID != "st-1" || got.Count != 2 || got.TotalSeconds != 900 || got.LongestSeconds != 600 {
+			t.Errorf("report = %+v; want st-1 with 2 gaps, 900s total, 600s longest", got)
+		}
+		if got.ThresholdSeconds != 120 || repo.lastGapsThreshold != 2*time.Minute {
+			t.Errorf("threshold = %vs (repo %v); want 120s", got.ThresholdSeconds, repo.lastGapsThreshold)
+		}
+		if got.To.Sub(got.From) != defaultGapSpan {
+			t.Errorf("range = %v–%v; want default span %v", got.From, got.To, defaultGapSpan)
+		}
+	})
+
+	t.Run("expects the interval the station reports", func(t *testing.T) {
+		repo := &mockRepo{station: types.Station{ID: "st-1", IntervalSeconds: 300}}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		for query, want := range map[string]time.Duration{"": 15 * time.Minute, "?interval=30s": 90 * time.Second} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/gaps"+query, nil)
+			req.SetPathValue("id", "st-1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleGaps(rec, req)
+
+			if rec.Code != http.StatusOK || repo.lastGapsThreshold != want {
+				t.Errorf("%q: status %d, threshold %v; want 200, %v", query, rec.Code, repo.lastGapsThreshold, want)
+			}
+		}
+	})
+
+	for name, query := range map[string]string{
+		"bad interval":   "interval=soon",
+		"zero interval":  "interval=0s",
+		"bad factor":     "factor=x",
+		"factor below 1": "factor=0.5",
+		"from after to":  "from=2025-03-02T00:00:00Z&to=2025-03-01T00:00:00Z",
+		"range too long": "from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z",
+	} {
+		t.Run("returns 400 for "+name, func(t *testing.T) {
+			ctrl := NewWeatherController(&mockRepo{}, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/gaps?"+query, nil)
+			req.SetPathValue("id", "st-1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleGaps(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+
+	t.Run("returns 500 when repository fails", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{gapsErr: errors.New("db down")}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/st-1/gaps", nil)
+		req.SetPathValue("id", "st-1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleGaps(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
+
+func Test_handleGapsPartial(t *testing.T) {
+	if err := views.LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	repo := &mockRepo{
+		stations: []types.Station{{ID: "1", Name: "Garden"}, {ID: "2", Name: "Attic"}},
+		gaps: map[string][]types.Gap{"1": {
+			{Start: start, End: start.Add(65 * time.Minute), Seconds: 3900},
+			{Start: start.Add(2 * time.Hour), End: start.Add(2*time.Hour + 4*time.Minute), Seconds: 240},
+		}},
+	}
+	ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+	rec := httptest.NewRecorder()
+
+	ctrl.handleGapsPartial(rec, httptest.NewRequest(http.MethodGet, "/partials/gaps", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Garden", "Attic", "1h 9m", "1h 5m", "over 3m"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q; got %q", want, body)
+		}
+	}
+	if strings.Count(body, "gaps-row-warn") != 1 {
+		t.Errorf("body = %q; want only Garden highlighted", body)
+	}
+}
+
+func Test_formatGapDuration(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{45, "45s"},
+		{59.6, "1m"},
+		{720, "12m"},
+		{3900, "1h 5m"},
+		{90000, "25h 0m"},
+	}
+	for _, tt := range tests {
+		if got := formatGapDuration(tt.seconds); got != tt.want {
+			t.Errorf("formatGapDuration(%v) = %q; want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+)
+
+type setRetentionRequest struct {
+	// Days of raw readings to keep; 0 keeps them forever and null reverts to
+	// the server default.
+	Days *int `json:"days"`
+}
+
+// handleSetRetention sets or (with days null) clears the raw readings
+// retention override of station {id}.
+func (c *weatherControllerImpl) handleSetRetention(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req setRetentionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if d := req.Days; d != nil && *d != 0 && (*d < types.MinRetentionDays || *d > types.MaxRetentionDays) {
+		utils.WriteError(w, http.StatusBadRequest,
+			fmt.Sprintf("'days' must be null, 0 or between %d and %d", types.MinRetentionDays, types.MaxRetentionDays))
+		return
+	}
+
+	err := c.repository.SetStationRetention(r.Context(), id, req.Days)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if errors.Is(err, repository.ErrQuotaExceeded) {
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set retention failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to update station")
+		return
+	}
+	station, err := c.repository.GetStation(r.Context(), id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set retention: reload station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, station)
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleSetRetention(t *testing.T) {
+	tests := []struct {
+		name string
+		repo *mockRepo
+		body string
+		want int
+	}{
+		{"sets retention", &mockRepo{station: types.Station{ID: "1"}}, `{"days": 90}`, http.StatusOK},
+		{"keeps forever", &mockRepo{station: types.Station{ID: "1"}}, `{"days": 0}`, http.StatusOK},
+		{"clears override", &mockRepo{station: types.Station{ID: "1"}}, `{"days": null}`, http.StatusOK},
+		{"invalid json", &mockRepo{}, `{`, http.StatusBadRequest},
+		{"below minimum", &mockRepo{}, `{"days": 7}`, http.StatusBadRequest},
+		{"negative", &mockRepo{}, `{"days": -1}`, http.StatusBadRequest},
+		{"above maximum", &mockRepo{}, `{"days": 36501}`, http.StatusBadRequest},
+		{"unknown station", &mockRepo{retentionErr: repository.ErrStationNotFound}, `{"days": 30}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			NewWeatherController(tt.repo, nil, nil, nil).RegisterRoutes(mux)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/stations/1/retention", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("passes days to repository", func(t *testing.T) {
+		repo := &mockRepo{}
+		mux := http.NewServeMux()
+		NewWeatherController(repo, nil, nil, nil).RegisterRoutes(mux)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/stations/1/retention", strings.NewReader(`{"days": 90}`)))
+
+		if repo.retention == nil || *repo.retention != 90 {
+			t.Errorf("retention = %v; want 90", repo.retention)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+// stationSnapshotsSpan is how far back the station page shows snapshots.
+const stationSnapshotsSpan = 24 * time.Hour
+
+// createSnapshotRequest is the body of POST /api/v1/stations/{id}/snapshots.
+// End defaults to Start, marking a single point in time.
+type createSnapshotRequest struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end"`
+	URL   string     `json:"url"`
+}
+
+func (c *weatherControllerImpl) handleSnapshots(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	from, to, page, err := parseReadingsQuery(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snapshots, err := c.repository.GetSnapshots(r.Context(), id, from, to)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WritePage(w, r, utils.Slice(snapshots, page), len(snapshots), page)
+}
+
+func (c *weatherControllerImpl) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		utils.WriteError(w, http.StatusBadRequest, "missing station id")
+		return
+	}
+
+	var req createSnapshotRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if err := types.ValidateSnapshotURL(req.URL); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "'url' "+err.Error())
+		return
+	}
+	if req.Start.IsZero() {
+		utils.WriteError(w, http.StatusBadRequest, "'start' is required")
+		return
+	}
+	end := req.Start
+	if req.End != nil {
+		end = *req.End
+	}
+	if end.Before(req.Start) {
+		utils.WriteError(w, http.StatusBadRequest, "'end' must be >= 'start'")
+		return
+	}
+
+	snapshot, err := c.repository.CreateSnapshot(r.Context(), id, req.Start, end, req.URL)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("create snapshot failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to create snapshot")
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, snapshot)
+}
+
+func (c *weatherControllerImpl) handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, snapshotID := r.PathValue("id"), r.PathValue("snapshotId")
+	err := c.repository.DeleteSnapshot(r.Context(), id, snapshotID)
+	if errors.Is(err, repository.ErrSnapshotNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("delete snapshot failed", "station_id", id, "snapshot_id", snapshotID, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to delete snapshot")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// snapshotMarkers formats snapshots for display, labeled like annotations.
+func snapshotMarkers(snapshots []types.Snapshot) []views.SnapshotMarker {
+	markers := make([]views.SnapshotMarker, 0, len(snapshots))
+	for _, s := range snapshots {
+		markers = append(markers, views.SnapshotMarker{
+			Label: timeRangeLabel(s.Start, s.End),
+			Title: s.Start.Format(time.RFC3339) + "/" + s.End.Format(time.RFC3339),
+			URL:   s.URL,
+		})
+	}
+	return markers
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleCreateSnapshot(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		repoErr    error
+		wantStatus int
+	}{
+		{"range", `{"start":"2025-01-01T10:00:00Z","end":"2025-01-01T10:10:00Z","url":" https://cam.example/a.jpg "}`, nil, http.StatusCreated},
+		{"point", `{"start":"2025-01-01T10:00:00Z","url":"https://cam.example/a.jpg"}`, nil, http.StatusCreated},
+		{"missing url", `{"start":"2025-01-01T10:00:00Z"}`, nil, http.StatusBadRequest},
+		{"relative url", `{"start":"2025-01-01T10:00:00Z","url":"/a.jpg"}`, nil, http.StatusBadRequest},
+		{"other scheme", `{"start":"2025-01-01T10:00:00Z","url":"ftp://cam.example/a.jpg"}`, nil, http.StatusBadRequest},
+		{"missing start", `{"url":"https://cam.example/a.jpg"}`, nil, http.StatusBadRequest},
+		{"end before start", `{"start":"2025-01-01T10:00:00Z","end":"2025-01-01T09:00:00Z","url":"https://cam.example/a.jpg"}`, nil, http.StatusBadRequest},
+		{"unknown station", `{"start":"2025-01-01T10:00:00Z","url":"https://cam.example/a.jpg"}`, repository.ErrStationNotFound, http.StatusNotFound},
+		{"repository error", `{"start":"2025-01-01T10:00:00Z","url":"https://cam.example/a.jpg"}`, errors.New("db error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{snapshotErr: tt.repoErr}
+			ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/stations/1/snapshots", strings.NewReader(tt.body))
+			req.SetPathValue("id", "1")
+			rec := httptest.NewRecorder()
+
+			ctrl.handleCreateSnapshot(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d; want %d (body %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusCreated {
+				return
+			}
+			if s := repo.lastSnapshot; s.URL != "https://cam.example/a.jpg" || s.End.Before(s.Start) {
+				t.Errorf("created snapshot = %+v; want trimmed URL and end >= start", s)
+			}
+		})
+	}
+}
+
+func Test_handleSnapshots(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo := &mockRepo{snapshots: []types.Snapshot{
+		{ID: "1", StationID: "1", Start: start, End: start, URL: "https://cam.example/a.jpg"},
+		{ID: "2", StationID: "1", Start: start, End: start, URL: "https://cam.example/b.jpg"},
+	}}
+	ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+	mux := http.NewServeMux()
+	ctrl.RegisterRoutes(mux)
+
+	t.Run("lists a page", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/snapshots?limit=1", nil))
+		var page struct {
+			Items []types.Snapshot `json:"items"`
+			Total int              `json:"total"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if page.Total != 2 || len(page.Items) != 1 || page.Items[0].ID != "1" {
+			t.Errorf("page = %+v; want total 2 with first item", page)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/stations/1/snapshots/1", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNoContent)
+		}
+		repo.snapshotErr = repository.ErrSnapshotNotFound
+		defer func() { repo.snapshotErr = nil }()
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/stations/1/snapshots/9", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
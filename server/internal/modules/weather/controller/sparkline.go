@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+)
+
+// loadSparkline charts the metric's bucket averages over the repository's
+// series window ending with the bucket holding now. The range is bucket
+// aligned so the series cache serves it without a query. It returns nil with
+// fewer than two buckets to draw.
+func (c *weatherControllerImpl) loadSparkline(ctx context.Context, stationID string, metric string, now time.Time) (*views.Sparkline, error) {
+	to := now.UTC().Truncate(repository.SeriesBucket).Add(repository.SeriesBucket)
+	from := to.Add(-repository.SeriesWindow)
+	buckets, err := c.repository.GetAggregate(ctx, stationID, from, to, repository.SeriesBucket)
+	if err != nil {
+		return nil, err
+	}
+	return sparkline(buckets, metric, from, to), nil
+}
+
+// sparkline places the metric's averages in buckets along [from, to) and
+// scales them between their minimum and maximum.
+func sparkline(buckets []types.AggregateBucket, metric string, from, to time.Time) *views.Sparkline {
+	info, ok := types.LookupMetric(metric)
+	if !ok {
+		return nil
+	}
+	type point struct {
+		at  time.Time
+		avg float64
+	}
+	var points []point
+	for _, b := range buckets {
+		if s := b.Get(metric); s.Avg != nil {
+			points = append(points, point{b.Start, *s.Avg})
+		}
+	}
+	if len(points) < 2 {
+		return nil
+	}
+	lo, hi := points[0].avg, points[0].avg
+	for _, p := range points[1:] {
+		lo, hi = min(lo, p.avg), max(hi, p.avg)
+	}
+
+	span := to.Sub(from)
+	var sb strings.Builder
+	for i, p := range points {
+		// Each average sits in the middle of its bucket.
+		x := float64(p.at.Sub(from)+repository.SeriesBucket/2) / float64(span) * views.SparklineWidth
+		y := float64(views.SparklineHeight) / 2
+		if hi > lo {
+			// One unit of margin keeps the line inside the box.
+			y = 1 + (hi-p.avg)/(hi-lo)*(views.SparklineHeight-2)
+		}
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%.1f,%.1f", x, y)
+	}
+	return &views.Sparkline{
+		Label:    info.Label + ", last 24 hours",
+		Points:   sb.String(),
+		MinLabel: fmt.Sprintf(info.Format, lo),
+		MaxLabel: fmt.Sprintf(info.Format, hi),
+	}
+}
+
+// cardSparkline charts the first of a card's metrics. The chart is an extra,
+// so a failed query is logged and the card shown without it.
+func (c *weatherControllerImpl) cardSparkline(ctx context.Context, stationID string, cardMetrics []string, now time.Time) *views.Sparkline {
+	metric := types.Metrics[0].Name
+	if len(cardMetrics) > 0 {
+		metric = cardMetrics[0]
+	}
+	sl, err := c.loadSparkline(ctx, stationID, metric, now)
+	if err != nil {
+		logging.FromContext(ctx).Warn("dashboard: get sparkline failed", "station_id", stationID, "error", err)
+		return nil
+	}
+	return sl
+}
@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_sparkline(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	avg := func(v float64) *float64 { return &v }
+	buckets := []types.AggregateBucket{
+		{Start: from, Temperature: types.MetricSummary{Count: 1, Avg: avg(10)}},
+		{Start: from.Add(12 * time.Hour), Temperature: types.MetricSummary{Count: 1, Avg: avg(20)}},
+		{Start: from.Add(23*time.Hour + 30*time.Minute), Humidity: types.MetricSummary{Count: 1, Avg: avg(50)}},
+	}
+
+	got := sparkline(buckets, types.MetricTemperature, from, to)
+	if got == nil {
+		t.Fatal("sparkline = nil; want a chart of 2 points")
+	}
+	if got.Points != "1.0,29.0 51.0,1.0" || got.MinLabel != "10.0°C" || got.MaxLabel != "20.0°C" {
+		t.Errorf("sparkline = %+v; want points at bucket middles scaled 10–20 °C", got)
+	}
+	if got := sparkline(buckets, types.MetricHumidity, from, to); got != nil {
+		t.Errorf("humidity sparkline = %+v; want nil with a single bucket", got)
+	}
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/photos"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+	"cloudpico-server/internal/utils"
+)
+
+// handleStationPage renders the detail page of station {id}: its latest
+// reading, data availability over the uptimeWindows and recent snapshots. Clients preferring
+// JSON (see wantsJSON) get the same data as a types.StationDetail.
+func (c *weatherControllerImpl) handleStationPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	asJSON := wantsJSON(r)
+	id := r.PathValue("id")
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		if asJSON {
+			utils.WriteError(w, http.StatusNotFound, "station not found")
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("station page: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	latest, err := c.repository.GetLatestReadings(r.Context(), station.ID, 1)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("station page: get latest reading failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load reading")
+		return
+	}
+	now := time.Now().UTC()
+	uptime, err := c.uptime(r.Context(), station.ID, []types.Station{station}, now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("station page: get uptime failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load uptime")
+		return
+	}
+
+	if asJSON {
+		station.Uptime = uptime[station.ID]
+		detail := types.StationDetail{Station: station}
+		if len(latest) != 0 {
+			detail.Latest = &latest[0]
+		}
+		utils.WriteJSON(w, http.StatusOK, detail)
+		return
+	}
+	snapshots, err := c.repository.GetSnapshots(r.Context(), station.ID, now.Add(-stationSnapshotsSpan), now)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("station page: get snapshots failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load snapshots")
+		return
+	}
+	data := views.StationData{
+		Station:        station,
+		Snapshots:      snapshotMarkers(snapshots),
+		PhotoURL:       photos.URL(station.PhotoPath),
+		ThresholdLabel: formatGapDuration(defaultGapFactor * gapInterval(station).Seconds()),
+	}
+	if station.Latitude != nil && station.Longitude != nil {
+		data.Location = fmt.Sprintf("%.5f, %.5f", *station.Latitude, *station.Longitude)
+	}
+	if len(latest) != 0 {
+		data.Reading = &latest[0]
+	}
+	for _, win := range uptimeWindows {
+		row := views.UptimeRow{Label: win.label}
+		if u := uptime[station.ID]; u != nil {
+			if pct := *win.field(u); pct != nil {
+				row.Percent = fmt.Sprintf("%.1f%%", *pct)
+				row.Warn = *pct < uptimeTarget
+			}
+		}
+		data.Uptime = append(data.Uptime, row)
+	}
+	data.Downsampling = downsamplingRows(station)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := views.RenderStation(w, &data); err != nil {
+		logging.FromContext(r.Context()).Error("station template render failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render page")
+		return
+	}
+}
+
+// downsamplingRows describes the station's effective downsampling policy, or
+// returns nil when the repository did not fill it in.
+func downsamplingRows(s types.Station) []views.DownsamplingRow {
+	d := s.Downsampling
+	if d == nil {
+		return nil
+	}
+	return []views.DownsamplingRow{
+		{Label: "Raw readings", Keep: keepLabel(d.RawDays), Override: s.RetentionDays != nil},
+		{Label: "Hourly rollups", Keep: keepLabel(d.HourlyDays), Override: s.HourlyRetentionDays != nil},
+		{Label: "Daily rollups", Keep: keepLabel(d.DailyDays), Override: s.DailyRetentionDays != nil},
+	}
+}
+
+func keepLabel(days int) string {
+	if days == 0 {
+		return "forever"
+	}
+	return fmt.Sprintf("%d days", days)
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/views"
+)
+
+func Test_handleStationPage(t *testing.T) {
+	if err := views.LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates: %v", err)
+	}
+	lat, lon := 52.2297, 21.0122
+	station := types.Station{ID: "1", Name: "Garden", Latitude: &lat, Longitude: &lon, GatewayVersion: "1.2.0"}
+
+	t.Run("renders reading and uptime", func(t *testing.T) {
+		repo := &mockRepo{
+			station: station,
+			latest:  []types.Reading{{StationID: "1", Time: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC), Value: 21.4}},
+			uptime:  map[string]float64{"1": 97.3},
+		}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/1", nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"Garden", "21.4°C", "52.22970, 21.01220", "Gateway 1.2.0", "Last 30 days", "97.3%", "longer than 3m"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("body missing %q; got %q", want, body)
+			}
+		}
+		if strings.Count(body, "uptime-row-warn") != len(uptimeWindows) {
+			t.Errorf("body = %q; want every window below target highlighted", body)
+		}
+	})
+
+	t.Run("renders downsampling policy", func(t *testing.T) {
+		days := 30
+		s := station
+		s.RetentionDays = &days
+		s.Downsampling = &types.Downsampling{RawDays: 30, HourlyDays: 365}
+		ctrl := NewWeatherController(&mockRepo{station: s}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/1", nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		body := rec.Body.String()
+		for _, want := range []string{"Downsampling", "30 days", "365 days", "forever"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("body missing %q; got %q", want, body)
+			}
+		}
+		if strings.Count(body, "downsampling-override") != 1 {
+			t.Errorf("body = %q; want only raw readings marked as overridden", body)
+		}
+	})
+
+	t.Run("station younger than the windows", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{station: station}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/1", nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		if body := rec.Body.String(); strings.Count(body, "n/a") != len(uptimeWindows) || !strings.Contains(body, "No recent reading") {
+			t.Errorf("body = %q; want n/a for every window and no reading", body)
+		}
+	})
+
+	t.Run("returns 404 for unknown station", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{stationErr: repository.ErrStationNotFound}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/9", nil)
+		req.SetPathValue("id", "9")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("returns 500 when uptime fails", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{station: station, uptimeErr: errors.New("db error")}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/1", nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("serves JSON to API clients", func(t *testing.T) {
+		repo := &mockRepo{
+			station: station,
+			latest:  []types.Reading{{StationID: "1", Time: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC), Value: 21.4}},
+			uptime:  map[string]float64{"1": 97.3},
+		}
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/1", nil)
+		req.Header.Set("Accept", "application/json")
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+			t.Errorf("Content-Type = %q; want application/json", ct)
+		}
+		if vary := rec.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("Vary = %q; want Accept", vary)
+		}
+		var got types.StationDetail
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if got.ID != "1" || got.Name != "Garden" || got.Latest == nil || got.Latest.Value != 21.4 {
+			t.Errorf("detail = %+v; want station 1 with its latest reading", got)
+		}
+		if got.Uptime == nil || got.Uptime.Month == nil || *got.Uptime.Month != 97.3 {
+			t.Errorf("uptime = %+v; want 97.3%% over 30 days", got.Uptime)
+		}
+	})
+
+	t.Run("returns JSON 404 to API clients", func(t *testing.T) {
+		ctrl := NewWeatherController(&mockRepo{stationErr: repository.ErrStationNotFound}, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/stations/9", nil)
+		req.Header.Set("Accept", "application/json")
+		req.SetPathValue("id", "9")
+		rec := httptest.NewRecorder()
+
+		ctrl.handleStationPage(rec, req)
+
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"error"`) {
+			t.Errorf("status = %d, body %q; want 404 with a JSON error", rec.Code, rec.Body.String())
+		}
+	})
+}
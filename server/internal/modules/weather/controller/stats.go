@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/utils"
+)
+
+// handleStats returns count, min/max/avg and standard deviation of each metric
+// of station {id} over [from, to] (default: the last 24 hours).
+func (c *weatherControllerImpl) handleStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	from, to, err := parseRangeQuery(r, time.Now().UTC())
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	station, err := c.repository.GetStation(r.Context(), id)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("stats: get station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+
+	stats, err := c.repository.GetStats(r.Context(), station.ID, from, to)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("stats: load failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load stats")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, stats)
+}
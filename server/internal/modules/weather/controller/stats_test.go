@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func Test_handleStats(t *testing.T) {
+	serve := func(repo *mockRepo, query string) *httptest.ResponseRecorder {
+		ctrl := NewWeatherController(repo, nil, nil, nil).(*weatherControllerImpl)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stations/1/stats"+query, nil)
+		req.SetPathValue("id", "1")
+		rec := httptest.NewRecorder()
+		ctrl.handleStats(rec, req)
+		return rec
+	}
+
+	t.Run("returns stats over the window", func(t *testing.T) {
+		sd := 1.5
+		repo := &mockRepo{
+			station: types.Station{ID: "1"},
+			stats:   types.Stats{Count: 4, Temperature: types.MetricStats{Count: 4, StdDev: &sd}},
+		}
+		rec := serve(repo, "?from=2025-02-01T00:00:00Z&to=2025-02-08T00:00:00Z")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d, body %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var got types.Stats
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got.StationID != "1" || got.Count != 4 || got.Temperature.StdDev == nil || *got.Temperature.StdDev != 1.5 {
+			t.Errorf("stats = %+v; want station 1 with 4 readings and stddev 1.5", got)
+		}
+		if want := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC); !got.From.Equal(want) {
+			t.Errorf("from = %v; want %v", got.From, want)
+		}
+	})
+
+	t.Run("defaults to the last 24 hours", func(t *testing.T) {
+		repo := &mockRepo{station: types.Station{ID: "1"}}
+		rec := serve(repo, "?to=2025-02-01T12:00:00Z")
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+		}
+		if span := repo.stats.To.Sub(repo.stats.From); span != 24*time.Hour {
+			t.Errorf("window = %v; want 24h", span)
+		}
+	})
+
+	t.Run("returns 400 when from is after to", func(t *testing.T) {
+		rec := serve(&mockRepo{}, "?from=2025-02-02T00:00:00Z&to=2025-02-01T00:00:00Z")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("returns 404 for unknown station", func(t *testing.T) {
+		rec := serve(&mockRepo{stationErr: repository.ErrStationNotFound}, "")
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("returns 500 when repository fails", func(t *testing.T) {
+		rec := serve(&mockRepo{station: types.Station{ID: "1"}, statsErr: errors.New("db error")}, "")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d; want %d", rec.Code, http.StatusInternalServerError)
+		}
+	})
+}
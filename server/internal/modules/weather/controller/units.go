@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// Unit systems of the units query parameter. Readings are stored in metric
+// units (°C, %, hPa); imperial converts temperature to °F and pressure to
+// inHg, leaving humidity as is.
+const (
+	unitsMetric   = "metric"
+	unitsImperial = "imperial"
+)
+
+// unitsHeader echoes the unit system on responses without a JSON envelope.
+const unitsHeader = "X-Units"
+
+// hPaToInHg converts hectopascals to inches of mercury.
+const hPaToInHg = 0.0295299830714
+
+// parseUnitsQuery parses units. Default: metric.
+func parseUnitsQuery(r *http.Request) (string, error) {
+	switch units := r.URL.Query().Get("units"); units {
+	case "", unitsMetric:
+		return unitsMetric, nil
+	case unitsImperial:
+		return units, nil
+	default:
+		return "", errors.New("invalid 'units' (allowed: metric, imperial)")
+	}
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
+
+// convertReadings returns readings in units. Readings carry 0 for metrics the
+// station did not report, so a missing temperature reads 32 °F in imperial.
+func convertReadings(readings []types.Reading, units string) []types.Reading {
+	if units != unitsImperial {
+		return readings
+	}
+	out := make([]types.Reading, len(readings))
+	for i, rd := range readings {
+		rd.Value = celsiusToFahrenheit(rd.Value)
+		rd.PressureHpa *= hPaToInHg
+		out[i] = rd
+	}
+	return out
+}
+
+// convertAggregate converts the temperature and pressure summaries of
+// buckets to units in place.
+func convertAggregate(buckets []types.AggregateBucket, units string) {
+	if units != unitsImperial {
+		return
+	}
+	for i := range buckets {
+		convertSummary(&buckets[i].Temperature, celsiusToFahrenheit)
+		convertSummary(&buckets[i].Pressure, func(hpa float64) float64 { return hpa * hPaToInHg })
+	}
+}
+
+func convertSummary(s *types.MetricSummary, convert func(float64) float64) {
+	for _, v := range []**float64{&s.Min, &s.Avg, &s.Max} {
+		if *v != nil {
+			converted := convert(**v)
+			*v = &converted
+		}
+	}
+}
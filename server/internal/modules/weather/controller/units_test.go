@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_parseUnitsQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "", want: unitsMetric},
+		{query: "units=metric", want: unitsMetric},
+		{query: "units=imperial", want: unitsImperial},
+		{query: "units=Imperial", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/readings?"+tt.query, nil)
+			got, err := parseUnitsQuery(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUnitsQuery() err = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseUnitsQuery() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
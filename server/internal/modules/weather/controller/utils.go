@@ -2,10 +2,16 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
 )
 
 const (
@@ -30,60 +36,186 @@ var historyRanges = map[string]historyRange{
 	"7d":  {Duration: 7 * 24 * time.Hour, Label: "Last 7 days"},
 }
 
-func parseReadingsQuery(r *http.Request) (from time.Time, to time.Time, limit int, err error) {
+// Readings page size: default and maximum limit.
+const (
+	defaultReadingsLimit = 100
+	maxReadingsLimit     = 1000
+)
+
+func parseReadingsQuery(r *http.Request) (from time.Time, to time.Time, page utils.Pagination, err error) {
 	q := r.URL.Query()
 
 	if s := q.Get("from"); s != "" {
 		from, err = time.Parse(time.RFC3339, s)
 		if err != nil {
-			return time.Time{}, time.Time{}, 0, errors.New("invalid 'from' (expected RFC3339)")
+			return time.Time{}, time.Time{}, utils.Pagination{}, errors.New("invalid 'from' (expected RFC3339)")
 		}
 	}
 	if s := q.Get("to"); s != "" {
 		to, err = time.Parse(time.RFC3339, s)
 		if err != nil {
-			return time.Time{}, time.Time{}, 0, errors.New("invalid 'to' (expected RFC3339)")
+			return time.Time{}, time.Time{}, utils.Pagination{}, errors.New("invalid 'to' (expected RFC3339)")
 		}
 	}
 	if !from.IsZero() && !to.IsZero() && from.After(to) {
-		return time.Time{}, time.Time{}, 0, errors.New("'from' must be <= 'to'")
+		return time.Time{}, time.Time{}, utils.Pagination{}, errors.New("'from' must be <= 'to'")
+	}
+
+	page, err = utils.ParsePagination(r, defaultReadingsLimit, maxReadingsLimit)
+	if err != nil {
+		return time.Time{}, time.Time{}, utils.Pagination{}, err
 	}
+	return from, to, page, nil
+}
 
+func parseLatestQuery(r *http.Request) (limit int, err error) {
+	q := r.URL.Query()
 	limit = 100
 	if s := q.Get("limit"); s != "" {
 		n, convErr := strconv.Atoi(s)
 		if convErr != nil {
-			return time.Time{}, time.Time{}, 0, errors.New("invalid 'limit' (expected integer)")
+			return 0, errors.New("invalid 'limit' (expected integer)")
 		}
 		if n <= 0 {
-			return time.Time{}, time.Time{}, 0, errors.New("'limit' must be > 0")
+			return 0, errors.New("'limit' must be > 0")
 		}
 		if n > 1000 {
-			return time.Time{}, time.Time{}, 0, errors.New("'limit' must be <= 1000")
+			return 0, errors.New("'limit' must be <= 1000")
 		}
 		limit = n
 	}
+	return limit, nil
+}
+
+const (
+	defaultHistogramBins = 20
+	maxHistogramBins     = 100
+	defaultHistogramSpan = 24 * time.Hour
+)
 
-	return from, to, limit, nil
+var histogramMetrics = map[string]bool{
+	types.MetricTemperature: true,
+	types.MetricHumidity:    true,
+	types.MetricPressure:    true,
 }
 
-func parseLatestQuery(r *http.Request) (limit int, err error) {
+// parseMetricRangeQuery parses metric, from, and to for per-metric endpoints.
+// Defaults: metric=temperature, to=now, from=to-24h.
+func parseMetricRangeQuery(r *http.Request, now time.Time) (metric string, from time.Time, to time.Time, err error) {
+	metric = r.URL.Query().Get("metric")
+	if metric == "" {
+		metric = types.MetricTemperature
+	}
+	if !histogramMetrics[metric] {
+		return "", time.Time{}, time.Time{}, errors.New("invalid 'metric' (expected temperature, humidity, or pressure)")
+	}
+	from, to, err = parseRangeQuery(r, now)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, err
+	}
+	return metric, from, to, nil
+}
+
+// parseRangeQuery parses from and to. Defaults: to=now, from=to-24h.
+func parseRangeQuery(r *http.Request, now time.Time) (from time.Time, to time.Time, err error) {
 	q := r.URL.Query()
-	limit = 100
-	if s := q.Get("limit"); s != "" {
+
+	to = now
+	if s := q.Get("to"); s != "" {
+		to, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'to' (expected RFC3339)")
+		}
+	}
+	from = to.Add(-defaultHistogramSpan)
+	if s := q.Get("from"); s != "" {
+		from, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'from' (expected RFC3339)")
+		}
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("'from' must be <= 'to'")
+	}
+	return from, to, nil
+}
+
+// parseHistogramQuery parses metric, from, to, and bins for the histogram endpoint.
+// Defaults: metric=temperature, to=now, from=to-24h, bins=20.
+func parseHistogramQuery(r *http.Request, now time.Time) (metric string, from time.Time, to time.Time, bins int, err error) {
+	metric, from, to, err = parseMetricRangeQuery(r, now)
+	if err != nil {
+		return "", time.Time{}, time.Time{}, 0, err
+	}
+
+	bins = defaultHistogramBins
+	if s := r.URL.Query().Get("bins"); s != "" {
 		n, convErr := strconv.Atoi(s)
 		if convErr != nil {
-			return 0, errors.New("invalid 'limit' (expected integer)")
+			return "", time.Time{}, time.Time{}, 0, errors.New("invalid 'bins' (expected integer)")
 		}
 		if n <= 0 {
-			return 0, errors.New("'limit' must be > 0")
+			return "", time.Time{}, time.Time{}, 0, errors.New("'bins' must be > 0")
 		}
-		if n > 1000 {
-			return 0, errors.New("'limit' must be <= 1000")
+		if n > maxHistogramBins {
+			return "", time.Time{}, time.Time{}, 0, fmt.Errorf("'bins' must be <= %d", maxHistogramBins)
 		}
-		limit = n
+		bins = n
 	}
-	return limit, nil
+
+	return metric, from, to, bins, nil
+}
+
+// aggregateBuckets are the bucket widths of the aggregate endpoint, with the
+// range covered when 'from' is omitted.
+var aggregateBuckets = map[string]struct {
+	Width       time.Duration
+	DefaultSpan time.Duration
+}{
+	types.BucketHour: {Width: time.Hour, DefaultSpan: 24 * time.Hour},
+	types.BucketDay:  {Width: 24 * time.Hour, DefaultSpan: 30 * 24 * time.Hour},
+}
+
+// maxAggregateBuckets bounds the buckets one aggregate request may span.
+const maxAggregateBuckets = 5000
+
+// parseAggregateQuery parses bucket, from, and to for the aggregate endpoint.
+// Defaults: bucket=1h, to=now, from=to-24h (to-30d for 1d buckets). from is
+// rounded down to the start of its bucket.
+func parseAggregateQuery(r *http.Request, now time.Time) (bucket string, width time.Duration, from time.Time, to time.Time, err error) {
+	q := r.URL.Query()
+
+	bucket = q.Get("bucket")
+	if bucket == "" {
+		bucket = types.BucketHour
+	}
+	info, ok := aggregateBuckets[bucket]
+	if !ok {
+		return "", 0, time.Time{}, time.Time{}, errors.New("invalid 'bucket' (expected 1h or 1d)")
+	}
+
+	to = now
+	if s := q.Get("to"); s != "" {
+		to, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return "", 0, time.Time{}, time.Time{}, errors.New("invalid 'to' (expected RFC3339)")
+		}
+	}
+	from = to.Add(-info.DefaultSpan)
+	if s := q.Get("from"); s != "" {
+		from, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return "", 0, time.Time{}, time.Time{}, errors.New("invalid 'from' (expected RFC3339)")
+		}
+	}
+	if from.After(to) {
+		return "", 0, time.Time{}, time.Time{}, errors.New("'from' must be <= 'to'")
+	}
+	from = from.UTC().Truncate(info.Width)
+	if to.Sub(from) > maxAggregateBuckets*info.Width {
+		return "", 0, time.Time{}, time.Time{}, fmt.Errorf("range spans more than %d buckets; use a larger bucket or a shorter range", maxAggregateBuckets)
+	}
+	return bucket, info.Width, from, to, nil
 }
 
 func resolveHistoryRange(key string) (historyRange, bool) {
@@ -110,6 +242,98 @@ func parseHistoryPage(r *http.Request) int {
 	return n
 }
 
+// resolveHistoryPosition returns the page and cursor query params when either
+// is present. Otherwise it keeps the cookie's position if station and range are
+// unchanged, and starts over at the newest page if not.
+func resolveHistoryPosition(r *http.Request, state weatherState, stationID, rangeKey string) (page int, cursor string) {
+	q := r.URL.Query()
+	if q.Get("page") != "" || q.Get("cursor") != "" {
+		return parseHistoryPage(r), q.Get("cursor")
+	}
+	if stationID == state.StationID && rangeKey == state.RangeKey && state.Page >= 1 {
+		return state.Page, state.Cursor
+	}
+	return 1, ""
+}
+
+// History cursors, as carried in URLs and the state cookie: "" is the newest
+// page, "older:<ts>" and "newer:<ts>" continue from the reading at ts, and
+// "oldest" is the oldest page.
+const (
+	historyCursorOlder  = "older:"
+	historyCursorNewer  = "newer:"
+	historyCursorOldest = "oldest"
+)
+
+// formatHistoryCursor returns the history cursor string of c.
+func formatHistoryCursor(c repository.ReadingsCursor) string {
+	switch {
+	case c.Time.IsZero() && c.Newer:
+		return historyCursorOldest
+	case c.Time.IsZero():
+		return ""
+	case c.Newer:
+		return historyCursorNewer + c.Time.UTC().Format(time.RFC3339Nano)
+	default:
+		return historyCursorOlder + c.Time.UTC().Format(time.RFC3339Nano)
+	}
+}
+
+// parseHistoryCursor parses a history cursor string. Invalid cursors are the
+// newest page.
+func parseHistoryCursor(s string) repository.ReadingsCursor {
+	if s == historyCursorOldest {
+		return repository.ReadingsCursor{Newer: true}
+	}
+	var c repository.ReadingsCursor
+	ts, ok := strings.CutPrefix(s, historyCursorOlder)
+	if !ok {
+		if ts, ok = strings.CutPrefix(s, historyCursorNewer); !ok {
+			return repository.ReadingsCursor{}
+		}
+		c.Newer = true
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return repository.ReadingsCursor{}
+	}
+	c.Time = t
+	return c
+}
+
+// historyURL returns the shareable /history URL for a view state.
+func historyURL(stationID, rangeKey string, page int, cursor string) string {
+	val := url.Values{}
+	val.Set("station_id", stationID)
+	val.Set("range", rangeKey)
+	val.Set("page", strconv.Itoa(page))
+	if cursor != "" {
+		val.Set("cursor", cursor)
+	}
+	return "/history?" + val.Encode()
+}
+
+// setHistoryURLHeader points the browser URL at target on htmx requests. It
+// pushes a history entry only when the view changed, so polling does not fill
+// the back stack, and replaces a bare /history so the first load is shareable.
+func setHistoryURLHeader(w http.ResponseWriter, r *http.Request, target string) {
+	if r.Header.Get("HX-Request") != "true" {
+		return
+	}
+	current, err := url.Parse(r.Header.Get("HX-Current-URL"))
+	if err != nil || current.Path != "/history" {
+		w.Header().Set("HX-Push-Url", target)
+		return
+	}
+	if current.RawQuery == "" {
+		w.Header().Set("HX-Replace-Url", target)
+		return
+	}
+	if "/history?"+current.Query().Encode() != target {
+		w.Header().Set("HX-Push-Url", target)
+	}
+}
+
 func zeroAsNullTime(t time.Time) any {
 	if t.IsZero() {
 		return nil
@@ -122,16 +346,19 @@ type weatherState struct {
 	StationID string
 	RangeKey  string
 	Page      int
+	Cursor    string // history cursor of the page, see parseHistoryCursor
 }
 
-// readWeatherStateCookie parses the weather_state cookie and returns station_id, range key, and page.
-// Returns zero values when the cookie is missing or invalid. Range and page are validated.
-func readWeatherStateCookie(r *http.Request) weatherState {
-	c, err := r.Cookie(weatherStateCookieName)
+// readWeatherStateCookie parses the weather_state cookie and returns station_id, range key, page,
+// and cursor.
+// Returns zero values when the cookie is missing, invalid, unsigned, or fails signature checks.
+// An unsigned cookie from before signing is replaced with a signed one on the next write.
+func readWeatherStateCookie(cookies *utils.CookieSigner, r *http.Request) weatherState {
+	value, err := cookies.ReadCookie(r, weatherStateCookieName)
 	if err != nil {
 		return weatherState{}
 	}
-	vals, err := url.ParseQuery(c.Value)
+	vals, err := url.ParseQuery(value)
 	if err != nil {
 		return weatherState{}
 	}
@@ -146,12 +373,13 @@ func readWeatherStateCookie(r *http.Request) weatherState {
 			page = n
 		}
 	}
-	return weatherState{StationID: stationID, RangeKey: rangeKey, Page: page}
+	cursor := formatHistoryCursor(parseHistoryCursor(vals.Get("cursor")))
+	return weatherState{StationID: stationID, RangeKey: rangeKey, Page: page, Cursor: cursor}
 }
 
 // writeWeatherStateCookie sets the weather_state cookie with the given state.
 // rangeKey must be a valid history range key (use defaultHistoryRangeKey if unsure).
-func writeWeatherStateCookie(w http.ResponseWriter, stationID, rangeKey string, page int) {
+func writeWeatherStateCookie(cookies *utils.CookieSigner, w http.ResponseWriter, stationID, rangeKey string, page int, cursor string) {
 	if _, ok := historyRanges[rangeKey]; !ok {
 		rangeKey = defaultHistoryRangeKey
 	}
@@ -162,7 +390,10 @@ func writeWeatherStateCookie(w http.ResponseWriter, stationID, rangeKey string,
 	val.Set("station_id", stationID)
 	val.Set("range", rangeKey)
 	val.Set("page", strconv.Itoa(page))
-	http.SetCookie(w, &http.Cookie{
+	if cursor != "" {
+		val.Set("cursor", cursor)
+	}
+	cookies.SetCookie(w, &http.Cookie{
 		Name:     weatherStateCookieName,
 		Value:    val.Encode(),
 		Path:     "/",
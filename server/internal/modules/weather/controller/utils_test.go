@@ -7,26 +7,29 @@ import (
 	"strconv"
 	"testing"
 	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/utils"
 )
 
 func Test_parseReadingsQuery(t *testing.T) {
 	t.Run("no params returns defaults", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings", nil)
-		from, to, limit, err := parseReadingsQuery(req)
+		from, to, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
 		if !from.IsZero() || !to.IsZero() {
 			t.Errorf("from.IsZero()=%v to.IsZero()=%v; want both true", from.IsZero(), to.IsZero())
 		}
-		if limit != 100 {
-			t.Errorf("limit = %d; want 100", limit)
+		if page.Limit != 100 {
+			t.Errorf("limit = %d; want 100", page.Limit)
 		}
 	})
 
 	t.Run("valid from only", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings?from=2025-01-01T00:00:00Z", nil)
-		from, to, limit, err := parseReadingsQuery(req)
+		from, to, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
@@ -37,14 +40,14 @@ func Test_parseReadingsQuery(t *testing.T) {
 		if !to.IsZero() {
 			t.Errorf("to should be zero; got %v", to)
 		}
-		if limit != 100 {
-			t.Errorf("limit = %d; want 100", limit)
+		if page.Limit != 100 {
+			t.Errorf("limit = %d; want 100", page.Limit)
 		}
 	})
 
 	t.Run("valid to only", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings?to=2025-12-31T23:59:59Z", nil)
-		from, to, limit, err := parseReadingsQuery(req)
+		from, to, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
@@ -55,14 +58,14 @@ func Test_parseReadingsQuery(t *testing.T) {
 		if !to.Equal(wantTo) {
 			t.Errorf("to = %v; want %v", to, wantTo)
 		}
-		if limit != 100 {
-			t.Errorf("limit = %d; want 100", limit)
+		if page.Limit != 100 {
+			t.Errorf("limit = %d; want 100", page.Limit)
 		}
 	})
 
 	t.Run("valid from and to", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings?from=2025-01-01T00:00:00Z&to=2025-01-31T12:00:00Z", nil)
-		from, to, limit, err := parseReadingsQuery(req)
+		from, to, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
@@ -71,8 +74,8 @@ func Test_parseReadingsQuery(t *testing.T) {
 		if !from.Equal(wantFrom) || !to.Equal(wantTo) {
 			t.Errorf("from=%v to=%v; want from=%v to=%v", from, to, wantFrom, wantTo)
 		}
-		if limit != 100 {
-			t.Errorf("limit = %d; want 100", limit)
+		if page.Limit != 100 {
+			t.Errorf("limit = %d; want 100", page.Limit)
 		}
 	})
 
@@ -111,34 +114,34 @@ func Test_parseReadingsQuery(t *testing.T) {
 
 	t.Run("valid limit", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings?limit=50", nil)
-		_, _, limit, err := parseReadingsQuery(req)
+		_, _, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
-		if limit != 50 {
-			t.Errorf("limit = %d; want 50", limit)
+		if page.Limit != 50 {
+			t.Errorf("limit = %d; want 50", page.Limit)
 		}
 	})
 
 	t.Run("limit 1 allowed", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings?limit=1", nil)
-		_, _, limit, err := parseReadingsQuery(req)
+		_, _, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
-		if limit != 1 {
-			t.Errorf("limit = %d; want 1", limit)
+		if page.Limit != 1 {
+			t.Errorf("limit = %d; want 1", page.Limit)
 		}
 	})
 
 	t.Run("limit 1000 allowed", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/readings?limit=1000", nil)
-		_, _, limit, err := parseReadingsQuery(req)
+		_, _, page, err := parseReadingsQuery(req)
 		if err != nil {
 			t.Fatalf("parseReadingsQuery() err = %v; want nil", err)
 		}
-		if limit != 1000 {
-			t.Errorf("limit = %d; want 1000", limit)
+		if page.Limit != 1000 {
+			t.Errorf("limit = %d; want 1000", page.Limit)
 		}
 	})
 
@@ -410,7 +413,7 @@ func Test_parseHistoryPage(t *testing.T) {
 func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("no cookie returns zero state", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.StationID != "" || got.RangeKey != "" || got.Page != 0 {
 			t.Errorf("readWeatherStateCookie() = %+v; want zero weatherState", got)
 		}
@@ -419,7 +422,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("malformed cookie value returns zero state", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "not-valid-query%%"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.StationID != "" || got.RangeKey != "" || got.Page != 0 {
 			t.Errorf("readWeatherStateCookie(malformed) = %+v; want zero weatherState", got)
 		}
@@ -428,7 +431,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("valid cookie parses all fields", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=st1&range=7d&page=3"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.StationID != "st1" || got.RangeKey != "7d" || got.Page != 3 {
 			t.Errorf("readWeatherStateCookie() = %+v; want StationID=st1 RangeKey=7d Page=3", got)
 		}
@@ -437,7 +440,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("invalid range key in cookie yields empty RangeKey", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=st1&range=badkey&page=2"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.RangeKey != "" {
 			t.Errorf("readWeatherStateCookie(invalid range) RangeKey = %q; want \"\"", got.RangeKey)
 		}
@@ -449,7 +452,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("negative page in cookie yields page 1", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=x&range=24h&page=-1"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.Page != 1 {
 			t.Errorf("readWeatherStateCookie(negative page) Page = %d; want 1", got.Page)
 		}
@@ -458,7 +461,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("zero page in cookie yields page 1", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=x&range=24h&page=0"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.Page != 1 {
 			t.Errorf("readWeatherStateCookie(page=0) Page = %d; want 1", got.Page)
 		}
@@ -467,7 +470,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("non-integer page in cookie yields page 1", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=x&range=24h&page=abc"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.Page != 1 {
 			t.Errorf("readWeatherStateCookie(page=abc) Page = %d; want 1", got.Page)
 		}
@@ -476,7 +479,7 @@ func Test_readWeatherStateCookie(t *testing.T) {
 	t.Run("missing optional fields use defaults", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=only"})
-		got := readWeatherStateCookie(req)
+		got := readWeatherStateCookie(nil, req)
 		if got.StationID != "only" {
 			t.Errorf("StationID = %q; want \"only\"", got.StationID)
 		}
@@ -487,6 +490,41 @@ func Test_readWeatherStateCookie(t *testing.T) {
 			t.Errorf("Page = %d; want 1 (default)", got.Page)
 		}
 	})
+
+	t.Run("signed cookie round trip", func(t *testing.T) {
+		signer := utils.NewCookieSigner([]byte("0123456789abcdef0123456789abcdef"))
+		w := httptest.NewRecorder()
+		writeWeatherStateCookie(signer, w, "st1", "7d", 3, "older:2025-02-03T10:00:00Z")
+		c := w.Result().Cookies()[0]
+		if stationID, _, _ := parseCookieValue(c.Value); stationID != "" {
+			t.Errorf("cookie value %q should be signed, not a plain query string", c.Value)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(c)
+		got := readWeatherStateCookie(signer, req)
+		if got.StationID != "st1" || got.RangeKey != "7d" || got.Page != 3 || got.Cursor != "older:2025-02-03T10:00:00Z" {
+			t.Errorf("readWeatherStateCookie() = %+v; want StationID=st1 RangeKey=7d Page=3 with the cursor", got)
+		}
+	})
+
+	t.Run("tampered signed cookie is ignored", func(t *testing.T) {
+		signer := utils.NewCookieSigner([]byte("0123456789abcdef0123456789abcdef"))
+		other := utils.NewCookieSigner([]byte("ffffffffffffffffffffffffffffffff"))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: other.Sign(weatherStateCookieName, "station_id=st9&range=1h&page=2")})
+		if got := readWeatherStateCookie(signer, req); got != (weatherState{}) {
+			t.Errorf("readWeatherStateCookie(tampered) = %+v; want zero weatherState", got)
+		}
+	})
+
+	t.Run("legacy unsigned cookie is ignored", func(t *testing.T) {
+		signer := utils.NewCookieSigner([]byte("0123456789abcdef0123456789abcdef"))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: weatherStateCookieName, Value: "station_id=st1&range=6h&page=2"})
+		if got := readWeatherStateCookie(signer, req); got != (weatherState{}) {
+			t.Errorf("readWeatherStateCookie(legacy) = %+v; want zero weatherState", got)
+		}
+	})
 }
 
 func parseCookieValue(value string) (stationID, rangeKey string, page int) {
@@ -500,10 +538,94 @@ func parseCookieValue(value string) (stationID, rangeKey string, page int) {
 	return stationID, rangeKey, page
 }
 
+func Test_resolveHistoryPosition(t *testing.T) {
+	state := weatherState{StationID: "st1", RangeKey: "24h", Page: 3, Cursor: "older:2025-02-03T10:00:00Z"}
+	tests := []struct {
+		name       string
+		url        string
+		stationID  string
+		rangeKey   string
+		wantPage   int
+		wantCursor string
+	}{
+		{"page param wins", "/partials/history?page=5", "st2", "1h", 5, ""},
+		{"cursor param wins", "/partials/history?page=2&cursor=oldest", "st1", "24h", 2, "oldest"},
+		{"unchanged state keeps cookie position", "/partials/history", "st1", "24h", 3, state.Cursor},
+		{"station change resets position", "/partials/history", "st2", "24h", 1, ""},
+		{"range change resets position", "/partials/history", "st1", "7d", 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			page, cursor := resolveHistoryPosition(r, state, tt.stationID, tt.rangeKey)
+			if page != tt.wantPage || cursor != tt.wantCursor {
+				t.Errorf("resolveHistoryPosition() = %d, %q; want %d, %q", page, cursor, tt.wantPage, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func Test_parseHistoryCursor(t *testing.T) {
+	ts := time.Date(2025, 2, 3, 10, 0, 0, 500, time.UTC)
+	for _, c := range []repository.ReadingsCursor{
+		{},
+		{Newer: true},
+		{Time: ts},
+		{Time: ts, Newer: true},
+	} {
+		s := formatHistoryCursor(c)
+		if got := parseHistoryCursor(s); !got.Time.Equal(c.Time) || got.Newer != c.Newer {
+			t.Errorf("parseHistoryCursor(%q) = %+v; want %+v", s, got, c)
+		}
+	}
+	for _, s := range []string{"older:", "older:yesterday", "sideways:2025-02-03T10:00:00Z", "2025-02-03T10:00:00Z"} {
+		if got := parseHistoryCursor(s); got != (repository.ReadingsCursor{}) {
+			t.Errorf("parseHistoryCursor(%q) = %+v; want the newest page", s, got)
+		}
+	}
+}
+
+func Test_setHistoryURLHeader(t *testing.T) {
+	target := historyURL("st1", "24h", 2, "")
+	if want := "/history?page=2&range=24h&station_id=st1"; target != want {
+		t.Fatalf("historyURL() = %q; want %q", target, want)
+	}
+	tests := []struct {
+		name        string
+		htmx        bool
+		currentURL  string
+		wantPush    string
+		wantReplace string
+	}{
+		{"non-htmx request", false, "", "", ""},
+		{"view changed", true, "http://example.com/history?page=1&range=24h&station_id=st1", target, ""},
+		{"same view in different param order", true, "http://example.com/history?station_id=st1&range=24h&page=2", "", ""},
+		{"bare history page is replaced", true, "http://example.com/history", "", target},
+		{"different page entirely", true, "http://example.com/", target, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/partials/history", nil)
+			if tt.htmx {
+				r.Header.Set("HX-Request", "true")
+				r.Header.Set("HX-Current-URL", tt.currentURL)
+			}
+			w := httptest.NewRecorder()
+			setHistoryURLHeader(w, r, target)
+			if got := w.Header().Get("HX-Push-Url"); got != tt.wantPush {
+				t.Errorf("HX-Push-Url = %q; want %q", got, tt.wantPush)
+			}
+			if got := w.Header().Get("HX-Replace-Url"); got != tt.wantReplace {
+				t.Errorf("HX-Replace-Url = %q; want %q", got, tt.wantReplace)
+			}
+		})
+	}
+}
+
 func Test_writeWeatherStateCookie(t *testing.T) {
 	t.Run("writes cookie with correct name and encoded value", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		writeWeatherStateCookie(w, "st1", "24h", 2)
+		writeWeatherStateCookie(nil, w, "st1", "24h", 2, "")
 		header := w.Header().Get("Set-Cookie")
 		if header == "" {
 			t.Fatal("Set-Cookie header missing")
@@ -532,7 +654,7 @@ func Test_writeWeatherStateCookie(t *testing.T) {
 
 	t.Run("invalid range key uses default", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		writeWeatherStateCookie(w, "st1", "invalid", 1)
+		writeWeatherStateCookie(nil, w, "st1", "invalid", 1, "")
 		c := w.Result().Cookies()[0]
 		_, rangeKey, page := parseCookieValue(c.Value)
 		if rangeKey != defaultHistoryRangeKey {
@@ -545,7 +667,7 @@ func Test_writeWeatherStateCookie(t *testing.T) {
 
 	t.Run("page less than 1 uses 1", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		writeWeatherStateCookie(w, "st1", "24h", 0)
+		writeWeatherStateCookie(nil, w, "st1", "24h", 0, "")
 		c := w.Result().Cookies()[0]
 		_, _, page := parseCookieValue(c.Value)
 		if page != 1 {
@@ -555,7 +677,7 @@ func Test_writeWeatherStateCookie(t *testing.T) {
 
 	t.Run("negative page uses 1", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		writeWeatherStateCookie(w, "x", "1h", -5)
+		writeWeatherStateCookie(nil, w, "x", "1h", -5, "")
 		c := w.Result().Cookies()[0]
 		_, _, page := parseCookieValue(c.Value)
 		if page != 1 {
@@ -563,3 +685,67 @@ func Test_writeWeatherStateCookie(t *testing.T) {
 		}
 	})
 }
+
+func Test_parseHistogramQuery(t *testing.T) {
+	now := time.Date(2025, 2, 3, 12, 0, 0, 0, time.UTC)
+
+	t.Run("no params returns defaults", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/histogram", nil)
+		metric, from, to, bins, err := parseHistogramQuery(req, now)
+		if err != nil {
+			t.Fatalf("parseHistogramQuery() err = %v; want nil", err)
+		}
+		if metric != "temperature" {
+			t.Errorf("metric = %q; want temperature", metric)
+		}
+		if !to.Equal(now) || !from.Equal(now.Add(-24*time.Hour)) {
+			t.Errorf("from, to = %v, %v; want last 24h before %v", from, to, now)
+		}
+		if bins != 20 {
+			t.Errorf("bins = %d; want 20", bins)
+		}
+	})
+
+	t.Run("explicit values", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/histogram?metric=humidity&from=2025-01-01T00:00:00Z&to=2025-01-02T00:00:00Z&bins=5", nil)
+		metric, from, to, bins, err := parseHistogramQuery(req, now)
+		if err != nil {
+			t.Fatalf("parseHistogramQuery() err = %v; want nil", err)
+		}
+		if metric != "humidity" || bins != 5 {
+			t.Errorf("metric, bins = %q, %d; want humidity, 5", metric, bins)
+		}
+		if !from.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("from, to = %v, %v", from, to)
+		}
+	})
+
+	t.Run("from defaults relative to explicit to", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/histogram?to=2025-01-02T00:00:00Z", nil)
+		_, from, _, _, err := parseHistogramQuery(req, now)
+		if err != nil {
+			t.Fatalf("parseHistogramQuery() err = %v; want nil", err)
+		}
+		if want := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC); !from.Equal(want) {
+			t.Errorf("from = %v; want %v", from, want)
+		}
+	})
+
+	invalid := map[string]string{
+		"unknown metric": "/histogram?metric=wind",
+		"bad from":       "/histogram?from=yesterday",
+		"bad to":         "/histogram?to=today",
+		"from after to":  "/histogram?from=2025-01-02T00:00:00Z&to=2025-01-01T00:00:00Z",
+		"bins not int":   "/histogram?bins=abc",
+		"bins zero":      "/histogram?bins=0",
+		"bins too large": "/histogram?bins=101",
+	}
+	for name, target := range invalid {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if _, _, _, _, err := parseHistogramQuery(req, now); err == nil {
+				t.Errorf("parseHistogramQuery(%q) err = nil; want error", target)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Package downsampling applies the per-station policy of which resolutions of
+// readings are kept at which ages: raw readings for RawDays, then hourly
+// rollups for HourlyDays, then daily rollups for DailyDays (see
+// types.Downsampling). It fills in Station.Downsampling and serves
+// PUT /api/v1/stations/{id}/downsampling.
+package downsampling
+
+import (
+	"context"
+	"fmt"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// Policy is the server's downsampling policy.
+type Policy struct {
+	// Defaults apply to stations without overrides.
+	Defaults types.Downsampling
+	// MaxRawDays, when positive, caps every raw retention, including
+	// overrides set before the cap and ones that keep readings forever (see
+	// types.Quotas).
+	MaxRawDays int
+}
+
+// Validate checks that every default is 0 or within the retention bounds.
+func (p Policy) Validate() error {
+	for _, d := range []struct {
+		name string
+		days int
+	}{{"raw", p.Defaults.RawDays}, {"hourly", p.Defaults.HourlyDays}, {"daily", p.Defaults.DailyDays}} {
+		if err := CheckDays(d.days); err != nil {
+			return fmt.Errorf("%s retention: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// CheckDays reports why days cannot be a retention: it is neither 0 (forever)
+// nor between types.MinRetentionDays and types.MaxRetentionDays.
+func CheckDays(days int) error {
+	if days != 0 && (days < types.MinRetentionDays || days > types.MaxRetentionDays) {
+		return fmt.Errorf("must be 0 or between %d and %d days, got %d", types.MinRetentionDays, types.MaxRetentionDays, days)
+	}
+	return nil
+}
+
+// For returns the policy station s is kept by: the defaults with its
+// overrides applied and the raw retention capped by MaxRawDays. A coarser
+// resolution is kept at least as long as a finer one, so the hourly rollups
+// cover the days raw readings were pruned from and the daily rollups those
+// of the hourly rollups.
+func (p Policy) For(s types.Station) types.Downsampling {
+	d := p.Defaults
+	if s.RetentionDays != nil {
+		d.RawDays = *s.RetentionDays
+	}
+	if s.HourlyRetentionDays != nil {
+		d.HourlyDays = *s.HourlyRetentionDays
+	}
+	if s.DailyRetentionDays != nil {
+		d.DailyDays = *s.DailyRetentionDays
+	}
+	if p.MaxRawDays > 0 && (d.RawDays <= 0 || d.RawDays > p.MaxRawDays) {
+		d.RawDays = p.MaxRawDays
+	}
+	// Overrides written straight to the database may be shorter than the
+	// rollup lookback.
+	d.RawDays = atLeast(d.RawDays, types.MinRetentionDays)
+	d.HourlyDays = atLeast(d.HourlyDays, d.RawDays)
+	d.DailyDays = atLeast(d.DailyDays, d.HourlyDays)
+	return d
+}
+
+// atLeast returns the longer of two retentions, where 0 is forever.
+func atLeast(days, floor int) int {
+	if days == 0 || floor == 0 {
+		return 0
+	}
+	return max(days, floor)
+}
+
+// Repository is a WeatherRepository decorator that fills in the effective
+// policy of the stations it returns.
+type Repository struct {
+	repository.WeatherRepository
+	policy Policy
+}
+
+// New wraps repo to apply policy.
+func New(repo repository.WeatherRepository, policy Policy) *Repository {
+	return &Repository{WeatherRepository: repo, policy: policy}
+}
+
+// Policy returns the applied policy.
+func (r *Repository) Policy() Policy {
+	return r.policy
+}
+
+func (r *Repository) GetStation(ctx context.Context, stationID string) (types.Station, error) {
+	s, err := r.WeatherRepository.GetStation(ctx, stationID)
+	if err != nil {
+		return s, err
+	}
+	d := r.policy.For(s)
+	s.Downsampling = &d
+	return s, nil
+}
+
+func (r *Repository) GetStations(ctx context.Context) ([]types.Station, error) {
+	stations, err := r.WeatherRepository.GetStations(ctx)
+	for i := range stations {
+		d := r.policy.For(stations[i])
+		stations[i].Downsampling = &d
+	}
+	return stations, err
+}
@@ -0,0 +1,124 @@
+package downsampling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+func intp(v int) *int { return &v }
+
+func TestPolicyFor(t *testing.T) {
+	policy := Policy{Defaults: types.Downsampling{RawDays: 30, HourlyDays: 365}}
+	tests := []struct {
+		name    string
+		policy  Policy
+		station types.Station
+		want    types.Downsampling
+	}{
+		{"defaults", policy, types.Station{}, types.Downsampling{RawDays: 30, HourlyDays: 365}},
+		{"overrides", policy,
+			types.Station{RetentionDays: intp(90), HourlyRetentionDays: intp(730), DailyRetentionDays: intp(3650)},
+			types.Downsampling{RawDays: 90, HourlyDays: 730, DailyDays: 3650}},
+		{"rollups outlive raw readings", policy,
+			types.Station{RetentionDays: intp(0), DailyRetentionDays: intp(30)},
+			types.Downsampling{}},
+		{"daily outlives hourly", policy,
+			types.Station{HourlyRetentionDays: intp(400), DailyRetentionDays: intp(100)},
+			types.Downsampling{RawDays: 30, HourlyDays: 400, DailyDays: 400}},
+		{"below the rollup lookback", policy,
+			types.Station{RetentionDays: intp(2)},
+			types.Downsampling{RawDays: types.MinRetentionDays, HourlyDays: 365}},
+		{"raw ceiling", Policy{Defaults: types.Downsampling{RawDays: 30}, MaxRawDays: 60},
+			types.Station{RetentionDays: intp(0)},
+			types.Downsampling{RawDays: 60}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.For(tt.station); got != tt.want {
+				t.Errorf("For() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := (Policy{Defaults: types.Downsampling{RawDays: 30, HourlyDays: 365}}).Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+	if err := (Policy{Defaults: types.Downsampling{HourlyDays: 7}}).Validate(); err == nil {
+		t.Error("Validate(hourly 7) = nil, want an error")
+	}
+}
+
+// fakeRepo stores the downsampling overrides of station "1".
+type fakeRepo struct {
+	repository.WeatherRepository
+	station types.Station
+}
+
+func (r *fakeRepo) GetStation(ctx context.Context, stationID string) (types.Station, error) {
+	if stationID != r.station.ID {
+		return types.Station{}, repository.ErrStationNotFound
+	}
+	return r.station, nil
+}
+
+func (r *fakeRepo) SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error {
+	if stationID != r.station.ID {
+		return repository.ErrStationNotFound
+	}
+	r.station.RetentionDays, r.station.HourlyRetentionDays, r.station.DailyRetentionDays = rawDays, hourlyDays, dailyDays
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"sets overrides", "/api/v1/stations/1/downsampling", `{"rawDays": 30, "hourlyDays": 365, "dailyDays": 0}`, http.StatusOK},
+		{"reverts to defaults", "/api/v1/stations/1/downsampling", `{}`, http.StatusOK},
+		{"invalid json", "/api/v1/stations/1/downsampling", `{`, http.StatusBadRequest},
+		{"below minimum", "/api/v1/stations/1/downsampling", `{"hourlyDays": 7}`, http.StatusBadRequest},
+		{"negative", "/api/v1/stations/1/downsampling", `{"dailyDays": -1}`, http.StatusBadRequest},
+		{"unknown station", "/api/v1/stations/42/downsampling", `{"rawDays": 30}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := New(&fakeRepo{station: types.Station{ID: "1"}}, Policy{Defaults: types.Downsampling{RawDays: 90}})
+			mux := http.NewServeMux()
+			mux.Handle("PUT /api/v1/stations/{id}/downsampling", NewHandler(repo))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d; want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("returns the effective policy", func(t *testing.T) {
+		repo := New(&fakeRepo{station: types.Station{ID: "1"}}, Policy{Defaults: types.Downsampling{RawDays: 90}})
+		mux := http.NewServeMux()
+		mux.Handle("PUT /api/v1/stations/{id}/downsampling", NewHandler(repo))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/v1/stations/1/downsampling", strings.NewReader(`{"hourlyDays": 365}`)))
+
+		var got types.Station
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		want := types.Downsampling{RawDays: 90, HourlyDays: 365}
+		if got.Downsampling == nil || *got.Downsampling != want {
+			t.Errorf("downsampling = %+v, want %+v", got.Downsampling, want)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package downsampling
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/utils"
+)
+
+// Request is the body of PUT /api/v1/stations/{id}/downsampling. Each field
+// is the days to keep that resolution: 0 keeps it forever and null reverts to
+// the server default.
+type Request struct {
+	RawDays    *int `json:"rawDays"`
+	HourlyDays *int `json:"hourlyDays"`
+	DailyDays  *int `json:"dailyDays"`
+}
+
+// Handler serves PUT /api/v1/stations/{id}/downsampling.
+type Handler struct {
+	repo repository.WeatherRepository
+}
+
+// NewHandler returns a handler storing overrides through repo, which should
+// be the Repository filling in the policy of the station it returns.
+func NewHandler(repo repository.WeatherRepository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// ServeHTTP replaces the downsampling overrides of station {id} and returns
+// the station with its effective policy.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	for _, f := range []struct {
+		name string
+		days *int
+	}{{"rawDays", req.RawDays}, {"hourlyDays", req.HourlyDays}, {"dailyDays", req.DailyDays}} {
+		if f.days == nil {
+			continue
+		}
+		if err := CheckDays(*f.days); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "'"+f.name+"' "+err.Error())
+			return
+		}
+	}
+
+	err := h.repo.SetStationDownsampling(r.Context(), id, req.RawDays, req.HourlyDays, req.DailyDays)
+	if errors.Is(err, repository.ErrStationNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "station not found")
+		return
+	}
+	if errors.Is(err, repository.ErrQuotaExceeded) {
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set downsampling failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to update station")
+		return
+	}
+	station, err := h.repo.GetStation(r.Context(), id)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("set downsampling: reload station failed", "station_id", id, "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load station")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, station)
+}
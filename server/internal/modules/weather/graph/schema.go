@@ -0,0 +1,181 @@
+// Package graph serves stations and readings over GraphQL, so a dashboard
+// can fetch every station with its latest reading and recent history in
+// one request:
+//
+//	{ stations { id name latest { time temperature } readings(from: "2025-01-01T00:00:00Z") { time temperature } } }
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloudpico-server/internal/graphql"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// Readings field defaults: the range before to, and the page size and its
+// maximum, as in the REST readings endpoint.
+const (
+	defaultSpan  = 24 * time.Hour
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// NewSchema returns the schema:
+//
+//	type Query {
+//	  stations: [Station!]!
+//	  station(id: ID!): Station  # null when there is no such station
+//	}
+//	type Station {
+//	  id: ID!  name: String!  shadowOf: ID
+//	  latitude: Float  longitude: Float
+//	  gatewayVersion: String  firmwareVersion: String
+//	  latest: Reading
+//	  # Newest first; to defaults to now and from to 24 hours before to.
+//	  readings(from: String, to: String, limit: Int = 100): [Reading!]!
+//	}
+//	type Reading {
+//	  stationId: ID!  time: String!  receivedAt: String
+//	  temperature: Float!  humidity: Float  pressure: Float
+//	}
+//
+// Times are RFC 3339. now is the clock used for the readings defaults.
+func NewSchema(repo repository.WeatherRepository, now func() time.Time) (*graphql.Schema, error) {
+	reading := &graphql.Object{Name: "Reading", Fields: map[string]*graphql.Field{
+		"stationId":   readingField("ID!", func(r types.Reading) any { return r.StationID }),
+		"time":        readingField("String!", func(r types.Reading) any { return r.Time.UTC().Format(time.RFC3339Nano) }),
+		"receivedAt":  readingField("String", func(r types.Reading) any { return formatTime(r.ReceivedAt) }),
+		"temperature": readingField("Float!", func(r types.Reading) any { return r.Value }),
+		// 0 means the station did not report the metric.
+		"humidity": readingField("Float", func(r types.Reading) any { return nonZero(r.HumidityPct) }),
+		"pressure": readingField("Float", func(r types.Reading) any { return nonZero(r.PressureHpa) }),
+	}}
+
+	station := &graphql.Object{Name: "Station", Fields: map[string]*graphql.Field{
+		"id":              stationField("ID!", func(s types.Station) any { return s.ID }),
+		"name":            stationField("String!", func(s types.Station) any { return s.Name }),
+		"shadowOf":        stationField("ID", func(s types.Station) any { return nonEmpty(s.ShadowOf) }),
+		"latitude":        stationField("Float", func(s types.Station) any { return s.Latitude }),
+		"longitude":       stationField("Float", func(s types.Station) any { return s.Longitude }),
+		"gatewayVersion":  stationField("String", func(s types.Station) any { return nonEmpty(s.GatewayVersion) }),
+		"firmwareVersion": stationField("String", func(s types.Station) any { return nonEmpty(s.FirmwareVersion) }),
+		"latest": {
+			Type: "Reading",
+			Resolve: func(ctx context.Context, source any, _ map[string]any) (any, error) {
+				latest, err := repo.GetLatestReadings(ctx, source.(types.Station).ID, 1)
+				if err != nil || len(latest) == 0 {
+					return nil, err
+				}
+				return latest[0], nil
+			},
+		},
+		"readings": {
+			Type: "[Reading!]!",
+			Args: map[string]graphql.Arg{
+				"from":  {Type: "String"},
+				"to":    {Type: "String"},
+				"limit": {Type: "Int", Default: defaultLimit},
+			},
+			Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				from, to, limit, err := readingsArgs(args, now())
+				if err != nil {
+					return nil, err
+				}
+				return repo.GetReadings(ctx, source.(types.Station).ID, from, to, limit, 0, nil)
+			},
+			Size: func(args map[string]any) int {
+				n, _ := args["limit"].(int)
+				return min(n, maxLimit)
+			},
+		},
+	}}
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"stations": {
+			Type: "[Station!]!",
+			Resolve: func(ctx context.Context, _ any, _ map[string]any) (any, error) {
+				return repo.GetStations(ctx)
+			},
+		},
+		"station": {
+			Type: "Station",
+			Args: map[string]graphql.Arg{"id": {Type: "ID!"}},
+			Resolve: func(ctx context.Context, _ any, args map[string]any) (any, error) {
+				s, err := repo.GetStation(ctx, args["id"].(string))
+				if errors.Is(err, repository.ErrStationNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, err
+				}
+				return s, nil
+			},
+		},
+	}}
+
+	return graphql.NewSchema(query, station, reading)
+}
+
+// readingsArgs parses the arguments of Station.readings.
+func readingsArgs(args map[string]any, now time.Time) (from, to time.Time, limit int, err error) {
+	to = now
+	if s, ok := args["to"].(string); ok {
+		if to, err = time.Parse(time.RFC3339, s); err != nil {
+			return from, to, 0, errors.New("invalid 'to' (expected RFC3339)")
+		}
+	}
+	from = to.Add(-defaultSpan)
+	if s, ok := args["from"].(string); ok {
+		if from, err = time.Parse(time.RFC3339, s); err != nil {
+			return from, to, 0, errors.New("invalid 'from' (expected RFC3339)")
+		}
+	}
+	if from.After(to) {
+		return from, to, 0, errors.New("'from' must be <= 'to'")
+	}
+	limit = defaultLimit
+	if n, ok := args["limit"].(int); ok {
+		limit = n
+	}
+	if limit <= 0 || limit > maxLimit {
+		return from, to, 0, fmt.Errorf("'limit' must be between 1 and %d", maxLimit)
+	}
+	return from, to, limit, nil
+}
+
+func stationField(typ string, get func(types.Station) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(types.Station)), nil
+	}}
+}
+
+func readingField(typ string, get func(types.Reading) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(_ context.Context, source any, _ map[string]any) (any, error) {
+		return get(source.(types.Reading)), nil
+	}}
+}
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nonZero(v float64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	return nonEmpty(t.UTC().Format(time.RFC3339Nano))
+}
@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/graphql"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// fakeRepo serves fixed stations and readings, newest first, and records the
+// readings range asked for.
+type fakeRepo struct {
+	repository.WeatherRepository
+	stations []types.Station
+	readings map[string][]types.Reading
+
+	from, to time.Time
+	limit    int
+}
+
+func (r *fakeRepo) GetStations(ctx context.Context) ([]types.Station, error) { return r.stations, nil }
+
+func (r *fakeRepo) GetStation(ctx context.Context, id string) (types.Station, error) {
+	for _, s := range r.stations {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return types.Station{}, repository.ErrStationNotFound
+}
+
+func (r *fakeRepo) GetLatestReadings(ctx context.Context, id string, limit int) ([]types.Reading, error) {
+	return r.readings[id][:min(limit, len(r.readings[id]))], nil
+}
+
+func (r *fakeRepo) GetReadings(ctx context.Context, id string, from, to time.Time, limit, offset int, fields []string) ([]types.Reading, error) {
+	if id == "broken" {
+		return nil, errors.New("database is locked")
+	}
+	r.from, r.to, r.limit = from, to, limit
+	var out []types.Reading
+	for _, rd := range r.readings[id] {
+		if !rd.Time.Before(from) && !rd.Time.After(to) && len(out) < limit {
+			out = append(out, rd)
+		}
+	}
+	return out, nil
+}
+
+var now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newFakeRepo() *fakeRepo {
+	lat := 52.23
+	received := now.Add(-time.Minute)
+	return &fakeRepo{
+		stations: []types.Station{
+			{ID: "1", Name: "garden", Latitude: &lat, FirmwareVersion: "1.2.0"},
+			{ID: "2", Name: "garage", ShadowOf: "1"},
+		},
+		readings: map[string][]types.Reading{
+			"1": {
+				{StationID: "1", Time: now.Add(-time.Hour), Value: 21.5, HumidityPct: 40, ReceivedAt: &received},
+				{StationID: "1", Time: now.Add(-2 * time.Hour), Value: 20, PressureHpa: 1013},
+				{StationID: "1", Time: now.Add(-30 * time.Hour), Value: 15},
+			},
+		},
+	}
+}
+
+func execute(t *testing.T, repo *fakeRepo, query string) string {
+	t.Helper()
+	s, err := NewSchema(repo, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("NewSchema() error = %v", err)
+	}
+	b, err := json.Marshal(s.Execute(context.Background(), graphql.Request{Query: query}))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	return string(b)
+}
+
+func TestSchema_stations(t *testing.T) {
+	repo := newFakeRepo()
+	got := execute(t, repo, `{
+		stations {
+			id name shadowOf latitude firmwareVersion gatewayVersion
+			latest { time temperature humidity pressure receivedAt }
+			readings { temperature }
+		}
+	}`)
+	want := `{"data":{"stations":[` +
+		`{"id":"1","name":"garden","shadowOf":null,"latitude":52.23,"firmwareVersion":"1.2.0","gatewayVersion":null,` +
+		`"latest":{"time":"2025-03-01T11:00:00Z","temperature":21.5,"humidity":40,"pressure":null,"receivedAt":"2025-03-01T11:59:00Z"},` +
+		`"readings":[{"temperature":21.5},{"temperature":20}]},` +
+		`{"id":"2","name":"garage","shadowOf":"1","latitude":null,"firmwareVersion":null,"gatewayVersion":null,"latest":null,"readings":[]}` +
+		`]}}`
+	if got != want {
+		t.Errorf("stations =\n%s\nwant\n%s", got, want)
+	}
+	if !repo.from.Equal(now.Add(-24*time.Hour)) || !repo.to.Equal(now) || repo.limit != defaultLimit {
+		t.Errorf("readings asked for [%v, %v] limit %d; want the last 24 hours, limit %d", repo.from, repo.to, repo.limit, defaultLimit)
+	}
+}
+
+func TestSchema_station(t *testing.T) {
+	for _, tt := range []struct {
+		name, query, want string
+	}{
+		{
+			name:  "readings range",
+			query: `{ station(id: "1") { name readings(from: "2025-02-28T00:00:00Z", to: "2025-03-01T10:30:00Z", limit: 5) { time pressure } } }`,
+			want:  `{"data":{"station":{"name":"garden","readings":[{"time":"2025-03-01T10:00:00Z","pressure":1013},{"time":"2025-02-28T06:00:00Z","pressure":null}]}}}`,
+		},
+		{
+			name:  "unknown station",
+			query: `{ station(id: "9") { name } }`,
+			want:  `{"data":{"station":null}}`,
+		},
+		{
+			name:  "invalid range",
+			query: `{ station(id: "1") { name readings(from: "yesterday") { time } } }`,
+			want:  `{"data":{"station":null},"errors":[{"message":"invalid 'from' (expected RFC3339)","locations":[{"line":1,"column":27}],"path":["station","readings"]}]}`,
+		},
+		{
+			name:  "limit too large",
+			query: `{ station(id: "1") { readings(limit: 5000) { time } } }`,
+			want:  `{"data":{"station":null},"errors":[{"message":"'limit' must be between 1 and 1000","locations":[{"line":1,"column":22}],"path":["station","readings"]}]}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, newFakeRepo(), tt.query); got != tt.want {
+				t.Errorf("station =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchema_repositoryErrors(t *testing.T) {
+	repo := newFakeRepo()
+	repo.stations = append(repo.stations, types.Station{ID: "broken", Name: "attic"})
+	got := execute(t, repo, `{ stations { name readings { time } } }`)
+	want := `{"data":null,"errors":[{"message":"database is locked","locations":[{"line":1,"column":19}],"path":["stations",2,"readings"]}]}`
+	if got != want {
+		t.Errorf("stations =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchema_cost(t *testing.T) {
+	var q strings.Builder
+	q.WriteString("{ stations { ")
+	for i := range 240 {
+		fmt.Fprintf(&q, "r%d: readings(limit: 1000) { time } ", i)
+	}
+	q.WriteString("} }")
+	repo := newFakeRepo()
+	got := execute(t, repo, q.String())
+	if !strings.Contains(got, "more than 1000000 values") || strings.Contains(got, `"data"`) {
+		t.Errorf("aliased readings = %s; want the query refused", got)
+	}
+	if repo.limit != 0 {
+		t.Errorf("readings were fetched for a refused query")
+	}
+}
@@ -0,0 +1,266 @@
+// Package health scores how well each station is reporting, from 0 to 100.
+//
+// The score combines five components, each itself 0–100: freshness (age of
+// the newest reading), gaps (availability over the last 24 hours), battery
+// voltage, radio signal (RSSI) and the share of recent messages rejected by
+// validation. Components the server knows nothing about, e.g. the battery of
+// a station that does not report one, are left out and the weights of the
+// others scaled up.
+package health
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// Component score bounds: at or past the first value a component scores 100,
+// at or past the second 0, linearly in between.
+const (
+	// freshAge matches the default gap threshold (3 × the 60s interval).
+	freshAge = 3 * time.Minute
+	staleAge = time.Hour
+	// Single-cell LiPo range; the Pico browns out below ~3.3 V.
+	batteryFull  = 4.0
+	batteryEmpty = 3.3
+	rssiStrong   = -60
+	rssiWeak     = -90
+)
+
+// Status thresholds: a score of at least goodScore is "good", of at least
+// fairScore "fair", anything lower "poor".
+const (
+	goodScore = 80
+	fairScore = 50
+)
+
+// window is how many recent messages of a station the rejection rate covers.
+const window = 100
+
+// Weights are the relative weights of the components; they need not sum to
+// 100.
+type Weights struct {
+	Freshness  float64
+	Gaps       float64
+	Battery    float64
+	RSSI       float64
+	Rejections float64
+}
+
+// DefaultWeights favors data actually arriving over the signals predicting
+// that it will stop.
+var DefaultWeights = Weights{Freshness: 30, Gaps: 25, Battery: 20, RSSI: 10, Rejections: 15}
+
+// ParseWeights parses comma-separated name=weight pairs, e.g.
+// "freshness=40,rssi=0", over DefaultWeights. Names are freshness, gaps,
+// battery, rssi and rejections; a weight of 0 ignores a component. An empty
+// string returns DefaultWeights.
+func ParseWeights(s string) (Weights, error) {
+	w := DefaultWeights
+	fields := map[string]*float64{
+		"freshness":  &w.Freshness,
+		"gaps":       &w.Gaps,
+		"battery":    &w.Battery,
+		"rssi":       &w.RSSI,
+		"rejections": &w.Rejections,
+	}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return Weights{}, fmt.Errorf("invalid weight %q (expected name=weight)", pair)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		field, ok := fields[name]
+		if !ok {
+			return Weights{}, fmt.Errorf("unknown health component %q (allowed: freshness, gaps, battery, rssi, rejections)", name)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return Weights{}, fmt.Errorf("invalid %s weight %q (expected a number >= 0)", name, value)
+		}
+		*field = v
+	}
+	if w.Freshness+w.Gaps+w.Battery+w.RSSI+w.Rejections == 0 {
+		return Weights{}, fmt.Errorf("at least one health weight must be > 0")
+	}
+	return w, nil
+}
+
+// Inputs are what the score of one station is computed from. Nil fields are
+// unknown.
+type Inputs struct {
+	Age       *time.Duration // since the newest reading
+	UptimePct *float64       // availability over the last 24 hours
+	Battery   *float64       // volts
+	RSSI      *int           // dBm
+	// Received and Rejected count the recent messages of the station.
+	Received int
+	Rejected int
+}
+
+// Score combines in into a health score with weights w. It returns nil when
+// no weighted component is known.
+func Score(in Inputs, w Weights) *types.Health {
+	h := types.Health{}
+	if in.Age != nil {
+		h.Freshness = component(in.Age.Seconds()-freshAge.Seconds(), staleAge.Seconds()-freshAge.Seconds())
+	}
+	if in.UptimePct != nil {
+		v := math.Max(0, math.Min(100, *in.UptimePct))
+		h.Gaps = &v
+	}
+	if in.Battery != nil {
+		h.Battery = component(*in.Battery-batteryFull, batteryEmpty-batteryFull)
+	}
+	if in.RSSI != nil {
+		h.RSSI = component(float64(*in.RSSI-rssiStrong), rssiWeak-rssiStrong)
+	}
+	if in.Received > 0 {
+		v := 100 * (1 - float64(in.Rejected)/float64(in.Received))
+		h.Rejections = &v
+	}
+
+	var sum, total float64
+	for _, c := range []struct {
+		score  *float64
+		weight float64
+	}{
+		{h.Freshness, w.Freshness},
+		{h.Gaps, w.Gaps},
+		{h.Battery, w.Battery},
+		{h.RSSI, w.RSSI},
+		{h.Rejections, w.Rejections},
+	} {
+		if c.score != nil && c.weight > 0 {
+			sum += *c.score * c.weight
+			total += c.weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	h.Score = int(math.Round(sum / total))
+	switch {
+	case h.Score >= goodScore:
+		h.Status = types.HealthGood
+	case h.Score >= fairScore:
+		h.Status = types.HealthFair
+	default:
+		h.Status = types.HealthPoor
+	}
+	return &h
+}
+
+// component scores a value from d, its offset from the best bound, and zero,
+// the worst bound's: 100 at the best bound or beyond it, 0 at the worst or
+// beyond it.
+func component(d, zero float64) *float64 {
+	v := 100 * math.Max(0, math.Min(1, 1-d/zero))
+	return &v
+}
+
+// Tracker remembers what ingest learns about each station that is not
+// stored with its readings: the latest battery and RSSI values and which
+// recent messages were rejected. It is safe for concurrent use.
+type Tracker struct {
+	weights Weights
+
+	mu       sync.Mutex
+	stations map[string]*stationState
+	version  uint64
+}
+
+type stationState struct {
+	battery  *float64
+	rssi     *int
+	outcomes [window]bool // true for rejected messages; a ring
+	n        int          // messages recorded, up to window
+	next     int
+}
+
+// NewTracker returns a tracker scoring with weights.
+func NewTracker(weights Weights) *Tracker {
+	return &Tracker{weights: weights, stations: make(map[string]*stationState)}
+}
+
+// Accept records a message stored as reading t.
+func (tr *Tracker) Accept(t cloudpico_shared.Telemetry) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	s := tr.state(t.StationID)
+	if t.Battery != nil {
+		v := *t.Battery
+		s.battery = &v
+	}
+	if t.RSSI != nil {
+		v := *t.RSSI
+		s.rssi = &v
+	}
+	s.record(false)
+	tr.version++
+}
+
+// Reject records a message of stationID that failed validation.
+func (tr *Tracker) Reject(stationID string) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.state(stationID).record(true)
+	tr.version++
+}
+
+// Version changes whenever a message is recorded, so responses built from the
+// tracker can be revalidated.
+func (tr *Tracker) Version() uint64 {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.version
+}
+
+// Inputs returns the tracked inputs of stationID, without Age and UptimePct.
+func (tr *Tracker) Inputs(stationID string) Inputs {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	s, ok := tr.stations[stationID]
+	if !ok {
+		return Inputs{}
+	}
+	in := Inputs{Battery: s.battery, RSSI: s.rssi, Received: s.n}
+	for _, rejected := range s.outcomes[:s.n] {
+		if rejected {
+			in.Rejected++
+		}
+	}
+	return in
+}
+
+// Score scores stationID from what the tracker knows of it and the age of its
+// newest reading and its uptime, either of which may be nil.
+func (tr *Tracker) Score(stationID string, age *time.Duration, uptimePct *float64) *types.Health {
+	in := tr.Inputs(stationID)
+	in.Age, in.UptimePct = age, uptimePct
+	return Score(in, tr.weights)
+}
+
+func (tr *Tracker) state(stationID string) *stationState {
+	s, ok := tr.stations[stationID]
+	if !ok {
+		s = &stationState{}
+		tr.stations[stationID] = s
+	}
+	return s
+}
+
+func (s *stationState) record(rejected bool) {
+	s.outcomes[s.next] = rejected
+	s.next = (s.next + 1) % window
+	s.n = min(s.n+1, window)
+}
@@ -0,0 +1,141 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+func TestParseWeights(t *testing.T) {
+	t.Run("empty uses the defaults", func(t *testing.T) {
+		w, err := ParseWeights("")
+		if err != nil || w != DefaultWeights {
+			t.Errorf("ParseWeights(\"\") = %+v, %v; want defaults", w, err)
+		}
+	})
+
+	t.Run("overrides named components", func(t *testing.T) {
+		w, err := ParseWeights(" Freshness=40, rssi=0 ,")
+		if err != nil {
+			t.Fatalf("ParseWeights() error = %v", err)
+		}
+		want := DefaultWeights
+		want.Freshness, want.RSSI = 40, 0
+		if w != want {
+			t.Errorf("ParseWeights() = %+v; want %+v", w, want)
+		}
+	})
+
+	for _, tt := range []struct{ in, want string }{
+		{"freshness", "expected name=weight"},
+		{"uptime=10", "unknown health component"},
+		{"gaps=-1", "invalid gaps weight"},
+		{"battery=lots", "invalid battery weight"},
+		{"freshness=0,gaps=0,battery=0,rssi=0,rejections=0", "at least one"},
+	} {
+		t.Run("rejects "+tt.in, func(t *testing.T) {
+			if _, err := ParseWeights(tt.in); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseWeights(%q) error = %v; want %q", tt.in, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScore(t *testing.T) {
+	duration := func(d time.Duration) *time.Duration { return &d }
+	float := func(v float64) *float64 { return &v }
+	integer := func(v int) *int { return &v }
+
+	t.Run("healthy station scores 100", func(t *testing.T) {
+		h := Score(Inputs{
+			Age:       duration(time.Minute),
+			UptimePct: float(100),
+			Battery:   float(4.1),
+			RSSI:      integer(-50),
+			Received:  10,
+		}, DefaultWeights)
+		if h == nil || h.Score != 100 || h.Status != types.HealthGood {
+			t.Fatalf("Score() = %+v; want 100, good", h)
+		}
+	})
+
+	t.Run("scales components between their bounds", func(t *testing.T) {
+		h := Score(Inputs{
+			Age:      duration(staleAge),
+			Battery:  float(3.65),
+			RSSI:     integer(-75),
+			Received: 4,
+			Rejected: 1,
+		}, DefaultWeights)
+		if h == nil {
+			t.Fatal("Score() = nil")
+		}
+		for name, got := range map[string]*float64{"freshness": h.Freshness, "battery": h.Battery, "rssi": h.RSSI, "rejections": h.Rejections} {
+			want := map[string]float64{"freshness": 0, "battery": 50, "rssi": 50, "rejections": 75}[name]
+			if got == nil || *got < want-0.001 || *got > want+0.001 {
+				t.Errorf("%s = %v; want %v", name, got, want)
+			}
+		}
+		if h.Gaps != nil {
+			t.Errorf("gaps = %v; want nil without uptime", *h.Gaps)
+		}
+		// (0×30 + 50×20 + 50×10 + 75×15) / (30+20+10+15)
+		if h.Score != 35 || h.Status != types.HealthPoor {
+			t.Errorf("Score() = %d %s; want 35 poor", h.Score, h.Status)
+		}
+	})
+
+	t.Run("leaves out unknown and unweighted components", func(t *testing.T) {
+		w := DefaultWeights
+		w.Gaps = 0
+		h := Score(Inputs{UptimePct: float(0), RSSI: integer(-75)}, w)
+		if h == nil || h.Score != 50 || h.Status != types.HealthFair {
+			t.Errorf("Score() = %+v; want 50 fair from RSSI alone", h)
+		}
+	})
+
+	t.Run("nothing known", func(t *testing.T) {
+		if h := Score(Inputs{}, DefaultWeights); h != nil {
+			t.Errorf("Score() = %+v; want nil", h)
+		}
+	})
+}
+
+func TestTracker(t *testing.T) {
+	battery, rssi := 3.9, -70
+	tr := NewTracker(DefaultWeights)
+	tr.Accept(cloudpico_shared.Telemetry{StationID: "pico-1", Battery: &battery, RSSI: &rssi})
+	// Readings without battery or RSSI keep the last known values.
+	tr.Accept(cloudpico_shared.Telemetry{StationID: "pico-1"})
+	tr.Reject("pico-1")
+	battery = 3.0
+
+	in := tr.Inputs("pico-1")
+	if in.Battery == nil || *in.Battery != 3.9 || in.RSSI == nil || *in.RSSI != -70 {
+		t.Errorf("Inputs() battery = %v, rssi = %v; want 3.9 and -70", in.Battery, in.RSSI)
+	}
+	if in.Received != 3 || in.Rejected != 1 {
+		t.Errorf("Inputs() = %d received, %d rejected; want 3 and 1", in.Received, in.Rejected)
+	}
+	if tr.Version() != 3 {
+		t.Errorf("Version() = %d; want 3", tr.Version())
+	}
+
+	t.Run("rejections cover the last window messages", func(t *testing.T) {
+		for range window {
+			tr.Accept(cloudpico_shared.Telemetry{StationID: "pico-1"})
+		}
+		if in := tr.Inputs("pico-1"); in.Received != window || in.Rejected != 0 {
+			t.Errorf("Inputs() = %d received, %d rejected; want %d and 0", in.Received, in.Rejected, window)
+		}
+	})
+
+	t.Run("unknown station", func(t *testing.T) {
+		if h := tr.Score("pico-2", nil, nil); h != nil {
+			t.Errorf("Score(pico-2) = %+v; want nil", h)
+		}
+	})
+}
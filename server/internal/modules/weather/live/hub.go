@@ -0,0 +1,219 @@
+// Package live pushes readings to connected clients as they are ingested.
+//
+// A Hub subscribes once to events.ReadingCreated and fans each reading out to
+// its subscriptions, each filtered to a set of stations. The WebSocket
+// handler at GET /ws/readings and the Server-Sent Events handler at
+// GET /api/v1/stream are its clients.
+package live
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/events"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// DefaultBuffer is how many readings a subscription may fall behind by
+// before it is closed.
+const DefaultBuffer = 64
+
+// historySize is how many recent readings the hub keeps so a reconnecting
+// client can resume where it stopped.
+const historySize = 1024
+
+// Event is a reading and its ID in the hub's stream. IDs increase; they are
+// prefixed with the hub's start time so IDs from before a server restart are
+// recognized as unknown.
+type Event struct {
+	ID      string
+	Reading cloudpico_shared.Telemetry
+}
+
+// Hub fans readings out to subscriptions. The zero value is not usable; use
+// NewHub.
+type Hub struct {
+	buffer int
+	epoch  string
+
+	mu      sync.RWMutex
+	subs    map[*Subscription]struct{}
+	seq     uint64
+	history []entry // ring of the last historySize readings
+}
+
+type entry struct {
+	seq     uint64
+	reading cloudpico_shared.Telemetry
+}
+
+// NewHub returns a hub whose subscriptions buffer up to buffer readings;
+// values below 1 mean DefaultBuffer.
+func NewHub(buffer int) *Hub {
+	if buffer < 1 {
+		buffer = DefaultBuffer
+	}
+	return &Hub{
+		buffer: buffer,
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Attach feeds the hub the readings published on bus and returns a function
+// that stops it.
+func (h *Hub) Attach(bus *events.Bus) (detach func()) {
+	return bus.Subscribe(events.ReadingCreated, func(e events.Event) {
+		if t, ok := e.Payload.(cloudpico_shared.Telemetry); ok {
+			h.Publish(t)
+		}
+	})
+}
+
+// Publish delivers t to every subscription whose filter accepts its station.
+// It does not block: a subscription whose buffer is full is closed, and its
+// client is expected to reconnect and catch up from the API.
+func (h *Hub) Publish(t cloudpico_shared.Telemetry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.seq++
+	e := entry{seq: h.seq, reading: t}
+	if len(h.history) < historySize {
+		h.history = append(h.history, e)
+	} else {
+		h.history[(h.seq-1)%historySize] = e
+	}
+	ev := h.event(e)
+	for s := range h.subs {
+		if !s.accepts(t.StationID) {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			h.remove(s)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the readings of stationIDs, or of every
+// station when stationIDs is empty.
+func (h *Hub) Subscribe(stationIDs []string) *Subscription {
+	s, _, _ := h.Resume(stationIDs, "")
+	return s
+}
+
+// Resume subscribes like Subscribe and also returns the readings of the
+// subscribed stations published after the event lastID, so a client that
+// reconnects misses none. ok is false, and nothing is replayed, when lastID
+// is empty or is not a recent event of this hub, e.g. from before a restart.
+func (h *Hub) Resume(stationIDs []string, lastID string) (s *Subscription, replay []Event, ok bool) {
+	s = &Subscription{ch: make(chan Event, h.buffer)}
+	s.Filter(stationIDs)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[s] = struct{}{}
+	last, ok := h.parseID(lastID)
+	if !ok {
+		return s, nil, false
+	}
+	for seq := last + 1; seq <= h.seq; seq++ {
+		if e := h.history[(seq-1)%historySize]; s.accepts(e.reading.StationID) {
+			replay = append(replay, h.event(e))
+		}
+	}
+	return s, replay, true
+}
+
+// parseID returns the sequence number of id if the hub still has every
+// reading after it.
+func (h *Hub) parseID(id string) (uint64, bool) {
+	epoch, n, found := strings.Cut(id, "-")
+	if !found || epoch != h.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(n, 10, 64)
+	if err != nil || seq > h.seq || h.seq-seq > uint64(len(h.history)) {
+		return 0, false
+	}
+	return seq, true
+}
+
+func (h *Hub) event(e entry) Event {
+	return Event{ID: fmt.Sprintf("%s-%d", h.epoch, e.seq), Reading: e.reading}
+}
+
+// Unsubscribe removes s and closes its channel. It may be called more than
+// once.
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(s)
+}
+
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	close(s.ch)
+}
+
+// Subscribers returns the number of open subscriptions.
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Subscription receives the readings of the stations in its filter.
+type Subscription struct {
+	ch chan Event
+
+	mu       sync.RWMutex
+	stations map[string]bool // nil accepts every station
+}
+
+// C delivers the readings; it is closed when the subscription ends.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Filter replaces the stations s receives; empty means every station.
+func (s *Subscription) Filter(stationIDs []string) {
+	var stations map[string]bool
+	if len(stationIDs) > 0 {
+		stations = make(map[string]bool, len(stationIDs))
+		for _, id := range stationIDs {
+			stations[id] = true
+		}
+	}
+	s.mu.Lock()
+	s.stations = stations
+	s.mu.Unlock()
+}
+
+// Stations returns the filter, sorted; nil means every station.
+func (s *Subscription) Stations() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.stations == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(s.stations))
+	for id := range s.stations {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func (s *Subscription) accepts(stationID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stations == nil || s.stations[stationID]
+}
@@ -0,0 +1,92 @@
+package live
+
+import (
+	"testing"
+
+	"cloudpico-server/internal/events"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+func TestHub(t *testing.T) {
+	t.Run("filters by station", func(t *testing.T) {
+		bus := events.NewBus()
+		h := NewHub(4)
+		detach := h.Attach(bus)
+		defer detach()
+		all, garden := h.Subscribe(nil), h.Subscribe([]string{"1"})
+
+		for _, id := range []string{"1", "2"} {
+			bus.Publish(events.Event{Topic: events.ReadingCreated, StationID: id, Payload: cloudpico_shared.Telemetry{StationID: id}})
+		}
+
+		if got := len(all.C()); got != 2 {
+			t.Errorf("unfiltered subscription got %d readings; want 2", got)
+		}
+		if got := len(garden.C()); got != 1 || (<-garden.C()).Reading.StationID != "1" {
+			t.Errorf("station 1 subscription got %d readings; want only station 1", got)
+		}
+	})
+
+	t.Run("changing the filter", func(t *testing.T) {
+		h := NewHub(4)
+		s := h.Subscribe([]string{"1"})
+		s.Filter([]string{"3", "2"})
+		h.Publish(cloudpico_shared.Telemetry{StationID: "1"})
+		h.Publish(cloudpico_shared.Telemetry{StationID: "2"})
+
+		if got := s.Stations(); len(got) != 2 || got[0] != "2" || got[1] != "3" {
+			t.Errorf("Stations() = %v; want [2 3]", got)
+		}
+		if len(s.C()) != 1 {
+			t.Errorf("got %d readings; want 1 for station 2", len(s.C()))
+		}
+		s.Filter(nil)
+		if s.Stations() != nil {
+			t.Errorf("Stations() = %v after clearing; want nil (every station)", s.Stations())
+		}
+	})
+
+	t.Run("resume replays missed readings", func(t *testing.T) {
+		h := NewHub(4)
+		first := h.Subscribe(nil)
+		for _, id := range []string{"1", "2", "1"} {
+			h.Publish(cloudpico_shared.Telemetry{StationID: id})
+		}
+		last := (<-first.C()).ID
+
+		s, replay, ok := h.Resume([]string{"1"}, last)
+		if !ok || len(replay) != 1 || replay[0].Reading.StationID != "1" || replay[0].ID <= last {
+			t.Errorf("Resume(%s) = %+v, %v; want the later station 1 reading", last, replay, ok)
+		}
+		h.Publish(cloudpico_shared.Telemetry{StationID: "1"})
+		if len(s.C()) != 1 {
+			t.Errorf("resumed subscription got %d new readings; want 1", len(s.C()))
+		}
+
+		for _, id := range []string{"", "0-1", last + "x"} {
+			if _, replay, ok := h.Resume(nil, id); ok || replay != nil {
+				t.Errorf("Resume(%q) = %v, %v; want nothing to replay", id, replay, ok)
+			}
+		}
+	})
+
+	t.Run("closes subscriptions that fall behind", func(t *testing.T) {
+		h := NewHub(1)
+		slow, other := h.Subscribe(nil), h.Subscribe([]string{"2"})
+		h.Publish(cloudpico_shared.Telemetry{StationID: "1"})
+		h.Publish(cloudpico_shared.Telemetry{StationID: "1"})
+
+		<-slow.C()
+		if _, ok := <-slow.C(); ok {
+			t.Error("slow subscription still open; want closed")
+		}
+		if h.Subscribers() != 1 {
+			t.Errorf("Subscribers() = %d; want 1", h.Subscribers())
+		}
+		h.Unsubscribe(slow) // already removed
+		h.Unsubscribe(other)
+		if h.Subscribers() != 0 {
+			t.Errorf("Subscribers() = %d; want 0", h.Subscribers())
+		}
+	})
+}
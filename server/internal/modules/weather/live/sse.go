@@ -0,0 +1,105 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/logging"
+)
+
+// heartbeatInterval is how often an idle stream sends a comment, so proxies
+// keep the connection open and clients notice when it is gone.
+const heartbeatInterval = 15 * time.Second
+
+// retryMillis is the reconnect delay suggested to EventSource clients.
+const retryMillis = 3000
+
+// StreamHandler serves GET /api/v1/stream.
+type StreamHandler struct {
+	hub       *Hub
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(hub *Hub) *StreamHandler {
+	return &StreamHandler{hub: hub, heartbeat: heartbeatInterval}
+}
+
+// ServeHTTP streams Server-Sent Events: a "reading" event, with the reading
+// in the telemetry format as data, for every reading stored while the client
+// is connected. station_id, repeated or comma-separated, limits the stream to
+// some stations. Each event has an ID; a client reconnecting with
+// Last-Event-ID (or the last_event_id parameter, for clients that cannot set
+// headers) first gets the readings it missed. When those are no longer known
+// it gets a "reset" event instead and should reload what it shows from the
+// API. A client that falls behind is disconnected and expected to resume.
+func (h *StreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	sub, replay, resumed := h.hub.Resume(stationIDs(r.URL.Query()["station_id"]), lastID)
+	defer h.hub.Unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-store")
+	// Ask nginx not to buffer the stream.
+	hdr.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retryMillis); err != nil {
+		return
+	}
+	if lastID != "" && !resumed {
+		if _, err := fmt.Fprint(w, "event: reset\ndata: {}\n\n"); err != nil {
+			return
+		}
+	}
+	for _, e := range replay {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		logging.FromContext(r.Context()).Warn("stream: response cannot be flushed", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C():
+			if !ok {
+				logging.FromContext(r.Context()).Warn("stream: client fell behind; disconnected", "remote", r.RemoteAddr)
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes e as a "reading" event. JSON has no raw newlines, so the
+// data fits one line.
+func writeEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e.Reading)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: reading\ndata: %s\n\n", e.ID, data)
+	return err
+}
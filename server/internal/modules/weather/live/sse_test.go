@@ -0,0 +1,99 @@
+package live
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+func TestStreamHandler(t *testing.T) {
+	hub := NewHub(DefaultBuffer)
+	h := NewStreamHandler(hub)
+	h.heartbeat = 10 * time.Millisecond
+	srv := httptest.NewServer(h)
+	// Registered first, so it runs after the streams are closed.
+	t.Cleanup(srv.Close)
+
+	// open starts a stream and returns a reader of its lines once the
+	// subscription is registered.
+	open := func(t *testing.T, query, lastEventID string) *bufio.Scanner {
+		t.Helper()
+		before := hub.Subscribers()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/stream"+query, nil)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Content-Type = %q; want text/event-stream", ct)
+		}
+		for deadline := time.Now().Add(time.Second); hub.Subscribers() == before && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		return bufio.NewScanner(resp.Body)
+	}
+	// next returns the next event's fields, skipping comments and retry.
+	next := func(t *testing.T, sc *bufio.Scanner) map[string]string {
+		t.Helper()
+		ev := map[string]string{}
+		for sc.Scan() {
+			line := sc.Text()
+			if line == "" {
+				if ev["event"] != "" {
+					return ev
+				}
+				continue
+			}
+			if k, v, ok := strings.Cut(line, ": "); ok && k != "" {
+				ev[k] = v
+			}
+		}
+		t.Fatalf("stream ended: %v", sc.Err())
+		return nil
+	}
+
+	sc := open(t, "?station_id=1", "")
+	hub.Publish(cloudpico_shared.Telemetry{StationID: "2"})
+	hub.Publish(cloudpico_shared.Telemetry{StationID: "1"})
+	first := next(t, sc)
+	if first["event"] != "reading" || first["id"] == "" || !strings.Contains(first["data"], `"station_id":"1"`) {
+		t.Fatalf("event = %v; want the station 1 reading with an ID", first)
+	}
+
+	t.Run("heartbeat", func(t *testing.T) {
+		for sc.Scan() {
+			if sc.Text() == ": heartbeat" {
+				return
+			}
+		}
+		t.Error("no heartbeat comment")
+	})
+
+	t.Run("resumes after Last-Event-ID", func(t *testing.T) {
+		missed := 7
+		hub.Publish(cloudpico_shared.Telemetry{StationID: "1", Sequence: &missed})
+		sc := open(t, "?station_id=1", first["id"])
+		if ev := next(t, sc); ev["event"] != "reading" || !strings.Contains(ev["data"], `"sequence":7`) {
+			t.Errorf("event = %v; want the missed reading replayed", ev)
+		}
+	})
+
+	t.Run("unknown Last-Event-ID resets", func(t *testing.T) {
+		sc := open(t, "", "stale-1")
+		if ev := next(t, sc); ev["event"] != "reset" {
+			t.Errorf("event = %v; want reset", ev)
+		}
+	})
+}
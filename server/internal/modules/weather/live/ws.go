@@ -0,0 +1,174 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+	"github.com/gorilla/websocket"
+
+	"cloudpico-server/internal/logging"
+)
+
+const (
+	// pingInterval is how often the server pings an idle client.
+	pingInterval = 30 * time.Second
+	// pongWait is how long a client may stay silent, pongs included, before
+	// it is disconnected.
+	pongWait = 2 * pingInterval
+	// writeWait bounds writing one message.
+	writeWait = 10 * time.Second
+	// maxClientMessage bounds a message from the client.
+	maxClientMessage = 4096
+)
+
+// Message types sent over the WebSocket.
+const (
+	TypeReading    = "reading"
+	TypeSubscribed = "subscribed"
+	TypeError      = "error"
+)
+
+// Message is one server message. Reading is set for TypeReading; StationIDs,
+// for TypeSubscribed, is the new filter, empty for every station.
+type Message struct {
+	Type       string                      `json:"type"`
+	Reading    *cloudpico_shared.Telemetry `json:"reading,omitempty"`
+	StationIDs []string                    `json:"station_ids,omitempty"`
+	Error      string                      `json:"error,omitempty"`
+}
+
+// subscribeRequest is a client message replacing the station filter.
+type subscribeRequest struct {
+	Type       string   `json:"type"`
+	StationIDs []string `json:"station_ids"`
+}
+
+// upgrader accepts same-origin browsers and clients sending no Origin.
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+// Handler serves GET /ws/readings.
+type Handler struct {
+	hub *Hub
+}
+
+func NewHandler(hub *Hub) *Handler {
+	return &Handler{hub: hub}
+}
+
+// ServeHTTP upgrades the request to a WebSocket and sends every reading
+// ingested from then on as a TypeReading message. station_id, repeated or
+// comma-separated, limits the stream to some stations; the client changes the
+// filter by sending {"type": "subscribe", "station_ids": [...]}, which is
+// confirmed with a TypeSubscribed message. A client that falls behind is
+// closed with status 1013 (try again later).
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error status.
+		logging.FromContext(r.Context()).Debug("ws: upgrade failed", "error", err)
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			logging.FromContext(r.Context()).Debug("ws: close failed", "error", err)
+		}
+	}()
+
+	sub := h.hub.Subscribe(stationIDs(r.URL.Query()["station_id"]))
+	defer h.hub.Unsubscribe(sub)
+	logging.FromContext(r.Context()).Debug("ws: client connected", "remote", r.RemoteAddr, "stations", sub.Stations())
+
+	replies := make(chan Message, 1)
+	done, stop := make(chan struct{}), make(chan struct{})
+	defer close(stop)
+	go h.read(conn, sub, replies, done, stop)
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		var msg Message
+		select {
+		case <-done:
+			return
+		case e, ok := <-sub.C():
+			if !ok {
+				deadline := time.Now().Add(writeWait)
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client fell behind"), deadline)
+				logging.FromContext(r.Context()).Warn("ws: client fell behind; disconnected", "remote", r.RemoteAddr)
+				return
+			}
+			msg = Message{Type: TypeReading, Reading: &e.Reading}
+		case msg = <-replies:
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+			continue
+		}
+		if err := conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+			return
+		}
+		if err := conn.WriteJSON(msg); err != nil {
+			logging.FromContext(r.Context()).Debug("ws: write failed", "remote", r.RemoteAddr, "error", err)
+			return
+		}
+	}
+}
+
+// read applies the client's filter changes until the connection fails or
+// closes, then closes done. Replies go to the writer until stop is closed.
+func (h *Handler) read(conn *websocket.Conn, sub *Subscription, replies chan<- Message, done, stop chan struct{}) {
+	defer close(done)
+	reply := func(m Message) bool {
+		select {
+		case replies <- m:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	conn.SetReadLimit(maxClientMessage)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+		var req subscribeRequest
+		m := Message{Type: TypeError}
+		switch err := json.Unmarshal(data, &req); {
+		case err != nil:
+			m.Error = "invalid JSON message"
+		case req.Type != "subscribe":
+			m.Error = fmt.Sprintf("unknown message type %q", req.Type)
+		default:
+			sub.Filter(stationIDs(req.StationIDs))
+			m = Message{Type: TypeSubscribed, StationIDs: sub.Stations()}
+		}
+		if !reply(m) {
+			return
+		}
+	}
+}
+
+// stationIDs splits comma-separated station IDs and drops empty ones.
+func stationIDs(values []string) []string {
+	var ids []string
+	for _, v := range values {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,89 @@
+package live
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+	"github.com/gorilla/websocket"
+)
+
+func TestHandler(t *testing.T) {
+	// dial connects a client to a handler on its own hub.
+	dial := func(t *testing.T, query string) (*Hub, *websocket.Conn) {
+		t.Helper()
+		hub := NewHub(DefaultBuffer)
+		srv := httptest.NewServer(NewHandler(hub))
+		t.Cleanup(srv.Close)
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/readings"+query, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+			t.Fatalf("set deadline: %v", err)
+		}
+		// The subscription is registered once the handler runs.
+		for deadline := time.Now().Add(time.Second); hub.Subscribers() == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+		return hub, conn
+	}
+	read := func(t *testing.T, conn *websocket.Conn) Message {
+		t.Helper()
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return msg
+	}
+
+	t.Run("streams readings of the filtered stations", func(t *testing.T) {
+		hub, conn := dial(t, "?station_id=1,3")
+		temp := 21.5
+		hub.Publish(cloudpico_shared.Telemetry{StationID: "2"})
+		hub.Publish(cloudpico_shared.Telemetry{StationID: "1", Temperature: &temp})
+
+		msg := read(t, conn)
+		if msg.Type != TypeReading || msg.Reading == nil || msg.Reading.StationID != "1" || *msg.Reading.Temperature != temp {
+			t.Errorf("message = %+v; want the station 1 reading", msg)
+		}
+	})
+
+	t.Run("subscribe changes the filter", func(t *testing.T) {
+		hub, conn := dial(t, "")
+		if err := conn.WriteJSON(subscribeRequest{Type: "subscribe", StationIDs: []string{"2"}}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if msg := read(t, conn); msg.Type != TypeSubscribed || len(msg.StationIDs) != 1 || msg.StationIDs[0] != "2" {
+			t.Fatalf("message = %+v; want subscribed to [2]", msg)
+		}
+		hub.Publish(cloudpico_shared.Telemetry{StationID: "1"})
+		hub.Publish(cloudpico_shared.Telemetry{StationID: "2"})
+		if msg := read(t, conn); msg.Reading == nil || msg.Reading.StationID != "2" {
+			t.Errorf("message = %+v; want the station 2 reading", msg)
+		}
+	})
+
+	t.Run("invalid messages get an error", func(t *testing.T) {
+		_, conn := dial(t, "")
+		for _, raw := range []string{`{`, `{"type":"unsubscribe"}`} {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(raw)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if msg := read(t, conn); msg.Type != TypeError || msg.Error == "" {
+				t.Errorf("reply to %s = %+v; want an error", raw, msg)
+			}
+		}
+	})
+
+	t.Run("plain requests are refused", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewHandler(NewHub(1)).ServeHTTP(rec, httptest.NewRequest("GET", "/ws/readings", nil))
+		if rec.Code != 400 {
+			t.Errorf("status = %d; want 400", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package weather
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	cloudpico_shared "cloudpico-shared/types"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/batch"
+	"cloudpico-server/internal/modules/weather/controller"
+	"cloudpico-server/internal/modules/weather/downsampling"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/openapi"
+	"cloudpico-server/internal/utils"
+)
+
+// openAPISpec is the generated OpenAPI specification of the API; regenerate it
+// with go generate ./cmd/openapigen.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI returns the OpenAPI specification of the routes the module serves.
+// Webhooks are left out: their payloads are configured per deployment. So are
+// the WebSocket stream at /ws/readings, which OpenAPI cannot describe, and
+// /graphql, which has a schema of its own (see package graph).
+func OpenAPI() ([]byte, error) {
+	ops := append(controller.Operations(),
+		openapi.Operation{
+			Pattern: "POST /api/v1/readings:batch", ID: "ingestBatch", Tag: "readings",
+			Summary:  "Ingest a backlog of telemetry; rejected items are reported by index",
+			Request:  map[string]any{"application/json": []cloudpico_shared.Telemetry{}},
+			Response: map[string]any{"application/json": batch.Response{}},
+		},
+		openapi.Operation{
+			Pattern: "GET /api/v1/stream", ID: "streamReadings", Tag: "readings",
+			Summary:  "Server-Sent Events: a reading event per stored reading; resume with Last-Event-ID",
+			Query:    []openapi.Param{{Name: "station_id", Description: "Station IDs to follow, repeated or comma-separated; default every station"}, {Name: "last_event_id", Description: "Resume after this event, like the Last-Event-ID header"}},
+			Response: map[string]any{"text/event-stream": ""},
+		},
+		openapi.Operation{
+			Pattern: "GET /api/v1/quotas", ID: "getQuotas", Tag: "stations",
+			Summary:  "Quotas and their current usage; 0 is not enforced",
+			Response: map[string]any{"application/json": types.QuotaStatus{}},
+		},
+		openapi.Operation{
+			Pattern: "PUT /api/v1/stations/{id}/downsampling", ID: "setStationDownsampling", Tag: "stations",
+			Summary:  "Set how long a station keeps raw readings and hourly and daily rollups; null reverts to the server default",
+			Request:  map[string]any{"application/json": downsampling.Request{}},
+			Response: map[string]any{"application/json": types.Station{}},
+		},
+	)
+	return openapi.Spec{
+		Title:       "Cloudpico API",
+		Version:     "1",
+		Description: "Stations and weather readings. Times are RFC 3339; metric values are in metric units unless units=imperial is requested.",
+		Error:       utils.ErrorResponse{},
+		Operations:  ops,
+	}.JSON()
+}
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(openAPISpec); err != nil {
+		logging.FromContext(r.Context()).Error("openapi: write response failed", "error", err)
+	}
+}
+
+// apiDocsTmpl renders Swagger UI for the specification. The UI is loaded from
+// a CDN, which is why it is only served in development.
+var apiDocsTmpl = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>Cloudpico · API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: {{ .SpecURL }}, dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`))
+
+func handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := apiDocsTmpl.Execute(w, struct{ SpecURL string }{"/api/v1/openapi.json"}); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render api docs", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to render api docs")
+	}
+}
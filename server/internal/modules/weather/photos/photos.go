@@ -0,0 +1,194 @@
+// Package photos stores station photos on disk and renders their thumbnails.
+package photos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ThumbSize is the bounding box, in pixels, of generated thumbnails.
+const ThumbSize = 160
+
+// MaxPixels caps the width × height of a photo. A few kilobytes of PNG or GIF
+// can declare an image that takes gigabytes to decode, so the size is checked
+// before decoding.
+const MaxPixels = 25_000_000
+
+var (
+	ErrTooLarge        = errors.New("photo too large")
+	ErrUnsupportedType = errors.New("unsupported photo type (allowed: png, jpeg, gif)")
+)
+
+var extensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+// Store writes photos under Dir/stations and serves Dir at URLPrefix.
+type Store struct {
+	Dir      string
+	MaxBytes int64
+	now      func() time.Time
+}
+
+// URLPrefix is where stored photos are served.
+const URLPrefix = "/uploads/"
+
+func NewStore(dir string, maxBytes int64) *Store {
+	return &Store{Dir: dir, MaxBytes: maxBytes, now: time.Now}
+}
+
+// Save validates r as a png/jpeg/gif image no larger than MaxBytes, writes it
+// together with a JPEG thumbnail, and returns the photo path relative to Dir.
+func (s *Store) Save(stationID string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.MaxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("read photo: %w", err)
+	}
+	if int64(len(data)) > s.MaxBytes {
+		return "", ErrTooLarge
+	}
+	ext, ok := extensions[http.DetectContentType(data)]
+	if !ok {
+		return "", ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return "", fmt.Errorf("%w: %dx%d pixels, at most %d allowed", ErrTooLarge, cfg.Width, cfg.Height, MaxPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedType, err)
+	}
+
+	// A timestamp in the name keeps browser caches from showing a replaced photo.
+	base := fmt.Sprintf("%s-%d", safeName(stationID), s.now().UnixNano())
+	rel := path.Join("stations", base+ext)
+	if err := os.MkdirAll(filepath.Join(s.Dir, "stations"), 0o755); err != nil {
+		return "", fmt.Errorf("create photo dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, filepath.FromSlash(rel)), data, 0o644); err != nil {
+		return "", fmt.Errorf("write photo: %w", err)
+	}
+
+	var thumb bytes.Buffer
+	if err := jpeg.Encode(&thumb, Thumbnail(img, ThumbSize), &jpeg.Options{Quality: 85}); err != nil {
+		return "", fmt.Errorf("encode thumbnail: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, filepath.FromSlash(ThumbPath(rel))), thumb.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("write thumbnail: %w", err)
+	}
+	return rel, nil
+}
+
+// Remove deletes a stored photo and its thumbnail. Missing files are ignored.
+func (s *Store) Remove(rel string) error {
+	if rel == "" {
+		return nil
+	}
+	for _, p := range []string{rel, ThumbPath(rel)} {
+		if err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(p))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves stored files, but no directory listings; mount it at
+// URLPrefix.
+func (s *Store) Handler() http.Handler {
+	return http.StripPrefix(URLPrefix, http.FileServer(filesOnly{http.Dir(s.Dir)}))
+}
+
+// filesOnly is a file system whose directories do not exist, so a file server
+// answers 404 instead of listing every stored photo.
+type filesOnly struct {
+	fs http.FileSystem
+}
+
+func (f filesOnly) Open(name string) (http.File, error) {
+	file, err := f.fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		_ = file.Close()
+		return nil, os.ErrNotExist
+	}
+	return file, nil
+}
+
+// ThumbPath returns the thumbnail path for a stored photo path.
+func ThumbPath(rel string) string {
+	return strings.TrimSuffix(rel, path.Ext(rel)) + "-thumb.jpg"
+}
+
+// URL returns the public URL of a stored path, or "" when rel is empty.
+func URL(rel string) string {
+	if rel == "" {
+		return ""
+	}
+	return URLPrefix + rel
+}
+
+// Thumbnail scales img down to fit a size×size box, averaging source pixels.
+// Images that already fit are returned unchanged.
+func Thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+cr, g+cg, bl+cb, a+ca, n+1
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
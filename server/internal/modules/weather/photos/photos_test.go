@@ -0,0 +1,149 @@
+package photos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader returns the start of a PNG declaring a w×h image, enough for
+// image.DecodeConfig but not for decoding.
+func pngHeader(w, h uint32) []byte {
+	ihdr := binary.BigEndian.AppendUint32([]byte("IHDR"), w)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8-bit RGBA, no interlace
+	out := append([]byte("\x89PNG\r\n\x1a\n"), 0, 0, 0, 13)
+	out = append(out, ihdr...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(ihdr))
+}
+
+func TestStore_Save(t *testing.T) {
+	newStore := func(t *testing.T, maxBytes int64) *Store {
+		s := NewStore(t.TempDir(), maxBytes)
+		s.now = func() time.Time { return time.Unix(0, 42) }
+		return s
+	}
+
+	t.Run("writes photo and thumbnail", func(t *testing.T) {
+		s := newStore(t, 1<<20)
+		rel, err := s.Save("3", bytes.NewReader(pngBytes(t, 400, 200)))
+		if err != nil {
+			t.Fatalf("Save() = %v", err)
+		}
+		if rel != "stations/3-42.png" {
+			t.Errorf("rel = %q; want stations/3-42.png", rel)
+		}
+		f, err := os.Open(filepath.Join(s.Dir, "stations", "3-42-thumb.jpg"))
+		if err != nil {
+			t.Fatalf("open thumbnail: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+		cfg, _, err := image.DecodeConfig(f)
+		if err != nil {
+			t.Fatalf("decode thumbnail: %v", err)
+		}
+		if cfg.Width != ThumbSize || cfg.Height != ThumbSize/2 {
+			t.Errorf("thumbnail = %dx%d; want %dx%d", cfg.Width, cfg.Height, ThumbSize, ThumbSize/2)
+		}
+	})
+
+	t.Run("rejects oversized upload", func(t *testing.T) {
+		s := newStore(t, 10)
+		if _, err := s.Save("3", bytes.NewReader(pngBytes(t, 4, 4))); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Save() = %v; want ErrTooLarge", err)
+		}
+	})
+
+	t.Run("rejects too many pixels before decoding", func(t *testing.T) {
+		s := newStore(t, 1<<20)
+		if _, err := s.Save("3", bytes.NewReader(pngHeader(50000, 50000))); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Save() = %v; want ErrTooLarge", err)
+		}
+	})
+
+	t.Run("rejects non-image", func(t *testing.T) {
+		s := newStore(t, 1<<20)
+		if _, err := s.Save("3", strings.NewReader("<svg></svg>")); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Save() = %v; want ErrUnsupportedType", err)
+		}
+	})
+
+	t.Run("sanitizes station id in file name", func(t *testing.T) {
+		s := newStore(t, 1<<20)
+		rel, err := s.Save("../x", bytes.NewReader(pngBytes(t, 2, 2)))
+		if err != nil {
+			t.Fatalf("Save() = %v", err)
+		}
+		if rel != "stations/___x-42.png" {
+			t.Errorf("rel = %q; want stations/___x-42.png", rel)
+		}
+	})
+}
+
+func TestThumbnail(t *testing.T) {
+	small := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	if got := Thumbnail(small, 160); got != image.Image(small) {
+		t.Error("small image was resized")
+	}
+	tall := image.NewRGBA(image.Rect(0, 0, 100, 400))
+	if b := Thumbnail(tall, 160).Bounds(); b.Dx() != 40 || b.Dy() != 160 {
+		t.Errorf("bounds = %v; want 40x160", b)
+	}
+}
+
+func TestThumbPathAndURL(t *testing.T) {
+	if got := ThumbPath("stations/3-1.png"); got != "stations/3-1-thumb.jpg" {
+		t.Errorf("ThumbPath() = %q", got)
+	}
+	if got := URL(""); got != "" {
+		t.Errorf("URL(\"\") = %q; want empty", got)
+	}
+	if got := URL("stations/3-1.png"); got != "/uploads/stations/3-1.png" {
+		t.Errorf("URL() = %q", got)
+	}
+}
+
+func TestStore_Handler(t *testing.T) {
+	s := NewStore(t.TempDir(), 1<<20)
+	rel, err := s.Save("3", bytes.NewReader(pngBytes(t, 2, 2)))
+	if err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	for path, want := range map[string]int{
+		URL(rel):                http.StatusOK,
+		URLPrefix:               http.StatusNotFound,
+		URLPrefix + "stations/": http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d; want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package quota
+
+import (
+	"net/http"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/utils"
+)
+
+// Handler serves GET /api/v1/quotas.
+type Handler struct {
+	repo *Repository
+}
+
+func NewHandler(repo *Repository) *Handler {
+	return &Handler{repo: repo}
+}
+
+// ServeHTTP returns the quotas and the current usage. A quota of 0 is not
+// enforced.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, err := h.repo.Status(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("quotas: load usage failed", "error", err)
+		utils.WriteError(w, http.StatusInternalServerError, "failed to load quota usage")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, status)
+}
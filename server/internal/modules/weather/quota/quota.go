@@ -0,0 +1,290 @@
+// Package quota enforces the server's storage quotas (see types.Quotas) on
+// the writes that add stations or readings or set retention, and serves the
+// quota status at GET /api/v1/quotas.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// day is the period readings are counted over.
+const day = 24 * time.Hour
+
+// Repository is a WeatherRepository decorator that rejects writes exceeding
+// the quotas with repository.ErrQuotaExceeded. Every ingest path (MQTT,
+// webhooks, batch uploads) and the station API store through it.
+//
+// Readings are counted per UTC day of receipt. A station's count is loaded
+// from the database on its first reading of the day and kept in memory after
+// that, so checking a reading costs no extra query.
+type Repository struct {
+	repository.WeatherRepository
+	quotas types.Quotas
+	now    func() time.Time
+
+	// mu serializes writes, so concurrent readings cannot both take a
+	// station's last reading or the last station.
+	mu     sync.Mutex
+	day    time.Time      // UTC day counts holds
+	counts map[string]int // readings received on day, by station ID
+}
+
+// New wraps repo to enforce quotas.
+func New(repo repository.WeatherRepository, quotas types.Quotas) *Repository {
+	return &Repository{WeatherRepository: repo, quotas: quotas, now: time.Now}
+}
+
+// Quotas returns the enforced quotas.
+func (q *Repository) Quotas() types.Quotas {
+	return q.quotas
+}
+
+// InsertReading stores the reading unless it would create a station past
+// MaxStations or exceed its station's MaxReadingsPerDay.
+func (q *Repository) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	if q.quotas.MaxStations <= 0 && q.quotas.MaxReadingsPerDay <= 0 {
+		return q.WeatherRepository.InsertReading(ctx, stationID, ts, receivedAt, temperature, humidity, pressure)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	b := &batch{q: q}
+	id, err := b.admit(ctx, stationID)
+	if err != nil {
+		return err
+	}
+	if err := q.WeatherRepository.InsertReading(ctx, stationID, ts, receivedAt, temperature, humidity, pressure); err != nil {
+		return err
+	}
+	q.stored(id)
+	return nil
+}
+
+// InsertReadings stores the readings within the quotas; the others get
+// repository.ErrQuotaExceeded at their index in errs. Readings earlier in the
+// batch count against the quotas of later ones.
+func (q *Repository) InsertReadings(ctx context.Context, readings []repository.NewReading) (errs []error, err error) {
+	if q.quotas.MaxStations <= 0 && q.quotas.MaxReadingsPerDay <= 0 {
+		return q.WeatherRepository.InsertReadings(ctx, readings)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	errs = make([]error, len(readings))
+	var (
+		admitted []repository.NewReading
+		index    []int    // readings index of each admitted reading
+		ids      []string // station ID of each admitted reading, "" for new stations
+	)
+	b := &batch{q: q}
+	for i, nr := range readings {
+		id, err := b.admit(ctx, nr.StationID)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		admitted = append(admitted, nr)
+		index = append(index, i)
+		ids = append(ids, id)
+	}
+	if len(admitted) == 0 {
+		return errs, nil
+	}
+	stored, err := q.WeatherRepository.InsertReadings(ctx, admitted)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range index {
+		errs[i] = stored[j]
+		if stored[j] == nil {
+			q.stored(ids[j])
+		}
+	}
+	return errs, nil
+}
+
+// CreateStation creates the station unless it would exceed MaxStations.
+func (q *Repository) CreateStation(ctx context.Context, name string, latitude *float64, longitude *float64) (types.Station, error) {
+	if q.quotas.MaxStations > 0 {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		b := &batch{q: q}
+		id, err := b.resolve(ctx, name)
+		if err != nil {
+			return types.Station{}, err
+		}
+		if id != "" {
+			return types.Station{}, repository.ErrStationExists
+		}
+		if err := b.admitStation(name); err != nil {
+			return types.Station{}, err
+		}
+	}
+	return q.WeatherRepository.CreateStation(ctx, name, latitude, longitude)
+}
+
+// CreateShadowStation creates the shadow station unless a new station would
+// exceed MaxStations. Turning an existing station into a shadow is allowed.
+func (q *Repository) CreateShadowStation(ctx context.Context, primaryID string, name string) (types.Station, error) {
+	if q.quotas.MaxStations > 0 {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		b := &batch{q: q}
+		id, err := b.resolve(ctx, name)
+		if err != nil {
+			return types.Station{}, err
+		}
+		if id == "" {
+			if err := b.admitStation(name); err != nil {
+				return types.Station{}, err
+			}
+		}
+	}
+	return q.WeatherRepository.CreateShadowStation(ctx, primaryID, name)
+}
+
+// SetStationRetention sets the override unless it exceeds MaxRetentionDays.
+// Clearing it is always allowed: the default is checked at startup.
+func (q *Repository) SetStationRetention(ctx context.Context, stationID string, days *int) error {
+	if limit := q.quotas.MaxRetentionDays; limit > 0 && days != nil && (*days == 0 || *days > limit) {
+		return fmt.Errorf("%w: retention must be at most %d days", repository.ErrQuotaExceeded, limit)
+	}
+	return q.WeatherRepository.SetStationRetention(ctx, stationID, days)
+}
+
+// SetStationDownsampling sets the overrides unless the raw retention exceeds
+// MaxRetentionDays; rollups are not limited.
+func (q *Repository) SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error {
+	if limit := q.quotas.MaxRetentionDays; limit > 0 && rawDays != nil && (*rawDays == 0 || *rawDays > limit) {
+		return fmt.Errorf("%w: retention must be at most %d days", repository.ErrQuotaExceeded, limit)
+	}
+	return q.WeatherRepository.SetStationDownsampling(ctx, stationID, rawDays, hourlyDays, dailyDays)
+}
+
+// Status returns the quotas and the current usage: the stations and the
+// readings each received today.
+func (q *Repository) Status(ctx context.Context) (types.QuotaStatus, error) {
+	stations, err := q.GetStations(ctx)
+	if err != nil {
+		return types.QuotaStatus{}, err
+	}
+	today := q.now().UTC().Truncate(day)
+	out := types.QuotaStatus{Quotas: q.quotas, Stations: len(stations), Usage: make([]types.StationQuotaUsage, 0, len(stations))}
+	for _, s := range stations {
+		n, err := q.GetReceivedCount(ctx, s.ID, today, today.Add(day))
+		if err != nil {
+			return types.QuotaStatus{}, fmt.Errorf("station %s: %w", s.ID, err)
+		}
+		out.Usage = append(out.Usage, types.StationQuotaUsage{StationID: s.ID, StationName: s.Name, ReadingsToday: n})
+	}
+	return out, nil
+}
+
+// count returns the readings station id received today. Called with q.mu held.
+func (q *Repository) count(ctx context.Context, id string) (int, error) {
+	today := q.now().UTC().Truncate(day)
+	if !today.Equal(q.day) {
+		q.day, q.counts = today, make(map[string]int)
+	}
+	if n, ok := q.counts[id]; ok {
+		return n, nil
+	}
+	n, err := q.GetReceivedCount(ctx, id, today, today.Add(day))
+	if err != nil {
+		return 0, fmt.Errorf("count readings received today: %w", err)
+	}
+	q.counts[id] = n
+	return n, nil
+}
+
+// stored counts a stored reading of station id, "" for a station the reading
+// created, whose count is loaded on its next reading. Called with q.mu held.
+func (q *Repository) stored(id string) {
+	if n, ok := q.counts[id]; ok && id != "" {
+		q.counts[id] = n + 1
+	}
+}
+
+// batch admits the readings of one write call, counting the readings and
+// stations it admitted against the quotas of the following ones.
+type batch struct {
+	q           *Repository
+	stations    []types.Station // loaded on first use
+	newStations map[string]bool // names of stations the batch creates
+	pending     map[string]int  // readings admitted, by station ID or new name
+}
+
+// admit checks a reading for station (an ID or a name) against the quotas
+// and returns the station's ID, or "" when the reading creates it.
+func (b *batch) admit(ctx context.Context, station string) (string, error) {
+	id, err := b.resolve(ctx, station)
+	if err != nil {
+		return "", err
+	}
+	key := id
+	if id == "" {
+		key = station
+		if !b.newStations[station] {
+			if err := b.admitStation(station); err != nil {
+				return "", err
+			}
+		}
+	}
+	if limit := b.q.quotas.MaxReadingsPerDay; limit > 0 {
+		n := 0
+		if id != "" {
+			if n, err = b.q.count(ctx, id); err != nil {
+				return "", err
+			}
+		}
+		if n+b.pending[key] >= limit {
+			return "", fmt.Errorf("%w: station %s received %d readings today, the daily limit", repository.ErrQuotaExceeded, station, limit)
+		}
+	}
+	if b.pending == nil {
+		b.pending = make(map[string]int)
+	}
+	b.pending[key]++
+	return id, nil
+}
+
+// admitStation checks that a new station named name is within MaxStations.
+func (b *batch) admitStation(name string) error {
+	if limit := b.q.quotas.MaxStations; limit > 0 && len(b.stations)+len(b.newStations) >= limit {
+		return fmt.Errorf("%w: station %s would exceed the limit of %d stations", repository.ErrQuotaExceeded, name, limit)
+	}
+	if b.newStations == nil {
+		b.newStations = make(map[string]bool)
+	}
+	b.newStations[name] = true
+	return nil
+}
+
+// resolve returns the ID of the station with ID or name station, or "" when
+// there is none yet. Numeric IDs are taken as is, as ingest does.
+func (b *batch) resolve(ctx context.Context, station string) (string, error) {
+	if _, err := strconv.Atoi(station); err == nil {
+		return station, nil
+	}
+	if b.stations == nil {
+		stations, err := b.q.GetStations(ctx)
+		if err != nil {
+			return "", fmt.Errorf("load stations: %w", err)
+		}
+		b.stations = stations
+		if b.stations == nil {
+			b.stations = []types.Station{}
+		}
+	}
+	for _, s := range b.stations {
+		if s.Name == station {
+			return s.ID, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,265 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// fakeRepo stores readings by station ID and creates stations for unknown
+// names, like the SQLite repository.
+type fakeRepo struct {
+	repository.WeatherRepository
+	stations  []types.Station
+	received  map[string]int // readings received today, by station ID
+	retention map[string]*int
+	queries   int // GetReceivedCount calls
+}
+
+func newFakeRepo(names ...string) *fakeRepo {
+	r := &fakeRepo{received: map[string]int{}, retention: map[string]*int{}}
+	for _, name := range names {
+		r.station(name)
+	}
+	return r
+}
+
+func (r *fakeRepo) station(name string) string {
+	if _, err := strconv.Atoi(name); err == nil {
+		return name
+	}
+	for _, s := range r.stations {
+		if s.Name == name {
+			return s.ID
+		}
+	}
+	id := strconv.Itoa(len(r.stations) + 1)
+	r.stations = append(r.stations, types.Station{ID: id, Name: name})
+	return id
+}
+
+func (r *fakeRepo) GetStations(ctx context.Context) ([]types.Station, error) {
+	return r.stations, nil
+}
+
+func (r *fakeRepo) GetReceivedCount(ctx context.Context, stationID string, from, to time.Time) (int, error) {
+	r.queries++
+	return r.received[stationID], nil
+}
+
+func (r *fakeRepo) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	r.received[r.station(stationID)]++
+	return nil
+}
+
+func (r *fakeRepo) InsertReadings(ctx context.Context, readings []repository.NewReading) ([]error, error) {
+	for _, nr := range readings {
+		r.received[r.station(nr.StationID)]++
+	}
+	return make([]error, len(readings)), nil
+}
+
+func (r *fakeRepo) CreateStation(ctx context.Context, name string, latitude *float64, longitude *float64) (types.Station, error) {
+	return types.Station{ID: r.station(name), Name: name, Latitude: latitude, Longitude: longitude}, nil
+}
+
+func (r *fakeRepo) CreateShadowStation(ctx context.Context, primaryID string, name string) (types.Station, error) {
+	id := r.station(name)
+	return types.Station{ID: id, Name: name, ShadowOf: primaryID}, nil
+}
+
+func (r *fakeRepo) SetStationRetention(ctx context.Context, stationID string, days *int) error {
+	r.retention[stationID] = days
+	return nil
+}
+
+func (r *fakeRepo) SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error {
+	r.retention[stationID] = rawDays
+	return nil
+}
+
+var now = time.Date(2025, 6, 15, 13, 30, 0, 0, time.UTC)
+
+func newRepository(inner *fakeRepo, quotas types.Quotas) *Repository {
+	q := New(inner, quotas)
+	q.now = func() time.Time { return now }
+	return q
+}
+
+func insert(q *Repository, station string) error {
+	temp := 20.0
+	return q.InsertReading(context.Background(), station, now, now, &temp, nil, nil)
+}
+
+func TestInsertReading_MaxStations(t *testing.T) {
+	q := newRepository(newFakeRepo("garden"), types.Quotas{MaxStations: 2})
+
+	for _, station := range []string{"garden", "1", "attic", "attic"} {
+		if err := insert(q, station); err != nil {
+			t.Fatalf("insert %s: %v", station, err)
+		}
+	}
+	err := insert(q, "garage")
+	if !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Fatalf("insert new station past quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if err := insert(q, "garden"); err != nil {
+		t.Errorf("insert existing station at quota: %v", err)
+	}
+}
+
+func TestInsertReading_MaxReadingsPerDay(t *testing.T) {
+	inner := newFakeRepo("garden")
+	inner.received["1"] = 2
+	q := newRepository(inner, types.Quotas{MaxReadingsPerDay: 3})
+
+	if err := insert(q, "garden"); err != nil {
+		t.Fatalf("insert third reading: %v", err)
+	}
+	if err := insert(q, "1"); !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Fatalf("insert fourth reading: err = %v, want ErrQuotaExceeded", err)
+	}
+	if inner.queries != 1 {
+		t.Errorf("GetReceivedCount calls = %d, want 1 (then counted in memory)", inner.queries)
+	}
+
+	// A new UTC day starts a new count.
+	q.now = func() time.Time { return now.Add(12 * time.Hour) }
+	inner.received["1"] = 0
+	if err := insert(q, "garden"); err != nil {
+		t.Errorf("insert on the next day: %v", err)
+	}
+}
+
+func TestInsertReadings(t *testing.T) {
+	inner := newFakeRepo("garden")
+	inner.received["1"] = 1
+	q := newRepository(inner, types.Quotas{MaxStations: 2, MaxReadingsPerDay: 2})
+
+	readings := []repository.NewReading{
+		{StationID: "garden"}, // 2nd today
+		{StationID: "garden"}, // 3rd: over
+		{StationID: "attic"},  // creates the 2nd station
+		{StationID: "attic"},
+		{StationID: "attic"},  // 3rd: over
+		{StationID: "garage"}, // 3rd station: over
+	}
+	errs, err := q.InsertReadings(context.Background(), readings)
+	if err != nil {
+		t.Fatalf("InsertReadings: %v", err)
+	}
+	var rejected []int
+	for i, err := range errs {
+		if errors.Is(err, repository.ErrQuotaExceeded) {
+			rejected = append(rejected, i)
+		} else if err != nil {
+			t.Errorf("errs[%d] = %v", i, err)
+		}
+	}
+	if want := []int{1, 4, 5}; !reflect.DeepEqual(rejected, want) {
+		t.Errorf("rejected = %v, want %v", rejected, want)
+	}
+	if want := map[string]int{"1": 2, "2": 2}; !reflect.DeepEqual(inner.received, want) {
+		t.Errorf("stored = %v, want %v", inner.received, want)
+	}
+	if err := insert(q, "garden"); !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Errorf("insert after batch: err = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestCreateShadowStation(t *testing.T) {
+	q := newRepository(newFakeRepo("garden", "attic"), types.Quotas{MaxStations: 2})
+
+	if _, err := q.CreateShadowStation(context.Background(), "1", "garage"); !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Errorf("new shadow station past quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := q.CreateShadowStation(context.Background(), "1", "attic"); err != nil {
+		t.Errorf("existing station as shadow: %v", err)
+	}
+}
+
+func TestCreateStation(t *testing.T) {
+	q := newRepository(newFakeRepo("garden", "attic"), types.Quotas{MaxStations: 3})
+
+	if _, err := q.CreateStation(context.Background(), "garage", nil, nil); err != nil {
+		t.Fatalf("CreateStation within quota: %v", err)
+	}
+	if _, err := q.CreateStation(context.Background(), "shed", nil, nil); !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Errorf("CreateStation past quota: err = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := q.CreateStation(context.Background(), "garden", nil, nil); !errors.Is(err, repository.ErrStationExists) {
+		t.Errorf("CreateStation(taken name): err = %v, want ErrStationExists", err)
+	}
+}
+
+func TestSetStationRetention(t *testing.T) {
+	inner := newFakeRepo("garden")
+	q := newRepository(inner, types.Quotas{MaxRetentionDays: 90})
+	forever, ninety, year := 0, 90, 365
+
+	for _, days := range []*int{&forever, &year} {
+		if err := q.SetStationRetention(context.Background(), "1", days); !errors.Is(err, repository.ErrQuotaExceeded) {
+			t.Errorf("SetStationRetention(%d): err = %v, want ErrQuotaExceeded", *days, err)
+		}
+	}
+	for _, days := range []*int{&ninety, nil} {
+		if err := q.SetStationRetention(context.Background(), "1", days); err != nil {
+			t.Errorf("SetStationRetention(%v): %v", days, err)
+		}
+	}
+	if _, ok := inner.retention["1"]; !ok {
+		t.Error("retention not stored")
+	}
+}
+
+func TestSetStationDownsampling(t *testing.T) {
+	inner := newFakeRepo("garden")
+	q := newRepository(inner, types.Quotas{MaxRetentionDays: 90})
+	forever, ninety, year := 0, 90, 365
+
+	if err := q.SetStationDownsampling(context.Background(), "1", &year, nil, nil); !errors.Is(err, repository.ErrQuotaExceeded) {
+		t.Errorf("SetStationDownsampling(raw 365): err = %v, want ErrQuotaExceeded", err)
+	}
+	if err := q.SetStationDownsampling(context.Background(), "1", &ninety, &year, &forever); err != nil {
+		t.Errorf("SetStationDownsampling(raw 90, rollups unlimited): %v", err)
+	}
+	if got := inner.retention["1"]; got == nil || *got != ninety {
+		t.Errorf("raw retention = %v, want 90", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	inner := newFakeRepo("garden", "attic")
+	inner.received["1"] = 120
+	q := newRepository(inner, types.Quotas{MaxStations: 5, MaxReadingsPerDay: 1440})
+
+	rec := httptest.NewRecorder()
+	NewHandler(q).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/quotas", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var got types.QuotaStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := types.QuotaStatus{
+		Quotas:   types.Quotas{MaxStations: 5, MaxReadingsPerDay: 1440},
+		Stations: 2,
+		Usage: []types.StationQuotaUsage{
+			{StationID: "1", StationName: "garden", ReadingsToday: 120},
+			{StationID: "2", StationName: "attic", ReadingsToday: 0},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("status = %+v, want %+v", got, want)
+	}
+}
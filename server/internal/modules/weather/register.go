@@ -1,19 +1,246 @@
 package weather
 
 import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/graphql"
+	"cloudpico-server/internal/httpapi"
+	"cloudpico-server/internal/module"
+	"cloudpico-server/internal/modules/weather/batch"
 	"cloudpico-server/internal/modules/weather/controller"
+	"cloudpico-server/internal/modules/weather/downsampling"
+	"cloudpico-server/internal/modules/weather/graph"
+	"cloudpico-server/internal/modules/weather/health"
+	"cloudpico-server/internal/modules/weather/live"
+	"cloudpico-server/internal/modules/weather/photos"
+	"cloudpico-server/internal/modules/weather/quota"
 	"cloudpico-server/internal/modules/weather/repository"
 	"cloudpico-server/internal/modules/weather/service"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/modules/weather/webhook"
 	"cloudpico-server/internal/mqtt"
-	"database/sql"
-	"net/http"
+	"cloudpico-server/internal/scheduler"
+	"cloudpico-server/internal/utils"
+	cloudpico_shared "cloudpico-shared/types"
 )
 
-func RegisterFeature(mux *http.ServeMux, db *sql.DB, subscriber *mqtt.Subscriber) {
-	weatherRepository := repository.NewRepository(db)
-	weatherService := service.NewService(weatherRepository)
-	weatherService.Register(subscriber)
-	weatherController := controller.NewWeatherController(weatherRepository)
+// rollupLookback is how many past days the rollup jobs recompute on
+// every run, so readings delivered late (e.g. replayed from a gateway outbox)
+// still reach the rollups.
+const rollupLookback = 7 * 24 * time.Hour
+
+// Options configures the weather module.
+type Options struct {
+	// Repository defaults to SQLite for both stations and readings in the
+	// database from module.Deps.
+	Repository repository.WeatherRepository
+	Photos     *photos.Store
+	Cookies    *utils.CookieSigner
+	// MergeWindow, when positive, merges partial readings into the reading
+	// stored in the same bucket (see service.MergeStage).
+	MergeWindow time.Duration
+	// Webhooks are served at POST /api/v1/webhooks/{name}.
+	Webhooks []webhook.Definition
+	// RetentionDays is how many days of raw readings the retention job keeps
+	// for stations without an override; 0 keeps them forever.
+	RetentionDays int
+	// HourlyRetentionDays and DailyRetentionDays are the same for hourly and
+	// daily rollups; see package downsampling.
+	HourlyRetentionDays int
+	DailyRetentionDays  int
+	// Quotas limit the stations and readings ingest and the API may add and
+	// the retention stations may keep; see package quota.
+	Quotas types.Quotas
+	// HealthWeights weigh the components of station health scores; the zero
+	// value uses health.DefaultWeights.
+	HealthWeights health.Weights
+	// APIDocs serves Swagger UI for the OpenAPI specification at /api/docs.
+	APIDocs bool
+	// IngestAcks acknowledges every stored reading carrying a sequence on
+	// service.AckTopic, so gateways can prune their store-and-forward buffers.
+	IngestAcks bool
+	// DiskSpace, when set, refuses ingest while it returns an error (see
+	// service.DiskSpaceStage).
+	DiskSpace func() error
+}
+
+// Module serves stations and readings and ingests telemetry from MQTT and
+// webhooks.
+type Module struct {
+	opts       Options
+	repository repository.WeatherRepository
+	policy     downsampling.Policy
+	service    *service.Service
+	subscriber *mqtt.Subscriber
+	bus        *events.Bus
+	hub        *live.Hub
+	sched      *scheduler.Scheduler
+}
+
+func NewModule(opts Options) *Module {
+	return &Module{opts: opts}
+}
+
+func (m *Module) Name() string { return "weather" }
+
+// Migrations returns nil: the weather tables are part of the core schema.
+func (m *Module) Migrations() fs.FS { return nil }
+
+func (m *Module) RegisterRoutes(mux *http.ServeMux, deps module.Deps) error {
+	policy := downsampling.Policy{
+		Defaults: types.Downsampling{
+			RawDays:    m.opts.RetentionDays,
+			HourlyDays: m.opts.HourlyRetentionDays,
+			DailyDays:  m.opts.DailyRetentionDays,
+		},
+		MaxRawDays: m.opts.Quotas.MaxRetentionDays,
+	}
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("weather: %w", err)
+	}
+	if err := validateQuotas(m.opts.Quotas, m.opts.RetentionDays); err != nil {
+		return err
+	}
+	weatherRepository := m.opts.Repository
+	if weatherRepository == nil {
+		weatherRepository = repository.NewRepository(deps.DB)
+	}
+	quotas := quota.New(weatherRepository, m.opts.Quotas)
+	weatherRepository = downsampling.New(quotas, policy)
+	weatherService := service.NewService(weatherRepository, deps.Bus)
+	if m.opts.DiskSpace != nil {
+		if err := weatherService.Pipeline().InsertAfter("sanitize", service.DiskSpaceStage(m.opts.DiskSpace)); err != nil {
+			return err
+		}
+	}
+	if m.opts.MergeWindow > 0 {
+		if err := weatherService.Pipeline().InsertBefore("persist", service.MergeStage(weatherRepository, m.opts.MergeWindow)); err != nil {
+			return err
+		}
+	}
+	m.repository = weatherRepository
+	m.policy = policy
+	m.service = weatherService
+	m.subscriber = deps.Subscriber
+	m.bus = deps.Bus
+	m.sched = deps.Scheduler
+	weights := m.opts.HealthWeights
+	if weights == (health.Weights{}) {
+		weights = health.DefaultWeights
+	}
+	healthTracker := health.NewTracker(weights)
+	weatherService.Pipeline().Observe(trackHealth(healthTracker))
+	weatherController := controller.NewWeatherController(weatherRepository, m.opts.Photos, m.opts.Cookies, healthTracker)
 	weatherController.RegisterRoutes(mux)
+	mux.Handle("POST /api/v1/webhooks/{name}", webhook.NewHandler(m.opts.Webhooks, weatherService.Ingest, attachSnapshot(weatherRepository)))
+	mux.Handle("POST /api/v1/readings:batch", batch.NewHandler(weatherService.IngestBatch))
+	m.hub = live.NewHub(live.DefaultBuffer)
+	mux.Handle("GET /ws/readings", httpapi.Streaming(live.NewHandler(m.hub)))
+	mux.Handle("GET /api/v1/stream", httpapi.Streaming(live.NewStreamHandler(m.hub)))
+	mux.Handle("GET /api/v1/quotas", quota.NewHandler(quotas))
+	mux.Handle("PUT /api/v1/stations/{id}/downsampling", downsampling.NewHandler(weatherRepository))
+	schema, err := graph.NewSchema(weatherRepository, time.Now)
+	if err != nil {
+		return err
+	}
+	graphqlHandler := graphql.NewHandler(schema)
+	mux.Handle("GET /graphql", graphqlHandler)
+	mux.Handle("POST /graphql", graphqlHandler)
+	mux.HandleFunc("GET /api/v1/openapi.json", handleOpenAPI)
+	if m.opts.APIDocs {
+		mux.HandleFunc("GET /api/docs", handleAPIDocs)
+	}
+	return nil
+}
+
+// attachSnapshot stores the snapshots webhooks map.
+func attachSnapshot(repo repository.WeatherRepository) webhook.AttachFunc {
+	return func(ctx context.Context, s webhook.Snapshot) error {
+		_, err := repo.CreateSnapshot(ctx, s.StationID, s.Start, s.End, s.URL)
+		return err
+	}
+}
+
+// trackHealth feeds ingest outcomes to t: stored readings and messages
+// rejected as invalid. Failures to store a reading say nothing about the
+// station and are left out.
+func trackHealth(t *health.Tracker) service.Observer {
+	return func(tel cloudpico_shared.Telemetry, err error) {
+		switch {
+		case tel.StationID == "":
+		case err == nil:
+			t.Accept(tel)
+		case service.Rejected(err):
+			t.Reject(tel.StationID)
+		}
+	}
+}
+
+// validateQuotas checks the quotas and that the default retention is within
+// the retention ceiling.
+func validateQuotas(q types.Quotas, retentionDays int) error {
+	if q.MaxStations < 0 || q.MaxReadingsPerDay < 0 || q.MaxRetentionDays < 0 {
+		return fmt.Errorf("weather: quotas must not be negative, got %+v", q)
+	}
+	if d := q.MaxRetentionDays; d > 0 {
+		if d < types.MinRetentionDays || d > types.MaxRetentionDays {
+			return fmt.Errorf("weather: retention quota must be between %d and %d days, got %d", types.MinRetentionDays, types.MaxRetentionDays, d)
+		}
+		if retentionDays == 0 || retentionDays > d {
+			return fmt.Errorf("weather: retention must be between 1 and %d days under the retention quota, got %d", d, retentionDays)
+		}
+	}
+	return nil
+}
 
+// StartWorkers subscribes the ingest pipeline to telemetry and the live
+// stream to ingested readings, and registers the hourly rollup jobs, which
+// feed the climate pages and long-range charts, and the daily retention job,
+// which downsamples stations by pruning each resolution past its age.
+func (m *Module) StartWorkers(ctx context.Context) error {
+	m.service.Register(m.subscriber, m.opts.IngestAcks)
+	m.hub.Attach(m.bus)
+	if err := m.sched.Register(scheduler.Job{
+		Name: "retention",
+		Spec: "@daily",
+		Run: func(ctx context.Context) error {
+			n, err := service.PruneReadings(ctx, m.repository, m.policy, time.Now())
+			slog.Debug("retention applied", "readings_deleted", n)
+			return err
+		},
+	}); err != nil {
+		return err
+	}
+	if err := m.sched.Register(scheduler.Job{
+		Name: "hourly-rollup",
+		Spec: "@hourly",
+		Run: func(ctx context.Context) error {
+			n, err := m.repository.RollupHourly(ctx, time.Now().UTC().Add(-rollupLookback))
+			if err != nil {
+				return err
+			}
+			slog.Debug("hourly rollups updated", "hours", n)
+			return nil
+		},
+	}); err != nil {
+		return err
+	}
+	return m.sched.Register(scheduler.Job{
+		Name: "daily-rollup",
+		Spec: "@hourly",
+		Run: func(ctx context.Context) error {
+			n, err := m.repository.RollupDaily(ctx, time.Now().UTC().Add(-rollupLookback))
+			if err != nil {
+				return err
+			}
+			slog.Debug("daily rollups updated", "days", n)
+			return nil
+		},
+	})
 }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+//go:embed sql/get-annotations.sql
+var getAnnotationsSQL string
+
+//go:embed sql/insert-annotation.sql
+var insertAnnotationSQL string
+
+//go:embed sql/delete-annotation.sql
+var deleteAnnotationSQL string
+
+// ErrAnnotationNotFound is returned when an annotation does not exist for the station.
+var ErrAnnotationNotFound = errors.New("annotation not found")
+
+// GetAnnotations returns the station's annotations overlapping [from, to],
+// ordered by start time. A zero bound is open.
+func (r *repositoryImpl) GetAnnotations(ctx context.Context, stationID string, from time.Time, to time.Time) ([]types.Annotation, error) {
+	rows, err := r.db.QueryContext(ctx, getAnnotationsSQL, stationID, timestampBound(from), timestampBound(to))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close annotations rows", "error", err)
+		}
+	}()
+	var out []types.Annotation
+	for rows.Next() {
+		a, err := scanAnnotation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// CreateAnnotation attaches note to [start, end] of a station.
+func (r *repositoryImpl) CreateAnnotation(ctx context.Context, stationID string, start time.Time, end time.Time, note string) (types.Annotation, error) {
+	if _, err := r.GetStation(ctx, stationID); err != nil {
+		return types.Annotation{}, err
+	}
+	return scanAnnotation(r.db.QueryRowContext(ctx, insertAnnotationSQL, stationID,
+		formatTimestamp(start), formatTimestamp(end), note))
+}
+
+// DeleteAnnotation removes an annotation of a station.
+func (r *repositoryImpl) DeleteAnnotation(ctx context.Context, stationID string, annotationID string) error {
+	res, err := r.db.ExecContext(ctx, deleteAnnotationSQL, annotationID, stationID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAnnotationNotFound
+	}
+	return nil
+}
+
+func scanAnnotation(row interface{ Scan(...any) error }) (types.Annotation, error) {
+	var a types.Annotation
+	var start, end, created string
+	if err := row.Scan(&a.ID, &a.StationID, &start, &end, &a.Note, &created); err != nil {
+		return types.Annotation{}, err
+	}
+	var err error
+	if a.Start, err = parseTimestamp(start); err != nil {
+		return types.Annotation{}, err
+	}
+	if a.End, err = parseTimestamp(end); err != nil {
+		return types.Annotation{}, err
+	}
+	if a.CreatedAt, err = parseTimestamp(created); err != nil {
+		return types.Annotation{}, err
+	}
+	return a, nil
+}
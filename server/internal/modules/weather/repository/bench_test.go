@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// benchRowsEnv sets the number of readings the history benchmarks run on,
+// e.g. 10000000; the default keeps go test -bench quick.
+const benchRowsEnv = "CLOUDPICO_BENCH_ROWS"
+
+var benchStart = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// benchInterval is the spacing of the seeded readings.
+const benchInterval = 10 * time.Second
+
+// seedBenchReadings inserts n readings of station 1, benchInterval apart from
+// benchStart, with all metrics.
+const seedBenchReadings = `
+INSERT INTO stations (id, name) VALUES (1, 'bench');
+WITH RECURSIVE seq(i) AS (SELECT 0 UNION ALL SELECT i + 1 FROM seq WHERE i + 1 < ?1)
+INSERT INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa, received_at)
+SELECT 1, (?2 + i * ?3) * 1000,
+  10 + i % 20, 40 + i % 50, 990 + i % 40, NULL
+FROM seq;`
+
+// BenchmarkHistoryQueries runs the range queries behind the readings API and
+// the history page on one station's readings in a database file.
+func BenchmarkHistoryQueries(b *testing.B) {
+	n := 100_000
+	if s := os.Getenv(benchRowsEnv); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n < 1000 {
+			b.Fatalf("%s=%q: want a number of readings >= 1000", benchRowsEnv, s)
+		}
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(b.TempDir(), "bench.db")+"?_journal_mode=WAL&_synchronous=OFF")
+	if err != nil {
+		b.Fatalf("open db: %v", err)
+	}
+	b.Cleanup(func() { _ = db.Close() })
+	if _, err := db.Exec(testSchema); err != nil {
+		b.Fatalf("exec schema: %v", err)
+	}
+	if _, err := db.Exec(seedBenchReadings, n, benchStart.Unix(), int(benchInterval/time.Second)); err != nil {
+		b.Fatalf("seed %d readings: %v", n, err)
+	}
+	if _, err := db.Exec(`ANALYZE`); err != nil {
+		b.Fatalf("analyze: %v", err)
+	}
+	repo := NewRepository(db)
+	end := benchStart.Add(time.Duration(n) * benchInterval)
+	mid := benchStart.Add(time.Duration(n/2) * benchInterval)
+	day := 24 * time.Hour
+
+	for _, bm := range []struct {
+		name string
+		run  func() error
+	}{
+		{"page/newest", func() error {
+			_, err := repo.GetReadingsPage(context.Background(), "1", benchStart, end, ReadingsCursor{}, 100, nil)
+			return err
+		}},
+		{"page/middle", func() error {
+			_, err := repo.GetReadingsPage(context.Background(), "1", benchStart, end, ReadingsCursor{Time: mid}, 100, nil)
+			return err
+		}},
+		{"offset/middle", func() error {
+			_, err := repo.GetReadings(context.Background(), "1", benchStart, end, 100, n/2, nil)
+			return err
+		}},
+		{"count/day", func() error {
+			_, err := repo.GetReadingsCount(context.Background(), "1", mid, mid.Add(day))
+			return err
+		}},
+		{"count/all", func() error {
+			_, err := repo.GetReadingsCount(context.Background(), "1", benchStart, end)
+			return err
+		}},
+		{"aggregate/30d-hourly", func() error {
+			_, err := repo.GetAggregate(context.Background(), "1", mid, mid.Add(30*day), time.Hour)
+			return err
+		}},
+		{"stats/7d", func() error {
+			_, err := repo.GetStats(context.Background(), "1", mid, mid.Add(7*day))
+			return err
+		}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for b.Loop() {
+				if err := bm.run(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
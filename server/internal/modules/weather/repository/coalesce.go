@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloudpico-server/internal/metrics"
+	"cloudpico-server/internal/modules/weather/types"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescedReads counts hot reads answered by an identical read that was
+// already in flight, labeled by method name.
+var coalescedReads = metrics.Default.NewCounterVec(
+	"cloudpico_repository_coalesced_total",
+	"Weather repository reads served by an identical read already in flight.",
+	"query",
+)
+
+// coalescing is a WeatherRepository decorator for the reads every open
+// dashboard or history tab repeats on refresh: callers arriving while an
+// identical read is in flight wait for its result instead of querying again.
+// Other methods go straight to repo.
+type coalescing struct {
+	WeatherRepository
+	group singleflight.Group
+}
+
+// NewCoalescing wraps repo so identical concurrent GetStations and
+// GetReadingsCount calls run one query.
+func NewCoalescing(repo WeatherRepository) WeatherRepository {
+	return &coalescing{WeatherRepository: repo}
+}
+
+// do runs fn once per key among concurrent callers and counts the callers
+// that shared another's result. The shared read runs on a context that is not
+// canceled with the caller that started it, so a caller timing out does not
+// fail the others waiting for it.
+func (c *coalescing) do(query, key string, fn func() (any, error)) (any, error) {
+	ran := false
+	v, err, _ := c.group.Do(query+"\x00"+key, func() (any, error) {
+		ran = true
+		return fn()
+	})
+	if !ran {
+		coalescedReads.Inc(query)
+	}
+	return v, err
+}
+
+// GetStations returns a copy of the shared result, as callers fill in
+// fields such as Uptime.
+func (c *coalescing) GetStations(ctx context.Context) ([]types.Station, error) {
+	v, err := c.do("GetStations", "", func() (any, error) {
+		return c.WeatherRepository.GetStations(context.WithoutCancel(ctx))
+	})
+	stations, _ := v.([]types.Station)
+	return slices.Clone(stations), err
+}
+
+func (c *coalescing) GetReadingsCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error) {
+	key := fmt.Sprintf("%s|%d|%d", stationID, from.UnixNano(), to.UnixNano())
+	v, err := c.do("GetReadingsCount", key, func() (any, error) {
+		return c.WeatherRepository.GetReadingsCount(context.WithoutCancel(ctx), stationID, from, to)
+	})
+	n, _ := v.(int)
+	return n, err
+}
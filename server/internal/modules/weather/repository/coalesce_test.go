@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// blockingStations counts GetStations and GetReadingsCount calls and blocks
+// them until release is closed; other methods are unused.
+type blockingStations struct {
+	WeatherRepository
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingStations) GetStations(ctx context.Context) ([]types.Station, error) {
+	if b.calls.Add(1) == 1 {
+		close(b.started)
+	}
+	<-b.release
+	return []types.Station{{ID: "1"}}, nil
+}
+
+func (b *blockingStations) GetReadingsCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error) {
+	b.calls.Add(1)
+	return len(stationID), nil
+}
+
+func TestCoalescing(t *testing.T) {
+	t.Run("concurrent identical reads run once", func(t *testing.T) {
+		inner := &blockingStations{started: make(chan struct{}), release: make(chan struct{})}
+		repo := NewCoalescing(inner)
+		before := coalescedReads.Value("GetStations")
+
+		const tabs = 20
+		results := make([][]types.Station, tabs)
+		var wg sync.WaitGroup
+		for i := range tabs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = repo.GetStations(context.Background())
+			}()
+			if i == 0 {
+				<-inner.started
+			}
+		}
+		// Give the other callers time to join the read in flight.
+		time.Sleep(50 * time.Millisecond)
+		close(inner.release)
+		wg.Wait()
+
+		if got := inner.calls.Load(); got != 1 {
+			t.Errorf("queries = %d; want 1", got)
+		}
+		if got := coalescedReads.Value("GetStations") - before; got != tabs-1 {
+			t.Errorf("coalesced = %d; want %d", got, tabs-1)
+		}
+		// Every caller gets its own copy to modify.
+		results[0][0].Name = "changed"
+		if results[1][0].Name != "" {
+			t.Error("callers share the returned slice")
+		}
+	})
+
+	t.Run("sequential reads are not coalesced", func(t *testing.T) {
+		inner := &blockingStations{}
+		repo := NewCoalescing(inner)
+		now := time.Now()
+		for range 2 {
+			if n, err := repo.GetReadingsCount(context.Background(), "abc", now, now); err != nil || n != 3 {
+				t.Fatalf("GetReadingsCount() = %d, %v; want 3", n, err)
+			}
+		}
+		if got := inner.calls.Load(); got != 2 {
+			t.Errorf("queries = %d; want 2", got)
+		}
+	})
+}
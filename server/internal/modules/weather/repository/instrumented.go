@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/metrics"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// queryDurations records the duration of every instrumented repository call,
+// labeled by method name.
+var queryDurations = metrics.Default.NewHistogramVec(
+	"cloudpico_repository_query_duration_seconds",
+	"Duration of weather repository queries.",
+	"query",
+	metrics.DefaultDurationBuckets,
+)
+
+// unknownRows marks calls whose result has no row count (inserts and updates).
+const unknownRows = -1
+
+// instrumented is a WeatherRepository decorator that times every call into
+// queryDurations and logs calls taking at least slow at WARN.
+type instrumented struct {
+	repo WeatherRepository
+	slow time.Duration
+}
+
+// NewInstrumented wraps repo so each call is measured. A non-positive slow
+// threshold disables the slow-query log; durations are recorded either way.
+func NewInstrumented(repo WeatherRepository, slow time.Duration) WeatherRepository {
+	return &instrumented{repo: repo, slow: slow}
+}
+
+func (q *instrumented) observe(ctx context.Context, query string, start time.Time, rows int, err error) {
+	d := time.Since(start)
+	queryDurations.ObserveDuration(query, d)
+	if q.slow <= 0 || d < q.slow {
+		return
+	}
+	attrs := []any{"query", query, "duration", d}
+	if rows != unknownRows {
+		attrs = append(attrs, "rows", rows)
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	logging.FromContext(ctx).Warn("slow repository query", attrs...)
+}
+
+// oneRow is the row count of a call returning a single value.
+func oneRow(err error) int {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+func (q *instrumented) GetStations(ctx context.Context) ([]types.Station, error) {
+	start := time.Now()
+	out, err := q.repo.GetStations(ctx)
+	q.observe(ctx, "GetStations", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetStation(ctx context.Context, stationID string) (types.Station, error) {
+	start := time.Now()
+	out, err := q.repo.GetStation(ctx, stationID)
+	q.observe(ctx, "GetStation", start, oneRow(err), err)
+	return out, err
+}
+
+func (q *instrumented) CreateStation(ctx context.Context, name string, latitude *float64, longitude *float64) (types.Station, error) {
+	start := time.Now()
+	out, err := q.repo.CreateStation(ctx, name, latitude, longitude)
+	q.observe(ctx, "CreateStation", start, unknownRows, err)
+	return out, err
+}
+
+func (q *instrumented) CreateShadowStation(ctx context.Context, primaryID string, name string) (types.Station, error) {
+	start := time.Now()
+	out, err := q.repo.CreateShadowStation(ctx, primaryID, name)
+	q.observe(ctx, "CreateShadowStation", start, unknownRows, err)
+	return out, err
+}
+
+func (q *instrumented) SetStationPhoto(ctx context.Context, stationID string, photoPath string) error {
+	start := time.Now()
+	err := q.repo.SetStationPhoto(ctx, stationID, photoPath)
+	q.observe(ctx, "SetStationPhoto", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) SetStationLocation(ctx context.Context, stationID string, latitude *float64, longitude *float64) error {
+	start := time.Now()
+	err := q.repo.SetStationLocation(ctx, stationID, latitude, longitude)
+	q.observe(ctx, "SetStationLocation", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) SetStationVersions(ctx context.Context, stationID string, gatewayVersion string, firmwareVersion string) error {
+	start := time.Now()
+	err := q.repo.SetStationVersions(ctx, stationID, gatewayVersion, firmwareVersion)
+	q.observe(ctx, "SetStationVersions", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) SetStationRetention(ctx context.Context, stationID string, days *int) error {
+	start := time.Now()
+	err := q.repo.SetStationRetention(ctx, stationID, days)
+	q.observe(ctx, "SetStationRetention", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error {
+	start := time.Now()
+	err := q.repo.SetStationDownsampling(ctx, stationID, rawDays, hourlyDays, dailyDays)
+	q.observe(ctx, "SetStationDownsampling", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) SetStationInterval(ctx context.Context, stationID string, seconds int) error {
+	start := time.Now()
+	err := q.repo.SetStationInterval(ctx, stationID, seconds)
+	q.observe(ctx, "SetStationInterval", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) GetAnnotations(ctx context.Context, stationID string, from time.Time, to time.Time) ([]types.Annotation, error) {
+	start := time.Now()
+	out, err := q.repo.GetAnnotations(ctx, stationID, from, to)
+	q.observe(ctx, "GetAnnotations", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) CreateAnnotation(ctx context.Context, stationID string, startTime time.Time, end time.Time, note string) (types.Annotation, error) {
+	start := time.Now()
+	out, err := q.repo.CreateAnnotation(ctx, stationID, startTime, end, note)
+	q.observe(ctx, "CreateAnnotation", start, unknownRows, err)
+	return out, err
+}
+
+func (q *instrumented) DeleteAnnotation(ctx context.Context, stationID string, annotationID string) error {
+	start := time.Now()
+	err := q.repo.DeleteAnnotation(ctx, stationID, annotationID)
+	q.observe(ctx, "DeleteAnnotation", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) GetSnapshots(ctx context.Context, stationID string, from time.Time, to time.Time) ([]types.Snapshot, error) {
+	start := time.Now()
+	out, err := q.repo.GetSnapshots(ctx, stationID, from, to)
+	q.observe(ctx, "GetSnapshots", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) CreateSnapshot(ctx context.Context, stationID string, startTime time.Time, end time.Time, url string) (types.Snapshot, error) {
+	start := time.Now()
+	out, err := q.repo.CreateSnapshot(ctx, stationID, startTime, end, url)
+	q.observe(ctx, "CreateSnapshot", start, unknownRows, err)
+	return out, err
+}
+
+func (q *instrumented) DeleteSnapshot(ctx context.Context, stationID string, snapshotID string) error {
+	start := time.Now()
+	err := q.repo.DeleteSnapshot(ctx, stationID, snapshotID)
+	q.observe(ctx, "DeleteSnapshot", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error) {
+	start := time.Now()
+	out, err := q.repo.GetLatestReadings(ctx, stationID, limit)
+	q.observe(ctx, "GetLatestReadings", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error) {
+	start := time.Now()
+	out, err := q.repo.GetLatestMetrics(ctx, stationID)
+	q.observe(ctx, "GetLatestMetrics", start, oneRow(err), err)
+	return out, err
+}
+
+func (q *instrumented) GetReadings(ctx context.Context, stationID string, from time.Time, to time.Time, limit int, offset int, fields []string) ([]types.Reading, error) {
+	start := time.Now()
+	out, err := q.repo.GetReadings(ctx, stationID, from, to, limit, offset, fields)
+	q.observe(ctx, "GetReadings", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetReadingsPage(ctx context.Context, stationID string, from time.Time, to time.Time, cursor ReadingsCursor, limit int, fields []string) ([]types.Reading, error) {
+	start := time.Now()
+	out, err := q.repo.GetReadingsPage(ctx, stationID, from, to, cursor, limit, fields)
+	q.observe(ctx, "GetReadingsPage", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetReadingsCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.GetReadingsCount(ctx, stationID, from, to)
+	q.observe(ctx, "GetReadingsCount", start, oneRow(err), err)
+	return out, err
+}
+
+func (q *instrumented) GetReceivedCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.GetReceivedCount(ctx, stationID, from, to)
+	q.observe(ctx, "GetReceivedCount", start, oneRow(err), err)
+	return out, err
+}
+
+// EachReading is timed including fn, which usually writes the readings to a
+// client; rows counts the readings passed to fn.
+func (q *instrumented) EachReading(ctx context.Context, stationID string, from time.Time, to time.Time, fn func(types.StoredReading) error) error {
+	start := time.Now()
+	rows := 0
+	err := q.repo.EachReading(ctx, stationID, from, to, func(sr types.StoredReading) error {
+		rows++
+		return fn(sr)
+	})
+	q.observe(ctx, "EachReading", start, rows, err)
+	return err
+}
+
+func (q *instrumented) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	start := time.Now()
+	err := q.repo.InsertReading(ctx, stationID, ts, receivedAt, temperature, humidity, pressure)
+	q.observe(ctx, "InsertReading", start, unknownRows, err)
+	return err
+}
+
+func (q *instrumented) InsertReadings(ctx context.Context, readings []NewReading) ([]error, error) {
+	start := time.Now()
+	errs, err := q.repo.InsertReadings(ctx, readings)
+	q.observe(ctx, "InsertReadings", start, len(readings), err)
+	return errs, err
+}
+
+func (q *instrumented) GetReadingTime(ctx context.Context, stationID string, from time.Time, to time.Time) (time.Time, bool, error) {
+	start := time.Now()
+	out, ok, err := q.repo.GetReadingTime(ctx, stationID, from, to)
+	rows := 0
+	if ok {
+		rows = 1
+	}
+	q.observe(ctx, "GetReadingTime", start, rows, err)
+	return out, ok, err
+}
+
+func (q *instrumented) GetGaps(ctx context.Context, stationID string, from time.Time, to time.Time, threshold time.Duration) ([]types.Gap, error) {
+	start := time.Now()
+	out, err := q.repo.GetGaps(ctx, stationID, from, to, threshold)
+	q.observe(ctx, "GetGaps", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetUptime(ctx context.Context, stationID string, from time.Time, to time.Time, threshold time.Duration) (map[string]float64, error) {
+	start := time.Now()
+	out, err := q.repo.GetUptime(ctx, stationID, from, to, threshold)
+	q.observe(ctx, "GetUptime", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetHistogram(ctx context.Context, stationID string, metric string, from time.Time, to time.Time, bins int) ([]types.HistogramBucket, error) {
+	start := time.Now()
+	out, err := q.repo.GetHistogram(ctx, stationID, metric, from, to, bins)
+	q.observe(ctx, "GetHistogram", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetMetricSeries(ctx context.Context, stationID string, metric string, from time.Time, to time.Time) ([]types.MetricPoint, error) {
+	start := time.Now()
+	out, err := q.repo.GetMetricSeries(ctx, stationID, metric, from, to)
+	q.observe(ctx, "GetMetricSeries", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) GetSummary(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Summary, error) {
+	start := time.Now()
+	out, err := q.repo.GetSummary(ctx, stationID, from, to)
+	q.observe(ctx, "GetSummary", start, oneRow(err), err)
+	return out, err
+}
+
+func (q *instrumented) GetStats(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Stats, error) {
+	start := time.Now()
+	out, err := q.repo.GetStats(ctx, stationID, from, to)
+	q.observe(ctx, "GetStats", start, oneRow(err), err)
+	return out, err
+}
+
+func (q *instrumented) GetAggregate(ctx context.Context, stationID string, from time.Time, to time.Time, bucket time.Duration) ([]types.AggregateBucket, error) {
+	start := time.Now()
+	out, err := q.repo.GetAggregate(ctx, stationID, from, to, bucket)
+	q.observe(ctx, "GetAggregate", start, len(out), err)
+	return out, err
+}
+
+func (q *instrumented) RollupDaily(ctx context.Context, from time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.RollupDaily(ctx, from)
+	q.observe(ctx, "RollupDaily", start, out, err)
+	return out, err
+}
+
+func (q *instrumented) RollupHourly(ctx context.Context, from time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.RollupHourly(ctx, from)
+	q.observe(ctx, "RollupHourly", start, out, err)
+	return out, err
+}
+
+func (q *instrumented) DeleteHourlyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.DeleteHourlyRollupsBefore(ctx, stationID, before)
+	q.observe(ctx, "DeleteHourlyRollupsBefore", start, out, err)
+	return out, err
+}
+
+func (q *instrumented) DeleteDailyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.DeleteDailyRollupsBefore(ctx, stationID, before)
+	q.observe(ctx, "DeleteDailyRollupsBefore", start, out, err)
+	return out, err
+}
+
+func (q *instrumented) DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	start := time.Now()
+	out, err := q.repo.DeleteReadingsBefore(ctx, stationID, before)
+	q.observe(ctx, "DeleteReadingsBefore", start, out, err)
+	return out, err
+}
+
+func (q *instrumented) GetMonthlyClimate(ctx context.Context, stationID string) ([]types.MonthlyClimate, error) {
+	start := time.Now()
+	out, err := q.repo.GetMonthlyClimate(ctx, stationID)
+	q.observe(ctx, "GetMonthlyClimate", start, len(out), err)
+	return out, err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// slowStations delays GetStations and fails InsertReading; other methods are unused.
+type slowStations struct {
+	WeatherRepository
+	delay time.Duration
+}
+
+func (s slowStations) GetStations(ctx context.Context) ([]types.Station, error) {
+	time.Sleep(s.delay)
+	return []types.Station{{ID: "1"}, {ID: "2"}}, nil
+}
+
+func (s slowStations) InsertReading(context.Context, string, time.Time, time.Time, *float64, *float64, *float64) error {
+	time.Sleep(s.delay)
+	return errors.New("disk full")
+}
+
+func TestInstrumented(t *testing.T) {
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	t.Run("logs slow calls with row counts", func(t *testing.T) {
+		logs.Reset()
+		before := queryDurations.Count("GetStations")
+		repo := NewInstrumented(slowStations{delay: 5 * time.Millisecond}, time.Millisecond)
+
+		stations, err := repo.GetStations(context.Background())
+		if err != nil || len(stations) != 2 {
+			t.Fatalf("GetStations() = %v, %v; want the wrapped result", stations, err)
+		}
+		if got := queryDurations.Count("GetStations") - before; got != 1 {
+			t.Errorf("observations = %d; want 1", got)
+		}
+		out := logs.String()
+		for _, want := range []string{"level=WARN", "slow repository query", "query=GetStations", "rows=2", "duration="} {
+			if !strings.Contains(out, want) {
+				t.Errorf("log missing %q; got %q", want, out)
+			}
+		}
+	})
+
+	t.Run("logs errors without row count", func(t *testing.T) {
+		logs.Reset()
+		repo := NewInstrumented(slowStations{delay: 5 * time.Millisecond}, time.Millisecond)
+
+		if err := repo.InsertReading(context.Background(), "1", time.Time{}, time.Time{}, nil, nil, nil); err == nil {
+			t.Fatal("InsertReading() = nil; want the wrapped error")
+		}
+		out := logs.String()
+		if !strings.Contains(out, "query=InsertReading") || !strings.Contains(out, `error="disk full"`) || strings.Contains(out, "rows=") {
+			t.Errorf("log = %q; want InsertReading with error and no rows", out)
+		}
+	})
+
+	t.Run("fast calls and disabled threshold are not logged", func(t *testing.T) {
+		logs.Reset()
+		before := queryDurations.Count("GetStations")
+		if _, err := NewInstrumented(slowStations{}, time.Hour).GetStations(context.Background()); err != nil {
+			t.Fatalf("GetStations: %v", err)
+		}
+		if _, err := NewInstrumented(slowStations{delay: time.Millisecond}, 0).GetStations(context.Background()); err != nil {
+			t.Fatalf("GetStations: %v", err)
+		}
+		if logs.Len() != 0 {
+			t.Errorf("log = %q; want empty", logs.String())
+		}
+		if got := queryDurations.Count("GetStations") - before; got != 2 {
+			t.Errorf("observations = %d; want 2", got)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// LatestCache keeps each station's latest reading, and the latest value of
+// each metric, in memory so the dashboard and the latest-reading endpoints do
+// not query the readings store. Entries
+// are loaded from the store on first use (e.g. after startup) and refreshed
+// by InsertReading, which the ingest pipeline calls for every reading. Other
+// ReadingsStore methods go straight to the store.
+type LatestCache struct {
+	ReadingsStore
+	*stationNames
+
+	mu      sync.RWMutex
+	latest  map[string][]types.Reading     // by station ID
+	metrics map[string]types.LatestMetrics // by station ID
+}
+
+var _ ReadingsStore = (*LatestCache)(nil)
+
+// NewLatestCache wraps readings. stations resolves the station names that
+// telemetry may use instead of IDs.
+func NewLatestCache(readings ReadingsStore, stations StationStore) *LatestCache {
+	return &LatestCache{
+		ReadingsStore: readings,
+		stationNames:  newStationNames(stations),
+		latest:        make(map[string][]types.Reading),
+		metrics:       make(map[string]types.LatestMetrics),
+	}
+}
+
+// GetLatestReadings serves limit 1 from memory; larger limits read the store.
+func (c *LatestCache) GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error) {
+	if limit != 1 {
+		return c.ReadingsStore.GetLatestReadings(ctx, stationID, limit)
+	}
+	c.mu.RLock()
+	cached, ok := c.latest[stationID]
+	c.mu.RUnlock()
+	if ok {
+		return slices.Clone(cached), nil
+	}
+
+	loaded, err := c.ReadingsStore.GetLatestReadings(ctx, stationID, 1)
+	if err != nil {
+		return nil, err
+	}
+	// Stations without readings are not cached, so requests for unknown IDs
+	// cannot grow the map.
+	if len(loaded) == 0 {
+		return loaded, nil
+	}
+	c.mu.Lock()
+	// An insert that completed meanwhile stored a fresher entry.
+	if _, ok := c.latest[stationID]; !ok {
+		c.latest[stationID] = slices.Clone(loaded)
+	}
+	c.mu.Unlock()
+	return loaded, nil
+}
+
+// GetLatestMetrics serves the latest value of each metric from memory.
+func (c *LatestCache) GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error) {
+	c.mu.RLock()
+	cached, ok := c.metrics[stationID]
+	c.mu.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	loaded, err := c.ReadingsStore.GetLatestMetrics(ctx, stationID)
+	if err != nil {
+		return types.LatestMetrics{}, err
+	}
+	// As for readings, only stations that have reported are cached.
+	if loaded.Temperature == nil && loaded.Humidity == nil && loaded.Pressure == nil {
+		return loaded, nil
+	}
+	c.mu.Lock()
+	if _, ok := c.metrics[stationID]; !ok {
+		c.metrics[stationID] = loaded
+	}
+	c.mu.Unlock()
+	return loaded, nil
+}
+
+// InsertReading stores the reading and then caches the station's latest
+// stored reading, which may merge this one into an existing row.
+func (c *LatestCache) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	if err := c.ReadingsStore.InsertReading(ctx, stationID, ts, receivedAt, temperature, humidity, pressure); err != nil {
+		return err
+	}
+	if id, ok := c.resolve(ctx, stationID); ok {
+		c.updateMetrics(id, ts, receivedAt, temperature, humidity, pressure)
+		c.refresh(ctx, id)
+	} else {
+		logging.FromContext(ctx).Warn("latest cache: station not found after insert", "station", stationID)
+	}
+	return nil
+}
+
+// InsertReadings stores the readings and then caches the latest stored
+// reading of each station with a stored reading.
+func (c *LatestCache) InsertReadings(ctx context.Context, readings []NewReading) ([]error, error) {
+	errs, err := c.ReadingsStore.InsertReadings(ctx, readings)
+	if err != nil {
+		return errs, err
+	}
+	refreshed := make(map[string]bool)
+	for i, nr := range readings {
+		if errs[i] != nil {
+			continue
+		}
+		id, ok := c.resolve(ctx, nr.StationID)
+		if !ok {
+			logging.FromContext(ctx).Warn("latest cache: station not found after insert", "station", nr.StationID)
+			continue
+		}
+		c.updateMetrics(id, nr.Time, nr.ReceivedAt, nr.Temperature, nr.Humidity, nr.Pressure)
+		refreshed[id] = true
+	}
+	for id := range refreshed {
+		c.refresh(ctx, id)
+	}
+	return errs, nil
+}
+
+// refresh caches the station's latest stored reading.
+func (c *LatestCache) refresh(ctx context.Context, id string) {
+	latest, err := c.ReadingsStore.GetLatestReadings(ctx, id, 1)
+	if err != nil {
+		// Drop the entry so the next read falls back to the store.
+		logging.FromContext(ctx).Warn("latest cache: refresh failed", "station_id", id, "error", err)
+		c.mu.Lock()
+		delete(c.latest, id)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Concurrent inserts may refresh out of order; never go back in time.
+	if cached := c.latest[id]; len(cached) > 0 && (len(latest) == 0 || latest[0].Time.Before(cached[0].Time)) {
+		return
+	}
+	c.latest[id] = latest
+}
+
+// DeleteReadingsBefore deletes the readings and drops the station's cached
+// entries, which may refer to deleted readings.
+func (c *LatestCache) DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	n, err := c.ReadingsStore.DeleteReadingsBefore(ctx, stationID, before)
+	if n > 0 || err != nil {
+		c.mu.Lock()
+		delete(c.latest, stationID)
+		delete(c.metrics, stationID)
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+// updateMetrics advances the cached metrics of a station that is already
+// cached with the non-nil values of an inserted reading. Stations not cached
+// yet are loaded from the store on their next read.
+func (c *LatestCache) updateMetrics(stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.metrics[stationID]
+	if !ok {
+		return
+	}
+	ra := receivedAt.UTC()
+	for _, u := range []struct {
+		value *float64
+		dst   **types.LatestMetric
+	}{{temperature, &m.Temperature}, {humidity, &m.Humidity}, {pressure, &m.Pressure}} {
+		if u.value == nil || (*u.dst != nil && ts.Before((*u.dst).Time)) {
+			continue
+		}
+		*u.dst = &types.LatestMetric{Value: *u.value, Time: ts.UTC(), ReceivedAt: &ra}
+	}
+	c.metrics[stationID] = m
+}
+
+// stationNames resolves the station names that telemetry may use instead
+// of IDs, for the caches keyed by station ID.
+type stationNames struct {
+	stations StationStore
+
+	mu    sync.RWMutex
+	names map[string]string // station name → ID
+}
+
+func newStationNames(stations StationStore) *stationNames {
+	return &stationNames{stations: stations, names: make(map[string]string)}
+}
+
+// resolve returns the ID of a station given by ID or name.
+func (n *stationNames) resolve(ctx context.Context, stationID string) (string, bool) {
+	if _, err := strconv.Atoi(stationID); err == nil {
+		return stationID, true
+	}
+	n.mu.RLock()
+	id, ok := n.names[stationID]
+	n.mu.RUnlock()
+	if ok {
+		return id, true
+	}
+
+	// The insert may have just created the station, so reload the names.
+	stations, err := n.stations.GetStations(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Warn("station names: load stations failed", "error", err)
+		return "", false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, s := range stations {
+		n.names[s.Name] = s.ID
+	}
+	id, ok = n.names[stationID]
+	return id, ok
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatestCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden')`); err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES (1, unixepoch('2025-01-01T10:00:00Z') * 1000, 10)`); err != nil {
+		t.Fatalf("insert reading: %v", err)
+	}
+	stations := &repositoryImpl{db: db}
+	cache := NewLatestCache(NewSQLiteReadings(db), stations)
+	temp := func(v float64) *float64 { return &v }
+
+	latest, err := cache.GetLatestReadings(context.Background(), "1", 1)
+	if err != nil || len(latest) != 1 || latest[0].Value != 10 {
+		t.Fatalf("GetLatestReadings() = %v, %v; want the stored reading", latest, err)
+	}
+
+	// Rows written behind the cache's back are not seen: reads come from memory.
+	if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES (1, unixepoch('2025-01-01T11:00:00Z') * 1000, 11)`); err != nil {
+		t.Fatalf("insert reading: %v", err)
+	}
+	if latest, _ := cache.GetLatestReadings(context.Background(), "1", 1); latest[0].Value != 10 {
+		t.Errorf("cached value = %v; want 10", latest[0].Value)
+	}
+	if latest, _ := cache.GetLatestReadings(context.Background(), "1", 5); len(latest) != 2 {
+		t.Errorf("GetLatestReadings(limit 5) len = %d; want 2 from the store", len(latest))
+	}
+
+	t.Run("insert refreshes the entry", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		if err := cache.InsertReading(context.Background(), "1", ts, ts, temp(12), nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		// A partial reading at the same time merges into the stored row.
+		if err := cache.InsertReading(context.Background(), "1", ts, ts, nil, temp(40), nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		latest, _ := cache.GetLatestReadings(context.Background(), "1", 1)
+		if !latest[0].Time.Equal(ts) || latest[0].Value != 12 || latest[0].HumidityPct != 40 {
+			t.Errorf("latest = %+v; want merged 12 °C, 40%% at 12:00", latest[0])
+		}
+	})
+
+	t.Run("older insert keeps the latest", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+		if err := cache.InsertReading(context.Background(), "1", ts, ts, temp(9), nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		if latest, _ := cache.GetLatestReadings(context.Background(), "1", 1); latest[0].Value != 12 {
+			t.Errorf("latest value = %v; want 12", latest[0].Value)
+		}
+	})
+
+	t.Run("insert by name", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)
+		if err := cache.InsertReading(context.Background(), "Attic", ts, ts, temp(20), nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		id, ok := cache.resolve(context.Background(), "Attic")
+		if !ok {
+			t.Fatal("resolve(Attic) not found")
+		}
+		cache.mu.RLock()
+		cached := cache.latest[id]
+		cache.mu.RUnlock()
+		if len(cached) != 1 || cached[0].Value != 20 {
+			t.Errorf("cached entry for %s = %v; want the new reading", id, cached)
+		}
+	})
+
+	t.Run("station without readings is not cached", func(t *testing.T) {
+		latest, err := cache.GetLatestReadings(context.Background(), "42", 1)
+		if err != nil || len(latest) != 0 {
+			t.Errorf("GetLatestReadings(42) = %v, %v; want none", latest, err)
+		}
+		cache.mu.RLock()
+		_, ok := cache.latest["42"]
+		cache.mu.RUnlock()
+		if ok {
+			t.Error("unknown station was cached")
+		}
+	})
+	t.Run("metrics advance independently", func(t *testing.T) {
+		before, err := cache.GetLatestMetrics(context.Background(), "1")
+		if err != nil || before.Temperature == nil || before.Temperature.Value != 12 {
+			t.Fatalf("GetLatestMetrics() = %+v, %v; want 12 °C", before, err)
+		}
+		ts := time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC)
+		if err := cache.InsertReading(context.Background(), "1", ts, ts, nil, nil, temp(1005)); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		// An older temperature must not replace the cached one.
+		old := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+		if err := cache.InsertReading(context.Background(), "1", old, old, temp(1), nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		got, _ := cache.GetLatestMetrics(context.Background(), "1")
+		if got.Pressure == nil || got.Pressure.Value != 1005 || !got.Pressure.Time.Equal(ts) {
+			t.Errorf("Pressure = %+v; want 1005 at 14:00", got.Pressure)
+		}
+		if got.Temperature == nil || got.Temperature.Value != before.Temperature.Value {
+			t.Errorf("Temperature = %+v; want unchanged %v", got.Temperature, before.Temperature.Value)
+		}
+		if got.Humidity == nil || got.Humidity.Value != 40 {
+			t.Errorf("Humidity = %+v; want 40", got.Humidity)
+		}
+	})
+
+	t.Run("batch insert refreshes the entry", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC)
+		errs, err := cache.InsertReadings(context.Background(), []NewReading{
+			{StationID: "1", Time: ts, ReceivedAt: ts, Temperature: temp(15)},
+			{StationID: "1", Time: ts.Add(time.Minute), ReceivedAt: ts, Humidity: temp(101)}, // rejected
+		})
+		if err != nil || errs[0] != nil || errs[1] == nil {
+			t.Fatalf("InsertReadings() = %v, %v; want the second reading rejected", errs, err)
+		}
+		if latest, _ := cache.GetLatestReadings(context.Background(), "1", 1); !latest[0].Time.Equal(ts) || latest[0].Value != 15 {
+			t.Errorf("latest = %+v; want 15 °C at 15:00", latest[0])
+		}
+		if got, _ := cache.GetLatestMetrics(context.Background(), "1"); got.Temperature == nil || got.Temperature.Value != 15 || got.Humidity.Value != 40 {
+			t.Errorf("metrics = %+v; want 15 °C and the earlier humidity", got)
+		}
+	})
+
+	t.Run("delete drops the entry", func(t *testing.T) {
+		if _, err := cache.GetLatestMetrics(context.Background(), "1"); err != nil {
+			t.Fatalf("GetLatestMetrics: %v", err)
+		}
+		if _, err := cache.DeleteReadingsBefore(context.Background(), "1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("DeleteReadingsBefore: %v", err)
+		}
+		if latest, err := cache.GetLatestReadings(context.Background(), "1", 1); err != nil || len(latest) != 0 {
+			t.Errorf("GetLatestReadings() = %v, %v; want none after delete", latest, err)
+		}
+		if got, err := cache.GetLatestMetrics(context.Background(), "1"); err != nil || got.Temperature != nil {
+			t.Errorf("GetLatestMetrics() = %+v, %v; want none after delete", got, err)
+		}
+	})
+}
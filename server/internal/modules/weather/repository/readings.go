@@ -0,0 +1,736 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+//go:embed sql/get-latest-reading.sql
+var getLatestReadingSQL string
+
+//go:embed sql/get-latest-metric.sql
+var getLatestMetricSQL string
+
+//go:embed sql/get-readings.sql
+var getReadingsSQL string
+
+//go:embed sql/get-readings-count.sql
+var getReadingsCountSQL string
+
+//go:embed sql/insert-reading.sql
+var insertReadingSQL string
+
+//go:embed sql/get-reading-time.sql
+var getReadingTimeSQL string
+
+//go:embed sql/get-station-id-by-name.sql
+var getStationIDByNameSQL string
+
+//go:embed sql/get-histogram.sql
+var getHistogramSQL string
+
+//go:embed sql/get-metric-series.sql
+var getMetricSeriesSQL string
+
+//go:embed sql/get-gaps.sql
+var getGapsSQL string
+
+//go:embed sql/get-uptime.sql
+var getUptimeSQL string
+
+//go:embed sql/get-summary.sql
+var getSummarySQL string
+
+//go:embed sql/get-readings-older.sql
+var getReadingsOlderSQL string
+
+//go:embed sql/get-readings-newer.sql
+var getReadingsNewerSQL string
+
+//go:embed sql/get-readings-export.sql
+var getReadingsExportSQL string
+
+//go:embed sql/get-received-count.sql
+var getReceivedCountSQL string
+
+//go:embed sql/get-stats.sql
+var getStatsSQL string
+
+//go:embed sql/get-aggregate.sql
+var getAggregateSQL string
+
+//go:embed sql/get-aggregate-hourly.sql
+var getAggregateHourlySQL string
+
+//go:embed sql/delete-readings-before.sql
+var deleteReadingsBeforeSQL string
+
+// metricColumns maps metric names to readings columns. Only these values are
+// ever substituted into SQL text.
+var metricColumns = map[string]string{
+	types.MetricTemperature: "temperature_c",
+	types.MetricHumidity:    "humidity_pct",
+	types.MetricPressure:    "pressure_hpa",
+}
+
+// sqliteReadings is the ReadingsStore over the readings table.
+type sqliteReadings struct {
+	db *sql.DB
+}
+
+func NewSQLiteReadings(db *sql.DB) ReadingsStore {
+	return &sqliteReadings{db: db}
+}
+
+// GetReadingTime returns the timestamp of the earliest reading for the station
+// (ID or name) in [from, to), and false when there is none.
+func (r *sqliteReadings) GetReadingTime(ctx context.Context, stationID string, from time.Time, to time.Time) (time.Time, bool, error) {
+	var ts int64
+	err := r.db.QueryRowContext(ctx, getReadingTimeSQL, stationID,
+		tsMillis(from), tsMillis(to)).Scan(&ts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return tsTime(ts), true, nil
+}
+
+// GetMetricSeries returns the non-null values of metric in [from, to], oldest first.
+func (r *sqliteReadings) GetMetricSeries(ctx context.Context, stationID string, metric string, from time.Time, to time.Time) ([]types.MetricPoint, error) {
+	column, ok := metricColumns[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+	query := strings.ReplaceAll(getMetricSeriesSQL, "{{column}}", column)
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	rows, err := r.db.QueryContext(ctx, query, stationID, fromMs, toMs)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close metric series rows", "error", err)
+		}
+	}()
+	var out []types.MetricPoint
+	for rows.Next() {
+		var p types.MetricPoint
+		var ts int64
+		if err := rows.Scan(&ts, &p.Value); err != nil {
+			return nil, err
+		}
+		p.Time = tsTime(ts)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetReceivedCount returns the number of the station's readings received in
+// [from, to). Readings stored without a receipt time are not counted.
+func (r *sqliteReadings) GetReceivedCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, getReceivedCountSQL, stationID,
+		formatTimestamp(from), formatTimestamp(to)).Scan(&n)
+	return n, err
+}
+
+// EachReading calls fn with each reading of the station in [from, to], oldest
+// first, as the rows are read, so ranges of any size are never held in memory.
+// It stops at the first error fn returns and returns it.
+func (r *sqliteReadings) EachReading(ctx context.Context, stationID string, from time.Time, to time.Time, fn func(types.StoredReading) error) error {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	rows, err := r.db.QueryContext(ctx, getReadingsExportSQL, stationID, fromMs, toMs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close export rows", "error", err)
+		}
+	}()
+	for rows.Next() {
+		var (
+			sr         types.StoredReading
+			ts         int64
+			receivedAt sql.NullString
+		)
+		if err := rows.Scan(&ts, &sr.Temperature, &sr.Humidity, &sr.Pressure, &receivedAt); err != nil {
+			return err
+		}
+		sr.Time = tsTime(ts)
+		if receivedAt.Valid {
+			t, err := parseTimestamp(receivedAt.String)
+			if err != nil {
+				return err
+			}
+			sr.ReceivedAt = &t
+		}
+		if err := fn(sr); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetSummary returns count and min/avg/max per metric over [from, to].
+func (r *sqliteReadings) GetSummary(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Summary, error) {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	out := types.Summary{StationID: stationID, From: from, To: to}
+	var first, last sql.NullInt64
+	err := r.db.QueryRowContext(ctx, getSummarySQL, stationID, fromMs, toMs).Scan(
+		&out.Count,
+		&out.Temperature.Count, &out.Temperature.Min, &out.Temperature.Avg, &out.Temperature.Max,
+		&out.Humidity.Count, &out.Humidity.Min, &out.Humidity.Avg, &out.Humidity.Max,
+		&out.Pressure.Count, &out.Pressure.Min, &out.Pressure.Avg, &out.Pressure.Max,
+		&first, &last,
+	)
+	if err != nil {
+		return types.Summary{}, err
+	}
+	if first.Valid {
+		t := tsTime(first.Int64)
+		out.First = &t
+	}
+	if last.Valid {
+		t := tsTime(last.Int64)
+		out.Last = &t
+	}
+	return out, nil
+}
+
+// GetStats returns count, min/max/avg and standard deviation per metric over
+// [from, to]. Deviations are summed around the mean in SQL rather than from
+// sums of squares, which lose precision for values far from zero such as
+// pressure.
+func (r *sqliteReadings) GetStats(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Stats, error) {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	out := types.Stats{StationID: stationID, From: from, To: to}
+	var squares [3]sql.NullFloat64
+	err := r.db.QueryRowContext(ctx, getStatsSQL, stationID, fromMs, toMs).Scan(
+		&out.Count,
+		&out.Temperature.Count, &out.Temperature.Min, &out.Temperature.Max, &out.Temperature.Avg, &squares[0],
+		&out.Humidity.Count, &out.Humidity.Min, &out.Humidity.Max, &out.Humidity.Avg, &squares[1],
+		&out.Pressure.Count, &out.Pressure.Min, &out.Pressure.Max, &out.Pressure.Avg, &squares[2],
+	)
+	if err != nil {
+		return types.Stats{}, err
+	}
+	for i, m := range []*types.MetricStats{&out.Temperature, &out.Humidity, &out.Pressure} {
+		if m.Count >= 2 && squares[i].Valid {
+			sd := math.Sqrt(squares[i].Float64 / float64(m.Count-1))
+			m.StdDev = &sd
+		}
+	}
+	return out, nil
+}
+
+// GetAggregate returns count and min/avg/max per metric for each bucket of
+// readings in [from, to), oldest first. Buckets are aligned to multiples of
+// bucket since the Unix epoch (UTC hours and days); empty buckets are omitted.
+func (r *sqliteReadings) GetAggregate(ctx context.Context, stationID string, from time.Time, to time.Time, bucket time.Duration) ([]types.AggregateBucket, error) {
+	if bucket < time.Second {
+		return nil, fmt.Errorf("bucket must be >= 1s, got %s", bucket)
+	}
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	query := getAggregateSQL
+	if bucket%time.Hour == 0 {
+		query = getAggregateHourlySQL
+	}
+	rows, err := r.db.QueryContext(ctx, query, int64(bucket/time.Second), stationID, fromMs, toMs)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close aggregate rows", "error", err)
+		}
+	}()
+
+	buckets := []types.AggregateBucket{}
+	for rows.Next() {
+		var start int64
+		var b types.AggregateBucket
+		if err := rows.Scan(
+			&start, &b.Count,
+			&b.Temperature.Count, &b.Temperature.Min, &b.Temperature.Avg, &b.Temperature.Max,
+			&b.Humidity.Count, &b.Humidity.Min, &b.Humidity.Avg, &b.Humidity.Max,
+			&b.Pressure.Count, &b.Pressure.Min, &b.Pressure.Avg, &b.Pressure.Max,
+		); err != nil {
+			return nil, err
+		}
+		b.Start = time.Unix(start, 0).UTC()
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+func (r *sqliteReadings) GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error) {
+	rows, err := r.db.QueryContext(ctx, getLatestReadingSQL, stationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close latest readings rows", "error", err)
+		}
+	}()
+	return scanReadings(rows)
+}
+
+// GetLatestMetrics returns the latest non-null value of each metric, which
+// may come from different readings.
+func (r *sqliteReadings) GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error) {
+	out := types.LatestMetrics{StationID: stationID}
+	for metric, dst := range map[string]**types.LatestMetric{
+		types.MetricTemperature: &out.Temperature,
+		types.MetricHumidity:    &out.Humidity,
+		types.MetricPressure:    &out.Pressure,
+	} {
+		query := strings.ReplaceAll(getLatestMetricSQL, "{{column}}", metricColumns[metric])
+		var m types.LatestMetric
+		var ts int64
+		var receivedAt sql.NullString
+		err := r.db.QueryRowContext(ctx, query, stationID).Scan(&ts, &m.Value, &receivedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return types.LatestMetrics{}, fmt.Errorf("latest %s: %w", metric, err)
+		}
+		m.Time = tsTime(ts)
+		if receivedAt.Valid {
+			ra, err := parseTimestamp(receivedAt.String)
+			if err != nil {
+				return types.LatestMetrics{}, err
+			}
+			m.ReceivedAt = &ra
+		}
+		*dst = &m
+	}
+	return out, nil
+}
+
+// selectMetrics fills the metric placeholders of a readings query with the
+// columns of fields, or of every metric when fields is empty; the other
+// metrics read as 0 without touching their columns.
+func selectMetrics(query string, fields []string) (string, error) {
+	for _, metric := range fields {
+		if _, ok := metricColumns[metric]; !ok {
+			return "", fmt.Errorf("unknown metric %q", metric)
+		}
+	}
+	for metric, column := range metricColumns {
+		expr := "0"
+		if len(fields) == 0 || slices.Contains(fields, metric) {
+			expr = "COALESCE(" + column + ", 0)"
+		}
+		query = strings.ReplaceAll(query, "{{"+metric+"}}", expr)
+	}
+	return query, nil
+}
+
+// GetReadings returns up to limit readings in [from, to] after skipping
+// offset, newest first, with only the metrics in fields (all when empty).
+func (r *sqliteReadings) GetReadings(ctx context.Context, stationID string, from time.Time, to time.Time, limit int, offset int, fields []string) ([]types.Reading, error) {
+	query, err := selectMetrics(getReadingsSQL, fields)
+	if err != nil {
+		return nil, err
+	}
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	rows, err := r.db.QueryContext(ctx, query, stationID, fromMs, toMs, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close readings rows", "error", err)
+		}
+	}()
+	return scanReadings(rows)
+}
+
+// GetReadingsPage returns up to limit readings in [from, to] next to cursor,
+// newest first. Unlike GetReadings with an offset, it seeks on the
+// (station_id, ts) key, so deep pages cost the same as the first one. A
+// cursor outside [from, to] starts over at the matching end of the range.
+// Only the metrics in fields are selected, all when it is empty.
+func (r *sqliteReadings) GetReadingsPage(ctx context.Context, stationID string, from time.Time, to time.Time, cursor ReadingsCursor, limit int, fields []string) ([]types.Reading, error) {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	var query string
+	var args []any
+	if cursor.Newer {
+		lower, bound := ">=", fromMs
+		if !cursor.Time.IsZero() && !cursor.Time.Before(from) {
+			lower, bound = ">", tsMillis(cursor.Time)
+		}
+		query = strings.ReplaceAll(getReadingsNewerSQL, "{{lower}}", lower)
+		args = []any{stationID, bound, toMs, limit}
+	} else {
+		upper, bound := "<=", toMs
+		if !cursor.Time.IsZero() && !cursor.Time.After(to) {
+			upper, bound = "<", tsMillis(cursor.Time)
+		}
+		query = strings.ReplaceAll(getReadingsOlderSQL, "{{upper}}", upper)
+		args = []any{stationID, fromMs, bound, limit}
+	}
+	query, err := selectMetrics(query, fields)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close readings page rows", "error", err)
+		}
+	}()
+	readings, err := scanReadings(rows)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.Newer {
+		slices.Reverse(readings)
+	}
+	return readings, nil
+}
+
+func (r *sqliteReadings) GetReadingsCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error) {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	var n int
+	err := r.db.QueryRowContext(ctx, getReadingsCountSQL, stationID, fromMs, toMs).Scan(&n)
+	return n, err
+}
+
+// GetHistogram returns bins equal-width buckets spanning the min..max of metric
+// over [from, to]. It returns no buckets when there are no values in range.
+func (r *sqliteReadings) GetHistogram(ctx context.Context, stationID string, metric string, from time.Time, to time.Time, bins int) ([]types.HistogramBucket, error) {
+	column, ok := metricColumns[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+	if bins <= 0 {
+		return nil, fmt.Errorf("bins must be > 0, got %d", bins)
+	}
+	query := strings.ReplaceAll(getHistogramSQL, "{{column}}", column)
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	rows, err := r.db.QueryContext(ctx, query, stationID, fromMs, toMs, bins, bins)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close histogram rows", "error", err)
+		}
+	}()
+
+	var buckets []types.HistogramBucket
+	for rows.Next() {
+		var idx, n int
+		var lo, hi float64
+		if err := rows.Scan(&idx, &n, &lo, &hi); err != nil {
+			return nil, err
+		}
+		if buckets == nil {
+			buckets = make([]types.HistogramBucket, bins)
+			width := (hi - lo) / float64(bins)
+			for i := range buckets {
+				buckets[i].Lower = lo + float64(i)*width
+				buckets[i].Upper = lo + float64(i+1)*width
+			}
+			buckets[bins-1].Upper = hi
+		}
+		if idx >= 0 && idx < bins {
+			buckets[idx].Count = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if buckets == nil {
+		buckets = []types.HistogramBucket{}
+	}
+	return buckets, nil
+}
+
+// GetGaps returns the periods in [from, to] longer than threshold without a
+// reading, oldest first. Time before the first and after the last reading in
+// the range counts, so a station with no readings has one gap spanning the range.
+func (r *sqliteReadings) GetGaps(ctx context.Context, stationID string, from time.Time, to time.Time, threshold time.Duration) ([]types.Gap, error) {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	rows, err := r.db.QueryContext(ctx, getGapsSQL, stationID, fromMs, toMs, threshold.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close gaps rows", "error", err)
+		}
+	}()
+
+	gaps := []types.Gap{}
+	for rows.Next() {
+		var startMs, endMs int64
+		if err := rows.Scan(&startMs, &endMs); err != nil {
+			return nil, err
+		}
+		start, end := tsTime(startMs), tsTime(endMs)
+		gaps = append(gaps, types.Gap{Start: start, End: end, Seconds: end.Sub(start).Seconds()})
+	}
+	return gaps, rows.Err()
+}
+
+// GetUptime returns, by station ID, the percentage of [from, to] not covered
+// by gaps longer than threshold (see GetGaps). Stations are counted from their
+// creation and omitted when created after to. An empty stationID selects all
+// stations.
+func (r *sqliteReadings) GetUptime(ctx context.Context, stationID string, from time.Time, to time.Time, threshold time.Duration) (map[string]float64, error) {
+	fromMs := tsMillis(from)
+	toMs := tsMillis(to)
+	rows, err := r.db.QueryContext(ctx, getUptimeSQL, fromMs, toMs, threshold.Seconds(), stationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close uptime rows", "error", err)
+		}
+	}()
+
+	uptime := make(map[string]float64)
+	for rows.Next() {
+		var id string
+		var span, gap float64
+		if err := rows.Scan(&id, &span, &gap); err != nil {
+			return nil, err
+		}
+		if span <= 0 {
+			continue
+		}
+		uptime[id] = max(0, 100*(1-gap/span))
+	}
+	return uptime, rows.Err()
+}
+
+func scanReadings(rows *sql.Rows) ([]types.Reading, error) {
+	var out []types.Reading
+	for rows.Next() {
+		var rec types.Reading
+		var ts int64
+		var receivedAt sql.NullString
+		if err := rows.Scan(&rec.StationID, &ts, &rec.Value, &rec.HumidityPct, &rec.PressureHpa, &receivedAt); err != nil {
+			return nil, err
+		}
+		rec.Time = tsTime(ts)
+		if receivedAt.Valid {
+			ra, err := parseTimestamp(receivedAt.String)
+			if err != nil {
+				return nil, err
+			}
+			rec.ReceivedAt = &ra
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+// tsMillis is t as stored in readings.ts: milliseconds since the Unix epoch,
+// an integer that range bounds and the (station_id, ts) key compare cheaply.
+// Precision below a millisecond is dropped.
+func tsMillis(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+// tsTime is the UTC time of a readings.ts value.
+func tsTime(ms int64) time.Time {
+	return time.UnixMilli(ms).UTC()
+}
+
+// timestampLayout is the storage format of received_at: UTC with exactly nine
+// fractional digits, so that comparing and ordering the text, as the queries
+// do, matches comparing the times.
+const timestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+// formatTimestamp formats t in timestampLayout, for storage and as a bound
+// compared with stored times.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampLayout)
+}
+
+// timestampBound formats an optional range bound: the zero time is the empty
+// string the queries treat as open, any other time as formatTimestamp.
+func timestampBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatTimestamp(t)
+}
+
+// timestampReadLayouts are the formats parseTimestamp accepts: the storage
+// format first, then those of rows written before it (RFC 3339 with any
+// fraction or offset) and SQLite's own datetime().
+var timestampReadLayouts = []string{timestampLayout, time.RFC3339Nano, "2006-01-02 15:04:05.999999999"}
+
+// parseTimestamp parses a stored time in any of timestampReadLayouts and
+// returns it in UTC.
+func parseTimestamp(ts string) (time.Time, error) {
+	for _, layout := range timestampReadLayouts {
+		if t, err := time.Parse(layout, ts); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parse timestamp %q: not in any of the layouts %q", ts, timestampReadLayouts)
+}
+
+// InsertReading stores a reading. A reading at an existing station and
+// timestamp is merged: metrics passed as nil keep their stored values.
+func (r *sqliteReadings) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	return insertReading(ctx, r.db, stationID, ts, receivedAt, temperature, humidity, pressure)
+}
+
+// InsertReadings stores readings in one transaction, each as InsertReading
+// would. A reading that fails is skipped and its error returned at its index
+// in errs; the others are still stored. err reports a failure of the
+// transaction itself, in which case nothing is stored.
+func (r *sqliteReadings) InsertReadings(ctx context.Context, readings []NewReading) (errs []error, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	errs = make([]error, len(readings))
+	for i, nr := range readings {
+		// A savepoint per reading undoes a station created for a reading
+		// that then fails.
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT reading"); err != nil {
+			return nil, err
+		}
+		errs[i] = insertReading(ctx, tx, nr.StationID, nr.Time, nr.ReceivedAt, nr.Temperature, nr.Humidity, nr.Pressure)
+		if errs[i] != nil {
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO reading"); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "RELEASE reading"); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// execQuerier is implemented by *sql.DB and *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertReading(ctx context.Context, q execQuerier, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	tsMs := tsMillis(ts)
+
+	// Resolve station ID - stationID might be a name or an ID string
+	// First try to parse as integer ID, if that fails, look up by name
+	var dbStationID int
+	var err error
+
+	// Try parsing as integer first
+	if parsedID, parseErr := strconv.Atoi(stationID); parseErr == nil {
+		// It's a numeric ID, use it directly
+		dbStationID = parsedID
+	} else {
+		// It's likely a station name, get or create it dynamically
+		// Execute INSERT OR IGNORE first, then SELECT to get the ID
+		_, err = q.ExecContext(ctx, "INSERT OR IGNORE INTO stations (name, metadata) VALUES (?, '{}')", stationID)
+		if err != nil {
+			return fmt.Errorf("create station %q: %w", stationID, err)
+		}
+		// Now get the station ID (whether it was just created or already existed)
+		err = q.QueryRowContext(ctx, getStationIDByNameSQL, stationID).Scan(&dbStationID)
+		if err != nil {
+			return fmt.Errorf("get station ID for %q: %w", stationID, err)
+		}
+		logging.FromContext(ctx).Debug("resolved station", "name", stationID, "id", dbStationID)
+	}
+
+	// Validate humidity range (0-100) if provided
+	if humidity != nil {
+		if *humidity < 0 || *humidity > 100 {
+			return fmt.Errorf("humidity_pct out of range: %f (must be 0-100)", *humidity)
+		}
+	}
+
+	// Validate pressure is positive if provided
+	if pressure != nil {
+		if *pressure <= 0 {
+			return fmt.Errorf("pressure_hpa must be positive: %f", *pressure)
+		}
+	}
+
+	var tempVal interface{}
+	if temperature != nil {
+		tempVal = *temperature
+	}
+
+	var humidityVal interface{}
+	if humidity != nil {
+		humidityVal = *humidity
+	}
+
+	var pressureVal interface{}
+	if pressure != nil {
+		pressureVal = *pressure
+	}
+
+	var receivedAtVal interface{}
+	if !receivedAt.IsZero() {
+		receivedAtVal = formatTimestamp(receivedAt)
+	}
+
+	_, err = q.ExecContext(ctx, insertReadingSQL, dbStationID, tsMs, tempVal, humidityVal, pressureVal, receivedAtVal)
+	if err != nil {
+		return fmt.Errorf("insert reading: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteReadingsBefore deletes the station's readings measured before before
+// and returns the number deleted.
+func (r *sqliteReadings) DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, deleteReadingsBeforeSQL, stationID, tsMillis(before))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
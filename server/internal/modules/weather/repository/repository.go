@@ -1,64 +1,192 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
-	"log/slog"
 	"strconv"
 	"time"
 
+	"cloudpico-server/internal/logging"
 	"cloudpico-server/internal/modules/weather/types"
 )
 
 //go:embed sql/get-stations.sql
 var getStationsSQL string
 
-//go:embed sql/get-latest-reading.sql
-var getLatestReadingSQL string
+//go:embed sql/get-station.sql
+var getStationSQL string
 
-//go:embed sql/get-readings.sql
-var getReadingsSQL string
+//go:embed sql/insert-station.sql
+var insertStationSQL string
 
-//go:embed sql/get-readings-count.sql
-var getReadingsCountSQL string
+//go:embed sql/upsert-shadow-station.sql
+var upsertShadowStationSQL string
 
-//go:embed sql/insert-reading.sql
-var insertReadingSQL string
+//go:embed sql/update-station-photo.sql
+var updateStationPhotoSQL string
 
-//go:embed sql/get-station-id-by-name.sql
-var getStationIDByNameSQL string
+//go:embed sql/update-station-location.sql
+var updateStationLocationSQL string
+
+//go:embed sql/update-station-versions.sql
+var updateStationVersionsSQL string
+
+//go:embed sql/update-station-retention.sql
+var updateStationRetentionSQL string
+
+//go:embed sql/update-station-interval.sql
+var updateStationIntervalSQL string
+
+//go:embed sql/update-station-downsampling.sql
+var updateStationDownsamplingSQL string
+
+// ErrStationNotFound is returned when a station lookup by ID has no match.
+var ErrStationNotFound = errors.New("station not found")
+
+// ErrStationExists is returned when creating a station whose name is taken.
+var ErrStationExists = errors.New("station already exists")
+
+// ErrInvalidShadow is returned when a station cannot shadow the requested
+// primary: it is the primary itself, or the primary is a shadow.
+var ErrInvalidShadow = errors.New("invalid shadow station")
+
+// ErrShadowConflict is returned when linking an existing station as a shadow
+// would move it from another primary, or chain the shadows it has.
+var ErrShadowConflict = errors.New("station is linked to other stations")
+
+// ErrQuotaExceeded is returned when a write would exceed a configured quota;
+// see package quota.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// StationStore holds station metadata and the notes and snapshots attached
+// to stations.
+type StationStore interface {
+	GetStations(ctx context.Context) ([]types.Station, error)
+	GetStation(ctx context.Context, stationID string) (types.Station, error)
+	CreateStation(ctx context.Context, name string, latitude *float64, longitude *float64) (types.Station, error)
+	CreateShadowStation(ctx context.Context, primaryID string, name string) (types.Station, error)
+	SetStationPhoto(ctx context.Context, stationID string, photoPath string) error
+	SetStationLocation(ctx context.Context, stationID string, latitude *float64, longitude *float64) error
+	SetStationVersions(ctx context.Context, stationID string, gatewayVersion string, firmwareVersion string) error
+	SetStationRetention(ctx context.Context, stationID string, days *int) error
+	SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error
+	SetStationInterval(ctx context.Context, stationID string, seconds int) error
+	GetAnnotations(ctx context.Context, stationID string, from time.Time, to time.Time) ([]types.Annotation, error)
+	CreateAnnotation(ctx context.Context, stationID string, start time.Time, end time.Time, note string) (types.Annotation, error)
+	DeleteAnnotation(ctx context.Context, stationID string, annotationID string) error
+	GetSnapshots(ctx context.Context, stationID string, from time.Time, to time.Time) ([]types.Snapshot, error)
+	CreateSnapshot(ctx context.Context, stationID string, start time.Time, end time.Time, url string) (types.Snapshot, error)
+	DeleteSnapshot(ctx context.Context, stationID string, snapshotID string) error
+}
+
+// ReadingsStore holds the readings time series and answers the queries over
+// it. Stations are identified by the IDs StationStore returns; InsertReading
+// also accepts a station name and creates the station on first use.
+type ReadingsStore interface {
+	GetLatestReadings(ctx context.Context, stationID string, limit int) ([]types.Reading, error)
+	GetLatestMetrics(ctx context.Context, stationID string) (types.LatestMetrics, error)
+	GetReadings(ctx context.Context, stationID string, from time.Time, to time.Time, limit int, offset int, fields []string) ([]types.Reading, error)
+	GetReadingsPage(ctx context.Context, stationID string, from time.Time, to time.Time, cursor ReadingsCursor, limit int, fields []string) ([]types.Reading, error)
+	GetReadingsCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error)
+	GetReceivedCount(ctx context.Context, stationID string, from time.Time, to time.Time) (int, error)
+	EachReading(ctx context.Context, stationID string, from time.Time, to time.Time, fn func(types.StoredReading) error) error
+	InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error
+	InsertReadings(ctx context.Context, readings []NewReading) (errs []error, err error)
+	GetReadingTime(ctx context.Context, stationID string, from time.Time, to time.Time) (time.Time, bool, error)
+	GetGaps(ctx context.Context, stationID string, from time.Time, to time.Time, threshold time.Duration) ([]types.Gap, error)
+	GetUptime(ctx context.Context, stationID string, from time.Time, to time.Time, threshold time.Duration) (map[string]float64, error)
+	GetHistogram(ctx context.Context, stationID string, metric string, from time.Time, to time.Time, bins int) ([]types.HistogramBucket, error)
+	GetMetricSeries(ctx context.Context, stationID string, metric string, from time.Time, to time.Time) ([]types.MetricPoint, error)
+	GetSummary(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Summary, error)
+	GetStats(ctx context.Context, stationID string, from time.Time, to time.Time) (types.Stats, error)
+	GetAggregate(ctx context.Context, stationID string, from time.Time, to time.Time, bucket time.Duration) ([]types.AggregateBucket, error)
+	RollupDaily(ctx context.Context, from time.Time) (int, error)
+	RollupHourly(ctx context.Context, from time.Time) (int, error)
+	DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error)
+	DeleteHourlyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error)
+	DeleteDailyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error)
+	GetMonthlyClimate(ctx context.Context, stationID string) ([]types.MonthlyClimate, error)
+}
+
+// ReadingsCursor positions a page of a station's readings, which are listed
+// newest first. The page continues from the reading at Time towards older
+// readings, or with Newer towards newer ones. A zero Time starts at the
+// newest reading, or with Newer at the oldest.
+type ReadingsCursor struct {
+	Time  time.Time
+	Newer bool
+}
+
+// NewReading is one reading for InsertReadings; the fields are the
+// InsertReading arguments.
+type NewReading struct {
+	StationID   string
+	Time        time.Time
+	ReceivedAt  time.Time
+	Temperature *float64
+	Humidity    *float64
+	Pressure    *float64
+}
 
 type WeatherRepository interface {
-	GetStations() ([]types.Station, error)
-	GetLatestReadings(stationID string, limit int) ([]types.Reading, error)
-	GetReadings(stationID string, from time.Time, to time.Time, limit int, offset int) ([]types.Reading, error)
-	GetReadingsCount(stationID string, from time.Time, to time.Time) (int, error)
-	InsertReading(stationID string, ts time.Time, temperature *float64, humidity *float64, pressure *float64) error
+	StationStore
+	ReadingsStore
 }
 
-type repositoryImpl struct {
-	db *sql.DB
+// Readings backends accepted by Open.
+const (
+	BackendSQLite = "sqlite"
+)
+
+// Open returns a repository keeping stations in db and readings in the named
+// backend, with latest readings and recent aggregates cached in memory (see
+// LatestCache and SeriesCache). An empty backend selects SQLite.
+func Open(db *sql.DB, readingsBackend string) (WeatherRepository, error) {
+	stations := &repositoryImpl{db: db}
+	var readings ReadingsStore
+	switch readingsBackend {
+	case "", BackendSQLite:
+		readings = NewSQLiteReadings(db)
+	default:
+		return nil, fmt.Errorf("unknown readings backend %q", readingsBackend)
+	}
+	return New(stations, NewSeriesCache(NewLatestCache(readings, stations), stations)), nil
 }
 
+// New combines a station store and a readings store.
+func New(stations StationStore, readings ReadingsStore) WeatherRepository {
+	return struct {
+		StationStore
+		ReadingsStore
+	}{stations, readings}
+}
+
+// NewRepository returns a repository keeping stations and readings in db.
 func NewRepository(db *sql.DB) WeatherRepository {
-	return &repositoryImpl{db: db}
+	return New(&repositoryImpl{db: db}, NewSQLiteReadings(db))
 }
 
-func (r *repositoryImpl) GetStations() ([]types.Station, error) {
-	rows, err := r.db.Query(getStationsSQL)
+type repositoryImpl struct {
+	db *sql.DB
+}
+
+func (r *repositoryImpl) GetStations(ctx context.Context) ([]types.Station, error) {
+	rows, err := r.db.QueryContext(ctx, getStationsSQL)
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
-			slog.Error("close stations rows", "error", err)
+			logging.FromContext(ctx).Error("close stations rows", "error", err)
 		}
 	}()
 	var out []types.Station
 	for rows.Next() {
 		var s types.Station
-		if err := rows.Scan(&s.ID, &s.Name); err != nil {
+		if err := rows.Scan(&s.ID, &s.Name, &s.ShadowOf, &s.PhotoPath, &s.Latitude, &s.Longitude, &s.GatewayVersion, &s.FirmwareVersion, &s.RetentionDays, &s.HourlyRetentionDays, &s.DailyRetentionDays, &s.IntervalSeconds); err != nil {
 			return nil, err
 		}
 		out = append(out, s)
@@ -66,124 +194,170 @@ func (r *repositoryImpl) GetStations() ([]types.Station, error) {
 	return out, rows.Err()
 }
 
-func (r *repositoryImpl) GetLatestReadings(stationID string, limit int) ([]types.Reading, error) {
-	rows, err := r.db.Query(getLatestReadingSQL, stationID, limit)
-	if err != nil {
-		return nil, err
+func (r *repositoryImpl) GetStation(ctx context.Context, stationID string) (types.Station, error) {
+	var s types.Station
+	err := r.db.QueryRowContext(ctx, getStationSQL, stationID).Scan(&s.ID, &s.Name, &s.ShadowOf, &s.PhotoPath, &s.Latitude, &s.Longitude, &s.GatewayVersion, &s.FirmwareVersion, &s.RetentionDays, &s.HourlyRetentionDays, &s.DailyRetentionDays, &s.IntervalSeconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return types.Station{}, ErrStationNotFound
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.Error("close latest readings rows", "error", err)
-		}
-	}()
-	return scanReadings(rows)
+	return s, err
 }
 
-func (r *repositoryImpl) GetReadings(stationID string, from time.Time, to time.Time, limit int, offset int) ([]types.Reading, error) {
-	fromStr := from.UTC().Format(time.RFC3339Nano)
-	toStr := to.UTC().Format(time.RFC3339Nano)
-	rows, err := r.db.Query(getReadingsSQL, stationID, fromStr, toStr, limit, offset)
+// SetStationPhoto stores the photo path for a station; an empty path clears it.
+func (r *repositoryImpl) SetStationPhoto(ctx context.Context, stationID string, photoPath string) error {
+	res, err := r.db.ExecContext(ctx, updateStationPhotoSQL, photoPath, stationID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.Error("close readings rows", "error", err)
-		}
-	}()
-	return scanReadings(rows)
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStationNotFound
+	}
+	return nil
 }
 
-func (r *repositoryImpl) GetReadingsCount(stationID string, from time.Time, to time.Time) (int, error) {
-	fromStr := from.UTC().Format(time.RFC3339Nano)
-	toStr := to.UTC().Format(time.RFC3339Nano)
-	var n int
-	err := r.db.QueryRow(getReadingsCountSQL, stationID, fromStr, toStr).Scan(&n)
-	return n, err
+// SetStationLocation stores a station's coordinates; nil values clear them.
+func (r *repositoryImpl) SetStationLocation(ctx context.Context, stationID string, latitude *float64, longitude *float64) error {
+	res, err := r.db.ExecContext(ctx, updateStationLocationSQL, latitude, longitude, stationID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStationNotFound
+	}
+	return nil
 }
 
-func scanReadings(rows *sql.Rows) ([]types.Reading, error) {
-	var out []types.Reading
-	for rows.Next() {
-		var rec types.Reading
-		var ts string
-		if err := rows.Scan(&rec.StationID, &ts, &rec.Value, &rec.HumidityPct, &rec.PressureHpa); err != nil {
-			return nil, err
-		}
-		t, err := time.Parse(time.RFC3339Nano, ts)
-		if err != nil {
-			var err2 error
-			t, err2 = time.Parse(time.RFC3339, ts)
-			if err2 != nil {
-				return nil, fmt.Errorf("parse timestamp %q: RFC3339Nano: %w; RFC3339: %w", ts, err, err2)
-			}
-		}
-		rec.Time = t
-		out = append(out, rec)
+// SetStationVersions records the software versions last reported by a station.
+// stationID may be an ID or a name, as in telemetry; empty versions leave the
+// stored value unchanged.
+func (r *repositoryImpl) SetStationVersions(ctx context.Context, stationID string, gatewayVersion string, firmwareVersion string) error {
+	res, err := r.db.ExecContext(ctx, updateStationVersionsSQL, gatewayVersion, firmwareVersion, stationID)
+	if err != nil {
+		return err
 	}
-	return out, rows.Err()
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStationNotFound
+	}
+	return nil
 }
 
-func (r *repositoryImpl) InsertReading(stationID string, ts time.Time, temperature *float64, humidity *float64, pressure *float64) error {
-	tsStr := ts.UTC().Format(time.RFC3339Nano)
-	
-	// Resolve station ID - stationID might be a name or an ID string
-	// First try to parse as integer ID, if that fails, look up by name
-	var dbStationID int
-	var err error
-	
-	// Try parsing as integer first
-	if parsedID, parseErr := strconv.Atoi(stationID); parseErr == nil {
-		// It's a numeric ID, use it directly
-		dbStationID = parsedID
-	} else {
-		// It's likely a station name, get or create it dynamically
-		// Execute INSERT OR IGNORE first, then SELECT to get the ID
-		_, err = r.db.Exec("INSERT OR IGNORE INTO stations (name, metadata) VALUES (?, '{}')", stationID)
-		if err != nil {
-			return fmt.Errorf("create station %q: %w", stationID, err)
-		}
-		// Now get the station ID (whether it was just created or already existed)
-		err = r.db.QueryRow(getStationIDByNameSQL, stationID).Scan(&dbStationID)
-		if err != nil {
-			return fmt.Errorf("get station ID for %q: %w", stationID, err)
-		}
-		slog.Debug("resolved station", "name", stationID, "id", dbStationID)
+// SetStationRetention stores a station's raw readings retention in days; nil
+// clears the override.
+func (r *repositoryImpl) SetStationRetention(ctx context.Context, stationID string, days *int) error {
+	res, err := r.db.ExecContext(ctx, updateStationRetentionSQL, days, stationID)
+	if err != nil {
+		return err
 	}
-	
-	// Validate humidity range (0-100) if provided
-	if humidity != nil {
-		if *humidity < 0 || *humidity > 100 {
-			return fmt.Errorf("humidity_pct out of range: %f (must be 0-100)", *humidity)
-		}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
 	}
-	
-	// Validate pressure is positive if provided
-	if pressure != nil {
-		if *pressure <= 0 {
-			return fmt.Errorf("pressure_hpa must be positive: %f", *pressure)
-		}
+	if n == 0 {
+		return ErrStationNotFound
 	}
-	
-	var tempVal interface{}
-	if temperature != nil {
-		tempVal = *temperature
+	return nil
+}
+
+// SetStationDownsampling stores a station's retention overrides, in days, for
+// raw readings and hourly and daily rollups; nil clears an override.
+func (r *repositoryImpl) SetStationDownsampling(ctx context.Context, stationID string, rawDays *int, hourlyDays *int, dailyDays *int) error {
+	res, err := r.db.ExecContext(ctx, updateStationDownsamplingSQL, rawDays, hourlyDays, dailyDays, stationID)
+	if err != nil {
+		return err
 	}
-	
-	var humidityVal interface{}
-	if humidity != nil {
-		humidityVal = *humidity
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
 	}
-	
-	var pressureVal interface{}
-	if pressure != nil {
-		pressureVal = *pressure
+	if n == 0 {
+		return ErrStationNotFound
 	}
-	
-	_, err = r.db.Exec(insertReadingSQL, dbStationID, tsStr, tempVal, humidityVal, pressureVal)
+	return nil
+}
+
+// SetStationInterval records the interval between readings, in seconds,
+// last reported by a station. stationID may be an ID or a name, as in
+// telemetry.
+func (r *repositoryImpl) SetStationInterval(ctx context.Context, stationID string, seconds int) error {
+	res, err := r.db.ExecContext(ctx, updateStationIntervalSQL, seconds, stationID)
 	if err != nil {
-		return fmt.Errorf("insert reading: %w", err)
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStationNotFound
 	}
-	
 	return nil
 }
+
+// CreateStation registers a station named name, optionally with its location,
+// before it reports. It returns ErrStationExists when the name is taken.
+func (r *repositoryImpl) CreateStation(ctx context.Context, name string, latitude *float64, longitude *float64) (types.Station, error) {
+	res, err := r.db.ExecContext(ctx, insertStationSQL, name, latitude, longitude)
+	if err != nil {
+		return types.Station{}, fmt.Errorf("insert station %q: %w", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return types.Station{}, err
+	}
+	if n == 0 {
+		return types.Station{}, ErrStationExists
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return types.Station{}, err
+	}
+	return r.GetStation(ctx, strconv.FormatInt(id, 10))
+}
+
+// CreateShadowStation registers name as a shadow of primaryID. If a station with
+// that name already exists (e.g. the new sensor is already reporting), it is linked instead,
+// unless it shadows another primary or has shadows itself (ErrShadowConflict).
+func (r *repositoryImpl) CreateShadowStation(ctx context.Context, primaryID string, name string) (types.Station, error) {
+	primary, err := r.GetStation(ctx, primaryID)
+	if err != nil {
+		return types.Station{}, err
+	}
+	if primary.ShadowOf != "" {
+		return types.Station{}, fmt.Errorf("%w: station %s is itself a shadow of %s", ErrInvalidShadow, primary.ID, primary.ShadowOf)
+	}
+	if name == primary.Name {
+		return types.Station{}, fmt.Errorf("%w: a station cannot shadow itself", ErrInvalidShadow)
+	}
+	res, err := r.db.ExecContext(ctx, upsertShadowStationSQL, name, primary.ID)
+	if err != nil {
+		return types.Station{}, fmt.Errorf("upsert shadow station %q: %w", name, err)
+	}
+	linked, err := res.RowsAffected()
+	if err != nil {
+		return types.Station{}, err
+	}
+	var id int
+	if err := r.db.QueryRowContext(ctx, getStationIDByNameSQL, name).Scan(&id); err != nil {
+		return types.Station{}, fmt.Errorf("get station ID for %q: %w", name, err)
+	}
+	station, err := r.GetStation(ctx, strconv.Itoa(id))
+	if err != nil || linked > 0 {
+		return station, err
+	}
+	if station.ShadowOf != "" {
+		return types.Station{}, fmt.Errorf("%w: station %q already shadows station %s", ErrShadowConflict, name, station.ShadowOf)
+	}
+	return types.Station{}, fmt.Errorf("%w: station %q has shadow stations", ErrShadowConflict, name)
+}
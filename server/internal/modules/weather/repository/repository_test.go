@@ -1,35 +1,112 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"cloudpico-server/internal/modules/weather/types"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// Minimal schema matching tools/migrate/sql/0001_schema.sql for in-memory tests.
+// Minimal schema matching tools/migrate/sql migrations for in-memory tests.
 const testSchema = `
 CREATE TABLE IF NOT EXISTS stations (
   id         INTEGER PRIMARY KEY,
   name       TEXT    NOT NULL,
   created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
-  metadata   TEXT
+  metadata   TEXT,
+  shadow_of  INTEGER REFERENCES stations(id) ON DELETE SET NULL,
+  photo_path TEXT,
+  latitude   REAL,
+  longitude  REAL,
+  gateway_version  TEXT,
+  firmware_version TEXT,
+  archived_at      TEXT,
+  retention_days   INTEGER,
+  interval_seconds INTEGER,
+  hourly_retention_days INTEGER,
+  daily_retention_days  INTEGER
 );
 CREATE UNIQUE INDEX IF NOT EXISTS idx_stations_name ON stations(name);
 
 CREATE TABLE IF NOT EXISTS readings (
   station_id      INTEGER NOT NULL,
-  ts              TEXT    NOT NULL,
+  ts              INTEGER NOT NULL,
   temperature_c   REAL,
   humidity_pct    REAL,
   pressure_hpa    REAL,
+  received_at     TEXT,
   PRIMARY KEY (station_id, ts),
   FOREIGN KEY (station_id) REFERENCES stations(id) ON UPDATE CASCADE ON DELETE CASCADE
 );
 CREATE INDEX IF NOT EXISTS idx_readings_station_ts ON readings(station_id, ts);
 CREATE INDEX IF NOT EXISTS idx_readings_ts ON readings(ts);
+
+CREATE TABLE IF NOT EXISTS annotations (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  start_ts   TEXT    NOT NULL,
+  end_ts     TEXT    NOT NULL,
+  note       TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (end_ts >= start_ts)
+);
+
+CREATE TABLE IF NOT EXISTS snapshots (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  start_ts   TEXT    NOT NULL,
+  end_ts     TEXT    NOT NULL,
+  url        TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (end_ts >= start_ts)
+);
+
+CREATE TABLE IF NOT EXISTS daily_rollups (
+  station_id        INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  day               TEXT    NOT NULL,
+  readings          INTEGER NOT NULL,
+  temperature_count INTEGER NOT NULL,
+  temperature_min   REAL,
+  temperature_avg   REAL,
+  temperature_max   REAL,
+  humidity_count    INTEGER NOT NULL,
+  humidity_min      REAL,
+  humidity_avg      REAL,
+  humidity_max      REAL,
+  pressure_count    INTEGER NOT NULL,
+  pressure_min      REAL,
+  pressure_avg      REAL,
+  pressure_max      REAL,
+  PRIMARY KEY (station_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS hourly_rollups (
+  station_id        INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  hour              INTEGER NOT NULL,
+  readings          INTEGER NOT NULL,
+  temperature_count INTEGER NOT NULL,
+  temperature_min   REAL,
+  temperature_avg   REAL,
+  temperature_max   REAL,
+  humidity_count    INTEGER NOT NULL,
+  humidity_min      REAL,
+  humidity_avg      REAL,
+  humidity_max      REAL,
+  pressure_count    INTEGER NOT NULL,
+  pressure_min      REAL,
+  pressure_avg      REAL,
+  pressure_max      REAL,
+  PRIMARY KEY (station_id, hour)
+);
 `
 
 func setupTestDB(t *testing.T) *sql.DB {
@@ -69,7 +146,7 @@ func TestGetStations_Empty(t *testing.T) {
 	}()
 	repo := NewRepository(db)
 
-	stations, err := repo.GetStations()
+	stations, err := repo.GetStations(context.Background())
 	if err != nil {
 		t.Fatalf("GetStations: %v", err)
 	}
@@ -91,7 +168,7 @@ func TestGetStations_WithData(t *testing.T) {
 	}
 	repo := NewRepository(db)
 
-	stations, err := repo.GetStations()
+	stations, err := repo.GetStations(context.Background())
 	if err != nil {
 		t.Fatalf("GetStations: %v", err)
 	}
@@ -107,6 +184,31 @@ func TestGetStations_WithData(t *testing.T) {
 	}
 }
 
+func TestGetStations_SkipsArchived(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name, archived_at) VALUES (1, 'Alpha', NULL), (2, 'Beta', '2025-03-01T00:00:00Z')`)
+	if err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	repo := NewRepository(db)
+
+	stations, err := repo.GetStations(context.Background())
+	if err != nil {
+		t.Fatalf("GetStations: %v", err)
+	}
+	if len(stations) != 1 || stations[0].ID != "1" {
+		t.Errorf("GetStations() = %+v; want only station 1", stations)
+	}
+	if _, err := repo.GetStation(context.Background(), "2"); err != nil {
+		t.Errorf("GetStation(archived) = %v; want nil", err)
+	}
+}
+
 func TestGetLatestReadings_Empty(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
@@ -120,7 +222,7 @@ func TestGetLatestReadings_Empty(t *testing.T) {
 	}
 	repo := NewRepository(db)
 
-	readings, err := repo.GetLatestReadings("1", 100)
+	readings, err := repo.GetLatestReadings(context.Background(), "1", 100)
 	if err != nil {
 		t.Fatalf("GetLatestReadings: %v", err)
 	}
@@ -142,16 +244,16 @@ func TestGetLatestReadings_WithData(t *testing.T) {
 	}
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c) VALUES
-		(1, '2025-02-01T12:00:00Z', 10.0),
-		(1, '2025-02-01T13:00:00Z', 11.5),
-		(1, '2025-02-01T14:00:00Z', 12.0)
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 11.5),
+		(1, unixepoch('2025-02-01T14:00:00Z') * 1000, 12.0)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
 	}
 	repo := NewRepository(db)
 
-	readings, err := repo.GetLatestReadings("1", 100)
+	readings, err := repo.GetLatestReadings(context.Background(), "1", 100)
 	if err != nil {
 		t.Fatalf("GetLatestReadings: %v", err)
 	}
@@ -182,18 +284,18 @@ func TestGetLatestReadings_RespectsLimit(t *testing.T) {
 	}
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c) VALUES
-		(1, '2025-02-01T12:00:00Z', 10.0),
-		(1, '2025-02-01T13:00:00Z', 11.5),
-		(1, '2025-02-01T14:00:00Z', 12.0),
-		(1, '2025-02-01T15:00:00Z', 13.0),
-		(1, '2025-02-01T16:00:00Z', 14.0)
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 11.5),
+		(1, unixepoch('2025-02-01T14:00:00Z') * 1000, 12.0),
+		(1, unixepoch('2025-02-01T15:00:00Z') * 1000, 13.0),
+		(1, unixepoch('2025-02-01T16:00:00Z') * 1000, 14.0)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
 	}
 	repo := NewRepository(db)
 
-	readings, err := repo.GetLatestReadings("1", 2)
+	readings, err := repo.GetLatestReadings(context.Background(), "1", 2)
 	if err != nil {
 		t.Fatalf("GetLatestReadings: %v", err)
 	}
@@ -215,7 +317,7 @@ func TestGetLatestReadings_UnknownStation(t *testing.T) {
 	}()
 	repo := NewRepository(db)
 
-	readings, err := repo.GetLatestReadings("999", 100)
+	readings, err := repo.GetLatestReadings(context.Background(), "999", 100)
 	if err != nil {
 		t.Fatalf("GetLatestReadings: %v", err)
 	}
@@ -239,7 +341,7 @@ func TestGetReadings_EmptyRange(t *testing.T) {
 
 	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
-	readings, err := repo.GetReadings("1", from, to, 10, 0)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 10, 0, nil)
 	if err != nil {
 		t.Fatalf("GetReadings: %v", err)
 	}
@@ -261,11 +363,11 @@ func TestGetReadings_WithData(t *testing.T) {
 	}
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c) VALUES
-		(1, '2025-02-01T10:00:00Z', 8.0),
-		(1, '2025-02-01T11:00:00Z', 9.0),
-		(1, '2025-02-01T12:00:00Z', 10.0),
-		(1, '2025-02-01T13:00:00Z', 11.0),
-		(1, '2025-02-01T14:00:00Z', 12.0)
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 8.0),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 9.0),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 11.0),
+		(1, unixepoch('2025-02-01T14:00:00Z') * 1000, 12.0)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
@@ -274,7 +376,7 @@ func TestGetReadings_WithData(t *testing.T) {
 
 	from := time.Date(2025, 2, 1, 11, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 2, 1, 13, 59, 59, 0, time.UTC)
-	readings, err := repo.GetReadings("1", from, to, 10, 0)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 10, 0, nil)
 	if err != nil {
 		t.Fatalf("GetReadings: %v", err)
 	}
@@ -301,10 +403,10 @@ func TestGetReadings_HumidityAndPressure(t *testing.T) {
 	// Insert readings with mixed humidity/pressure: set values and NULLs (COALESCE → 0)
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa) VALUES
-		(1, '2025-02-01T10:00:00Z', 8.0, 65.0, 1013.25),
-		(1, '2025-02-01T11:00:00Z', 9.0, NULL, 1012.0),
-		(1, '2025-02-01T12:00:00Z', 10.0, 70.5, NULL),
-		(1, '2025-02-01T13:00:00Z', 11.0, NULL, NULL)
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 8.0, 65.0, 1013.25),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 9.0, NULL, 1012.0),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 10.0, 70.5, NULL),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 11.0, NULL, NULL)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
@@ -313,7 +415,7 @@ func TestGetReadings_HumidityAndPressure(t *testing.T) {
 
 	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
-	readings, err := repo.GetReadings("1", from, to, 10, 0)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 10, 0, nil)
 	if err != nil {
 		t.Fatalf("GetReadings: %v", err)
 	}
@@ -343,6 +445,45 @@ func TestGetReadings_HumidityAndPressure(t *testing.T) {
 	}
 }
 
+func TestGetReadings_Fields(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 8.0, 65.0, 1013.25)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 10, 0, []string{types.MetricHumidity})
+	if err != nil {
+		t.Fatalf("GetReadings: %v", err)
+	}
+	if len(readings) != 1 || readings[0].Value != 0 || readings[0].HumidityPct != 65.0 || readings[0].PressureHpa != 0 {
+		t.Errorf("GetReadings(humidity) = %+v; want humidity 65 only", readings)
+	}
+	page, err := repo.GetReadingsPage(context.Background(), "1", from, to, ReadingsCursor{}, 10, []string{types.MetricTemperature, types.MetricPressure})
+	if err != nil {
+		t.Fatalf("GetReadingsPage: %v", err)
+	}
+	if len(page) != 1 || page[0].Value != 8.0 || page[0].HumidityPct != 0 || page[0].PressureHpa != 1013.25 {
+		t.Errorf("GetReadingsPage(temperature, pressure) = %+v; want temperature 8 and pressure 1013.25", page)
+	}
+	if _, err := repo.GetReadings(context.Background(), "1", from, to, 10, 0, []string{"wind"}); err == nil {
+		t.Error("GetReadings(unknown field): want error")
+	}
+}
+
 func TestGetReadings_RespectsLimit(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
@@ -356,9 +497,9 @@ func TestGetReadings_RespectsLimit(t *testing.T) {
 	}
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c) VALUES
-		(1, '2025-02-01T10:00:00Z', 10.0),
-		(1, '2025-02-01T11:00:00Z', 11.0),
-		(1, '2025-02-01T12:00:00Z', 12.0)
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 11.0),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
@@ -367,7 +508,7 @@ func TestGetReadings_RespectsLimit(t *testing.T) {
 
 	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
-	readings, err := repo.GetReadings("1", from, to, 2, 0)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 2, 0, nil)
 	if err != nil {
 		t.Fatalf("GetReadings: %v", err)
 	}
@@ -393,10 +534,10 @@ func TestGetReadings_RespectsOffset(t *testing.T) {
 	}
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c) VALUES
-		(1, '2025-02-01T10:00:00Z', 10.0),
-		(1, '2025-02-01T11:00:00Z', 11.0),
-		(1, '2025-02-01T12:00:00Z', 12.0),
-		(1, '2025-02-01T13:00:00Z', 13.0)
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 11.0),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 13.0)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
@@ -405,7 +546,7 @@ func TestGetReadings_RespectsOffset(t *testing.T) {
 
 	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
-	readings, err := repo.GetReadings("1", from, to, 2, 2)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 2, 2, nil)
 	if err != nil {
 		t.Fatalf("GetReadings: %v", err)
 	}
@@ -418,6 +559,63 @@ func TestGetReadings_RespectsOffset(t *testing.T) {
 	}
 }
 
+func TestGetReadingsPage(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1'), (2, 'S2')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO readings (station_id, ts, temperature_c) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 11.0),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 13.0),
+		(1, unixepoch('2025-02-01T14:00:00Z') * 1000, 14.0),
+		(2, unixepoch('2025-02-01T12:30:00Z') * 1000, 99.0)
+	`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time { return time.Date(2025, 2, 1, hour, 0, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		name   string
+		cursor ReadingsCursor
+		want   []float64
+	}{
+		{"newest page", ReadingsCursor{}, []float64{14, 13}},
+		{"older than cursor", ReadingsCursor{Time: at(13)}, []float64{12, 11}},
+		{"last older page is short", ReadingsCursor{Time: at(11)}, []float64{10}},
+		{"newer than cursor, newest first", ReadingsCursor{Time: at(10), Newer: true}, []float64{12, 11}},
+		{"oldest page", ReadingsCursor{Newer: true}, []float64{11, 10}},
+		{"cursor after the range starts over at the newest", ReadingsCursor{Time: to.Add(time.Hour)}, []float64{14, 13}},
+		{"cursor before the range starts over at the oldest", ReadingsCursor{Time: from.Add(-time.Hour), Newer: true}, []float64{11, 10}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			readings, err := repo.GetReadingsPage(context.Background(), "1", from, to, tc.cursor, 2, nil)
+			if err != nil {
+				t.Fatalf("GetReadingsPage: %v", err)
+			}
+			got := make([]float64, len(readings))
+			for i, r := range readings {
+				got[i] = r.Value
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("GetReadingsPage(%+v) values = %v; want %v", tc.cursor, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestGetReadings_NullTemperature(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
@@ -429,7 +627,7 @@ func TestGetReadings_NullTemperature(t *testing.T) {
 	if err != nil {
 		t.Fatalf("insert station: %v", err)
 	}
-	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES (1, '2025-02-01T12:00:00Z', NULL)`)
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES (1, unixepoch('2025-02-01T12:00:00Z') * 1000, NULL)`)
 	if err != nil {
 		t.Fatalf("insert reading: %v", err)
 	}
@@ -437,7 +635,7 @@ func TestGetReadings_NullTemperature(t *testing.T) {
 
 	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
-	readings, err := repo.GetReadings("1", from, to, 10, 0)
+	readings, err := repo.GetReadings(context.Background(), "1", from, to, 10, 0, nil)
 	if err != nil {
 		t.Fatalf("GetReadings: %v", err)
 	}
@@ -463,9 +661,9 @@ func TestGetReadingsCount(t *testing.T) {
 	}
 	_, err = db.Exec(`
 		INSERT INTO readings (station_id, ts, temperature_c) VALUES
-		(1, '2025-02-01T10:00:00Z', 10.0),
-		(1, '2025-02-01T11:00:00Z', 11.0),
-		(1, '2025-02-01T12:00:00Z', 12.0)
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 11.0),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0)
 	`)
 	if err != nil {
 		t.Fatalf("insert readings: %v", err)
@@ -474,14 +672,14 @@ func TestGetReadingsCount(t *testing.T) {
 
 	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
-	n, err := repo.GetReadingsCount("1", from, to)
+	n, err := repo.GetReadingsCount(context.Background(), "1", from, to)
 	if err != nil {
 		t.Fatalf("GetReadingsCount: %v", err)
 	}
 	if n != 3 {
 		t.Errorf("GetReadingsCount: got %d, want 3", n)
 	}
-	n, err = repo.GetReadingsCount("1", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	n, err = repo.GetReadingsCount(context.Background(), "1", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
 	if err != nil {
 		t.Fatalf("GetReadingsCount (empty range): %v", err)
 	}
@@ -490,179 +688,1629 @@ func TestGetReadingsCount(t *testing.T) {
 	}
 }
 
-func TestInsertReading_ByNumericStationID(t *testing.T) {
+func TestGetReceivedCount(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
 			t.Fatalf("close db: %v", closeErr)
 		}
 	}()
-	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central')`)
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
 	if err != nil {
 		t.Fatalf("insert station: %v", err)
 	}
+	_, err = db.Exec(`
+		INSERT INTO readings (station_id, ts, temperature_c, received_at) VALUES
+		(1, unixepoch('2025-01-20T10:00:00Z') * 1000, 10.0, '2025-02-01T09:00:00.000000000Z'),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 11.0, '2025-02-01T11:00:02.000000000Z'),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0, '2025-02-02T00:00:00.000000000Z'),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 13.0, NULL)
+	`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
 	repo := NewRepository(db)
 
-	ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
-	temp := 22.5
-	hum := 65.0
-	press := 1013.25
+	// Counted by receipt, not by timestamp: the backfilled reading counts and
+	// readings without a receive time do not.
+	n, err := repo.GetReceivedCount(context.Background(), "1", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetReceivedCount: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("GetReceivedCount: got %d, want 2", n)
+	}
+}
 
-	err = repo.InsertReading("1", ts, &temp, &hum, &press)
+func TestEachReading(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`)
 	if err != nil {
-		t.Fatalf("InsertReading: %v", err)
+		t.Fatalf("insert station: %v", err)
 	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa, received_at) VALUES
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 9.0, NULL, 1013.25, '2025-02-01T12:00:03.000000000Z'),
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 2.0, 40, NULL, NULL),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, NULL, 41, NULL, NULL),
+		(1, unixepoch('2025-02-03T10:00:00Z') * 1000, 5.0, NULL, NULL, NULL)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
 
-	readings, err := repo.GetLatestReadings("1", 1)
+	var got []types.StoredReading
+	err = repo.EachReading(context.Background(), "1", from, to, func(sr types.StoredReading) error {
+		got = append(got, sr)
+		return nil
+	})
 	if err != nil {
-		t.Fatalf("GetLatestReadings: %v", err)
+		t.Fatalf("EachReading: %v", err)
 	}
-	if len(readings) != 1 {
-		t.Fatalf("GetLatestReadings: got %d readings, want 1", len(readings))
+	if len(got) != 3 {
+		t.Fatalf("got %d readings; want 3 in range", len(got))
 	}
-	if readings[0].Value != 22.5 || readings[0].HumidityPct != 65.0 || readings[0].PressureHpa != 1013.25 {
-		t.Errorf("reading: got temp=%v humidity=%v pressure=%v, want 22.5, 65, 1013.25",
-			readings[0].Value, readings[0].HumidityPct, readings[0].PressureHpa)
+	for i, hour := range []int{10, 11, 12} {
+		if want := time.Date(2025, 2, 1, hour, 0, 0, 0, time.UTC); !got[i].Time.Equal(want) {
+			t.Errorf("reading %d at %v; want %v (oldest first)", i, got[i].Time, want)
+		}
 	}
-	if readings[0].StationID != "1" {
-		t.Errorf("StationID: got %q, want 1", readings[0].StationID)
+	if got[1].Temperature != nil || got[1].Humidity == nil || *got[1].Humidity != 41 || got[1].Pressure != nil {
+		t.Errorf("reading 1 = %+v; want only humidity 41", got[1])
+	}
+	if got[2].ReceivedAt == nil || got[2].ReceivedAt.Second() != 3 || got[0].ReceivedAt != nil {
+		t.Errorf("ReceivedAt = %v, %v; want nil, then 12:00:03", got[0].ReceivedAt, got[2].ReceivedAt)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.EachReading(context.Background(), "1", from, to, func(types.StoredReading) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("EachReading with failing fn = %v after %d calls; want the fn error after 1", err, calls)
 	}
 }
 
-func TestInsertReading_ByStationName(t *testing.T) {
+func TestGetHistogram(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
 			t.Fatalf("close db: %v", closeErr)
 		}
 	}()
-	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (2, 'Alpha')`)
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`)
 	if err != nil {
 		t.Fatalf("insert station: %v", err)
 	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0, 20),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 12.0, 21),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 19.0, NULL),
+		(1, unixepoch('2025-02-01T13:00:00Z') * 1000, 20.0, NULL),
+		(1, unixepoch('2025-02-03T13:00:00Z') * 1000, 50.0, NULL)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
 	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
 
-	ts := time.Date(2025, 3, 15, 10, 30, 0, 0, time.UTC)
-	temp := 18.0
-	hum := 50.0
-	press := 1015.0
+	buckets, err := repo.GetHistogram(context.Background(), "1", "temperature", from, to, 2)
+	if err != nil {
+		t.Fatalf("GetHistogram: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("GetHistogram: got %d buckets, want 2", len(buckets))
+	}
+	if buckets[0].Lower != 10 || buckets[0].Upper != 15 || buckets[0].Count != 2 {
+		t.Errorf("bucket 0 = %+v; want [10,15) count 2", buckets[0])
+	}
+	// The maximum value lands in the last bucket.
+	if buckets[1].Lower != 15 || buckets[1].Upper != 20 || buckets[1].Count != 2 {
+		t.Errorf("bucket 1 = %+v; want [15,20] count 2", buckets[1])
+	}
 
-	err = repo.InsertReading("Alpha", ts, &temp, &hum, &press)
+	// NULL values are ignored; a single distinct value collapses into bucket 0.
+	buckets, err = repo.GetHistogram(context.Background(), "1", "humidity", from, to, 4)
 	if err != nil {
-		t.Fatalf("InsertReading(Alpha): %v", err)
+		t.Fatalf("GetHistogram humidity: %v", err)
+	}
+	total := 0
+	for _, b := range buckets {
+		total += b.Count
+	}
+	if len(buckets) != 4 || total != 2 {
+		t.Errorf("humidity buckets = %+v; want 4 buckets totalling 2", buckets)
 	}
 
-	readings, err := repo.GetLatestReadings("2", 1)
+	buckets, err = repo.GetHistogram(context.Background(), "1", "pressure", from, to, 4)
 	if err != nil {
-		t.Fatalf("GetLatestReadings: %v", err)
+		t.Fatalf("GetHistogram pressure: %v", err)
 	}
-	if len(readings) != 1 {
-		t.Fatalf("GetLatestReadings: got %d readings, want 1", len(readings))
+	if buckets == nil || len(buckets) != 0 {
+		t.Errorf("pressure buckets = %#v; want empty non-nil slice", buckets)
 	}
-	if readings[0].Value != 18.0 || readings[0].HumidityPct != 50.0 || readings[0].PressureHpa != 1015.0 {
-		t.Errorf("reading: got temp=%v humidity=%v pressure=%v, want 18, 50, 1015",
-			readings[0].Value, readings[0].HumidityPct, readings[0].PressureHpa)
+
+	if _, err := repo.GetHistogram(context.Background(), "1", "ts; DROP TABLE readings", from, to, 4); err == nil {
+		t.Error("GetHistogram unknown metric: want error")
+	}
+	if _, err := repo.GetHistogram(context.Background(), "1", "temperature", from, to, 0); err == nil {
+		t.Error("GetHistogram bins=0: want error")
 	}
 }
 
-func TestInsertReading_InvalidHumidity(t *testing.T) {
+func TestCreateStation(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
 			t.Fatalf("close db: %v", closeErr)
 		}
 	}()
-	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	repo := NewRepository(db)
+	lat, lon := 52.23, 21.01
+
+	created, err := repo.CreateStation(context.Background(), "Garden", &lat, &lon)
 	if err != nil {
-		t.Fatalf("insert station: %v", err)
+		t.Fatalf("CreateStation: %v", err)
+	}
+	if created.ID == "" || created.Name != "Garden" || created.Latitude == nil || *created.Latitude != lat {
+		t.Errorf("created = %+v; want Garden at %v", created, lat)
+	}
+	if _, err := repo.CreateStation(context.Background(), "Garden", nil, nil); !errors.Is(err, ErrStationExists) {
+		t.Errorf("CreateStation(taken name) error = %v; want ErrStationExists", err)
+	}
+}
+
+func TestCreateShadowStation(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Old'), (2, 'Existing')`)
+	if err != nil {
+		t.Fatalf("insert stations: %v", err)
 	}
 	repo := NewRepository(db)
 
-	ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
-	temp := 20.0
+	created, err := repo.CreateShadowStation(context.Background(), "1", "New")
+	if err != nil {
+		t.Fatalf("CreateShadowStation(new): %v", err)
+	}
+	if created.Name != "New" || created.ShadowOf != "1" {
+		t.Errorf("created = %+v; want New shadowing 1", created)
+	}
 
-	t.Run("humidity_below_zero", func(t *testing.T) {
-		hum := -1.0
-		press := 1013.0
-		err := repo.InsertReading("1", ts, &temp, &hum, &press)
-		if err == nil {
-			t.Fatal("InsertReading: expected error for humidity -1")
+	linked, err := repo.CreateShadowStation(context.Background(), "1", "Existing")
+	if err != nil {
+		t.Fatalf("CreateShadowStation(existing): %v", err)
+	}
+	if linked.ID != "2" || linked.ShadowOf != "1" {
+		t.Errorf("linked = %+v; want station 2 shadowing 1", linked)
+	}
+
+	if _, err := repo.CreateShadowStation(context.Background(), "42", "X"); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("CreateShadowStation(unknown primary) err = %v; want ErrStationNotFound", err)
+	}
+	if _, err := repo.CreateShadowStation(context.Background(), "1", "Old"); !errors.Is(err, ErrInvalidShadow) {
+		t.Errorf("CreateShadowStation(self) err = %v; want ErrInvalidShadow", err)
+	}
+	if _, err := repo.CreateShadowStation(context.Background(), "2", "Y"); !errors.Is(err, ErrInvalidShadow) {
+		t.Errorf("CreateShadowStation(primary is shadow) err = %v; want ErrInvalidShadow", err)
+	}
+	if again, err := repo.CreateShadowStation(context.Background(), "1", "Existing"); err != nil || again.ShadowOf != "1" {
+		t.Errorf("CreateShadowStation(linked again) = %+v, %v; want station 2 still shadowing 1", again, err)
+	}
+
+	stations, err := repo.GetStations(context.Background())
+	if err != nil {
+		t.Fatalf("GetStations: %v", err)
+	}
+	for _, s := range stations {
+		if s.Name == "Old" && s.ShadowOf != "" {
+			t.Errorf("primary station has ShadowOf = %q; want empty", s.ShadowOf)
 		}
-		if !strings.Contains(err.Error(), "humidity_pct") || !strings.Contains(err.Error(), "0-100") {
-			t.Errorf("error message: got %q", err.Error())
+	}
+}
+
+func TestCreateShadowStation_conflicts(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
 		}
-	})
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name, shadow_of) VALUES (1, 'Garden', NULL), (2, 'Attic', NULL), (3, 'Probe', 1)`)
+	if err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	repo := NewRepository(db)
 
-	t.Run("humidity_above_100", func(t *testing.T) {
-		hum := 101.0
-		press := 1013.0
-		err := repo.InsertReading("1", ts, &temp, &hum, &press)
-		if err == nil {
-			t.Fatal("InsertReading: expected error for humidity 101")
+	for name, tt := range map[string]struct{ primary, station string }{
+		"shadow of another primary": {"2", "Probe"},
+		"station with shadows":      {"2", "Garden"},
+	} {
+		if _, err := repo.CreateShadowStation(context.Background(), tt.primary, tt.station); !errors.Is(err, ErrShadowConflict) {
+			t.Errorf("%s: err = %v; want ErrShadowConflict", name, err)
 		}
-		if !strings.Contains(err.Error(), "humidity_pct") || !strings.Contains(err.Error(), "0-100") {
-			t.Errorf("error message: got %q", err.Error())
+	}
+	for id, want := range map[string]string{"1": "", "3": "1"} {
+		s, err := repo.GetStation(context.Background(), id)
+		if err != nil || s.ShadowOf != want {
+			t.Errorf("station %s = %+v, %v; want ShadowOf %q", id, s, err, want)
 		}
-	})
+	}
 }
 
-func TestInsertReading_InvalidPressure(t *testing.T) {
+func TestSetStationPhoto(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
 			t.Fatalf("close db: %v", closeErr)
 		}
 	}()
-	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden')`)
 	if err != nil {
 		t.Fatalf("insert station: %v", err)
 	}
 	repo := NewRepository(db)
 
-	ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
-	temp := 20.0
-	hum := 50.0
+	if err := repo.SetStationPhoto(context.Background(), "1", "stations/1-1.png"); err != nil {
+		t.Fatalf("SetStationPhoto: %v", err)
+	}
+	got, err := repo.GetStation(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetStation: %v", err)
+	}
+	if got.PhotoPath != "stations/1-1.png" {
+		t.Errorf("PhotoPath = %q; want stations/1-1.png", got.PhotoPath)
+	}
 
-	t.Run("pressure_zero", func(t *testing.T) {
-		press := 0.0
-		err := repo.InsertReading("1", ts, &temp, &hum, &press)
-		if err == nil {
-			t.Fatal("InsertReading: expected error for pressure 0")
-		}
-		if !strings.Contains(err.Error(), "pressure_hpa") || !strings.Contains(err.Error(), "positive") {
-			t.Errorf("error message: got %q", err.Error())
-		}
-	})
+	if err := repo.SetStationPhoto(context.Background(), "1", ""); err != nil {
+		t.Fatalf("SetStationPhoto(clear): %v", err)
+	}
+	stations, err := repo.GetStations(context.Background())
+	if err != nil {
+		t.Fatalf("GetStations: %v", err)
+	}
+	if len(stations) != 1 || stations[0].PhotoPath != "" {
+		t.Errorf("stations = %+v; want cleared photo", stations)
+	}
 
-	t.Run("pressure_negative", func(t *testing.T) {
-		press := -10.0
-		err := repo.InsertReading("1", ts, &temp, &hum, &press)
-		if err == nil {
-			t.Fatal("InsertReading: expected error for pressure -10")
-		}
-		if !strings.Contains(err.Error(), "pressure_hpa") || !strings.Contains(err.Error(), "positive") {
-			t.Errorf("error message: got %q", err.Error())
-		}
-	})
+	if err := repo.SetStationPhoto(context.Background(), "42", "x.png"); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("SetStationPhoto(unknown) err = %v; want ErrStationNotFound", err)
+	}
 }
 
-// Ensure repo implements the interface.
-var _ WeatherRepository = (*repositoryImpl)(nil)
-
-func TestRepository_ImplementsInterface(t *testing.T) {
+func TestSetStationLocation(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() {
 		if closeErr := db.Close(); closeErr != nil {
 			t.Fatalf("close db: %v", closeErr)
 		}
 	}()
-	_, _ = db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
 	repo := NewRepository(db)
-	// Compile-time check; also call all methods for coverage.
-	_, _ = repo.GetStations()
-	_, _ = repo.GetLatestReadings("1", 100)
-	_, _ = repo.GetReadings("1", time.Now().Add(-24*time.Hour), time.Now(), 10, 0)
-	_, _ = repo.GetReadingsCount("1", time.Now().Add(-24*time.Hour), time.Now())
+
+	before, err := repo.GetStation(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetStation: %v", err)
+	}
+	if before.Latitude != nil || before.Longitude != nil {
+		t.Errorf("new station location = %v, %v; want nil", before.Latitude, before.Longitude)
+	}
+
+	lat, lon := 52.23, 21.01
+	if err := repo.SetStationLocation(context.Background(), "1", &lat, &lon); err != nil {
+		t.Fatalf("SetStationLocation: %v", err)
+	}
+	got, err := repo.GetStation(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetStation: %v", err)
+	}
+	if got.Latitude == nil || *got.Latitude != lat || got.Longitude == nil || *got.Longitude != lon {
+		t.Errorf("location = %v, %v; want %v, %v", got.Latitude, got.Longitude, lat, lon)
+	}
+
+	if err := repo.SetStationLocation(context.Background(), "42", &lat, &lon); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("SetStationLocation(unknown) err = %v; want ErrStationNotFound", err)
+	}
+}
+
+func TestSetStationRetention(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	days := 90
+	if err := repo.SetStationRetention(context.Background(), "1", &days); err != nil {
+		t.Fatalf("SetStationRetention: %v", err)
+	}
+	got, err := repo.GetStation(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetStation: %v", err)
+	}
+	if got.RetentionDays == nil || *got.RetentionDays != days {
+		t.Errorf("retention = %v; want %d", got.RetentionDays, days)
+	}
+
+	if err := repo.SetStationRetention(context.Background(), "1", nil); err != nil {
+		t.Fatalf("SetStationRetention(nil): %v", err)
+	}
+	stations, err := repo.GetStations(context.Background())
+	if err != nil {
+		t.Fatalf("GetStations: %v", err)
+	}
+	if len(stations) != 1 || stations[0].RetentionDays != nil {
+		t.Errorf("stations = %+v; want one station without retention", stations)
+	}
+
+	if err := repo.SetStationRetention(context.Background(), "42", &days); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("SetStationRetention(unknown) err = %v; want ErrStationNotFound", err)
+	}
+}
+
+func TestSetStationDownsampling(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name, retention_days) VALUES (1, 'Garden', 90)`); err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	raw, daily := 30, 0
+	if err := repo.SetStationDownsampling(context.Background(), "1", &raw, nil, &daily); err != nil {
+		t.Fatalf("SetStationDownsampling: %v", err)
+	}
+	got, err := repo.GetStation(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetStation: %v", err)
+	}
+	if got.RetentionDays == nil || *got.RetentionDays != raw || got.HourlyRetentionDays != nil ||
+		got.DailyRetentionDays == nil || *got.DailyRetentionDays != daily {
+		t.Errorf("retention = %v, %v, %v; want 30, nil, 0", got.RetentionDays, got.HourlyRetentionDays, got.DailyRetentionDays)
+	}
+
+	if err := repo.SetStationDownsampling(context.Background(), "42", &raw, nil, nil); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("SetStationDownsampling(unknown) err = %v; want ErrStationNotFound", err)
+	}
+}
+
+func TestDeleteReadingsBefore(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden'), (2, 'Garage');
+INSERT INTO readings (station_id, ts, temperature_c) VALUES
+  (1, unixepoch('2025-01-01T12:00:00Z') * 1000, 1), (1, unixepoch('2025-01-02T00:00:00Z') * 1000, 2), (1, unixepoch('2025-01-03T08:00:00Z') * 1000 + 500, 3),
+  (2, unixepoch('2025-01-01T12:00:00Z') * 1000, 4)`)
+	if err != nil {
+		t.Fatalf("insert data: %v", err)
+	}
+	repo := NewRepository(db)
+
+	n, err := repo.DeleteReadingsBefore(context.Background(), "1", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("DeleteReadingsBefore: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("deleted = %d; want 1", n)
+	}
+	for station, want := range map[string]int{"1": 2, "2": 1} {
+		count, err := repo.GetReadingsCount(context.Background(), station, time.Time{}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("GetReadingsCount: %v", err)
+		}
+		if count != want {
+			t.Errorf("station %s readings = %d; want %d", station, count, want)
+		}
+	}
+}
+
+func TestSetStationVersions(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'pico-0000002A')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	t.Run("by name", func(t *testing.T) {
+		if err := repo.SetStationVersions(context.Background(), "pico-0000002A", "1.3.0", "0.9.1"); err != nil {
+			t.Fatalf("SetStationVersions: %v", err)
+		}
+		got, err := repo.GetStation(context.Background(), "1")
+		if err != nil {
+			t.Fatalf("GetStation: %v", err)
+		}
+		if got.GatewayVersion != "1.3.0" || got.FirmwareVersion != "0.9.1" {
+			t.Errorf("versions = %q, %q; want 1.3.0, 0.9.1", got.GatewayVersion, got.FirmwareVersion)
+		}
+	})
+
+	t.Run("empty version keeps stored value", func(t *testing.T) {
+		if err := repo.SetStationVersions(context.Background(), "1", "1.4.0", ""); err != nil {
+			t.Fatalf("SetStationVersions: %v", err)
+		}
+		got, err := repo.GetStation(context.Background(), "1")
+		if err != nil {
+			t.Fatalf("GetStation: %v", err)
+		}
+		if got.GatewayVersion != "1.4.0" || got.FirmwareVersion != "0.9.1" {
+			t.Errorf("versions = %q, %q; want 1.4.0, 0.9.1", got.GatewayVersion, got.FirmwareVersion)
+		}
+	})
+
+	t.Run("unknown station", func(t *testing.T) {
+		if err := repo.SetStationVersions(context.Background(), "missing", "1.0.0", ""); !errors.Is(err, ErrStationNotFound) {
+			t.Errorf("SetStationVersions(unknown) err = %v; want ErrStationNotFound", err)
+		}
+	})
+}
+
+func TestSetStationInterval(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'pico-0000002A')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	if err := repo.SetStationInterval(context.Background(), "pico-0000002A", 120); err != nil {
+		t.Fatalf("SetStationInterval: %v", err)
+	}
+	got, err := repo.GetStation(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetStation: %v", err)
+	}
+	if got.IntervalSeconds != 120 {
+		t.Errorf("IntervalSeconds = %d; want 120", got.IntervalSeconds)
+	}
+	if err := repo.SetStationInterval(context.Background(), "missing", 60); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("SetStationInterval(unknown) err = %v; want ErrStationNotFound", err)
+	}
+}
+
+func TestGetMetricSeries(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, pressure_hpa) VALUES
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0, 1000),
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0, NULL),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, NULL, 1001)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+
+	points, err := repo.GetMetricSeries(context.Background(), "1", "temperature", from, to)
+	if err != nil {
+		t.Fatalf("GetMetricSeries: %v", err)
+	}
+	if len(points) != 2 || points[0].Value != 10 || points[1].Value != 12 {
+		t.Errorf("points = %+v; want 10 then 12", points)
+	}
+	if _, err := repo.GetMetricSeries(context.Background(), "1", "wind", from, to); err == nil {
+		t.Error("GetMetricSeries(unknown metric) err = nil; want error")
+	}
+}
+
+func TestGetLatestMetrics(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S'), (2, 'Empty')`)
+	if err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, pressure_hpa, received_at) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0, 1000, '2025-02-01T10:00:05.000000000Z'),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, NULL, 1001, NULL),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 12.0, NULL, '2025-02-01T12:00:03.000000000Z')`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+
+	got, err := repo.GetLatestMetrics(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetLatestMetrics: %v", err)
+	}
+	if got.Temperature == nil || got.Temperature.Value != 12 || !got.Temperature.Time.Equal(time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Temperature = %+v; want 12 at 12:00", got.Temperature)
+	}
+	if got.Temperature != nil && (got.Temperature.ReceivedAt == nil || !got.Temperature.ReceivedAt.Equal(time.Date(2025, 2, 1, 12, 0, 3, 0, time.UTC))) {
+		t.Errorf("Temperature.ReceivedAt = %v; want 12:00:03", got.Temperature.ReceivedAt)
+	}
+	if got.Pressure == nil || got.Pressure.Value != 1001 || got.Pressure.ReceivedAt != nil {
+		t.Errorf("Pressure = %+v; want 1001 at 11:00 without receipt time", got.Pressure)
+	}
+	if got.Humidity != nil {
+		t.Errorf("Humidity = %+v; want nil", got.Humidity)
+	}
+
+	empty, err := repo.GetLatestMetrics(context.Background(), "2")
+	if err != nil || empty.Temperature != nil || empty.Humidity != nil || empty.Pressure != nil {
+		t.Errorf("GetLatestMetrics(no readings) = %+v, %v; want no metrics", empty, err)
+	}
+}
+
+func TestGetSummary(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0, 40),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 20.0, NULL),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, NULL, 60)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+
+	sum, err := repo.GetSummary(context.Background(), "1", from, to)
+	if err != nil {
+		t.Fatalf("GetSummary: %v", err)
+	}
+	if sum.Count != 3 {
+		t.Errorf("Count = %d; want 3", sum.Count)
+	}
+	if sum.Temperature.Count != 2 || *sum.Temperature.Min != 10 || *sum.Temperature.Avg != 15 || *sum.Temperature.Max != 20 {
+		t.Errorf("Temperature = %+v; want count 2, 10/15/20", sum.Temperature)
+	}
+	if sum.Humidity.Count != 2 || *sum.Humidity.Avg != 50 {
+		t.Errorf("Humidity = %+v; want count 2 avg 50", sum.Humidity)
+	}
+	if sum.Pressure.Count != 0 || sum.Pressure.Min != nil {
+		t.Errorf("Pressure = %+v; want empty", sum.Pressure)
+	}
+	if sum.First == nil || !sum.First.Equal(time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)) || sum.Last == nil {
+		t.Errorf("First, Last = %v, %v", sum.First, sum.Last)
+	}
+
+	empty, err := repo.GetSummary(context.Background(), "1", to, to.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetSummary (empty): %v", err)
+	}
+	if empty.Count != 0 || empty.First != nil || empty.Temperature.Avg != nil {
+		t.Errorf("empty summary = %+v", empty)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 2.0, 40, 1013.25),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 4.0, NULL, 1013.25),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 9.0, NULL, 1013.25)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+
+	stats, err := repo.GetStats(context.Background(), "1", from, to)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Count != 3 {
+		t.Errorf("Count = %d; want 3", stats.Count)
+	}
+	// Mean 5, squared deviations 9 + 1 + 16 = 26, sample variance 13.
+	temp := stats.Temperature
+	if temp.Count != 3 || *temp.Min != 2 || *temp.Max != 9 || *temp.Avg != 5 || temp.StdDev == nil || *temp.StdDev != math.Sqrt(13) {
+		t.Errorf("Temperature = %+v; want count 3, 2/9/5, stddev sqrt(13)", temp)
+	}
+	if stats.Humidity.Count != 1 || stats.Humidity.StdDev != nil {
+		t.Errorf("Humidity = %+v; want one value without stddev", stats.Humidity)
+	}
+	if stats.Pressure.StdDev == nil || *stats.Pressure.StdDev != 0 {
+		t.Errorf("Pressure stddev = %v; want exactly 0 for constant values", stats.Pressure.StdDev)
+	}
+
+	empty, err := repo.GetStats(context.Background(), "1", to, to.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetStats (empty): %v", err)
+	}
+	if empty.Count != 0 || empty.Temperature.Avg != nil || empty.Temperature.StdDev != nil {
+		t.Errorf("empty stats = %+v", empty)
+	}
+}
+
+func TestGetAggregate(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct) VALUES
+		(1, unixepoch('2025-02-01T10:05:00Z') * 1000 + 500, 10.0, 40),
+		(1, unixepoch('2025-02-01T10:55:00Z') * 1000, 20.0, NULL),
+		(1, unixepoch('2025-02-01T12:00:00Z') * 1000, NULL, 60),
+		(1, unixepoch('2025-02-02T00:00:00Z') * 1000, 30.0, NULL)`)
+	if err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+
+	hourly, err := repo.GetAggregate(context.Background(), "1", from, to, time.Hour)
+	if err != nil {
+		t.Fatalf("GetAggregate(1h): %v", err)
+	}
+	if len(hourly) != 2 {
+		t.Fatalf("hourly buckets = %+v; want 2 (the reading at 'to' is excluded)", hourly)
+	}
+	first := hourly[0]
+	if !first.Start.Equal(time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)) || first.Count != 2 {
+		t.Errorf("bucket 0 = %+v; want 10:00 with 2 readings", first)
+	}
+	if first.Temperature.Count != 2 || *first.Temperature.Min != 10 || *first.Temperature.Avg != 15 || *first.Temperature.Max != 20 {
+		t.Errorf("bucket 0 temperature = %+v; want count 2, 10/15/20", first.Temperature)
+	}
+	if first.Pressure.Count != 0 || first.Pressure.Avg != nil {
+		t.Errorf("bucket 0 pressure = %+v; want empty", first.Pressure)
+	}
+	if !hourly[1].Start.Equal(time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)) || hourly[1].Temperature.Min != nil {
+		t.Errorf("bucket 1 = %+v; want 12:00 without temperature", hourly[1])
+	}
+
+	daily, err := repo.GetAggregate(context.Background(), "1", from, to.Add(24*time.Hour), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("GetAggregate(1d): %v", err)
+	}
+	if len(daily) != 2 || daily[0].Count != 3 || !daily[1].Start.Equal(to) || daily[1].Count != 1 {
+		t.Errorf("daily buckets = %+v; want 3 readings on Feb 1 and 1 on Feb 2", daily)
+	}
+
+	empty, err := repo.GetAggregate(context.Background(), "2", from, to, time.Hour)
+	if err != nil {
+		t.Fatalf("GetAggregate (empty): %v", err)
+	}
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("empty aggregate = %#v; want empty slice", empty)
+	}
+}
+
+func TestInsertReading_ByNumericStationID(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	temp := 22.5
+	hum := 65.0
+	press := 1013.25
+
+	err = repo.InsertReading(context.Background(), "1", ts, ts, &temp, &hum, &press)
+	if err != nil {
+		t.Fatalf("InsertReading: %v", err)
+	}
+
+	readings, err := repo.GetLatestReadings(context.Background(), "1", 1)
+	if err != nil {
+		t.Fatalf("GetLatestReadings: %v", err)
+	}
+	if len(readings) != 1 {
+		t.Fatalf("GetLatestReadings: got %d readings, want 1", len(readings))
+	}
+	if readings[0].Value != 22.5 || readings[0].HumidityPct != 65.0 || readings[0].PressureHpa != 1013.25 {
+		t.Errorf("reading: got temp=%v humidity=%v pressure=%v, want 22.5, 65, 1013.25",
+			readings[0].Value, readings[0].HumidityPct, readings[0].PressureHpa)
+	}
+	if readings[0].StationID != "1" {
+		t.Errorf("StationID: got %q, want 1", readings[0].StationID)
+	}
+}
+
+func TestInsertReading_ReceivedAt(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+	temp := 20.0
+
+	t.Run("stores receipt time separately from measured time", func(t *testing.T) {
+		ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+		received := ts.Add(90 * time.Second)
+		if err := repo.InsertReading(context.Background(), "1", ts, received, &temp, nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		readings, err := repo.GetReadings(context.Background(), "1", ts, ts, 10, 0, nil)
+		if err != nil {
+			t.Fatalf("GetReadings: %v", err)
+		}
+		if len(readings) != 1 {
+			t.Fatalf("GetReadings: got %d readings, want 1", len(readings))
+		}
+		if !readings[0].Time.Equal(ts) {
+			t.Errorf("Time = %v; want %v", readings[0].Time, ts)
+		}
+		if readings[0].ReceivedAt == nil || !readings[0].ReceivedAt.Equal(received) {
+			t.Errorf("ReceivedAt = %v; want %v", readings[0].ReceivedAt, received)
+		}
+	})
+
+	t.Run("zero receipt time is stored as NULL", func(t *testing.T) {
+		ts := time.Date(2025, 2, 1, 13, 0, 0, 0, time.UTC)
+		if err := repo.InsertReading(context.Background(), "1", ts, time.Time{}, &temp, nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		readings, err := repo.GetLatestReadings(context.Background(), "1", 1)
+		if err != nil {
+			t.Fatalf("GetLatestReadings: %v", err)
+		}
+		if len(readings) != 1 {
+			t.Fatalf("GetLatestReadings: got %d readings, want 1", len(readings))
+		}
+		if readings[0].ReceivedAt != nil {
+			t.Errorf("ReceivedAt = %v; want nil", readings[0].ReceivedAt)
+		}
+	})
+}
+
+func TestInsertReading_StoredTimestamps(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+	temp := 20.0
+	base := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	// ts is stored as unix milliseconds, received_at as canonical text; as
+	// RFC 3339, 12:00:00Z and 12:00:00.5Z would sort the wrong way round.
+	for _, ts := range []time.Time{base.Add(500 * time.Millisecond), base.In(time.FixedZone("CET", 3600))} {
+		if err := repo.InsertReading(context.Background(), "1", ts, ts, &temp, nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+	}
+
+	var stored []string
+	rows, err := db.Query(`SELECT ts || ' ' || received_at FROM readings ORDER BY ts`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		stored = append(stored, s)
+	}
+	want := []string{
+		"1738411200000 2025-02-01T12:00:00.000000000Z",
+		"1738411200500 2025-02-01T12:00:00.500000000Z",
+	}
+	if !slices.Equal(stored, want) {
+		t.Errorf("stored = %q; want %q", stored, want)
+	}
+
+	latest, err := repo.GetLatestReadings(context.Background(), "1", 1)
+	if err != nil || len(latest) != 1 || !latest[0].Time.Equal(base.Add(500*time.Millisecond)) {
+		t.Errorf("GetLatestReadings = %v, %v; want the reading at 12:00:00.5", latest, err)
+	}
+	if loc := latest[0].Time.Location(); loc != time.UTC {
+		t.Errorf("Time location = %v; want UTC", loc)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2025, 2, 1, 12, 0, 0, 250000000, time.UTC)
+	for _, ts := range []string{
+		"2025-02-01T12:00:00.250000000Z",
+		"2025-02-01T12:00:00.25Z",
+		"2025-02-01T13:00:00.25+01:00",
+		"2025-02-01 12:00:00.25",
+	} {
+		got, err := parseTimestamp(ts)
+		if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("parseTimestamp(%q) = %v, %v; want %v", ts, got, err, want)
+		}
+	}
+	if _, err := parseTimestamp("yesterday"); err == nil {
+		t.Error("parseTimestamp(yesterday) = nil error; want error")
+	}
+}
+
+func TestInsertReading_ByStationName(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (2, 'Alpha')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	ts := time.Date(2025, 3, 15, 10, 30, 0, 0, time.UTC)
+	temp := 18.0
+	hum := 50.0
+	press := 1015.0
+
+	err = repo.InsertReading(context.Background(), "Alpha", ts, ts, &temp, &hum, &press)
+	if err != nil {
+		t.Fatalf("InsertReading(Alpha): %v", err)
+	}
+
+	readings, err := repo.GetLatestReadings(context.Background(), "2", 1)
+	if err != nil {
+		t.Fatalf("GetLatestReadings: %v", err)
+	}
+	if len(readings) != 1 {
+		t.Fatalf("GetLatestReadings: got %d readings, want 1", len(readings))
+	}
+	if readings[0].Value != 18.0 || readings[0].HumidityPct != 50.0 || readings[0].PressureHpa != 1015.0 {
+		t.Errorf("reading: got temp=%v humidity=%v pressure=%v, want 18, 50, 1015",
+			readings[0].Value, readings[0].HumidityPct, readings[0].PressureHpa)
+	}
+}
+
+func TestInsertReadings(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden')`); err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	temp, badHumidity := 21.5, 120.0
+
+	errs, err := repo.InsertReadings(context.Background(), []NewReading{
+		{StationID: "1", Time: ts, Temperature: &temp},
+		{StationID: "Attic", Time: ts, Humidity: &badHumidity},
+		{StationID: "1", Time: ts.Add(time.Minute), Temperature: &temp},
+	})
+	if err != nil {
+		t.Fatalf("InsertReadings: %v", err)
+	}
+	if len(errs) != 3 || errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Fatalf("errs = %v; want only the second reading rejected", errs)
+	}
+	count, err := repo.GetReadingsCount(context.Background(), "1", ts, ts.Add(time.Hour))
+	if err != nil || count != 2 {
+		t.Errorf("GetReadingsCount() = %d, %v; want 2", count, err)
+	}
+	// The station created for the rejected reading is rolled back with it.
+	stations, err := repo.GetStations(context.Background())
+	if err != nil || len(stations) != 1 {
+		t.Errorf("GetStations() = %+v, %v; want only Garden", stations, err)
+	}
+}
+
+func TestInsertReading_InvalidHumidity(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	temp := 20.0
+
+	t.Run("humidity_below_zero", func(t *testing.T) {
+		hum := -1.0
+		press := 1013.0
+		err := repo.InsertReading(context.Background(), "1", ts, ts, &temp, &hum, &press)
+		if err == nil {
+			t.Fatal("InsertReading: expected error for humidity -1")
+		}
+		if !strings.Contains(err.Error(), "humidity_pct") || !strings.Contains(err.Error(), "0-100") {
+			t.Errorf("error message: got %q", err.Error())
+		}
+	})
+
+	t.Run("humidity_above_100", func(t *testing.T) {
+		hum := 101.0
+		press := 1013.0
+		err := repo.InsertReading(context.Background(), "1", ts, ts, &temp, &hum, &press)
+		if err == nil {
+			t.Fatal("InsertReading: expected error for humidity 101")
+		}
+		if !strings.Contains(err.Error(), "humidity_pct") || !strings.Contains(err.Error(), "0-100") {
+			t.Errorf("error message: got %q", err.Error())
+		}
+	})
+}
+
+func TestInsertReading_InvalidPressure(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+
+	ts := time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)
+	temp := 20.0
+	hum := 50.0
+
+	t.Run("pressure_zero", func(t *testing.T) {
+		press := 0.0
+		err := repo.InsertReading(context.Background(), "1", ts, ts, &temp, &hum, &press)
+		if err == nil {
+			t.Fatal("InsertReading: expected error for pressure 0")
+		}
+		if !strings.Contains(err.Error(), "pressure_hpa") || !strings.Contains(err.Error(), "positive") {
+			t.Errorf("error message: got %q", err.Error())
+		}
+	})
+
+	t.Run("pressure_negative", func(t *testing.T) {
+		press := -10.0
+		err := repo.InsertReading(context.Background(), "1", ts, ts, &temp, &hum, &press)
+		if err == nil {
+			t.Fatal("InsertReading: expected error for pressure -10")
+		}
+		if !strings.Contains(err.Error(), "pressure_hpa") || !strings.Contains(err.Error(), "positive") {
+			t.Errorf("error message: got %q", err.Error())
+		}
+	})
+}
+
+// Ensure the SQLite stores implement the interfaces.
+var (
+	_ StationStore  = (*repositoryImpl)(nil)
+	_ ReadingsStore = (*sqliteReadings)(nil)
+)
+
+func TestOpen(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	for _, backend := range []string{"", BackendSQLite} {
+		repo, err := Open(db, backend)
+		if err != nil || repo == nil {
+			t.Errorf("Open(%q) = %v, %v; want repository", backend, repo, err)
+		}
+	}
+	if _, err := Open(db, "influx"); err == nil || !strings.Contains(err.Error(), "influx") {
+		t.Errorf("Open(influx) err = %v; want unknown backend error", err)
+	}
+}
+
+func TestRepository_ImplementsInterface(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, _ = db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S1')`)
+	repo := NewRepository(db)
+	// Compile-time check; also call all methods for coverage.
+	_, _ = repo.GetStations(context.Background())
+	_, _ = repo.GetLatestReadings(context.Background(), "1", 100)
+	_, _ = repo.GetReadings(context.Background(), "1", time.Now().Add(-24*time.Hour), time.Now(), 10, 0, nil)
+	_, _ = repo.GetReadingsCount(context.Background(), "1", time.Now().Add(-24*time.Hour), time.Now())
 	temp, hum, press := 20.0, 50.0, 1013.0
-	_ = repo.InsertReading("1", time.Now(), &temp, &hum, &press)
+	_ = repo.InsertReading(context.Background(), "1", time.Now(), time.Now(), &temp, &hum, &press)
+}
+
+func TestInsertReading_MergesPartialReadings(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central')`)
+	if err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	repo := NewRepository(db)
+	ts := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	temp, hum, press, newTemp := 20.0, 55.0, 1012.0, 21.0
+
+	if err := repo.InsertReading(context.Background(), "1", ts, time.Time{}, &temp, &hum, nil); err != nil {
+		t.Fatalf("InsertReading: %v", err)
+	}
+	if err := repo.InsertReading(context.Background(), "Central", ts, time.Time{}, nil, nil, &press); err != nil {
+		t.Fatalf("InsertReading(pressure only): %v", err)
+	}
+	if err := repo.InsertReading(context.Background(), "1", ts, time.Time{}, &newTemp, nil, nil); err != nil {
+		t.Fatalf("InsertReading(temperature only): %v", err)
+	}
+
+	readings, err := repo.GetReadings(context.Background(), "1", ts, ts, 10, 0, nil)
+	if err != nil {
+		t.Fatalf("GetReadings: %v", err)
+	}
+	if len(readings) != 1 {
+		t.Fatalf("GetReadings: got %d readings, want 1", len(readings))
+	}
+	r := readings[0]
+	if r.Value != newTemp || r.HumidityPct != hum || r.PressureHpa != press {
+		t.Errorf("reading = %v °C, %v %%, %v hPa; want %v, %v, %v", r.Value, r.HumidityPct, r.PressureHpa, newTemp, hum, press)
+	}
+}
+
+func TestGetReadingTime(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central');
+		INSERT INTO readings (station_id, ts, temperature_c) VALUES
+			(1, unixepoch('2025-03-01T10:00:05Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:00:45Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:01:00Z') * 1000, 20);`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, stationID := range []string{"1", "Central"} {
+		ts, ok, err := repo.GetReadingTime(context.Background(), stationID, from, from.Add(time.Minute))
+		if err != nil || !ok {
+			t.Fatalf("GetReadingTime(%s) = %v, %v, %v; want a reading", stationID, ts, ok, err)
+		}
+		if want := from.Add(5 * time.Second); !ts.Equal(want) {
+			t.Errorf("GetReadingTime(%s) = %v; want earliest in bucket %v", stationID, ts, want)
+		}
+	}
+
+	if _, ok, err := repo.GetReadingTime(context.Background(), "1", from.Add(-time.Minute), from); err != nil || ok {
+		t.Errorf("GetReadingTime(empty bucket) = %v, %v; want false, nil", ok, err)
+	}
+	if _, ok, err := repo.GetReadingTime(context.Background(), "unknown", from, from.Add(time.Minute)); err != nil || ok {
+		t.Errorf("GetReadingTime(unknown station) = %v, %v; want false, nil", ok, err)
+	}
+}
+
+func TestGetGaps(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Central'), (2, 'Silent');
+		INSERT INTO readings (station_id, ts, temperature_c) VALUES
+			(1, unixepoch('2025-03-01T10:01:00Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:02:00Z') * 1000 + 500, 20),
+			(1, unixepoch('2025-03-01T10:03:00Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:20:00Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:21:00Z') * 1000, 20);`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	at := func(min int) time.Time { return from.Add(time.Duration(min) * time.Minute) }
+
+	t.Run("reports gaps between readings and at the range end", func(t *testing.T) {
+		gaps, err := repo.GetGaps(context.Background(), "1", from, to, 3*time.Minute)
+		if err != nil {
+			t.Fatalf("GetGaps: %v", err)
+		}
+		want := []types.Gap{
+			{Start: at(3), End: at(20), Seconds: 17 * 60},
+			{Start: at(21), End: at(30), Seconds: 9 * 60},
+		}
+		if !reflect.DeepEqual(gaps, want) {
+			t.Errorf("GetGaps() = %+v; want %+v", gaps, want)
+		}
+	})
+
+	t.Run("station without readings has one gap spanning the range", func(t *testing.T) {
+		gaps, err := repo.GetGaps(context.Background(), "2", from, to, 3*time.Minute)
+		if err != nil {
+			t.Fatalf("GetGaps: %v", err)
+		}
+		if len(gaps) != 1 || !gaps[0].Start.Equal(from) || !gaps[0].End.Equal(to) {
+			t.Errorf("GetGaps() = %+v; want one gap %v–%v", gaps, from, to)
+		}
+	})
+
+	t.Run("no gaps above threshold", func(t *testing.T) {
+		gaps, err := repo.GetGaps(context.Background(), "1", at(1), at(3), time.Minute+time.Second)
+		if err != nil {
+			t.Fatalf("GetGaps: %v", err)
+		}
+		if len(gaps) != 0 {
+			t.Errorf("GetGaps() = %+v; want none", gaps)
+		}
+	})
+}
+
+func TestGetUptime(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	_, err := db.Exec(`INSERT INTO stations (id, name, created_at) VALUES
+			(1, 'Central', '2025-01-01T00:00:00Z'),
+			(2, 'Late', '2025-03-01T10:15:00Z'),
+			(3, 'Future', '2025-04-01T00:00:00Z');
+		INSERT INTO readings (station_id, ts, temperature_c) VALUES
+			(1, unixepoch('2025-03-01T10:01:00Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:02:00Z') * 1000 + 500, 20),
+			(1, unixepoch('2025-03-01T10:03:00Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:20:00Z') * 1000, 20),
+			(1, unixepoch('2025-03-01T10:21:00Z') * 1000, 20),
+			(2, unixepoch('2025-03-01T10:16:00Z') * 1000, 20),
+			(2, unixepoch('2025-03-01T10:17:00Z') * 1000, 20),
+			(2, unixepoch('2025-03-01T10:18:00Z') * 1000, 20);`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	repo := NewRepository(db)
+	from := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	// julianday() arithmetic is accurate to about a millisecond.
+	near := func(a, b float64) bool { return a-b < 1e-3 && b-a < 1e-3 }
+
+	t.Run("all stations, counted from creation", func(t *testing.T) {
+		uptime, err := repo.GetUptime(context.Background(), "", from, to, 3*time.Minute)
+		if err != nil {
+			t.Fatalf("GetUptime: %v", err)
+		}
+		// Station 1: 26 of 30 minutes in gaps. Station 2: 12 of 15 minutes.
+		want := map[string]float64{"1": 100 * 4.0 / 30, "2": 20}
+		if len(uptime) != len(want) {
+			t.Fatalf("GetUptime() = %v; want %v", uptime, want)
+		}
+		for id, w := range want {
+			if got, ok := uptime[id]; !ok || !near(got, w) {
+				t.Errorf("uptime[%s] = %v; want %v", id, got, w)
+			}
+		}
+	})
+
+	t.Run("single station without gaps", func(t *testing.T) {
+		uptime, err := repo.GetUptime(context.Background(), "1", from.Add(time.Minute), from.Add(3*time.Minute), 3*time.Minute)
+		if err != nil {
+			t.Fatalf("GetUptime: %v", err)
+		}
+		if len(uptime) != 1 || !near(uptime["1"], 100) {
+			t.Errorf("GetUptime() = %v; want map[1:100]", uptime)
+		}
+	})
+}
+
+func TestAnnotations(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden'), (2, 'Attic')`); err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	repo := NewRepository(db)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	moved, err := repo.CreateAnnotation(context.Background(), "1", base.Add(2*time.Hour), base.Add(4*time.Hour), "sensor moved indoors")
+	if err != nil {
+		t.Fatalf("CreateAnnotation: %v", err)
+	}
+	if moved.ID == "" || moved.StationID != "1" || !moved.Start.Equal(base.Add(2*time.Hour)) || moved.CreatedAt.IsZero() {
+		t.Errorf("CreateAnnotation() = %+v; want ID, station 1, start 02:00, created_at", moved)
+	}
+	if _, err := repo.CreateAnnotation(context.Background(), "1", base.Add(10*time.Hour), base.Add(10*time.Hour), "calibration changed"); err != nil {
+		t.Fatalf("CreateAnnotation: %v", err)
+	}
+	if _, err := repo.CreateAnnotation(context.Background(), "2", base.Add(3*time.Hour), base.Add(3*time.Hour), "other station"); err != nil {
+		t.Fatalf("CreateAnnotation: %v", err)
+	}
+	if _, err := repo.CreateAnnotation(context.Background(), "42", base, base, "x"); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("CreateAnnotation(unknown) err = %v; want ErrStationNotFound", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{"overlapping start", base.Add(3 * time.Hour), base.Add(5 * time.Hour), []string{"sensor moved indoors"}},
+		{"point inside", base.Add(9 * time.Hour), base.Add(11 * time.Hour), []string{"calibration changed"}},
+		{"fractional bounds", base.Add(10*time.Hour - 500*time.Millisecond), base.Add(10*time.Hour + 500*time.Millisecond), []string{"calibration changed"}},
+		{"open bounds", time.Time{}, time.Time{}, []string{"sensor moved indoors", "calibration changed"}},
+		{"none", base.Add(5 * time.Hour), base.Add(9 * time.Hour), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.GetAnnotations(context.Background(), "1", tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("GetAnnotations: %v", err)
+			}
+			var notes []string
+			for _, a := range got {
+				notes = append(notes, a.Note)
+			}
+			if !reflect.DeepEqual(notes, tt.want) {
+				t.Errorf("notes = %v; want %v", notes, tt.want)
+			}
+		})
+	}
+
+	if err := repo.DeleteAnnotation(context.Background(), "2", moved.ID); !errors.Is(err, ErrAnnotationNotFound) {
+		t.Errorf("DeleteAnnotation(other station) err = %v; want ErrAnnotationNotFound", err)
+	}
+	if err := repo.DeleteAnnotation(context.Background(), "1", moved.ID); err != nil {
+		t.Fatalf("DeleteAnnotation: %v", err)
+	}
+	if got, _ := repo.GetAnnotations(context.Background(), "1", time.Time{}, time.Time{}); len(got) != 1 {
+		t.Errorf("annotations after delete = %d; want 1", len(got))
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden'), (2, 'Attic')`); err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	repo := NewRepository(db)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	storm, err := repo.CreateSnapshot(context.Background(), "1", base.Add(2*time.Hour), base.Add(2*time.Hour+10*time.Minute), "https://cam.example/storm.jpg")
+	if err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if storm.ID == "" || storm.URL != "https://cam.example/storm.jpg" || !storm.End.Equal(base.Add(2*time.Hour+10*time.Minute)) || storm.CreatedAt.IsZero() {
+		t.Errorf("CreateSnapshot() = %+v; want ID, URL, end 02:10, created_at", storm)
+	}
+	if _, err := repo.CreateSnapshot(context.Background(), "1", base.Add(8*time.Hour), base.Add(8*time.Hour), "https://cam.example/dawn.jpg"); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if _, err := repo.CreateSnapshot(context.Background(), "2", base.Add(2*time.Hour), base.Add(2*time.Hour), "https://cam.example/attic.jpg"); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	if _, err := repo.CreateSnapshot(context.Background(), "42", base, base, "https://cam.example/x.jpg"); !errors.Is(err, ErrStationNotFound) {
+		t.Errorf("CreateSnapshot(unknown) err = %v; want ErrStationNotFound", err)
+	}
+
+	got, err := repo.GetSnapshots(context.Background(), "1", base.Add(2*time.Hour+5*time.Minute), base.Add(9*time.Hour))
+	if err != nil {
+		t.Fatalf("GetSnapshots: %v", err)
+	}
+	var urls []string
+	for _, s := range got {
+		urls = append(urls, s.URL)
+	}
+	if want := []string{"https://cam.example/storm.jpg", "https://cam.example/dawn.jpg"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("GetSnapshots() urls = %v; want %v", urls, want)
+	}
+
+	// Whole and fractional seconds must compare in time order.
+	if _, err := repo.CreateSnapshot(context.Background(), "2", base.Add(10*time.Hour), base.Add(10*time.Hour), "https://cam.example/ten.jpg"); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	got, err = repo.GetSnapshots(context.Background(), "2", base.Add(10*time.Hour-500*time.Millisecond), base.Add(10*time.Hour+500*time.Millisecond))
+	if err != nil || len(got) != 1 || got[0].URL != "https://cam.example/ten.jpg" {
+		t.Errorf("GetSnapshots(10:00 ± 0.5s) = %+v, %v; want ten.jpg", got, err)
+	}
+
+	if err := repo.DeleteSnapshot(context.Background(), "2", storm.ID); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("DeleteSnapshot(other station) err = %v; want ErrSnapshotNotFound", err)
+	}
+	if err := repo.DeleteSnapshot(context.Background(), "1", storm.ID); err != nil {
+		t.Fatalf("DeleteSnapshot: %v", err)
+	}
+	if got, _ := repo.GetSnapshots(context.Background(), "1", time.Time{}, time.Time{}); len(got) != 1 {
+		t.Errorf("snapshots after delete = %d; want 1", len(got))
+	}
+}
+
+func TestRollupDaily(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`); err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c, humidity_pct) VALUES
+		(1, unixepoch('2025-02-01T10:00:00Z') * 1000, 10.0, 40),
+		(1, unixepoch('2025-02-01T23:59:59Z') * 1000 + 500, 20.0, NULL),
+		(1, unixepoch('2025-02-02T00:00:00Z') * 1000, 5.0, 60)`); err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+
+	t.Run("backfills every day when empty", func(t *testing.T) {
+		n, err := repo.RollupDaily(context.Background(), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("RollupDaily: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("RollupDaily = %d; want 2", n)
+		}
+		var count int
+		var min, avg, max float64
+		err = db.QueryRow(`SELECT readings, temperature_min, temperature_avg, temperature_max FROM daily_rollups WHERE day = '2025-02-01'`).
+			Scan(&count, &min, &avg, &max)
+		if err != nil {
+			t.Fatalf("select rollup: %v", err)
+		}
+		if count != 2 || min != 10 || avg != 15 || max != 20 {
+			t.Errorf("2025-02-01 = %d readings, %v/%v/%v; want 2, 10/15/20", count, min, avg, max)
+		}
+	})
+
+	t.Run("recomputes days from from onward", func(t *testing.T) {
+		if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES
+			(1, unixepoch('2025-02-01T12:00:00Z') * 1000, 0.0), (1, unixepoch('2025-02-02T12:00:00Z') * 1000, 15.0)`); err != nil {
+			t.Fatalf("insert readings: %v", err)
+		}
+		n, err := repo.RollupDaily(context.Background(), time.Date(2025, 2, 2, 18, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("RollupDaily: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("RollupDaily = %d; want 1", n)
+		}
+		var first, second int
+		if err := db.QueryRow(`SELECT
+			(SELECT readings FROM daily_rollups WHERE day = '2025-02-01'),
+			(SELECT readings FROM daily_rollups WHERE day = '2025-02-02')`).Scan(&first, &second); err != nil {
+			t.Fatalf("select rollups: %v", err)
+		}
+		if first != 2 || second != 2 {
+			t.Errorf("readings = %d, %d; want 2 (untouched), 2", first, second)
+		}
+	})
+}
+
+func TestRollupHourly(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S')`); err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES
+		(1, unixepoch('2025-02-01T10:05:00Z') * 1000, 10.0),
+		(1, unixepoch('2025-02-01T10:55:00Z') * 1000, 20.0),
+		(1, unixepoch('2025-02-01T11:00:00Z') * 1000, 30.0),
+		(1, unixepoch('2025-02-01T12:30:00Z') * 1000, 40.0)`); err != nil {
+		t.Fatalf("insert readings: %v", err)
+	}
+	repo := NewRepository(db)
+	ctx := context.Background()
+
+	n, err := repo.RollupHourly(ctx, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("RollupHourly: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("RollupHourly = %d; want 3 (backfill)", n)
+	}
+
+	// Once the raw readings of 10:00 and 11:00 are pruned, a whole-hour
+	// aggregate falls back to their rollups.
+	if _, err := repo.DeleteReadingsBefore(ctx, "1", time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("DeleteReadingsBefore: %v", err)
+	}
+	from, to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)
+	buckets, err := repo.GetAggregate(ctx, "1", from, to, 2*time.Hour)
+	if err != nil {
+		t.Fatalf("GetAggregate: %v", err)
+	}
+	if len(buckets) != 2 {
+		t.Fatalf("buckets = %+v; want 10:00 and 12:00", buckets)
+	}
+	if b := buckets[0]; b.Count != 3 || b.Temperature.Count != 3 || *b.Temperature.Min != 10 || *b.Temperature.Avg != 20 || *b.Temperature.Max != 30 {
+		t.Errorf("10:00 = %+v (temperature %+v); want 3 readings, 10/20/30", b, b.Temperature)
+	}
+	if b := buckets[1]; b.Count != 1 || *b.Temperature.Avg != 40 {
+		t.Errorf("12:00 = %+v; want the raw reading", b)
+	}
+
+	if n, err := repo.DeleteHourlyRollupsBefore(ctx, "1", time.Date(2025, 2, 1, 11, 0, 0, 0, time.UTC)); err != nil || n != 1 {
+		t.Errorf("DeleteHourlyRollupsBefore = %d, %v; want 1", n, err)
+	}
+}
+
+func TestDeleteDailyRollupsBefore(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S'), (2, 'T')`); err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO daily_rollups (station_id, day, readings, temperature_count, humidity_count, pressure_count) VALUES
+		(1, '2025-01-01', 1, 0, 0, 0), (1, '2025-01-02', 1, 0, 0, 0), (2, '2025-01-01', 1, 0, 0, 0)`); err != nil {
+		t.Fatalf("insert rollups: %v", err)
+	}
+	repo := NewRepository(db)
+
+	n, err := repo.DeleteDailyRollupsBefore(context.Background(), "1", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("DeleteDailyRollupsBefore: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("DeleteDailyRollupsBefore = %d; want 1", n)
+	}
+}
+
+func TestGetMonthlyClimate(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'S'), (2, 'T')`); err != nil {
+		t.Fatalf("insert stations: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO daily_rollups (station_id, day, readings,
+		temperature_count, temperature_min, temperature_avg, temperature_max,
+		humidity_count, humidity_min, humidity_avg, humidity_max,
+		pressure_count, pressure_min, pressure_avg, pressure_max) VALUES
+		(1, '2025-01-01', 3, 3, -2, 1, 4, 0, NULL, NULL, NULL, 0, NULL, NULL, NULL),
+		(1, '2025-01-02', 1, 1, 5, 5, 5, 1, 80, 80, 80, 0, NULL, NULL, NULL),
+		(1, '2025-02-01', 1, 1, 7, 7, 7, 0, NULL, NULL, NULL, 1, 1010, 1010, 1010),
+		(2, '2025-01-01', 1, 1, 30, 30, 30, 0, NULL, NULL, NULL, 0, NULL, NULL, NULL)`); err != nil {
+		t.Fatalf("insert rollups: %v", err)
+	}
+	repo := NewRepository(db)
+
+	months, err := repo.GetMonthlyClimate(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("GetMonthlyClimate: %v", err)
+	}
+	if len(months) != 2 || months[0].Month != "2025-01" || months[1].Month != "2025-02" {
+		t.Fatalf("months = %+v; want 2025-01, 2025-02", months)
+	}
+	jan := months[0]
+	if jan.Days != 2 || jan.Count != 4 {
+		t.Errorf("January days, count = %d, %d; want 2, 4", jan.Days, jan.Count)
+	}
+	// The average is weighted by readings: (3×1 + 1×5) / 4.
+	if jan.Temperature.Count != 4 || *jan.Temperature.Min != -2 || *jan.Temperature.Avg != 2 || *jan.Temperature.Max != 5 {
+		t.Errorf("January temperature = %+v; want count 4, -2/2/5", jan.Temperature)
+	}
+	if jan.Humidity.Count != 1 || *jan.Humidity.Avg != 80 || jan.Pressure.Avg != nil {
+		t.Errorf("January humidity, pressure = %+v, %+v", jan.Humidity, jan.Pressure)
+	}
+	if months[1].Pressure.Avg == nil || *months[1].Pressure.Avg != 1010 {
+		t.Errorf("February pressure = %+v; want avg 1010", months[1].Pressure)
+	}
+
+	none, err := repo.GetMonthlyClimate(context.Background(), "3")
+	if err != nil {
+		t.Fatalf("GetMonthlyClimate (no rollups): %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("GetMonthlyClimate (no rollups) = %+v; want empty", none)
+	}
 }
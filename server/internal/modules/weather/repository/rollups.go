@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+//go:embed sql/rollup-daily.sql
+var rollupDailySQL string
+
+//go:embed sql/rollup-hourly.sql
+var rollupHourlySQL string
+
+//go:embed sql/delete-hourly-rollups-before.sql
+var deleteHourlyRollupsBeforeSQL string
+
+//go:embed sql/delete-daily-rollups-before.sql
+var deleteDailyRollupsBeforeSQL string
+
+//go:embed sql/get-monthly-climate.sql
+var getMonthlyClimateSQL string
+
+// RollupDaily recomputes the daily rollups of every station for the UTC days
+// from from's day onward and returns the number of days written. When no
+// rollups exist yet, all readings are rolled up regardless of from.
+func (r *sqliteReadings) RollupDaily(ctx context.Context, from time.Time) (int, error) {
+	y, m, d := from.UTC().Date()
+	res, err := r.db.ExecContext(ctx, rollupDailySQL, tsMillis(time.Date(y, m, d, 0, 0, 0, 0, time.UTC)))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// RollupHourly recomputes the hourly rollups of every station for the UTC
+// hours from from's hour onward and returns the number of hours written. When
+// no rollups exist yet, all readings are rolled up regardless of from.
+func (r *sqliteReadings) RollupHourly(ctx context.Context, from time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, rollupHourlySQL, tsMillis(from.UTC().Truncate(time.Hour)))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// DeleteHourlyRollupsBefore deletes the station's hourly rollups of hours
+// starting before before and returns how many it deleted.
+func (r *sqliteReadings) DeleteHourlyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, deleteHourlyRollupsBeforeSQL, stationID, tsMillis(before))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// DeleteDailyRollupsBefore deletes the station's daily rollups of UTC days
+// before before's day and returns how many it deleted.
+func (r *sqliteReadings) DeleteDailyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, deleteDailyRollupsBeforeSQL, stationID, before.UTC().Format(time.DateOnly))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// GetMonthlyClimate aggregates the station's daily rollups by UTC calendar
+// month, oldest first. TemperatureAvgChange is left for the caller to fill.
+func (r *sqliteReadings) GetMonthlyClimate(ctx context.Context, stationID string) ([]types.MonthlyClimate, error) {
+	rows, err := r.db.QueryContext(ctx, getMonthlyClimateSQL, stationID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close monthly climate rows", "error", err)
+		}
+	}()
+	var out []types.MonthlyClimate
+	for rows.Next() {
+		var m types.MonthlyClimate
+		if err := rows.Scan(&m.Month, &m.Days, &m.Count,
+			&m.Temperature.Count, &m.Temperature.Min, &m.Temperature.Avg, &m.Temperature.Max,
+			&m.Humidity.Count, &m.Humidity.Min, &m.Humidity.Avg, &m.Humidity.Max,
+			&m.Pressure.Count, &m.Pressure.Min, &m.Pressure.Avg, &m.Pressure.Max,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
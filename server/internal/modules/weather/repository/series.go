@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// The recent series SeriesCache keeps: the last SeriesWindow of each station
+// in SeriesBucket aggregates, as drawn by the dashboard sparklines and the
+// default history range.
+const (
+	SeriesWindow = 24 * time.Hour
+	SeriesBucket = 30 * time.Minute
+)
+
+// SeriesCache keeps each station's recent aggregates (see SeriesWindow) in
+// memory so the landing page does not scan the readings store however many
+// rows it holds. GetAggregate calls with SeriesBucket buckets whose range is
+// bucket aligned and within the window are served from memory; others go to
+// the store. A station's series is loaded on first use and each insert
+// recomputes the bucket it falls in, so merged readings are counted once.
+// Other ReadingsStore methods go straight to the store.
+type SeriesCache struct {
+	ReadingsStore
+	*stationNames
+	now func() time.Time
+
+	mu     sync.RWMutex
+	series map[string]*stationSeries // by station ID
+}
+
+// stationSeries holds a station's buckets starting at since or later.
+type stationSeries struct {
+	since   time.Time
+	buckets map[time.Time]types.AggregateBucket // by bucket start
+}
+
+var _ ReadingsStore = (*SeriesCache)(nil)
+
+// NewSeriesCache wraps readings. stations resolves the station names that
+// telemetry may use instead of IDs.
+func NewSeriesCache(readings ReadingsStore, stations StationStore) *SeriesCache {
+	return &SeriesCache{
+		ReadingsStore: readings,
+		stationNames:  newStationNames(stations),
+		now:           time.Now,
+		series:        make(map[string]*stationSeries),
+	}
+}
+
+// seriesStart returns the start of the oldest bucket in the window at now.
+func seriesStart(now time.Time) time.Time {
+	return now.UTC().Truncate(SeriesBucket).Add(-SeriesWindow)
+}
+
+// GetAggregate serves recent SeriesBucket aggregates from memory.
+func (c *SeriesCache) GetAggregate(ctx context.Context, stationID string, from time.Time, to time.Time, bucket time.Duration) ([]types.AggregateBucket, error) {
+	now := c.now()
+	if bucket != SeriesBucket || !from.Equal(from.Truncate(bucket)) || !to.Equal(to.Truncate(bucket)) ||
+		from.Before(seriesStart(now)) {
+		return c.ReadingsStore.GetAggregate(ctx, stationID, from, to, bucket)
+	}
+	s, err := c.load(ctx, stationID, now)
+	if err != nil {
+		return nil, err
+	}
+	out := []types.AggregateBucket{}
+	if s == nil {
+		return out, nil
+	}
+	c.mu.RLock()
+	for start, b := range s.buckets {
+		if !start.Before(from) && start.Before(to) {
+			out = append(out, b)
+		}
+	}
+	c.mu.RUnlock()
+	slices.SortFunc(out, func(a, b types.AggregateBucket) int { return a.Start.Compare(b.Start) })
+	return out, nil
+}
+
+// load returns the station's cached series, loading it from the store if
+// needed and dropping buckets that left the window. Stations without recent
+// readings are not cached, so requests for unknown IDs cannot grow the map;
+// nil is returned for them.
+func (c *SeriesCache) load(ctx context.Context, stationID string, now time.Time) (*stationSeries, error) {
+	since := seriesStart(now)
+	c.mu.Lock()
+	s, ok := c.series[stationID]
+	if ok {
+		maps.DeleteFunc(s.buckets, func(start time.Time, _ types.AggregateBucket) bool { return start.Before(since) })
+		s.since = since
+	}
+	c.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	// Readings stamped ahead of the server clock count too.
+	loaded, err := c.ReadingsStore.GetAggregate(ctx, stationID, since, now.Add(SeriesWindow), SeriesBucket)
+	if err != nil || len(loaded) == 0 {
+		return nil, err
+	}
+	s = &stationSeries{since: since, buckets: make(map[time.Time]types.AggregateBucket, len(loaded))}
+	for _, b := range loaded {
+		s.buckets[b.Start] = b
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// An insert that completed meanwhile stored a fresher entry.
+	if cached, ok := c.series[stationID]; ok {
+		return cached, nil
+	}
+	c.series[stationID] = s
+	return s, nil
+}
+
+// InsertReading stores the reading and then recomputes its bucket.
+func (c *SeriesCache) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	if err := c.ReadingsStore.InsertReading(ctx, stationID, ts, receivedAt, temperature, humidity, pressure); err != nil {
+		return err
+	}
+	if id, ok := c.resolve(ctx, stationID); ok {
+		c.refresh(ctx, id, ts.UTC().Truncate(SeriesBucket))
+	} else {
+		logging.FromContext(ctx).Warn("series cache: station not found after insert", "station", stationID)
+	}
+	return nil
+}
+
+// InsertReadings stores the readings and then recomputes each bucket they
+// fall in once.
+func (c *SeriesCache) InsertReadings(ctx context.Context, readings []NewReading) ([]error, error) {
+	errs, err := c.ReadingsStore.InsertReadings(ctx, readings)
+	if err != nil {
+		return errs, err
+	}
+	type key struct {
+		id    string
+		start time.Time
+	}
+	refreshed := make(map[key]bool)
+	for i, nr := range readings {
+		if errs[i] != nil {
+			continue
+		}
+		id, ok := c.resolve(ctx, nr.StationID)
+		if !ok {
+			logging.FromContext(ctx).Warn("series cache: station not found after insert", "station", nr.StationID)
+			continue
+		}
+		refreshed[key{id, nr.Time.UTC().Truncate(SeriesBucket)}] = true
+	}
+	for k := range refreshed {
+		c.refresh(ctx, k.id, k.start)
+	}
+	return errs, nil
+}
+
+// refresh recomputes the bucket starting at start of a station that is
+// already cached. Stations not cached yet are loaded on their next read.
+func (c *SeriesCache) refresh(ctx context.Context, id string, start time.Time) {
+	c.mu.RLock()
+	s, ok := c.series[id]
+	covered := ok && !start.Before(s.since)
+	c.mu.RUnlock()
+	if !covered {
+		return
+	}
+	buckets, err := c.ReadingsStore.GetAggregate(ctx, id, start, start.Add(SeriesBucket), SeriesBucket)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		// Drop the entry so the next read reloads it from the store.
+		logging.FromContext(ctx).Warn("series cache: refresh failed", "station_id", id, "error", err)
+		delete(c.series, id)
+		return
+	}
+	if len(buckets) == 0 {
+		return
+	}
+	// Concurrent inserts may refresh out of order; readings are only added
+	// or merged, so never go back to a smaller count.
+	if cached, ok := s.buckets[start]; ok && cached.Count > buckets[0].Count {
+		return
+	}
+	s.buckets[start] = buckets[0]
+}
+
+// DeleteReadingsBefore deletes the readings and drops the station's cached
+// series, which may include deleted readings.
+func (c *SeriesCache) DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	n, err := c.ReadingsStore.DeleteReadingsBefore(ctx, stationID, before)
+	if n > 0 || err != nil {
+		c.mu.Lock()
+		delete(c.series, stationID)
+		c.mu.Unlock()
+	}
+	return n, err
+}
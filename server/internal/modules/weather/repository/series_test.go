@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSeriesCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() {
+		if closeErr := db.Close(); closeErr != nil {
+			t.Fatalf("close db: %v", closeErr)
+		}
+	}()
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (1, 'Garden')`); err != nil {
+		t.Fatalf("insert station: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES (1, unixepoch('2025-01-01T10:05:00Z') * 1000, 10)`); err != nil {
+		t.Fatalf("insert reading: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 12, 10, 0, 0, time.UTC)
+	cache := NewSeriesCache(NewSQLiteReadings(db), &repositoryImpl{db: db})
+	cache.now = func() time.Time { return now }
+	temp := func(v float64) *float64 { return &v }
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	buckets, err := cache.GetAggregate(context.Background(), "1", from, to, SeriesBucket)
+	if err != nil || len(buckets) != 1 || buckets[0].Count != 1 {
+		t.Fatalf("GetAggregate() = %v, %v; want the stored reading's bucket", buckets, err)
+	}
+
+	// Rows written behind the cache's back are not seen: reads come from memory.
+	if _, err := db.Exec(`INSERT INTO readings (station_id, ts, temperature_c) VALUES (1, unixepoch('2025-01-01T11:05:00Z') * 1000, 11)`); err != nil {
+		t.Fatalf("insert reading: %v", err)
+	}
+	if buckets, _ := cache.GetAggregate(context.Background(), "1", from, to, SeriesBucket); len(buckets) != 1 {
+		t.Errorf("cached buckets = %d; want 1", len(buckets))
+	}
+	if buckets, _ := cache.GetAggregate(context.Background(), "1", from, to, time.Hour); len(buckets) != 2 {
+		t.Errorf("hourly buckets = %d; want 2 from the store", len(buckets))
+	}
+	if buckets, _ := cache.GetAggregate(context.Background(), "1", from.Add(time.Minute), to, SeriesBucket); len(buckets) != 2 {
+		t.Errorf("unaligned range buckets = %d; want 2 from the store", len(buckets))
+	}
+
+	t.Run("insert recomputes the bucket", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 12, 5, 0, 0, time.UTC)
+		if err := cache.InsertReading(context.Background(), "1", ts, ts, temp(12), nil, nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		// A partial reading at the same time merges into the stored row.
+		if err := cache.InsertReading(context.Background(), "1", ts, ts, nil, temp(40), nil); err != nil {
+			t.Fatalf("InsertReading: %v", err)
+		}
+		buckets, _ := cache.GetAggregate(context.Background(), "1", from, to, SeriesBucket)
+		if len(buckets) != 2 {
+			t.Fatalf("buckets = %v; want 10:00 and 12:00", buckets)
+		}
+		b := buckets[1]
+		if !b.Start.Equal(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)) || b.Count != 1 ||
+			*b.Temperature.Avg != 12 || b.Humidity.Avg == nil || *b.Humidity.Avg != 40 {
+			t.Errorf("12:00 bucket = %+v; want one merged reading, 12 °C and 40%%", b)
+		}
+	})
+
+	t.Run("insert by name", func(t *testing.T) {
+		ts := time.Date(2025, 1, 1, 12, 6, 0, 0, time.UTC)
+		if _, err := cache.InsertReadings(context.Background(), []NewReading{{StationID: "Garden", Time: ts, ReceivedAt: ts, Temperature: temp(14)}}); err != nil {
+			t.Fatalf("InsertReadings: %v", err)
+		}
+		buckets, _ := cache.GetAggregate(context.Background(), "1", from, to, SeriesBucket)
+		if n := len(buckets); n != 2 || buckets[1].Count != 2 || *buckets[1].Temperature.Avg != 13 {
+			t.Errorf("12:00 bucket = %+v; want 2 readings averaging 13 °C", buckets[n-1])
+		}
+	})
+
+	t.Run("window slides", func(t *testing.T) {
+		now = now.Add(SeriesWindow - time.Hour)
+		from := seriesStart(now)
+		buckets, _ := cache.GetAggregate(context.Background(), "1", from, from.Add(SeriesWindow), SeriesBucket)
+		if len(buckets) != 1 || !buckets[0].Start.Equal(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("buckets = %v; want only 12:00 left in the window", buckets)
+		}
+		cache.mu.RLock()
+		n := len(cache.series["1"].buckets)
+		cache.mu.RUnlock()
+		if n != 1 {
+			t.Errorf("cached buckets = %d; want the old one dropped", n)
+		}
+	})
+
+	t.Run("delete drops the series", func(t *testing.T) {
+		if _, err := cache.DeleteReadingsBefore(context.Background(), "1", time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC)); err != nil {
+			t.Fatalf("DeleteReadingsBefore: %v", err)
+		}
+		cache.mu.RLock()
+		_, ok := cache.series["1"]
+		cache.mu.RUnlock()
+		if ok {
+			t.Error("series still cached after delete")
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+//go:embed sql/get-snapshots.sql
+var getSnapshotsSQL string
+
+//go:embed sql/insert-snapshot.sql
+var insertSnapshotSQL string
+
+//go:embed sql/delete-snapshot.sql
+var deleteSnapshotSQL string
+
+// ErrSnapshotNotFound is returned when a snapshot does not exist for the station.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// GetSnapshots returns the station's snapshots overlapping [from, to],
+// ordered by start time. A zero bound is open.
+func (r *repositoryImpl) GetSnapshots(ctx context.Context, stationID string, from time.Time, to time.Time) ([]types.Snapshot, error) {
+	rows, err := r.db.QueryContext(ctx, getSnapshotsSQL, stationID, timestampBound(from), timestampBound(to))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			logging.FromContext(ctx).Error("close snapshots rows", "error", err)
+		}
+	}()
+	var out []types.Snapshot
+	for rows.Next() {
+		s, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// CreateSnapshot attaches the image at url to [start, end] of a station.
+func (r *repositoryImpl) CreateSnapshot(ctx context.Context, stationID string, start time.Time, end time.Time, url string) (types.Snapshot, error) {
+	if _, err := r.GetStation(ctx, stationID); err != nil {
+		return types.Snapshot{}, err
+	}
+	return scanSnapshot(r.db.QueryRowContext(ctx, insertSnapshotSQL, stationID,
+		formatTimestamp(start), formatTimestamp(end), url))
+}
+
+// DeleteSnapshot removes a snapshot of a station.
+func (r *repositoryImpl) DeleteSnapshot(ctx context.Context, stationID string, snapshotID string) error {
+	res, err := r.db.ExecContext(ctx, deleteSnapshotSQL, snapshotID, stationID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSnapshotNotFound
+	}
+	return nil
+}
+
+func scanSnapshot(row interface{ Scan(...any) error }) (types.Snapshot, error) {
+	var s types.Snapshot
+	var start, end, created string
+	if err := row.Scan(&s.ID, &s.StationID, &start, &end, &s.URL, &created); err != nil {
+		return types.Snapshot{}, err
+	}
+	var err error
+	if s.Start, err = parseTimestamp(start); err != nil {
+		return types.Snapshot{}, err
+	}
+	if s.End, err = parseTimestamp(end); err != nil {
+		return types.Snapshot{}, err
+	}
+	if s.CreatedAt, err = parseTimestamp(created); err != nil {
+		return types.Snapshot{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1 @@
+DELETE FROM annotations WHERE id = ? AND station_id = ?;
@@ -0,0 +1,2 @@
+DELETE FROM daily_rollups
+WHERE station_id = ? AND day < ?;
@@ -0,0 +1,2 @@
+DELETE FROM hourly_rollups
+WHERE station_id = ? AND hour < ?;
@@ -0,0 +1,2 @@
+DELETE FROM readings
+WHERE station_id = ? AND ts < ?;
@@ -0,0 +1 @@
+DELETE FROM snapshots WHERE id = ? AND station_id = ?;
@@ -0,0 +1,29 @@
+-- Like get-aggregate.sql for buckets of whole hours: hours before the
+-- station's oldest raw reading, pruned by retention, come from its hourly
+-- rollups, with averages weighted by their counts.
+WITH cutoff AS (
+  SELECT COALESCE(MIN(ts) / 3600000 * 3600000, ?4) AS ts FROM readings WHERE station_id = ?2
+),
+points AS (
+  SELECT ts / 1000 / ?1 * ?1 AS bucket, 1 AS readings,
+    temperature_c IS NOT NULL AS temperature_count, temperature_c AS temperature_min, temperature_c AS temperature_sum, temperature_c AS temperature_max,
+    humidity_pct IS NOT NULL AS humidity_count, humidity_pct AS humidity_min, humidity_pct AS humidity_sum, humidity_pct AS humidity_max,
+    pressure_hpa IS NOT NULL AS pressure_count, pressure_hpa AS pressure_min, pressure_hpa AS pressure_sum, pressure_hpa AS pressure_max
+  FROM readings
+  WHERE station_id = ?2 AND ts >= ?3 AND ts < ?4
+  UNION ALL
+  SELECT h.hour / 1000 / ?1 * ?1, h.readings,
+    h.temperature_count, h.temperature_min, h.temperature_avg * h.temperature_count, h.temperature_max,
+    h.humidity_count, h.humidity_min, h.humidity_avg * h.humidity_count, h.humidity_max,
+    h.pressure_count, h.pressure_min, h.pressure_avg * h.pressure_count, h.pressure_max
+  FROM hourly_rollups h, cutoff c
+  WHERE h.station_id = ?2 AND h.hour >= ?3 AND h.hour < ?4 AND h.hour < c.ts
+)
+SELECT bucket,
+  SUM(readings),
+  SUM(temperature_count), MIN(temperature_min), SUM(temperature_sum) / NULLIF(SUM(temperature_count), 0), MAX(temperature_max),
+  SUM(humidity_count), MIN(humidity_min), SUM(humidity_sum) / NULLIF(SUM(humidity_count), 0), MAX(humidity_max),
+  SUM(pressure_count), MIN(pressure_min), SUM(pressure_sum) / NULLIF(SUM(pressure_count), 0), MAX(pressure_max)
+FROM points
+GROUP BY bucket
+ORDER BY bucket;
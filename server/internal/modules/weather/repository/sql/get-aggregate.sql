@@ -0,0 +1,9 @@
+SELECT ts / 1000 / ?1 * ?1 AS bucket,
+  COUNT(*),
+  COUNT(temperature_c), MIN(temperature_c), AVG(temperature_c), MAX(temperature_c),
+  COUNT(humidity_pct), MIN(humidity_pct), AVG(humidity_pct), MAX(humidity_pct),
+  COUNT(pressure_hpa), MIN(pressure_hpa), AVG(pressure_hpa), MAX(pressure_hpa)
+FROM readings
+WHERE station_id = ?2 AND ts >= ?3 AND ts < ?4
+GROUP BY bucket
+ORDER BY bucket;
@@ -0,0 +1,15 @@
+-- The range bounds are added as points so that missing data at either end of
+-- the range counts as a gap.
+WITH points AS (
+  SELECT ts FROM readings WHERE station_id = ?1 AND ts >= ?2 AND ts <= ?3
+  UNION ALL SELECT ?2
+  UNION ALL SELECT ?3
+),
+ordered AS (
+  SELECT ts, LAG(ts) OVER (ORDER BY ts) AS prev
+  FROM points
+)
+SELECT prev, ts
+FROM ordered
+WHERE prev IS NOT NULL AND (ts - prev) / 1000.0 > ?4
+ORDER BY prev;
@@ -0,0 +1,18 @@
+WITH vals AS (
+  SELECT {{column}} AS v
+  FROM readings
+  WHERE station_id = ? AND ts >= ? AND ts <= ? AND {{column}} IS NOT NULL
+),
+bounds AS (
+  SELECT MIN(v) AS lo, MAX(v) AS hi FROM vals
+)
+SELECT
+  CASE WHEN bounds.hi = bounds.lo THEN 0
+       ELSE MIN(CAST((vals.v - bounds.lo) * ? / (bounds.hi - bounds.lo) AS INTEGER), ? - 1)
+  END AS bucket,
+  COUNT(*) AS n,
+  bounds.lo,
+  bounds.hi
+FROM vals, bounds
+GROUP BY bucket
+ORDER BY bucket;
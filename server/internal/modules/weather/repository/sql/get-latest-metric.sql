@@ -0,0 +1,5 @@
+SELECT ts, {{column}} AS v, received_at
+FROM readings
+WHERE station_id = ? AND {{column}} IS NOT NULL
+ORDER BY ts DESC
+LIMIT 1;
@@ -1,7 +1,8 @@
 SELECT CAST(station_id AS TEXT) AS station_id, ts,
   COALESCE(temperature_c, 0) AS value,
   COALESCE(humidity_pct, 0) AS humidity_pct,
-  COALESCE(pressure_hpa, 0) AS pressure_hpa
+  COALESCE(pressure_hpa, 0) AS pressure_hpa,
+  received_at
 FROM readings
 WHERE station_id = ?
 ORDER BY ts DESC
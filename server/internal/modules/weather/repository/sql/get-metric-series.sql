@@ -0,0 +1,4 @@
+SELECT ts, {{column}} AS v
+FROM readings
+WHERE station_id = ? AND ts >= ? AND ts <= ? AND {{column}} IS NOT NULL
+ORDER BY ts ASC;
@@ -0,0 +1,11 @@
+SELECT substr(day, 1, 7) AS month, COUNT(*), SUM(readings),
+  SUM(temperature_count), MIN(temperature_min),
+  SUM(temperature_avg * temperature_count) / NULLIF(SUM(temperature_count), 0), MAX(temperature_max),
+  SUM(humidity_count), MIN(humidity_min),
+  SUM(humidity_avg * humidity_count) / NULLIF(SUM(humidity_count), 0), MAX(humidity_max),
+  SUM(pressure_count), MIN(pressure_min),
+  SUM(pressure_avg * pressure_count) / NULLIF(SUM(pressure_count), 0), MAX(pressure_max)
+FROM daily_rollups
+WHERE station_id = ?
+GROUP BY month
+ORDER BY month;
@@ -0,0 +1,6 @@
+SELECT r.ts
+FROM readings r
+JOIN stations s ON s.id = r.station_id
+WHERE (s.id = ?1 OR s.name = ?1) AND r.ts >= ?2 AND r.ts < ?3
+ORDER BY r.ts
+LIMIT 1;
@@ -0,0 +1,4 @@
+SELECT ts, temperature_c, humidity_pct, pressure_hpa, received_at
+FROM readings
+WHERE station_id = ? AND ts >= ? AND ts <= ?
+ORDER BY ts ASC;
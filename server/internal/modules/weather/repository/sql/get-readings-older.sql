@@ -0,0 +1,9 @@
+SELECT CAST(station_id AS TEXT) AS station_id, ts,
+  {{temperature}} AS value,
+  {{humidity}} AS humidity_pct,
+  {{pressure}} AS pressure_hpa,
+  received_at
+FROM readings
+WHERE station_id = ? AND ts >= ? AND ts {{upper}} ?
+ORDER BY ts DESC
+LIMIT ?;
@@ -0,0 +1,3 @@
+SELECT COUNT(*)
+FROM readings
+WHERE station_id = ? AND received_at >= ? AND received_at < ?;
@@ -0,0 +1,7 @@
+-- Snapshots overlapping [?2, ?3]; an empty bound is open.
+SELECT CAST(id AS TEXT), CAST(station_id AS TEXT), start_ts, end_ts, url, created_at
+FROM snapshots
+WHERE station_id = ?1
+  AND (?2 = '' OR end_ts >= ?2)
+  AND (?3 = '' OR start_ts <= ?3)
+ORDER BY start_ts, id;
@@ -1,3 +1,6 @@
-SELECT CAST(id AS TEXT) AS id, name
+SELECT CAST(id AS TEXT) AS id, name, COALESCE(CAST(shadow_of AS TEXT), '') AS shadow_of, COALESCE(photo_path, '') AS photo_path, latitude, longitude,
+  COALESCE(gateway_version, '') AS gateway_version, COALESCE(firmware_version, '') AS firmware_version, retention_days,
+  hourly_retention_days, daily_retention_days, COALESCE(interval_seconds, 0) AS interval_seconds
 FROM stations
+WHERE archived_at IS NULL
 ORDER BY name;
@@ -0,0 +1,16 @@
+WITH selected AS (
+  SELECT temperature_c, humidity_pct, pressure_hpa
+  FROM readings
+  WHERE station_id = ? AND ts >= ? AND ts <= ?
+), mean AS (
+  SELECT AVG(temperature_c) AS t, AVG(humidity_pct) AS h, AVG(pressure_hpa) AS p
+  FROM selected
+)
+SELECT COUNT(*),
+  COUNT(temperature_c), MIN(temperature_c), MAX(temperature_c), mean.t,
+  SUM((temperature_c - mean.t) * (temperature_c - mean.t)),
+  COUNT(humidity_pct), MIN(humidity_pct), MAX(humidity_pct), mean.h,
+  SUM((humidity_pct - mean.h) * (humidity_pct - mean.h)),
+  COUNT(pressure_hpa), MIN(pressure_hpa), MAX(pressure_hpa), mean.p,
+  SUM((pressure_hpa - mean.p) * (pressure_hpa - mean.p))
+FROM selected, mean;
@@ -0,0 +1,7 @@
+SELECT COUNT(*),
+  COUNT(temperature_c), MIN(temperature_c), AVG(temperature_c), MAX(temperature_c),
+  COUNT(humidity_pct), MIN(humidity_pct), AVG(humidity_pct), MAX(humidity_pct),
+  COUNT(pressure_hpa), MIN(pressure_hpa), AVG(pressure_hpa), MAX(pressure_hpa),
+  MIN(ts), MAX(ts)
+FROM readings
+WHERE station_id = ? AND ts >= ? AND ts <= ?;
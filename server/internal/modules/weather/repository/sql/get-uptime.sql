@@ -0,0 +1,32 @@
+-- Per station: the covered span and the time in gaps longer than the
+-- threshold. A station is only counted from its creation; the span bounds are
+-- added as points so missing data at either end counts as a gap. Times are
+-- unix milliseconds, as readings.ts.
+WITH stations_ms AS (
+  SELECT id, CAST(strftime('%s', created_at) AS INTEGER) * 1000
+    + CAST(substr(strftime('%f', created_at), 4, 3) AS INTEGER) AS created_ms
+  FROM stations
+  WHERE ?4 = '' OR id = ?4
+),
+bounds AS (
+  SELECT id AS station_id, MAX(created_ms, ?1) AS start_ts
+  FROM stations_ms
+  WHERE created_ms < ?2
+),
+points AS (
+  SELECT r.station_id, r.ts
+  FROM readings r
+  JOIN bounds b ON b.station_id = r.station_id
+  WHERE r.ts >= ?1 AND r.ts <= ?2 AND r.ts >= b.start_ts
+  UNION ALL SELECT station_id, start_ts FROM bounds
+  UNION ALL SELECT station_id, ?2 FROM bounds
+),
+intervals AS (
+  SELECT station_id,
+    (ts - LAG(ts) OVER (PARTITION BY station_id ORDER BY ts)) / 1000.0 AS seconds
+  FROM points
+)
+SELECT CAST(b.station_id AS TEXT),
+  (?2 - b.start_ts) / 1000.0 AS span,
+  COALESCE((SELECT SUM(i.seconds) FROM intervals i WHERE i.station_id = b.station_id AND i.seconds > ?3), 0) AS gap
+FROM bounds b;
@@ -0,0 +1,3 @@
+INSERT INTO annotations (station_id, start_ts, end_ts, note)
+VALUES (?, ?, ?, ?)
+RETURNING CAST(id AS TEXT), CAST(station_id AS TEXT), start_ts, end_ts, note, created_at;
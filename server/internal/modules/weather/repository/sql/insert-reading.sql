@@ -1,2 +1,7 @@
-INSERT OR REPLACE INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa)
-VALUES (?, ?, ?, ?, ?);
+INSERT INTO readings (station_id, ts, temperature_c, humidity_pct, pressure_hpa, received_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (station_id, ts) DO UPDATE SET
+  temperature_c = COALESCE(excluded.temperature_c, temperature_c),
+  humidity_pct = COALESCE(excluded.humidity_pct, humidity_pct),
+  pressure_hpa = COALESCE(excluded.pressure_hpa, pressure_hpa),
+  received_at = COALESCE(excluded.received_at, received_at);
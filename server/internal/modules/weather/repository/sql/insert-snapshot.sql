@@ -0,0 +1,3 @@
+INSERT INTO snapshots (station_id, start_ts, end_ts, url)
+VALUES (?, ?, ?, ?)
+RETURNING CAST(id AS TEXT), CAST(station_id AS TEXT), start_ts, end_ts, url, created_at;
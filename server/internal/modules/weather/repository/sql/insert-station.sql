@@ -0,0 +1,2 @@
+INSERT INTO stations (name, metadata, latitude, longitude) VALUES (?, '{}', ?, ?)
+ON CONFLICT(name) DO NOTHING;
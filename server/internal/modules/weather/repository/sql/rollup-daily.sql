@@ -0,0 +1,28 @@
+INSERT INTO daily_rollups (
+  station_id, day, readings,
+  temperature_count, temperature_min, temperature_avg, temperature_max,
+  humidity_count, humidity_min, humidity_avg, humidity_max,
+  pressure_count, pressure_min, pressure_avg, pressure_max
+)
+SELECT station_id, date(ts / 1000, 'unixepoch') AS day, COUNT(*),
+  COUNT(temperature_c), MIN(temperature_c), AVG(temperature_c), MAX(temperature_c),
+  COUNT(humidity_pct), MIN(humidity_pct), AVG(humidity_pct), MAX(humidity_pct),
+  COUNT(pressure_hpa), MIN(pressure_hpa), AVG(pressure_hpa), MAX(pressure_hpa)
+FROM readings
+-- With no rollups yet, backfill every reading.
+WHERE NOT EXISTS (SELECT 1 FROM daily_rollups) OR ts >= ?
+GROUP BY station_id, day
+ON CONFLICT (station_id, day) DO UPDATE SET
+  readings = excluded.readings,
+  temperature_count = excluded.temperature_count,
+  temperature_min = excluded.temperature_min,
+  temperature_avg = excluded.temperature_avg,
+  temperature_max = excluded.temperature_max,
+  humidity_count = excluded.humidity_count,
+  humidity_min = excluded.humidity_min,
+  humidity_avg = excluded.humidity_avg,
+  humidity_max = excluded.humidity_max,
+  pressure_count = excluded.pressure_count,
+  pressure_min = excluded.pressure_min,
+  pressure_avg = excluded.pressure_avg,
+  pressure_max = excluded.pressure_max;
@@ -0,0 +1 @@
+UPDATE stations SET retention_days = ?, hourly_retention_days = ?, daily_retention_days = ? WHERE id = ?;
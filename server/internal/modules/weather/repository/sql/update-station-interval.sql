@@ -0,0 +1 @@
+UPDATE stations SET interval_seconds = ?1 WHERE id = ?2 OR name = ?2;
@@ -0,0 +1 @@
+UPDATE stations SET latitude = ?, longitude = ? WHERE id = ?;
@@ -0,0 +1 @@
+UPDATE stations SET photo_path = NULLIF(?, '') WHERE id = ?;
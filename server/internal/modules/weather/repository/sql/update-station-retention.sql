@@ -0,0 +1 @@
+UPDATE stations SET retention_days = ? WHERE id = ?;
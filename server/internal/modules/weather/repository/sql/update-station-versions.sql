@@ -0,0 +1,4 @@
+UPDATE stations
+SET gateway_version = COALESCE(NULLIF(?1, ''), gateway_version),
+  firmware_version = COALESCE(NULLIF(?2, ''), firmware_version)
+WHERE id = ?3 OR name = ?3;
@@ -0,0 +1,4 @@
+INSERT INTO stations (name, metadata, shadow_of) VALUES (?, '{}', ?)
+ON CONFLICT(name) DO UPDATE SET shadow_of = excluded.shadow_of
+WHERE (stations.shadow_of IS NULL OR stations.shadow_of = excluded.shadow_of)
+  AND NOT EXISTS (SELECT 1 FROM stations AS shadows WHERE shadows.shadow_of = stations.id);
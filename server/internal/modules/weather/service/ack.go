@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"cloudpico-server/internal/events"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// Publisher sends a raw MQTT message. Implemented by *mqtt.Subscriber.
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+}
+
+// AckTopic returns the topic a station's reading with the given sequence is
+// acknowledged on. Gateways subscribe to stations/+/ack/+.
+func AckTopic(stationID string, sequence int) string {
+	return "stations/" + stationID + "/ack/" + strconv.Itoa(sequence)
+}
+
+// registerAckPublisher acknowledges every stored reading that carries a sequence
+// number, so gateways can drop it from their replay buffer once the server has
+// persisted it rather than when the broker took it.
+func registerAckPublisher(bus *events.Bus, publisher Publisher) func() {
+	return bus.Subscribe(events.ReadingCreated, func(e events.Event) {
+		t, ok := e.Payload.(cloudpico_shared.Telemetry)
+		if !ok || t.Sequence == nil {
+			return
+		}
+		ack := cloudpico_shared.Ack{StationID: t.StationID, Sequence: *t.Sequence, Timestamp: t.Timestamp, Metrics: t.Metrics()}
+		data, err := json.Marshal(ack)
+		if err != nil {
+			slog.Error("failed to marshal ack", "station_id", t.StationID, "error", err)
+			return
+		}
+		// ReadingCreated is published from the MQTT message callback; paho must not
+		// block on a publish token there, so send the ack from its own goroutine.
+		go func() {
+			if err := publisher.Publish(AckTopic(t.StationID, ack.Sequence), data); err != nil {
+				// The gateway resends unacknowledged readings, so a lost ack only costs a duplicate.
+				slog.Warn("failed to publish ack", "station_id", t.StationID, "sequence", ack.Sequence, "error", err)
+			}
+		}()
+	})
+}
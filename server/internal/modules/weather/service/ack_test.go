@@ -0,0 +1,70 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/events"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+type fakePublisher struct {
+	sent chan [2]string
+	err  error
+}
+
+func (f *fakePublisher) Publish(topic string, payload []byte) error {
+	f.sent <- [2]string{topic, string(payload)}
+	return f.err
+}
+
+func TestAckPublisher(t *testing.T) {
+	t.Run("acks readings with a sequence", func(t *testing.T) {
+		bus := events.NewBus()
+		pub := &fakePublisher{sent: make(chan [2]string, 1)}
+		defer registerAckPublisher(bus, pub)()
+		seq, humidity := 42, 55.0
+		ts := time.Date(2025, 2, 3, 14, 30, 0, 0, time.UTC)
+
+		bus.Publish(events.Event{
+			Topic:   events.ReadingCreated,
+			Payload: cloudpico_shared.Telemetry{StationID: "pico-1", Timestamp: ts, Humidity: &humidity, Sequence: &seq},
+		})
+
+		select {
+		case msg := <-pub.sent:
+			if msg[0] != "stations/pico-1/ack/42" {
+				t.Errorf("topic = %q; want stations/pico-1/ack/42", msg[0])
+			}
+			var ack cloudpico_shared.Ack
+			if err := json.Unmarshal([]byte(msg[1]), &ack); err != nil {
+				t.Fatalf("unmarshal ack: %v", err)
+			}
+			if ack.StationID != "pico-1" || ack.Sequence != 42 || !ack.Timestamp.Equal(ts) ||
+				len(ack.Metrics) != 1 || ack.Metrics[0] != cloudpico_shared.MetricHumidity {
+				t.Errorf("ack = %+v; want pico-1 seq 42 of humidity at %v", ack, ts)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no ack published")
+		}
+	})
+
+	t.Run("skips readings without a sequence", func(t *testing.T) {
+		bus := events.NewBus()
+		pub := &fakePublisher{sent: make(chan [2]string, 1), err: errors.New("unused")}
+		defer registerAckPublisher(bus, pub)()
+
+		bus.Publish(events.Event{
+			Topic:   events.ReadingCreated,
+			Payload: cloudpico_shared.Telemetry{StationID: "pico-1", Timestamp: time.Now()},
+		})
+
+		select {
+		case msg := <-pub.sent:
+			t.Errorf("unexpected ack %v", msg)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+)
+
+// IngestBatch runs payloads, each a telemetry object, through the ingest
+// pipeline as if published on topic source. The readings that pass the
+// stages before persist are stored together in one transaction; the stages
+// after persist then run for each stored reading. It returns one error per
+// payload, nil for readings stored or dropped. err is set when the batch
+// could not be stored at all, in which case none of it was.
+func (s *Service) IngestBatch(ctx context.Context, source string, payloads []json.RawMessage) (errs []error, err error) {
+	before, after, ok := s.pipeline.split("persist")
+	if !ok {
+		return nil, errors.New("ingest pipeline has no persist stage")
+	}
+
+	receivedAt := time.Now().UTC()
+	errs = make([]error, len(payloads))
+	var (
+		pending []*Ingest
+		index   []int // payload index of each pending reading
+		batch   []repository.NewReading
+	)
+	for i, payload := range payloads {
+		in := &Ingest{Topic: source, Payload: payload, ReceivedAt: receivedAt}
+		passed, err := runStages(ctx, before, in)
+		if err != nil {
+			errs[i] = err
+			s.pipeline.notify(in.Telemetry, err)
+			continue
+		}
+		if !passed {
+			continue
+		}
+		t := in.Telemetry
+		pending = append(pending, in)
+		index = append(index, i)
+		batch = append(batch, repository.NewReading{
+			StationID:   t.StationID,
+			Time:        t.Timestamp,
+			ReceivedAt:  in.ReceivedAt,
+			Temperature: t.Temperature,
+			Humidity:    t.Humidity,
+			Pressure:    t.Pressure,
+		})
+	}
+	if len(batch) == 0 {
+		return errs, nil
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "inserting readings batch", "source", source, "readings", len(batch), "rejected", len(payloads)-len(batch))
+	stored, err := s.repository.InsertReadings(ctx, batch)
+	if err != nil {
+		return nil, &StageError{Stage: "persist", Err: err}
+	}
+	for j, in := range pending {
+		i := index[j]
+		if stored[j] != nil {
+			errs[i] = &StageError{Stage: "persist", Err: stored[j]}
+		} else if _, err := runStages(ctx, after, in); err != nil {
+			errs[i] = err
+		}
+		s.pipeline.notify(in.Telemetry, errs[i])
+	}
+	return errs, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cloudpico-server/internal/events"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+func TestService_IngestBatch(t *testing.T) {
+	payloads := []json.RawMessage{
+		json.RawMessage(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":21.5}`),
+		json.RawMessage(`{"station_id":"pico-1","temperature_c":21.6}`),
+		json.RawMessage(`{"station_id":"skip","timestamp":"2025-02-03T14:31:00Z","temperature_c":1}`),
+		json.RawMessage(`{"station_id":"pico-2","timestamp":"2025-02-03T14:32:00Z","humidity_pct":40}`),
+		json.RawMessage(`"not telemetry"`),
+	}
+
+	t.Run("stores valid items and reports the others", func(t *testing.T) {
+		repo := &fakeRepo{}
+		bus := events.NewBus()
+		var published []string
+		bus.Subscribe(events.ReadingCreated, func(e events.Event) { published = append(published, e.StationID) })
+		s := NewService(repo, bus)
+		drop := ProcessorFunc("drop", func(_ context.Context, in *Ingest) error {
+			if in.Telemetry.StationID == "skip" {
+				return ErrDrop
+			}
+			return nil
+		})
+		if err := s.Pipeline().InsertBefore("persist", drop); err != nil {
+			t.Fatal(err)
+		}
+		var observed, rejected int
+		s.Pipeline().Observe(func(_ cloudpico_shared.Telemetry, err error) {
+			observed++
+			if Rejected(err) {
+				rejected++
+			}
+		})
+
+		errs, err := s.IngestBatch(context.Background(), "api/batch", payloads)
+		if err != nil {
+			t.Fatalf("IngestBatch() error = %v", err)
+		}
+		if len(errs) != len(payloads) {
+			t.Fatalf("errs = %v; want one per payload", errs)
+		}
+		for i, prefix := range []string{"", "validate:", "", "", "decode:"} {
+			if prefix == "" && errs[i] != nil || prefix != "" && (errs[i] == nil || !strings.HasPrefix(errs[i].Error(), prefix)) {
+				t.Errorf("errs[%d] = %v; want %q", i, errs[i], prefix)
+			}
+		}
+		if want := []string{"pico-1", "pico-2"}; !reflect.DeepEqual(repo.inserted, want) || !reflect.DeepEqual(published, want) {
+			t.Errorf("inserted = %v, published = %v; want %v", repo.inserted, published, want)
+		}
+		if observed != 4 || rejected != 2 {
+			t.Errorf("observed %d outcomes, %d rejected; want 4 and 2 (the dropped item is not observed)", observed, rejected)
+		}
+	})
+
+	t.Run("reports persist errors per item", func(t *testing.T) {
+		repo := &fakeRepo{insertErr: errors.New("humidity_pct out of range")}
+		errs, err := NewService(repo, nil).IngestBatch(context.Background(), "api/batch", payloads[:1])
+		if err != nil {
+			t.Fatalf("IngestBatch() error = %v", err)
+		}
+		if len(errs) != 1 || !errors.Is(errs[0], repo.insertErr) || !strings.HasPrefix(errs[0].Error(), "persist:") {
+			t.Errorf("errs = %v; want the wrapped insert error", errs)
+		}
+	})
+}
@@ -1,11 +1,16 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
-	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/metrics"
 	internalmqtt "cloudpico-server/internal/mqtt"
 	cloudpico_shared "cloudpico-shared/types"
 
@@ -35,6 +40,10 @@ func validateTelemetry(t cloudpico_shared.Telemetry) error {
 		}
 	}
 
+	if t.Interval != nil && *t.Interval <= 0 {
+		return fmt.Errorf("interval_s must be positive: %d", *t.Interval)
+	}
+
 	// At least one sensor reading should be present
 	if t.Temperature == nil && t.Humidity == nil && t.Pressure == nil {
 		return fmt.Errorf("at least one sensor reading (temperature, humidity, or pressure) is required")
@@ -43,71 +52,82 @@ func validateTelemetry(t cloudpico_shared.Telemetry) error {
 	return nil
 }
 
-func parseTelemetry(payload []byte) (cloudpico_shared.Telemetry, error) {
-	var telemetry cloudpico_shared.Telemetry
-	if err := json.Unmarshal(payload, &telemetry); err != nil {
-		return cloudpico_shared.Telemetry{}, err
+// decodeTelemetry unmarshals payload into t, which is reset first so optional
+// fields from a previous message never leak into this one.
+func decodeTelemetry(payload []byte, t *cloudpico_shared.Telemetry) error {
+	*t = cloudpico_shared.Telemetry{}
+	if err := json.Unmarshal(payload, t); err != nil {
+		*t = cloudpico_shared.Telemetry{}
+		return err
 	}
-	return telemetry, nil
+	return nil
 }
 
-// formatOptFloat formats an optional float for logging; returns "-" if nil.
-func formatOptFloat(p *float64, unit string) string {
+// optFloatAttr returns a float attribute for an optional value, or "-" if nil.
+// Typed attributes keep the per-message log line free of fmt allocations.
+func optFloatAttr(key string, p *float64) slog.Attr {
 	if p == nil {
-		return "-"
+		return slog.String(key, "-")
 	}
-	return fmt.Sprintf("%f %s", *p, unit)
+	return slog.Float64(key, *p)
 }
 
-// formatOptInt formats an optional int for logging; returns "-" if nil.
-func formatOptInt(p *int) string {
+// optIntAttr returns an int attribute for an optional value, or "-" if nil.
+func optIntAttr(key string, p *int) slog.Attr {
 	if p == nil {
-		return "-"
+		return slog.String(key, "-")
 	}
-	return fmt.Sprintf("%d", *p)
+	return slog.Int(key, *p)
 }
 
-// registerMQTTHandler sets up the weather module's MQTT message handler
-func registerMQTTHandler(subscriber *internalmqtt.Subscriber, repo repository.WeatherRepository) {
-	subscriber.SetMessageHandler(func(msg mqtt.Message) error {
-		telemetry, err := parseTelemetry(msg.Payload())
-		if err != nil {
-			return err
-		}
-
-		if err := validateTelemetry(telemetry); err != nil {
-			return err
-		}
+// messageFailures counts MQTT messages the pipeline failed, by the stage
+// that failed them; "decode" counts payloads that are not telemetry JSON.
+var messageFailures = metrics.Default.NewCounterVec("cloudpico_mqtt_message_failures_total",
+	"MQTT messages that failed to ingest, by pipeline stage.", "stage")
 
-		slog.Info("inserting reading",
-			"station_id", telemetry.StationID,
-			"timestamp", telemetry.Timestamp.String(),
-			"temperature", formatOptFloat(telemetry.Temperature, "°C"),
-			"humidity", formatOptFloat(telemetry.Humidity, "%"),
-			"pressure", formatOptFloat(telemetry.Pressure, "hPa"),
-			"battery", formatOptFloat(telemetry.Battery, "V"),
-			"sequence", formatOptInt(telemetry.Sequence),
-		)
+// failedStage returns the pipeline stage err comes from.
+func failedStage(err error) string {
+	var se *StageError
+	if errors.As(err, &se) {
+		return se.Stage
+	}
+	return "unknown"
+}
 
-		err = repo.InsertReading(
-			telemetry.StationID,
-			telemetry.Timestamp,
-			telemetry.Temperature,
-			telemetry.Humidity,
-			telemetry.Pressure,
+// ingestPool recycles Ingest values between messages. Subscribers on the event
+// bus receive the Telemetry by value, so nothing they hold points into a pooled Ingest.
+var ingestPool = sync.Pool{New: func() any { return new(Ingest) }}
+
+// handleMessage runs a single MQTT message through the ingest pipeline.
+func handleMessage(ctx context.Context, pipeline *Pipeline, topic string, payload []byte) error {
+	in := ingestPool.Get().(*Ingest)
+	defer func() {
+		*in = Ingest{}
+		ingestPool.Put(in)
+	}()
+	in.Topic = topic
+	in.Payload = payload
+	in.ReceivedAt = time.Now().UTC()
+
+	if err := pipeline.Run(ctx, in); err != nil {
+		messageFailures.Inc(failedStage(err))
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelError, "failed to ingest reading",
+			slog.String("topic", in.Topic),
+			slog.String("station_id", in.Telemetry.StationID),
+			slog.Any("error", err),
 		)
+		return err
+	}
 
-		if err != nil {
-			slog.Error("failed to insert reading",
-				"station_id", telemetry.StationID,
-				"error", err,
-			)
-			return err
-		}
+	logging.FromContext(ctx).LogAttrs(ctx, slog.LevelDebug, "successfully stored telemetry",
+		slog.String("station_id", in.Telemetry.StationID),
+	)
+	return nil
+}
 
-		slog.Debug("successfully stored telemetry",
-			"station_id", telemetry.StationID,
-		)
-		return nil
+// registerMQTTHandler sets up the weather module's MQTT message handler
+func registerMQTTHandler(subscriber *internalmqtt.Subscriber, pipeline *Pipeline) {
+	subscriber.SetMessageHandler(func(msg mqtt.Message) error {
+		return handleMessage(context.Background(), pipeline, msg.Topic(), msg.Payload())
 	})
 }
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// ErrDrop is returned by a processor to stop the pipeline without treating the
+// message as a failure (e.g. an outlier filter discarding a reading).
+var ErrDrop = errors.New("reading dropped")
+
+// Ingest carries a single message through the ingest pipeline. Processors read
+// and mutate it in place.
+type Ingest struct {
+	Topic      string
+	Payload    []byte
+	ReceivedAt time.Time
+	Telemetry  cloudpico_shared.Telemetry
+}
+
+// Processor is a single ingest pipeline stage.
+type Processor interface {
+	Name() string
+	Process(ctx context.Context, in *Ingest) error
+}
+
+type processorFunc struct {
+	name string
+	fn   func(ctx context.Context, in *Ingest) error
+}
+
+func (p processorFunc) Name() string { return p.name }
+
+func (p processorFunc) Process(ctx context.Context, in *Ingest) error { return p.fn(ctx, in) }
+
+// ProcessorFunc adapts a function to a named Processor.
+func ProcessorFunc(name string, fn func(ctx context.Context, in *Ingest) error) Processor {
+	return processorFunc{name: name, fn: fn}
+}
+
+// StageError is the error of a stage that failed a message.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string { return e.Stage + ": " + e.Err.Error() }
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// rejectingStages are the stages whose failures mean the message itself is
+// invalid, as opposed to the server failing to store it.
+var rejectingStages = map[string]bool{"decode": true, "validate": true, "sanitize": true}
+
+// Rejected reports whether err, from Run or IngestBatch, rejected the
+// message as invalid.
+func Rejected(err error) bool {
+	var se *StageError
+	return errors.As(err, &se) && rejectingStages[se.Stage]
+}
+
+// Observer is told the outcome of each message the pipeline does not drop:
+// err is nil once every stage passed. t is the telemetry as far as the
+// stages got; its StationID is empty when the message could not be decoded.
+type Observer func(t cloudpico_shared.Telemetry, err error)
+
+// Pipeline runs registered processors in order for every ingested message.
+type Pipeline struct {
+	processors []Processor
+	observers  []Observer
+}
+
+func NewPipeline(processors ...Processor) *Pipeline {
+	return &Pipeline{processors: processors}
+}
+
+// Use appends processors to the end of the pipeline.
+func (p *Pipeline) Use(processors ...Processor) {
+	p.processors = append(p.processors, processors...)
+}
+
+// InsertBefore inserts proc before the stage with the given name.
+func (p *Pipeline) InsertBefore(name string, proc Processor) error {
+	for i, existing := range p.processors {
+		if existing.Name() == name {
+			p.processors = append(p.processors[:i], append([]Processor{proc}, p.processors[i:]...)...)
+			return nil
+		}
+	}
+	return fmt.Errorf("pipeline stage %q not found", name)
+}
+
+// InsertAfter inserts proc after the stage with the given name.
+func (p *Pipeline) InsertAfter(name string, proc Processor) error {
+	for i, existing := range p.processors {
+		if existing.Name() == name {
+			p.processors = append(p.processors[:i+1], append([]Processor{proc}, p.processors[i+1:]...)...)
+			return nil
+		}
+	}
+	return fmt.Errorf("pipeline stage %q not found", name)
+}
+
+// Observe registers o for the outcome of every message. Observers run
+// synchronously after the stages and must not block.
+func (p *Pipeline) Observe(o Observer) {
+	p.observers = append(p.observers, o)
+}
+
+func (p *Pipeline) notify(t cloudpico_shared.Telemetry, err error) {
+	for _, o := range p.observers {
+		o(t, err)
+	}
+}
+
+// Stages returns the names of the registered processors in order.
+func (p *Pipeline) Stages() []string {
+	names := make([]string, 0, len(p.processors))
+	for _, proc := range p.processors {
+		names = append(names, proc.Name())
+	}
+	return names
+}
+
+// Run passes in through every stage. It stops at the first error; ErrDrop is
+// reported as a nil error.
+func (p *Pipeline) Run(ctx context.Context, in *Ingest) error {
+	passed, err := runStages(ctx, p.processors, in)
+	if passed || err != nil {
+		p.notify(in.Telemetry, err)
+	}
+	return err
+}
+
+// split returns the processors before and after the named stage.
+func (p *Pipeline) split(name string) (before, after []Processor, ok bool) {
+	for i, proc := range p.processors {
+		if proc.Name() == name {
+			return p.processors[:i], p.processors[i+1:], true
+		}
+	}
+	return nil, nil, false
+}
+
+// runStages passes in through procs and reports whether it passed all of
+// them. It stops at the first error; ErrDrop is reported as a nil error.
+func runStages(ctx context.Context, procs []Processor, in *Ingest) (bool, error) {
+	for _, proc := range procs {
+		if err := proc.Process(ctx, in); err != nil {
+			if errors.Is(err, ErrDrop) {
+				return false, nil
+			}
+			return false, &StageError{Stage: proc.Name(), Err: err}
+		}
+	}
+	return true, nil
+}
+
+// NewDefaultPipeline returns the standard ingest pipeline:
+// decode → validate → sanitize → persist → versions → interval → publish.
+// Calibration and enrichment stages are inserted around these by name.
+func NewDefaultPipeline(repo repository.WeatherRepository, bus *events.Bus) *Pipeline {
+	return NewPipeline(
+		ProcessorFunc("decode", decodeStage),
+		ProcessorFunc("validate", validateStage),
+		ProcessorFunc("sanitize", sanitizeStage),
+		ProcessorFunc("persist", persistStage(repo)),
+		ProcessorFunc("versions", versionsStage(repo)),
+		ProcessorFunc("interval", intervalStage(repo)),
+		ProcessorFunc("publish", publishStage(bus)),
+	)
+}
+
+func decodeStage(_ context.Context, in *Ingest) error {
+	return decodeTelemetry(in.Payload, &in.Telemetry)
+}
+
+func validateStage(_ context.Context, in *Ingest) error {
+	return validateTelemetry(in.Telemetry)
+}
+
+// sanitizeStage normalizes station IDs and timestamps and discards non-finite values.
+func sanitizeStage(_ context.Context, in *Ingest) error {
+	t := &in.Telemetry
+	t.StationID = strings.TrimSpace(t.StationID)
+	t.Timestamp = t.Timestamp.UTC()
+	t.Temperature = finiteOrNil(t.Temperature)
+	t.Humidity = finiteOrNil(t.Humidity)
+	t.Pressure = finiteOrNil(t.Pressure)
+	t.Battery = finiteOrNil(t.Battery)
+	if t.StationID == "" {
+		return fmt.Errorf("station_id is required")
+	}
+	if t.Temperature == nil && t.Humidity == nil && t.Pressure == nil {
+		return fmt.Errorf("no finite sensor readings")
+	}
+	return nil
+}
+
+func finiteOrNil(p *float64) *float64 {
+	if p == nil || math.IsNaN(*p) || math.IsInf(*p, 0) {
+		return nil
+	}
+	return p
+}
+
+// MergeStage returns a processor, to run before persist, that lets sources
+// report metrics at different cadences. A reading missing some metrics is
+// moved onto the earliest stored reading in the same window-aligned bucket,
+// so persist merges it there instead of adding a sparse row. Complete
+// readings are left alone.
+func MergeStage(repo repository.ReadingsStore, window time.Duration) Processor {
+	return ProcessorFunc("merge", func(ctx context.Context, in *Ingest) error {
+		t := &in.Telemetry
+		if window <= 0 || (t.Temperature != nil && t.Humidity != nil && t.Pressure != nil) {
+			return nil
+		}
+		from := t.Timestamp.Truncate(window)
+		ts, ok, err := repo.GetReadingTime(ctx, t.StationID, from, from.Add(window))
+		if err != nil {
+			return fmt.Errorf("find reading to merge into: %w", err)
+		}
+		if ok && !ts.Equal(t.Timestamp) {
+			logging.FromContext(ctx).DebugContext(ctx, "merging partial reading",
+				"station_id", t.StationID, "timestamp", t.Timestamp, "into", ts)
+			t.Timestamp = ts.UTC()
+		}
+		return nil
+	})
+}
+
+// DiskSpaceStage returns a processor, to run before persist, that fails
+// every reading while check reports too little disk space to store it (see
+// db.DiskGuard). Gateways keep the readings they get no ack for and resend
+// them later.
+func DiskSpaceStage(check func() error) Processor {
+	return ProcessorFunc("disk", func(context.Context, *Ingest) error {
+		return check()
+	})
+}
+
+func persistStage(repo repository.ReadingsStore) func(context.Context, *Ingest) error {
+	return func(ctx context.Context, in *Ingest) error {
+		t := in.Telemetry
+		logging.FromContext(ctx).LogAttrs(ctx, slog.LevelInfo, "inserting reading",
+			slog.String("station_id", t.StationID),
+			slog.Time("timestamp", t.Timestamp),
+			optFloatAttr("temperature_c", t.Temperature),
+			optFloatAttr("humidity_pct", t.Humidity),
+			optFloatAttr("pressure_hpa", t.Pressure),
+			optFloatAttr("battery_v", t.Battery),
+			optIntAttr("sequence", t.Sequence),
+		)
+		return repo.InsertReading(ctx, t.StationID, t.Timestamp, in.ReceivedAt, t.Temperature, t.Humidity, t.Pressure)
+	}
+}
+
+// versionsStage records the gateway and firmware versions from telemetry
+// metadata on the station. It runs after persist so the station exists; a
+// failure is logged rather than failing an already stored reading.
+func versionsStage(repo repository.WeatherRepository) func(context.Context, *Ingest) error {
+	return func(ctx context.Context, in *Ingest) error {
+		meta := in.Telemetry.Metadata
+		if meta == nil || (meta.GatewayVersion == "" && meta.FirmwareVersion == "") {
+			return nil
+		}
+		gateway := strings.TrimSpace(meta.GatewayVersion)
+		firmware := strings.TrimSpace(meta.FirmwareVersion)
+		if err := repo.SetStationVersions(ctx, in.Telemetry.StationID, gateway, firmware); err != nil {
+			slog.Warn("failed to record station versions",
+				"station_id", in.Telemetry.StationID,
+				"gateway_version", gateway,
+				"firmware_version", firmware,
+				"error", err,
+			)
+		}
+		return nil
+	}
+}
+
+// intervalStage records the reading interval a station reports on it, for
+// gap detection. Like versionsStage it runs after persist and only logs a
+// failure.
+func intervalStage(repo repository.WeatherRepository) func(context.Context, *Ingest) error {
+	return func(ctx context.Context, in *Ingest) error {
+		t := in.Telemetry
+		if t.Interval == nil {
+			return nil
+		}
+		if err := repo.SetStationInterval(ctx, t.StationID, *t.Interval); err != nil {
+			logging.FromContext(ctx).Warn("failed to record station interval",
+				"station_id", t.StationID,
+				"interval_s", *t.Interval,
+				"error", err,
+			)
+		}
+		return nil
+	}
+}
+
+// publishStage announces the persisted reading on the event bus.
+func publishStage(bus *events.Bus) func(context.Context, *Ingest) error {
+	return func(_ context.Context, in *Ingest) error {
+		bus.Publish(events.Event{
+			Topic:     events.ReadingCreated,
+			StationID: in.Telemetry.StationID,
+			Payload:   in.Telemetry,
+		})
+		return nil
+	}
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/events"
+	"cloudpico-server/internal/modules/weather/repository"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// fakeRepo records InsertReading calls. Other repository methods are not used by the pipeline.
+type fakeRepo struct {
+	repository.WeatherRepository
+	inserted  []string
+	insertErr error
+	versions  [][3]string
+	intervals map[string]int
+	// readingTime is returned by GetReadingTime when set.
+	readingTime time.Time
+	inserts     []time.Time
+}
+
+func (f *fakeRepo) InsertReading(ctx context.Context, stationID string, ts time.Time, receivedAt time.Time, temperature *float64, humidity *float64, pressure *float64) error {
+	if f.insertErr != nil {
+		return f.insertErr
+	}
+	f.inserted = append(f.inserted, stationID)
+	f.inserts = append(f.inserts, ts)
+	return nil
+}
+
+func (f *fakeRepo) InsertReadings(ctx context.Context, readings []repository.NewReading) ([]error, error) {
+	errs := make([]error, len(readings))
+	for i, r := range readings {
+		errs[i] = f.InsertReading(ctx, r.StationID, r.Time, r.ReceivedAt, r.Temperature, r.Humidity, r.Pressure)
+	}
+	return errs, nil
+}
+
+func (f *fakeRepo) GetReadingTime(ctx context.Context, stationID string, from time.Time, to time.Time) (time.Time, bool, error) {
+	if f.readingTime.IsZero() || f.readingTime.Before(from) || !f.readingTime.Before(to) {
+		return time.Time{}, false, nil
+	}
+	return f.readingTime, true, nil
+}
+
+func (f *fakeRepo) SetStationInterval(ctx context.Context, stationID string, seconds int) error {
+	if f.intervals == nil {
+		f.intervals = make(map[string]int)
+	}
+	f.intervals[stationID] = seconds
+	return nil
+}
+
+func (f *fakeRepo) SetStationVersions(ctx context.Context, stationID string, gatewayVersion string, firmwareVersion string) error {
+	f.versions = append(f.versions, [3]string{stationID, gatewayVersion, firmwareVersion})
+	return nil
+}
+
+func TestPipeline_Run(t *testing.T) {
+	t.Run("runs stages in order", func(t *testing.T) {
+		var order []string
+		stage := func(name string) Processor {
+			return ProcessorFunc(name, func(context.Context, *Ingest) error {
+				order = append(order, name)
+				return nil
+			})
+		}
+		p := NewPipeline(stage("a"), stage("b"))
+		p.Use(stage("d"))
+		if err := p.InsertBefore("d", stage("c")); err != nil {
+			t.Fatalf("InsertBefore: %v", err)
+		}
+		if err := p.InsertAfter("d", stage("e")); err != nil {
+			t.Fatalf("InsertAfter: %v", err)
+		}
+
+		if err := p.Run(context.Background(), &Ingest{}); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		want := []string{"a", "b", "c", "d", "e"}
+		if !reflect.DeepEqual(order, want) {
+			t.Errorf("order = %v; want %v", order, want)
+		}
+		if !reflect.DeepEqual(p.Stages(), want) {
+			t.Errorf("Stages() = %v; want %v", p.Stages(), want)
+		}
+	})
+
+	t.Run("insert relative to unknown stage fails", func(t *testing.T) {
+		p := NewPipeline()
+		if err := p.InsertBefore("missing", ProcessorFunc("x", nil)); err == nil {
+			t.Error("InsertBefore(missing) = nil; want error")
+		}
+		if err := p.InsertAfter("missing", ProcessorFunc("x", nil)); err == nil {
+			t.Error("InsertAfter(missing) = nil; want error")
+		}
+	})
+
+	t.Run("stops at first error and names the stage", func(t *testing.T) {
+		ran := false
+		p := NewPipeline(
+			ProcessorFunc("fail", func(context.Context, *Ingest) error { return errors.New("boom") }),
+			ProcessorFunc("after", func(context.Context, *Ingest) error { ran = true; return nil }),
+		)
+		err := p.Run(context.Background(), &Ingest{})
+		if err == nil || !strings.Contains(err.Error(), "fail: boom") {
+			t.Errorf("Run() = %v; want error mentioning stage", err)
+		}
+		if ran {
+			t.Error("stage after failure ran")
+		}
+	})
+
+	t.Run("ErrDrop stops without error", func(t *testing.T) {
+		ran := false
+		p := NewPipeline(
+			ProcessorFunc("drop", func(context.Context, *Ingest) error { return ErrDrop }),
+			ProcessorFunc("after", func(context.Context, *Ingest) error { ran = true; return nil }),
+		)
+		if err := p.Run(context.Background(), &Ingest{}); err != nil {
+			t.Errorf("Run() = %v; want nil", err)
+		}
+		if ran {
+			t.Error("stage after drop ran")
+		}
+	})
+}
+
+func TestPipeline_Observe(t *testing.T) {
+	type outcome struct {
+		stationID string
+		err       error
+	}
+	var got []outcome
+	p := NewDefaultPipeline(&fakeRepo{}, nil)
+	p.Observe(func(tel cloudpico_shared.Telemetry, err error) {
+		got = append(got, outcome{tel.StationID, err})
+	})
+	if err := p.InsertBefore("persist", ProcessorFunc("outliers", func(_ context.Context, in *Ingest) error {
+		if in.Telemetry.StationID == "pico-drop" {
+			return ErrDrop
+		}
+		return nil
+	})); err != nil {
+		t.Fatalf("InsertBefore: %v", err)
+	}
+
+	for _, payload := range []string{
+		`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":20}`,
+		`{"station_id":"pico-1","timestamp":"2025-02-03T14:31:00Z","humidity_pct":140}`,
+		`{"station_id":"pico-drop","timestamp":"2025-02-03T14:32:00Z","temperature_c":20}`,
+	} {
+		_ = p.Run(context.Background(), &Ingest{Payload: []byte(payload)})
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("observed %+v; want the stored and the rejected message, not the dropped one", got)
+	}
+	if got[0].stationID != "pico-1" || got[0].err != nil {
+		t.Errorf("first outcome = %+v; want pico-1 stored", got[0])
+	}
+	if got[1].stationID != "pico-1" || !Rejected(got[1].err) {
+		t.Errorf("second outcome = %+v; want pico-1 rejected", got[1])
+	}
+}
+
+func TestRejected(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&StageError{Stage: "validate", Err: errors.New("humidity out of range")}, true},
+		{&StageError{Stage: "decode", Err: errors.New("bad JSON")}, true},
+		{&StageError{Stage: "persist", Err: errors.New("db locked")}, false},
+		{errors.New("validate: not a stage error"), false},
+	} {
+		if got := Rejected(tt.err); got != tt.want {
+			t.Errorf("Rejected(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultPipeline(t *testing.T) {
+	t.Run("persists valid telemetry", func(t *testing.T) {
+		repo := &fakeRepo{}
+		in := &Ingest{Payload: []byte(`{"station_id":"  pico-1 ","timestamp":"2025-02-03T14:30:00+01:00","temperature_c":21.5}`)}
+
+		if err := NewDefaultPipeline(repo, nil).Run(context.Background(), in); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		if !reflect.DeepEqual(repo.inserted, []string{"pico-1"}) {
+			t.Errorf("inserted = %v; want [pico-1]", repo.inserted)
+		}
+		if in.Telemetry.Timestamp.Location() != time.UTC {
+			t.Errorf("timestamp location = %v; want UTC", in.Telemetry.Timestamp.Location())
+		}
+	})
+
+	t.Run("publishes reading.created after persisting", func(t *testing.T) {
+		repo := &fakeRepo{}
+		bus := events.NewBus()
+		var got []events.Event
+		bus.Subscribe(events.ReadingCreated, func(e events.Event) { got = append(got, e) })
+		in := &Ingest{Payload: []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","humidity_pct":40}`)}
+
+		if err := NewDefaultPipeline(repo, bus).Run(context.Background(), in); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		if len(got) != 1 || got[0].StationID != "pico-1" {
+			t.Fatalf("events = %+v; want one reading.created for pico-1", got)
+		}
+		if _, ok := got[0].Payload.(cloudpico_shared.Telemetry); !ok {
+			t.Errorf("payload type = %T; want Telemetry", got[0].Payload)
+		}
+	})
+
+	t.Run("records versions from telemetry metadata", func(t *testing.T) {
+		repo := &fakeRepo{}
+		in := &Ingest{Payload: []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":20,` +
+			`"metadata":{"gateway_version":"1.3.0","firmware_version":"0.9.1"}}`)}
+
+		if err := NewDefaultPipeline(repo, nil).Run(context.Background(), in); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		want := [][3]string{{"pico-1", "1.3.0", "0.9.1"}}
+		if !reflect.DeepEqual(repo.versions, want) {
+			t.Errorf("versions = %v; want %v", repo.versions, want)
+		}
+	})
+
+	t.Run("records the reported interval", func(t *testing.T) {
+		repo := &fakeRepo{}
+		in := &Ingest{Payload: []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":20,"interval_s":120}`)}
+
+		if err := NewDefaultPipeline(repo, nil).Run(context.Background(), in); err != nil {
+			t.Fatalf("Run() = %v; want nil", err)
+		}
+		if want := map[string]int{"pico-1": 120}; !reflect.DeepEqual(repo.intervals, want) {
+			t.Errorf("intervals = %v; want %v", repo.intervals, want)
+		}
+	})
+
+	t.Run("rejects invalid JSON", func(t *testing.T) {
+		repo := &fakeRepo{}
+		err := NewDefaultPipeline(repo, nil).Run(context.Background(), &Ingest{Payload: []byte(`{`)})
+		if err == nil || !strings.HasPrefix(err.Error(), "decode:") {
+			t.Errorf("Run() = %v; want decode error", err)
+		}
+		if len(repo.inserted) != 0 {
+			t.Errorf("inserted = %v; want none", repo.inserted)
+		}
+	})
+
+	t.Run("rejects telemetry without readings", func(t *testing.T) {
+		repo := &fakeRepo{}
+		in := &Ingest{Payload: []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z"}`)}
+		err := NewDefaultPipeline(repo, nil).Run(context.Background(), in)
+		if err == nil || !strings.HasPrefix(err.Error(), "validate:") {
+			t.Errorf("Run() = %v; want validate error", err)
+		}
+	})
+
+	t.Run("rejects blank station id after trimming", func(t *testing.T) {
+		repo := &fakeRepo{}
+		in := &Ingest{Payload: []byte(`{"station_id":"   ","timestamp":"2025-02-03T14:30:00Z","temperature_c":1}`)}
+		err := NewDefaultPipeline(repo, nil).Run(context.Background(), in)
+		if err == nil || !strings.HasPrefix(err.Error(), "sanitize:") {
+			t.Errorf("Run() = %v; want sanitize error", err)
+		}
+	})
+
+	t.Run("returns persist error", func(t *testing.T) {
+		repo := &fakeRepo{insertErr: errors.New("db locked")}
+		in := &Ingest{Payload: []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":1}`)}
+		err := NewDefaultPipeline(repo, nil).Run(context.Background(), in)
+		if err == nil || !errors.Is(err, repo.insertErr) {
+			t.Errorf("Run() = %v; want wrapped insert error", err)
+		}
+	})
+}
+
+func TestHandleMessage_doesNotLeakBetweenMessages(t *testing.T) {
+	repo := &fakeRepo{}
+	bus := events.NewBus()
+	var got []cloudpico_shared.Telemetry
+	bus.Subscribe(events.ReadingCreated, func(e events.Event) {
+		got = append(got, e.Payload.(cloudpico_shared.Telemetry))
+	})
+	p := NewDefaultPipeline(repo, bus)
+
+	first := []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":20,"sequence":7}`)
+	second := []byte(`{"station_id":"pico-2","timestamp":"2025-02-03T14:31:00Z","humidity_pct":40}`)
+	for _, payload := range [][]byte{first, second} {
+		if err := handleMessage(context.Background(), p, "stations/x/telemetry", payload); err != nil {
+			t.Fatalf("handleMessage() = %v; want nil", err)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("events = %d; want 2", len(got))
+	}
+	if got[1].Sequence != nil || got[1].Temperature != nil {
+		t.Errorf("second telemetry = %+v; want no sequence or temperature", got[1])
+	}
+	if got[0].Sequence == nil || *got[0].Sequence != 7 {
+		t.Errorf("first telemetry sequence = %v; want 7", got[0].Sequence)
+	}
+}
+
+func TestDiskSpaceStage(t *testing.T) {
+	repo := &fakeRepo{}
+	full := errors.New("disk space low")
+	p := NewDefaultPipeline(repo, nil)
+	if err := p.InsertAfter("sanitize", DiskSpaceStage(func() error { return full })); err != nil {
+		t.Fatal(err)
+	}
+	in := &Ingest{Payload: []byte(`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":1}`)}
+	err := p.Run(context.Background(), in)
+	if !errors.Is(err, full) || !strings.HasPrefix(err.Error(), "disk:") || Rejected(err) {
+		t.Errorf("Run() = %v; want the disk stage error, not a rejection", err)
+	}
+	if len(repo.inserted) != 0 {
+		t.Errorf("inserted %d readings; want none", len(repo.inserted))
+	}
+}
+
+func TestHandleMessage_countsFailures(t *testing.T) {
+	p := NewDefaultPipeline(&fakeRepo{insertErr: errors.New("db locked")}, nil)
+	decode, persist := messageFailures.Value("decode"), messageFailures.Value("persist")
+	for _, payload := range []string{
+		`not json`,
+		`{"station_id":"pico-1","timestamp":"2025-02-03T14:30:00Z","temperature_c":1}`,
+	} {
+		if err := handleMessage(context.Background(), p, "stations/pico-1/telemetry", []byte(payload)); err == nil {
+			t.Errorf("handleMessage(%s) = nil; want an error", payload)
+		}
+	}
+	if got := messageFailures.Value("decode") - decode; got != 1 {
+		t.Errorf("decode failures = %d; want 1", got)
+	}
+	if got := messageFailures.Value("persist") - persist; got != 1 {
+		t.Errorf("persist failures = %d; want 1", got)
+	}
+}
+
+// BenchmarkHandleMessage measures allocations on the ingest hot path with an
+// in-memory repository, so only decode, logging and event overhead is counted.
+func BenchmarkHandleMessage(b *testing.B) {
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	b.Cleanup(func() { slog.SetDefault(prev) })
+
+	payload := []byte(`{"station_id":"pico-0000002A","timestamp":"2025-02-03T14:30:00Z",` +
+		`"temperature_c":21.5,"humidity_pct":55,"pressure_hpa":1013.25,"sequence":42}`)
+	repo := &fakeRepo{}
+	bus := events.NewBus()
+	bus.Subscribe(events.ReadingCreated, func(events.Event) {})
+	p := NewDefaultPipeline(repo, bus)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		repo.inserted = repo.inserted[:0]
+		if err := handleMessage(context.Background(), p, "stations/pico-0000002A/telemetry", payload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMergeStage(t *testing.T) {
+	stored := time.Date(2025, 3, 1, 10, 0, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		payload string
+		want    time.Time
+	}{
+		{"partial reading moves onto stored reading in bucket",
+			`{"station_id":"s1","timestamp":"2025-03-01T10:00:40Z","pressure_hpa":1012}`, stored},
+		{"partial reading in another bucket keeps its time",
+			`{"station_id":"s1","timestamp":"2025-03-01T10:01:10Z","pressure_hpa":1012}`, time.Date(2025, 3, 1, 10, 1, 10, 0, time.UTC)},
+		{"complete reading keeps its time",
+			`{"station_id":"s1","timestamp":"2025-03-01T10:00:40Z","temperature_c":20,"humidity_pct":50,"pressure_hpa":1012}`, time.Date(2025, 3, 1, 10, 0, 40, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepo{readingTime: stored}
+			p := NewDefaultPipeline(repo, nil)
+			if err := p.InsertBefore("persist", MergeStage(repo, time.Minute)); err != nil {
+				t.Fatalf("InsertBefore: %v", err)
+			}
+			if err := p.Run(context.Background(), &Ingest{Payload: []byte(tt.payload)}); err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+			if len(repo.inserts) != 1 || !repo.inserts[0].Equal(tt.want) {
+				t.Errorf("inserted at %v; want %v", repo.inserts, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/downsampling"
+	"cloudpico-server/internal/modules/weather/repository"
+)
+
+// PruneReadings applies each station's downsampling policy (see
+// downsampling.Policy.For): it deletes raw readings, hourly rollups and daily
+// rollups older than the station keeps them. A retention of 0 keeps that
+// resolution forever. Everything is kept for whole UTC days, so a retention
+// of n days at now keeps today and the n days before it. Archived stations
+// are not pruned. It returns the number of readings deleted.
+func PruneReadings(ctx context.Context, repo repository.WeatherRepository, policy downsampling.Policy, now time.Time) (int, error) {
+	stations, err := repo.GetStations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	var (
+		total int
+		errs  []error
+	)
+	for _, s := range stations {
+		keep := policy.For(s)
+		for _, p := range []struct {
+			what   string
+			days   int
+			delete func(context.Context, string, time.Time) (int, error)
+		}{
+			{"readings", keep.RawDays, repo.DeleteReadingsBefore},
+			{"hourly rollups", keep.HourlyDays, repo.DeleteHourlyRollupsBefore},
+			{"daily rollups", keep.DailyDays, repo.DeleteDailyRollupsBefore},
+		} {
+			if p.days <= 0 {
+				continue
+			}
+			before := today.AddDate(0, 0, -p.days)
+			n, err := p.delete(ctx, s.ID, before)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("station %s: prune %s: %w", s.ID, p.what, err))
+				continue
+			}
+			if n > 0 {
+				slog.Info("pruned "+p.what, "station_id", s.ID, "before", before, "rows", n)
+			}
+			if p.what == "readings" {
+				total += n
+			}
+		}
+	}
+	return total, errors.Join(errs...)
+}
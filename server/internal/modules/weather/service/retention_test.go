@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/modules/weather/downsampling"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+)
+
+// retentionRepo records DeleteReadingsBefore calls, and the rollup deletions
+// by station ID.
+type retentionRepo struct {
+	repository.WeatherRepository
+	stations  []types.Station
+	deleted   map[string]time.Time
+	hourly    map[string]time.Time
+	daily     map[string]time.Time
+	deleteErr map[string]error
+}
+
+func (r *retentionRepo) GetStations(ctx context.Context) ([]types.Station, error) {
+	return r.stations, nil
+}
+
+func (r *retentionRepo) DeleteReadingsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	if err := r.deleteErr[stationID]; err != nil {
+		return 0, err
+	}
+	r.deleted[stationID] = before
+	return 2, nil
+}
+
+func (r *retentionRepo) DeleteHourlyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	if r.hourly != nil {
+		r.hourly[stationID] = before
+	}
+	return 1, nil
+}
+
+func (r *retentionRepo) DeleteDailyRollupsBefore(ctx context.Context, stationID string, before time.Time) (int, error) {
+	if r.daily != nil {
+		r.daily[stationID] = before
+	}
+	return 1, nil
+}
+
+func TestPruneReadings(t *testing.T) {
+	forever, ninety := 0, 90
+	stations := []types.Station{
+		{ID: "1", Name: "Outdoor", RetentionDays: &forever},
+		{ID: "2", Name: "Test rig", RetentionDays: &ninety},
+		{ID: "3", Name: "Garage"},
+	}
+	now := time.Date(2025, 6, 15, 13, 30, 0, 0, time.UTC)
+
+	t.Run("overrides and default", func(t *testing.T) {
+		repo := &retentionRepo{stations: stations, deleted: map[string]time.Time{}}
+		n, err := PruneReadings(context.Background(), repo, downsampling.Policy{Defaults: types.Downsampling{RawDays: 365}}, now)
+		if err != nil {
+			t.Fatalf("PruneReadings() error = %v", err)
+		}
+		want := map[string]time.Time{
+			"2": time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC),
+			"3": time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC),
+		}
+		if !reflect.DeepEqual(repo.deleted, want) {
+			t.Errorf("deleted before = %v, want %v", repo.deleted, want)
+		}
+		if n != 4 {
+			t.Errorf("PruneReadings() = %d, want 4", n)
+		}
+	})
+
+	t.Run("no default keeps stations without override", func(t *testing.T) {
+		repo := &retentionRepo{stations: stations, deleted: map[string]time.Time{}}
+		if _, err := PruneReadings(context.Background(), repo, downsampling.Policy{}, now); err != nil {
+			t.Fatalf("PruneReadings() error = %v", err)
+		}
+		if _, ok := repo.deleted["2"]; !ok || len(repo.deleted) != 1 {
+			t.Errorf("deleted = %v, want only station 2", repo.deleted)
+		}
+	})
+
+	t.Run("ceiling caps overrides and forever", func(t *testing.T) {
+		repo := &retentionRepo{stations: stations, deleted: map[string]time.Time{}}
+		if _, err := PruneReadings(context.Background(), repo, downsampling.Policy{Defaults: types.Downsampling{RawDays: 365}, MaxRawDays: 30}, now); err != nil {
+			t.Fatalf("PruneReadings() error = %v", err)
+		}
+		before := time.Date(2025, 5, 16, 0, 0, 0, 0, time.UTC)
+		want := map[string]time.Time{"1": before, "2": before, "3": before}
+		if !reflect.DeepEqual(repo.deleted, want) {
+			t.Errorf("deleted before = %v, want %v", repo.deleted, want)
+		}
+	})
+
+	t.Run("rollups", func(t *testing.T) {
+		thirty, year := 30, 365
+		stations := []types.Station{
+			{ID: "1", Name: "Outdoor", RetentionDays: &forever},
+			{ID: "2", Name: "Test rig", RetentionDays: &thirty, HourlyRetentionDays: &thirty},
+		}
+		repo := &retentionRepo{stations: stations, deleted: map[string]time.Time{}, hourly: map[string]time.Time{}, daily: map[string]time.Time{}}
+		policy := downsampling.Policy{Defaults: types.Downsampling{RawDays: 30, HourlyDays: year}}
+		if _, err := PruneReadings(context.Background(), repo, policy, now); err != nil {
+			t.Fatalf("PruneReadings() error = %v", err)
+		}
+		// Station 1 keeps raw readings forever, so its rollups too.
+		if want := map[string]time.Time{"2": time.Date(2025, 5, 16, 0, 0, 0, 0, time.UTC)}; !reflect.DeepEqual(repo.hourly, want) {
+			t.Errorf("hourly deleted before = %v, want %v", repo.hourly, want)
+		}
+		if len(repo.daily) != 0 {
+			t.Errorf("daily deleted = %v, want none", repo.daily)
+		}
+	})
+
+	t.Run("continues after a failing station", func(t *testing.T) {
+		boom := errors.New("boom")
+		repo := &retentionRepo{stations: stations, deleted: map[string]time.Time{}, deleteErr: map[string]error{"2": boom}}
+		n, err := PruneReadings(context.Background(), repo, downsampling.Policy{Defaults: types.Downsampling{RawDays: 365}}, now)
+		if !errors.Is(err, boom) {
+			t.Errorf("PruneReadings() error = %v, want %v", err, boom)
+		}
+		if n != 2 || len(repo.deleted) != 1 {
+			t.Errorf("PruneReadings() = %d, deleted %v; want station 3 pruned", n, repo.deleted)
+		}
+	})
+}
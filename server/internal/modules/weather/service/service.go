@@ -1,18 +1,50 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+
+	"cloudpico-server/internal/events"
 	"cloudpico-server/internal/modules/weather/repository"
 	"cloudpico-server/internal/mqtt"
+	cloudpico_shared "cloudpico-shared/types"
 )
 
 type Service struct {
 	repository repository.WeatherRepository
+	pipeline   *Pipeline
+	bus        *events.Bus
+}
+
+func NewService(repository repository.WeatherRepository, bus *events.Bus) *Service {
+	return &Service{
+		repository: repository,
+		pipeline:   NewDefaultPipeline(repository, bus),
+		bus:        bus,
+	}
+}
+
+// Pipeline returns the ingest pipeline so callers can register additional stages
+// before Register is called.
+func (s *Service) Pipeline() *Pipeline {
+	return s.pipeline
 }
 
-func NewService(repository repository.WeatherRepository) *Service {
-	return &Service{repository: repository}
+// Register subscribes the ingest pipeline to telemetry. With acks, every
+// stored reading carrying a sequence is acknowledged on AckTopic.
+func (s *Service) Register(subscriber *mqtt.Subscriber, acks bool) {
+	registerMQTTHandler(subscriber, s.pipeline)
+	if acks && s.bus != nil {
+		registerAckPublisher(s.bus, subscriber)
+	}
 }
 
-func (s *Service) Register(subscriber *mqtt.Subscriber) {
-	registerMQTTHandler(subscriber, s.repository)
+// Ingest runs telemetry received outside MQTT through the ingest pipeline, as
+// if it had been published on topic source (e.g. "webhook/netatmo").
+func (s *Service) Ingest(ctx context.Context, source string, t cloudpico_shared.Telemetry) error {
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return handleMessage(ctx, s.pipeline, source, payload)
 }
@@ -1,10 +1,128 @@
 package types
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Metric names accepted by per-metric endpoints.
+const (
+	MetricTemperature = "temperature"
+	MetricHumidity    = "humidity"
+	MetricPressure    = "pressure"
+)
+
+// MetricInfo describes how a metric is displayed.
+type MetricInfo struct {
+	Name  string // one of the Metric* names
+	Label string // e.g. "Temperature"
+	// Format formats a value with its unit, e.g. "%.1f°C".
+	Format string
+}
+
+// Metrics is the metric registry: every metric a station may report, in the
+// default display order.
+var Metrics = []MetricInfo{
+	{Name: MetricTemperature, Label: "Temperature", Format: "%.1f°C"},
+	{Name: MetricHumidity, Label: "Humidity", Format: "%.0f%% humidity"},
+	{Name: MetricPressure, Label: "Pressure", Format: "%.0f hPa"},
+}
+
+// LookupMetric returns the registry entry of the named metric.
+func LookupMetric(name string) (MetricInfo, bool) {
+	for _, m := range Metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return MetricInfo{}, false
+}
+
+// Retention bounds, in days, for raw readings and rollups. MinRetentionDays
+// exceeds the days the rollup jobs recompute, so pruned days keep their
+// rollups and pruned rollups are not recomputed.
+const (
+	MinRetentionDays = 8
+	MaxRetentionDays = 36500
+)
+
+// Downsampling is how many days a station keeps each resolution of its
+// readings: raw readings, then hourly rollups, then daily rollups. 0 keeps
+// that resolution forever.
+type Downsampling struct {
+	RawDays    int `json:"rawDays"`
+	HourlyDays int `json:"hourlyDays"`
+	DailyDays  int `json:"dailyDays"`
+}
+
+// Quotas limit what the server stores, so an instance can be opened to other
+// users. Zero fields are unlimited.
+type Quotas struct {
+	// MaxStations bounds the stations, archived ones excluded, that ingest
+	// and the API may create.
+	MaxStations int `json:"maxStations"`
+	// MaxReadingsPerDay bounds the readings each station may ingest per UTC
+	// day of receipt.
+	MaxReadingsPerDay int `json:"maxReadingsPerDay"`
+	// MaxRetentionDays caps the raw readings retention of every station;
+	// keeping readings forever is then not allowed.
+	MaxRetentionDays int `json:"maxRetentionDays"`
+}
+
+// QuotaStatus is the configured quotas and the usage counted against them.
+type QuotaStatus struct {
+	Quotas   Quotas `json:"quotas"`
+	Stations int    `json:"stations"`
+	// Usage lists the readings each station received today (UTC), by station.
+	Usage []StationQuotaUsage `json:"usage"`
+}
+
+// StationQuotaUsage is the usage of one station.
+type StationQuotaUsage struct {
+	StationID     string `json:"stationId"`
+	StationName   string `json:"stationName"`
+	ReadingsToday int    `json:"readingsToday"`
+}
 
 type Station struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ShadowOf string `json:"shadowOf,omitempty"` // primary station ID when this is a shadow station
+	// PhotoPath is relative to the uploads directory; see photos.URL.
+	PhotoPath string   `json:"photoPath,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+	// GatewayVersion and FirmwareVersion are the versions last reported in
+	// telemetry metadata.
+	GatewayVersion  string `json:"gatewayVersion,omitempty"`
+	FirmwareVersion string `json:"firmwareVersion,omitempty"`
+	// RetentionDays overrides the server's raw readings retention for the
+	// station: 0 keeps readings forever, nil uses the default.
+	RetentionDays *int `json:"retentionDays,omitempty"`
+	// HourlyRetentionDays and DailyRetentionDays override the server's
+	// rollup retention likewise.
+	HourlyRetentionDays *int `json:"hourlyRetentionDays,omitempty"`
+	DailyRetentionDays  *int `json:"dailyRetentionDays,omitempty"`
+	// Downsampling is the policy the station is kept by, its overrides
+	// applied; see package downsampling.
+	Downsampling *Downsampling `json:"downsampling,omitempty"`
+	// IntervalSeconds is the interval between readings last reported by a
+	// station that adapts it, or 0 when unknown.
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+	// Uptime is filled by the stations API and StationDetail only.
+	Uptime *Uptime `json:"uptime,omitempty"`
+	// Health is filled by the stations API only; nil when nothing is known
+	// about the station yet.
+	Health *Health `json:"health,omitempty"`
+}
+
+// StationDetail is a station with its uptime and latest reading: the station
+// page as served to JSON clients.
+type StationDetail struct {
+	Station
+	Latest *Reading `json:"latest,omitempty"`
 }
 
 type Reading struct {
@@ -13,4 +131,319 @@ type Reading struct {
 	Value       float64   `json:"value"`       // temperature °C
 	HumidityPct float64   `json:"humidityPct"` // 0–100 or 0 if unset
 	PressureHpa float64   `json:"pressureHpa"` // hPa or 0 if unset
+	// ReceivedAt is when the server ingested the reading; Time is the
+	// device-reported measurement time. Nil for readings stored before
+	// receipt times were recorded.
+	ReceivedAt *time.Time `json:"receivedAt,omitempty"`
+}
+
+// StoredReading is a reading as stored, with the metrics the station did not
+// report left nil.
+type StoredReading struct {
+	Time        time.Time
+	Temperature *float64
+	Humidity    *float64
+	Pressure    *float64
+	ReceivedAt  *time.Time
+}
+
+// LatestMetric is the most recent non-null value of one metric. Metrics may
+// arrive in separate (merged) readings, so each carries its own times.
+type LatestMetric struct {
+	Value      float64    `json:"value"`
+	Time       time.Time  `json:"time"`
+	ReceivedAt *time.Time `json:"receivedAt,omitempty"`
+	// AgeSeconds and Stale are filled by the API: the time since Time, and
+	// whether it exceeds the gap threshold.
+	AgeSeconds float64 `json:"ageSeconds"`
+	Stale      bool    `json:"stale"`
+}
+
+// LatestMetrics is the latest value of each metric of a station; a metric is
+// null when the station has never reported it.
+type LatestMetrics struct {
+	StationID   string        `json:"stationId"`
+	Temperature *LatestMetric `json:"temperature"`
+	Humidity    *LatestMetric `json:"humidity"`
+	Pressure    *LatestMetric `json:"pressure"`
+}
+
+// Get returns the latest value of the named metric, or nil when the station
+// has never reported it or the name is unknown.
+func (m LatestMetrics) Get(name string) *LatestMetric {
+	switch name {
+	case MetricTemperature:
+		return m.Temperature
+	case MetricHumidity:
+		return m.Humidity
+	case MetricPressure:
+		return m.Pressure
+	}
+	return nil
+}
+
+// Annotation is a note attached to a time range of a station, e.g. "sensor
+// moved indoors". End equals Start for a single point in time.
+type Annotation struct {
+	ID        string    `json:"id"`
+	StationID string    `json:"stationId"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Snapshot is an image attached to a time range of a station, e.g. a sky
+// camera frame, by URL. End equals Start for a single point in time.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	StationID string    `json:"stationId"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MaxSnapshotURLLen bounds a snapshot URL in bytes.
+const MaxSnapshotURLLen = 2048
+
+// ValidateSnapshotURL checks that s is an absolute http or https URL of at
+// most MaxSnapshotURLLen bytes, as pages link to it and embed it.
+func ValidateSnapshotURL(s string) error {
+	if s == "" {
+		return errors.New("is required")
+	}
+	if len(s) > MaxSnapshotURLLen {
+		return fmt.Errorf("must be at most %d bytes", MaxSnapshotURLLen)
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// HistogramBucket is one bin of a value distribution: Lower <= v < Upper
+// (the last bucket also includes Upper).
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int     `json:"count"`
+}
+
+type Histogram struct {
+	StationID string            `json:"stationId"`
+	Metric    string            `json:"metric"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Total     int               `json:"total"`
+	Buckets   []HistogramBucket `json:"buckets"`
+}
+
+// MetricPoint is a single non-null value of one metric.
+type MetricPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Comparison summarizes how a shadow station's readings deviate from its primary.
+// Bias and MeanAbsError are shadow − primary; DriftPerDay is the slope of that
+// difference over time.
+type Comparison struct {
+	PrimaryID    string    `json:"primaryId"`
+	ShadowID     string    `json:"shadowId"`
+	Metric       string    `json:"metric"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Pairs        int       `json:"pairs"`
+	Bias         *float64  `json:"bias"`
+	MeanAbsError *float64  `json:"meanAbsError"`
+	DriftPerDay  *float64  `json:"driftPerDay"`
+}
+
+// MetricSummary holds min/avg/max of one metric; the pointers are nil when Count is 0.
+type MetricSummary struct {
+	Count int      `json:"count"`
+	Min   *float64 `json:"min"`
+	Avg   *float64 `json:"avg"`
+	Max   *float64 `json:"max"`
+}
+
+// Summary aggregates a station's readings over [From, To].
+type Summary struct {
+	StationID   string        `json:"stationId"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Count       int           `json:"count"`
+	First       *time.Time    `json:"first"`
+	Last        *time.Time    `json:"last"`
+	Temperature MetricSummary `json:"temperature"`
+	Humidity    MetricSummary `json:"humidity"`
+	Pressure    MetricSummary `json:"pressure"`
+}
+
+// MetricStats describes the distribution of one metric. StdDev is the sample
+// standard deviation; it is nil below two values, the others when Count is 0.
+type MetricStats struct {
+	Count  int      `json:"count"`
+	Min    *float64 `json:"min"`
+	Max    *float64 `json:"max"`
+	Avg    *float64 `json:"avg"`
+	StdDev *float64 `json:"stddev"`
+}
+
+// Stats describes a station's readings over [From, To].
+type Stats struct {
+	StationID   string      `json:"stationId"`
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	Count       int         `json:"count"`
+	Temperature MetricStats `json:"temperature"`
+	Humidity    MetricStats `json:"humidity"`
+	Pressure    MetricStats `json:"pressure"`
+}
+
+// Aggregate bucket widths accepted by GetAggregate.
+const (
+	BucketHour = "1h"
+	BucketDay  = "1d"
+)
+
+// AggregateBucket aggregates a station's readings in [Start, Start+bucket).
+// Buckets start on UTC hour or day boundaries.
+type AggregateBucket struct {
+	Start       time.Time     `json:"start"`
+	Count       int           `json:"count"`
+	Temperature MetricSummary `json:"temperature"`
+	Humidity    MetricSummary `json:"humidity"`
+	Pressure    MetricSummary `json:"pressure"`
+}
+
+// Get returns the summary of the named metric, or a zero summary for unknown
+// names.
+func (b AggregateBucket) Get(name string) MetricSummary {
+	switch name {
+	case MetricTemperature:
+		return b.Temperature
+	case MetricHumidity:
+		return b.Humidity
+	case MetricPressure:
+		return b.Pressure
+	}
+	return MetricSummary{}
+}
+
+// Aggregate is a station's readings over [From, To) downsampled into
+// buckets; buckets without readings are omitted. Units is the unit system of
+// the values, "metric" or "imperial".
+type Aggregate struct {
+	StationID string            `json:"stationId"`
+	Bucket    string            `json:"bucket"`
+	Units     string            `json:"units"`
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Buckets   []AggregateBucket `json:"buckets"`
+}
+
+// MonthlyClimate aggregates a station's daily rollups over one UTC calendar
+// month ("2025-03"). Days counts the days with readings.
+type MonthlyClimate struct {
+	Month       string        `json:"month"`
+	Days        int           `json:"days"`
+	Count       int           `json:"count"`
+	Temperature MetricSummary `json:"temperature"`
+	Humidity    MetricSummary `json:"humidity"`
+	Pressure    MetricSummary `json:"pressure"`
+	// TemperatureAvgChange is Temperature.Avg minus that of the same month a
+	// year earlier; nil when either is missing.
+	TemperatureAvgChange *float64 `json:"temperatureAvgChange"`
+}
+
+// ClimateYear is one row of the monthly climate matrix. Months[0] is January;
+// a month without data is null.
+type ClimateYear struct {
+	Year   int                 `json:"year"`
+	Months [12]*MonthlyClimate `json:"months"`
+}
+
+// Climate is a station's monthly climate matrix, oldest year first.
+type Climate struct {
+	StationID string        `json:"stationId"`
+	Years     []ClimateYear `json:"years"`
+}
+
+// Gap is a period with no readings.
+type Gap struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Seconds float64   `json:"seconds"`
+}
+
+// GapReport lists a station's gaps over [From, To]: periods with no readings
+// longer than ExpectedInterval × Factor (ThresholdSeconds).
+type GapReport struct {
+	StationID               string    `json:"stationId"`
+	From                    time.Time `json:"from"`
+	To                      time.Time `json:"to"`
+	ExpectedIntervalSeconds float64   `json:"expectedIntervalSeconds"`
+	Factor                  float64   `json:"factor"`
+	ThresholdSeconds        float64   `json:"thresholdSeconds"`
+	Count                   int       `json:"count"`
+	TotalSeconds            float64   `json:"totalSeconds"`
+	LongestSeconds          float64   `json:"longestSeconds"`
+	Gaps                    []Gap     `json:"gaps"`
+}
+
+// Uptime holds a station's data availability: the percentage of each window
+// not covered by gaps. A nil field means the station did not exist yet.
+type Uptime struct {
+	Day   *float64 `json:"24h,omitempty"`
+	Week  *float64 `json:"7d,omitempty"`
+	Month *float64 `json:"30d,omitempty"`
+}
+
+// Health statuses, from the health score.
+const (
+	HealthGood = "good"
+	HealthFair = "fair"
+	HealthPoor = "poor"
+)
+
+// Health is a station's composite health score from 0 (failing) to 100, and
+// the component scores, also 0–100, it combines (see package health). A nil
+// component is unknown and left out of the score.
+type Health struct {
+	Score      int      `json:"score"`
+	Status     string   `json:"status"` // HealthGood, HealthFair or HealthPoor
+	Freshness  *float64 `json:"freshness,omitempty"`
+	Gaps       *float64 `json:"gaps,omitempty"`
+	Battery    *float64 `json:"battery,omitempty"`
+	RSSI       *float64 `json:"rssi,omitempty"`
+	Rejections *float64 `json:"rejections,omitempty"`
+}
+
+// GeoJSON (RFC 7946) types for the stations feed.
+
+type FeatureCollection struct {
+	Type     string    `json:"type"` // always "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+type Feature struct {
+	Type       string            `json:"type"` // always "Feature"
+	ID         string            `json:"id"`
+	Geometry   *Geometry         `json:"geometry"` // null when the station has no location
+	Properties StationProperties `json:"properties"`
+}
+
+// Geometry is a GeoJSON Point; Coordinates are [longitude, latitude].
+type Geometry struct {
+	Type        string     `json:"type"` // always "Point"
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type StationProperties struct {
+	Name     string   `json:"name"`
+	ShadowOf string   `json:"shadowOf,omitempty"`
+	Latest   *Reading `json:"latest"` // null when the station has not reported
 }
@@ -3,9 +3,11 @@ package views
 import (
 	"cloudpico-server/internal/modules/weather/types"
 	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
+	"time"
 )
 
 var dashboardTmpl *template.Template
@@ -59,16 +61,124 @@ func RenderHistory(w io.Writer, data *HistoryParams) error {
 type StationReading struct {
 	StationID   string
 	StationName string
+	ThumbURL    string // empty when the station has no photo
+	Favorite    bool
 	Reading     *types.Reading
+	// Metrics holds each metric's own latest value and freshness; nil
+	// falls back to Reading.
+	Metrics *types.LatestMetrics
+	// CardMetrics are the metric names shown on the card, in order; nil
+	// shows every metric in registry order.
+	CardMetrics []string
+	// Sparkline charts the card's first metric over the last 24 hours; nil
+	// without enough readings.
+	Sparkline *Sparkline
+	// Health colors the card's health indicator; nil hides it.
+	Health *types.Health
 }
+
+// Sparkline is a small line chart of one metric's bucket averages, drawn as
+// an SVG polyline in a SparklineWidth × SparklineHeight view box.
+type Sparkline struct {
+	Label    string // e.g. "Temperature, last 24 hours"
+	Points   string // polyline points, "x,y x,y …"
+	MinLabel string // lowest average with unit, e.g. "12.5°C"
+	MaxLabel string
+}
+
+// Sparkline view box size.
+const (
+	SparklineWidth  = 100
+	SparklineHeight = 30
+)
+
+// MetricValue is one metric shown on a station card.
+type MetricValue struct {
+	Name  string
+	Text  string // value with unit, e.g. "21.5°C"
+	Time  time.Time
+	Stale bool
+}
+
+// Values returns the card's metric values in CardMetrics order, leaving out
+// metrics the station has no value for. The first is the card's headline.
+func (s StationReading) Values() []MetricValue {
+	names := s.CardMetrics
+	if names == nil {
+		for _, m := range types.Metrics {
+			names = append(names, m.Name)
+		}
+	}
+	var out []MetricValue
+	for _, name := range names {
+		info, ok := types.LookupMetric(name)
+		if !ok {
+			continue
+		}
+		var v *types.LatestMetric
+		if s.Metrics != nil {
+			v = s.Metrics.Get(name)
+		} else if s.Reading != nil {
+			v = readingMetric(*s.Reading, name)
+		}
+		if v == nil {
+			continue
+		}
+		out = append(out, MetricValue{Name: name, Text: fmt.Sprintf(info.Format, v.Value), Time: v.Time, Stale: v.Stale})
+	}
+	return out
+}
+
+// readingMetric returns the named metric of r, or nil when it is unset
+// (humidity and pressure are 0 then).
+func readingMetric(r types.Reading, name string) *types.LatestMetric {
+	value := r.Value
+	switch name {
+	case types.MetricHumidity:
+		value = r.HumidityPct
+	case types.MetricPressure:
+		value = r.PressureHpa
+	}
+	if value == 0 && name != types.MetricTemperature {
+		return nil
+	}
+	return &types.LatestMetric{Value: value, Time: r.Time}
+}
+
+// CardMetricOption is a metric in the dashboard's card metrics toggles.
+type CardMetricOption struct {
+	Name    string
+	Label   string
+	Enabled bool
+}
+
 type DashboardData struct {
-	Stations []StationReading
+	Stations    []StationReading
+	CardMetrics []CardMetricOption
+}
+
+// RenderCardMetricsPartial executes only the card metrics toggles partial
+// into w.
+func RenderCardMetricsPartial(w io.Writer, data *DashboardData) error {
+	if dashboardTmpl == nil {
+		return errors.New("dashboard template not loaded: call views.LoadTemplates during startup")
+	}
+	return dashboardTmpl.ExecuteTemplate(w, "partials/card-metrics.html", data)
 }
 
-// PaginationItem is one entry in the pagination bar: either a page number or an ellipsis.
-type PaginationItem struct {
-	Page     int
-	Ellipsis bool
+// AnnotationMarker is a station note shown on the history list and charts.
+type AnnotationMarker struct {
+	Label string // time range, e.g. "2025-01-02 3:04 PM – 5:00 PM"
+	Title string // RFC 3339 interval, for the tooltip
+	Note  string
+}
+
+// SnapshotMarker is a station snapshot shown on the history list and the
+// station page.
+type SnapshotMarker struct {
+	Label string // time range, e.g. "2025-01-02 3:04 PM – 5:00 PM"
+	Title string // RFC 3339 interval, for the tooltip
+	URL   string
 }
 
 // HistoryData is the view model for the history partial.
@@ -78,13 +188,19 @@ type HistoryData struct {
 	RangeLabel  string
 	RangeKey    string // for pagination links, e.g. "24h"
 	Readings    []types.Reading
-	CurrentPage int
+	Annotations []AnnotationMarker // notes overlapping the range
+	Snapshots   []SnapshotMarker   // images overlapping the range
+	CurrentPage int                // approximate: counted from the links followed
 	TotalPages  int
-	HasPrev     bool
-	HasNext     bool
+	HasPrev     bool // newer readings exist
+	HasNext     bool // older readings exist
 	PrevPage    int
 	NextPage    int
-	PageItems   []PaginationItem // page numbers and ellipsis for the pagination bar
+	PrevCursor  string // history cursor of the newer page
+	NextCursor  string // history cursor of the older page
+	// Sparkline charts temperature over the default range; nil for other
+	// ranges and without enough readings.
+	Sparkline *Sparkline
 }
 
 // RenderHistoryPartial executes only the history partial into w.
@@ -105,3 +221,145 @@ func RenderStationsPartial(w io.Writer, data *DashboardData) error {
 	}
 	return dashboardTmpl.ExecuteTemplate(w, "partials/stations.html", data)
 }
+
+// HistogramBar is one bar of the distribution chart.
+type HistogramBar struct {
+	Label     string // value range, e.g. "20.0–20.5 °C"
+	Count     int
+	HeightPct int // 0–100, relative to the tallest bar
+}
+
+// HistogramData is the view model for the histogram partial.
+type HistogramData struct {
+	StationID  string
+	Metric     string
+	RangeLabel string
+	Total      int
+	Bars       []HistogramBar
+	MinLabel   string // lower bound of the first bar, for the x axis
+	MaxLabel   string // upper bound of the last bar, for the x axis
+	// Annotations overlapping the range; they explain shifts in the distribution.
+	Annotations []AnnotationMarker
+}
+
+// RenderHistogramPartial executes only the histogram partial into w.
+func RenderHistogramPartial(w io.Writer, data *HistogramData) error {
+	if dashboardTmpl == nil {
+		return errors.New("dashboard template not loaded: call views.LoadTemplates during startup")
+	}
+	return dashboardTmpl.ExecuteTemplate(w, "partials/histogram.html", data)
+}
+
+// GapRow is one station's line in the gaps widget.
+type GapRow struct {
+	StationName string
+	Count       int
+	Downtime    string // total gap time, e.g. "1h 5m"
+	Longest     string
+}
+
+// GapsData is the view model for the gaps partial.
+type GapsData struct {
+	RangeLabel     string
+	ThresholdLabel string // shortest period counted as a gap, e.g. "3m"
+	Rows           []GapRow
+}
+
+// RenderGapsPartial executes only the gaps partial into w.
+func RenderGapsPartial(w io.Writer, data *GapsData) error {
+	if dashboardTmpl == nil {
+		return errors.New("dashboard template not loaded: call views.LoadTemplates during startup")
+	}
+	return dashboardTmpl.ExecuteTemplate(w, "partials/gaps.html", data)
+}
+
+// UptimeRow is one availability window on the station page.
+type UptimeRow struct {
+	Label   string // e.g. "Last 24 hours"
+	Percent string // e.g. "99.5%"; empty when the station is younger than the window
+	Warn    bool   // below the availability target
+}
+
+// DownsamplingRow is one resolution of readings on the station page.
+type DownsamplingRow struct {
+	Label    string // e.g. "Hourly rollups"
+	Keep     string // e.g. "365 days" or "forever"
+	Override bool   // set for the station rather than the server default
+}
+
+// StationData is the view model for the station detail page.
+type StationData struct {
+	Station        types.Station
+	PhotoURL       string // empty when the station has no photo
+	Location       string // "lat, lon"; empty when not set
+	Reading        *types.Reading
+	ThresholdLabel string // shortest period counted as a gap, e.g. "3m"
+	Uptime         []UptimeRow
+	Downsampling   []DownsamplingRow // empty when the policy is unknown
+	Snapshots      []SnapshotMarker  // from the last 24 hours
+}
+
+func RenderStation(w io.Writer, data *StationData) error {
+	if dashboardTmpl == nil {
+		return errors.New("station template not loaded: call views.LoadTemplates during startup")
+	}
+	return dashboardTmpl.ExecuteTemplate(w, "station.html", data)
+}
+
+// ClimateMonthRow is one month of a year's table on the climate page. Values
+// are formatted; empty strings render as n/a.
+type ClimateMonthRow struct {
+	Month       string // e.g. "Mar"
+	Days        int
+	TempMin     string
+	TempAvg     string
+	TempMax     string
+	TempChange  string // vs. the same month a year earlier, e.g. "+1.2"
+	HumidityAvg string
+	PressureAvg string
+}
+
+// ClimateYearTable lists the months of one year that have data.
+type ClimateYearTable struct {
+	Year int
+	Rows []ClimateMonthRow
+}
+
+// ClimateMatrixRow is one calendar month across years: the average
+// temperature per year in ClimateData.MatrixYears order.
+type ClimateMatrixRow struct {
+	Month string
+	Cells []string
+}
+
+// ClimateData is the view model for the monthly climate page.
+type ClimateData struct {
+	Station     types.Station
+	Years       []ClimateYearTable // newest first
+	MatrixYears []int              // oldest first
+	Matrix      []ClimateMatrixRow // January to December
+}
+
+func RenderClimate(w io.Writer, data *ClimateData) error {
+	if dashboardTmpl == nil {
+		return errors.New("climate template not loaded: call views.LoadTemplates during startup")
+	}
+	return dashboardTmpl.ExecuteTemplate(w, "climate.html", data)
+}
+
+// OnboardingData is the view model for the onboarding page shown in place of
+// the dashboard while there are no stations. Name, Latitude and Longitude
+// refill the add-station form after Error.
+type OnboardingData struct {
+	Error     string
+	Name      string
+	Latitude  string
+	Longitude string
+}
+
+func RenderOnboarding(w io.Writer, data *OnboardingData) error {
+	if dashboardTmpl == nil {
+		return errors.New("onboarding template not loaded: call views.LoadTemplates during startup")
+	}
+	return dashboardTmpl.ExecuteTemplate(w, "onboarding.html", data)
+}
@@ -3,6 +3,7 @@ package views
 import (
 	"bytes"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
 	"testing/fstest"
@@ -233,3 +234,259 @@ func TestRenderDashboard_writeError(t *testing.T) {
 type failingWriter struct{ err error }
 
 func (f *failingWriter) Write([]byte) (int, error) { return 0, f.err }
+
+func TestRenderHistogramPartial(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+
+	var buf bytes.Buffer
+	err := RenderHistogramPartial(&buf, &HistogramData{
+		Metric:     "temperature",
+		RangeLabel: "Last 24 hours",
+		Total:      3,
+		Bars:       []HistogramBar{{Label: "10.0–15.0 °C", Count: 3, HeightPct: 100}},
+		MinLabel:   "10.0 °C",
+		MaxLabel:   "15.0 °C",
+	})
+	if err != nil {
+		t.Fatalf("RenderHistogramPartial() = %v; want nil", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "histogram-bar") || !strings.Contains(out, "10.0 °C") || !strings.Contains(out, "15.0 °C") {
+		t.Errorf("output missing bars or axis labels; got %q", out)
+	}
+}
+
+func TestRenderGapsPartial(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+
+	t.Run("renders a row per station", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := RenderGapsPartial(&buf, &GapsData{
+			RangeLabel:     "Last 24 hours",
+			ThresholdLabel: "3m",
+			Rows: []GapRow{
+				{StationName: "Garden", Count: 2, Downtime: "1h 9m", Longest: "1h 5m"},
+				{StationName: "Attic"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("RenderGapsPartial() = %v; want nil", err)
+		}
+		out := buf.String()
+		if strings.Count(out, "<tr") != 3 || !strings.Contains(out, "1h 9m") || !strings.Contains(out, "over 3m") {
+			t.Errorf("output missing rows or labels; got %q", out)
+		}
+	})
+
+	t.Run("renders empty state", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RenderGapsPartial(&buf, &GapsData{RangeLabel: "Last 24 hours"}); err != nil {
+			t.Fatalf("RenderGapsPartial() = %v; want nil", err)
+		}
+		if !strings.Contains(buf.String(), "No stations") {
+			t.Errorf("output = %q; want empty state", buf.String())
+		}
+	})
+}
+
+func TestRenderStation(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	var buf bytes.Buffer
+	err := RenderStation(&buf, &StationData{
+		Station:        types.Station{ID: "1", Name: "Garden", FirmwareVersion: "0.3.1"},
+		ThresholdLabel: "3m",
+		Uptime: []UptimeRow{
+			{Label: "Last 24 hours", Percent: "100.0%"},
+			{Label: "Last 7 days", Percent: "98.0%", Warn: true},
+			{Label: "Last 30 days"},
+		},
+		Downsampling: []DownsamplingRow{
+			{Label: "Raw readings", Keep: "30 days", Override: true},
+			{Label: "Daily rollups", Keep: "forever"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RenderStation() = %v; want nil", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"<h1>Garden</h1>", "Firmware 0.3.1", "No recent reading", "98.0%", "n/a", "30 days <span class=\"downsampling-override\">", "forever"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q; got %q", want, out)
+		}
+	}
+	if strings.Count(out, "uptime-row-warn") != 1 {
+		t.Errorf("output = %q; want one highlighted window", out)
+	}
+}
+
+func TestRenderStationsPartial_favorites(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	var buf bytes.Buffer
+	err := RenderStationsPartial(&buf, &DashboardData{Stations: []StationReading{
+		{StationID: "2", StationName: "Garden", Favorite: true},
+		{StationID: "1", StationName: "Attic"},
+	}})
+	if err != nil {
+		t.Fatalf("RenderStationsPartial() = %v; want nil", err)
+	}
+	out := buf.String()
+	for _, want := range []string{`hx-delete="/api/v1/stations/2/favorite"`, `hx-post="/api/v1/stations/1/favorite"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q; got %q", want, out)
+		}
+	}
+	if strings.Index(out, "Garden") > strings.Index(out, "Attic") {
+		t.Error("output does not keep the given station order")
+	}
+}
+
+func TestRenderStationsPartial_health(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	var buf bytes.Buffer
+	err := RenderStationsPartial(&buf, &DashboardData{Stations: []StationReading{
+		{StationID: "1", StationName: "Attic", Health: &types.Health{Score: 42, Status: types.HealthPoor}},
+		{StationID: "2", StationName: "Garden"},
+	}})
+	if err != nil {
+		t.Fatalf("RenderStationsPartial() = %v; want nil", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `class="health-indicator health-poor" title="Health 42/100 (poor)">42<`) {
+		t.Errorf("output = %q; want a poor health indicator", out)
+	}
+	if strings.Count(out, "health-indicator") != 1 {
+		t.Errorf("output = %q; want no indicator without a score", out)
+	}
+}
+
+func TestRenderStationsPartial_latestMetrics(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	ts := time.Date(2025, 2, 3, 14, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := RenderStationsPartial(&buf, &DashboardData{Stations: []StationReading{{
+		StationID:   "1",
+		StationName: "Garden",
+		Reading:     &types.Reading{Value: 21.5, Time: ts},
+		Metrics: &types.LatestMetrics{
+			Temperature: &types.LatestMetric{Value: 21.5, Time: ts},
+			Humidity:    &types.LatestMetric{Value: 48, Time: ts.Add(-time.Hour), Stale: true},
+		},
+	}}})
+	if err != nil {
+		t.Fatalf("RenderStationsPartial() = %v; want nil", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"21.5°C", `class="reading-humidity is-stale" title="2025-02-03T13:30:00Z"`, "48% humidity"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q; got %q", want, out)
+		}
+	}
+	// A metric the station never reported is left out rather than shown as 0.
+	if strings.Contains(out, "hPa") {
+		t.Errorf("output shows pressure without a value; got %q", out)
+	}
+}
+
+func TestRenderStationsPartial_cardMetrics(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	ts := time.Date(2025, 2, 3, 14, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	err := RenderStationsPartial(&buf, &DashboardData{Stations: []StationReading{{
+		StationID:   "1",
+		StationName: "Garden",
+		Reading:     &types.Reading{Value: 21.5, HumidityPct: 48, Time: ts},
+		Metrics: &types.LatestMetrics{
+			Temperature: &types.LatestMetric{Value: 21.5, Time: ts},
+			Humidity:    &types.LatestMetric{Value: 48, Time: ts},
+		},
+		CardMetrics: []string{"pressure", "humidity"},
+	}}})
+	if err != nil {
+		t.Fatalf("RenderStationsPartial() = %v; want nil", err)
+	}
+	out := buf.String()
+	// Pressure has no value, so humidity becomes the headline; temperature is disabled.
+	if !strings.Contains(out, `class="reading-value reading-humidity"`) {
+		t.Errorf("output does not lead with humidity; got %q", out)
+	}
+	if strings.Contains(out, "°C") || strings.Contains(out, "hPa") {
+		t.Errorf("output shows a disabled or missing metric; got %q", out)
+	}
+}
+
+func TestStationReading_Values(t *testing.T) {
+	ts := time.Date(2025, 2, 3, 14, 30, 0, 0, time.UTC)
+	sr := StationReading{Reading: &types.Reading{Value: 21.5, PressureHpa: 1013, Time: ts}}
+	got := sr.Values()
+	want := []MetricValue{
+		{Name: "temperature", Text: "21.5°C", Time: ts},
+		{Name: "pressure", Text: "1013 hPa", Time: ts},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %+v; want %+v (unset humidity left out)", got, want)
+	}
+}
+
+func TestRenderHistoryPartial_annotations(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	var buf bytes.Buffer
+	err := RenderHistoryPartial(&buf, &HistoryData{
+		RangeLabel:  "Last 24 hours",
+		Annotations: []AnnotationMarker{{Label: "2025-01-02 3:04 PM", Title: "2025-01-02T15:04:00Z/2025-01-02T15:04:00Z", Note: "sensor <moved>"}},
+	})
+	if err != nil {
+		t.Fatalf("RenderHistoryPartial() = %v; want nil", err)
+	}
+	out := buf.String()
+	for _, want := range []string{`class="annotation-marker"`, "2025-01-02 3:04 PM", "sensor &lt;moved&gt;"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q; got %q", want, out)
+		}
+	}
+}
+
+func TestRenderClimate(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates(): %v", err)
+	}
+	var buf bytes.Buffer
+	err := RenderClimate(&buf, &ClimateData{
+		Station:     types.Station{ID: "1", Name: "Garden"},
+		Years:       []ClimateYearTable{{Year: 2025, Rows: []ClimateMonthRow{{Month: "Mar", Days: 31, TempMin: "-1.5", TempAvg: "6.2", TempMax: "17.0", TempChange: "+0.8"}}}},
+		MatrixYears: []int{2024, 2025},
+		Matrix:      []ClimateMatrixRow{{Month: "Mar", Cells: []string{"5.4", "6.2"}}, {Month: "Apr", Cells: []string{"9.9", ""}}},
+	})
+	if err != nil {
+		t.Fatalf("RenderClimate() = %v; want nil", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Garden climate", `href="/stations/1"`, "<th scope=\"col\">2024</th>", "&#43;0.8", "17.0", "9.9", "n/a"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q; got %q", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderClimate(&buf, &ClimateData{Station: types.Station{ID: "1", Name: "Garden"}}); err != nil {
+		t.Fatalf("RenderClimate(empty) = %v; want nil", err)
+	}
+	if !strings.Contains(buf.String(), "No daily rollups yet.") {
+		t.Errorf("empty output = %q; want the no-data note", buf.String())
+	}
+}
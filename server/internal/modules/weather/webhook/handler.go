@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloudpico-server/internal/db"
+	"cloudpico-server/internal/logging"
+	"cloudpico-server/internal/modules/weather/repository"
+	"cloudpico-server/internal/modules/weather/types"
+	"cloudpico-server/internal/utils"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// maxBodyBytes bounds a webhook request body.
+const maxBodyBytes = 1 << 20
+
+// IngestFunc stores one mapped reading; source identifies the webhook in logs.
+type IngestFunc func(ctx context.Context, source string, t cloudpico_shared.Telemetry) error
+
+// AttachFunc stores one mapped snapshot. It returns
+// repository.ErrStationNotFound when the station does not exist.
+type AttachFunc func(ctx context.Context, s Snapshot) error
+
+// ingestResponse is the body of a successful webhook request.
+type ingestResponse struct {
+	Accepted  int `json:"accepted"`
+	Snapshots int `json:"snapshots,omitempty"`
+}
+
+// Handler serves POST /api/v1/webhooks/{name}.
+type Handler struct {
+	defs   map[string]Definition
+	ingest IngestFunc
+	attach AttachFunc
+	replay *replayGuard
+}
+
+func NewHandler(defs []Definition, ingest IngestFunc, attach AttachFunc) *Handler {
+	byName := make(map[string]Definition, len(defs))
+	for _, def := range defs {
+		byName[def.Name] = def
+	}
+	return &Handler{defs: byName, ingest: ingest, attach: attach, replay: newReplayGuard()}
+}
+
+// ServeHTTP authenticates the request with the webhook's token, sent as
+// "Authorization: Bearer <token>" or in X-Webhook-Token, maps the body,
+// ingests every reading in order and then attaches every snapshot. It stops
+// at the first reading or snapshot that fails; those before it stay stored.
+// Webhooks with a replay window answer 422 to requests without a fresh
+// timestamp and nonce, 401 to a signature not matching them and the body, and
+// 409 to a nonce used before.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	def, ok := h.defs[name]
+	if !ok {
+		utils.WriteError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+	if !authorized(r, def.Token) {
+		utils.WriteError(w, http.StatusUnauthorized, "invalid webhook token")
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, "body too large")
+		return
+	}
+	if def.ReplayWindow > 0 {
+		if err := h.replay.check(def, r, body, time.Now()); err != nil {
+			logging.FromContext(r.Context()).Warn("webhook: request refused by replay check", "webhook", def.Name, "remote", r.RemoteAddr, "error", err)
+			status := http.StatusUnprocessableEntity
+			switch {
+			case errors.Is(err, errBadSignature):
+				status = http.StatusUnauthorized
+			case errors.Is(err, errReplayed):
+				status = http.StatusConflict
+			}
+			utils.WriteError(w, status, err.Error())
+			return
+		}
+	}
+	now := time.Now().UTC()
+	var readings []cloudpico_shared.Telemetry
+	if def.MapsMetrics() {
+		if readings, err = def.Map(body, now); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	snapshots, err := def.Snapshots(body, now)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, s := range snapshots {
+		if err := types.ValidateSnapshotURL(s.URL); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "snapshot_url "+err.Error())
+			return
+		}
+	}
+
+	source := "webhook/" + def.Name
+	for i, t := range readings {
+		if err := h.ingest(r.Context(), source, t); err != nil {
+			logging.FromContext(r.Context()).Warn("webhook: reading rejected", "webhook", def.Name, "record", i, "error", err)
+			if errors.Is(err, repository.ErrQuotaExceeded) {
+				utils.WriteError(w, http.StatusTooManyRequests, err.Error())
+				return
+			}
+			if errors.Is(err, db.ErrDiskSpaceLow) {
+				utils.WriteError(w, http.StatusInsufficientStorage, err.Error())
+				return
+			}
+			utils.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+	for i, s := range snapshots {
+		if err := h.attach(r.Context(), s); err != nil {
+			logging.FromContext(r.Context()).Warn("webhook: snapshot rejected", "webhook", def.Name, "snapshot", i, "error", err)
+			if errors.Is(err, repository.ErrStationNotFound) {
+				utils.WriteError(w, http.StatusUnprocessableEntity, "station "+s.StationID+" not found")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, "failed to attach snapshot")
+			return
+		}
+	}
+	logging.FromContext(r.Context()).Debug("webhook: readings ingested", "webhook", def.Name, "count", len(readings), "snapshots", len(snapshots))
+	utils.WriteJSON(w, http.StatusOK, ingestResponse{Accepted: len(readings), Snapshots: len(snapshots)})
+}
+
+func authorized(r *http.Request, token string) bool {
+	got := r.Header.Get("X-Webhook-Token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		got = bearer
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudpico-server/internal/metrics"
+)
+
+// Headers a webhook with a replay window requires on every request.
+const (
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderNonce     = "X-Webhook-Nonce"
+	// HeaderSignature is the hex HMAC-SHA256 of Sign, optionally prefixed
+	// "sha256=".
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Nonce length bounds, so a nonce is hard to guess and cheap to keep.
+const (
+	minNonceLen = 16
+	maxNonceLen = 128
+)
+
+// replayRejections counts requests refused by the replay check, by reason.
+var replayRejections = metrics.Default.NewCounterVec(
+	"cloudpico_webhook_replay_rejections_total",
+	"Webhook requests refused by the replay check, by reason (missing, invalid, signature, skew, replayed).",
+	"reason",
+)
+
+var (
+	errMissingReplayHeaders = errors.New(HeaderTimestamp + ", " + HeaderNonce + " and " + HeaderSignature + " are required")
+	errBadSignature         = errors.New(HeaderSignature + " does not match the request")
+	errInvalidNonce         = errors.New(HeaderNonce + " must be 16 to 128 characters")
+	errInvalidTimestamp     = errors.New(HeaderTimestamp + " must be Unix seconds or RFC 3339")
+	errClockSkew            = errors.New(HeaderTimestamp + " is outside the replay window")
+	errReplayed             = errors.New(HeaderNonce + " was already used")
+)
+
+// replayGuard remembers the nonces of accepted requests for as long as their
+// timestamps stay inside the replay window, after which the timestamp check
+// refuses them on its own. It is safe for concurrent use.
+type replayGuard struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time // webhook name + nonce → when it may be forgotten
+	nextPrune int
+}
+
+func newReplayGuard() *replayGuard {
+	return &replayGuard{seen: make(map[string]time.Time), nextPrune: 1024}
+}
+
+// Sign returns the signature of a request to a webhook with a replay
+// window: the HMAC-SHA256, keyed by the webhook's secret, of its timestamp
+// and nonce headers and its body, each but the last followed by a newline.
+// The secret never travels with the request, so a captured request cannot be
+// stamped afresh.
+func Sign(secret, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// check refuses r, whose body is body, when it lacks a timestamp, nonce or
+// signature, when the signature does not match def's secret, when its
+// timestamp is more than window away from now, or when def already accepted
+// its nonce. An accepted nonce is remembered, so retries need a new one.
+func (g *replayGuard) check(def Definition, r *http.Request, body []byte, now time.Time) error {
+	ts, nonce := strings.TrimSpace(r.Header.Get(HeaderTimestamp)), strings.TrimSpace(r.Header.Get(HeaderNonce))
+	sig := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(HeaderSignature)), "sha256=")
+	if ts == "" || nonce == "" || sig == "" {
+		replayRejections.Inc("missing")
+		return errMissingReplayHeaders
+	}
+	if len(nonce) < minNonceLen || len(nonce) > maxNonceLen {
+		replayRejections.Inc("invalid")
+		return errInvalidNonce
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, Sign(def.Secret, ts, nonce, body)) {
+		replayRejections.Inc("signature")
+		return errBadSignature
+	}
+	sent, err := parseRequestTime(ts)
+	if err != nil {
+		replayRejections.Inc("invalid")
+		return errInvalidTimestamp
+	}
+	if sent.Before(now.Add(-def.ReplayWindow)) || sent.After(now.Add(def.ReplayWindow)) {
+		replayRejections.Inc("skew")
+		return errClockSkew
+	}
+
+	key := def.Name + "\x00" + nonce
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if expires, ok := g.seen[key]; ok && now.Before(expires) {
+		replayRejections.Inc("replayed")
+		return errReplayed
+	}
+	g.seen[key] = sent.Add(def.ReplayWindow)
+	if len(g.seen) >= g.nextPrune {
+		g.prune(now)
+	}
+	return nil
+}
+
+// prune forgets expired nonces and lets the cache grow to twice what is left
+// before pruning again.
+func (g *replayGuard) prune(now time.Time) {
+	for key, expires := range g.seen {
+		if !now.Before(expires) {
+			delete(g.seen, key)
+		}
+	}
+	g.nextPrune = max(1024, 2*len(g.seen))
+}
+
+// parseRequestTime parses Unix seconds or an RFC 3339 time.
+func parseRequestTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
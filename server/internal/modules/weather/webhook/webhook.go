@@ -0,0 +1,414 @@
+// Package webhook ingests telemetry and camera snapshots pushed over HTTP by
+// third-party services.
+//
+// Each webhook is described by a Definition loaded from a JSON file: a name
+// (the URL segment of POST /api/v1/webhooks/{name}), a shared token, and a
+// mapping from field paths in the pushed JSON to telemetry fields. Services
+// can be added by editing the file, without code changes.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+// Telemetry fields a mapping can fill, named as in the telemetry JSON.
+const (
+	FieldStationID   = "station_id"
+	FieldTimestamp   = "timestamp"
+	FieldTemperature = "temperature_c"
+	FieldHumidity    = "humidity_pct"
+	FieldPressure    = "pressure_hpa"
+	FieldBattery     = "battery_v"
+	// FieldSnapshotURL maps the URL of a camera image to attach to the
+	// station at the record's timestamp. It is not a telemetry field.
+	FieldSnapshotURL = "snapshot_url"
+)
+
+// maxReplayWindow bounds how long nonces are remembered.
+const maxReplayWindow = time.Hour
+
+var (
+	metricFields = []string{FieldTemperature, FieldHumidity, FieldPressure, FieldBattery}
+	validName    = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// Definition configures one inbound webhook.
+type Definition struct {
+	Name  string
+	Token string
+	// StationID is assigned to every reading unless Fields maps station_id.
+	StationID string
+	// Records is the path to an array of readings in the body; empty means the
+	// body (or, if it is an array, each of its elements) is one reading.
+	Records string
+	// Fields maps telemetry field names to source paths.
+	Fields map[string]string
+	// TimestampUnit is "s" or "ms" for numeric timestamps; strings are parsed as
+	// RFC 3339. Without a timestamp mapping the receive time is used.
+	TimestampUnit string
+	// SnapshotWindow is how long after its timestamp a mapped snapshot
+	// covers; zero marks a point in time.
+	SnapshotWindow time.Duration
+	// ReplayWindow, when positive, requires every request to carry a
+	// timestamp within this much of the server's clock and a nonce not used
+	// before, signed with Secret (see HeaderTimestamp, HeaderNonce and
+	// HeaderSignature).
+	ReplayWindow time.Duration
+	// Secret keys the signatures of a webhook with a replay window. Unlike
+	// Token it is never sent.
+	Secret string
+}
+
+type definitionJSON struct {
+	Name          string            `json:"name"`
+	Token         string            `json:"token"`
+	StationID     string            `json:"station_id"`
+	Records       string            `json:"records"`
+	Fields        map[string]string `json:"fields"`
+	TimestampUnit string            `json:"timestamp_unit"`
+	// SnapshotWindow and ReplayWindow are Go durations such as "10m".
+	SnapshotWindow string `json:"snapshot_window"`
+	ReplayWindow   string `json:"replay_window"`
+	Secret         string `json:"secret"`
+}
+
+// LoadDefinitions reads webhook definitions from a JSON file; see ParseDefinitions.
+func LoadDefinitions(path string) ([]Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read webhooks file: %w", err)
+	}
+	defs, err := ParseDefinitions(data)
+	if err != nil {
+		return nil, fmt.Errorf("webhooks file %s: %w", path, err)
+	}
+	return defs, nil
+}
+
+// ParseDefinitions parses a JSON array of webhooks:
+//
+//	[{"name": "netatmo", "token": "…", "station_id": "garden",
+//	  "records": "body.devices",
+//	  "fields": {"timestamp": "time_utc", "temperature_c": "dashboard_data.Temperature"},
+//	  "timestamp_unit": "s"}]
+//
+// Paths are dot-separated keys; numeric segments index arrays ("data.0.temp").
+// At least one metric or snapshot_url field is required, and a station comes
+// from either station_id or a station_id field mapping. snapshot_window sets
+// how long a snapshot covers, e.g. "10m". replay_window, at most 1h, turns on
+// replay protection for senders able to stamp and sign their requests, e.g.
+// "5m", and requires a secret of at least 16 characters other than the token.
+func ParseDefinitions(data []byte) ([]Definition, error) {
+	var raw []definitionJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse webhooks: %w", err)
+	}
+	defs := make([]Definition, 0, len(raw))
+	names := make(map[string]bool, len(raw))
+	for i, r := range raw {
+		def, err := r.definition()
+		if err != nil {
+			return nil, fmt.Errorf("webhook %d (%s): %w", i, r.Name, err)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("webhook %d: duplicate name %q", i, def.Name)
+		}
+		names[def.Name] = true
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+func (r definitionJSON) definition() (Definition, error) {
+	def := Definition{
+		Name:          strings.TrimSpace(r.Name),
+		Token:         r.Token,
+		StationID:     strings.TrimSpace(r.StationID),
+		Records:       strings.TrimSpace(r.Records),
+		Fields:        make(map[string]string, len(r.Fields)),
+		TimestampUnit: strings.TrimSpace(r.TimestampUnit),
+	}
+	if !validName.MatchString(def.Name) {
+		return Definition{}, fmt.Errorf("name must be lowercase letters, digits, '-' or '_'")
+	}
+	if len(def.Token) < 16 {
+		return Definition{}, fmt.Errorf("token must be at least 16 characters")
+	}
+	known := append([]string{FieldStationID, FieldTimestamp, FieldSnapshotURL}, metricFields...)
+	hasMetric := false
+	for field, path := range r.Fields {
+		path = strings.TrimSpace(path)
+		if !slices.Contains(known, field) {
+			return Definition{}, fmt.Errorf("unknown field %q (allowed: %s)", field, strings.Join(known, ", "))
+		}
+		if path == "" {
+			return Definition{}, fmt.Errorf("field %q: path is required", field)
+		}
+		def.Fields[field] = path
+		hasMetric = hasMetric || slices.Contains(metricFields, field)
+	}
+	if !hasMetric && def.Fields[FieldSnapshotURL] == "" {
+		return Definition{}, fmt.Errorf("fields must map at least one of %s", strings.Join(append(metricFields, FieldSnapshotURL), ", "))
+	}
+	if def.StationID == "" && def.Fields[FieldStationID] == "" {
+		return Definition{}, fmt.Errorf("station_id or a station_id field is required")
+	}
+	switch def.TimestampUnit {
+	case "":
+		def.TimestampUnit = "s"
+	case "s", "ms":
+	default:
+		return Definition{}, fmt.Errorf("invalid timestamp_unit %q (allowed: s, ms)", def.TimestampUnit)
+	}
+	if w := strings.TrimSpace(r.SnapshotWindow); w != "" {
+		d, err := time.ParseDuration(w)
+		if err != nil || d < 0 {
+			return Definition{}, fmt.Errorf("invalid snapshot_window %q", r.SnapshotWindow)
+		}
+		def.SnapshotWindow = d
+	}
+	if w := strings.TrimSpace(r.ReplayWindow); w != "" {
+		d, err := time.ParseDuration(w)
+		if err != nil || d <= 0 || d > maxReplayWindow {
+			return Definition{}, fmt.Errorf("invalid replay_window %q (between 1s and %v)", r.ReplayWindow, maxReplayWindow)
+		}
+		def.ReplayWindow = d
+	}
+	def.Secret = r.Secret
+	switch {
+	case def.ReplayWindow > 0 && len(def.Secret) < 16:
+		return Definition{}, fmt.Errorf("replay_window requires a secret of at least 16 characters")
+	case def.ReplayWindow > 0 && def.Secret == def.Token:
+		return Definition{}, fmt.Errorf("secret must differ from the token, which is sent with every request")
+	case def.ReplayWindow == 0 && def.Secret != "":
+		return Definition{}, fmt.Errorf("secret is only used with replay_window")
+	}
+	return def, nil
+}
+
+// MapsMetrics reports whether the definition maps any telemetry metric; a
+// snapshot-only webhook ingests no readings.
+func (d Definition) MapsMetrics() bool {
+	for _, field := range metricFields {
+		if d.Fields[field] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Map extracts the readings in body. Readings without a mapped timestamp are
+// stamped with receivedAt.
+func (d Definition) Map(body []byte, receivedAt time.Time) ([]cloudpico_shared.Telemetry, error) {
+	records, err := d.records(body)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]cloudpico_shared.Telemetry, 0, len(records))
+	for i, rec := range records {
+		t, err := d.mapRecord(rec, receivedAt)
+		if err != nil {
+			return nil, recordError(records, i, err)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// Snapshot is a camera image a webhook record attaches to its station.
+type Snapshot struct {
+	StationID string
+	Start     time.Time
+	End       time.Time
+	URL       string
+}
+
+// Snapshots extracts the snapshots in body: one per record with a non-empty
+// snapshot_url, covering SnapshotWindow from the record's timestamp. It
+// returns none when the definition does not map snapshot_url.
+func (d Definition) Snapshots(body []byte, receivedAt time.Time) ([]Snapshot, error) {
+	path := d.Fields[FieldSnapshotURL]
+	if path == "" {
+		return nil, nil
+	}
+	records, err := d.records(body)
+	if err != nil {
+		return nil, err
+	}
+	var out []Snapshot
+	for i, rec := range records {
+		// Like a metric, a missing or null URL is simply not reported.
+		v, ok := lookup(rec, path)
+		if !ok || v == nil {
+			continue
+		}
+		url, ok := v.(string)
+		if !ok {
+			return nil, recordError(records, i, fmt.Errorf("snapshot_url: %q is not a string", path))
+		}
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		t, err := d.mapRecord(rec, receivedAt)
+		if err != nil {
+			return nil, recordError(records, i, err)
+		}
+		out = append(out, Snapshot{StationID: t.StationID, Start: t.Timestamp, End: t.Timestamp.Add(d.SnapshotWindow), URL: url})
+	}
+	return out, nil
+}
+
+// recordError prefixes err with the record index when the body has several.
+func recordError(records []any, i int, err error) error {
+	if len(records) > 1 {
+		return fmt.Errorf("record %d: %w", i, err)
+	}
+	return err
+}
+
+// records decodes body and returns its records.
+func (d Definition) records(body []byte) ([]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	records := []any{doc}
+	if d.Records != "" {
+		v, ok := lookup(doc, d.Records)
+		if !ok {
+			return nil, fmt.Errorf("records path %q not found", d.Records)
+		}
+		doc = v
+	}
+	if arr, ok := doc.([]any); ok {
+		records = arr
+	} else if d.Records != "" {
+		return nil, fmt.Errorf("records path %q is not an array", d.Records)
+	}
+	return records, nil
+}
+
+func (d Definition) mapRecord(rec any, receivedAt time.Time) (cloudpico_shared.Telemetry, error) {
+	t := cloudpico_shared.Telemetry{StationID: d.StationID, Timestamp: receivedAt}
+	if path := d.Fields[FieldStationID]; path != "" {
+		v, ok := lookup(rec, path)
+		if !ok {
+			return t, fmt.Errorf("station_id: path %q not found", path)
+		}
+		switch v := v.(type) {
+		case string:
+			t.StationID = strings.TrimSpace(v)
+		case json.Number:
+			t.StationID = v.String()
+		default:
+			return t, fmt.Errorf("station_id: %q is not a string or number", path)
+		}
+	}
+	if path := d.Fields[FieldTimestamp]; path != "" {
+		ts, err := d.timestamp(rec, path)
+		if err != nil {
+			return t, fmt.Errorf("timestamp: %w", err)
+		}
+		t.Timestamp = ts
+	}
+	targets := map[string]**float64{
+		FieldTemperature: &t.Temperature,
+		FieldHumidity:    &t.Humidity,
+		FieldPressure:    &t.Pressure,
+		FieldBattery:     &t.Battery,
+	}
+	for _, field := range metricFields {
+		path := d.Fields[field]
+		if path == "" {
+			continue
+		}
+		// A missing or null metric is simply not reported.
+		v, ok := lookup(rec, path)
+		if !ok || v == nil {
+			continue
+		}
+		f, err := number(v)
+		if err != nil {
+			return t, fmt.Errorf("%s: %q %w", field, path, err)
+		}
+		*targets[field] = &f
+	}
+	return t, nil
+}
+
+func (d Definition) timestamp(rec any, path string) (time.Time, error) {
+	v, ok := lookup(rec, path)
+	if !ok {
+		return time.Time{}, fmt.Errorf("path %q not found", path)
+	}
+	if s, ok := v.(string); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return ts.UTC(), nil
+		}
+	}
+	n, err := number(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a number", path)
+	}
+	if d.TimestampUnit == "ms" {
+		return time.UnixMilli(int64(n)).UTC(), nil
+	}
+	sec, frac := math.Modf(n)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+}
+
+// lookup follows a dot-separated path through decoded JSON.
+func lookup(v any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// number converts a JSON number or numeric string to a finite float.
+func number(v any) (float64, error) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = strings.TrimSpace(v)
+	default:
+		return 0, errors.New("is not a number")
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("is not a number")
+	}
+	return f, nil
+}
@@ -0,0 +1,360 @@
+package webhook
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloudpico-server/internal/db"
+	"cloudpico-server/internal/modules/weather/repository"
+	cloudpico_shared "cloudpico-shared/types"
+)
+
+const (
+	testToken  = "0123456789abcdef"
+	testSecret = "fedcba9876543210"
+)
+
+func TestParseDefinitions(t *testing.T) {
+	valid := `[{"name":"netatmo","token":"` + testToken + `","station_id":"garden","fields":{"temperature_c":"temp"}}]`
+	defs, err := ParseDefinitions([]byte(valid))
+	if err != nil {
+		t.Fatalf("ParseDefinitions() = %v; want nil", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "netatmo" || defs[0].TimestampUnit != "s" {
+		t.Errorf("defs = %+v; want netatmo with timestamp_unit s", defs)
+	}
+
+	tests := []struct {
+		name, json, want string
+	}{
+		{"bad name", `[{"name":"Net Atmo","token":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"}}]`, "name must be"},
+		{"short token", `[{"name":"a","token":"x","station_id":"1","fields":{"temperature_c":"t"}}]`, "token"},
+		{"unknown field", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"wind":"w"}}]`, "unknown field"},
+		{"no metric", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"timestamp":"ts"}}]`, "at least one"},
+		{"no station", `[{"name":"a","token":"` + testToken + `","fields":{"temperature_c":"t"}}]`, "station_id"},
+		{"bad window", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"snapshot_url":"img"},"snapshot_window":"soon"}]`, "snapshot_window"},
+		{"bad replay window", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"},"replay_window":"2h"}]`, "replay_window"},
+		{"replay window without secret", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"},"replay_window":"5m"}]`, "secret"},
+		{"secret is the token", `[{"name":"a","token":"` + testToken + `","secret":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"},"replay_window":"5m"}]`, "differ"},
+		{"secret without replay window", `[{"name":"a","token":"` + testToken + `","secret":"` + testSecret + `","station_id":"1","fields":{"temperature_c":"t"}}]`, "replay_window"},
+		{"bad unit", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"},"timestamp_unit":"us"}]`, "timestamp_unit"},
+		{"duplicate", `[{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"}},{"name":"a","token":"` + testToken + `","station_id":"1","fields":{"temperature_c":"t"}}]`, "duplicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDefinitions([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseDefinitions() err = %v; want containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefinition_Map(t *testing.T) {
+	received := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("records with paths", func(t *testing.T) {
+		def := Definition{
+			Records: "body.devices",
+			Fields: map[string]string{
+				FieldStationID:   "id",
+				FieldTimestamp:   "data.time",
+				FieldTemperature: "data.readings.0",
+				FieldHumidity:    "data.rh",
+			},
+			TimestampUnit: "s",
+		}
+		body := `{"body":{"devices":[
+			{"id":"garden","data":{"time":1740830400,"readings":[21.5],"rh":"55"}},
+			{"id":7,"data":{"time":1740830460,"readings":[19],"rh":null}}]}}`
+		got, err := def.Map([]byte(body), received)
+		if err != nil {
+			t.Fatalf("Map() = %v; want nil", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("len = %d; want 2", len(got))
+		}
+		first := got[0]
+		if first.StationID != "garden" || !first.Timestamp.Equal(time.Unix(1740830400, 0)) ||
+			first.Temperature == nil || *first.Temperature != 21.5 || first.Humidity == nil || *first.Humidity != 55 {
+			t.Errorf("first = %+v; want garden at 1740830400, 21.5 °C, 55%%", first)
+		}
+		if got[1].StationID != "7" || got[1].Humidity != nil {
+			t.Errorf("second = %+v; want station 7 without humidity", got[1])
+		}
+	})
+
+	t.Run("static station and receive time", func(t *testing.T) {
+		def := Definition{StationID: "5", Fields: map[string]string{FieldPressure: "p"}, TimestampUnit: "s"}
+		got, err := def.Map([]byte(`{"p":1013.2}`), received)
+		if err != nil {
+			t.Fatalf("Map() = %v; want nil", err)
+		}
+		if got[0].StationID != "5" || !got[0].Timestamp.Equal(received) || *got[0].Pressure != 1013.2 {
+			t.Errorf("got = %+v; want station 5 at receive time", got[0])
+		}
+	})
+
+	t.Run("timestamps", func(t *testing.T) {
+		for _, tt := range []struct {
+			unit, value string
+			want        time.Time
+		}{
+			{"s", `"2025-03-01T10:00:00+01:00"`, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)},
+			{"ms", `1740830400500`, time.UnixMilli(1740830400500)},
+			{"s", `1740830400.25`, time.Unix(1740830400, 250_000_000)},
+		} {
+			def := Definition{StationID: "5", Fields: map[string]string{FieldTimestamp: "ts", FieldTemperature: "t"}, TimestampUnit: tt.unit}
+			got, err := def.Map([]byte(`{"ts":`+tt.value+`,"t":1}`), received)
+			if err != nil || !got[0].Timestamp.Equal(tt.want) {
+				t.Errorf("Map(ts=%s, %s) = %v, %v; want %v", tt.value, tt.unit, got, err, tt.want)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		def := Definition{StationID: "5", Records: "items", Fields: map[string]string{FieldTemperature: "t"}}
+		for _, body := range []string{`{`, `{"other":[]}`, `{"items":{}}`, `{"items":[{"t":"warm"}]}`} {
+			if _, err := def.Map([]byte(body), received); err == nil {
+				t.Errorf("Map(%s) err = nil; want error", body)
+			}
+		}
+	})
+}
+
+func TestDefinition_Snapshots(t *testing.T) {
+	received := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	def := Definition{
+		Fields:         map[string]string{FieldStationID: "cam", FieldTimestamp: "ts", FieldSnapshotURL: "image.url"},
+		TimestampUnit:  "s",
+		SnapshotWindow: 10 * time.Minute,
+	}
+
+	got, err := def.Snapshots([]byte(`[
+		{"cam":"7","ts":1740830400,"image":{"url":" https://cam.example/a.jpg "}},
+		{"cam":"7","ts":1740830700,"image":{"url":null}},
+		{"cam":"8","ts":1740831000}
+	]`), received)
+	if err != nil {
+		t.Fatalf("Snapshots() err = %v", err)
+	}
+	start := time.Unix(1740830400, 0).UTC()
+	want := []Snapshot{{StationID: "7", Start: start, End: start.Add(10 * time.Minute), URL: "https://cam.example/a.jpg"}}
+	if len(got) != 1 || got[0] != want[0] {
+		t.Errorf("Snapshots() = %+v; want %+v", got, want)
+	}
+
+	if _, err := def.Snapshots([]byte(`{"cam":"7","image":{"url":42}}`), received); err == nil {
+		t.Error("Snapshots(numeric url) err = nil; want error")
+	}
+	if got, err := (Definition{StationID: "7", Fields: map[string]string{FieldTemperature: "t"}}).Snapshots([]byte(`{"t":1}`), received); got != nil || err != nil {
+		t.Errorf("Snapshots() without snapshot_url = %v, %v; want none", got, err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	def := Definition{Name: "acme", Token: testToken, StationID: "5", Fields: map[string]string{FieldTemperature: "t"}, TimestampUnit: "s"}
+	cam := Definition{Name: "cam", Token: testToken, StationID: "5", Fields: map[string]string{FieldSnapshotURL: "url"}, TimestampUnit: "s"}
+	var got []cloudpico_shared.Telemetry
+	var snapshots []Snapshot
+	var source string
+	var ingestErr, attachErr error
+	h := NewHandler([]Definition{def, cam}, func(_ context.Context, src string, tel cloudpico_shared.Telemetry) error {
+		source = src
+		got = append(got, tel)
+		return ingestErr
+	}, func(_ context.Context, s Snapshot) error {
+		snapshots = append(snapshots, s)
+		return attachErr
+	})
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/webhooks/{name}", h)
+
+	post := func(name, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/"+name, strings.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("acme", `[{"t":20},{"t":21}]`, "Authorization", "Bearer "+testToken); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"accepted":2`) {
+		t.Errorf("POST = %d %s; want 200 accepted 2", rec.Code, rec.Body.String())
+	}
+	if len(got) != 2 || source != "webhook/acme" || got[0].StationID != "5" {
+		t.Errorf("ingested %+v from %q; want 2 readings for station 5 from webhook/acme", got, source)
+	}
+
+	tests := []struct {
+		name   string
+		hook   string
+		body   string
+		header []string
+		want   int
+	}{
+		{"X-Webhook-Token header", "acme", `{"t":1}`, []string{"X-Webhook-Token", testToken}, http.StatusOK},
+		{"unknown webhook", "other", `{"t":1}`, []string{"X-Webhook-Token", testToken}, http.StatusNotFound},
+		{"missing token", "acme", `{"t":1}`, nil, http.StatusUnauthorized},
+		{"wrong token", "acme", `{"t":1}`, []string{"Authorization", "Bearer nope"}, http.StatusUnauthorized},
+		{"unmappable body", "acme", `{"t":"x"}`, []string{"X-Webhook-Token", testToken}, http.StatusBadRequest},
+		{"relative snapshot url", "cam", `{"url":"/a.jpg"}`, []string{"X-Webhook-Token", testToken}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := post(tt.hook, tt.body, tt.header...); rec.Code != tt.want {
+				t.Errorf("status = %d; want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	t.Run("rejected reading", func(t *testing.T) {
+		ingestErr = errors.New("validate: humidity_pct out of range")
+		defer func() { ingestErr = nil }()
+		if rec := post("acme", `{"t":1}`, "X-Webhook-Token", testToken); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d; want 422", rec.Code)
+		}
+	})
+	t.Run("disk space low", func(t *testing.T) {
+		ingestErr = fmt.Errorf("disk: %w: 12 MiB free", db.ErrDiskSpaceLow)
+		defer func() { ingestErr = nil }()
+		if rec := post("acme", `{"t":1}`, "X-Webhook-Token", testToken); rec.Code != http.StatusInsufficientStorage {
+			t.Errorf("status = %d; want 507", rec.Code)
+		}
+	})
+	t.Run("snapshot only", func(t *testing.T) {
+		got, snapshots = nil, nil
+		rec := post("cam", `{"url":"https://cam.example/a.jpg"}`, "X-Webhook-Token", testToken)
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"snapshots":1`) {
+			t.Errorf("POST = %d %s; want 200 snapshots 1", rec.Code, rec.Body.String())
+		}
+		if len(got) != 0 || len(snapshots) != 1 || snapshots[0].StationID != "5" || !snapshots[0].Start.Equal(snapshots[0].End) {
+			t.Errorf("ingested %+v, attached %+v; want one point-in-time snapshot for station 5", got, snapshots)
+		}
+	})
+
+	t.Run("snapshot for unknown station", func(t *testing.T) {
+		attachErr = repository.ErrStationNotFound
+		defer func() { attachErr = nil }()
+		if rec := post("cam", `{"url":"https://cam.example/a.jpg"}`, "X-Webhook-Token", testToken); rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d; want 422", rec.Code)
+		}
+	})
+}
+
+func TestHandler_replayWindow(t *testing.T) {
+	def := Definition{Name: "device", Token: testToken, Secret: testSecret, StationID: "5", Fields: map[string]string{FieldTemperature: "t"}, TimestampUnit: "s", ReplayWindow: 5 * time.Minute}
+	ingested := 0
+	h := NewHandler([]Definition{def}, func(context.Context, string, cloudpico_shared.Telemetry) error {
+		ingested++
+		return nil
+	}, nil)
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/webhooks/{name}", h)
+	const body = `{"t":20}`
+	sign := func(ts, nonce string) string { return hex.EncodeToString(Sign(testSecret, ts, nonce, []byte(body))) }
+	postSigned := func(ts, nonce, sig string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/device", strings.NewReader(body))
+		req.Header.Set("X-Webhook-Token", testToken)
+		if ts != "" {
+			req.Header.Set(HeaderTimestamp, ts)
+		}
+		if nonce != "" {
+			req.Header.Set(HeaderNonce, nonce)
+		}
+		req.Header.Set(HeaderSignature, sig)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	post := func(ts, nonce string) int { return postSigned(ts, nonce, sign(ts, nonce)) }
+	now := time.Now()
+	unix := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	replayed := replayRejections.Value("replayed")
+
+	if code := post(unix(now), "nonce-0000000001"); code != http.StatusOK {
+		t.Fatalf("first request = %d; want 200", code)
+	}
+	if code := post(unix(now), "nonce-0000000001"); code != http.StatusConflict {
+		t.Errorf("replayed request = %d; want 409", code)
+	}
+	if got := replayRejections.Value("replayed") - replayed; got != 1 {
+		t.Errorf("replayed rejections = %d; want 1", got)
+	}
+	if code := postSigned(unix(now), "nonce-0000000002", "sha256="+sign(unix(now), "nonce-0000000002")); code != http.StatusOK {
+		t.Errorf("prefixed signature = %d; want 200", code)
+	}
+	if code := post(now.Add(time.Minute).Format(time.RFC3339), "nonce-0000000003"); code != http.StatusOK {
+		t.Errorf("RFC 3339 timestamp with a new nonce = %d; want 200", code)
+	}
+
+	// A captured request restamped with a fresh timestamp and nonce keeps the
+	// signature of the old ones.
+	captured := sign(unix(now), "nonce-0000000001")
+	for _, tt := range []struct {
+		name, ts, nonce, sig string
+	}{
+		{"restamped capture", unix(now.Add(time.Second)), "nonce-0000000004", captured},
+		{"signed with the token", unix(now), "nonce-0000000005", hex.EncodeToString(Sign(testToken, unix(now), "nonce-0000000005", []byte(body)))},
+		{"not hex", unix(now), "nonce-0000000006", "signature"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := postSigned(tt.ts, tt.nonce, tt.sig); code != http.StatusUnauthorized {
+				t.Errorf("status = %d; want 401", code)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name, ts, nonce string
+	}{
+		{"missing nonce", unix(now), ""},
+		{"missing timestamp", "", "nonce-0000000007"},
+		{"short nonce", unix(now), "n3"},
+		{"malformed timestamp", "yesterday", "nonce-0000000008"},
+		{"stale timestamp", unix(now.Add(-10 * time.Minute)), "nonce-0000000009"},
+		{"future timestamp", unix(now.Add(10 * time.Minute)), "nonce-0000000010"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := post(tt.ts, tt.nonce); code != http.StatusUnprocessableEntity {
+				t.Errorf("status = %d; want 422", code)
+			}
+		})
+	}
+	if code := postSigned(unix(now), "nonce-0000000011", ""); code != http.StatusUnprocessableEntity {
+		t.Errorf("missing signature = %d; want 422", code)
+	}
+	if ingested != 3 {
+		t.Errorf("ingested %d readings; want 3", ingested)
+	}
+}
+
+func TestReplayGuard_prune(t *testing.T) {
+	def := Definition{Name: "device", Secret: testSecret, ReplayWindow: time.Minute}
+	g := newReplayGuard()
+	now := time.Now()
+	for i := range 1024 {
+		ts, nonce := strconv.FormatInt(now.Add(-50*time.Second).Unix(), 10), fmt.Sprintf("nonce-%010d", i)
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderNonce, nonce)
+		req.Header.Set(HeaderSignature, hex.EncodeToString(Sign(testSecret, ts, nonce, nil)))
+		if err := g.check(def, req, nil, now); err != nil {
+			t.Fatalf("check(%d) = %v", i, err)
+		}
+	}
+	// Those nonces expire 10s from now; the next prune forgets them.
+	g.prune(now.Add(15 * time.Second))
+	if len(g.seen) != 0 || g.nextPrune != 1024 {
+		t.Errorf("after prune: %d nonces, next prune at %d; want 0 and 1024", len(g.seen), g.nextPrune)
+	}
+}
@@ -8,10 +8,55 @@ import (
 	"time"
 
 	"cloudpico-server/internal/config"
+	"cloudpico-server/internal/metrics"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
+// A broker disconnects the older of two clients sharing a client ID; with
+// auto-reconnect on both sides they keep taking the session from each other.
+// stormDrops lost connections within stormWindow are reported as such a
+// takeover storm.
+const (
+	stormDrops  = 5
+	stormWindow = 2 * time.Minute
+)
+
+var (
+	connectionsLost = metrics.Default.NewCounter("cloudpico_mqtt_connections_lost_total",
+		"MQTT connections to the broker lost.")
+	takeoverStorms = metrics.Default.NewCounter("cloudpico_mqtt_takeover_storms_total",
+		"Bursts of lost MQTT connections typical of a duplicate client ID.")
+	messagesReceived = metrics.Default.NewCounter("cloudpico_mqtt_messages_received_total",
+		"MQTT messages received on the subscribed topic.")
+)
+
+// stormDetector tracks recent lost connections to recognize takeover storms.
+type stormDetector struct {
+	drops    []time.Time
+	storming bool
+}
+
+// lost records a lost connection and reports whether it starts a storm. A
+// storm is reported once and ends when the drops in the window fall below
+// stormDrops again.
+func (d *stormDetector) lost(now time.Time) (started bool, recent int) {
+	kept := d.drops[:0]
+	for _, t := range d.drops {
+		if now.Sub(t) < stormWindow {
+			kept = append(kept, t)
+		}
+	}
+	d.drops = append(kept, now)
+	if len(d.drops) < stormDrops {
+		d.storming = false
+		return false, len(d.drops)
+	}
+	started = !d.storming
+	d.storming = true
+	return started, len(d.drops)
+}
+
 type MessageHandler func(mqtt.Message) error
 type Subscriber struct {
 	client    mqtt.Client
@@ -22,6 +67,8 @@ type Subscriber struct {
 	stopCh chan struct{}
 
 	messageHandler func(mqtt.Message) error
+
+	storms stormDetector // guarded by mu
 }
 
 func NewSubscriber(cfg config.Config) *Subscriber {
@@ -75,6 +122,7 @@ func (s *Subscriber) messageCallback(_ mqtt.Client, msg mqtt.Message) {
 	if s == nil || msg == nil || s.messageHandler == nil {
 		return
 	}
+	messagesReceived.Inc()
 	defer func() {
 		if err := recover(); err != nil {
 			slog.Error("mqtt message handler panic", "error", err, "topic", msg.Topic())
@@ -135,8 +183,21 @@ func getOptions(s *Subscriber) *mqtt.ClientOptions {
 		}
 	})
 	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
-		s.setConnected(false)
-		slog.Warn("mqtt connection lost", "broker", cfg.MQTTBroker, "port", cfg.MQTTPort)
+		s.mu.Lock()
+		s.connected = false
+		started, recent := s.storms.lost(time.Now())
+		s.mu.Unlock()
+		connectionsLost.Inc()
+		slog.Warn("mqtt connection lost", "broker", cfg.MQTTBroker, "port", cfg.MQTTPort, "error", err)
+		if started {
+			takeoverStorms.Inc()
+			slog.Error("mqtt connection lost repeatedly; another client is probably using the same client ID",
+				"client_id", cfg.MQTTClientID,
+				"drops", recent,
+				"window", stormWindow.String(),
+				"hint", "give every server instance its own MQTT_CLIENT_ID and keep it stable across restarts (the session is persistent); check the broker log for clients connecting with this ID",
+			)
+		}
 	})
 	return opts
 }
@@ -156,6 +217,21 @@ func (s *Subscriber) SetMessageHandler(handler MessageHandler) {
 	s.messageHandler = handler
 }
 
+// Publish sends payload on topic with QoS 1. It fails if the subscriber is not connected.
+func (s *Subscriber) Publish(topic string, payload []byte) error {
+	if s.client == nil || !s.Connected() {
+		return fmt.Errorf("mqtt not connected")
+	}
+	token := s.client.Publish(topic, 1, false, payload)
+	if !token.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("publish timeout for topic %s", topic)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	return nil
+}
+
 func (s *Subscriber) Disconnect() {
 	s.client.Disconnect(0)
 }
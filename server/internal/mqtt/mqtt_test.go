@@ -0,0 +1,39 @@
+package mqtt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStormDetector(t *testing.T) {
+	var d stormDetector
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	step := stormWindow / (2 * stormDrops)
+
+	for i := 1; i < stormDrops; i++ {
+		if started, recent := d.lost(now); started || recent != i {
+			t.Fatalf("drop %d: lost() = %v, %d; want false, %d", i, started, recent, i)
+		}
+		now = now.Add(step)
+	}
+	if started, recent := d.lost(now); !started || recent != stormDrops {
+		t.Fatalf("drop %d: lost() = %v, %d; want a storm start", stormDrops, started, recent)
+	}
+	now = now.Add(step)
+	if started, _ := d.lost(now); started {
+		t.Error("further drop reported the same storm again")
+	}
+
+	// After a quiet window the old drops expire and the storm ends.
+	now = now.Add(stormWindow)
+	if started, recent := d.lost(now); started || recent != 1 {
+		t.Errorf("drop after quiet window: lost() = %v, %d; want false, 1", started, recent)
+	}
+	for range stormDrops - 2 {
+		now = now.Add(step)
+		d.lost(now)
+	}
+	if started, _ := d.lost(now.Add(step)); !started {
+		t.Error("new burst after the storm ended was not reported")
+	}
+}
@@ -0,0 +1,377 @@
+// Package openapi renders an OpenAPI 3.1 description of HTTP operations. The
+// JSON schemas are derived from Go types following encoding/json's rules for
+// field names, omitempty and embedded structs, as package tsgen does for
+// TypeScript.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// File stands for an uploaded file in a multipart request body.
+type File []byte
+
+var (
+	fileType       = reflect.TypeFor[File]()
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	marshalerType  = reflect.TypeFor[json.Marshaler]()
+)
+
+// Spec describes an API.
+type Spec struct {
+	Title       string
+	Version     string
+	Description string
+	// Error is a value of the JSON body of error responses.
+	Error      any
+	Operations []Operation
+}
+
+// Operation describes one route.
+type Operation struct {
+	// Pattern is the ServeMux pattern, e.g. "GET /api/v1/stations/{id}". Its
+	// wildcards become required path parameters.
+	Pattern string
+	ID      string
+	Summary string
+	Tag     string
+	Query   []Param
+	// Request maps media types to a value of the request body's type.
+	Request map[string]any
+	// Status is the status of a successful response; 0 means 200.
+	Status int
+	// Response maps media types to a value of the response body's type, e.g.
+	// {"text/csv": ""}. Empty means the response has no body.
+	Response map[string]any
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name        string
+	Description string
+	// Type is the JSON schema type; empty means "string".
+	Type     string
+	Format   string
+	Enum     []string
+	Required bool
+}
+
+// wildcardRe matches the wildcards of a ServeMux pattern: {id}, {path...}
+// and {$}.
+var wildcardRe = regexp.MustCompile(`\{([^}]*)\}`)
+
+// JSON renders the spec as indented JSON. Operations must have distinct
+// patterns and IDs, and types rendering under the same schema name must be
+// the same type.
+func (s Spec) JSON() ([]byte, error) {
+	g := &generator{schemas: map[string]any{}, types: map[string]reflect.Type{}}
+	errSchema, err := g.schema(reflect.TypeOf(s.Error))
+	if err != nil {
+		return nil, err
+	}
+	paths := map[string]map[string]any{}
+	ids := map[string]bool{}
+	for _, op := range s.Operations {
+		method, path, ok := strings.Cut(op.Pattern, " ")
+		if !ok {
+			return nil, fmt.Errorf("openapi: pattern %q has no method", op.Pattern)
+		}
+		if ids[op.ID] {
+			return nil, fmt.Errorf("openapi: duplicate operation ID %q", op.ID)
+		}
+		ids[op.ID] = true
+		var params []any
+		path = wildcardRe.ReplaceAllStringFunc(path, func(w string) string {
+			name := strings.TrimSuffix(w[1:len(w)-1], "...")
+			if name == "$" {
+				return ""
+			}
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+			})
+			return "{" + name + "}"
+		})
+		for _, p := range op.Query {
+			params = append(params, p.object())
+		}
+		o, err := g.operation(op, params, errSchema)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %s: %w", op.Pattern, err)
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		key := strings.ToLower(method)
+		if _, dup := paths[path][key]; dup {
+			return nil, fmt.Errorf("openapi: duplicate pattern %q", op.Pattern)
+		}
+		paths[path][key] = o
+	}
+	info := map[string]any{"title": s.Title, "version": s.Version}
+	if s.Description != "" {
+		info["description"] = s.Description
+	}
+	out, err := json.MarshalIndent(map[string]any{
+		"openapi":    "3.1.0",
+		"info":       info,
+		"paths":      paths,
+		"components": map[string]any{"schemas": g.schemas},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+func (p Param) object() map[string]any {
+	schema := map[string]any{"type": "string"}
+	if p.Type != "" {
+		schema["type"] = p.Type
+	}
+	if p.Format != "" {
+		schema["format"] = p.Format
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	o := map[string]any{"name": p.Name, "in": "query", "schema": schema}
+	if p.Description != "" {
+		o["description"] = p.Description
+	}
+	if p.Required {
+		o["required"] = true
+	}
+	return o
+}
+
+type generator struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+}
+
+func (g *generator) operation(op Operation, params []any, errSchema map[string]any) (map[string]any, error) {
+	o := map[string]any{"operationId": op.ID, "summary": op.Summary}
+	if op.Tag != "" {
+		o["tags"] = []string{op.Tag}
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+	if len(op.Request) > 0 {
+		content, err := g.content(op.Request)
+		if err != nil {
+			return nil, err
+		}
+		o["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	ok := map[string]any{"description": http.StatusText(status)}
+	if len(op.Response) > 0 {
+		content, err := g.content(op.Response)
+		if err != nil {
+			return nil, err
+		}
+		ok["content"] = content
+	}
+	o["responses"] = map[string]any{
+		strconv.Itoa(status): ok,
+		"default": map[string]any{
+			"description": "Error",
+			"content":     map[string]any{"application/json": map[string]any{"schema": errSchema}},
+		},
+	}
+	return o, nil
+}
+
+func (g *generator) content(media map[string]any) (map[string]any, error) {
+	content := map[string]any{}
+	for mediaType, v := range media {
+		schema, err := g.schema(reflect.TypeOf(v))
+		if err != nil {
+			return nil, err
+		}
+		content[mediaType] = map[string]any{"schema": schema}
+	}
+	return content, nil
+}
+
+func (g *generator) schema(t reflect.Type) (map[string]any, error) {
+	switch {
+	case t == nil:
+		return nil, fmt.Errorf("nil type")
+	case t == fileType:
+		return map[string]any{"type": "string", "format": "binary"}, nil
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case t == rawMessageType:
+		return map[string]any{}, nil
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		return map[string]any{}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Pointer:
+		s, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullable(s), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key %s is not a string", t.Key())
+		}
+		elem, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": elem}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := schemaName(t)
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if prev, ok := g.types[name]; ok {
+			if prev != t {
+				return nil, fmt.Errorf("%s and %s both render as %s", prev, t, name)
+			}
+			return ref, nil
+		}
+		g.types[name] = t
+		s, err := g.object(t)
+		if err != nil {
+			return nil, err
+		}
+		g.schemas[name] = s
+		return ref, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// object renders the JSON object of struct t.
+func (g *generator) object(t reflect.Type) (map[string]any, error) {
+	props := map[string]any{}
+	var required []string
+	if err := g.fields(t, props, &required); err != nil {
+		return nil, err
+	}
+	o := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		o["required"] = required
+	}
+	return o, nil
+}
+
+func (g *generator) fields(t reflect.Type, props map[string]any, required *[]string) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		// Untagged embedded structs contribute their fields.
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.fields(ft, props, required); err != nil {
+					return err
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		optional := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		// Omitted nil pointers are never null.
+		if optional && ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		s, err := g.schema(ft)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		props[name] = s
+		if !optional {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// nullable allows null in addition to the values of s.
+func nullable(s map[string]any) map[string]any {
+	if typ, ok := s["type"].(string); ok {
+		out := make(map[string]any, len(s))
+		for k, v := range s {
+			out[k] = v
+		}
+		out["type"] = []string{typ, "null"}
+		return out
+	}
+	if len(s) == 0 {
+		return s // any value, null included
+	}
+	return map[string]any{"oneOf": []any{s, map[string]any{"type": "null"}}}
+}
+
+// schemaName is the exported name of t. Generic types are named after their
+// type arguments: Page[types.Station] is "StationPage".
+func schemaName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if generic {
+		var prefix strings.Builder
+		for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+			prefix.WriteString(exported(arg[strings.LastIndex(arg, ".")+1:]))
+		}
+		name = prefix.String() + exported(name)
+	}
+	return exported(name)
+}
+
+func exported(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type page[T any] struct {
+	Items []T `json:"items"`
+}
+
+type item struct {
+	ID      string            `json:"id"`
+	Note    *string           `json:"note"`
+	Parent  *item             `json:"parent"`
+	Child   *item             `json:"child,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	At      time.Time         `json:"at"`
+	Data    []byte            `json:"data,omitzero"`
+	Ignored string            `json:"-"`
+	hidden  string
+}
+
+type withEmbedded struct {
+	item
+	Extra int `json:"extra"`
+}
+
+type upload struct {
+	File File `json:"file"`
+}
+
+func generate(t *testing.T, ops ...Operation) map[string]any {
+	t.Helper()
+	out, err := Spec{Title: "Test", Version: "1", Error: struct {
+		Error string `json:"error"`
+	}{}, Operations: ops}.JSON()
+	if err != nil {
+		t.Fatalf("JSON() = %v; want nil", err)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(out, &spec); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+	return spec
+}
+
+// at returns the value at the path of object keys in v.
+func at(t *testing.T, v any, path ...string) any {
+	t.Helper()
+	for i, key := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("%s: %v is not an object", strings.Join(path[:i], "."), v)
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func TestSpec_Schemas(t *testing.T) {
+	spec := generate(t, Operation{
+		Pattern: "GET /items", ID: "listItems",
+		Response: map[string]any{"application/json": page[item]{}},
+	}, Operation{
+		Pattern: "GET /embedded", ID: "getEmbedded",
+		Response: map[string]any{"application/json": withEmbedded{}},
+	})
+
+	ref := at(t, spec, "paths", "/items", "get", "responses", "200", "content", "application/json", "schema", "$ref")
+	if ref != "#/components/schemas/ItemPage" {
+		t.Errorf("generic response ref = %v; want ItemPage", ref)
+	}
+	schema := at(t, spec, "components", "schemas", "Item")
+	for _, tc := range []struct {
+		path []string
+		want any
+	}{
+		{[]string{"properties", "id", "type"}, "string"},
+		{[]string{"properties", "note", "type"}, []any{"string", "null"}},
+		{[]string{"properties", "parent", "oneOf"}, []any{map[string]any{"$ref": "#/components/schemas/Item"}, map[string]any{"type": "null"}}},
+		{[]string{"properties", "child", "$ref"}, "#/components/schemas/Item"},
+		{[]string{"properties", "tags", "type"}, "object"},
+		{[]string{"properties", "at", "format"}, "date-time"},
+		{[]string{"properties", "data", "contentEncoding"}, "base64"},
+		{[]string{"required"}, []any{"id", "note", "parent", "at"}},
+	} {
+		if got := at(t, schema, tc.path...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Item %v = %v; want %v", tc.path, got, tc.want)
+		}
+	}
+	if props := at(t, schema, "properties").(map[string]any); len(props) != 7 {
+		t.Errorf("Item properties = %v; want 7 without ignored and unexported fields", props)
+	}
+	if props := at(t, spec, "components", "schemas", "WithEmbedded", "properties").(map[string]any); props["id"] == nil || props["extra"] == nil {
+		t.Errorf("WithEmbedded properties = %v; want the embedded fields and extra", props)
+	}
+	if got := at(t, spec, "paths", "/items", "get", "responses", "default", "content", "application/json", "schema", "required"); !reflect.DeepEqual(got, []any{"error"}) {
+		t.Errorf("error schema required = %v; want [error]", got)
+	}
+}
+
+func TestSpec_Operations(t *testing.T) {
+	spec := generate(t, Operation{
+		Pattern: "POST /items/{id}/files/{path...}", ID: "upload", Tag: "items",
+		Query:   []Param{{Name: "limit", Type: "integer", Required: true}},
+		Request: map[string]any{"multipart/form-data": upload{}},
+		Status:  http.StatusNoContent,
+	})
+	op := at(t, spec, "paths", "/items/{id}/files/{path}", "post")
+	params := at(t, op, "parameters").([]any)
+	if len(params) != 3 || at(t, params[1], "name") != "path" || at(t, params[1], "in") != "path" ||
+		at(t, params[2], "in") != "query" || at(t, params[2], "schema", "type") != "integer" {
+		t.Errorf("parameters = %v; want id and path in the path, limit in the query", params)
+	}
+	if got := at(t, spec, "components", "schemas", "Upload", "properties", "file", "format"); got != "binary" {
+		t.Errorf("file format = %v; want binary", got)
+	}
+	if got := at(t, op, "responses", "204"); !reflect.DeepEqual(got, map[string]any{"description": "No Content"}) {
+		t.Errorf("204 response = %v; want no content", got)
+	}
+}
+
+func TestSpec_Errors(t *testing.T) {
+	type other struct{}
+	for name, ops := range map[string][]Operation{
+		"duplicate pattern": {{Pattern: "GET /a", ID: "a"}, {Pattern: "GET /a", ID: "b"}},
+		"duplicate ID":      {{Pattern: "GET /a", ID: "a"}, {Pattern: "GET /b", ID: "a"}},
+		"no method":         {{Pattern: "/a", ID: "a"}},
+		"name clash": {
+			{Pattern: "GET /a", ID: "a", Response: map[string]any{"application/json": item{}}},
+			{Pattern: "GET /b", ID: "b", Response: map[string]any{"application/json": struct{ X Item }{}}},
+		},
+		"unsupported type": {{Pattern: "GET /a", ID: "a", Response: map[string]any{"application/json": make(chan int)}}},
+	} {
+		if _, err := (Spec{Error: other{}, Operations: ops}).JSON(); err == nil {
+			t.Errorf("%s: JSON() = nil error; want error", name)
+		}
+	}
+}
+
+// Item clashes with item, which also renders as "Item".
+type Item struct{}
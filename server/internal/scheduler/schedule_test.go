@@ -0,0 +1,49 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpec(t *testing.T) {
+	base := time.Date(2025, 2, 3, 10, 17, 30, 0, time.UTC) // Monday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"15m", base.Add(15 * time.Minute)},
+		{"@every 1h", base.Add(time.Hour)},
+		{"@hourly", time.Date(2025, 2, 3, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, 2, 9, 0, 0, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, 2, 3, 10, 20, 0, 0, time.UTC)},
+		{"0 6 * * 1", time.Date(2025, 2, 10, 6, 0, 0, 0, time.UTC)},
+		{"30 9-17 * * 1-5", time.Date(2025, 2, 3, 10, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 2, 9, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2025, 2, 15, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match.
+		{"0 0 28 * 2", time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSpec(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSpec(%q) = %v", tt.spec, err)
+			}
+			if got := s.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpec_invalid(t *testing.T) {
+	for _, spec := range []string{"", "0s", "-1m", "@every x", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := ParseSpec(spec); err == nil {
+				t.Errorf("ParseSpec(%q) = nil error; want error", spec)
+			}
+		})
+	}
+}
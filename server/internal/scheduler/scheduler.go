@@ -0,0 +1,247 @@
+// Package scheduler runs background jobs on cron or interval schedules for the
+// lifetime of the server.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned by Trigger and Job for an unknown job name.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned by Trigger when the job is already running.
+	ErrJobRunning = errors.New("job is already running")
+	// ErrNotStarted is returned by Trigger before Start has been called.
+	ErrNotStarted = errors.New("scheduler not started")
+)
+
+// Trigger values recorded in Status.LastTrigger.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Job is a named unit of background work.
+type Job struct {
+	Name string
+	// Spec is a schedule accepted by ParseSpec, e.g. "@every 1h" or "0 6 * * 1".
+	Spec string
+	// RunOnStart runs the job once immediately instead of waiting for the first activation.
+	RunOnStart bool
+	Run        func(ctx context.Context) error
+}
+
+// Status is a snapshot of a job's schedule and last run.
+type Status struct {
+	Name         string        `json:"name"`
+	Spec         string        `json:"spec"`
+	Running      bool          `json:"running"`
+	Runs         int           `json:"runs"`
+	Skipped      int           `json:"skipped"` // activations skipped because the previous run was still going
+	LastStart    *time.Time    `json:"lastStart,omitempty"`
+	LastTrigger  string        `json:"lastTrigger,omitempty"` // TriggerSchedule or TriggerManual
+	LastDuration time.Duration `json:"lastDurationNs,omitempty"`
+	LastError    string        `json:"lastError,omitempty"`
+	NextRun      *time.Time    `json:"nextRun,omitempty"`
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Scheduler runs registered jobs until its context is canceled.
+type Scheduler struct {
+	mu   sync.Mutex
+	jobs map[string]*entry
+	ctx  context.Context // set by Start; manual runs share it
+	wg   sync.WaitGroup
+	now  func() time.Time
+}
+
+func New() *Scheduler {
+	return &Scheduler{jobs: make(map[string]*entry), now: time.Now}
+}
+
+// Register adds a job. It fails on an invalid spec or a duplicate name.
+// Jobs registered after Start are not run.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return fmt.Errorf("job must have a name and a run func")
+	}
+	schedule, err := ParseSpec(job.Spec)
+	if err != nil {
+		return fmt.Errorf("job %q: %w", job.Name, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("job %q already registered", job.Name)
+	}
+	s.jobs[job.Name] = &entry{job: job, schedule: schedule, status: Status{Name: job.Name, Spec: job.Spec}}
+	return nil
+}
+
+// Start launches one goroutine per job. They stop when ctx is canceled; use Wait to block until they exit.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go func(e *entry) {
+			defer s.wg.Done()
+			s.loop(ctx, e)
+		}(e)
+	}
+}
+
+// Wait blocks until all job goroutines, including in-flight runs, have exited.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Jobs returns the status of every registered job, sorted by name.
+func (s *Scheduler) Jobs() []Status {
+	s.mu.Lock()
+	out := make([]Status, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		e.mu.Lock()
+		out = append(out, e.status)
+		e.mu.Unlock()
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Job returns the status of the named job.
+func (s *Scheduler) Job(name string) (Status, error) {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	s.mu.Unlock()
+	if !ok {
+		return Status{}, ErrJobNotFound
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status, nil
+}
+
+// Trigger starts an immediate run of the named job in the background and
+// returns its status; poll Job for the outcome. The regular schedule is not
+// affected. It fails with ErrJobRunning rather than queueing behind a run in progress.
+func (s *Scheduler) Trigger(name string) (Status, error) {
+	s.mu.Lock()
+	e, ok := s.jobs[name]
+	ctx := s.ctx
+	s.mu.Unlock()
+	if !ok {
+		return Status{}, ErrJobNotFound
+	}
+	if ctx == nil {
+		return Status{}, ErrNotStarted
+	}
+	if err := ctx.Err(); err != nil {
+		return Status{}, err
+	}
+	if !s.start(ctx, e, TriggerManual) {
+		return Status{}, ErrJobRunning
+	}
+	slog.Info("scheduler: job triggered manually", "job", name)
+	return s.Job(name)
+}
+
+func (s *Scheduler) loop(ctx context.Context, e *entry) {
+	slog.Info("scheduler: job registered", "job", e.job.Name, "spec", e.job.Spec)
+	if e.job.RunOnStart {
+		s.fire(ctx, e)
+	}
+	for {
+		next := e.schedule.Next(s.now())
+		if next.IsZero() {
+			slog.Warn("scheduler: job has no future activations", "job", e.job.Name)
+			return
+		}
+		e.mu.Lock()
+		e.status.NextRun = &next
+		e.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.fire(ctx, e)
+		}
+	}
+}
+
+// fire starts a scheduled run unless the previous run is still going.
+func (s *Scheduler) fire(ctx context.Context, e *entry) {
+	if !s.start(ctx, e, TriggerSchedule) {
+		slog.Warn("scheduler: previous run still in progress; skipping", "job", e.job.Name)
+	}
+}
+
+// start launches a run in the background. It returns false, counting a skipped
+// activation for scheduled runs, if the job is already running.
+func (s *Scheduler) start(ctx context.Context, e *entry, trigger string) bool {
+	e.mu.Lock()
+	if e.status.Running {
+		if trigger == TriggerSchedule {
+			e.status.Skipped++
+		}
+		e.mu.Unlock()
+		return false
+	}
+	start := s.now()
+	e.status.Running = true
+	e.status.LastStart = &start
+	e.status.LastTrigger = trigger
+	e.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := runJob(ctx, e.job)
+		duration := s.now().Sub(start)
+
+		e.mu.Lock()
+		e.status.Running = false
+		e.status.Runs++
+		e.status.LastDuration = duration
+		e.status.LastError = ""
+		if err != nil {
+			e.status.LastError = err.Error()
+		}
+		e.mu.Unlock()
+
+		if err != nil {
+			slog.Error("scheduler: job failed", "job", e.job.Name, "trigger", trigger, "error", err, "duration_ms", duration.Milliseconds())
+			return
+		}
+		slog.Info("scheduler: job finished", "job", e.job.Name, "trigger", trigger, "duration_ms", duration.Milliseconds())
+	}()
+	return true
+}
+
+// runJob calls job.Run, converting a panic into an error.
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
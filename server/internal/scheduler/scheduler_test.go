@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Register(t *testing.T) {
+	s := New()
+	run := func(context.Context) error { return nil }
+	if err := s.Register(Job{Name: "a", Spec: "@every 1m", Run: run}); err != nil {
+		t.Fatalf("Register() = %v; want nil", err)
+	}
+	tests := []struct {
+		name string
+		job  Job
+	}{
+		{"duplicate name", Job{Name: "a", Spec: "1m", Run: run}},
+		{"invalid spec", Job{Name: "b", Spec: "not a spec", Run: run}},
+		{"missing run", Job{Name: "c", Spec: "1m"}},
+		{"missing name", Job{Spec: "1m", Run: run}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Register(tt.job); err == nil {
+				t.Error("Register() = nil; want error")
+			}
+		})
+	}
+}
+
+func TestScheduler_runsJobs(t *testing.T) {
+	s := New()
+	var ticks, onStart atomic.Int32
+	mustRegister(t, s, Job{Name: "tick", Spec: "5ms", Run: func(context.Context) error {
+		ticks.Add(1)
+		return errors.New("failures do not stop the job")
+	}})
+	mustRegister(t, s, Job{Name: "start", Spec: "@every 1h", RunOnStart: true, Run: func(context.Context) error {
+		onStart.Add(1)
+		return nil
+	}})
+	mustRegister(t, s, Job{Name: "panics", Spec: "5ms", Run: func(context.Context) error {
+		panic("boom")
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(40 * time.Millisecond)
+	cancel()
+	s.Wait()
+
+	if ticks.Load() < 2 {
+		t.Errorf("tick job ran %d times; want >= 2", ticks.Load())
+	}
+	if onStart.Load() != 1 {
+		t.Errorf("RunOnStart job ran %d times; want 1", onStart.Load())
+	}
+
+	statuses := s.Jobs()
+	if len(statuses) != 3 || statuses[0].Name != "panics" || statuses[2].Name != "tick" {
+		t.Fatalf("Jobs() = %+v; want 3 jobs sorted by name", statuses)
+	}
+	if statuses[0].LastError != "panic: boom" {
+		t.Errorf("panics LastError = %q; want panic: boom", statuses[0].LastError)
+	}
+	if statuses[1].Runs != 1 || statuses[1].LastError != "" || statuses[1].NextRun == nil {
+		t.Errorf("start status = %+v; want one successful run and a next run", statuses[1])
+	}
+}
+
+func TestScheduler_preventsOverlap(t *testing.T) {
+	s := New()
+	var running, maxRunning atomic.Int32
+	mustRegister(t, s, Job{Name: "slow", Spec: "2ms", Run: func(ctx context.Context) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		if n > maxRunning.Load() {
+			maxRunning.Store(n)
+		}
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	s.Wait()
+
+	if maxRunning.Load() != 1 {
+		t.Errorf("max concurrent runs = %d; want 1", maxRunning.Load())
+	}
+	if st := s.Jobs()[0]; st.Skipped == 0 {
+		t.Errorf("Skipped = 0; want skipped activations while running")
+	}
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	var runs atomic.Int32
+	mustRegister(t, s, Job{Name: "rollup", Spec: "@every 1h", Run: func(ctx context.Context) error {
+		runs.Add(1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return errors.New("partial rollup")
+	}})
+
+	if _, err := s.Trigger("rollup"); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("Trigger before Start err = %v; want ErrNotStarted", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	if _, err := s.Trigger("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Trigger(missing) err = %v; want ErrJobNotFound", err)
+	}
+	st, err := s.Trigger("rollup")
+	if err != nil {
+		t.Fatalf("Trigger() = %v", err)
+	}
+	if !st.Running || st.LastTrigger != TriggerManual {
+		t.Errorf("status = %+v; want running manual run", st)
+	}
+	if _, err := s.Trigger("rollup"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("second Trigger err = %v; want ErrJobRunning", err)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		st, err = s.Job("rollup")
+		if err != nil {
+			t.Fatalf("Job() = %v", err)
+		}
+		if !st.Running || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if st.Running || st.Runs != 1 || st.LastError != "partial rollup" || st.Skipped != 0 {
+		t.Errorf("status = %+v; want one finished run with its error and no skips", st)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("runs = %d; want 1", runs.Load())
+	}
+	if _, err := s.Job("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Job(missing) err = %v; want ErrJobNotFound", err)
+	}
+}
+
+func mustRegister(t *testing.T, s *Scheduler, job Job) {
+	t.Helper()
+	if err := s.Register(job); err != nil {
+		t.Fatalf("Register(%s) = %v", job.Name, err)
+	}
+}
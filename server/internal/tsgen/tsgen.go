@@ -0,0 +1,219 @@
+// Package tsgen renders Go API types as TypeScript interfaces, following
+// encoding/json's rules for field names, omitempty and embedded structs.
+package tsgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// T stands in for the type parameter of a generic type: Add(Page[T]{})
+// renders "interface Page<T>".
+type T struct{}
+
+var (
+	placeholderType = reflect.TypeFor[T]()
+	timeType        = reflect.TypeFor[time.Time]()
+	rawMessageType  = reflect.TypeFor[json.RawMessage]()
+	marshalerType   = reflect.TypeFor[json.Marshaler]()
+)
+
+// Generator collects struct types and the named structs they reference.
+type Generator struct {
+	types []reflect.Type
+	names map[string]reflect.Type
+}
+
+func New() *Generator {
+	return &Generator{names: make(map[string]reflect.Type)}
+}
+
+// Add registers the struct type of each value, e.g. Add(types.Station{}).
+func (g *Generator) Add(values ...any) error {
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		if t == nil || t.Kind() != reflect.Struct || t.Name() == "" {
+			return fmt.Errorf("tsgen: %v is not a named struct", t)
+		}
+		if err := g.add(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Generator) add(t reflect.Type) error {
+	name := typeName(t)
+	if prev, ok := g.names[name]; ok {
+		if prev != t {
+			return fmt.Errorf("tsgen: %s and %s both render as %s", prev, t, name)
+		}
+		return nil
+	}
+	g.names[name] = t
+	g.types = append(g.types, t)
+	return nil
+}
+
+// Write renders every registered type, then the named structs they reference,
+// in the order they were found.
+func (g *Generator) Write(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("// Code generated by tsgen. DO NOT EDIT.\n")
+	for i := 0; i < len(g.types); i++ {
+		t := g.types[i]
+		b.WriteString("\nexport interface " + typeName(t))
+		if isGeneric(t) {
+			b.WriteString("<T>")
+		}
+		b.WriteString(" {\n")
+		if err := g.writeFields(&b, t, "  "); err != nil {
+			return err
+		}
+		b.WriteString("}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (g *Generator) writeFields(b *strings.Builder, t reflect.Type, indent string) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		// Untagged embedded structs contribute their fields.
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.writeFields(b, ft, indent); err != nil {
+					return err
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		optional := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		nullable := false
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+			nullable = !optional
+		}
+		ts, err := g.tsType(ft, indent)
+		if err != nil {
+			return fmt.Errorf("tsgen: %s.%s: %w", t, f.Name, err)
+		}
+		if nullable {
+			ts += " | null"
+		}
+		b.WriteString(indent + quoteName(name))
+		if optional {
+			b.WriteString("?")
+		}
+		b.WriteString(": " + ts + ";\n")
+	}
+	return nil
+}
+
+func (g *Generator) tsType(t reflect.Type, indent string) (string, error) {
+	switch {
+	case t == placeholderType:
+		return "T", nil
+	case t == timeType:
+		return "string", nil // RFC 3339
+	case t == rawMessageType:
+		return "unknown", nil
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		return "unknown", nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.Interface:
+		return "unknown", nil
+	case reflect.Pointer:
+		return g.tsType(t.Elem(), indent)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string", nil // base64
+		}
+		elem, err := g.tsType(t.Elem(), indent)
+		if err != nil {
+			return "", err
+		}
+		if t.Elem().Kind() == reflect.Pointer {
+			elem += " | null" // nil elements marshal as null
+		}
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]", nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return "", fmt.Errorf("map key %s is not a string", t.Key())
+		}
+		elem, err := g.tsType(t.Elem(), indent)
+		if err != nil {
+			return "", err
+		}
+		return "Record<string, " + elem + ">", nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			var b strings.Builder
+			b.WriteString("{\n")
+			if err := g.writeFields(&b, t, indent+"  "); err != nil {
+				return "", err
+			}
+			b.WriteString(indent + "}")
+			return b.String(), nil
+		}
+		if err := g.add(t); err != nil {
+			return "", err
+		}
+		return typeName(t), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+// typeName drops the type arguments of generic types: Page[T] is "Page".
+func typeName(t reflect.Type) string {
+	name, _, _ := strings.Cut(t.Name(), "[")
+	return name
+}
+
+func isGeneric(t reflect.Type) bool {
+	return strings.Contains(t.Name(), "[")
+}
+
+// quoteName quotes property names that are not valid identifiers, e.g. "24h".
+func quoteName(name string) string {
+	for i, r := range name {
+		if r != '_' && r != '$' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && (i == 0 || !(r >= '0' && r <= '9')) {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
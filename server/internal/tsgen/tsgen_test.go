@@ -0,0 +1,117 @@
+package tsgen
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type inner struct {
+	Note string `json:"note"`
+}
+
+type Base struct {
+	ID string `json:"id"`
+}
+
+type sample struct {
+	Base
+	Name     string         `json:"name"`
+	Count    int            `json:"count,omitempty"`
+	Ratio    *float64       `json:"ratio,omitempty"`
+	Parent   *inner         `json:"parent"`
+	At       time.Time      `json:"at"`
+	Tags     []string       `json:"tags"`
+	Labels   map[string]int `json:"labels"`
+	Extra    any            `json:"extra"`
+	Window   *float64       `json:"24h,omitempty"`
+	Hidden   string         `json:"-"`
+	NoTag    bool
+	Children []inner         `json:"children"`
+	Point    struct{ X int } `json:"point"`
+	private  string
+	Nested   map[string][]bool `json:"nested"`
+	Slots    [2]*inner         `json:"slots"`
+}
+
+type page[E any] struct {
+	Items []E `json:"items"`
+}
+
+func TestGenerator(t *testing.T) {
+	t.Run("renders fields and referenced structs", func(t *testing.T) {
+		g := New()
+		if err := g.Add(sample{}); err != nil {
+			t.Fatalf("Add() = %v; want nil", err)
+		}
+		var b strings.Builder
+		if err := g.Write(&b); err != nil {
+			t.Fatalf("Write() = %v; want nil", err)
+		}
+		want := `// Code generated by tsgen. DO NOT EDIT.
+
+export interface sample {
+  id: string;
+  name: string;
+  count?: number;
+  ratio?: number;
+  parent: inner | null;
+  at: string;
+  tags: string[];
+  labels: Record<string, number>;
+  extra: unknown;
+  "24h"?: number;
+  NoTag: boolean;
+  children: inner[];
+  point: {
+    X: number;
+  };
+  nested: Record<string, boolean[]>;
+  slots: (inner | null)[];
+}
+
+export interface inner {
+  note: string;
+}
+`
+		if got := b.String(); got != want {
+			t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+		}
+	})
+
+	t.Run("renders generic types with a placeholder parameter", func(t *testing.T) {
+		g := New()
+		if err := g.Add(page[T]{}); err != nil {
+			t.Fatalf("Add() = %v; want nil", err)
+		}
+		var b strings.Builder
+		if err := g.Write(&b); err != nil {
+			t.Fatalf("Write() = %v; want nil", err)
+		}
+		if !strings.Contains(b.String(), "export interface page<T> {\n  items: T[];\n}") {
+			t.Errorf("Write() = %q; want page<T> with items: T[]", b.String())
+		}
+	})
+
+	t.Run("rejects non-struct types", func(t *testing.T) {
+		if err := New().Add("x"); err == nil {
+			t.Error("Add(string) = nil; want error")
+		}
+		if err := New().Add(struct{ X int }{}); err == nil {
+			t.Error("Add(anonymous struct) = nil; want error")
+		}
+	})
+
+	t.Run("rejects non-string map keys", func(t *testing.T) {
+		g := New()
+		type bad struct {
+			M map[int]string `json:"m"`
+		}
+		if err := g.Add(bad{}); err != nil {
+			t.Fatalf("Add() = %v; want nil", err)
+		}
+		if err := g.Write(&strings.Builder{}); err == nil {
+			t.Error("Write() = nil; want error for map[int]string")
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotModified sets the ETag and, when modified is not zero, Last-Modified
+// validators of a GET or HEAD response, and answers 304 Not Modified when the
+// request's If-None-Match or, without it, If-Modified-Since shows the
+// client's copy is current. It reports whether it did; the handler then
+// returns without building the body. etag is a quoted entity tag, weak
+// (W/"…") when the body may be compressed. If-None-Match compares tags
+// weakly, as RFC 9110 requires for GET.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	h := w.Header()
+	h.Set("ETag", etag)
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if !etagMatches(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		// HTTP dates have whole seconds.
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+	// A 304 carries the validators but no body headers.
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether the If-None-Match list matches etag, ignoring
+// weakness.
+func etagMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
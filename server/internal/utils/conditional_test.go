@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	const etag = `W/"abc"`
+	modified := time.Date(2025, 1, 1, 12, 0, 0, 500_000_000, time.UTC)
+	lastModified := "Wed, 01 Jan 2025 12:00:00 GMT"
+
+	for _, tc := range []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{"no preconditions", http.MethodGet, nil, false},
+		{"matching etag", http.MethodGet, map[string]string{"If-None-Match": `W/"abc"`}, true},
+		{"strong form of the etag", http.MethodGet, map[string]string{"If-None-Match": `"abc"`}, true},
+		{"etag in a list", http.MethodHead, map[string]string{"If-None-Match": `"x", W/"abc"`}, true},
+		{"wildcard", http.MethodGet, map[string]string{"If-None-Match": `*`}, true},
+		{"other etag", http.MethodGet, map[string]string{"If-None-Match": `"x"`}, false},
+		{"etag wins over date", http.MethodGet, map[string]string{"If-None-Match": `"x"`, "If-Modified-Since": lastModified}, false},
+		{"same date", http.MethodGet, map[string]string{"If-Modified-Since": lastModified}, true},
+		{"older date", http.MethodGet, map[string]string{"If-Modified-Since": "Wed, 01 Jan 2025 11:59:59 GMT"}, false},
+		{"invalid date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"not a read", http.MethodPost, map[string]string{"If-None-Match": `W/"abc"`}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/v1/stations", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", "application/json")
+
+			got := NotModified(rec, req, etag, modified)
+
+			if got != tc.want {
+				t.Fatalf("NotModified() = %v; want %v", got, tc.want)
+			}
+			if got && (rec.Code != http.StatusNotModified || rec.Header().Get("Content-Type") != "") {
+				t.Errorf("response = %d, Content-Type %q; want a bare 304", rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if tc.method != http.MethodPost &&
+				(rec.Header().Get("ETag") != etag || rec.Header().Get("Last-Modified") != lastModified) {
+				t.Errorf("validators = %q, %q; want %q, %q",
+					rec.Header().Get("ETag"), rec.Header().Get("Last-Modified"), etag, lastModified)
+			}
+		})
+	}
+}
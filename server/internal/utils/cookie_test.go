@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCookieSigner(t *testing.T) {
+	s := NewCookieSigner([]byte("0123456789abcdef0123456789abcdef"))
+	const value = "station_id=1&range=24h&page=2"
+
+	t.Run("round trip", func(t *testing.T) {
+		signed := s.Sign("weather_state", value)
+		if !strings.HasPrefix(signed, "v1.") {
+			t.Errorf("Sign() = %q; want v1. prefix", signed)
+		}
+		got, err := s.Verify("weather_state", signed)
+		if err != nil || got != value {
+			t.Errorf("Verify() = %q, %v; want %q, nil", got, err, value)
+		}
+	})
+
+	t.Run("rejects tampered value", func(t *testing.T) {
+		signed := s.Sign("weather_state", value)
+		forged := s.Sign("weather_state", "station_id=2&range=24h&page=2")
+		tampered := signed[:strings.LastIndex(signed, ".")] + forged[strings.LastIndex(forged, "."):]
+		if _, err := s.Verify("weather_state", tampered); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("Verify(tampered) err = %v; want ErrInvalidCookie", err)
+		}
+	})
+
+	t.Run("rejects value moved to another cookie", func(t *testing.T) {
+		signed := s.Sign("weather_state", value)
+		if _, err := s.Verify("other", signed); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("Verify(other name) err = %v; want ErrInvalidCookie", err)
+		}
+	})
+
+	t.Run("rejects value signed with another key", func(t *testing.T) {
+		other := NewCookieSigner([]byte("another-secret-another-secret-xx"))
+		if _, err := s.Verify("weather_state", other.Sign("weather_state", value)); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("Verify(other key) err = %v; want ErrInvalidCookie", err)
+		}
+	})
+
+	t.Run("rejects legacy unsigned value with ErrUnsignedCookie", func(t *testing.T) {
+		got, err := s.Verify("weather_state", value)
+		if !errors.Is(err, ErrUnsignedCookie) || got != "" {
+			t.Errorf("Verify(legacy) = %q, %v; want \"\", ErrUnsignedCookie", got, err)
+		}
+	})
+
+	t.Run("rejects malformed signed value", func(t *testing.T) {
+		for _, v := range []string{"v1.", "v1.abc", "v1.!!.abc", "v1.YQ.!!"} {
+			if _, err := s.Verify("weather_state", v); !errors.Is(err, ErrInvalidCookie) {
+				t.Errorf("Verify(%q) err = %v; want ErrInvalidCookie", v, err)
+			}
+		}
+	})
+
+	t.Run("nil signer passes values through", func(t *testing.T) {
+		var nilSigner *CookieSigner
+		if got := nilSigner.Sign("weather_state", value); got != value {
+			t.Errorf("Sign() = %q; want %q", got, value)
+		}
+		if got, err := nilSigner.Verify("weather_state", value); err != nil || got != value {
+			t.Errorf("Verify() = %q, %v; want %q, nil", got, err, value)
+		}
+	})
+
+	t.Run("SetCookie and ReadCookie", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.SetCookie(w, &http.Cookie{Name: "weather_state", Value: value, Path: "/"})
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Path != "/" {
+			t.Fatalf("cookies = %+v; want one cookie with Path /", cookies)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookies[0])
+		got, err := s.ReadCookie(r, "weather_state")
+		if err != nil || got != value {
+			t.Errorf("ReadCookie() = %q, %v; want %q, nil", got, err, value)
+		}
+		if _, err := s.ReadCookie(r, "missing"); !errors.Is(err, http.ErrNoCookie) {
+			t.Errorf("ReadCookie(missing) err = %v; want http.ErrNoCookie", err)
+		}
+	})
+}
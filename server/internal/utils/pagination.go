@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Pagination is the limit/offset window requested for a collection.
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
+// PageLinks are request URLs for the current, next, and previous pages.
+// Next and Prev are omitted at the ends of the collection.
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// Page is the envelope for every collection response.
+type Page[T any] struct {
+	Items  []T       `json:"items"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+	Links  PageLinks `json:"links"`
+}
+
+// ParsePagination reads the limit and offset query parameters. limit defaults
+// to defaultLimit and must be in 1..maxLimit; offset defaults to 0.
+func ParsePagination(r *http.Request, defaultLimit, maxLimit int) (Pagination, error) {
+	q := r.URL.Query()
+	p := Pagination{Limit: defaultLimit}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return Pagination{}, errors.New("invalid 'limit' (expected integer)")
+		}
+		if n <= 0 {
+			return Pagination{}, errors.New("'limit' must be > 0")
+		}
+		if n > maxLimit {
+			return Pagination{}, fmt.Errorf("'limit' must be <= %d", maxLimit)
+		}
+		p.Limit = n
+	}
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return Pagination{}, errors.New("invalid 'offset' (expected integer)")
+		}
+		if n < 0 {
+			return Pagination{}, errors.New("'offset' must be >= 0")
+		}
+		p.Offset = n
+	}
+	return p, nil
+}
+
+// NewPage wraps one page of items in the collection envelope. Links keep the
+// request's other query parameters.
+func NewPage[T any](r *http.Request, items []T, total int, p Pagination) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	page := Page[T]{
+		Items:  items,
+		Total:  total,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+		Links:  PageLinks{Self: pageURL(r, p.Limit, p.Offset)},
+	}
+	if p.Offset+p.Limit < total {
+		page.Links.Next = pageURL(r, p.Limit, p.Offset+p.Limit)
+	}
+	if p.Offset > 0 {
+		page.Links.Prev = pageURL(r, p.Limit, max(0, p.Offset-p.Limit))
+	}
+	return page
+}
+
+// WritePage writes one page of items as a 200 JSON envelope.
+func WritePage[T any](w http.ResponseWriter, r *http.Request, items []T, total int, p Pagination) {
+	WriteJSON(w, http.StatusOK, NewPage(r, items, total, p))
+}
+
+// Slice returns the window of items selected by p, for collections held in memory.
+func Slice[T any](items []T, p Pagination) []T {
+	if p.Offset >= len(items) {
+		return []T{}
+	}
+	end := min(len(items), p.Offset+p.Limit)
+	return items[p.Offset:end]
+}
+
+func pageURL(r *http.Request, limit, offset int) string {
+	u := *r.URL
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Pagination
+		wantErr bool
+	}{
+		{"", Pagination{Limit: 50}, false},
+		{"limit=10&offset=20", Pagination{Limit: 10, Offset: 20}, false},
+		{"limit=100", Pagination{Limit: 100}, false},
+		{"limit=101", Pagination{}, true},
+		{"limit=0", Pagination{}, true},
+		{"limit=abc", Pagination{}, true},
+		{"offset=-1", Pagination{}, true},
+		{"offset=x", Pagination{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil)
+			got, err := ParsePagination(r, 50, 100)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePagination() err = %v; wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePagination() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/items?station=1&limit=2&offset=2", nil)
+
+	t.Run("middle page has next and prev links", func(t *testing.T) {
+		page := NewPage(r, []int{3, 4}, 5, Pagination{Limit: 2, Offset: 2})
+		if page.Links.Self != "/items?limit=2&offset=2&station=1" {
+			t.Errorf("Self = %q", page.Links.Self)
+		}
+		if page.Links.Next != "/items?limit=2&offset=4&station=1" {
+			t.Errorf("Next = %q", page.Links.Next)
+		}
+		if page.Links.Prev != "/items?limit=2&offset=0&station=1" {
+			t.Errorf("Prev = %q", page.Links.Prev)
+		}
+	})
+
+	t.Run("ends of collection omit links", func(t *testing.T) {
+		page := NewPage(r, []int{1, 2}, 2, Pagination{Limit: 2})
+		if page.Links.Next != "" || page.Links.Prev != "" {
+			t.Errorf("Links = %+v; want only self", page.Links)
+		}
+	})
+
+	t.Run("nil items encode as empty array", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		WritePage[int](w, r, nil, 0, Pagination{Limit: 2})
+		var got map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if items, ok := got["items"].([]any); !ok || len(items) != 0 {
+			t.Errorf("items = %v; want []", got["items"])
+		}
+		for _, key := range []string{"total", "limit", "offset", "links"} {
+			if _, ok := got[key]; !ok {
+				t.Errorf("envelope missing %q", key)
+			}
+		}
+	})
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	if got := Slice(items, Pagination{Limit: 2, Offset: 3}); len(got) != 2 || got[0] != 4 {
+		t.Errorf("Slice() = %v; want [4 5]", got)
+	}
+	if got := Slice(items, Pagination{Limit: 2, Offset: 10}); got == nil || len(got) != 0 {
+		t.Errorf("Slice() past end = %v; want []", got)
+	}
+}
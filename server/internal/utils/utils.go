@@ -15,9 +15,41 @@ func WriteJSON(w http.ResponseWriter, status int, v any) {
 	}
 }
 
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error   string `json:"error"` // HTTP status text
+	Message string `json:"message"`
+}
+
 func WriteError(w http.ResponseWriter, status int, msg string) {
-	WriteJSON(w, status, map[string]any{
-		"error":   http.StatusText(status),
-		"message": msg,
+	WriteJSON(w, status, ErrorResponse{
+		Error:   http.StatusText(status),
+		Message: msg,
 	})
 }
+
+// Problem is an RFC 9457 problem details body.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"` // HTTP status text
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance,omitempty"` // request path
+}
+
+// WriteProblem writes an application/problem+json response about the
+// request to instance.
+func WriteProblem(w http.ResponseWriter, status int, detail, instance string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: instance,
+	})
+	if err != nil {
+		slog.Error("failed to write JSON", "error", err)
+	}
+}
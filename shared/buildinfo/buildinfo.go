@@ -0,0 +1,87 @@
+// Package buildinfo describes the running binary so logs, APIs and messages
+// from a deployment can be matched to the exact build.
+//
+// Binaries set the version, commit and build date with -ldflags, e.g.
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// and pass them to New. Without them, the commit falls back to the VCS stamp
+// the Go toolchain embeds when building from a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Unknown is reported for metadata that was not set at build time.
+const Unknown = "unknown"
+
+// Info is the build metadata of a binary. GoVersion, OS and Arch describe
+// the toolchain and platform it was built with.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// New returns the build metadata from the -ldflags values version, commit and
+// buildDate. Empty values are filled from the embedded VCS stamp where
+// possible, and otherwise reported as Unknown ("dev" for the version).
+func New(version, commit, buildDate string) Info {
+	info := Info{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if info.Commit == "" {
+		info.Commit = vcsCommit()
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = Unknown
+	}
+	return info
+}
+
+// vcsCommit returns the revision stamped by the go command, with a "-dirty"
+// suffix for builds of a modified tree, or "" when there is none.
+func vcsCommit() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision, modified string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// ShortCommit returns the first 12 characters of the commit, for log lines
+// and version strings.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 && i.Commit != Unknown {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
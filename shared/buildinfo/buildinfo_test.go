@@ -0,0 +1,28 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	got := New("1.2.3", "0123456789abcdef0123", "2025-03-01T12:00:00Z")
+	want := Info{Version: "1.2.3", Commit: "0123456789abcdef0123", BuildDate: "2025-03-01T12:00:00Z", GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if got != want {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+	if s := got.ShortCommit(); s != "0123456789ab" {
+		t.Errorf("ShortCommit() = %q", s)
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	// Test binaries carry no VCS stamp, so nothing fills the commit.
+	got := New("", "", "")
+	if got.Version != "dev" || got.Commit != Unknown || got.BuildDate != Unknown {
+		t.Errorf("New() = %+v", got)
+	}
+	if s := got.ShortCommit(); s != Unknown {
+		t.Errorf("ShortCommit() = %q", s)
+	}
+}
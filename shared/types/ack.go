@@ -0,0 +1,18 @@
+package types
+
+import "time"
+
+// Ack is published by the server on stations/{id}/ack/{sequence} once a
+// telemetry message carrying a Sequence has been stored. Gateways use it to
+// prune their replay buffer.
+type Ack struct {
+	StationID string `json:"station_id"`
+	Sequence  int    `json:"sequence"`
+	// Timestamp is the authoritative reading time the server stored.
+	Timestamp time.Time `json:"timestamp"`
+	// Metrics are the metrics of the message the server stored. A gateway
+	// publishing one message per metric shares the sequence between them, so
+	// it keeps the reading until every metric was acknowledged. Empty
+	// acknowledges the whole reading.
+	Metrics []string `json:"metrics,omitempty"`
+}
@@ -11,4 +11,56 @@ type Telemetry struct {
 	Pressure    *float64  `json:"pressure_hpa,omitempty"`
 	Battery     *float64  `json:"battery_v,omitempty"`
 	Sequence    *int      `json:"sequence,omitempty"`
+	RSSI        *int      `json:"rssi_dbm,omitempty"` // signal strength at the gateway
+	// Interval is the seconds between readings last reported by a station
+	// that adapts it; the server widens its gap detection to match.
+	Interval *int      `json:"interval_s,omitempty"`
+	Metadata *Metadata `json:"metadata,omitempty"`
+}
+
+// Metadata identifies the software that produced a message so version
+// regressions can be correlated with data quality. Empty fields are unknown.
+type Metadata struct {
+	GatewayVersion  string `json:"gateway_version,omitempty"`
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	// GatewayCommit and GatewayBuildDate identify the gateway build; they are
+	// sent in station health messages.
+	GatewayCommit    string `json:"gateway_commit,omitempty"`
+	GatewayBuildDate string `json:"gateway_build_date,omitempty"`
+}
+
+// Metric names, as {metric} expands to in gateway topic templates and as
+// listed in Ack.Metrics.
+const (
+	MetricTemperature = "temperature"
+	MetricHumidity    = "humidity"
+	MetricPressure    = "pressure"
+)
+
+// MetricNames lists every metric, in the order messages split per metric are
+// published.
+var MetricNames = []string{MetricTemperature, MetricHumidity, MetricPressure}
+
+// Metric returns the field holding metric name, or nil for an unknown name.
+func (t *Telemetry) Metric(name string) **float64 {
+	switch name {
+	case MetricTemperature:
+		return &t.Temperature
+	case MetricHumidity:
+		return &t.Humidity
+	case MetricPressure:
+		return &t.Pressure
+	}
+	return nil
+}
+
+// Metrics returns the names of the metrics t carries.
+func (t Telemetry) Metrics() []string {
+	var names []string
+	for _, name := range MetricNames {
+		if *t.Metric(name) != nil {
+			names = append(names, name)
+		}
+	}
+	return names
 }
@@ -2,4 +2,13 @@ module cloudpico-tools
 
 go 1.25.6
 
-require github.com/mattn/go-sqlite3 v1.14.33
+require (
+	github.com/eclipse/paho.mqtt.golang v1.5.1
+	github.com/mattn/go-sqlite3 v1.14.33
+)
+
+require (
+	github.com/gorilla/websocket v1.5.3 // indirect
+	golang.org/x/net v0.44.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+)
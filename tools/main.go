@@ -1,25 +1,83 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	"cloudpico-tools/migrate"
+	"cloudpico-tools/probe"
+	"cloudpico-tools/replay"
+	"cloudpico-tools/stations"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s <command>\n  migrate      apply pending schema/seed migrations (squash: print a baseline schema)\n  stations     add, rename, archive or locate stations\n  mqtt-replay  republish captured or stored telemetry to a broker\n  probe        check a running server end to end (exits 2 when a check fails)\n", os.Args[0])
+		os.Exit(1)
+	}
+
 	dbPath := os.Getenv("SQLITE_PATH")
 	if dbPath == "" {
 		dbPath = "../dev/sqlite/app.db"
 	}
 	dbPath = filepath.Clean(dbPath)
 
+	// mqtt-replay only opens the database when replaying stored readings.
+	if os.Args[1] == "mqtt-replay" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := replay.Run(ctx, os.Args[2:], func() (*sql.DB, error) { return Open(dbPath) }, os.Stdout)
+		stop()
+		if err != nil {
+			if errors.Is(err, replay.ErrUsage) {
+				fmt.Fprint(os.Stderr, replay.Usage)
+			}
+			fmt.Fprintf(os.Stderr, "mqtt-replay: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// probe only talks to the server and broker.
+	if os.Args[1] == "probe" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := probe.Run(ctx, os.Args[2:], os.Stdout)
+		stop()
+		switch {
+		case errors.Is(err, probe.ErrFailed):
+			os.Exit(2)
+		case err != nil:
+			if errors.Is(err, probe.ErrUsage) {
+				fmt.Fprint(os.Stderr, probe.Usage)
+			}
+			fmt.Fprintf(os.Stderr, "probe: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// migrate squash replays the migrations on an in-memory database.
+	if os.Args[1] == "migrate" && len(os.Args) > 2 {
+		if os.Args[2] != "squash" || len(os.Args) > 3 {
+			fmt.Fprintf(os.Stderr, "usage: %s migrate [squash]\n", os.Args[0])
+			os.Exit(1)
+		}
+		if err := migrate.Squash(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "migrate squash: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	conn, err := Open(dbPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "db open: %v\n", err)
@@ -31,11 +89,6 @@ func main() {
 		}
 	}()
 
-	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "usage: %s <command>\n  migrate  apply pending schema/seed migrations\n", os.Args[0])
-		os.Exit(1)
-	}
-
 	switch os.Args[1] {
 	case "migrate":
 		if err := migrate.Run(conn); err != nil {
@@ -43,6 +96,14 @@ func main() {
 			os.Exit(1)
 		}
 		fmt.Println("migrations applied")
+	case "stations":
+		if err := stations.Run(conn, os.Args[2:], os.Stdout); err != nil {
+			if errors.Is(err, stations.ErrUsage) {
+				fmt.Fprint(os.Stderr, stations.Usage)
+			}
+			fmt.Fprintf(os.Stderr, "stations: %v\n", err)
+			os.Exit(1)
+		}
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
 		os.Exit(1)
@@ -0,0 +1,208 @@
+package migrate
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// baselineSQL is the schema of the core migration chain up to the version in
+// its "-- baseline:" header, as written by Squash. Fresh databases apply it
+// instead of replaying each migration.
+//
+//go:embed baseline.sql
+var baselineSQL string
+
+var baselineVersionRe = regexp.MustCompile(`(?m)^-- baseline: (\d{4})$`)
+
+// Squash replays the embedded core migrations on an empty in-memory database
+// and writes the resulting schema and rows to w as a baseline for Run.
+func Squash(w io.Writer) error {
+	sub, err := fs.Sub(sqlFS, migrationsDir)
+	if err != nil {
+		return err
+	}
+	out, err := squash(sub)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+func squash(fsys fs.FS) (string, error) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1) // each connection would get its own in-memory database
+
+	if err := run(db, fsys, ""); err != nil {
+		return "", err
+	}
+	var version, name string
+	err = db.QueryRow("SELECT version, name FROM " + tableName + " ORDER BY version DESC LIMIT 1").Scan(&version, &name)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("no migrations to squash")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Code generated by \"go run . migrate squash\"; DO NOT EDIT.\n")
+	fmt.Fprintf(&b, "-- Schema after migrations up to %s_%s.sql. Run applies it to fresh\n", version, name)
+	fmt.Fprintf(&b, "-- databases instead of replaying them; later migrations apply on top.\n")
+	fmt.Fprintf(&b, "-- baseline: %s\n", version)
+
+	// sqlite_master lists objects in creation order, so tables precede the
+	// indexes on them and referenced tables precede their references.
+	rows, err := db.Query(`SELECT type, name, sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' AND name != ?
+		ORDER BY rowid`, tableName)
+	if err != nil {
+		return "", err
+	}
+	var tables []string
+	for rows.Next() {
+		var typ, name, stmt string
+		if err := rows.Scan(&typ, &name, &stmt); err != nil {
+			rows.Close()
+			return "", err
+		}
+		if typ == "table" {
+			tables = append(tables, name)
+		}
+		fmt.Fprintf(&b, "\n%s;\n", stmt)
+	}
+	if err := rows.Close(); err != nil {
+		return "", err
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	for _, table := range tables {
+		if err := dumpRows(db, &b, table); err != nil {
+			return "", fmt.Errorf("dump %s: %w", table, err)
+		}
+	}
+	return b.String(), nil
+}
+
+// dumpRows writes an INSERT statement for each row of table.
+func dumpRows(db *sql.DB, b *strings.Builder, table string) error {
+	quoted := `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+	rows, err := db.Query("SELECT * FROM " + quoted)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		lits := make([]string, len(vals))
+		for i, v := range vals {
+			lit, err := sqlLiteral(v)
+			if err != nil {
+				return fmt.Errorf("column %s: %w", cols[i], err)
+			}
+			lits[i] = lit
+		}
+		fmt.Fprintf(b, "\nINSERT INTO %s VALUES (%s);\n", quoted, strings.Join(lits, ", "))
+	}
+	return rows.Err()
+}
+
+// sqlLiteral formats a value scanned from SQLite as an SQL literal.
+func sqlLiteral(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'", nil
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// applyBaseline applies baseline to a fresh database, one without tables
+// other than the migrations table, and records the migrations of fsys it
+// covers as applied. It does nothing for other databases or without a
+// baseline.
+func applyBaseline(db *sql.DB, fsys fs.FS, baseline string) error {
+	m := baselineVersionRe.FindStringSubmatch(baseline)
+	if m == nil {
+		return nil
+	}
+	version := m[1]
+
+	if err := ensureMigrationsTable(db); err != nil {
+		return fmt.Errorf("ensure migrations table: %w", err)
+	}
+	var tables, applied int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != ?`, tableName).Scan(&tables)
+	if err != nil {
+		return err
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + tableName).Scan(&applied); err != nil {
+		return err
+	}
+	if tables > 0 || applied > 0 {
+		return nil
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return fmt.Errorf("read migrations dir: %w", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(baseline); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		v, name, ok := parseMigrationFilename(e.Name())
+		if !ok || v > version {
+			continue
+		}
+		if _, err := tx.Exec("INSERT INTO "+tableName+" (version, name) VALUES (?, ?)", v, name); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("migration baseline applied", "version", version)
+	return nil
+}
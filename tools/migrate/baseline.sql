@@ -0,0 +1,120 @@
+-- Code generated by "go run . migrate squash"; DO NOT EDIT.
+-- Schema after migrations up to 0020_annotations_canonical_timestamps.sql. Run applies it to fresh
+-- databases instead of replaying them; later migrations apply on top.
+-- baseline: 0020
+
+CREATE TABLE stations (
+  id         INTEGER PRIMARY KEY,                 -- rowid alias; fast PK
+  name       TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  metadata   TEXT                                     -- optional; store JSON string if you want
+, shadow_of INTEGER REFERENCES stations(id) ON DELETE SET NULL, photo_path TEXT, latitude REAL CHECK (latitude IS NULL OR (latitude >= -90.0 AND latitude <= 90.0)), longitude REAL CHECK (longitude IS NULL OR (longitude >= -180.0 AND longitude <= 180.0)), gateway_version TEXT, firmware_version TEXT, archived_at TEXT, retention_days INTEGER CHECK (retention_days IS NULL OR retention_days >= 0), interval_seconds INTEGER CHECK (interval_seconds IS NULL OR interval_seconds > 0), hourly_retention_days INTEGER CHECK (hourly_retention_days IS NULL OR hourly_retention_days >= 0), daily_retention_days INTEGER CHECK (daily_retention_days IS NULL OR daily_retention_days >= 0));
+
+CREATE UNIQUE INDEX idx_stations_name
+ON stations(name);
+
+CREATE INDEX idx_stations_shadow_of
+ON stations(shadow_of);
+
+CREATE TABLE annotations (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  start_ts   TEXT    NOT NULL,
+  end_ts     TEXT    NOT NULL,
+  note       TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (end_ts >= start_ts)
+);
+
+CREATE INDEX idx_annotations_station_start
+ON annotations(station_id, start_ts);
+
+CREATE TABLE daily_rollups (
+  station_id        INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  day               TEXT    NOT NULL,
+  readings          INTEGER NOT NULL,
+  temperature_count INTEGER NOT NULL,
+  temperature_min   REAL,
+  temperature_avg   REAL,
+  temperature_max   REAL,
+  humidity_count    INTEGER NOT NULL,
+  humidity_min      REAL,
+  humidity_avg      REAL,
+  humidity_max      REAL,
+  pressure_count    INTEGER NOT NULL,
+  pressure_min      REAL,
+  pressure_avg      REAL,
+  pressure_max      REAL,
+  PRIMARY KEY (station_id, day)
+);
+
+CREATE TABLE "readings" (
+  station_id      INTEGER NOT NULL,
+  ts              INTEGER NOT NULL,               -- unix milliseconds, UTC
+  temperature_c   REAL,
+  humidity_pct    REAL,
+  pressure_hpa    REAL,
+  received_at     TEXT,
+
+  -- Composite primary key: one reading per station per timestamp
+  PRIMARY KEY (station_id, ts),
+
+  -- FK back to stations
+  FOREIGN KEY (station_id) REFERENCES stations(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE,
+
+  -- Basic sanity checks (adjust ranges as you like)
+  CHECK (humidity_pct IS NULL OR (humidity_pct >= 0.0 AND humidity_pct <= 100.0)),
+  CHECK (pressure_hpa IS NULL OR pressure_hpa > 0.0)
+);
+
+CREATE INDEX idx_readings_station_ts
+ON readings(station_id, ts);
+
+CREATE INDEX idx_readings_ts
+ON readings(ts);
+
+CREATE TABLE snapshots (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  start_ts   TEXT    NOT NULL,
+  end_ts     TEXT    NOT NULL,
+  url        TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (end_ts >= start_ts)
+);
+
+CREATE INDEX idx_snapshots_station_start
+ON snapshots(station_id, start_ts);
+
+CREATE TABLE config_snapshot (
+  id         INTEGER PRIMARY KEY CHECK (id = 1),
+  settings   TEXT    NOT NULL, -- JSON array of {"name", "value"}
+  version    TEXT    NOT NULL, -- build version
+  started_at TEXT    NOT NULL
+);
+
+CREATE TABLE db_heartbeat (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  at TEXT    NOT NULL
+);
+
+CREATE TABLE hourly_rollups (
+  station_id        INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  hour              INTEGER NOT NULL,
+  readings          INTEGER NOT NULL,
+  temperature_count INTEGER NOT NULL,
+  temperature_min   REAL,
+  temperature_avg   REAL,
+  temperature_max   REAL,
+  humidity_count    INTEGER NOT NULL,
+  humidity_min      REAL,
+  humidity_avg      REAL,
+  humidity_max      REAL,
+  pressure_count    INTEGER NOT NULL,
+  pressure_min      REAL,
+  pressure_avg      REAL,
+  pressure_max      REAL,
+  PRIMARY KEY (station_id, hour)
+);
@@ -1,5 +1,8 @@
 // Package migrate runs SQLite schema migrations using a versioned migration table.
 // Migration files are named with a 4-digit prefix for order: 0001_name.sql, 0002_other.sql.
+//
+// baseline.sql, generated by Squash, consolidates the core migrations up to a
+// version; fresh databases start from it instead of replaying them.
 package migrate
 
 import (
@@ -23,8 +26,27 @@ const (
 var migrationFileRe = regexp.MustCompile(`^(\d{4})_(.+)\.sql$`)
 
 // Run ensures the schema_migrations table exists, then applies any embedded
-// migrations that have not yet been run, in order by version.
+// migrations that have not yet been run, in order by version. A fresh
+// database gets the baseline first, so only later migrations run.
 func Run(db *sql.DB) error {
+	sub, err := fs.Sub(sqlFS, migrationsDir)
+	if err != nil {
+		return err
+	}
+	if err := applyBaseline(db, sub, baselineSQL); err != nil {
+		return fmt.Errorf("apply baseline: %w", err)
+	}
+	return run(db, sub, "")
+}
+
+// RunFS applies the migrations at the root of fsys like Run. Versions are
+// recorded as "<prefix>/<version>", so a module numbers its migrations
+// independently of the core schema and of other modules.
+func RunFS(db *sql.DB, fsys fs.FS, prefix string) error {
+	return run(db, fsys, prefix+"/")
+}
+
+func run(db *sql.DB, fsys fs.FS, prefix string) error {
 	if err := ensureMigrationsTable(db); err != nil {
 		return fmt.Errorf("ensure migrations table: %w", err)
 	}
@@ -34,7 +56,7 @@ func Run(db *sql.DB) error {
 		return fmt.Errorf("list applied migrations: %w", err)
 	}
 
-	entries, err := fs.ReadDir(sqlFS, migrationsDir)
+	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
 		return fmt.Errorf("read migrations dir: %w", err)
 	}
@@ -48,10 +70,11 @@ func Run(db *sql.DB) error {
 		if !ok {
 			continue
 		}
+		version = prefix + version
 		if applied[version] {
 			continue
 		}
-		body, err := fs.ReadFile(sqlFS, migrationsDir+"/"+e.Name())
+		body, err := fs.ReadFile(fsys, e.Name())
 		if err != nil {
 			return fmt.Errorf("read migration %s: %w", e.Name(), err)
 		}
@@ -0,0 +1,279 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func coreFS(t *testing.T) fs.FS {
+	t.Helper()
+	sub, err := fs.Sub(sqlFS, migrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// schema returns the definitions of the objects in db and the applied versions.
+func schema(t *testing.T, db *sql.DB) (objects, versions []string) {
+	t.Helper()
+	query := func(q string) []string {
+		rows, err := db.Query(q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	return query("SELECT sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY name"),
+		query("SELECT version || '_' || name FROM schema_migrations ORDER BY version")
+}
+
+func TestBaselineIsCurrent(t *testing.T) {
+	got, err := squash(coreFS(t))
+	if err != nil {
+		t.Fatalf("squash() = %v", err)
+	}
+	if got != baselineSQL {
+		t.Error("baseline.sql is stale; regenerate it from tools/ with: go run . migrate squash > migrate/baseline.sql")
+	}
+}
+
+func TestRun_Baseline(t *testing.T) {
+	replayed := openTestDB(t)
+	if err := run(replayed, coreFS(t), ""); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+	wantObjects, wantVersions := schema(t, replayed)
+
+	fresh := openTestDB(t)
+	if err := Run(fresh); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	gotObjects, gotVersions := schema(t, fresh)
+	if !reflect.DeepEqual(gotObjects, wantObjects) {
+		t.Errorf("baseline schema = %q; want %q", gotObjects, wantObjects)
+	}
+	if !reflect.DeepEqual(gotVersions, wantVersions) {
+		t.Errorf("baseline versions = %q; want %q", gotVersions, wantVersions)
+	}
+}
+
+func TestApplyBaseline_SkipsExistingDatabase(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec(`CREATE TABLE stations (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyBaseline(db, coreFS(t), baselineSQL); err != nil {
+		t.Fatalf("applyBaseline() = %v", err)
+	}
+	if _, versions := schema(t, db); len(versions) != 0 {
+		t.Errorf("versions = %q; want none recorded for an existing database", versions)
+	}
+}
+
+// coreBefore returns the core migrations older than version.
+func coreBefore(t *testing.T, version string) fs.FS {
+	t.Helper()
+	before := fstest.MapFS{}
+	if err := fs.WalkDir(coreFS(t), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path >= version {
+			return err
+		}
+		data, err := fs.ReadFile(coreFS(t), path)
+		before[path] = &fstest.MapFile{Data: data}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return before
+}
+
+func TestReadingsCanonicalTimestamps(t *testing.T) {
+	db := openTestDB(t)
+	if err := run(db, coreBefore(t, "0012"), ""); err != nil {
+		t.Fatalf("run(up to 0011) = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (100, 'legacy');
+		INSERT INTO readings (station_id, ts, temperature_c, received_at) VALUES
+			(100, '2025-03-01T10:00:00Z', 1, '2025-03-01T10:00:01.5Z'),
+			(100, '2025-03-01T10:00:00.25Z', 2, NULL),
+			(100, '2025-03-01T12:00:00.123+02:00', 3, NULL),
+			(100, '2025-03-01 09:00:00', 4, '2025-03-01T09:00:01Z'),
+			(100, '2025-03-01T10:00:30.123456789Z', 5, NULL);`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := run(db, coreBefore(t, "0013"), ""); err != nil {
+		t.Fatalf("run(up to 0012) = %v", err)
+	}
+
+	rows, err := db.Query(`SELECT ts, temperature_c, COALESCE(received_at, '') FROM readings WHERE station_id = 100 ORDER BY ts`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var ts, receivedAt string
+		var temp float64
+		if err := rows.Scan(&ts, &temp, &receivedAt); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %g %s", ts, temp, receivedAt))
+	}
+	// The offset is converted to UTC, so that row sorts before 10:00:00.25.
+	want := []string{
+		"2025-03-01T09:00:00.000000000Z 4 2025-03-01T09:00:01.000000000Z",
+		"2025-03-01T10:00:00.000000000Z 1 2025-03-01T10:00:01.500000000Z",
+		"2025-03-01T10:00:00.123000000Z 3 ",
+		"2025-03-01T10:00:00.250000000Z 2 ",
+		"2025-03-01T10:00:30.123456789Z 5 ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readings = %q; want %q", got, want)
+	}
+}
+
+func TestReadingsTimestampMillis(t *testing.T) {
+	db := openTestDB(t)
+	if err := run(db, coreBefore(t, "0013"), ""); err != nil {
+		t.Fatalf("run(up to 0012) = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (100, 'legacy');
+		INSERT INTO readings (station_id, ts, temperature_c, received_at) VALUES
+			(100, '1969-12-31T23:59:59.000000000Z', 1, NULL),
+			(100, '2025-03-01T10:00:00.000000000Z', 2, '2025-03-01T10:00:01.500000000Z'),
+			(100, '2025-03-01T10:00:00.123456789Z', 3, NULL),
+			(100, '2025-03-01T10:00:00.123999999Z', 4, NULL),
+			(100, '2025-03-01T10:00:59.999999999Z', 6, NULL),
+			(100, 'yesterday', 5, NULL);`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := run(db, coreFS(t), ""); err != nil {
+		t.Fatalf("run() = %v", err)
+	}
+
+	rows, err := db.Query(`SELECT ts, typeof(ts), temperature_c, COALESCE(received_at, '') FROM readings WHERE station_id = 100 ORDER BY ts`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var ts int64
+		var typ, receivedAt string
+		var temp float64
+		if err := rows.Scan(&ts, &typ, &temp, &receivedAt); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s %g %s", ts, typ, temp, receivedAt))
+	}
+	// Sub-millisecond fractions are truncated, so 10:00:00.123... collapses
+	// into the later row; the unparsable row is dropped.
+	want := []string{
+		"-1000 integer 1 ",
+		"1740823200000 integer 2 2025-03-01T10:00:01.500000000Z",
+		"1740823200123 integer 4 ",
+		"1740823259999 integer 6 ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readings = %q; want %q", got, want)
+	}
+}
+
+func TestSnapshotsCanonicalTimestamps(t *testing.T) {
+	db := openTestDB(t)
+	if err := run(db, coreBefore(t, "0019"), ""); err != nil {
+		t.Fatalf("run(up to 0018) = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (100, 'legacy');
+		INSERT INTO snapshots (station_id, start_ts, end_ts, url) VALUES
+			(100, '2025-03-01T10:00:00Z', '2025-03-01T10:00:00Z', 'a'),
+			(100, '2025-03-01T10:00:00.5Z', '2025-03-01T10:00:01.123456789Z', 'b');`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := run(db, coreBefore(t, "0020"), ""); err != nil {
+		t.Fatalf("run(up to 0019) = %v", err)
+	}
+
+	rows, err := db.Query(`SELECT url, start_ts, end_ts FROM snapshots ORDER BY start_ts`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var url, start, end string
+		if err := rows.Scan(&url, &start, &end); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, url+" "+start+" "+end)
+	}
+	want := []string{
+		"a 2025-03-01T10:00:00.000000000Z 2025-03-01T10:00:00.000000000Z",
+		"b 2025-03-01T10:00:00.500000000Z 2025-03-01T10:00:01.123456789Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshots = %q; want %q", got, want)
+	}
+}
+
+func TestAnnotationsCanonicalTimestamps(t *testing.T) {
+	db := openTestDB(t)
+	if err := run(db, coreBefore(t, "0020"), ""); err != nil {
+		t.Fatalf("run(up to 0019) = %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO stations (id, name) VALUES (100, 'legacy');
+		INSERT INTO annotations (station_id, start_ts, end_ts, note) VALUES
+			(100, '2025-03-01T10:00:00.25Z', '2025-03-01T11:00:00Z', 'a'),
+			(100, '2025-03-01T10:00:00Z', '2025-03-01T10:00:00Z', 'b');`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if err := run(db, coreBefore(t, "0021"), ""); err != nil {
+		t.Fatalf("run(up to 0020) = %v", err)
+	}
+
+	rows, err := db.Query(`SELECT note, start_ts, end_ts FROM annotations ORDER BY start_ts`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var note, start, end string
+		if err := rows.Scan(&note, &start, &end); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, note+" "+start+" "+end)
+	}
+	want := []string{
+		"b 2025-03-01T10:00:00.000000000Z 2025-03-01T10:00:00.000000000Z",
+		"a 2025-03-01T10:00:00.250000000Z 2025-03-01T11:00:00.000000000Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("annotations = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,9 @@
+-- =========================
+-- shadow stations
+-- =========================
+-- A shadow station records the same conditions as its primary (e.g. a new
+-- sensor mounted next to an old one) so the two can be compared for bias/drift.
+ALTER TABLE stations ADD COLUMN shadow_of INTEGER REFERENCES stations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_stations_shadow_of
+ON stations(shadow_of);
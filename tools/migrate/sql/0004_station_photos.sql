@@ -0,0 +1,6 @@
+-- =========================
+-- station photos
+-- =========================
+-- Path of the uploaded photo relative to the uploads directory; the thumbnail
+-- path is derived from it.
+ALTER TABLE stations ADD COLUMN photo_path TEXT;
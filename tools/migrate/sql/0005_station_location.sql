@@ -0,0 +1,6 @@
+-- =========================
+-- station location
+-- =========================
+-- WGS84 coordinates in decimal degrees; NULL until the station is placed on the map.
+ALTER TABLE stations ADD COLUMN latitude REAL CHECK (latitude IS NULL OR (latitude >= -90.0 AND latitude <= 90.0));
+ALTER TABLE stations ADD COLUMN longitude REAL CHECK (longitude IS NULL OR (longitude >= -180.0 AND longitude <= 180.0));
@@ -0,0 +1,6 @@
+-- =========================
+-- reading receipt time
+-- =========================
+-- ts is the device-reported measurement time; received_at is when the server
+-- ingested the reading. NULL for rows written before this column existed.
+ALTER TABLE readings ADD COLUMN received_at TEXT;
@@ -0,0 +1,6 @@
+-- =========================
+-- station software versions
+-- =========================
+-- Last gateway and sensor firmware versions reported in telemetry metadata.
+ALTER TABLE stations ADD COLUMN gateway_version TEXT;
+ALTER TABLE stations ADD COLUMN firmware_version TEXT;
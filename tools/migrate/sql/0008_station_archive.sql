@@ -0,0 +1,6 @@
+-- =========================
+-- station archive
+-- =========================
+-- Set when a station is retired; archived stations are hidden from station lists
+-- but keep their readings.
+ALTER TABLE stations ADD COLUMN archived_at TEXT;
@@ -0,0 +1,17 @@
+-- =========================
+-- annotations
+-- =========================
+-- Notes attached to a time range of a station ("sensor moved indoors"). A point
+-- in time is stored with end_ts = start_ts.
+CREATE TABLE IF NOT EXISTS annotations (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  start_ts   TEXT    NOT NULL,
+  end_ts     TEXT    NOT NULL,
+  note       TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (end_ts >= start_ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_station_start
+ON annotations(station_id, start_ts);
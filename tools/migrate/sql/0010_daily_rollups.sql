@@ -0,0 +1,24 @@
+-- =========================
+-- daily_rollups
+-- =========================
+-- Per-station aggregates of one UTC day (day = 'YYYY-MM-DD'), recomputed from
+-- readings by the daily-rollup job. Averages are weighted by their count when
+-- days are combined into months.
+CREATE TABLE IF NOT EXISTS daily_rollups (
+  station_id        INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  day               TEXT    NOT NULL,
+  readings          INTEGER NOT NULL,
+  temperature_count INTEGER NOT NULL,
+  temperature_min   REAL,
+  temperature_avg   REAL,
+  temperature_max   REAL,
+  humidity_count    INTEGER NOT NULL,
+  humidity_min      REAL,
+  humidity_avg      REAL,
+  humidity_max      REAL,
+  pressure_count    INTEGER NOT NULL,
+  pressure_min      REAL,
+  pressure_avg      REAL,
+  pressure_max      REAL,
+  PRIMARY KEY (station_id, day)
+);
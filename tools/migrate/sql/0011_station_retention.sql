@@ -0,0 +1,6 @@
+-- =========================
+-- station retention
+-- =========================
+-- Days of raw readings the retention job keeps for the station, overriding the
+-- server default: NULL uses the default, 0 keeps readings forever.
+ALTER TABLE stations ADD COLUMN retention_days INTEGER CHECK (retention_days IS NULL OR retention_days >= 0);
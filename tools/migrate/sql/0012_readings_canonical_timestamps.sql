@@ -0,0 +1,29 @@
+-- =========================
+-- canonical reading timestamps
+-- =========================
+-- readings.ts and received_at were written with RFC 3339 and a variable number
+-- of fractional digits, so their text did not always sort in time order
+-- ("10:00:00.5Z" < "10:00:00Z"). They are now always UTC with nine fractional
+-- digits, e.g. 2025-03-01T10:00:00.500000000Z, which compares as time does.
+-- Values in other formats (offsets, a space separator) keep millisecond
+-- precision; values SQLite cannot parse are left alone. Rows of one station
+-- whose times only differed in format collapse into one.
+UPDATE OR REPLACE readings SET ts = COALESCE(CASE
+  WHEN ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z'
+    THEN substr(ts, 1, 19) || '.000000000Z'
+  WHEN ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9]*Z'
+    AND length(ts) <= 30
+    THEN substr(ts, 1, 20) || substr(substr(ts, 21, length(ts) - 21) || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%S', ts) || '.' || substr(strftime('%f', ts), 4, 3) || '000000Z'
+END, ts)
+WHERE ts NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]Z';
+
+UPDATE readings SET received_at = COALESCE(CASE
+  WHEN received_at GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z'
+    THEN substr(received_at, 1, 19) || '.000000000Z'
+  WHEN received_at GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9]*Z'
+    AND length(received_at) <= 30
+    THEN substr(received_at, 1, 20) || substr(substr(received_at, 21, length(received_at) - 21) || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%S', received_at) || '.' || substr(strftime('%f', received_at), 4, 3) || '000000Z'
+END, received_at)
+WHERE received_at NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]Z';
@@ -0,0 +1,49 @@
+-- =========================
+-- readings.ts as unix milliseconds
+-- =========================
+-- readings.ts becomes an INTEGER of milliseconds since the Unix epoch (UTC),
+-- so range bounds, the primary key and the indexes compare integers instead of
+-- 30-byte strings. Since 0012 every valid ts has the canonical form
+-- 2025-03-01T10:00:00.500000000Z; its fraction is truncated to milliseconds,
+-- as time.Time.UnixMilli does, and readings of one station that only differed
+-- below a millisecond collapse into one. Rows with any other ts, which 0012
+-- could not parse, are dropped. received_at stays canonical text.
+CREATE TABLE readings_new (
+  station_id      INTEGER NOT NULL,
+  ts              INTEGER NOT NULL,               -- unix milliseconds, UTC
+  temperature_c   REAL,
+  humidity_pct    REAL,
+  pressure_hpa    REAL,
+  received_at     TEXT,
+
+  -- Composite primary key: one reading per station per timestamp
+  PRIMARY KEY (station_id, ts),
+
+  -- FK back to stations
+  FOREIGN KEY (station_id) REFERENCES stations(id)
+    ON UPDATE CASCADE
+    ON DELETE CASCADE,
+
+  -- Basic sanity checks (adjust ranges as you like)
+  CHECK (humidity_pct IS NULL OR (humidity_pct >= 0.0 AND humidity_pct <= 100.0)),
+  CHECK (pressure_hpa IS NULL OR pressure_hpa > 0.0)
+);
+
+INSERT OR REPLACE INTO readings_new (station_id, ts, temperature_c, humidity_pct, pressure_hpa, received_at)
+SELECT station_id,
+  CAST(strftime('%s', substr(ts, 1, 19)) AS INTEGER) * 1000 + CAST(substr(ts, 21, 3) AS INTEGER),
+  temperature_c, humidity_pct, pressure_hpa, received_at
+FROM readings
+WHERE ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]Z'
+  AND strftime('%s', substr(ts, 1, 19)) IS NOT NULL
+ORDER BY station_id, ts;
+
+DROP TABLE readings;
+
+ALTER TABLE readings_new RENAME TO readings;
+
+CREATE INDEX idx_readings_station_ts
+ON readings(station_id, ts);
+
+CREATE INDEX idx_readings_ts
+ON readings(ts);
@@ -0,0 +1,17 @@
+-- =========================
+-- snapshots
+-- =========================
+-- Images (e.g. from a sky camera) attached to a time range of a station, by
+-- URL. A point in time is stored with end_ts = start_ts.
+CREATE TABLE IF NOT EXISTS snapshots (
+  id         INTEGER PRIMARY KEY,
+  station_id INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  start_ts   TEXT    NOT NULL,
+  end_ts     TEXT    NOT NULL,
+  url        TEXT    NOT NULL,
+  created_at TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  CHECK (end_ts >= start_ts)
+);
+
+CREATE INDEX IF NOT EXISTS idx_snapshots_station_start
+ON snapshots(station_id, start_ts);
@@ -0,0 +1,11 @@
+-- =========================
+-- config_snapshot
+-- =========================
+-- The settings the server last started with (see config.Settings), so the
+-- next start can log what changed. There is at most one row.
+CREATE TABLE IF NOT EXISTS config_snapshot (
+  id         INTEGER PRIMARY KEY CHECK (id = 1),
+  settings   TEXT    NOT NULL, -- JSON array of {"name", "value"}
+  version    TEXT    NOT NULL, -- build version
+  started_at TEXT    NOT NULL
+);
@@ -0,0 +1,10 @@
+-- =========================
+-- db_heartbeat
+-- =========================
+-- Written by the database health monitor on every probe (see db.Monitor): a
+-- write is what notices a held lock, a full disk or a read-only file. There
+-- is at most one row, holding the time of the last successful probe.
+CREATE TABLE IF NOT EXISTS db_heartbeat (
+  id INTEGER PRIMARY KEY CHECK (id = 1),
+  at TEXT    NOT NULL
+);
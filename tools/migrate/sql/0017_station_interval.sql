@@ -0,0 +1,6 @@
+-- =========================
+-- station reading interval
+-- =========================
+-- Seconds between readings last reported by a station that adapts its
+-- interval, used to widen its gap detection; NULL when never reported.
+ALTER TABLE stations ADD COLUMN interval_seconds INTEGER CHECK (interval_seconds IS NULL OR interval_seconds > 0);
@@ -0,0 +1,30 @@
+-- =========================
+-- downsampling
+-- =========================
+-- Per-station aggregates of one UTC hour, recomputed from readings by the
+-- hourly-rollup job; hour is the start in unix milliseconds, as readings.ts.
+-- Averages are weighted by their count when hours are combined.
+CREATE TABLE IF NOT EXISTS hourly_rollups (
+  station_id        INTEGER NOT NULL REFERENCES stations(id) ON DELETE CASCADE,
+  hour              INTEGER NOT NULL,
+  readings          INTEGER NOT NULL,
+  temperature_count INTEGER NOT NULL,
+  temperature_min   REAL,
+  temperature_avg   REAL,
+  temperature_max   REAL,
+  humidity_count    INTEGER NOT NULL,
+  humidity_min      REAL,
+  humidity_avg      REAL,
+  humidity_max      REAL,
+  pressure_count    INTEGER NOT NULL,
+  pressure_min      REAL,
+  pressure_avg      REAL,
+  pressure_max      REAL,
+  PRIMARY KEY (station_id, hour)
+);
+
+-- Days of hourly and daily rollups the retention job keeps for the station,
+-- overriding the server default as retention_days does for raw readings:
+-- NULL uses the default, 0 keeps them forever.
+ALTER TABLE stations ADD COLUMN hourly_retention_days INTEGER CHECK (hourly_retention_days IS NULL OR hourly_retention_days >= 0);
+ALTER TABLE stations ADD COLUMN daily_retention_days INTEGER CHECK (daily_retention_days IS NULL OR daily_retention_days >= 0);
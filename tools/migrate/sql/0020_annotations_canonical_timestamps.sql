@@ -0,0 +1,27 @@
+-- =========================
+-- canonical annotation timestamps
+-- =========================
+-- Annotation start_ts and end_ts were written as RFC 3339 with a variable number
+-- of fractional digits, so range queries and the end_ts >= start_ts check
+-- compared them out of time order ("10:00:00Z" > "10:00:00.5Z"). They now use
+-- the readings' received_at format, UTC with nine fractional digits (see
+-- 0012_readings_canonical_timestamps.sql).
+UPDATE annotations SET
+  start_ts = COALESCE(CASE
+  WHEN start_ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z'
+    THEN substr(start_ts, 1, 19) || '.000000000Z'
+  WHEN start_ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9]*Z'
+    AND length(start_ts) <= 30
+    THEN substr(start_ts, 1, 20) || substr(substr(start_ts, 21, length(start_ts) - 21) || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%S', start_ts) || '.' || substr(strftime('%f', start_ts), 4, 3) || '000000Z'
+END, start_ts),
+  end_ts = COALESCE(CASE
+  WHEN end_ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z'
+    THEN substr(end_ts, 1, 19) || '.000000000Z'
+  WHEN end_ts GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9]*Z'
+    AND length(end_ts) <= 30
+    THEN substr(end_ts, 1, 20) || substr(substr(end_ts, 21, length(end_ts) - 21) || '000000000', 1, 9) || 'Z'
+  ELSE strftime('%Y-%m-%dT%H:%M:%S', end_ts) || '.' || substr(strftime('%f', end_ts), 4, 3) || '000000Z'
+END, end_ts)
+WHERE start_ts NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]Z'
+   OR end_ts NOT GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9].[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]Z';
@@ -0,0 +1,389 @@
+// Package probe implements the "probe" command: synthetic monitoring of a
+// running server. Each run checks /healthz and the stations API and, with a
+// broker, publishes a canary reading over MQTT and polls the API until it can
+// be read back, measuring the end-to-end ingest latency. Results are printed,
+// exported in the Prometheus text format and reflected in the exit code.
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrUsage is returned for invalid command-line arguments.
+var ErrUsage = errors.New("invalid usage")
+
+// ErrFailed is returned by a single run when a check failed.
+var ErrFailed = errors.New("probe failed")
+
+// Usage describes the probe flags.
+const Usage = `usage: probe [flags]
+  -server <url>        server base URL (default http://localhost:8080)
+  -broker <url>        MQTT broker to publish a canary reading to; without it the ingest check is skipped
+  -station <name|id>   station of the canary readings (default probe-canary); use a dedicated one
+  -client-id <id>      MQTT client ID (default cloudpico-probe)
+  -timeout <dur>       limit of each check, e.g. 30s (default 30s)
+  -interval <dur>      repeat every interval until interrupted; 0 runs once (default 0)
+  -metrics-file <path> write the results in the Prometheus text format after every run
+  -listen <addr>       serve the results of the last run at /metrics, e.g. :9101
+`
+
+// Check names.
+const (
+	CheckHealth = "health"
+	CheckAPI    = "api"
+	CheckIngest = "ingest"
+)
+
+// Result is the outcome of one check. For the ingest check Duration is the
+// time from publishing the canary to reading it back from the API.
+type Result struct {
+	Check    string
+	OK       bool
+	Duration time.Duration
+	Err      error
+}
+
+// Publisher sends one MQTT message.
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+}
+
+// Prober runs the checks against one server.
+type Prober struct {
+	Server string
+	Client *http.Client
+	// Publisher and Station enable the ingest check.
+	Publisher Publisher
+	Station   string
+	Timeout   time.Duration
+	// PollInterval is the wait between reads while the canary is pending.
+	PollInterval time.Duration
+	Now          func() time.Time
+}
+
+// Run runs every check once, in order.
+func (p *Prober) Run(ctx context.Context) []Result {
+	results := []Result{
+		p.check(ctx, CheckHealth, func(ctx context.Context) error {
+			return p.get(ctx, "/healthz", nil)
+		}),
+		p.check(ctx, CheckAPI, func(ctx context.Context) error {
+			var page stationsPage
+			return p.get(ctx, "/api/v1/stations?limit=1", &page)
+		}),
+	}
+	if p.Publisher != nil {
+		results = append(results, p.checkIngest(ctx))
+	}
+	return results
+}
+
+func (p *Prober) check(ctx context.Context, name string, fn func(ctx context.Context) error) Result {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	start := p.Now()
+	err := fn(ctx)
+	return Result{Check: name, OK: err == nil, Duration: p.Now().Sub(start), Err: err}
+}
+
+// telemetry is the canary message, in the gateway's format. Ingest requires a
+// metric, so the canary reports 0 °C.
+type telemetry struct {
+	StationID   string    `json:"station_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	Temperature float64   `json:"temperature_c"`
+}
+
+// checkIngest publishes a canary reading timestamped now and polls the latest
+// reading of the station until it is the canary.
+func (p *Prober) checkIngest(ctx context.Context) Result {
+	return p.check(ctx, CheckIngest, func(ctx context.Context) error {
+		ts := p.Now().UTC().Truncate(time.Millisecond)
+		payload, err := json.Marshal(telemetry{StationID: p.Station, Timestamp: ts})
+		if err != nil {
+			return err
+		}
+		if err := p.Publisher.Publish("stations/"+p.Station+"/telemetry", payload); err != nil {
+			return fmt.Errorf("publish canary: %w", err)
+		}
+		id := ""
+		for {
+			if id == "" {
+				// A new station only exists once its first reading is stored.
+				if id, err = p.stationID(ctx); err != nil {
+					return err
+				}
+			}
+			if id != "" {
+				var latest []struct {
+					Time time.Time `json:"time"`
+				}
+				if err := p.get(ctx, "/api/v1/stations/"+url.PathEscape(id)+"/latest?limit=1", &latest); err != nil {
+					return err
+				}
+				if len(latest) > 0 && latest[0].Time.Equal(ts) {
+					return nil
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("canary reading of %s not readable after %s", ts.Format(time.RFC3339Nano), p.Timeout)
+			case <-time.After(p.PollInterval):
+			}
+		}
+	})
+}
+
+// stationsPage is the part of the stations API envelope the probe reads.
+type stationsPage struct {
+	Items []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"items"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// stationID returns the ID of the canary station, or "" while it does not
+// exist. Numeric stations are IDs already, as at ingest.
+func (p *Prober) stationID(ctx context.Context) (string, error) {
+	if _, err := strconv.Atoi(p.Station); err == nil {
+		return p.Station, nil
+	}
+	path := "/api/v1/stations?limit=1000"
+	for path != "" {
+		var page stationsPage
+		if err := p.get(ctx, path, &page); err != nil {
+			return "", err
+		}
+		for _, s := range page.Items {
+			if s.Name == p.Station {
+				return s.ID, nil
+			}
+		}
+		path = page.Links.Next
+	}
+	return "", nil
+}
+
+// get requests path on the server and decodes the JSON response into out
+// unless it is nil. Responses other than 200 are errors.
+func (p *Prober) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.Server, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: %s: %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("GET %s: decode: %w", path, err)
+	}
+	return nil
+}
+
+// WriteMetrics writes results in the Prometheus text format, with at as the
+// time of the run.
+func WriteMetrics(w io.Writer, results []Result, at time.Time) error {
+	var b bytes.Buffer
+	b.WriteString("# HELP cloudpico_probe_success Whether the check passed in the last run.\n# TYPE cloudpico_probe_success gauge\n")
+	for _, r := range results {
+		ok := 0
+		if r.OK {
+			ok = 1
+		}
+		fmt.Fprintf(&b, "cloudpico_probe_success{check=%q} %d\n", r.Check, ok)
+	}
+	b.WriteString("# HELP cloudpico_probe_duration_seconds Duration of the check in the last run; for ingest, from publishing the canary to reading it back.\n# TYPE cloudpico_probe_duration_seconds gauge\n")
+	for _, r := range results {
+		fmt.Fprintf(&b, "cloudpico_probe_duration_seconds{check=%q} %s\n", r.Check, strconv.FormatFloat(r.Duration.Seconds(), 'f', -1, 64))
+	}
+	b.WriteString("# HELP cloudpico_probe_last_run_timestamp_seconds Unix time of the last run.\n# TYPE cloudpico_probe_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&b, "cloudpico_probe_last_run_timestamp_seconds %d\n", at.Unix())
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeMetricsFile replaces path with the metrics of results, atomically so
+// a collector never reads a partial file.
+func writeMetricsFile(path string, results []Result, at time.Time) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := WriteMetrics(tmp, results, at); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Run parses the probe flags in args and probes the server: once, returning
+// ErrFailed when a check failed, or with -interval until ctx is done.
+func Run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("probe", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	server := fs.String("server", "http://localhost:8080", "")
+	broker := fs.String("broker", "", "")
+	station := fs.String("station", "probe-canary", "")
+	clientID := fs.String("client-id", "cloudpico-probe", "")
+	timeout := fs.Duration("timeout", 30*time.Second, "")
+	interval := fs.Duration("interval", 0, "")
+	metricsFile := fs.String("metrics-file", "", "")
+	listen := fs.String("listen", "", "")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%w: unexpected arguments %v", ErrUsage, fs.Args())
+	}
+	if *timeout <= 0 || *interval < 0 {
+		return fmt.Errorf("%w: -timeout must be > 0 and -interval >= 0", ErrUsage)
+	}
+	if *station == "" || strings.ContainsAny(*station, "/+#") {
+		return fmt.Errorf("%w: invalid -station %q", ErrUsage, *station)
+	}
+
+	p := &Prober{
+		Server:       *server,
+		Client:       &http.Client{},
+		Station:      *station,
+		Timeout:      *timeout,
+		PollInterval: 250 * time.Millisecond,
+		Now:          time.Now,
+	}
+	if *broker != "" {
+		pub, err := connect(ctx, *broker, *clientID)
+		if err != nil {
+			return err
+		}
+		defer pub.client.Disconnect(250)
+		p.Publisher = pub
+	}
+
+	var mu sync.Mutex
+	var last bytes.Buffer
+	if *listen != "" {
+		ln, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			_, _ = w.Write(last.Bytes())
+		})
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("probe metrics server stopped", "error", err)
+			}
+		}()
+		defer func() { _ = srv.Close() }()
+	}
+
+	for {
+		at := p.Now()
+		results := p.Run(ctx)
+		failed := false
+		for _, r := range results {
+			status := "ok"
+			if !r.OK {
+				status, failed = "FAIL", true
+			}
+			line := fmt.Sprintf("%s %s %s", r.Check, status, r.Duration.Round(time.Millisecond))
+			if r.Err != nil {
+				line += ": " + r.Err.Error()
+			}
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		last.Reset()
+		err := WriteMetrics(&last, results, at)
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if *metricsFile != "" {
+			if err := writeMetricsFile(*metricsFile, results, at); err != nil {
+				return fmt.Errorf("write metrics: %w", err)
+			}
+		}
+		if *interval == 0 {
+			if failed {
+				return ErrFailed
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// mqttPublisher publishes with QoS 1, as the gateway does.
+type mqttPublisher struct {
+	client mqtt.Client
+}
+
+func connect(ctx context.Context, broker, clientID string) (*mqttPublisher, error) {
+	opts := mqtt.NewClientOptions().AddBroker(broker).SetClientID(clientID).SetCleanSession(true)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-token.Done():
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("mqtt connect %s: %w", broker, err)
+	}
+	return &mqttPublisher{client: client}, nil
+}
+
+func (p *mqttPublisher) Publish(topic string, payload []byte) error {
+	token := p.client.Publish(topic, 1, false, payload)
+	if !token.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("publish timeout")
+	}
+	return token.Error()
+}
@@ -0,0 +1,147 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer stores the canary published through it and serves it back at
+// the API, like a server behind a broker.
+type fakeServer struct {
+	mu      sync.Mutex
+	stored  []telemetry
+	topics  []string
+	healthy bool
+}
+
+func (f *fakeServer) Publish(topic string, payload []byte) error {
+	var t telemetry
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.topics = append(f.topics, topic)
+	f.stored = append(f.stored, t)
+	return nil
+}
+
+func (f *fakeServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if !f.healthy {
+			http.Error(w, "db down", http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("GET /api/v1/stations", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		items := []map[string]string{{"id": "1", "name": "garden"}}
+		if len(f.stored) > 0 {
+			items = append(items, map[string]string{"id": "2", "name": "probe-canary"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": items, "links": map[string]string{}})
+	})
+	mux.HandleFunc("GET /api/v1/stations/2/latest", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		latest := []map[string]time.Time{}
+		if n := len(f.stored); n > 0 {
+			latest = append(latest, map[string]time.Time{"time": f.stored[n-1].Timestamp})
+		}
+		_ = json.NewEncoder(w).Encode(latest)
+	})
+	return mux
+}
+
+func newProber(f *fakeServer, url string) *Prober {
+	return &Prober{
+		Server:       url,
+		Client:       &http.Client{},
+		Publisher:    f,
+		Station:      "probe-canary",
+		Timeout:      time.Second,
+		PollInterval: time.Millisecond,
+		Now:          time.Now,
+	}
+}
+
+func TestProber_Run(t *testing.T) {
+	f := &fakeServer{healthy: true}
+	srv := httptest.NewServer(f.handler())
+	defer srv.Close()
+
+	results := newProber(f, srv.URL).Run(context.Background())
+	if len(results) != 3 {
+		t.Fatalf("results = %+v; want health, api and ingest", results)
+	}
+	for _, r := range results {
+		if !r.OK {
+			t.Errorf("%s failed: %v", r.Check, r.Err)
+		}
+	}
+	if len(f.topics) != 1 || f.topics[0] != "stations/probe-canary/telemetry" {
+		t.Errorf("published to %v; want one canary on stations/probe-canary/telemetry", f.topics)
+	}
+}
+
+func TestProber_Failures(t *testing.T) {
+	f := &fakeServer{healthy: false}
+	srv := httptest.NewServer(f.handler())
+	defer srv.Close()
+	p := newProber(f, srv.URL)
+	p.Station = "9" // an ID the server has no readings for
+
+	results := p.Run(context.Background())
+	got := map[string]Result{}
+	for _, r := range results {
+		got[r.Check] = r
+	}
+	if r := got[CheckHealth]; r.OK || !strings.Contains(r.Err.Error(), "500") {
+		t.Errorf("health = %+v; want failed with 500", r)
+	}
+	if !got[CheckAPI].OK {
+		t.Errorf("api = %+v; want ok", got[CheckAPI])
+	}
+	if r := got[CheckIngest]; r.OK || r.Err == nil {
+		t.Errorf("ingest = %+v; want failed", r)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	var b bytes.Buffer
+	results := []Result{
+		{Check: CheckHealth, OK: true, Duration: 12 * time.Millisecond},
+		{Check: CheckIngest, OK: false, Duration: 1500 * time.Millisecond},
+	}
+	if err := WriteMetrics(&b, results, time.Unix(1740830400, 0)); err != nil {
+		t.Fatalf("WriteMetrics() = %v", err)
+	}
+	for _, want := range []string{
+		`cloudpico_probe_success{check="health"} 1`,
+		`cloudpico_probe_success{check="ingest"} 0`,
+		`cloudpico_probe_duration_seconds{check="health"} 0.012`,
+		`cloudpico_probe_duration_seconds{check="ingest"} 1.5`,
+		`cloudpico_probe_last_run_timestamp_seconds 1740830400`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{{"-timeout", "0"}, {"-station", "a/b"}, {"extra"}} {
+		if err := Run(context.Background(), args, &bytes.Buffer{}); !errors.Is(err, ErrUsage) {
+			t.Errorf("Run(%v) = %v; want usage error", args, err)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Package replay implements the "mqtt-replay" command: it republishes captured
+// telemetry to an MQTT broker with the original spacing between messages, so
+// ingest bugs can be reproduced against a dev server.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// ErrUsage is returned for invalid command-line arguments.
+var ErrUsage = errors.New("invalid usage")
+
+// Usage describes the mqtt-replay flags.
+const Usage = `usage: mqtt-replay (-file <path> | -station <name> -from <time> -to <time>) [flags]
+  -file <path>       NDJSON telemetry, one message per line ("-" for stdin)
+  -station <name>    replay stored readings of this station (uses SQLITE_PATH)
+  -from, -to <time>  RFC 3339 range of stored readings
+  -broker <url>      MQTT broker (default tcp://localhost:1883)
+  -client-id <id>    MQTT client ID (default cloudpico-replay)
+  -speed <factor>    timing multiplier; 2 replays twice as fast, 0 without delays (default 1)
+  -max-delay <dur>   cap on the wait between two messages, e.g. 10s (default none)
+`
+
+// Message is one telemetry message to publish.
+type Message struct {
+	Topic   string
+	Payload []byte
+	// Time is the reading time; the spacing of consecutive times sets the
+	// replay timing.
+	Time time.Time
+}
+
+// telemetryHeader holds the fields of a telemetry message replay needs; the
+// payload is otherwise published byte for byte.
+type telemetryHeader struct {
+	StationID string    `json:"station_id"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func telemetryTopic(stationID string) string {
+	return "stations/" + stationID + "/telemetry"
+}
+
+// ReadNDJSON reads one telemetry message per line, as published by the
+// gateway. Blank lines are skipped.
+func ReadNDJSON(r io.Reader) ([]Message, error) {
+	var msgs []Message
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		payload := sc.Bytes()
+		if len(payload) == 0 {
+			continue
+		}
+		var h telemetryHeader
+		if err := json.Unmarshal(payload, &h); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if h.StationID == "" {
+			return nil, fmt.Errorf("line %d: missing station_id", line)
+		}
+		msgs = append(msgs, Message{
+			Topic:   telemetryTopic(h.StationID),
+			Payload: append([]byte(nil), payload...),
+			Time:    h.Timestamp,
+		})
+	}
+	return msgs, sc.Err()
+}
+
+// ReadDB loads the stored readings of the named station in [from, to] as
+// telemetry, oldest first.
+func ReadDB(db *sql.DB, station string, from, to time.Time) ([]Message, error) {
+	rows, err := db.Query(`SELECT r.ts, r.temperature_c, r.humidity_pct, r.pressure_hpa
+		FROM readings r JOIN stations s ON s.id = r.station_id
+		WHERE s.name = ? AND r.ts BETWEEN ? AND ?
+		ORDER BY r.ts`,
+		station, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("query readings: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var msgs []Message
+	for rows.Next() {
+		var ts int64
+		var t struct {
+			StationID   string    `json:"station_id"`
+			Timestamp   time.Time `json:"timestamp"`
+			Temperature *float64  `json:"temperature_c,omitempty"`
+			Humidity    *float64  `json:"humidity_pct,omitempty"`
+			Pressure    *float64  `json:"pressure_hpa,omitempty"`
+		}
+		if err := rows.Scan(&ts, &t.Temperature, &t.Humidity, &t.Pressure); err != nil {
+			return nil, fmt.Errorf("scan reading: %w", err)
+		}
+		t.StationID = station
+		// ts is stored as unix milliseconds.
+		t.Timestamp = time.UnixMilli(ts).UTC()
+		payload, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, Message{Topic: telemetryTopic(station), Payload: payload, Time: t.Timestamp})
+	}
+	return msgs, rows.Err()
+}
+
+// Publisher sends one message.
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+}
+
+// Replay publishes msgs in order. Before each message it waits for the time
+// between its reading and the previous one, divided by speed and capped at
+// maxDelay when maxDelay > 0. A speed of 0 publishes without waiting.
+func Replay(ctx context.Context, pub Publisher, msgs []Message, speed float64, maxDelay time.Duration) error {
+	for i, m := range msgs {
+		if i > 0 && speed > 0 {
+			delay := time.Duration(float64(m.Time.Sub(msgs[i-1].Time)) / speed)
+			if maxDelay > 0 {
+				delay = min(delay, maxDelay)
+			}
+			if delay > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(delay):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := pub.Publish(m.Topic, m.Payload); err != nil {
+			return fmt.Errorf("publish message %d to %s: %w", i+1, m.Topic, err)
+		}
+		slog.Debug("replayed message", "topic", m.Topic, "time", m.Time)
+	}
+	return nil
+}
+
+// Run parses the mqtt-replay flags in args, loads the messages and replays
+// them. openDB is only called for database replays.
+func Run(ctx context.Context, args []string, openDB func() (*sql.DB, error), out io.Writer) error {
+	fs := flag.NewFlagSet("mqtt-replay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("file", "", "")
+	station := fs.String("station", "", "")
+	fromStr := fs.String("from", "", "")
+	toStr := fs.String("to", "", "")
+	broker := fs.String("broker", "tcp://localhost:1883", "")
+	clientID := fs.String("client-id", "cloudpico-replay", "")
+	speed := fs.Float64("speed", 1, "")
+	maxDelay := fs.Duration("max-delay", 0, "")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+	if fs.NArg() != 0 || (*file == "") == (*station == "") {
+		return fmt.Errorf("%w: give either -file or -station", ErrUsage)
+	}
+	if *speed < 0 {
+		return fmt.Errorf("%w: -speed must be >= 0", ErrUsage)
+	}
+
+	var msgs []Message
+	var err error
+	if *file != "" {
+		msgs, err = readFile(*file)
+	} else {
+		msgs, err = readStation(openDB, *station, *fromStr, *toStr)
+	}
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		_, err := fmt.Fprintln(out, "no messages to replay")
+		return err
+	}
+
+	pub, err := connect(ctx, *broker, *clientID)
+	if err != nil {
+		return err
+	}
+	defer pub.client.Disconnect(250)
+
+	if err := Replay(ctx, pub, msgs, *speed, *maxDelay); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "replayed %d messages\n", len(msgs))
+	return err
+}
+
+func readFile(path string) ([]Message, error) {
+	if path == "-" {
+		return ReadNDJSON(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	msgs, err := ReadNDJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return msgs, nil
+}
+
+func readStation(openDB func() (*sql.DB, error), station, fromStr, toStr string) ([]Message, error) {
+	from, err := time.Parse(time.RFC3339, fromStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid -from %q (expected RFC 3339)", ErrUsage, fromStr)
+	}
+	to, err := time.Parse(time.RFC3339, toStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid -to %q (expected RFC 3339)", ErrUsage, toStr)
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: -from must be <= -to", ErrUsage)
+	}
+	db, err := openDB()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	return ReadDB(db, station, from, to)
+}
+
+// mqttPublisher publishes with QoS 1, as the gateway does.
+type mqttPublisher struct {
+	client mqtt.Client
+}
+
+func connect(ctx context.Context, broker, clientID string) (*mqttPublisher, error) {
+	opts := mqtt.NewClientOptions().AddBroker(broker).SetClientID(clientID).SetCleanSession(true)
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-token.Done():
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("mqtt connect %s: %w", broker, err)
+	}
+	return &mqttPublisher{client: client}, nil
+}
+
+func (p *mqttPublisher) Publish(topic string, payload []byte) error {
+	token := p.client.Publish(topic, 1, false, payload)
+	if !token.WaitTimeout(5 * time.Second) {
+		return fmt.Errorf("publish timeout")
+	}
+	return token.Error()
+}
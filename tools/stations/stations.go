@@ -0,0 +1,193 @@
+// Package stations implements the "stations" command: provisioning and
+// maintenance of weather stations directly in the server's SQLite database.
+//
+// Stations are referenced by numeric ID or by name.
+package stations
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrUsage is returned for invalid command-line arguments.
+var ErrUsage = errors.New("invalid usage")
+
+// Usage describes the stations subcommands.
+const Usage = `usage: stations <command> [arguments]
+  add [-lat <deg> -lon <deg>] <name>   create a station and print its ID
+  rename <station> <new-name>          change a station's name
+  archive <station>                    hide a station from station lists
+  set-location <station> <lat> <lon>   set a station's coordinates
+  set-retention <station> <days>       keep <days> of raw readings (0: forever,
+                                       "default": the server's READINGS_RETENTION_DAYS)
+`
+
+// MinRetentionDays is the shortest retention override the server accepts; it
+// must match the server's types.MinRetentionDays.
+const MinRetentionDays = 8
+
+// Run executes the stations subcommand in args and writes its result to out.
+func Run(db *sql.DB, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "add":
+		fs := flag.NewFlagSet("add", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		lat := fs.String("lat", "", "latitude in decimal degrees")
+		lon := fs.String("lon", "", "longitude in decimal degrees")
+		if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+			return ErrUsage
+		}
+		id, err := Add(db, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if *lat != "" || *lon != "" {
+			if err := setLocationArgs(db, strconv.FormatInt(id, 10), *lat, *lon); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(out, id)
+		return err
+	case "rename":
+		if len(args) != 2 {
+			return ErrUsage
+		}
+		return Rename(db, args[0], args[1])
+	case "archive":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		return Archive(db, args[0])
+	case "set-location":
+		if len(args) != 3 {
+			return ErrUsage
+		}
+		return setLocationArgs(db, args[0], args[1], args[2])
+	case "set-retention":
+		if len(args) != 2 {
+			return ErrUsage
+		}
+		if args[1] == "default" {
+			return SetRetention(db, args[0], nil)
+		}
+		days, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid days %q: %w", args[1], err)
+		}
+		return SetRetention(db, args[0], &days)
+	default:
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, cmd)
+	}
+}
+
+// Add creates a station and returns its ID. Names are unique.
+func Add(db *sql.DB, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, errors.New("station name is required")
+	}
+	res, err := db.Exec("INSERT INTO stations (name, metadata) VALUES (?, '{}')", name)
+	if err != nil {
+		return 0, fmt.Errorf("add station %q: %w", name, err)
+	}
+	return res.LastInsertId()
+}
+
+// Rename changes the name of station ref.
+func Rename(db *sql.DB, ref, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("station name is required")
+	}
+	id, err := resolve(db, ref)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("UPDATE stations SET name = ? WHERE id = ?", name, id); err != nil {
+		return fmt.Errorf("rename station %q: %w", ref, err)
+	}
+	return nil
+}
+
+// Archive marks station ref as archived. Archiving an archived station keeps
+// its original archive time.
+func Archive(db *sql.DB, ref string) error {
+	id, err := resolve(db, ref)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("UPDATE stations SET archived_at = COALESCE(archived_at, strftime('%Y-%m-%dT%H:%M:%fZ','now')) WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("archive station %q: %w", ref, err)
+	}
+	return nil
+}
+
+// SetLocation sets the WGS84 coordinates of station ref.
+func SetLocation(db *sql.DB, ref string, latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 {
+		return fmt.Errorf("latitude %v out of range -90..90", latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		return fmt.Errorf("longitude %v out of range -180..180", longitude)
+	}
+	id, err := resolve(db, ref)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("UPDATE stations SET latitude = ?, longitude = ? WHERE id = ?", latitude, longitude, id); err != nil {
+		return fmt.Errorf("set location of station %q: %w", ref, err)
+	}
+	return nil
+}
+
+// SetRetention overrides how many days of raw readings the server keeps for
+// station ref; 0 keeps them forever and nil reverts to the server default.
+func SetRetention(db *sql.DB, ref string, days *int) error {
+	if days != nil && *days != 0 && *days < MinRetentionDays {
+		return fmt.Errorf("retention must be 0 or at least %d days, got %d", MinRetentionDays, *days)
+	}
+	id, err := resolve(db, ref)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("UPDATE stations SET retention_days = ? WHERE id = ?", days, id); err != nil {
+		return fmt.Errorf("set retention of station %q: %w", ref, err)
+	}
+	return nil
+}
+
+func setLocationArgs(db *sql.DB, ref, lat, lon string) error {
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return fmt.Errorf("invalid latitude %q: %w", lat, err)
+	}
+	longitude, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return fmt.Errorf("invalid longitude %q: %w", lon, err)
+	}
+	return SetLocation(db, ref, latitude, longitude)
+}
+
+// resolve returns the ID of the station with ID or name ref. A numeric ref that
+// is both one station's ID and another's name selects by ID.
+func resolve(db *sql.DB, ref string) (int64, error) {
+	var id int64
+	err := db.QueryRow("SELECT id FROM stations WHERE CAST(id AS TEXT) = ?1 OR name = ?1 ORDER BY name = ?1 LIMIT 1", ref).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("station %q not found", ref)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find station %q: %w", ref, err)
+	}
+	return id, nil
+}
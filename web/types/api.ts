@@ -0,0 +1,230 @@
+// Code generated by tsgen. DO NOT EDIT.
+
+export interface Station {
+  id: string;
+  name: string;
+  shadowOf?: string;
+  photoPath?: string;
+  latitude?: number;
+  longitude?: number;
+  gatewayVersion?: string;
+  firmwareVersion?: string;
+  retentionDays?: number;
+  hourlyRetentionDays?: number;
+  dailyRetentionDays?: number;
+  downsampling?: Downsampling;
+  intervalSeconds?: number;
+  uptime?: Uptime;
+  health?: Health;
+}
+
+export interface StationDetail {
+  id: string;
+  name: string;
+  shadowOf?: string;
+  photoPath?: string;
+  latitude?: number;
+  longitude?: number;
+  gatewayVersion?: string;
+  firmwareVersion?: string;
+  retentionDays?: number;
+  hourlyRetentionDays?: number;
+  dailyRetentionDays?: number;
+  downsampling?: Downsampling;
+  intervalSeconds?: number;
+  uptime?: Uptime;
+  health?: Health;
+  latest?: Reading;
+}
+
+export interface Reading {
+  stationId: string;
+  time: string;
+  value: number;
+  humidityPct: number;
+  pressureHpa: number;
+  receivedAt?: string;
+}
+
+export interface Telemetry {
+  station_id: string;
+  timestamp: string;
+  temperature_c?: number;
+  humidity_pct?: number;
+  pressure_hpa?: number;
+  battery_v?: number;
+  sequence?: number;
+  rssi_dbm?: number;
+  interval_s?: number;
+  metadata?: Metadata;
+}
+
+export interface Annotation {
+  id: string;
+  stationId: string;
+  start: string;
+  end: string;
+  note: string;
+  createdAt: string;
+}
+
+export interface Snapshot {
+  id: string;
+  stationId: string;
+  start: string;
+  end: string;
+  url: string;
+  createdAt: string;
+}
+
+export interface Climate {
+  stationId: string;
+  years: ClimateYear[];
+}
+
+export interface Aggregate {
+  stationId: string;
+  bucket: string;
+  units: string;
+  from: string;
+  to: string;
+  buckets: AggregateBucket[];
+}
+
+export interface Stats {
+  stationId: string;
+  from: string;
+  to: string;
+  count: number;
+  temperature: MetricStats;
+  humidity: MetricStats;
+  pressure: MetricStats;
+}
+
+export interface LatestMetrics {
+  stationId: string;
+  temperature: LatestMetric | null;
+  humidity: LatestMetric | null;
+  pressure: LatestMetric | null;
+}
+
+export interface QuotaStatus {
+  quotas: Quotas;
+  stations: number;
+  usage: StationQuotaUsage[];
+}
+
+export interface Info {
+  version: string;
+  commit: string;
+  buildDate: string;
+  goVersion: string;
+  os: string;
+  arch: string;
+}
+
+export interface Page<T> {
+  items: T[];
+  total: number;
+  limit: number;
+  offset: number;
+  links: PageLinks;
+}
+
+export interface ErrorResponse {
+  error: string;
+  message: string;
+}
+
+export interface Downsampling {
+  rawDays: number;
+  hourlyDays: number;
+  dailyDays: number;
+}
+
+export interface Uptime {
+  "24h"?: number;
+  "7d"?: number;
+  "30d"?: number;
+}
+
+export interface Health {
+  score: number;
+  status: string;
+  freshness?: number;
+  gaps?: number;
+  battery?: number;
+  rssi?: number;
+  rejections?: number;
+}
+
+export interface Metadata {
+  gateway_version?: string;
+  firmware_version?: string;
+  gateway_commit?: string;
+  gateway_build_date?: string;
+}
+
+export interface ClimateYear {
+  year: number;
+  months: (MonthlyClimate | null)[];
+}
+
+export interface AggregateBucket {
+  start: string;
+  count: number;
+  temperature: MetricSummary;
+  humidity: MetricSummary;
+  pressure: MetricSummary;
+}
+
+export interface MetricStats {
+  count: number;
+  min: number | null;
+  max: number | null;
+  avg: number | null;
+  stddev: number | null;
+}
+
+export interface LatestMetric {
+  value: number;
+  time: string;
+  receivedAt?: string;
+  ageSeconds: number;
+  stale: boolean;
+}
+
+export interface Quotas {
+  maxStations: number;
+  maxReadingsPerDay: number;
+  maxRetentionDays: number;
+}
+
+export interface StationQuotaUsage {
+  stationId: string;
+  stationName: string;
+  readingsToday: number;
+}
+
+export interface PageLinks {
+  self: string;
+  next?: string;
+  prev?: string;
+}
+
+export interface MonthlyClimate {
+  month: string;
+  days: number;
+  count: number;
+  temperature: MetricSummary;
+  humidity: MetricSummary;
+  pressure: MetricSummary;
+  temperatureAvgChange: number | null;
+}
+
+export interface MetricSummary {
+  count: number;
+  min: number | null;
+  avg: number | null;
+  max: number | null;
+}